@@ -70,11 +70,17 @@ func main() {
 	flag.StringVar(&linkRuntime, "link-runtime", "", "Path to runtime for linking (generates Makefile with -I flag)")
 	flag.StringVar(&graphicsRuntime, "graphics-runtime", "tigr", "Graphics runtime: tigr (default), sdl2, none")
 	flag.BoolVar(&compiler.DebugMode, "debug", false, "Enable debug output")
+	flag.IntVar(&compiler.IntSize, "int-size", 64, "Width in bits of int and uint in the generated code: 64 (default), 32")
+	flag.BoolVar(&compiler.JSBigInt, "js-bigint", true, "Emit int64 and uint64, and 64-bit int and uint beyond 2^53, as BigInt in JavaScript, false keeps them numbers exact up to 2^53")
 	flag.Parse()
 	if sourceDir == "" {
 		fmt.Println("Please provide a source directory")
 		return
 	}
+	if compiler.IntSize != 32 && compiler.IntSize != 64 {
+		fmt.Println("Please provide an int size of 32 or 64")
+		return
+	}
 
 	// Parse output directory and name
 	outputDir := filepath.Dir(output)
@@ -13,59 +13,25 @@ type SemaTestCase struct {
 	Name           string
 	Code           string
 	ExpectedError  string
+	Args           []string // Extra compiler flags
 }
 
 var semaTestCases = []SemaTestCase{
 	{
-		Name: "iota",
+		Name: "nil_comparison_interface_with_methods",
 		Code: `package main
 
-const (
-	A = iota
-	B
-	C
-)
-
-func main() {
-}
-`,
-		ExpectedError: "iota is not allowed",
-	},
-	{
-		Name: "range_over_inline_literal",
-		Code: `package main
-
-func main() {
-	for _, x := range []int{1, 2, 3} {
-		_ = x
-	}
-}
-`,
-		ExpectedError: "range over inline slice literal",
-	},
-	{
-		Name: "nil_comparison_eq",
-		Code: `package main
-
-func main() {
-	var a []int
-	if a == nil {
-	}
+type Shape interface {
+	Area() int
 }
-`,
-		ExpectedError: "nil comparison",
-	},
-	{
-		Name: "nil_comparison_neq",
-		Code: `package main
 
 func main() {
-	var a []int
-	if a != nil {
+	var s Shape
+	if s == nil {
 	}
 }
 `,
-		ExpectedError: "nil comparison",
+		ExpectedError: "nil comparison is not supported for this type",
 	},
 	// Note: empty_interface and slice_of_empty_interface tests removed
 	// interface{} is now supported (maps to std::any/Box<dyn Any>/object)
@@ -146,185 +112,1253 @@ func main() {
 		ExpectedError: "slice self-reference pattern",
 	},
 	{
-		Name: "multiple_closures_capture_same_var",
+		Name: "map_struct_key",
 		Code: `package main
 
+type Point struct {
+	X int
+	Y int
+}
+
 func main() {
-	x := "hello"
-	fn1 := func() string { return x }
-	fn2 := func() string { return x }
-	_ = fn1
-	_ = fn2
+	m := make(map[Point]int)
+	_ = m
 }
 `,
-		ExpectedError: "multiple closures capture same variable",
+		ExpectedError: "unsupported map key type",
 	},
 	{
-		Name: "variable_shadowing_in_nested_block",
+		Name: "method_on_non_struct",
 		Code: `package main
 
+type Celsius float64
+
+func (c Celsius) Double() Celsius {
+	return c * 2
+}
+
 func main() {
-	col := 0
-	for {
-		if col >= 10 {
-			break
-		}
-		col := 1
-		_ = col
-	}
-	_ = col
+	_ = Celsius(1).Double()
 }
 `,
-		ExpectedError: "variable shadowing is not supported",
+		ExpectedError: "methods are only supported on struct types",
 	},
 	{
-		Name: "variable_shadowing_in_if_block",
+		Name: "anonymous_non_empty_interface",
 		Code: `package main
 
+func area(s interface{ Area() int }) int {
+	return s.Area()
+}
+
 func main() {
-	x := 5
-	if true {
-		x := 10
-		_ = x
+}
+`,
+		ExpectedError: "anonymous non-empty interfaces are not supported",
+	},
+	{
+		Name: "interface_embedding",
+		Code: `package main
+
+type Sizer interface {
+	Size() int
+}
+
+type Shape interface {
+	Sizer
+	Area() int
+}
+
+func main() {
+}
+`,
+		ExpectedError: "interface embedding is not supported",
+	},
+	{
+		Name: "type_assert_to_interface",
+		Code: `package main
+
+type Shape interface {
+	Area() int
+}
+
+func main() {
+	var x interface{}
+	x = 1
+	_, ok := x.(Shape)
+	_ = ok
+}
+`,
+		ExpectedError: "type assertion to an interface type is not supported",
+	},
+	{
+		Name: "interface_to_interface_conversion",
+		Code: `package main
+
+type Shape interface {
+	Area() int
+}
+
+type Square struct {
+	Side int
+}
+
+func (s Square) Area() int {
+	return s.Side * s.Side
+}
+
+func main() {
+	var s Shape = Square{Side: 2}
+	var a any = s
+	_ = a
+}
+`,
+		ExpectedError: "conversion between interface types is not supported",
+	},
+	{
+		Name: "method_value",
+		Code: `package main
+
+type Counter struct {
+	Count int
+}
+
+func (c Counter) Get() int {
+	return c.Count
+}
+
+func apply(f func() int) int {
+	return f()
+}
+
+func main() {
+	c := Counter{Count: 1}
+	_ = apply(c.Get)
+}
+`,
+		ExpectedError: "method values are not supported",
+	},
+	{
+		Name: "int_constant_overflow_32",
+		Code: `package main
+
+func main() {
+	big := 1 << 40
+	_ = big
+}
+`,
+		ExpectedError: "constant 1099511627776 overflows int with -int-size=32",
+		Args:          []string{"-int-size=32"},
+	},
+	{
+		Name: "type_switch_nil_case",
+		Code: `package main
+
+func main() {
+	var x interface{}
+	x = 1
+	switch x.(type) {
+	case nil:
+	case int:
 	}
-	_ = x
 }
 `,
-		ExpectedError: "variable shadowing is not supported",
+		ExpectedError: "nil case in a type switch is not supported",
 	},
+	{
+		Name: "type_switch_interface_case",
+		Code: `package main
+
+type Shape interface {
+	Area() int
 }
 
-// SemaValidTestCase represents code that SHOULD compile successfully
-type SemaValidTestCase struct {
-	Name string
-	Code string
+func main() {
+	var x interface{}
+	x = 1
+	switch x.(type) {
+	case Shape:
+	}
 }
+`,
+		ExpectedError: "type switch case on an interface type is not supported",
+	},
+	{
+		Name: "type_switch_named_and_underlying_case",
+		Code: `package main
 
-var semaValidTestCases = []SemaValidTestCase{
+type Color int
+
+func main() {
+	var x interface{}
+	x = Color(1)
+	switch x.(type) {
+	case int:
+	case Color:
+	}
+}
+`,
+		ExpectedError: "type switch cases with the same underlying type are not supported",
+	},
 	{
-		Name: "string_reassign_then_reuse",
+		Name: "type_switch_break",
 		Code: `package main
 
 func main() {
-	x := "hello"
-	x = x + " world"
-	y := x + "!"
-	_ = y
+	var x interface{}
+	x = 1
+	switch x.(type) {
+	case int:
+		break
+	}
 }
 `,
+		ExpectedError: "break inside a type switch is not supported",
 	},
 	{
-		Name: "string_plusequal_then_reuse",
+		Name: "select_break",
 		Code: `package main
 
 func main() {
-	x := "hello"
-	x += " world"
-	y := x + "!"
-	_ = y
+	ch := make(chan int, 1)
+	ch <- 1
+	select {
+	case <-ch:
+		break
+	}
 }
 `,
+		ExpectedError: "break inside a select statement is not supported",
 	},
 	{
-		Name: "struct_field_init_correct_order",
+		Name: "select_recv_into_field",
 		Code: `package main
 
-type Person struct {
-	Name string
-	Age  int
-	City string
+type box struct {
+	value int
 }
 
 func main() {
-	p := Person{
-		Name: "Alice",
-		Age:  30,
-		City: "NYC",
+	ch := make(chan int, 1)
+	ch <- 1
+	var b box
+	select {
+	case b.value = <-ch:
 	}
-	_ = p
+	_ = b
 }
 `,
+		ExpectedError: "select case receiving into an expression is not supported",
 	},
-	// Valid Rust ownership patterns
 	{
-		Name: "same_var_int_multiple_times_ok",
+		Name: "chan_len",
 		Code: `package main
 
-func add(a int, b int) int { return a + b }
+func main() {
+	ch := make(chan int, 1)
+	_ = len(ch)
+}
+`,
+		ExpectedError: "len and cap of channels are not supported",
+	},
+	{
+		Name: "package_var_without_type",
+		Code: `package main
+
+var limit = 10
 
 func main() {
-	x := 5
-	result := add(x, x)
-	_ = result
+	_ = limit
 }
 `,
+		ExpectedError: "package-level variable without explicit type",
 	},
 	{
-		Name: "slice_different_slices_assignment_ok",
+		Name: "package_var_multi_value_init",
 		Code: `package main
 
+func pair() (int, int) {
+	return 1, 2
+}
+
+var a, b int = pair()
+
 func main() {
-	slice1 := []int{1, 2, 3}
-	slice2 := []int{4, 5, 6}
-	slice1[0] = slice2[0]
-	_ = slice1
+	_ = a + b
 }
 `,
+		ExpectedError: "package-level variables initialized from a multi-value call are not supported",
 	},
 	{
-		Name: "slice_temp_variable_ok",
+		Name: "embedded_non_struct",
 		Code: `package main
 
+type Named interface {
+	Name() string
+}
+
+type Item struct {
+	Named
+	count int
+}
+
 func main() {
-	slice := []int{1, 2, 3, 4, 5}
-	tmp := slice[1]
-	slice[0] = tmp
-	_ = slice
+	i := Item{count: 1}
+	_ = i.count
 }
 `,
+		ExpectedError: "only struct types can be embedded",
 	},
 	{
-		Name: "single_closure_capture_ok",
+		Name: "interface_via_promoted_method",
 		Code: `package main
 
+type Sizer interface {
+	Size() int
+}
+
+type Base struct {
+	n int
+}
+
+func (b Base) Size() int {
+	return b.n
+}
+
+type Derived struct {
+	Base
+}
+
 func main() {
-	x := "hello"
-	fn := func() string { return x }
-	_ = fn
+	var s Sizer
+	s = Derived{Base: Base{n: 1}}
+	_ = s.Size()
 }
 `,
+		ExpectedError: "interface satisfied by a promoted method is not supported",
 	},
 	{
-		Name: "variable_reassignment_ok",
+		Name: "generic_slice_type",
 		Code: `package main
 
+type List[T any] []T
+
 func main() {
-	col := 0
-	for {
-		if col >= 10 {
-			break
-		}
-		col = col + 1
+	l := List[int]{1, 2}
+	_ = len(l)
+}
+`,
+		ExpectedError: "generic non-struct types are not supported",
+	},
+	{
+		Name: "method_constraint",
+		Code: `package main
+
+type Sizer interface {
+	Size() int
+}
+
+func Total[T Sizer](items []T) int {
+	total := 0
+	for _, item := range items {
+		total += item.Size()
 	}
-	_ = col
+	return total
+}
+
+func main() {
+	_ = Total[Sizer](nil)
 }
 `,
+		ExpectedError: "unsupported type parameter constraint",
 	},
 	{
-		Name: "different_var_names_in_nested_scope_ok",
+		Name: "string_union_constraint",
+		Code: `package main
+
+type Text interface {
+	~string | ~int
+}
+
+type Box[T Text] struct {
+	value T
+}
+
+func main() {
+	b := Box[string]{value: "a"}
+	_ = b.value
+}
+`,
+		ExpectedError: "unsupported type parameter constraint",
+	},
+	{
+		Name: "labeled_statement",
 		Code: `package main
 
 func main() {
-	col := 0
-	for {
-		if col >= 10 {
-			break
-		}
-		innerCol := 1
-		_ = innerCol
-		col = col + 1
+	n := 0
+again:
+	n++
+	if n < 3 {
+		goto again
+	}
+}
+`,
+		ExpectedError: "labeled statements are not supported",
+	},
+	{
+		Name: "indexed_array_literal",
+		Code: `package main
+
+func main() {
+	a := [4]int{2: 1}
+	_ = a
+}
+`,
+		ExpectedError: "indexed array literal elements are not supported",
+	},
+	{
+		Name: "pointer_to_int",
+		Code: `package main
+
+func main() {
+	x := 1
+	var p *int
+	_ = p
+	_ = x
+}
+`,
+		ExpectedError: "pointers to non-struct types are not supported",
+	},
+	{
+		Name: "new_int",
+		Code: `package main
+
+func main() {
+	p := new(int)
+	_ = p
+}
+`,
+		ExpectedError: "pointers to non-struct types are not supported",
+	},
+	{
+		Name: "address_of_field",
+		Code: `package main
+
+type Point struct {
+	X int
+}
+
+type Line struct {
+	From Point
+}
+
+func main() {
+	l := Line{}
+	p := &l.From
+	p.X = 1
+}
+`,
+		ExpectedError: "address-of operator is not supported here",
+	},
+	{
+		Name: "slice_of_array_field",
+		Code: `package main
+
+type Tile struct {
+	Pixels [4]int
+}
+
+func main() {
+	var t Tile
+	s := t.Pixels[1:]
+	s[0] = 1
+}
+`,
+		ExpectedError: "slicing this array is not supported",
+	},
+	{
+		Name: "pointer_to_interface",
+		Code: `package main
+
+import "fmt"
+
+type Point struct {
+	X int
+}
+
+func main() {
+	p := &Point{X: 1}
+	fmt.Println(p)
+}
+`,
+		ExpectedError: "pointer converted to an interface",
+	},
+	{
+		Name: "pointer_receiver_as_value",
+		Code: `package main
+
+type Counter struct {
+	Count int
+}
+
+func (c *Counter) Self() *Counter {
+	return c
+}
+
+func main() {
+	c := &Counter{}
+	_ = c.Self()
+}
+`,
+		ExpectedError: "pointer receiver used as a value",
+	},
+	{
+		Name: "rust_runtime_type_name",
+		Code: `package main
+
+type Map struct {
+	Tiles []int
+}
+
+func main() {
+	m := Map{}
+	_ = m
+}
+`,
+		ExpectedError: "type name 'Map' is reserved",
+	},
+}
+
+// SemaValidTestCase represents code that SHOULD compile successfully
+type SemaValidTestCase struct {
+	Name string
+	Code string
+	Args []string // Extra compiler flags
+}
+
+var semaValidTestCases = []SemaValidTestCase{
+	{
+		Name: "string_reassign_then_reuse",
+		Code: `package main
+
+func main() {
+	x := "hello"
+	x = x + " world"
+	y := x + "!"
+	_ = y
+}
+`,
+	},
+	{
+		Name: "string_plusequal_then_reuse",
+		Code: `package main
+
+func main() {
+	x := "hello"
+	x += " world"
+	y := x + "!"
+	_ = y
+}
+`,
+	},
+	{
+		Name: "struct_field_init_correct_order",
+		Code: `package main
+
+type Person struct {
+	Name string
+	Age  int
+	City string
+}
+
+func main() {
+	p := Person{
+		Name: "Alice",
+		Age:  30,
+		City: "NYC",
+	}
+	_ = p
+}
+`,
+	},
+	// Valid Rust ownership patterns
+	{
+		Name: "same_var_int_multiple_times_ok",
+		Code: `package main
+
+func add(a int, b int) int { return a + b }
+
+func main() {
+	x := 5
+	result := add(x, x)
+	_ = result
+}
+`,
+	},
+	{
+		Name: "slice_different_slices_assignment_ok",
+		Code: `package main
+
+func main() {
+	slice1 := []int{1, 2, 3}
+	slice2 := []int{4, 5, 6}
+	slice1[0] = slice2[0]
+	_ = slice1
+}
+`,
+	},
+	{
+		Name: "slice_temp_variable_ok",
+		Code: `package main
+
+func main() {
+	slice := []int{1, 2, 3, 4, 5}
+	tmp := slice[1]
+	slice[0] = tmp
+	_ = slice
+}
+`,
+	},
+	{
+		Name: "single_closure_capture_ok",
+		Code: `package main
+
+func main() {
+	x := "hello"
+	fn := func() string { return x }
+	_ = fn
+}
+`,
+	},
+	{
+		Name: "variable_reassignment_ok",
+		Code: `package main
+
+func main() {
+	col := 0
+	for {
+		if col >= 10 {
+			break
+		}
+		col = col + 1
+	}
+	_ = col
+}
+`,
+	},
+	{
+		Name: "different_var_names_in_nested_scope_ok",
+		Code: `package main
+
+func main() {
+	col := 0
+	for {
+		if col >= 10 {
+			break
+		}
+		innerCol := 1
+		_ = innerCol
+		col = col + 1
+	}
+	_ = col
+}
+`,
+	},
+	{
+		Name: "map_operations_ok",
+		Code: `package main
+
+func main() {
+	m := map[string]int{"a": 1}
+	m["b"] = 2
+	v, ok := m["a"]
+	delete(m, "a")
+	for k, n := range m {
+		_ = k
+		_ = n
+	}
+	_ = v
+	_ = ok
+}
+`,
+	},
+	{
+		Name: "methods_ok",
+		Code: `package main
+
+type Counter struct {
+	n int
+}
+
+func (c *Counter) Inc() {
+	c.n++
+}
+
+func (c Counter) Value() int {
+	return c.n
+}
+
+func main() {
+	c := Counter{n: 0}
+	c.Inc()
+	_ = c.Value()
+}
+`,
+	},
+	{
+		Name: "interfaces_ok",
+		Code: `package main
+
+type Shape interface {
+	Area() int
+}
+
+type Rect struct {
+	w int
+	h int
+}
+
+func (r Rect) Area() int {
+	return r.w * r.h
+}
+
+func main() {
+	var s Shape
+	s = Rect{w: 2, h: 3}
+	shapes := []Shape{s}
+	_ = shapes[0].Area()
+	r, ok := s.(Rect)
+	_ = r
+	_ = ok
+}
+`,
+	},
+	{
+		Name: "iota_constants",
+		Code: `package main
+
+type Color int
+
+const (
+	Red Color = iota
+	Green
+	_
+	Blue
+)
+
+const (
+	FlagA int = 1 << iota
+	FlagB
+)
+
+func main() {
+	_ = Blue
+	_ = FlagB
+}
+`,
+	},
+	{
+		Name: "package_vars_ok",
+		Code: `package main
+
+type Point struct {
+	X int
+	Y int
+}
+
+var total int = base * 2
+
+var base int = 21
+
+var origin = Point{X: 1, Y: 2}
+
+var names []string
+
+func main() {
+	names = append(names, "a")
+	_ = total + origin.X
+}
+`,
+	},
+	{
+		Name: "type_switch_ok",
+		Code: `package main
+
+type Celsius float64
+
+type Meters struct{ V int }
+
+type Feet struct{ V int }
+
+func describe(v interface{}) string {
+	switch x := v.(type) {
+	case int, bool:
+		return "number or bool"
+	case string:
+		return x
+	case Celsius:
+		return "temperature"
+	case Meters, Feet:
+		return "length"
+	default:
+		return "unknown"
+	}
+}
+
+func main() {
+	for i := 0; i < 3; i++ {
+		switch describe(i) {
+		case "unknown":
+			break
+		}
+	}
+}
+`,
+	},
+	{
+		Name: "defer_ok",
+		Code: `package main
+
+func cleanup(name string) {
+	println(name)
+}
+
+func work(n int) int {
+	defer cleanup("work")
+	defer func() {
+		println("closure")
+	}()
+	if n > 0 {
+		return n
+	}
+	return 0
+}
+
+func main() {
+	_ = work(1)
+}
+`,
+	},
+	{
+		Name: "panic_recover_ok",
+		Code: `package main
+
+func pop(items []int) int {
+	if len(items) == 0 {
+		panic("pop from empty stack")
+	}
+	return items[len(items)-1]
+}
+
+func safePop(items []int) int {
+	defer func() {
+		msg, ok := recover().(string)
+		if ok {
+			println(msg)
+		}
+	}()
+	return pop(items)
+}
+
+func main() {
+	_ = safePop([]int{})
+}
+`,
+	},
+	{
+		Name: "variadic_ok",
+		Code: `package main
+
+func sum(nums ...int) int {
+	total := 0
+	for _, n := range nums {
+		total += n
+	}
+	return total
+}
+
+func main() {
+	xs := []int{1, 2}
+	xs = append(xs, xs...)
+	_ = sum()
+	_ = sum(1, 2, 3)
+	_ = sum(xs...)
+}
+`,
+	},
+	{
+		Name: "named_results_ok",
+		Code: `package main
+
+func divmod(a int, b int) (q, r int) {
+	defer func() {
+		_, failed := recover().(string)
+		if failed {
+			q = -1
+		}
+	}()
+	q = a / b
+	r = a % b
+	return
+}
+
+func main() {
+	q, r := divmod(7, 2)
+	_ = q
+	_ = r
+}
+`,
+	},
+	{
+		Name: "struct_embedding_ok",
+		Code: `package main
+
+type Rect struct {
+	X, Y, Width, Height int
+}
+
+func (r *Rect) Move(dx int, dy int) {
+	r.X += dx
+	r.Y += dy
+}
+
+type Window struct {
+	Rect
+	Title string
+}
+
+func main() {
+	w := Window{Rect: Rect{Width: 4, Height: 3}, Title: "main"}
+	w.Move(1, 2)
+	w.X += w.Width
+	_ = w.Rect.Y
+}
+`,
+	},
+	{
+		Name: "generics_ok",
+		Code: `package main
+
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+type Stack[T any] struct {
+	items []T
+}
+
+func (s *Stack[T]) Push(v T) {
+	s.items = append(s.items, v)
+}
+
+func Sum[T Number](values []T) T {
+	var total T
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func Index[K comparable](keys []K, key K) int {
+	for i := 0; i < len(keys); i++ {
+		if keys[i] == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func main() {
+	s := Stack[int]{}
+	s.Push(1)
+	_ = Sum(s.items)
+	_ = Index([]string{"a"}, "a")
+}
+`,
+	},
+	{
+		Name: "labeled_loops_ok",
+		Code: `package main
+
+func main() {
+	count := 0
+	values := []int{1, 2, 3}
+outer:
+	for i := 0; i < 3; i++ {
+		for _, v := range values {
+			if v == i {
+				continue outer
+			}
+			if v > 2 {
+				break outer
+			}
+			count++
+		}
+	}
+sw:
+	switch count {
+	case 0:
+		break sw
+	default:
+		count--
+	}
+	_ = count
+}
+`,
+	},
+	{
+		Name: "arrays_ok",
+		Code: `package main
+
+type Grid struct {
+	Cells [4]int
+}
+
+func sum(a [4]int) int {
+	total := 0
+	for _, v := range a {
+		total += v
+	}
+	return total
+}
+
+func main() {
+	var g Grid
+	g.Cells[1] = 2
+	b := [...]int{1, 2, 3, 4}
+	s := b[:]
+	_ = sum(g.Cells) + len(b) + len(s)
+}
+`,
+	},
+	{
+		Name: "pointers_ok",
+		Code: `package main
+
+type Node struct {
+	Val  int
+	Next *Node
+}
+
+type List struct {
+	Head *Node
+}
+
+func (l *List) Push(v int) {
+	l.Head = &Node{Val: v, Next: l.Head}
+}
+
+func (l *List) Clear() {
+	*l = List{}
+}
+
+func main() {
+	var l List
+	l.Push(1)
+	n := new(Node)
+	n.Next = l.Head
+	local := Node{Val: 2}
+	p := &local
+	p.Val++
+	if n.Next != nil && p != n {
+		_ = *p
+	}
+	copied := local
+	q := &copied
+	var zero Node
+	z := &zero
+	if q != z {
+		l.Clear()
+	}
+}
+`,
+	},
+	{
+		Name: "nil_ok",
+		Code: `package main
+
+type Button struct {
+	OnClick func(int) int
+}
+
+func find(names []string, name string) any {
+	for _, n := range names {
+		if n == name {
+			return n
+		}
+	}
+	return nil
+}
+
+func main() {
+	var s []int
+	if s == nil {
+		s = append(s, 1)
+	}
+	s = nil
+	var b Button
+	if b.OnClick == nil {
+		b.OnClick = func(x int) int { return x * 2 }
+	}
+	if nil != b.OnClick {
+		_ = b.OnClick(1)
+	}
+	b.OnClick = nil
+	if find([]string{"a"}, "b") == nil {
+		_ = len(s)
+	}
+	var m map[string]int
+	if m == nil {
+		m = map[string]int{}
+	}
+	m = nil
+	_ = len(m)
+}
+`,
+	},
+	{
+		Name: "range_forms_ok",
+		Code: `package main
+
+func main() {
+	total := 0
+	for i := range 10 {
+		total += i
+	}
+	for range 3 {
+		total++
+	}
+	for i, r := range "héllo" {
+		total += i + int(r)
+	}
+	for _, x := range []int{1, 2, 3} {
+		total += x
+	}
+	for k := range map[string]int{"a": 1} {
+		total += len(k)
+	}
+	_ = total
+}
+`,
+	},
+	{
+		Name: "goroutines_ok",
+		Code: `package main
+
+func produce(ch chan int, n int) {
+	for i := 0; i < n; i++ {
+		ch <- i
+	}
+	close(ch)
+}
+
+func main() {
+	ch := make(chan int)
+	done := make(chan bool, 1)
+	go produce(ch, 3)
+	total := 0
+	for v := range ch {
+		total += v
+	}
+	select {
+	case done <- true:
+	default:
+	}
+	v, ok := <-ch
+	_, _, _ = total, v, ok
+}
+`,
+	},
+	{
+		Name: "int_size_32_ok",
+		Code: `package main
+
+func main() {
+	top := 1<<31 - 1
+	bottom := -1 << 31
+	var u uint
+	u = 4294967295
+	wide := int64(1) << 40
+	mask := uint64(1 << 63)
+	neg := int32(-8) >> 40
+	_, _, _, _, _, _ = top, bottom, u, wide, mask, neg
+}
+`,
+		Args: []string{"-int-size=32"},
+	},
+	{
+		Name: "multiple_closures_capture_same_var",
+		Code: `package main
+
+func main() {
+	x := "hello"
+	fn1 := func() string { return x }
+	fn2 := func() string { return x }
+	_ = fn1
+	_ = fn2
+}
+`,
+	},
+	{
+		Name: "closures_mutate_captured_var",
+		Code: `package main
+
+func main() {
+	count := 0
+	names := []string{}
+	inc := func() { count++ }
+	add := func(n string) { names = append(names, n) }
+	get := func() int { return count }
+	inc()
+	add("a")
+	names = append(names, "b")
+	_ = get() + len(names)
+}
+`,
+	},
+	{
+		Name: "variable_shadowing_in_nested_block",
+		Code: `package main
+
+func main() {
+	col := 0
+	for {
+		if col >= 10 {
+			break
+		}
+		col := 1
+		_ = col
+	}
+	_ = col
+}
+`,
+	},
+	{
+		Name: "variable_shadowing_in_if_block",
+		Code: `package main
+
+func main() {
+	x := 5
+	if true {
+		x := 10
+		_ = x
+	}
+	_ = x
+}
+`,
+	},
+	{
+		Name: "range_body_shadows_target",
+		Code: `package main
+
+func main() {
+	items := []int{1, 2}
+	for _, v := range items {
+		items := []int{v}
+		items = append(items, v)
+		_ = items
 	}
-	_ = col
 }
 `,
 	},
@@ -365,7 +1399,8 @@ func runSemaValidTest(t *testing.T, wd string, tc SemaValidTestCase) {
 	}
 
 	// Run the compiler - it should succeed
-	cmd := exec.Command("go", "run", ".", "--source="+testDir, "--output="+filepath.Join(testDir, "out"))
+	args := append([]string{"run", ".", "--source=" + testDir, "--output=" + filepath.Join(testDir, "out")}, tc.Args...)
+	cmd := exec.Command("go", args...)
 	cmd.Dir = wd
 	output, err := cmd.CombinedOutput()
 
@@ -412,7 +1447,8 @@ func runSemaTest(t *testing.T, wd string, tc SemaTestCase) {
 	}
 
 	// Run the compiler - it should fail
-	cmd := exec.Command("go", "run", ".", "--source="+testDir, "--output="+filepath.Join(testDir, "out"))
+	args := append([]string{"run", ".", "--source=" + testDir, "--output=" + filepath.Join(testDir, "out")}, tc.Args...)
+	cmd := exec.Command("go", args...)
 	cmd.Dir = wd
 	output, err := cmd.CombinedOutput()
 
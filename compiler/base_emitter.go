@@ -37,6 +37,10 @@ const (
 	PostVisitCallExprArgs VisitMethod = "PostVisitCallExprArgs"
 	PreVisitCallExprArg VisitMethod = "PreVisitCallExprArg"
 	PostVisitCallExprArg VisitMethod = "PostVisitCallExprArg"
+	PreVisitCallExprVariadicArgs VisitMethod = "PreVisitCallExprVariadicArgs"
+	PostVisitCallExprVariadicArgs VisitMethod = "PostVisitCallExprVariadicArgs"
+	PreVisitEllipsis VisitMethod = "PreVisitEllipsis"
+	PostVisitEllipsis VisitMethod = "PostVisitEllipsis"
 	PreVisitParenExpr VisitMethod = "PreVisitParenExpr"
 	PostVisitParenExpr VisitMethod = "PostVisitParenExpr"
 	PreVisitCompositeLit VisitMethod = "PreVisitCompositeLit"
@@ -61,6 +65,12 @@ const (
 	PostVisitIndexExprX VisitMethod = "PostVisitIndexExprX"
 	PreVisitIndexExprIndex VisitMethod = "PreVisitIndexExprIndex"
 	PostVisitIndexExprIndex VisitMethod = "PostVisitIndexExprIndex"
+	PreVisitIndexListExpr VisitMethod = "PreVisitIndexListExpr"
+	PostVisitIndexListExpr VisitMethod = "PostVisitIndexListExpr"
+	PreVisitIndexListExprIndices VisitMethod = "PreVisitIndexListExprIndices"
+	PostVisitIndexListExprIndices VisitMethod = "PostVisitIndexListExprIndices"
+	PreVisitIndexListExprIndex VisitMethod = "PreVisitIndexListExprIndex"
+	PostVisitIndexListExprIndex VisitMethod = "PostVisitIndexListExprIndex"
 	PreVisitUnaryExpr VisitMethod = "PreVisitUnaryExpr"
 	PostVisitUnaryExpr VisitMethod = "PostVisitUnaryExpr"
 	PreVisitSliceExpr VisitMethod = "PreVisitSliceExpr"
@@ -75,6 +85,8 @@ const (
 	PostVisitSliceExprLow VisitMethod = "PostVisitSliceExprLow"
 	PreVisitSliceExprHigh VisitMethod = "PreVisitSliceExprHigh"
 	PostVisitSliceExprHigh VisitMethod = "PostVisitSliceExprHigh"
+	PreVisitSliceExprMax VisitMethod = "PreVisitSliceExprMax"
+	PostVisitSliceExprMax VisitMethod = "PostVisitSliceExprMax"
 	PreVisitFuncType VisitMethod = "PreVisitFuncType"
 	PostVisitFuncType VisitMethod = "PostVisitFuncType"
 	PreVisitFuncTypeResults VisitMethod = "PreVisitFuncTypeResults"
@@ -115,6 +127,14 @@ const (
 	PostVisitStarExprX VisitMethod = "PostVisitStarExprX"
 	PreVisitInterfaceType VisitMethod = "PreVisitInterfaceType"
 	PostVisitInterfaceType VisitMethod = "PostVisitInterfaceType"
+	PreVisitMapType VisitMethod = "PreVisitMapType"
+	PostVisitMapType VisitMethod = "PostVisitMapType"
+	PreVisitChanType VisitMethod = "PreVisitChanType"
+	PostVisitChanType VisitMethod = "PostVisitChanType"
+	PreVisitMapKeyType VisitMethod = "PreVisitMapKeyType"
+	PostVisitMapKeyType VisitMethod = "PostVisitMapKeyType"
+	PreVisitMapValueType VisitMethod = "PreVisitMapValueType"
+	PostVisitMapValueType VisitMethod = "PostVisitMapValueType"
 	PreVisitExprStmt VisitMethod = "PreVisitExprStmt"
 	PostVisitExprStmt VisitMethod = "PostVisitExprStmt"
 	PreVisitExprStmtX VisitMethod = "PreVisitExprStmtX"
@@ -127,8 +147,35 @@ const (
 	PostVisitDeclStmtValueSpecNames VisitMethod = "PostVisitDeclStmtValueSpecNames"
 	PreVisitBranchStmt VisitMethod = "PreVisitBranchStmt"
 	PostVisitBranchStmt VisitMethod = "PostVisitBranchStmt"
+	PreVisitLabeledStmt VisitMethod = "PreVisitLabeledStmt"
+	PostVisitLabeledStmt VisitMethod = "PostVisitLabeledStmt"
 	PreVisitIncDecStmt VisitMethod = "PreVisitIncDecStmt"
 	PostVisitIncDecStmt VisitMethod = "PostVisitIncDecStmt"
+	PreVisitDeferStmt VisitMethod = "PreVisitDeferStmt"
+	PostVisitDeferStmt VisitMethod = "PostVisitDeferStmt"
+	PreVisitDeferStmtArg VisitMethod = "PreVisitDeferStmtArg"
+	PostVisitDeferStmtArg VisitMethod = "PostVisitDeferStmtArg"
+	PreVisitDeferStmtCall VisitMethod = "PreVisitDeferStmtCall"
+	PostVisitDeferStmtCall VisitMethod = "PostVisitDeferStmtCall"
+	PreVisitGoStmt VisitMethod = "PreVisitGoStmt"
+	PostVisitGoStmt VisitMethod = "PostVisitGoStmt"
+	PreVisitGoStmtArg VisitMethod = "PreVisitGoStmtArg"
+	PostVisitGoStmtArg VisitMethod = "PostVisitGoStmtArg"
+	PreVisitGoStmtCall VisitMethod = "PreVisitGoStmtCall"
+	PostVisitGoStmtCall VisitMethod = "PostVisitGoStmtCall"
+	PreVisitSendStmt VisitMethod = "PreVisitSendStmt"
+	PostVisitSendStmt VisitMethod = "PostVisitSendStmt"
+	PreVisitSendStmtValue VisitMethod = "PreVisitSendStmtValue"
+	PostVisitSendStmtValue VisitMethod = "PostVisitSendStmtValue"
+	PreVisitSelectStmt VisitMethod = "PreVisitSelectStmt"
+	PostVisitSelectStmt VisitMethod = "PostVisitSelectStmt"
+	PreVisitSelectStmtCase VisitMethod = "PreVisitSelectStmtCase"
+	PostVisitSelectStmtCase VisitMethod = "PostVisitSelectStmtCase"
+	PreVisitSelectStmtCaseValue VisitMethod = "PreVisitSelectStmtCaseValue"
+	PostVisitSelectStmtCaseValue VisitMethod = "PostVisitSelectStmtCaseValue"
+	PreVisitSelectStmtClauses VisitMethod = "PreVisitSelectStmtClauses"
+	PreVisitSelectStmtClause VisitMethod = "PreVisitSelectStmtClause"
+	PostVisitSelectStmtClause VisitMethod = "PostVisitSelectStmtClause"
 	PreVisitAssignStmt VisitMethod = "PreVisitAssignStmt"
 	PostVisitAssignStmt VisitMethod = "PostVisitAssignStmt"
 	PostVisitForStmt VisitMethod = "PostVisitForStmt"
@@ -177,7 +224,18 @@ const (
 	PostVisitCaseClauseList VisitMethod = "PostVisitCaseClauseList"
 	PreVisitCaseClauseListExpr VisitMethod = "PreVisitCaseClauseListExpr"
 	PostVisitCaseClauseListExpr VisitMethod = "PostVisitCaseClauseListExpr"
+	PreVisitTypeSwitchStmt VisitMethod = "PreVisitTypeSwitchStmt"
+	PostVisitTypeSwitchStmt VisitMethod = "PostVisitTypeSwitchStmt"
+	PreVisitTypeSwitchStmtX VisitMethod = "PreVisitTypeSwitchStmtX"
+	PostVisitTypeSwitchStmtX VisitMethod = "PostVisitTypeSwitchStmtX"
+	PreVisitTypeSwitchCaseClause VisitMethod = "PreVisitTypeSwitchCaseClause"
+	PostVisitTypeSwitchCaseClause VisitMethod = "PostVisitTypeSwitchCaseClause"
+	PreVisitTypeSwitchCaseClauseType VisitMethod = "PreVisitTypeSwitchCaseClauseType"
+	PostVisitTypeSwitchCaseClauseType VisitMethod = "PostVisitTypeSwitchCaseClauseType"
+	PreVisitTypeSwitchCaseClauseBody VisitMethod = "PreVisitTypeSwitchCaseClauseBody"
+	PostVisitTypeSwitchCaseClauseBody VisitMethod = "PostVisitTypeSwitchCaseClauseBody"
 	PreVisitBlockStmt VisitMethod = "PreVisitBlockStmt"
+	PreVisitFuncBodyStmts VisitMethod = "PreVisitFuncBodyStmts"
 	PostVisitBlockStmt VisitMethod = "PostVisitBlockStmt"
 	PreVisitBlockStmtList VisitMethod = "PreVisitBlockStmtList"
 	PostVisitBlockStmtList VisitMethod = "PostVisitBlockStmtList"
@@ -213,8 +271,22 @@ const (
 	PostVisitGenStructFieldType VisitMethod = "PostVisitGenStructFieldType"
 	PreVisitGenStructFieldName VisitMethod = "PreVisitGenStructFieldName"
 	PostVisitGenStructFieldName VisitMethod = "PostVisitGenStructFieldName"
+	PreVisitGenStructMethod VisitMethod = "PreVisitGenStructMethod"
+	PostVisitGenStructMethod VisitMethod = "PostVisitGenStructMethod"
+	PreVisitGenInterfaceInfo VisitMethod = "PreVisitGenInterfaceInfo"
+	PostVisitGenInterfaceInfo VisitMethod = "PostVisitGenInterfaceInfo"
+	PreVisitGenInterfaceMethod VisitMethod = "PreVisitGenInterfaceMethod"
+	PostVisitGenInterfaceMethod VisitMethod = "PostVisitGenInterfaceMethod"
 	PreVisitGenDeclConstName VisitMethod = "PreVisitGenDeclConstName"
 	PostVisitGenDeclConstName VisitMethod = "PostVisitGenDeclConstName"
+	PreVisitGenDeclVars VisitMethod = "PreVisitGenDeclVars"
+	PostVisitGenDeclVars VisitMethod = "PostVisitGenDeclVars"
+	PreVisitGenDeclVar VisitMethod = "PreVisitGenDeclVar"
+	PostVisitGenDeclVar VisitMethod = "PostVisitGenDeclVar"
+	PreVisitGenDeclVarType VisitMethod = "PreVisitGenDeclVarType"
+	PostVisitGenDeclVarType VisitMethod = "PostVisitGenDeclVarType"
+	PreVisitGenDeclVarValue VisitMethod = "PreVisitGenDeclVarValue"
+	PostVisitGenDeclVarValue VisitMethod = "PostVisitGenDeclVarValue"
 	PreVisitTypeAliasName VisitMethod = "PreVisitTypeAliasName"
 	PostVisitTypeAliasName VisitMethod = "PostVisitTypeAliasName"
 	PreVisitTypeAliasType VisitMethod = "PreVisitTypeAliasType"
@@ -252,6 +324,10 @@ func (v *BaseEmitter) PreVisitCallExprArgs(node []ast.Expr, indent int) {}
 func (v *BaseEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {}
 func (v *BaseEmitter) PreVisitCallExprArg(node ast.Expr, index int, indent int) {}
 func (v *BaseEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int) {}
+func (v *BaseEmitter) PreVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {}
+func (v *BaseEmitter) PostVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {}
+func (v *BaseEmitter) PreVisitEllipsis(node *ast.Ellipsis, indent int) {}
+func (v *BaseEmitter) PostVisitEllipsis(node *ast.Ellipsis, indent int) {}
 func (v *BaseEmitter) PreVisitParenExpr(node *ast.ParenExpr, indent int) {}
 func (v *BaseEmitter) PostVisitParenExpr(node *ast.ParenExpr, indent int) {}
 func (v *BaseEmitter) PreVisitCompositeLit(node *ast.CompositeLit, indent int) {}
@@ -276,6 +352,12 @@ func (v *BaseEmitter) PreVisitIndexExprX(node *ast.IndexExpr, indent int) {}
 func (v *BaseEmitter) PostVisitIndexExprX(node *ast.IndexExpr, indent int) {}
 func (v *BaseEmitter) PreVisitIndexExprIndex(node *ast.IndexExpr, indent int) {}
 func (v *BaseEmitter) PostVisitIndexExprIndex(node *ast.IndexExpr, indent int) {}
+func (v *BaseEmitter) PreVisitIndexListExpr(node *ast.IndexListExpr, indent int) {}
+func (v *BaseEmitter) PostVisitIndexListExpr(node *ast.IndexListExpr, indent int) {}
+func (v *BaseEmitter) PreVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {}
+func (v *BaseEmitter) PostVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {}
+func (v *BaseEmitter) PreVisitIndexListExprIndex(node ast.Expr, index int, indent int) {}
+func (v *BaseEmitter) PostVisitIndexListExprIndex(node ast.Expr, index int, indent int) {}
 func (v *BaseEmitter) PreVisitUnaryExpr(node *ast.UnaryExpr, indent int) {}
 func (v *BaseEmitter) PostVisitUnaryExpr(node *ast.UnaryExpr, indent int) {}
 func (v *BaseEmitter) PreVisitSliceExpr(node *ast.SliceExpr, indent int) {}
@@ -290,6 +372,8 @@ func (v *BaseEmitter) PreVisitSliceExprLow(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PostVisitSliceExprLow(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PreVisitSliceExprHigh(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PostVisitSliceExprHigh(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PreVisitSliceExprMax(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PostVisitSliceExprMax(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PreVisitFuncType(node *ast.FuncType, indent int) {}
 func (v *BaseEmitter) PostVisitFuncType(node *ast.FuncType, indent int) {}
 func (v *BaseEmitter) PreVisitFuncTypeResults(node *ast.FieldList, indent int) {}
@@ -330,6 +414,14 @@ func (v *BaseEmitter) PreVisitStarExprX(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PostVisitStarExprX(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PreVisitInterfaceType(node *ast.InterfaceType, indent int) {}
 func (v *BaseEmitter) PostVisitInterfaceType(node *ast.InterfaceType, indent int) {}
+func (v *BaseEmitter) PreVisitMapType(node *ast.MapType, indent int) {}
+func (v *BaseEmitter) PostVisitMapType(node *ast.MapType, indent int) {}
+func (v *BaseEmitter) PreVisitChanType(node *ast.ChanType, indent int) {}
+func (v *BaseEmitter) PostVisitChanType(node *ast.ChanType, indent int) {}
+func (v *BaseEmitter) PreVisitMapKeyType(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PostVisitMapKeyType(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PreVisitMapValueType(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PostVisitMapValueType(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PreVisitExprStmt(node *ast.ExprStmt, indent int) {}
 func (v *BaseEmitter) PostVisitExprStmt(node *ast.ExprStmt, indent int) {}
 func (v *BaseEmitter) PreVisitExprStmtX(node ast.Expr, indent int) {}
@@ -342,8 +434,35 @@ func (v *BaseEmitter) PreVisitDeclStmtValueSpecNames(node *ast.Ident, index int,
 func (v *BaseEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index int, indent int) {}
 func (v *BaseEmitter) PreVisitBranchStmt(node *ast.BranchStmt, indent int) {}
 func (v *BaseEmitter) PostVisitBranchStmt(node *ast.BranchStmt, indent int) {}
+func (v *BaseEmitter) PreVisitLabeledStmt(node *ast.LabeledStmt, indent int) {}
+func (v *BaseEmitter) PostVisitLabeledStmt(node *ast.LabeledStmt, indent int) {}
 func (v *BaseEmitter) PreVisitIncDecStmt(node *ast.IncDecStmt, indent int) {}
 func (v *BaseEmitter) PostVisitIncDecStmt(node *ast.IncDecStmt, indent int) {}
+func (v *BaseEmitter) PreVisitDeferStmt(node *ast.DeferStmt, indent int) {}
+func (v *BaseEmitter) PostVisitDeferStmt(node *ast.DeferStmt, indent int) {}
+func (v *BaseEmitter) PreVisitDeferStmtArg(node DeferArg, indent int) {}
+func (v *BaseEmitter) PostVisitDeferStmtArg(node DeferArg, indent int) {}
+func (v *BaseEmitter) PreVisitDeferStmtCall(node *ast.CallExpr, indent int) {}
+func (v *BaseEmitter) PostVisitDeferStmtCall(node *ast.CallExpr, indent int) {}
+func (v *BaseEmitter) PreVisitGoStmt(node *ast.GoStmt, indent int) {}
+func (v *BaseEmitter) PostVisitGoStmt(node *ast.GoStmt, indent int) {}
+func (v *BaseEmitter) PreVisitGoStmtArg(node DeferArg, indent int) {}
+func (v *BaseEmitter) PostVisitGoStmtArg(node DeferArg, indent int) {}
+func (v *BaseEmitter) PreVisitGoStmtCall(node *ast.CallExpr, indent int) {}
+func (v *BaseEmitter) PostVisitGoStmtCall(node *ast.CallExpr, indent int) {}
+func (v *BaseEmitter) PreVisitSendStmt(node *ast.SendStmt, indent int) {}
+func (v *BaseEmitter) PostVisitSendStmt(node *ast.SendStmt, indent int) {}
+func (v *BaseEmitter) PreVisitSendStmtValue(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PostVisitSendStmtValue(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PreVisitSelectStmt(node *ast.SelectStmt, indent int) {}
+func (v *BaseEmitter) PostVisitSelectStmt(node *ast.SelectStmt, indent int) {}
+func (v *BaseEmitter) PreVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {}
+func (v *BaseEmitter) PostVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {}
+func (v *BaseEmitter) PreVisitSelectStmtCaseValue(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PostVisitSelectStmtCaseValue(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PreVisitSelectStmtClauses(node *ast.SelectStmt, indent int) {}
+func (v *BaseEmitter) PreVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {}
+func (v *BaseEmitter) PostVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {}
 func (v *BaseEmitter) PreVisitAssignStmt(node *ast.AssignStmt, indent int) {}
 func (v *BaseEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {}
 func (v *BaseEmitter) PostVisitForStmt(node *ast.ForStmt, indent int) {}
@@ -392,7 +511,18 @@ func (v *BaseEmitter) PreVisitCaseClauseList(node []ast.Expr, indent int) {}
 func (v *BaseEmitter) PostVisitCaseClauseList(node []ast.Expr, indent int) {}
 func (v *BaseEmitter) PreVisitCaseClauseListExpr(node ast.Expr, index int, indent int) {}
 func (v *BaseEmitter) PostVisitCaseClauseListExpr(node ast.Expr, index int, indent int) {}
+func (v *BaseEmitter) PreVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {}
+func (v *BaseEmitter) PostVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {}
+func (v *BaseEmitter) PreVisitTypeSwitchStmtX(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PostVisitTypeSwitchStmtX(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PreVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {}
+func (v *BaseEmitter) PostVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {}
+func (v *BaseEmitter) PreVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {}
+func (v *BaseEmitter) PostVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {}
+func (v *BaseEmitter) PreVisitTypeSwitchCaseClauseBody(node *ast.CaseClause, index int, indent int) {}
+func (v *BaseEmitter) PostVisitTypeSwitchCaseClauseBody(node *ast.CaseClause, index int, indent int) {}
 func (v *BaseEmitter) PreVisitBlockStmt(node *ast.BlockStmt, indent int) {}
+func (v *BaseEmitter) PreVisitFuncBodyStmts(node *ast.BlockStmt, indent int) {}
 func (v *BaseEmitter) PostVisitBlockStmt(node *ast.BlockStmt, indent int) {}
 func (v *BaseEmitter) PreVisitBlockStmtList(node ast.Stmt, index int, indent int) {}
 func (v *BaseEmitter) PostVisitBlockStmtList(node ast.Stmt, index int, indent int) {}
@@ -428,8 +558,22 @@ func (v *BaseEmitter) PreVisitGenStructFieldType(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PostVisitGenStructFieldType(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PreVisitGenStructFieldName(node *ast.Ident, indent int) {}
 func (v *BaseEmitter) PostVisitGenStructFieldName(node *ast.Ident, indent int) {}
+func (v *BaseEmitter) PreVisitGenStructMethod(node *ast.FuncDecl, indent int) {}
+func (v *BaseEmitter) PostVisitGenStructMethod(node *ast.FuncDecl, indent int) {}
+func (v *BaseEmitter) PreVisitGenInterfaceInfo(node GenTypeInfo, indent int) {}
+func (v *BaseEmitter) PostVisitGenInterfaceInfo(node GenTypeInfo, indent int) {}
+func (v *BaseEmitter) PreVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {}
+func (v *BaseEmitter) PostVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {}
 func (v *BaseEmitter) PreVisitGenDeclConstName(node *ast.Ident, indent int) {}
 func (v *BaseEmitter) PostVisitGenDeclConstName(node *ast.Ident, indent int) {}
+func (v *BaseEmitter) PreVisitGenDeclVars(node []PackageVar, indent int) {}
+func (v *BaseEmitter) PostVisitGenDeclVars(node []PackageVar, indent int) {}
+func (v *BaseEmitter) PreVisitGenDeclVar(node PackageVar, indent int) {}
+func (v *BaseEmitter) PostVisitGenDeclVar(node PackageVar, indent int) {}
+func (v *BaseEmitter) PreVisitGenDeclVarType(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PostVisitGenDeclVarType(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PreVisitGenDeclVarValue(node ast.Expr, indent int) {}
+func (v *BaseEmitter) PostVisitGenDeclVarValue(node ast.Expr, indent int) {}
 func (v *BaseEmitter) PreVisitTypeAliasName(node *ast.Ident, indent int) {}
 func (v *BaseEmitter) PostVisitTypeAliasName(node *ast.Ident, indent int) {}
 func (v *BaseEmitter) PreVisitTypeAliasType(node ast.Expr, indent int) {}
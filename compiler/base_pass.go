@@ -31,12 +31,27 @@ var namespaces = map[string]struct{}{}
 type GenTypeInfo struct {
 	Name       string
 	Struct     *ast.StructType
+	Interface  *ast.InterfaceType // Set for interface types with methods
 	Other      ast.Node
 	IsExternal bool // Whether this struct is external or local
 	Pkg        string
 	BaseType   string
 }
 
+// PackageVar is a package-level variable declaration
+type PackageVar struct {
+	Name  *ast.Ident
+	Type  ast.Expr // Declared type, or the type of a composite literal value
+	Value ast.Expr // nil for zero-initialized variables
+}
+
+// DeferArg is an argument of a deferred call. It is evaluated into the
+// temporary Name at the defer statement, the deferred call reads the temporary.
+type DeferArg struct {
+	Name  string
+	Value ast.Expr
+}
+
 type BasePass struct {
 	PassName   string
 	outputFile string
@@ -46,10 +61,18 @@ type BasePass struct {
 }
 
 type BasePassVisitor struct {
-	pkg     *packages.Package
-	pass    *BasePass
-	nodes   []ast.Node
-	emitter Emitter
+	pkg        *packages.Package
+	pass       *BasePass
+	nodes      []ast.Node
+	emitter    Emitter
+	deferCount int
+	funcScopes []funcScope // Enclosing function bodies, innermost last
+}
+
+// funcScope is a function body being traversed with its named results
+type funcScope struct {
+	body    *ast.BlockStmt
+	results []*ast.Field
 }
 
 func (v *BasePass) Name() string {
@@ -69,12 +92,30 @@ func (v *BasePassVisitor) emitAsString(s string, indent int) string {
 func (v *BasePassVisitor) emitArgs(node *ast.CallExpr, indent int) {
 	v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitCallExprArgs)
 	v.emitter.PreVisitCallExprArgs(node.Args, indent)
+	// Arguments of a variadic parameter form their own list, indexed from 0
+	variadic := variadicArgsStart(v.pkg, node)
 	for i, arg := range node.Args {
+		index := i
+		if variadic >= 0 && i >= variadic {
+			if i == variadic {
+				v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitCallExprVariadicArgs)
+				v.emitter.PreVisitCallExprVariadicArgs(node, variadic, indent)
+			}
+			index = i - variadic
+		}
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitCallExprArg)
-		v.emitter.PreVisitCallExprArg(arg, i, indent)
+		v.emitter.PreVisitCallExprArg(arg, index, indent)
 		v.traverseExpression(arg, 0) // Function arguments
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitCallExprArg)
-		v.emitter.PostVisitCallExprArg(arg, i, indent)
+		v.emitter.PostVisitCallExprArg(arg, index, indent)
+	}
+	if variadic >= 0 {
+		if variadic == len(node.Args) {
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitCallExprVariadicArgs)
+			v.emitter.PreVisitCallExprVariadicArgs(node, variadic, indent)
+		}
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitCallExprVariadicArgs)
+		v.emitter.PostVisitCallExprVariadicArgs(node, variadic, indent)
 	}
 	v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitCallExprArgs)
 	v.emitter.PostVisitCallExprArgs(node.Args, indent)
@@ -82,6 +123,14 @@ func (v *BasePassVisitor) emitArgs(node *ast.CallExpr, indent int) {
 
 func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 	var str string
+	// len of an array is a constant, emitted as its value
+	if length := arrayLenLit(v.pkg, expr); length != nil {
+		expr = length
+	}
+	// and so is an integer constant expression that needs more than 32 bits
+	if lit := wideIntLit(v.pkg, expr); lit != nil {
+		expr = lit
+	}
 	switch e := expr.(type) {
 	case *ast.BasicLit:
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitBasicLit)
@@ -156,6 +205,12 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		v.traverseExpression(e.Elt, 0)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitArrayType)
 		v.emitter.PostVisitArrayType(*e, indent)
+	case *ast.Ellipsis:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitEllipsis)
+		v.emitter.PreVisitEllipsis(e, indent)
+		v.traverseExpression(e.Elt, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitEllipsis)
+		v.emitter.PostVisitEllipsis(e, indent)
 	case *ast.SelectorExpr:
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSelectorExpr)
 		v.emitter.PreVisitSelectorExpr(e, indent)
@@ -188,6 +243,23 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		v.emitter.PostVisitIndexExprIndex(e, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitIndexExpr)
 		v.emitter.PostVisitIndexExpr(e, indent)
+	case *ast.IndexListExpr:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitIndexListExpr)
+		v.emitter.PreVisitIndexListExpr(e, indent)
+		v.traverseExpression(e.X, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitIndexListExprIndices)
+		v.emitter.PreVisitIndexListExprIndices(e, indent)
+		for i, index := range e.Indices {
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitIndexListExprIndex)
+			v.emitter.PreVisitIndexListExprIndex(index, i, indent)
+			v.traverseExpression(index, 0)
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitIndexListExprIndex)
+			v.emitter.PostVisitIndexListExprIndex(index, i, indent)
+		}
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitIndexListExprIndices)
+		v.emitter.PostVisitIndexListExprIndices(e, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitIndexListExpr)
+		v.emitter.PostVisitIndexListExpr(e, indent)
 	case *ast.UnaryExpr:
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitUnaryExpr)
 		v.emitter.PreVisitUnaryExpr(e, indent)
@@ -205,7 +277,6 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		// Check and print Low, High, and Max
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSliceExprXBegin)
 		v.emitter.PreVisitSliceExprXBegin(e.X, indent)
-		v.traverseExpression(e.X, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSliceExprXBegin)
 		v.emitter.PostVisitSliceExprXBegin(e, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSliceExprLow)
@@ -217,7 +288,6 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		v.emitter.PostVisitSliceExprLow(e.Low, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSliceExprXEnd)
 		v.emitter.PreVisitSliceExprXEnd(e, indent)
-		v.traverseExpression(e.X, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSliceExprXEnd)
 		v.emitter.PostVisitSliceExprXEnd(e, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSliceExprHigh)
@@ -227,14 +297,17 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		}
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSliceExprHigh)
 		v.emitter.PostVisitSliceExprHigh(e.High, indent)
-		if e.Slice3 && e.Max != nil {
+		if e.Slice3 {
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSliceExprMax)
+			v.emitter.PreVisitSliceExprMax(e.Max, indent)
 			v.traverseExpression(e.Max, indent)
-		} else if e.Slice3 {
-			DebugLogPrintf("Max index: <nil>\n")
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSliceExprMax)
+			v.emitter.PostVisitSliceExprMax(e.Max, indent)
 		}
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSliceExpr)
 		v.emitter.PostVisitSliceExpr(e, indent)
 	case *ast.FuncType:
+		splitResultFields(v.pkg, e)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncType)
 		v.emitter.PreVisitFuncType(e, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncTypeResults)
@@ -279,6 +352,7 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitKeyValueExpr)
 		v.emitter.PostVisitKeyValueExpr(e, indent)
 	case *ast.FuncLit:
+		splitResultFields(v.pkg, e.Type)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncLit)
 		v.emitter.PreVisitFuncLit(e, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncLitTypeParams)
@@ -308,7 +382,7 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		v.emitter.PostVisitFuncLitTypeResults(e.Type.Results, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncLitBody)
 		v.emitter.PreVisitFuncLitBody(e.Body, indent)
-		v.traverseStmt(e.Body, indent+4)
+		v.traverseFuncBody(e.Type, e.Body, indent+4)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitFuncLitBody)
 		v.emitter.PostVisitFuncLitBody(e.Body, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitFuncLit)
@@ -333,7 +407,7 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		v.emitter.PreVisitStarExpr(e, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitStarExprX)
 		v.emitter.PreVisitStarExprX(e.X, indent)
-		v.traverseExpression(e.X, indent)
+		v.traverseExpression(e.X, 0)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitStarExprX)
 		v.emitter.PostVisitStarExprX(e.X, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitStarExpr)
@@ -343,6 +417,27 @@ func (v *BasePassVisitor) traverseExpression(expr ast.Expr, indent int) string {
 		v.emitter.PreVisitInterfaceType(e, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitInterfaceType)
 		v.emitter.PostVisitInterfaceType(e, indent)
+	case *ast.ChanType:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitChanType)
+		v.emitter.PreVisitChanType(e, indent)
+		v.traverseExpression(e.Value, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitChanType)
+		v.emitter.PostVisitChanType(e, indent)
+	case *ast.MapType:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitMapType)
+		v.emitter.PreVisitMapType(e, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitMapKeyType)
+		v.emitter.PreVisitMapKeyType(e.Key, indent)
+		v.traverseExpression(e.Key, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitMapKeyType)
+		v.emitter.PostVisitMapKeyType(e.Key, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitMapValueType)
+		v.emitter.PreVisitMapValueType(e.Value, indent)
+		v.traverseExpression(e.Value, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitMapValueType)
+		v.emitter.PostVisitMapValueType(e.Value, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitMapType)
+		v.emitter.PostVisitMapType(e, indent)
 	default:
 		panic(fmt.Sprintf("unsupported expression type: %T", e))
 	}
@@ -418,6 +513,7 @@ func (v *BasePassVisitor) traverseStmt(stmt ast.Stmt, indent int) {
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitAssignStmt)
 		v.emitter.PostVisitAssignStmt(stmt, indent)
 	case *ast.ReturnStmt:
+		stmt = v.expandNakedReturn(stmt)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitReturnStmt)
 		v.emitter.PreVisitReturnStmt(stmt, indent)
 		for i := 0; i < len(stmt.Results); i++ {
@@ -524,11 +620,51 @@ func (v *BasePassVisitor) traverseStmt(stmt ast.Stmt, indent int) {
 		}
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSwitchStmt)
 		v.emitter.PostVisitSwitchStmt(stmt, indent)
+	case *ast.TypeSwitchStmt:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitTypeSwitchStmt)
+		v.emitter.PreVisitTypeSwitchStmt(stmt, indent)
+		if stmt.Init != nil {
+			v.traverseStmt(stmt.Init, indent+2)
+		}
+		x, _ := typeSwitchSubject(stmt)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitTypeSwitchStmtX)
+		v.emitter.PreVisitTypeSwitchStmtX(x, indent)
+		v.traverseExpression(x, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitTypeSwitchStmtX)
+		v.emitter.PostVisitTypeSwitchStmtX(x, indent)
+		for i, clause := range typeSwitchClauses(stmt) {
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitTypeSwitchCaseClause)
+			v.emitter.PreVisitTypeSwitchCaseClause(clause, i, indent)
+			for j := 0; j < len(clause.List); j++ {
+				v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitTypeSwitchCaseClauseType)
+				v.emitter.PreVisitTypeSwitchCaseClauseType(clause.List[j], j, indent)
+				v.traverseExpression(clause.List[j], 0)
+				v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitTypeSwitchCaseClauseType)
+				v.emitter.PostVisitTypeSwitchCaseClauseType(clause.List[j], j, indent)
+			}
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitTypeSwitchCaseClauseBody)
+			v.emitter.PreVisitTypeSwitchCaseClauseBody(clause, i, indent)
+			for j := 0; j < len(clause.Body); j++ {
+				v.traverseStmt(clause.Body[j], indent+4)
+			}
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitTypeSwitchCaseClauseBody)
+			v.emitter.PostVisitTypeSwitchCaseClauseBody(clause, i, indent)
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitTypeSwitchCaseClause)
+			v.emitter.PostVisitTypeSwitchCaseClause(clause, i, indent)
+		}
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitTypeSwitchStmt)
+		v.emitter.PostVisitTypeSwitchStmt(stmt, indent)
 	case *ast.BranchStmt:
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitBranchStmt)
 		v.emitter.PreVisitBranchStmt(stmt, indent)
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitBranchStmt)
 		v.emitter.PostVisitBranchStmt(stmt, indent)
+	case *ast.LabeledStmt:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitLabeledStmt)
+		v.emitter.PreVisitLabeledStmt(stmt, indent)
+		v.traverseStmt(stmt.Stmt, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitLabeledStmt)
+		v.emitter.PostVisitLabeledStmt(stmt, indent)
 	case *ast.IncDecStmt:
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitIncDecStmt)
 		v.emitter.PreVisitIncDecStmt(stmt, indent)
@@ -554,9 +690,116 @@ func (v *BasePassVisitor) traverseStmt(stmt ast.Stmt, indent int) {
 		}
 		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitCaseClause)
 		v.emitter.PostVisitCaseClause(stmt, indent)
+	case *ast.DeferStmt:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitDeferStmt)
+		v.emitter.PreVisitDeferStmt(stmt, indent)
+		args, call := v.deferredCall(stmt.Call, "_defer")
+		for _, arg := range args {
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitDeferStmtArg)
+			v.emitter.PreVisitDeferStmtArg(arg, indent)
+			v.traverseExpression(arg.Value, 0)
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitDeferStmtArg)
+			v.emitter.PostVisitDeferStmtArg(arg, indent)
+		}
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitDeferStmtCall)
+		v.emitter.PreVisitDeferStmtCall(call, indent)
+		v.traverseExpression(call, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitDeferStmtCall)
+		v.emitter.PostVisitDeferStmtCall(call, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitDeferStmt)
+		v.emitter.PostVisitDeferStmt(stmt, indent)
+	case *ast.GoStmt:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGoStmt)
+		v.emitter.PreVisitGoStmt(stmt, indent)
+		args, call := v.deferredCall(stmt.Call, "_go")
+		for _, arg := range args {
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGoStmtArg)
+			v.emitter.PreVisitGoStmtArg(arg, indent)
+			v.traverseExpression(arg.Value, 0)
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGoStmtArg)
+			v.emitter.PostVisitGoStmtArg(arg, indent)
+		}
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGoStmtCall)
+		v.emitter.PreVisitGoStmtCall(call, indent)
+		v.traverseExpression(call, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGoStmtCall)
+		v.emitter.PostVisitGoStmtCall(call, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGoStmt)
+		v.emitter.PostVisitGoStmt(stmt, indent)
+	case *ast.SendStmt:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSendStmt)
+		v.emitter.PreVisitSendStmt(stmt, indent)
+		v.traverseExpression(stmt.Chan, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSendStmtValue)
+		v.emitter.PreVisitSendStmtValue(stmt.Value, indent)
+		v.traverseExpression(stmt.Value, 0)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSendStmtValue)
+		v.emitter.PostVisitSendStmtValue(stmt.Value, indent)
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSendStmt)
+		v.emitter.PostVisitSendStmt(stmt, indent)
+	case *ast.SelectStmt:
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSelectStmt)
+		v.emitter.PreVisitSelectStmt(stmt, indent)
+		// The channels and sent values of all cases are evaluated first, in
+		// source order, then the clause of the chosen case runs
+		index := 0
+		for _, s := range stmt.Body.List {
+			clause := s.(*ast.CommClause)
+			if clause.Comm == nil {
+				continue
+			}
+			ch, value, _ := selectCaseOp(clause)
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSelectStmtCase)
+			v.emitter.PreVisitSelectStmtCase(clause, index, indent)
+			v.traverseExpression(ch, 0)
+			if value != nil {
+				v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSelectStmtCaseValue)
+				v.emitter.PreVisitSelectStmtCaseValue(value, indent)
+				v.traverseExpression(value, 0)
+				v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSelectStmtCaseValue)
+				v.emitter.PostVisitSelectStmtCaseValue(value, indent)
+			}
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSelectStmtCase)
+			v.emitter.PostVisitSelectStmtCase(clause, index, indent)
+			index++
+		}
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSelectStmtClauses)
+		v.emitter.PreVisitSelectStmtClauses(stmt, indent)
+		index = 0
+		for _, s := range stmt.Body.List {
+			clause := s.(*ast.CommClause)
+			caseIndex := -1
+			if clause.Comm != nil {
+				caseIndex = index
+				index++
+			}
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitSelectStmtClause)
+			v.emitter.PreVisitSelectStmtClause(clause, caseIndex, indent)
+			for _, bodyStmt := range clause.Body {
+				v.traverseStmt(bodyStmt, indent+4)
+			}
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSelectStmtClause)
+			v.emitter.PostVisitSelectStmtClause(clause, caseIndex, indent)
+		}
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitSelectStmt)
+		v.emitter.PostVisitSelectStmt(stmt, indent)
 	case *ast.BlockStmt:
 		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitBlockStmt)
 		v.emitter.PreVisitBlockStmt(stmt, indent)
+		if n := len(v.funcScopes); n > 0 && v.funcScopes[n-1].body == stmt {
+			// Named results are zero-valued locals declared ahead of the
+			// function's own statements
+			for i, result := range v.funcScopes[n-1].results {
+				decl := namedResultDecl(result)
+				v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitBlockStmtList)
+				v.emitter.PreVisitBlockStmtList(decl, i, indent+2)
+				v.traverseStmt(decl, indent+2)
+				v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitBlockStmtList)
+				v.emitter.PostVisitBlockStmtList(decl, i, indent+2)
+			}
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncBodyStmts)
+			v.emitter.PreVisitFuncBodyStmts(stmt, indent)
+		}
 		for i := 0; i < len(stmt.List); i++ {
 			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitBlockStmtList)
 			v.emitter.PreVisitBlockStmtList(stmt.List[i], i, indent+2)
@@ -571,7 +814,90 @@ func (v *BasePassVisitor) traverseStmt(stmt ast.Stmt, indent int) {
 	}
 }
 
+// deferredCall splits a deferred call, or the call of a go statement, into
+// the arguments evaluated at the statement and the call run later, which
+// reads them from temporaries named after prefix. Constant arguments stay in
+// the call. The receiver of a method is evaluated at the statement too,
+// unless the method takes the address of a variable, which does not change.
+func (v *BasePassVisitor) deferredCall(call *ast.CallExpr, prefix string) ([]DeferArg, *ast.CallExpr) {
+	v.deferCount++
+	var args []DeferArg
+	deferred := *call
+	temp := func(value ast.Expr, name string) *ast.Ident {
+		tv := v.pkg.TypesInfo.Types[value]
+		ident := ast.NewIdent(name)
+		ident.NamePos = value.Pos()
+		v.pkg.TypesInfo.Types[ident] = tv
+		v.pkg.TypesInfo.Uses[ident] = types.NewVar(value.Pos(), v.pkg.Types, name, tv.Type)
+		args = append(args, DeferArg{Name: name, Value: value})
+		return ident
+	}
+	if sel, ok := call.Fun.(*ast.SelectorExpr); ok && evaluatedReceiver(v.pkg, sel) {
+		recv := &ast.SelectorExpr{X: temp(sel.X, fmt.Sprintf("%s%d_recv", prefix, v.deferCount)), Sel: sel.Sel}
+		v.pkg.TypesInfo.Types[recv] = v.pkg.TypesInfo.Types[sel]
+		v.pkg.TypesInfo.Selections[recv] = v.pkg.TypesInfo.Selections[sel]
+		deferred.Fun = recv
+	}
+	deferred.Args = make([]ast.Expr, len(call.Args))
+	for i, arg := range call.Args {
+		if v.pkg.TypesInfo.Types[arg].Value != nil {
+			deferred.Args[i] = arg
+			continue
+		}
+		deferred.Args[i] = temp(arg, fmt.Sprintf("%s%d_%d", prefix, v.deferCount, i))
+	}
+	v.pkg.TypesInfo.Types[&deferred] = v.pkg.TypesInfo.Types[call]
+	return args, &deferred
+}
+
+// evaluatedReceiver reports whether the receiver of the method value sel is
+// a value to evaluate: a pointer, an interface or a copied struct. A pointer
+// method on an addressable variable takes its address, which stays the same.
+func evaluatedReceiver(pkg *packages.Package, sel *ast.SelectorExpr) bool {
+	selection, ok := pkg.TypesInfo.Selections[sel]
+	if !ok || selection.Kind() != types.MethodVal {
+		return false
+	}
+	_, ptrRecv := selection.Obj().Type().(*types.Signature).Recv().Type().(*types.Pointer)
+	_, ptrX := pkg.TypesInfo.TypeOf(sel.X).Underlying().(*types.Pointer)
+	return !ptrRecv || ptrX
+}
+
+// traverseFuncBody traverses the body of a function, declaring its named
+// results at the start of the body
+func (v *BasePassVisitor) traverseFuncBody(ftype *ast.FuncType, body *ast.BlockStmt, indent int) {
+	scope := funcScope{body: body}
+	if ftype.Results != nil && len(ftype.Results.List) > 0 && len(ftype.Results.List[0].Names) > 0 {
+		scope.results = ftype.Results.List
+	}
+	v.funcScopes = append(v.funcScopes, scope)
+	v.traverseStmt(body, indent)
+	v.funcScopes = v.funcScopes[:len(v.funcScopes)-1]
+}
+
+// expandNakedReturn turns a naked return into one returning the named results
+func (v *BasePassVisitor) expandNakedReturn(stmt *ast.ReturnStmt) *ast.ReturnStmt {
+	n := len(v.funcScopes)
+	if len(stmt.Results) > 0 || n == 0 || len(v.funcScopes[n-1].results) == 0 {
+		return stmt
+	}
+	expanded := &ast.ReturnStmt{Return: stmt.Return}
+	for _, result := range v.funcScopes[n-1].results {
+		name := result.Names[0]
+		ref := ast.NewIdent(name.Name)
+		ref.NamePos = stmt.Return
+		obj := v.pkg.TypesInfo.Defs[name]
+		v.pkg.TypesInfo.Uses[ref] = obj
+		if tv, ok := v.pkg.TypesInfo.Types[firstUse(v.pkg, v.funcScopes[n-1].body, obj)]; ok {
+			v.pkg.TypesInfo.Types[ref] = tv
+		}
+		expanded.Results = append(expanded.Results, ref)
+	}
+	return expanded
+}
+
 func (v *BasePassVisitor) generateFuncDeclSignature(node *ast.FuncDecl) ast.Visitor {
+	splitResultFields(v.pkg, node.Type)
 	v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncDeclSignature)
 	v.emitter.PreVisitFuncDeclSignature(node, 0)
 	v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncDeclSignatureTypeResults)
@@ -629,7 +955,7 @@ func (v *BasePassVisitor) generateFuncDecl(node *ast.FuncDecl) ast.Visitor {
 	v.generateFuncDeclSignature(node)
 	v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitFuncDeclBody)
 	v.emitter.PreVisitFuncDeclBody(node.Body, 0)
-	v.traverseStmt(node.Body, 0)
+	v.traverseFuncBody(node.Type, node.Body, 0)
 	v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitFuncDeclBody)
 	v.emitter.PostVisitFuncDeclBody(node.Body, 0)
 	v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitFuncDecl)
@@ -645,7 +971,7 @@ func (v *BasePassVisitor) buildTypesGraph() map[string][]string {
 			if st, ok := node.Type.(*ast.StructType); ok {
 				structType := v.pkg.Name + "::" + node.Name.Name
 				for _, field := range st.Fields.List {
-					switch typ := field.Type.(type) {
+					switch typ := genericTypeExpr(field.Type).(type) {
 					case *ast.Ident:
 						if _, ok := primTypes[typ.Name]; !ok {
 							fieldType := v.pkg.Name + "::" + typ.Name
@@ -665,7 +991,7 @@ func (v *BasePassVisitor) buildTypesGraph() map[string][]string {
 							}
 						}
 					case *ast.ArrayType:
-						switch elt := typ.Elt.(type) {
+						switch elt := genericTypeExpr(typ.Elt).(type) {
 						case *ast.Ident:
 							fieldType := v.pkg.Name + "::" + elt.Name
 							if _, ok := primTypes[fieldType]; !ok {
@@ -716,6 +1042,51 @@ func (v *BasePassVisitor) GetBaseTypeNameFromSlices(node *ast.TypeSpec) string {
 	}
 }
 
+// structMethods returns the methods declared on the given struct type in source order.
+func (v *BasePassVisitor) structMethods(structName string) []*ast.FuncDecl {
+	var methods []*ast.FuncDecl
+	for _, node := range v.nodes {
+		if funcDecl, ok := node.(*ast.FuncDecl); ok && recvTypeName(funcDecl) == structName {
+			methods = append(methods, funcDecl)
+		}
+	}
+	return methods
+}
+
+// interfaceMethods returns the methods of an interface type as signature-only
+// function declarations, unnamed parameters get generated names so that
+// backends can forward them
+func interfaceMethods(iface *ast.InterfaceType) []*ast.FuncDecl {
+	var methods []*ast.FuncDecl
+	for _, field := range iface.Methods.List {
+		funcType, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			continue
+		}
+		params := &ast.FieldList{}
+		for _, param := range funcType.Params.List {
+			named := &ast.Field{Type: param.Type}
+			if len(param.Names) == 0 {
+				named.Names = []*ast.Ident{ast.NewIdent(fmt.Sprintf("p%d", len(params.List)))}
+			}
+			for _, name := range param.Names {
+				if name.Name == "_" {
+					name = ast.NewIdent(fmt.Sprintf("p%d", len(params.List)))
+				}
+				named.Names = append(named.Names, name)
+			}
+			params.List = append(params.List, named)
+		}
+		for _, name := range field.Names {
+			methods = append(methods, &ast.FuncDecl{
+				Name: name,
+				Type: &ast.FuncType{Func: funcType.Func, Params: params, Results: funcType.Results},
+			})
+		}
+	}
+	return methods
+}
+
 func (v *BasePassVisitor) gen(precedence map[string]int) {
 	typeInfos := make([]GenTypeInfo, 0)
 	for i := 0; i < len(v.nodes); i++ {
@@ -729,6 +1100,18 @@ func (v *BasePassVisitor) gen(precedence map[string]int) {
 					Pkg:        v.pkg.Name,
 					BaseType:   v.pkg.Name + "::" + node.Name.Name,
 				})
+			} else if isConstraintInterface(v.pkg, node) {
+				// Constraints only exist in the Go type checker
+				continue
+			} else if it, ok := node.Type.(*ast.InterfaceType); ok && len(it.Methods.List) > 0 {
+				typeInfos = append(typeInfos, GenTypeInfo{
+					Name:       node.Name.Name,
+					Interface:  it,
+					Other:      node,
+					IsExternal: false,
+					Pkg:        v.pkg.Name,
+					BaseType:   v.pkg.Name + "::" + node.Name.Name,
+				})
 			} else {
 				typeInfos = append(typeInfos, GenTypeInfo{
 					Name:       node.Name.Name,
@@ -771,8 +1154,27 @@ func (v *BasePassVisitor) gen(precedence map[string]int) {
 					v.emitter.PostVisitGenStructFieldName(fieldName, 0)
 				}
 			}
+			for _, method := range v.structMethods(typeInfos[i].Name) {
+				v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGenStructMethod)
+				v.emitter.PreVisitGenStructMethod(method, 2)
+				v.generateFuncDeclSignature(method)
+				v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenStructMethod)
+				v.emitter.PostVisitGenStructMethod(method, 2)
+			}
 			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenStructInfo)
 			v.emitter.PostVisitGenStructInfo(typeInfos[i], 0)
+		} else if typeInfos[i].Interface != nil {
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGenInterfaceInfo)
+			v.emitter.PreVisitGenInterfaceInfo(typeInfos[i], 0)
+			for _, method := range interfaceMethods(typeInfos[i].Interface) {
+				v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGenInterfaceMethod)
+				v.emitter.PreVisitGenInterfaceMethod(method, 2)
+				v.generateFuncDeclSignature(method)
+				v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenInterfaceMethod)
+				v.emitter.PostVisitGenInterfaceMethod(method, 2)
+			}
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenInterfaceInfo)
+			v.emitter.PostVisitGenInterfaceInfo(typeInfos[i], 0)
 		} else if node, ok := typeInfos[i].Other.(*ast.TypeSpec); ok {
 			if _, ok2 := typeInfos[i].Other.(*ast.StructType); !ok2 {
 				v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitTypeAliasName)
@@ -797,10 +1199,14 @@ func (v *BasePassVisitor) gen(precedence map[string]int) {
 			for _, spec := range genDecl.Specs {
 				valueSpec := spec.(*ast.ValueSpec)
 				for i, name := range valueSpec.Names {
+					// Blank constants only advance iota
+					if name.Name == "_" {
+						continue
+					}
 					v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGenDeclConstName)
 					v.emitter.PreVisitGenDeclConstName(name, 0)
-					if i < len(valueSpec.Values) {
-						v.traverseExpression(valueSpec.Values[i], 0)
+					if value := constSpecValue(v.pkg, valueSpec, i); value != nil {
+						v.traverseExpression(value, 0)
 					}
 					v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenDeclConstName)
 					v.emitter.PostVisitGenDeclConstName(name, 0)
@@ -821,6 +1227,32 @@ func (v *BasePassVisitor) gen(precedence map[string]int) {
 	}
 	v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitFuncDeclSignatures)
 	v.emitter.PostVisitFuncDeclSignatures(0)
+	// Package-level variables are declared after the function signatures so
+	// that initializers can call functions of the package
+	if vars := packageVars(v.pkg); len(vars) > 0 {
+		v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGenDeclVars)
+		v.emitter.PreVisitGenDeclVars(vars, 0)
+		for _, pv := range vars {
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGenDeclVar)
+			v.emitter.PreVisitGenDeclVar(pv, 0)
+			v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGenDeclVarType)
+			v.emitter.PreVisitGenDeclVarType(pv.Type, 0)
+			v.traverseExpression(pv.Type, 0)
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenDeclVarType)
+			v.emitter.PostVisitGenDeclVarType(pv.Type, 0)
+			if pv.Value != nil {
+				v.emitter.GetGoFIR().emitToFileBuffer("", PreVisitGenDeclVarValue)
+				v.emitter.PreVisitGenDeclVarValue(pv.Value, 0)
+				v.traverseExpression(pv.Value, 0)
+				v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenDeclVarValue)
+				v.emitter.PostVisitGenDeclVarValue(pv.Value, 0)
+			}
+			v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenDeclVar)
+			v.emitter.PostVisitGenDeclVar(pv, 0)
+		}
+		v.emitter.GetGoFIR().emitToFileBuffer("", PostVisitGenDeclVars)
+		v.emitter.PostVisitGenDeclVars(vars, 0)
+	}
 	for _, node := range v.nodes {
 		switch node := node.(type) {
 		case *ast.FuncDecl:
@@ -848,6 +1280,14 @@ func (v *BasePass) EpiLog() {
 
 func (v *BasePass) PreVisit(visitor ast.Visitor) {
 	cppVisitor := visitor.(*BasePassVisitor)
+	expandEmbedding(cppVisitor.pkg)
+	expandInstances(cppVisitor.pkg)
+	lowerRanges(cppVisitor.pkg)
+	lowerLoopVars(cppVisitor.pkg)
+	boxAddressedLocals(cppVisitor.pkg)
+	lowerNilComparisons(cppVisitor.pkg)
+	lowerIntAssignOps(cppVisitor.pkg)
+	typeNils(cppVisitor.pkg)
 	namespaces[cppVisitor.pkg.Name] = struct{}{}
 	// Add imported package names to namespaces
 	for _, imp := range cppVisitor.pkg.Imports {
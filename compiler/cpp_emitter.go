@@ -3,7 +3,9 @@ package compiler
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
+	"go/types"
 	"os"
 	"path/filepath"
 	"strings"
@@ -51,6 +53,56 @@ type CPPEmitter struct {
 	pendingValueName      string
 	pendingCollectionExpr string
 	pendingKeyName        string
+	pendingMapValueDecl   bool
+	// Map support
+	isMapRange       bool
+	isStringRange    bool
+	mapRangeValues   bool
+	insideAssignLhs  bool
+	tieAssign        bool // Assigning a tuple to existing variables with std::tie
+	mapCommaOkExpr   ast.Expr
+	mapMakeHintExpr  ast.Expr
+	sliceMakeLenExpr ast.Expr            // Length argument of the make([]T, n) call being emitted
+	compositeLits    []*ast.CompositeLit // Composite literals being emitted, innermost last
+	// Method support
+	currentFuncDecl    *ast.FuncDecl     // Function or method whose signature is being emitted
+	newCallArg         ast.Expr          // Type argument of the new(T) call being emitted
+	anyNilOperands     map[ast.Expr]bool // Operands of interface{} comparisons with nil
+	collectionNils     map[ast.Expr]bool // nil compared with a slice or a map, emitted as nullptr
+	skipOperator       bool              // The operator of the binary expression was emitted as a call
+	intOps             []intOp           // Integer operations of the binary expressions being emitted, innermost last
+	insideStructMethod bool              // Emitting a member function declaration inside its struct
+	suppressEmit       bool              // Skip output, e.g. method forward declarations
+	pendingRecvDecl    string            // Receiver binding to emit at the start of a method body
+	// Interface support
+	captureText       bool     // Collect emitted text into capturedText instead of the file
+	capturedText      string   // Interface method signature or type switch case type
+	ifaceImplText     string   // Forwarding overrides of the interface Impl template
+	typeAssertCommaOk ast.Expr // Type assertion of a comma-ok assignment (v, ok := x.(T))
+	chanRecvCommaOk   ast.Expr // Receive of a comma-ok assignment (v, ok := <-ch)
+	selectCount       int
+	selects           []string // Names of the select statements being emitted
+	typeSwitches      []cppTypeSwitch
+	typeSwitchCount   int
+	currentPackageVar PackageVar // Package-level variable being declared
+	// Defer support
+	deferScopes []deferScope // Enclosing function bodies, innermost last
+	deferCount  int          // Number of defer stacks named so far
+	// Labeled loops
+	continueLabels map[*ast.BlockStmt]string // Bodies of loops targeted by continue L, by label
+	// Closures
+	closureCells   closureCells            // Local variables shared with closures, kept in a shared_ptr
+	slicedArray    *ast.Ident              // Sliced array of the slice expression, whose cell is sliced
+	renames        map[types.Object]string // Locals renamed so they don't shadow an outer variable
+	calledFuncLits map[*ast.FuncLit]bool   // Function literals called directly, which capture by reference
+	valueLambdas   []bool                  // Whether each enclosing lambda captures by value, innermost last
+}
+
+// cppTypeSwitch is a type switch being lowered to an if-else chain on a
+// temporary holding the switch value
+type cppTypeSwitch struct {
+	name      string   // Temporary holding the switch value
+	caseTypes []string // Types of the current case clause
 }
 
 func (*CPPEmitter) lowerToBuiltins(selector string) string {
@@ -66,7 +118,11 @@ func (*CPPEmitter) lowerToBuiltins(selector string) string {
 	case "Print":
 		return "printf"
 	case "len":
-		return "std::size"
+		return "len"
+	case "delete":
+		return "map_delete"
+	case "close":
+		return "chan_close"
 	}
 	return selector
 }
@@ -77,8 +133,12 @@ func (cppe *CPPEmitter) emitToFile(s string) error {
 		cppe.rangeCollectionExpr += s
 		return nil
 	}
+	if cppe.captureText {
+		cppe.capturedText += s
+		return nil
+	}
 	// When suppressing range emit (key/value identifiers), skip
-	if cppe.suppressRangeEmit {
+	if cppe.suppressRangeEmit || cppe.suppressEmit {
 		return nil
 	}
 	return emitToFile(cppe.file, s)
@@ -97,6 +157,7 @@ func (cppe *CPPEmitter) GetFile() *os.File {
 }
 
 func (cppe *CPPEmitter) PreVisitProgram(indent int) {
+	cppTypesMap["int"], cppTypesMap["uint"] = intTypeNames("std::int32_t", "std::uint32_t", "std::int64_t", "std::uint64_t")
 	outputFile := cppe.Output
 	var err error
 	cppe.file, err = os.Create(outputFile)
@@ -110,7 +171,17 @@ func (cppe *CPPEmitter) PreVisitProgram(indent int) {
 		"#include <tuple>\n" +
 		"#include <any>\n" +
 		"#include <cstdint>\n" +
-		"#include <functional>\n")
+		"#include <cstdlib>\n" +
+		"#include <functional>\n" +
+		"#include <unordered_map>\n" +
+		"#include <algorithm>\n" +
+		"#include <memory>\n" +
+		"#include <stdexcept>\n" +
+		"#include <type_traits>\n" +
+		"#include <deque>\n" +
+		"#include <mutex>\n" +
+		"#include <condition_variable>\n" +
+		"#include <thread>\n")
 	cppe.file.WriteString(`#include <cstdarg> // For va_start, etc.
 #include <initializer_list>
 #include <iostream>
@@ -142,6 +213,13 @@ using uint32 = uint32_t;
 using uint64 = uint64_t;
 using float32 = float;
 using float64 = double;
+using Int = ` + cppTypesMap["int"] + `;
+using Uint = ` + cppTypesMap["uint"] + `;
+
+// Length of a string, slice, array or map as a Go int
+template <typename T> Int len(const T &v) {
+  return static_cast<Int>(std::size(v));
+}
 
 std::string string_format(const std::string fmt, ...) {
   int size =
@@ -169,6 +247,8 @@ void println() { printf("\n"); }
 
 void println(std::int8_t val) { printf("%d\n", val); }
 
+void println(std::uint8_t val) { printf("%d\n", val); }
+
 template<typename T>
 void println(const T& val) { std::cout << val << std::endl;}
 
@@ -179,38 +259,811 @@ void printf(const signed char c) {
 template<typename T>
 void printf(const T& val) { std::cout << val;}
 
-// Function to mimic Go's append behavior for std::vector
-template <typename T>
-std::vector<T>& append(std::vector<T> &vec,
-                      const std::initializer_list<T> &elements) {
-  std::vector<T>& result = vec;           // Create a copy of the original vector
-  result.insert(result.end(), elements); // Append the elements
-  return result;                         // Return the new vector
+// Go panics are thrown as GoPanic: the value passed to panic() and the
+// message printed when nothing recovers it
+struct GoPanic {
+  std::any value;
+  std::string message;
+  bool recovered = false;
+};
+
+// The panic whose deferred calls are running, recover() stops it
+thread_local GoPanic *recoverable_panic = nullptr;
+
+// Panic values print like Go prints them
+template <typename T> std::string panic_message(const T &value) {
+  if constexpr (std::is_same<T, bool>::value) {
+    return value ? "true" : "false";
+  } else if constexpr (std::is_floating_point<T>::value) {
+    char buf[32];
+    snprintf(buf, sizeof(buf), "%+.6e", (double)value);
+    std::string text(buf);
+    size_t e = text.find('e') + 2;
+    std::string exponent = text.substr(e);
+    while (exponent.size() < 3) {
+      exponent = "0" + exponent;
+    }
+    return text.substr(0, e) + exponent;
+  } else if constexpr (std::is_integral<T>::value) {
+    return std::to_string(+value);
+  } else if constexpr (std::is_convertible<T, std::string>::value) {
+    return std::string(value);
+  } else {
+    return "(panic value)";
+  }
+}
+
+template <typename T> [[noreturn]] void panic(const T &value) {
+  throw GoPanic{std::any(value), panic_message(value)};
+}
+
+// Panics raised by the runtime carry their message as the value
+[[noreturn]] void error_panic(const std::string &message) {
+  throw GoPanic{std::any(message), message};
+}
+
+[[noreturn]] void runtime_panic(const std::string &message) {
+  error_panic("runtime error: " + message);
+}
+
+// Go shifts: a count of at least the width of x shifts out every bit, a
+// negative count panics
+template <typename N> void check_shift_count(N n) {
+  if constexpr (std::is_signed<N>::value) {
+    if (n < 0) {
+      runtime_panic("negative shift amount");
+    }
+  }
+}
+
+template <typename T, typename N> T int_shl(T x, N n) {
+  check_shift_count(n);
+  if (static_cast<std::uint64_t>(n) >= sizeof(T) * 8) {
+    return 0;
+  }
+  return T(static_cast<std::make_unsigned_t<T>>(x) << n);
+}
+
+template <typename T, typename N> T int_shr(T x, N n) {
+  check_shift_count(n);
+  if (static_cast<std::uint64_t>(n) >= sizeof(T) * 8) {
+    return x < 0 ? T(-1) : T(0);
+  }
+  return T(x >> n);
+}
+
+// Go division: dividing by zero panics, and the most negative value divided
+// by -1 is itself, with a remainder of 0, where the native division traps
+template <typename T> T int_quo(T x, T y) {
+  if (y == 0) {
+    runtime_panic("integer divide by zero");
+  }
+  if constexpr (std::is_signed<T>::value) {
+    if (y == -1) {
+      return T(0 - static_cast<std::make_unsigned_t<T>>(x));
+    }
+  }
+  return T(x / y);
+}
+
+template <typename T> T int_rem(T x, T y) {
+  if (y == 0) {
+    runtime_panic("integer divide by zero");
+  }
+  if constexpr (std::is_signed<T>::value) {
+    if (y == -1) {
+      return 0;
+    }
+  }
+  return T(x % y);
+}
+
+std::any recover() {
+  if (recoverable_panic == nullptr || recoverable_panic->recovered) {
+    return std::any();
+  }
+  recoverable_panic->recovered = true;
+  return recoverable_panic->value;
+}
+
+// An unrecovered panic prints its message and exits with status 2, like Go
+[[noreturn]] void exit_panic() {
+  std::cout.flush();
+  try {
+    throw;
+  } catch (const GoPanic &p) {
+    std::cerr << "panic: " << p.message << std::endl;
+  } catch (const std::exception &e) {
+    std::cerr << "panic: " << e.what() << std::endl;
+  } catch (...) {
+    std::cerr << "panic: unknown exception" << std::endl;
+  }
+  std::exit(2);
+}
+
+// Index of a slice or string, checked like Go checks every index
+template <typename S>
+auto slice_at(S &&s, std::int64_t i) -> decltype(s[i]) {
+  if (i < 0) {
+    runtime_panic("index out of range [" + std::to_string(i) + "]");
+  }
+  if (i >= (std::int64_t)s.size()) {
+    runtime_panic("index out of range [" + std::to_string(i) + "] with length " + std::to_string(s.size()));
+  }
+  return s[i];
+}
+
+// A Go slice: length elements from offset in a backing array that the
+// slices taken from it share, of which capacity elements are usable
+template <typename T> struct slice {
+  using value_type = T;
+  std::shared_ptr<T[]> array;
+  Int offset = 0;
+  Int length = 0;
+  Int capacity = 0;
+
+  slice() {}
+  // []T{}: an empty slice, which unlike the nil slice has an array
+  explicit slice(Int capacity) : array(new T[capacity]()), capacity(capacity) {}
+  slice(std::initializer_list<T> elements)
+      : array(new T[elements.size()]()), length(elements.size()),
+        capacity(elements.size()) {
+    std::copy(elements.begin(), elements.end(), array.get());
+  }
+
+  T &operator[](Int i) const { return array[offset + i]; }
+  T *begin() const { return array.get() + offset; }
+  T *end() const { return begin() + length; }
+  std::size_t size() const { return length; }
+
+  // Only the nil slice has no array, an empty slice has one
+  bool operator==(std::nullptr_t) const { return !array; }
+  bool operator!=(std::nullptr_t) const { return bool(array); }
+};
+
+// make([]T, length, capacity): zero values, the capacity ones usable by append
+template <typename S> S make_slice(Int length, Int capacity) {
+  if (length < 0) {
+    runtime_panic("makeslice: len out of range");
+  }
+  if (capacity < length) {
+    runtime_panic("makeslice: cap out of range");
+  }
+  S s;
+  s.array.reset(new typename S::value_type[capacity]());
+  s.length = length;
+  s.capacity = capacity;
+  return s;
+}
+
+template <typename S> S make_slice(Int length) {
+  return make_slice<S>(length, length);
+}
+
+template <typename T> Int cap(const slice<T> &s) { return s.capacity; }
+
+template <typename T, std::size_t N> Int cap(const std::array<T, N> &) {
+  return N;
+}
+
+// Copies n elements between arrays that may overlap, like memmove
+template <typename T> void copy_elements(T *dst, const T *src, Int n) {
+  if (std::less<const T *>()(dst, src)) {
+    std::copy(src, src + n, dst);
+  } else {
+    std::copy_backward(src, src + n, dst + n);
+  }
+}
+
+// copy(dst, src) copies as many elements as both have and returns how many
+template <typename T> Int copy(const slice<T> &dst, const slice<T> &src) {
+  Int n = std::min(dst.length, src.length);
+  copy_elements(dst.begin(), src.begin(), n);
+  return n;
+}
+
+// Extends s by n elements, in its backing array while the capacity allows
+// and otherwise in a new array of twice the capacity
+template <typename T> slice<T> slice_grow(slice<T> s, Int n) {
+  Int length = s.length + n;
+  if (length > s.capacity) {
+    Int capacity = std::max(length, s.capacity * 2);
+    std::shared_ptr<T[]> array(new T[capacity]());
+    std::copy(s.begin(), s.end(), array.get());
+    s.array = array;
+    s.offset = 0;
+    s.capacity = capacity;
+  }
+  s.length = length;
+  return s;
+}
+
+// append(s, a, b): converting each element keeps append(names, "x") working
+template <typename T, typename... Rest>
+slice<T> append(const slice<T> &s, const typename slice<T>::value_type &element,
+                const Rest &...rest) {
+  T elements[] = {element, T(rest)...};
+  slice<T> result = slice_grow(s, 1 + sizeof...(rest));
+  std::copy(std::begin(elements), std::end(elements), result.end() - (1 + sizeof...(rest)));
+  return result;
 }
 
-// Overload to allow appending another vector
 template <typename T>
-std::vector<T>& append( std::vector<T> &vec,
-                      const std::vector<T> &elements) {
-  std::vector<T> result = vec; // Create a copy of the original vector
-  result.insert(result.end(), elements.begin(),
-                elements.end()); // Append the elements
-  return result;                 // Return the new vector
+slice<T> append(const slice<T> &s, const std::initializer_list<T> &elements) {
+  slice<T> result = slice_grow(s, elements.size());
+  std::copy(elements.begin(), elements.end(), result.end() - elements.size());
+  return result;
 }
 
+// append(s, t...) reads t before s grows, t may share its array
 template <typename T>
-std::vector<T>& append(std::vector<T> &vec, const T &element) {
-  std::vector<T>& result = vec; // Create a copy of the original vector
-  result.push_back(element);   // Append the single element
-  return result;               // Return the new vector
+slice<T> append(const slice<T> &s, const slice<T> &elements) {
+  slice<T> source = elements;
+  slice<T> result = slice_grow(s, source.length);
+  copy_elements(result.end() - source.length, source.begin(), source.length);
+  return result;
+}
+
+// Checks low <= high <= max <= limit, the capacity of a slice or the length
+// of a string, and panics with Go's message otherwise
+inline void check_slice_bounds(Int low, Int high, Int max, Int limit, bool slice3, const char *limitName) {
+  if (slice3 && (max < 0 || max > limit)) {
+    runtime_panic("slice bounds out of range [::" + std::to_string(max) + "] with " + limitName + " " + std::to_string(limit));
+  }
+  if (!slice3 && (high < 0 || high > limit)) {
+    runtime_panic("slice bounds out of range [:" + std::to_string(high) + "] with " + limitName + " " + std::to_string(limit));
+  }
+  if (slice3 && (high < 0 || high > max)) {
+    runtime_panic("slice bounds out of range [:" + std::to_string(high) + ":" + std::to_string(max) + "]");
+  }
+  if (low < 0 || low > high) {
+    runtime_panic("slice bounds out of range [" + std::to_string(low) + ":" + std::to_string(high) + (slice3 ? ":]" : "]"));
+  }
 }
 
-// Specialization for appending const char* to vector of strings
-std::vector<std::string>& append(std::vector<std::string> &vec, const char *element) {
-  std::vector<std::string>& result = vec;
-  result.push_back(std::string(element));
+// s[low:high:max] shares the backing array of s
+template <typename T>
+slice<T> reslice(const slice<T> &s, Int low, Int high, Int max) {
+  slice<T> result = s;
+  result.offset += low;
+  result.length = high - low;
+  result.capacity = max - low;
   return result;
 }
+
+template <typename T>
+slice<T> slice_expr(const slice<T> &s, Int low, Int high, Int max) {
+  check_slice_bounds(low, high, max, s.capacity, true, "capacity");
+  return reslice(s, low, high, max);
+}
+
+template <typename T> slice<T> slice_expr(const slice<T> &s, Int low, Int high) {
+  check_slice_bounds(low, high, s.capacity, s.capacity, false, "capacity");
+  return reslice(s, low, high, s.capacity);
+}
+
+template <typename T> slice<T> slice_expr(const slice<T> &s, Int low) {
+  return slice_expr(s, low, s.length);
+}
+
+// A slice of an array shares its elements with the shared_ptr cell holding
+// the array
+template <typename T, std::size_t N>
+slice<T> array_slice(const std::shared_ptr<std::array<T, N>> &a) {
+  slice<T> s;
+  s.array = std::shared_ptr<T[]>(a, a->data());
+  s.length = N;
+  s.capacity = N;
+  return s;
+}
+
+template <typename T, std::size_t N>
+slice<T> slice_expr(const std::shared_ptr<std::array<T, N>> &a, Int low, Int high, Int max) {
+  return slice_expr(array_slice(a), low, high, max);
+}
+
+template <typename T, std::size_t N>
+slice<T> slice_expr(const std::shared_ptr<std::array<T, N>> &a, Int low, Int high) {
+  check_slice_bounds(low, high, N, N, false, "length");
+  return reslice(array_slice(a), low, high, N);
+}
+
+template <typename T, std::size_t N>
+slice<T> slice_expr(const std::shared_ptr<std::array<T, N>> &a, Int low) {
+  return slice_expr(a, low, N);
+}
+
+inline std::string slice_expr(const std::string &s, Int low, Int high) {
+  check_slice_bounds(low, high, len(s), len(s), false, "length");
+  return s.substr(low, high - low);
+}
+
+inline std::string slice_expr(const std::string &s, Int low) {
+  return slice_expr(s, low, len(s));
+}
+
+// Type assertion x.(T) on an interface value, panics when the dynamic type differs
+template <typename T, typename I>
+T type_assert(const I &i) {
+  auto impl = std::dynamic_pointer_cast<typename I::template Impl<T>>(i.ptr);
+  if (!impl) {
+    error_panic("interface conversion: interface is not the asserted type");
+  }
+  return impl->value;
+}
+
+// Comma-ok type assertion: v, ok := x.(T)
+template <typename T, typename I>
+std::tuple<T, bool> type_assert_ok(const I &i) {
+  auto impl = std::dynamic_pointer_cast<typename I::template Impl<T>>(i.ptr);
+  if (!impl) {
+    return std::make_tuple(T{}, false);
+  }
+  return std::make_tuple(impl->value, true);
+}
+
+// Type switch case test: reports whether the dynamic type is T
+template <typename T, typename I>
+bool type_is(const I &i) {
+  return std::dynamic_pointer_cast<typename I::template Impl<T>>(i.ptr) != nullptr;
+}
+
+// String literals stored in std::any are const char *, they have type string
+template <typename T>
+bool type_is(const std::any &a) {
+  if constexpr (std::is_same<T, std::string>::value) {
+    if (a.type() == typeid(const char *)) {
+      return true;
+    }
+  }
+  return a.type() == typeid(T);
+}
+
+template <typename T>
+T type_assert(const std::any &a) {
+  if (!type_is<T>(a)) {
+    error_panic("interface conversion: interface is not the asserted type");
+  }
+  if constexpr (std::is_same<T, std::string>::value) {
+    if (a.type() == typeid(const char *)) {
+      return std::string(std::any_cast<const char *>(a));
+    }
+  }
+  return std::any_cast<T>(a);
+}
+
+template <typename T>
+std::tuple<T, bool> type_assert_ok(const std::any &a) {
+  if (!type_is<T>(a)) {
+    return std::make_tuple(T{}, false);
+  }
+  return std::make_tuple(type_assert<T>(a), true);
+}
+
+// Pointers to structs are shared, &T{...} and new(T) allocate the value
+template <typename T> std::shared_ptr<T> ptr_new(T value) {
+  return std::make_shared<T>(std::move(value));
+}
+
+// Dereference of a pointer, *p or p.f, panics on a nil pointer like in Go
+template <typename T> T &deref(const std::shared_ptr<T> &p) {
+  if (!p) {
+    runtime_panic("invalid memory address or nil pointer dereference");
+  }
+  return *p;
+}
+
+// A map value, the zero value is the nil map. Copies share the entries.
+template <typename K, typename V> struct map {
+  using key_type = K;
+  using mapped_type = V;
+  std::shared_ptr<std::unordered_map<K, V>> ref;
+
+  map() = default;
+  explicit map(Int hint) : ref(std::make_shared<std::unordered_map<K, V>>()) {
+    if (hint > 0) {
+      ref->reserve(hint);
+    }
+  }
+  map(std::initializer_list<std::pair<const K, V>> entries)
+      : ref(std::make_shared<std::unordered_map<K, V>>(entries)) {}
+
+  // m[k] as an assignment target inserts the key, a nil map has no entries
+  V &operator[](const K &key) const {
+    if (!ref) {
+      error_panic("assignment to entry in nil map");
+    }
+    return (*ref)[key];
+  }
+  size_t size() const { return ref ? ref->size() : 0; }
+  bool operator==(const map &other) const { return ref == other.ref; }
+  bool operator!=(const map &other) const { return ref != other.ref; }
+  bool operator==(std::nullptr_t) const { return !ref; }
+  bool operator!=(std::nullptr_t) const { return bool(ref); }
+};
+
+// Go map lookup: a missing key yields the zero value and is not inserted
+template <typename K, typename V>
+V map_get(const map<K, V> &m, const typename map<K, V>::key_type &key) {
+  if (!m.ref) {
+    return V{};
+  }
+  auto it = m.ref->find(key);
+  return it != m.ref->end() ? it->second : V{};
+}
+
+// Comma-ok lookup: v, ok := m[k]
+template <typename K, typename V>
+std::tuple<V, bool> map_get_ok(const map<K, V> &m,
+                               const typename map<K, V>::key_type &key) {
+  if (!m.ref) {
+    return std::make_tuple(V{}, false);
+  }
+  auto it = m.ref->find(key);
+  if (it == m.ref->end()) {
+    return std::make_tuple(V{}, false);
+  }
+  return std::make_tuple(it->second, true);
+}
+
+template <typename K, typename V>
+void map_delete(const map<K, V> &m, const typename map<K, V>::key_type &key) {
+  if (m.ref) {
+    m.ref->erase(key);
+  }
+}
+
+// Keys in ascending order, so range over a map is the same in every backend
+template <typename K, typename V> std::vector<K> map_keys(const map<K, V> &m) {
+  std::vector<K> keys;
+  if (!m.ref) {
+    return keys;
+  }
+  keys.reserve(m.ref->size());
+  for (const auto &entry : *m.ref) {
+    keys.push_back(entry.first);
+  }
+  std::sort(keys.begin(), keys.end());
+  return keys;
+}
+
+// Values in ascending key order
+template <typename K, typename V>
+std::vector<V> map_values(const map<K, V> &m) {
+  std::vector<V> values;
+  values.reserve(m.size());
+  for (const auto &key : map_keys(m)) {
+    values.push_back(m.ref->at(key));
+  }
+  return values;
+}
+
+// Decodes the UTF-8 sequence at offset i, returning the rune and its width.
+// Invalid or truncated sequences decode to U+FFFD with width 1, as in Go.
+inline std::tuple<std::int32_t, int> decode_rune(const std::string &s,
+                                                 size_t i) {
+  const std::tuple<std::int32_t, int> invalid{0xFFFD, 1};
+  unsigned char c = static_cast<unsigned char>(s[i]);
+  if (c < 0x80) {
+    return {c, 1};
+  }
+  int width = 0;
+  std::int32_t r = 0;
+  std::int32_t min = 0;
+  if ((c & 0xE0) == 0xC0) {
+    width = 2, r = c & 0x1F, min = 0x80;
+  } else if ((c & 0xF0) == 0xE0) {
+    width = 3, r = c & 0x0F, min = 0x800;
+  } else if ((c & 0xF8) == 0xF0) {
+    width = 4, r = c & 0x07, min = 0x10000;
+  } else {
+    return invalid;
+  }
+  if (i + width > s.size()) {
+    return invalid;
+  }
+  for (int k = 1; k < width; k++) {
+    unsigned char cc = static_cast<unsigned char>(s[i + k]);
+    if ((cc & 0xC0) != 0x80) {
+      return invalid;
+    }
+    r = (r << 6) | (cc & 0x3F);
+  }
+  if (r < min || r > 0x10FFFF || (r >= 0xD800 && r <= 0xDFFF)) {
+    return invalid;
+  }
+  return {r, width};
+}
+
+// Byte offsets and runes of a string, for range over a string
+inline std::vector<std::tuple<Int, std::int32_t>>
+string_range(const std::string &s) {
+  std::vector<std::tuple<Int, std::int32_t>> runes;
+  for (size_t i = 0; i < s.size();) {
+    auto [r, width] = decode_rune(s, i);
+    runes.emplace_back(static_cast<Int>(i), r);
+    i += width;
+  }
+  return runes;
+}
+
+// Deferred calls of a function. They run in LIFO order on every return,
+// after the results are evaluated, and when the function unwinds.
+struct DeferStack {
+  std::vector<std::function<void()>> calls;
+
+  void push(std::function<void()> call) { calls.push_back(std::move(call)); }
+
+  void run() {
+    while (!calls.empty()) {
+      auto call = std::move(calls.back());
+      calls.pop_back();
+      call();
+    }
+  }
+
+  template <typename T> T returning(T value) {
+    run();
+    return value;
+  }
+
+  // Runs the deferred calls for a panic unwinding the function, recover()
+  // in them stops it. Reports whether it was recovered.
+  bool recovered(GoPanic &p) {
+    struct Restore {
+      GoPanic *outer;
+      ~Restore() { recoverable_panic = outer; }
+    } restore{recoverable_panic};
+    recoverable_panic = &p;
+    run();
+    return p.recovered;
+  }
+
+  ~DeferStack() { run(); }
+};
+
+// Goroutines run on threads, one at a time: the running goroutine holds the
+// scheduler mutex and releases it only while it waits for a channel. When
+// every goroutine waits, none is left to wake the others.
+struct Scheduler {
+  std::mutex mutex;
+  std::condition_variable_any changed;
+  int alive = 1;
+  // Goroutines waiting since the last change, a change wakes all of them
+  int waiting = 0;
+  unsigned long changes = 0;
+
+  // Created by the main goroutine, which runs first
+  Scheduler() { mutex.lock(); }
+};
+
+// Never destroyed, goroutines may still wait on it when main returns
+inline Scheduler &scheduler() {
+  static Scheduler *s = new Scheduler();
+  return *s;
+}
+
+[[noreturn]] inline void deadlock() {
+  std::cout.flush();
+  std::cerr << "fatal error: all goroutines are asleep - deadlock!" << std::endl;
+  std::exit(2);
+}
+
+// Wakes the goroutines waiting for a channel to change
+inline void notify_change() {
+  auto &s = scheduler();
+  s.changes++;
+  s.waiting = 0;
+  s.changed.notify_all();
+}
+
+// Waits for a channel to change, letting the other goroutines run
+inline void wait_for_change() {
+  auto &s = scheduler();
+  if (++s.waiting == s.alive) {
+    deadlock();
+  }
+  auto seen = s.changes;
+  while (s.changes == seen) {
+    s.changed.wait(s.mutex);
+  }
+}
+
+// go f(): runs f in a new goroutine
+template <typename F> void go(F f) {
+  scheduler().alive++;
+  std::thread([f = std::move(f)]() mutable {
+    auto &s = scheduler();
+    s.mutex.lock();
+    try {
+      f();
+    } catch (...) {
+      exit_panic();
+    }
+    s.alive--;
+    if (s.waiting > 0 && s.waiting == s.alive) {
+      deadlock();
+    }
+    s.mutex.unlock();
+  }).detach();
+}
+
+template <typename T> struct Channel {
+  std::deque<T> items;
+  size_t capacity = 0;
+  bool closed = false;
+  // An unbuffered send waits until its item is received
+  size_t sent = 0;
+  size_t received = 0;
+  // Goroutines waiting to receive, an unbuffered send in a select needs one
+  size_t receivers = 0;
+
+  bool can_send() const {
+    return closed || (capacity > 0 ? items.size() < capacity : receivers > items.size());
+  }
+
+  bool can_recv() const { return closed || !items.empty(); }
+
+  void send(T value) {
+    if (closed) {
+      error_panic("send on closed channel");
+    }
+    items.push_back(std::move(value));
+    size_t seq = ++sent;
+    notify_change();
+    while (capacity == 0 && received < seq) {
+      wait_for_change();
+    }
+  }
+
+  // The zero value and false once the channel is closed and drained
+  std::tuple<T, bool> recv() {
+    if (items.empty()) {
+      return std::make_tuple(T{}, false);
+    }
+    T value = std::move(items.front());
+    items.pop_front();
+    received++;
+    notify_change();
+    return std::make_tuple(std::move(value), true);
+  }
+};
+
+// A channel value, the zero value is the nil channel. Copies share the channel.
+template <typename T> struct chan {
+  using value_type = T;
+  std::shared_ptr<Channel<T>> ref;
+
+  chan() = default;
+  explicit chan(int capacity) : ref(std::make_shared<Channel<T>>()) {
+    ref->capacity = capacity;
+  }
+  bool operator==(const chan &other) const { return ref == other.ref; }
+  bool operator!=(const chan &other) const { return ref != other.ref; }
+};
+
+// Operations on a nil channel block forever
+[[noreturn]] inline void block_forever() {
+  for (;;) {
+    wait_for_change();
+  }
+}
+
+template <typename T>
+void chan_send(const chan<T> &c, typename chan<T>::value_type value) {
+  if (!c.ref) {
+    block_forever();
+  }
+  while (!c.ref->closed && c.ref->capacity > 0 &&
+         c.ref->items.size() >= c.ref->capacity) {
+    wait_for_change();
+  }
+  c.ref->send(std::move(value));
+}
+
+// Comma-ok receive: v, ok := <-ch
+template <typename T> std::tuple<T, bool> chan_recv_ok(const chan<T> &c) {
+  if (!c.ref) {
+    block_forever();
+  }
+  c.ref->receivers++;
+  notify_change();
+  while (!c.ref->can_recv()) {
+    wait_for_change();
+  }
+  c.ref->receivers--;
+  return c.ref->recv();
+}
+
+template <typename T> T chan_recv(const chan<T> &c) {
+  return std::get<0>(chan_recv_ok(c));
+}
+
+template <typename T> void chan_close(const chan<T> &c) {
+  if (!c.ref) {
+    error_panic("close of nil channel");
+  }
+  if (c.ref->closed) {
+    error_panic("close of closed channel");
+  }
+  c.ref->closed = true;
+  notify_change();
+}
+
+// A case of a select statement, the cases of a nil channel are never ready
+struct SelectCase {
+  virtual ~SelectCase() = default;
+  virtual bool ready() = 0;
+  virtual void commit() = 0;
+  virtual void waiting(int delta) {}
+};
+
+template <typename T> struct SelectRecv : SelectCase {
+  chan<T> c;
+  T value{};
+  bool ok = false;
+
+  bool ready() override { return c.ref && c.ref->can_recv(); }
+  void commit() override { std::tie(value, ok) = c.ref->recv(); }
+  void waiting(int delta) override {
+    if (c.ref) {
+      c.ref->receivers += delta;
+    }
+  }
+};
+
+template <typename T> struct SelectSend : SelectCase {
+  chan<T> c;
+  T value;
+
+  bool ready() override { return c.ref && c.ref->can_send(); }
+  void commit() override { c.ref->send(value); }
+};
+
+template <typename T>
+std::shared_ptr<SelectRecv<T>> select_recv(const chan<T> &c) {
+  auto s = std::make_shared<SelectRecv<T>>();
+  s->c = c;
+  return s;
+}
+
+template <typename T>
+std::shared_ptr<SelectSend<T>> select_send(const chan<T> &c,
+                                           typename chan<T>::value_type value) {
+  auto s = std::make_shared<SelectSend<T>>();
+  s->c = c;
+  s->value = std::move(value);
+  return s;
+}
+
+// Runs the first ready case of a select, waiting for one unless the select
+// has a default. Returns the index of the case, -1 for default.
+inline int chan_select(std::vector<std::shared_ptr<SelectCase>> cases,
+                       bool has_default) {
+  bool registered = false;
+  for (;;) {
+    for (size_t i = 0; i < cases.size(); i++) {
+      if (cases[i]->ready()) {
+        for (auto &c : cases) {
+          c->waiting(registered ? -1 : 0);
+        }
+        cases[i]->commit();
+        return static_cast<int>(i);
+      }
+    }
+    if (has_default) {
+      return -1;
+    }
+    if (!registered) {
+      registered = true;
+      for (auto &c : cases) {
+        c->waiting(1);
+      }
+      notify_change();
+    }
+    wait_for_change();
+  }
+}
 `)
 	cppe.file.WriteString("\n\n")
 	if err != nil {
@@ -230,6 +1083,7 @@ func (cppe *CPPEmitter) PostVisitProgram(indent int) {
 }
 
 func (cppe *CPPEmitter) PreVisitPackage(pkg *packages.Package, indent int) {
+	cppe.pkg = pkg
 	name := pkg.Name
 	DebugLogPrintf("CPPEmitter: PreVisitPackage: %s (path: %s)", name, pkg.PkgPath)
 	if name == "main" {
@@ -276,6 +1130,14 @@ func (cppe *CPPEmitter) PreVisitBasicLit(e *ast.BasicLit, indent int) {
 		} else {
 			cppe.emitToFile(cppe.emitAsString(fmt.Sprintf("\"%s\"", value), 0))
 		}
+	} else if r, ok := runeLiteralValue(cppe.pkg, e); ok {
+		cppe.emitToFile(fmt.Sprintf("%d", r))
+	} else if isBoxedIntLit(cppe.pkg, e) {
+		typeName := cppTypesMap[cppe.pkg.TypesInfo.TypeOf(e).String()]
+		cppe.emitToFile(fmt.Sprintf("%s(%s)", typeName, e.Value))
+	} else if v := cppe.pkg.TypesInfo.Types[e].Value; e.Kind == token.INT && v != nil && constant.Sign(v) > 0 && !fitsInt64(v) {
+		// Too large for a signed literal, the value of a uint64
+		cppe.emitToFile(e.Value + "ULL")
 	} else {
 		cppe.emitToFile(cppe.emitAsString(e.Value, 0))
 	}
@@ -283,43 +1145,218 @@ func (cppe *CPPEmitter) PreVisitBasicLit(e *ast.BasicLit, indent int) {
 
 func (cppe *CPPEmitter) PreVisitIdent(e *ast.Ident, indent int) {
 	var str string
-	name := e.Name
+	name := localName(cppe.pkg, cppe.renames, e)
 	name = cppe.lowerToBuiltins(name)
 	if name == "nil" {
 		str = cppe.emitAsString("{}", indent)
-		cppe.emitToFile(str)
+		if isPointerExpr(cppe.pkg, e) || isFuncExpr(cppe.pkg, e) || cppe.collectionNils[e] {
+			str = cppe.emitAsString("nullptr", indent)
+		}
+		if !cppe.anyNilOperands[e] {
+			cppe.emitToFile(str)
+		}
+	} else if name == "_" && cppe.insideAssignLhs && cppe.tieAssign {
+		cppe.emitToFile(cppe.emitAsString("std::ignore", indent))
 	} else {
 		if n, ok := cppTypesMap[name]; ok {
 			str = cppe.emitAsString(n, indent)
 			cppe.emitToFile(str)
 		} else {
+			// A member can't be named like its type, embedded fields get a suffix
+			if isEmbeddedField(cppe.pkg, e) {
+				name += "_"
+			}
+			if e == cppe.slicedArray {
+				name = "_" + name
+			} else if cppe.closureCells.vars[cppe.pkg.TypesInfo.Uses[e]] {
+				name = "(*_" + name + ")"
+			}
 			str = cppe.emitAsString(name, indent)
 			cppe.emitToFile(str)
 		}
 	}
 }
 
+// PreVisitBinaryExpr emits the comparison of an interface{} with nil as a
+// test of whether the std::any holds a value. A slice or a map is compared
+// with nullptr, which only the nil one equals.
+func (cppe *CPPEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
+	op := cppe.intOp(node)
+	cppe.intOps = append(cppe.intOps, op)
+	cppe.emitToFile(op.open)
+	if isCollectionNilComparison(cppe.pkg, node) {
+		if cppe.collectionNils == nil {
+			cppe.collectionNils = make(map[ast.Expr]bool)
+		}
+		cppe.collectionNils[node.Y] = true
+	}
+	if !isAnyNilComparison(cppe.pkg, node) {
+		return
+	}
+	if cppe.anyNilOperands == nil {
+		cppe.anyNilOperands = make(map[ast.Expr]bool)
+	}
+	cppe.anyNilOperands[node.X] = true
+	cppe.anyNilOperands[node.Y] = true
+	if node.Op == token.EQL {
+		cppe.emitToFile("!")
+	}
+}
+
+func (cppe *CPPEmitter) PostVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
+	cppe.emitToFile(cppe.intOps[len(cppe.intOps)-1].close)
+	cppe.intOps = cppe.intOps[:len(cppe.intOps)-1]
+}
+
+// intOp returns the form of an integer operation that C++ would not perform
+// like Go. Arithmetic on 8 and 16-bit integers yields int, it is converted
+// back to wrap around: std::uint8_t(a + b). Shifts by a count that may be
+// negative or beyond the width, and division by a variable, which may be zero,
+// call the runtime: int_shl<std::uint8_t>(x, n), int_quo<std::int64_t>(a, b).
+// Signed overflow of the wider types wraps, the code is built with -fwrapv.
+func (cppe *CPPEmitter) intOp(node *ast.BinaryExpr) intOp {
+	basic := intOpType(cppe.pkg, node)
+	if basic == nil {
+		return intOp{}
+	}
+	t, ok := cppTypesMap[intTypeName(basic)]
+	if !ok {
+		return intOp{}
+	}
+	switch {
+	case (node.Op == token.SHL || node.Op == token.SHR) && !shiftCountInRange(cppe.pkg, node, basic):
+		name := map[token.Token]string{token.SHL: "int_shl", token.SHR: "int_shr"}[node.Op]
+		return intOp{open: name + "<" + t + ">(", sep: ", ", close: ")"}
+	case mayDivideByZero(cppe.pkg, node) || (isSignedQuo(cppe.pkg, node, basic) && intBits(basic) >= 32):
+		name := map[token.Token]string{token.QUO: "int_quo", token.REM: "int_rem"}[node.Op]
+		return intOp{open: name + "<" + t + ">(", sep: ", ", close: ")"}
+	case intBits(basic) < 32:
+		return intOp{open: t + "(", close: ")"}
+	}
+	return intOp{}
+}
+
+func (cppe *CPPEmitter) PostVisitBinaryExprLeft(node ast.Expr, indent int) {
+	if cppe.anyNilOperands[node] {
+		cppe.emitToFile(".has_value()")
+		cppe.skipOperator = true
+	}
+}
+
 func (cppe *CPPEmitter) PreVisitBinaryExprOperator(op token.Token, indent int) {
-	str := cppe.emitAsString(op.String()+" ", 1)
+	if cppe.skipOperator {
+		cppe.skipOperator = false
+		return
+	}
+	if sep := cppe.intOps[len(cppe.intOps)-1].sep; sep != "" {
+		cppe.emitToFile(sep)
+		return
+	}
+	content := op.String()
+	if op == token.AND_NOT {
+		content = "& ~"
+	}
+	str := cppe.emitAsString(content+" ", 1)
 	cppe.emitToFile(str)
 }
 
+func (cppe *CPPEmitter) PreVisitCallExpr(node *ast.CallExpr, indent int) {
+	if lit, ok := node.Fun.(*ast.FuncLit); ok {
+		if cppe.calledFuncLits == nil {
+			cppe.calledFuncLits = make(map[*ast.FuncLit]bool)
+		}
+		cppe.calledFuncLits[lit] = true
+	}
+	// make(map[K]V) and make(chan T) are emitted as the type itself, so skip "make"
+	if isMapMakeCall(cppe.pkg, node) || isChanMakeCall(cppe.pkg, node) {
+		cppe.suppressRangeEmit = true
+	}
+	// make([]T, n, c) -> make_slice<slice<T>>(n, c)
+	if isSliceMakeCall(cppe.pkg, node) {
+		cppe.emitToFile("make_slice")
+		cppe.suppressRangeEmit = true
+		cppe.sliceMakeLenExpr = node.Args[1]
+	}
+	// new(T) allocates the zero value, ptr_new(T{})
+	if isBuiltinCall(cppe.pkg, node, "new") {
+		cppe.emitToFile("ptr_new(")
+		cppe.suppressRangeEmit = true
+		cppe.newCallArg = node.Args[0]
+	}
+}
+
+func (cppe *CPPEmitter) PostVisitCallExprFun(node ast.Expr, indent int) {
+	if ident, ok := node.(*ast.Ident); ok && (ident.Name == "make" || ident.Name == "new") {
+		cppe.suppressRangeEmit = false
+	}
+}
+
 func (cppe *CPPEmitter) PreVisitCallExprArgs(node []ast.Expr, indent int) {
+	if len(node) == 1 && node[0] == cppe.newCallArg {
+		return
+	}
+	if len(node) > 0 && isSliceTypeExpr(cppe.pkg, node[0]) {
+		cppe.emitToFile("<")
+		return
+	}
+	if len(node) > 0 && (isMapTypeExpr(cppe.pkg, node[0]) || isChanTypeExpr(cppe.pkg, node[0])) {
+		// make(map[K]V, hint) -> map<K, V>(hint), make(chan T, size) -> chan<T>(size)
+		if len(node) > 1 {
+			cppe.mapMakeHintExpr = node[1]
+		}
+		return
+	}
 	str := cppe.emitAsString("(", 0)
 	cppe.emitToFile(str)
 }
 func (cppe *CPPEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {
+	if len(node) == 1 && node[0] == cppe.newCallArg {
+		cppe.newCallArg = nil
+		cppe.emitToFile("{})")
+		return
+	}
+	if len(node) == 1 && (isMapTypeExpr(cppe.pkg, node[0]) || isChanTypeExpr(cppe.pkg, node[0])) {
+		cppe.emitToFile("(0)")
+		return
+	}
 	str := cppe.emitAsString(")", 0)
 	cppe.emitToFile(str)
 }
 
 func (cppe *CPPEmitter) PreVisitCallExprArg(node ast.Expr, index int, indent int) {
+	if node == cppe.mapMakeHintExpr {
+		cppe.mapMakeHintExpr = nil
+		cppe.emitToFile("(")
+		return
+	}
+	if node == cppe.sliceMakeLenExpr {
+		cppe.sliceMakeLenExpr = nil
+		cppe.emitToFile(">(")
+		return
+	}
 	if index > 0 {
 		str := cppe.emitAsString(", ", 0)
 		cppe.emitToFile(str)
 	}
 }
 
+// PreVisitCallExprVariadicArgs packs the variadic arguments into a braced
+// slice initializer; a spread slice is passed as is
+func (cppe *CPPEmitter) PreVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {
+	if index > 0 {
+		cppe.emitToFile(", ")
+	}
+	if !node.Ellipsis.IsValid() {
+		cppe.emitToFile("{")
+	}
+}
+
+func (cppe *CPPEmitter) PostVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {
+	if !node.Ellipsis.IsValid() {
+		cppe.emitToFile("}")
+	}
+}
+
 func (cppe *CPPEmitter) PreVisitParenExpr(node *ast.ParenExpr, indent int) {
 	str := cppe.emitAsString("(", 0)
 	cppe.emitToFile(str)
@@ -329,12 +1366,46 @@ func (cppe *CPPEmitter) PostVisitParenExpr(node *ast.ParenExpr, indent int) {
 	cppe.emitToFile(str)
 }
 
+func (cppe *CPPEmitter) PreVisitCompositeLit(node *ast.CompositeLit, indent int) {
+	cppe.compositeLits = append(cppe.compositeLits, node)
+}
+
+func (cppe *CPPEmitter) PostVisitCompositeLit(node *ast.CompositeLit, indent int) {
+	cppe.compositeLits = cppe.compositeLits[:len(cppe.compositeLits)-1]
+}
+
+// insideMapCompositeLit reports whether the innermost composite literal is a map literal
+func (cppe *CPPEmitter) insideMapCompositeLit() bool {
+	return len(cppe.compositeLits) > 0 && isMapExpr(cppe.pkg, cppe.compositeLits[len(cppe.compositeLits)-1])
+}
+
+// emptyCollectionLit reports whether the innermost composite literal is an
+// empty slice or map literal, which is constructed with (0): T{} would be the
+// nil slice or map
+func (cppe *CPPEmitter) emptyCollectionLit() bool {
+	if len(cppe.compositeLits) == 0 {
+		return false
+	}
+	lit := cppe.compositeLits[len(cppe.compositeLits)-1]
+	return len(lit.Elts) == 0 && (isMapExpr(cppe.pkg, lit) || isSliceExpr(cppe.pkg, lit))
+}
+
+// PreVisitCompositeLitElts opens the element list. An empty slice or map
+// literal is a new one, slice<T>{} and map<K, V>{} would be nil
 func (cppe *CPPEmitter) PreVisitCompositeLitElts(node []ast.Expr, indent int) {
+	if cppe.emptyCollectionLit() {
+		cppe.emitToFile("(0")
+		return
+	}
 	str := cppe.emitAsString("{", 0)
 	cppe.emitToFile(str)
 }
 
 func (cppe *CPPEmitter) PostVisitCompositeLitElts(node []ast.Expr, indent int) {
+	if cppe.emptyCollectionLit() {
+		cppe.emitToFile(")")
+		return
+	}
 	str := cppe.emitAsString("}", 0)
 	cppe.emitToFile(str)
 }
@@ -346,16 +1417,67 @@ func (cppe *CPPEmitter) PreVisitCompositeLitElt(node ast.Expr, index int, indent
 	}
 }
 
+// PreVisitArrayType emits a slice as the runtime slice and a fixed-size array
+// as std::array, which has the value semantics of a Go array
 func (cppe *CPPEmitter) PreVisitArrayType(node ast.ArrayType, indent int) {
-	str := cppe.emitAsString("std::vector<", indent)
+	str := cppe.emitAsString("slice<", indent)
+	if node.Len != nil {
+		str = cppe.emitAsString("std::array<", indent)
+	}
 	cppe.emitToFile(str)
 }
 func (cppe *CPPEmitter) PostVisitArrayType(node ast.ArrayType, indent int) {
 	str := cppe.emitAsString(">", 0)
+	if node.Len != nil {
+		str = cppe.emitAsString(fmt.Sprintf(", %d>", arrayLen(cppe.pkg, node)), 0)
+	}
+	cppe.emitToFile(str)
+}
+
+// PreVisitEllipsis emits a variadic parameter ...T as slice<T>
+func (cppe *CPPEmitter) PreVisitEllipsis(node *ast.Ellipsis, indent int) {
+	cppe.emitToFile(cppe.emitAsString("slice<", indent))
+}
+func (cppe *CPPEmitter) PostVisitEllipsis(node *ast.Ellipsis, indent int) {
+	cppe.emitToFile(">")
+}
+
+func (cppe *CPPEmitter) PreVisitMapType(node *ast.MapType, indent int) {
+	str := cppe.emitAsString("map<", indent)
 	cppe.emitToFile(str)
 }
+func (cppe *CPPEmitter) PostVisitMapKeyType(node ast.Expr, indent int) {
+	cppe.emitToFile(", ")
+}
+func (cppe *CPPEmitter) PostVisitMapType(node *ast.MapType, indent int) {
+	cppe.emitToFile(">")
+}
+
+func (cppe *CPPEmitter) PreVisitChanType(node *ast.ChanType, indent int) {
+	cppe.emitToFile(cppe.emitAsString("chan<", indent))
+}
+func (cppe *CPPEmitter) PostVisitChanType(node *ast.ChanType, indent int) {
+	cppe.emitToFile(">")
+}
+
+// PreVisitSelectorExprX dereferences a pointer to select a field or method,
+// the receiver of a pointer method is already bound to the value
+func (cppe *CPPEmitter) PreVisitSelectorExprX(node ast.Expr, indent int) {
+	if isPointerExpr(cppe.pkg, node) && !isPointerRecvExpr(cppe.pkg, cppe.currentFuncDecl, node) {
+		cppe.emitToFile("deref(")
+	}
+}
 
 func (cppe *CPPEmitter) PostVisitSelectorExprX(node ast.Expr, indent int) {
+	// Interface values dispatch through the pointer to their implementation
+	if isNonEmptyInterface(cppe.pkg.TypesInfo.TypeOf(node)) {
+		cppe.emitToFile("->")
+		return
+	}
+	if isPointerExpr(cppe.pkg, node) && !isPointerRecvExpr(cppe.pkg, cppe.currentFuncDecl, node) {
+		cppe.emitToFile(").")
+		return
+	}
 	if ident, ok := node.(*ast.Ident); ok {
 		if cppe.lowerToBuiltins(ident.Name) == "" {
 			return
@@ -372,18 +1494,80 @@ func (cppe *CPPEmitter) PostVisitSelectorExprX(node ast.Expr, indent int) {
 	}
 }
 
+// isMapRead reports whether node is a map lookup that must not insert the key.
+// Assignment targets keep operator[], which inserts the zero value like Go does.
+func (cppe *CPPEmitter) isMapRead(node *ast.IndexExpr) bool {
+	return !cppe.insideAssignLhs && isMapIndexExpr(cppe.pkg, node)
+}
+
+// Slice and string indexes go through slice_at, which checks the bounds
+func (cppe *CPPEmitter) PreVisitIndexExpr(node *ast.IndexExpr, indent int) {
+	if node == cppe.mapCommaOkExpr {
+		cppe.emitToFile("map_get_ok(")
+	} else if cppe.isMapRead(node) {
+		cppe.emitToFile("map_get(")
+	} else if isSliceIndexExpr(cppe.pkg, node) {
+		cppe.emitToFile("slice_at(")
+	}
+}
+
 func (cppe *CPPEmitter) PreVisitIndexExprIndex(node *ast.IndexExpr, indent int) {
+	if isSliceIndexExpr(cppe.pkg, node) || cppe.isMapRead(node) {
+		cppe.emitToFile(", ")
+		return
+	}
 	str := cppe.emitAsString("[", 0)
 	cppe.emitToFile(str)
 }
 func (cppe *CPPEmitter) PostVisitIndexExprIndex(node *ast.IndexExpr, indent int) {
+	if isSliceIndexExpr(cppe.pkg, node) || cppe.isMapRead(node) {
+		cppe.emitToFile(")")
+		return
+	}
 	str := cppe.emitAsString("]", 0)
 	cppe.emitToFile(str)
 }
 
+// Instantiations of generic functions and types list their template arguments
+func (cppe *CPPEmitter) PreVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {
+	cppe.emitToFile("<")
+}
+
+func (cppe *CPPEmitter) PreVisitIndexListExprIndex(node ast.Expr, index int, indent int) {
+	if index > 0 {
+		cppe.emitToFile(", ")
+	}
+}
+
+func (cppe *CPPEmitter) PostVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {
+	cppe.emitToFile(">")
+}
+
 func (cppe *CPPEmitter) PreVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
-	str := cppe.emitAsString("(", 0)
-	str += cppe.emitAsString(node.Op.String(), 0)
+	// &x allocates a copy of x
+	if node.Op == token.AND {
+		cppe.emitToFile("ptr_new(")
+		return
+	}
+	if node.Op == token.ARROW {
+		if node == cppe.chanRecvCommaOk {
+			cppe.emitToFile("chan_recv_ok(")
+		} else {
+			cppe.emitToFile("chan_recv(")
+		}
+		return
+	}
+	// -x and ^x of 8 and 16-bit integers yield int, converted back to wrap
+	str := ""
+	if basic := intOpType(cppe.pkg, node); basic != nil && intBits(basic) < 32 {
+		str = cppTypesMap[intTypeName(basic)]
+	}
+	op := node.Op.String()
+	if node.Op == token.XOR {
+		op = "~"
+	}
+	str += cppe.emitAsString("(", 0)
+	str += cppe.emitAsString(op, 0)
 	cppe.emitToFile(str)
 }
 func (cppe *CPPEmitter) PostVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
@@ -391,51 +1575,32 @@ func (cppe *CPPEmitter) PostVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
 	cppe.emitToFile(str)
 }
 
+// A slice expression x[low:high:max] calls slice_expr, which shares the
+// backing array of a slice, or the cell of a sliced array; an omitted high or
+// max bound is left out
 func (cppe *CPPEmitter) PreVisitSliceExpr(node *ast.SliceExpr, indent int) {
-	str := cppe.emitAsString("std::vector<std::remove_reference<decltype(", indent)
-	cppe.emitToFile(str)
+	cppe.slicedArray = slicedArrayIdent(cppe.pkg, node.X)
+	cppe.emitToFile("slice_expr(")
 }
 func (cppe *CPPEmitter) PostVisitSliceExpr(node *ast.SliceExpr, indent int) {
-	str := cppe.emitAsString(")", 0)
-	cppe.emitToFile(str)
-}
-
-func (cppe *CPPEmitter) PostVisitSliceExprX(node ast.Expr, indent int) {
-	str := cppe.emitAsString("[0]", 0)
-	str += cppe.emitAsString(")>::type>(", 0)
-	cppe.emitToFile(str)
+	cppe.emitToFile(")")
 }
 
 func (cppe *CPPEmitter) PreVisitSliceExprLow(node ast.Expr, indent int) {
-	str := cppe.emitAsString(".begin() ", 0)
-	cppe.emitToFile(str)
-	if node != nil {
-		str := cppe.emitAsString("+ ", 0)
-		cppe.emitToFile(str)
-	} else {
-		DebugLogPrintf("Low index: <nil>\n")
+	cppe.emitToFile(", ")
+	if node == nil {
+		cppe.emitToFile("0")
 	}
 }
 
-func (cppe *CPPEmitter) PreVisitSliceExprXEnd(node ast.Expr, indent int) {
-	str := cppe.emitAsString(", ", 0)
-	cppe.emitToFile(str)
-}
-
 func (cppe *CPPEmitter) PreVisitSliceExprHigh(node ast.Expr, indent int) {
 	if node != nil {
-		str := cppe.emitAsString(".begin() ", 0)
-		cppe.emitToFile(str)
-		str = cppe.emitAsString("+ ", 0)
-		cppe.emitToFile(str)
-	} else {
-		str := cppe.emitAsString(".end() ", 0)
-		cppe.emitToFile(str)
+		cppe.emitToFile(", ")
 	}
 }
 
-func (cppe *CPPEmitter) PostVisitSliceExprHigh(node ast.Expr, indent int) {
-
+func (cppe *CPPEmitter) PreVisitSliceExprMax(node ast.Expr, indent int) {
+	cppe.emitToFile(", ")
 }
 
 func (cppe *CPPEmitter) PreVisitFuncType(node *ast.FuncType, indent int) {
@@ -462,10 +1627,18 @@ func (cppe *CPPEmitter) PreVisitFuncTypeResult(node *ast.Field, index int, inden
 }
 
 func (cppe *CPPEmitter) PreVisitKeyValueExpr(node *ast.KeyValueExpr, indent int) {
+	if cppe.insideMapCompositeLit() {
+		cppe.emitToFile("{")
+		return
+	}
 	str := cppe.emitAsString(".", 0)
 	cppe.emitToFile(str)
 }
 func (cppe *CPPEmitter) PostVisitKeyValueExpr(node *ast.KeyValueExpr, indent int) {
+	if cppe.insideMapCompositeLit() {
+		cppe.emitToFile("}")
+		return
+	}
 	str := cppe.emitAsString("\n", 0)
 	cppe.emitToFile(str)
 }
@@ -488,21 +1661,47 @@ func (cppe *CPPEmitter) PreVisitFuncTypeParam(node *ast.Field, index int, indent
 }
 
 func (cppe *CPPEmitter) PreVisitKeyValueExprValue(node ast.Expr, indent int) {
+	if cppe.insideMapCompositeLit() {
+		cppe.emitToFile(", ")
+		return
+	}
 	str := cppe.emitAsString("= ", 0)
 	cppe.emitToFile(str)
 }
 
+// A function literal called directly captures by reference. Any other may
+// outlive the variables it uses, so it captures copies, and the variables it
+// shares with the function live in shared_ptr cells. The receiver of a
+// pointer method is bound to the value and captured by reference.
 func (cppe *CPPEmitter) PreVisitFuncLit(node *ast.FuncLit, indent int) {
-	str := cppe.emitAsString("[&](", indent)
+	byValue := !cppe.calledFuncLits[node]
+	cppe.valueLambdas = append(cppe.valueLambdas, byValue)
+	capture := "&"
+	if byValue {
+		capture = "="
+		if cppe.currentFuncDecl != nil && hasPointerRecv(cppe.currentFuncDecl) && recvName(cppe.currentFuncDecl) != "" {
+			recv := cppe.pkg.TypesInfo.Defs[cppe.currentFuncDecl.Recv.List[0].Names[0]]
+			for _, v := range capturedVars(cppe.pkg, node) {
+				if v == recv {
+					capture += ", &" + v.Name()
+				}
+			}
+		}
+	}
+	str := cppe.emitAsString("["+capture+"](", indent)
 	cppe.emitToFile(str)
 }
 func (cppe *CPPEmitter) PostVisitFuncLit(node *ast.FuncLit, indent int) {
 	str := cppe.emitAsString("}", 0)
 	cppe.emitToFile(str)
+	cppe.valueLambdas = cppe.valueLambdas[:len(cppe.valueLambdas)-1]
 }
 
 func (cppe *CPPEmitter) PostVisitFuncLitTypeParams(node *ast.FieldList, indent int) {
 	str := cppe.emitAsString(")", 0)
+	if cppe.valueLambdas[len(cppe.valueLambdas)-1] {
+		str += " mutable"
+	}
 	str += cppe.emitAsString("->", 0)
 	cppe.emitToFile(str)
 }
@@ -517,13 +1716,14 @@ func (cppe *CPPEmitter) PreVisitFuncLitTypeParam(node *ast.Field, index int, ind
 
 func (cppe *CPPEmitter) PostVisitFuncLitTypeParam(node *ast.Field, index int, indent int) {
 	str := cppe.emitAsString(" ", 0)
-	str += cppe.emitAsString(node.Names[0].Name, indent)
+	str += cppe.emitAsString(localName(cppe.pkg, cppe.renames, node.Names[0]), indent)
 	cppe.emitToFile(str)
 }
 
 func (cppe *CPPEmitter) PreVisitFuncLitBody(node *ast.BlockStmt, indent int) {
 	str := cppe.emitAsString("{\n", 0)
 	cppe.emitToFile(str)
+	cppe.deferScopes = pushDeferScope(cppe.pkg, cppe.deferScopes, node, &cppe.deferCount)
 }
 
 func (cppe *CPPEmitter) PreVisitFuncLitTypeResults(node *ast.FieldList, indent int) {
@@ -540,23 +1740,39 @@ func (cppe *CPPEmitter) PreVisitFuncLitTypeResult(node *ast.Field, index int, in
 	}
 }
 
-func (cppe *CPPEmitter) PreVisitTypeAssertExprType(node ast.Expr, indent int) {
-	str := cppe.emitAsString("std::any_cast<", indent)
-	cppe.emitToFile(str)
+func (cppe *CPPEmitter) PreVisitTypeAssertExpr(node *ast.TypeAssertExpr, indent int) {
+	if node == cppe.typeAssertCommaOk {
+		cppe.emitToFile(cppe.emitAsString("type_assert_ok<", indent))
+	} else {
+		cppe.emitToFile(cppe.emitAsString("type_assert<", indent))
+	}
 }
 
 func (cppe *CPPEmitter) PreVisitTypeAssertExprX(node ast.Expr, indent int) {
-	str := cppe.emitAsString(">(std::any(", 0)
-	cppe.emitToFile(str)
+	cppe.emitToFile(">(")
 }
+
 func (cppe *CPPEmitter) PostVisitTypeAssertExprX(node ast.Expr, indent int) {
-	str := cppe.emitAsString("))", 0)
-	cppe.emitToFile(str)
+	cppe.emitToFile(")")
 }
 
+// PreVisitStarExpr emits a pointer type *T as std::shared_ptr<T> and a
+// dereference *p through deref, which checks for nil. The receiver of a
+// pointer method is bound to the value, *n is n itself
 func (cppe *CPPEmitter) PreVisitStarExpr(node *ast.StarExpr, indent int) {
-	str := cppe.emitAsString("*", 0)
-	cppe.emitToFile(str)
+	if isStructPointer(cppe.pkg.TypesInfo.TypeOf(node)) {
+		cppe.emitToFile(cppe.emitAsString("std::shared_ptr<", indent))
+	} else if !isPointerRecvExpr(cppe.pkg, cppe.currentFuncDecl, node.X) {
+		cppe.emitToFile("deref(")
+	}
+}
+
+func (cppe *CPPEmitter) PostVisitStarExpr(node *ast.StarExpr, indent int) {
+	if isStructPointer(cppe.pkg.TypesInfo.TypeOf(node)) {
+		cppe.emitToFile(">")
+	} else if !isPointerRecvExpr(cppe.pkg, cppe.currentFuncDecl, node.X) {
+		cppe.emitToFile(")")
+	}
 }
 
 func (cppe *CPPEmitter) PreVisitInterfaceType(node *ast.InterfaceType, indent int) {
@@ -574,15 +1790,41 @@ func (cppe *CPPEmitter) PreVisitDeclStmtValueSpecNames(node *ast.Ident, index in
 	cppe.emitToFile(str)
 }
 func (cppe *CPPEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index int, indent int) {
-	cppe.emitToFile(";")
+	// Value-initialized, as Go variables start at their zero value
+	cppe.emitToFile("{};")
 }
 
 func (cppe *CPPEmitter) PreVisitBranchStmt(node *ast.BranchStmt, indent int) {
 	str := cppe.emitAsString(node.Tok.String()+";", indent)
+	if node.Label != nil {
+		str = cppe.emitAsString("goto "+branchLabel(node.Label.Name, node.Tok)+";", indent)
+	}
 	cppe.emitToFile(str)
 }
 
+// PreVisitLabeledStmt prepares the goto targets of break L and continue L,
+// C++ has no labeled loops
+func (cppe *CPPEmitter) PreVisitLabeledStmt(node *ast.LabeledStmt, indent int) {
+	if _, continues := labelTargets(node); continues {
+		if cppe.continueLabels == nil {
+			cppe.continueLabels = make(map[*ast.BlockStmt]string)
+		}
+		cppe.continueLabels[loopBody(node.Stmt)] = node.Label.Name
+	}
+}
+
+func (cppe *CPPEmitter) PostVisitLabeledStmt(node *ast.LabeledStmt, indent int) {
+	if breaks, _ := labelTargets(node); breaks {
+		cppe.emitToFile("\n" + cppe.emitAsString(branchLabel(node.Label.Name, token.BREAK)+":;\n", indent))
+	}
+}
+
+func (cppe *CPPEmitter) PreVisitIncDecStmt(node *ast.IncDecStmt, indent int) {
+	cppe.insideAssignLhs = true
+}
+
 func (cppe *CPPEmitter) PostVisitIncDecStmt(node *ast.IncDecStmt, indent int) {
+	cppe.insideAssignLhs = false
 	str := cppe.emitAsString(node.Tok.String(), 0)
 	if !cppe.insideForPostCond {
 		str += cppe.emitAsString(";", 0)
@@ -619,11 +1861,23 @@ func (cppe *CPPEmitter) PreVisitAssignStmt(node *ast.AssignStmt, indent int) {
 		cppe.suppressRangeEmit = true
 		return
 	}
+	if isMapCommaOk(cppe.pkg, node) {
+		cppe.mapCommaOkExpr = node.Rhs[0]
+	}
+	if isTypeAssertCommaOk(node) {
+		cppe.typeAssertCommaOk = node.Rhs[0]
+	}
+	if isChanRecvCommaOk(node) {
+		cppe.chanRecvCommaOk = ast.Unparen(node.Rhs[0])
+	}
 	str := cppe.emitAsString("", indent)
 	cppe.emitToFile(str)
 }
 
 func (cppe *CPPEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {
+	cppe.mapCommaOkExpr = nil
+	cppe.typeAssertCommaOk = nil
+	cppe.chanRecvCommaOk = nil
 	// Reset blank identifier suppression if it was set
 	if cppe.suppressRangeEmit {
 		cppe.suppressRangeEmit = false
@@ -634,6 +1888,42 @@ func (cppe *CPPEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {
 		str := cppe.emitAsString(";", 0)
 		cppe.emitToFile(str)
 	}
+	cppe.emitToFile(cppe.closureCellDecls(node, 1))
+}
+
+func (cppe *CPPEmitter) PostVisitDeclStmt(node *ast.DeclStmt, indent int) {
+	cppe.emitToFile(cppe.closureCellDecls(node, 1))
+}
+
+// addSlicedArrayCells moves the sliced arrays declared in a function into
+// cells too, so that their slices share the array the cell holds
+func addSlicedArrayCells(pkg *packages.Package, decl *ast.FuncDecl, cells closureCells) {
+	if decl.Body == nil {
+		return
+	}
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		for _, ident := range declaredIdents(n) {
+			if obj := pkg.TypesInfo.Defs[ident]; isSlicedArray(pkg, obj) && !cells.vars[obj] {
+				cells.vars[obj] = true
+				cells.sites[n] = append(cells.sites[n], obj)
+			}
+		}
+		return true
+	})
+}
+
+// closureCellDecls moves the variables declared by node, a statement or the
+// block of their function or range loop, into shared_ptr cells used as (*_name)
+func (cppe *CPPEmitter) closureCellDecls(node ast.Node, indent int) string {
+	str := ""
+	for _, obj := range cppe.closureCells.sites[node] {
+		name := objectName(cppe.renames, obj)
+		str += cppe.emitAsString(fmt.Sprintf("auto _%s = std::make_shared<decltype(%s)>(%s);", name, name, name), indent)
+		if _, ok := node.(*ast.BlockStmt); ok {
+			str += "\n"
+		}
+	}
+	return str
 }
 
 func (cppe *CPPEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int) {
@@ -645,6 +1935,10 @@ func (cppe *CPPEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int)
 			if lit, ok := node.Rhs[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
 				str := cppe.emitAsString("std::string ", indent)
 				cppe.emitToFile(str)
+			} else if isIntConstant(cppe.pkg, node.Rhs[0]) {
+				// auto would give x := 5 the 32-bit type of the literal
+				typeName := cppTypesMap[cppe.pkg.TypesInfo.TypeOf(node.Rhs[0]).Underlying().String()]
+				cppe.emitToFile(cppe.emitAsString(typeName+" ", indent))
 			} else {
 				str := cppe.emitAsString("auto ", indent)
 				cppe.emitToFile(str)
@@ -665,9 +1959,12 @@ func (cppe *CPPEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int)
 		assignmentToken = "="
 	}
 	cppe.assignmentToken = assignmentToken
+	cppe.insideAssignLhs = true
+	cppe.tieAssign = node.Tok == token.ASSIGN && len(node.Lhs) > 1
 }
 
 func (cppe *CPPEmitter) PostVisitAssignStmtLhs(node *ast.AssignStmt, indent int) {
+	cppe.insideAssignLhs = false
 	if node.Tok.String() == ":=" && len(node.Lhs) > 1 {
 		str := cppe.emitAsString("]", indent)
 		cppe.emitToFile(str)
@@ -690,6 +1987,32 @@ func (cppe *CPPEmitter) PreVisitAssignStmtLhsExpr(node ast.Expr, index int, inde
 }
 
 func (cppe *CPPEmitter) PreVisitReturnStmt(node *ast.ReturnStmt, indent int) {
+	// Deferred calls run after the results are evaluated, while locals are alive
+	if stack := currentDeferStack(cppe.deferScopes); stack != "" {
+		if len(node.Results) == 0 {
+			cppe.emitToFile(cppe.emitAsString(stack+".run();\n", indent))
+		} else if names := resultNames(enclosingFuncResults(cppe.pkg, cppe.currentFuncDecl, node.Pos())); names != nil {
+			// Named results are assigned first, deferred calls may change them
+			cppe.emitToFile(cppe.emitAsString("{ ", indent))
+			if returnsNamedResults(node, names) {
+				cppe.suppressRangeEmit = true
+			} else if len(names) > 1 {
+				cppe.emitToFile("std::tie(" + strings.Join(names, ", ") + ") = ")
+				if len(node.Results) > 1 {
+					cppe.emitToFile("std::make_tuple(")
+				}
+			} else {
+				cppe.emitToFile(names[0] + " = ")
+			}
+			return
+		} else {
+			cppe.emitToFile(cppe.emitAsString("return "+stack+".returning(", indent))
+			if len(node.Results) > 1 {
+				cppe.emitToFile("std::make_tuple(")
+			}
+			return
+		}
+	}
 	str := cppe.emitAsString("return ", indent)
 	cppe.emitToFile(str)
 	if len(node.Results) > 1 {
@@ -699,10 +2022,26 @@ func (cppe *CPPEmitter) PreVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 }
 
 func (cppe *CPPEmitter) PostVisitReturnStmt(node *ast.ReturnStmt, indent int) {
+	if stack := currentDeferStack(cppe.deferScopes); stack != "" && len(node.Results) > 0 {
+		if names := resultNames(enclosingFuncResults(cppe.pkg, cppe.currentFuncDecl, node.Pos())); names != nil {
+			if returnsNamedResults(node, names) {
+				cppe.suppressRangeEmit = false
+			} else if len(node.Results) > 1 {
+				cppe.emitToFile(");")
+			} else {
+				cppe.emitToFile(";")
+			}
+			cppe.emitToFile(" " + stack + ".run(); return " + namedResultsValue(names) + "; }")
+			return
+		}
+	}
 	if len(node.Results) > 1 {
 		str := cppe.emitAsString(")", 0)
 		cppe.emitToFile(str)
 	}
+	if currentDeferStack(cppe.deferScopes) != "" && len(node.Results) > 0 {
+		cppe.emitToFile(")")
+	}
 	str := cppe.emitAsString(";", 0)
 	cppe.emitToFile(str)
 }
@@ -747,11 +2086,31 @@ func (cppe *CPPEmitter) PostVisitForStmtCond(node ast.Expr, indent int) {
 }
 
 func (cppe *CPPEmitter) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
-	// Check if this is a key-value range (both Key and Value present)
-	if node.Key != nil && node.Value != nil {
+	cppe.isMapRange = isMapExpr(cppe.pkg, node.X)
+	cppe.isStringRange = isStringExpr(cppe.pkg, node.X)
+	if cppe.isStringRange {
+		// Emit: for (auto [i, r] : string_range(s)), the key and value are
+		// suppressed until the string expression
+		bindings := "_"
+		if node.Key != nil || node.Value != nil {
+			key, value := stringRangeNames(cppe.pkg, cppe.renames, node)
+			bindings = fmt.Sprintf("[%s, %s]", key, value)
+		}
+		cppe.isKeyValueRange = false
+		cppe.emitToFile(cppe.emitAsString(fmt.Sprintf("for (auto %s : string_range(", bindings), indent))
+		cppe.suppressRangeEmit = true
+		return
+	}
+	cppe.mapRangeValues = node.Key == nil
+	// Check if this is a key-value range (both Key and Value present), an
+	// index-only range over a slice or array also counts the index
+	if node.Key != nil && (node.Value != nil || !cppe.isMapRange) {
 		cppe.isKeyValueRange = true
-		cppe.rangeKeyName = node.Key.(*ast.Ident).Name
-		cppe.rangeValueName = node.Value.(*ast.Ident).Name
+		cppe.rangeKeyName = localName(cppe.pkg, cppe.renames, node.Key.(*ast.Ident))
+		cppe.rangeValueName = ""
+		if node.Value != nil {
+			cppe.rangeValueName = localName(cppe.pkg, cppe.renames, node.Value.(*ast.Ident))
+		}
 		cppe.rangeCollectionExpr = ""
 		cppe.suppressRangeEmit = true
 		cppe.rangeStmtIndent = indent
@@ -777,7 +2136,9 @@ func (cppe *CPPEmitter) PreVisitRangeStmtValue(node ast.Expr, indent int) {
 }
 
 func (cppe *CPPEmitter) PostVisitRangeStmtValue(node ast.Expr, indent int) {
-	if cppe.isKeyValueRange {
+	if cppe.isStringRange {
+		cppe.suppressRangeEmit = false
+	} else if cppe.isKeyValueRange {
 		// Stop suppressing, start capturing collection expression
 		cppe.suppressRangeEmit = false
 		cppe.captureRangeExpr = true
@@ -789,10 +2150,21 @@ func (cppe *CPPEmitter) PostVisitRangeStmtValue(node ast.Expr, indent int) {
 
 func (cppe *CPPEmitter) PreVisitRangeStmtX(node ast.Expr, indent int) {
 	// For key-value range, we're already in capture mode
+	if cppe.isMapRange && !cppe.isKeyValueRange {
+		// for k := range m iterates keys, for _, v := range m iterates values
+		if cppe.mapRangeValues {
+			cppe.emitToFile("map_values(")
+		} else {
+			cppe.emitToFile("map_keys(")
+		}
+	}
 }
 
 func (cppe *CPPEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
-	if cppe.isKeyValueRange {
+	if cppe.isStringRange {
+		cppe.isStringRange = false
+		cppe.emitToFile("))\n")
+	} else if cppe.isKeyValueRange {
 		// Stop capturing and emit the complete for loop
 		cppe.captureRangeExpr = false
 		collection := cppe.rangeCollectionExpr
@@ -800,15 +2172,22 @@ func (cppe *CPPEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 		value := cppe.rangeValueName
 		indent := cppe.rangeStmtIndent
 
-		// Emit: for (size_t key = 0; key < collection.size(); key++)
-		str := cppe.emitAsString(fmt.Sprintf("for (size_t %s = 0; %s < %s.size(); %s++)\n", key, key, collection, key), indent)
-		cppe.emitToFile(str)
+		if cppe.isMapRange {
+			// Emit: for (auto key : map_keys(collection))
+			str := cppe.emitAsString(fmt.Sprintf("for (auto %s : map_keys(%s))\n", key, collection), indent)
+			cppe.emitToFile(str)
+		} else {
+			// Emit: for (size_t key = 0; key < collection.size(); key++)
+			str := cppe.emitAsString(fmt.Sprintf("for (size_t %s = 0; %s < %s.size(); %s++)\n", key, key, collection, key), indent)
+			cppe.emitToFile(str)
+		}
 
 		// Set pending value declaration to be emitted at start of body block
-		cppe.pendingRangeValueDecl = true
+		cppe.pendingRangeValueDecl = value != ""
 		cppe.pendingValueName = value
 		cppe.pendingCollectionExpr = collection
 		cppe.pendingKeyName = key
+		cppe.pendingMapValueDecl = cppe.isMapRange
 
 		// Reset range state
 		cppe.isKeyValueRange = false
@@ -816,6 +2195,9 @@ func (cppe *CPPEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 		cppe.rangeValueName = ""
 		cppe.rangeCollectionExpr = ""
 	} else {
+		if cppe.isMapRange {
+			cppe.emitToFile(")")
+		}
 		str := cppe.emitAsString(")\n", 0)
 		cppe.emitToFile(str)
 	}
@@ -866,27 +2248,310 @@ func (cppe *CPPEmitter) PostVisitCaseClauseListExpr(node ast.Expr, index int, in
 	cppe.emitToFile(str)
 }
 
+// Type switches become an if-else chain of type_is<T>() tests on a temporary
+// that holds the switch value
+func (cppe *CPPEmitter) PreVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
+	cppe.typeSwitchCount++
+	cppe.typeSwitches = append(cppe.typeSwitches, cppTypeSwitch{
+		name: fmt.Sprintf("_ts%d", cppe.typeSwitchCount),
+	})
+	cppe.emitToFile(cppe.emitAsString("{\n", indent))
+}
+
+func (cppe *CPPEmitter) PostVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
+	cppe.typeSwitches = cppe.typeSwitches[:len(cppe.typeSwitches)-1]
+	cppe.emitToFile("\n" + cppe.emitAsString("}", indent))
+}
+
+func (cppe *CPPEmitter) currentTypeSwitch() *cppTypeSwitch {
+	return &cppe.typeSwitches[len(cppe.typeSwitches)-1]
+}
+
+func (cppe *CPPEmitter) PreVisitTypeSwitchStmtX(node ast.Expr, indent int) {
+	cppe.emitToFile(cppe.emitAsString("const auto& "+cppe.currentTypeSwitch().name+" = ", indent+2))
+}
+
+func (cppe *CPPEmitter) PostVisitTypeSwitchStmtX(node ast.Expr, indent int) {
+	cppe.emitToFile(";\n")
+}
+
+func (cppe *CPPEmitter) PreVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {
+	cppe.currentTypeSwitch().caseTypes = nil
+	switch {
+	case index == 0 && node.List == nil:
+		cppe.emitToFile(cppe.emitAsString("", indent+2))
+	case index == 0:
+		cppe.emitToFile(cppe.emitAsString("if (", indent+2))
+	case node.List == nil:
+		cppe.emitToFile(" else ")
+	default:
+		cppe.emitToFile(" else if (")
+	}
+}
+
+func (cppe *CPPEmitter) PostVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {
+	cppe.emitToFile(cppe.emitAsString("}", indent+2))
+}
+
+func (cppe *CPPEmitter) PreVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {
+	if index > 0 {
+		cppe.emitToFile(" || ")
+	}
+	cppe.captureText = true
+	cppe.capturedText = ""
+}
+
+func (cppe *CPPEmitter) PostVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {
+	cppe.captureText = false
+	ts := cppe.currentTypeSwitch()
+	ts.caseTypes = append(ts.caseTypes, cppe.capturedText)
+	cppe.emitToFile(fmt.Sprintf("type_is<%s>(%s)", cppe.capturedText, ts.name))
+}
+
+// PreVisitTypeSwitchCaseClauseBody opens the clause and binds the switch
+// variable: to the asserted value in single type clauses, to the switch value
+// otherwise
+func (cppe *CPPEmitter) PreVisitTypeSwitchCaseClauseBody(node *ast.CaseClause, index int, indent int) {
+	if node.List != nil {
+		cppe.emitToFile(") ")
+	}
+	cppe.emitToFile("{\n")
+	obj := typeSwitchClauseVar(cppe.pkg, node)
+	if obj == nil {
+		return
+	}
+	ts := cppe.currentTypeSwitch()
+	value := ts.name
+	if len(ts.caseTypes) == 1 {
+		value = fmt.Sprintf("type_assert<%s>(%s)", ts.caseTypes[0], ts.name)
+	}
+	cppe.emitToFile(cppe.emitAsString(fmt.Sprintf("auto %s = %s;\n", objectName(cppe.renames, obj), value), indent+4))
+}
+
 func (cppe *CPPEmitter) PreVisitBlockStmt(node *ast.BlockStmt, indent int) {
 	str := cppe.emitAsString("{\n", indent)
 	cppe.emitToFile(str)
 
+	if cppe.pendingRecvDecl != "" {
+		cppe.emitToFile(cppe.emitAsString(cppe.pendingRecvDecl, indent+2))
+		cppe.pendingRecvDecl = ""
+	}
 	// If we have a pending value declaration from key-value range, emit it now
 	if cppe.pendingRangeValueDecl {
-		valueDecl := cppe.emitAsString(fmt.Sprintf("auto %s = %s[%s];\n",
+		valueFormat := "auto %s = %s[%s];\n"
+		if cppe.pendingMapValueDecl {
+			valueFormat = "auto %s = map_get(%s, %s);\n"
+		}
+		valueDecl := cppe.emitAsString(fmt.Sprintf(valueFormat,
 			cppe.pendingValueName, cppe.pendingCollectionExpr, cppe.pendingKeyName), indent+2)
 		cppe.emitToFile(valueDecl)
+		cppe.pendingMapValueDecl = false
 		cppe.pendingRangeValueDecl = false
 		cppe.pendingValueName = ""
 		cppe.pendingCollectionExpr = ""
 		cppe.pendingKeyName = ""
 	}
+	cppe.emitToFile(cppe.closureCellDecls(node, indent+2))
+	// The body of a loop targeted by continue L is nested in a block, so that
+	// the goto to its end doesn't cross the declarations of the body
+	if _, ok := cppe.continueLabels[node]; ok {
+		cppe.emitToFile(cppe.emitAsString("{\n", indent+2))
+	}
+}
+
+func (cppe *CPPEmitter) PreVisitFuncBodyStmts(node *ast.BlockStmt, indent int) {
+	if isDeferScopeBody(cppe.deferScopes, node) {
+		if isMainBody(cppe.pkg, cppe.currentFuncDecl, node) {
+			cppe.emitToFile(cppe.emitAsString("try {\n", indent+2))
+		}
+		if scope := currentDeferScope(cppe.deferScopes); scope.stack != "" {
+			cppe.emitToFile(cppe.emitAsString("DeferStack "+scope.stack+";\n", indent+2))
+			if scope.recovers {
+				cppe.emitToFile(cppe.emitAsString("try {\n", indent+2))
+			}
+		}
+	}
 }
 
 func (cppe *CPPEmitter) PostVisitBlockStmt(node *ast.BlockStmt, indent int) {
+	if isDeferScopeBody(cppe.deferScopes, node) {
+		if scope := currentDeferScope(cppe.deferScopes); scope.stack != "" {
+			// A recovered panic returns the zero values from the function
+			if scope.recovers {
+				str := cppe.emitAsString("} catch (GoPanic &_panic) {\n", indent+2)
+				str += cppe.emitAsString("if (!"+scope.stack+".recovered(_panic)) throw;\n", indent+4)
+				str += cppe.emitAsString("}\n", indent+2)
+				cppe.emitToFile(str)
+			}
+			cppe.emitToFile(cppe.emitAsString(scope.stack+".run();\n", indent+2))
+			if names := resultNames(enclosingFuncResults(cppe.pkg, cppe.currentFuncDecl, node.Lbrace+1)); scope.recovers && names != nil {
+				cppe.emitToFile(cppe.emitAsString("return "+namedResultsValue(names)+";\n", indent+2))
+			} else if scope.recovers && funcBodyHasResults(cppe.pkg, cppe.currentFuncDecl, node) {
+				cppe.emitToFile(cppe.emitAsString("return {};\n", indent+2))
+			}
+		}
+		if isMainBody(cppe.pkg, cppe.currentFuncDecl, node) {
+			str := cppe.emitAsString("} catch (...) {\n", indent+2)
+			str += cppe.emitAsString("exit_panic();\n", indent+4)
+			str += cppe.emitAsString("}\n", indent+2)
+			cppe.emitToFile(str)
+		}
+		cppe.deferScopes = cppe.deferScopes[:len(cppe.deferScopes)-1]
+	}
+	if label, ok := cppe.continueLabels[node]; ok {
+		cppe.emitToFile(cppe.emitAsString("}\n", indent+2))
+		cppe.emitToFile(cppe.emitAsString(branchLabel(label, token.CONTINUE)+":;\n", indent+2))
+	}
 	str := cppe.emitAsString("}", indent)
 	cppe.emitToFile(str)
 }
 
+// Deferred calls are pushed as lambdas. Arguments are evaluated into init
+// captures at the defer statement. Defers at the top of the function body
+// capture other variables by reference, the stack runs before they go out of
+// scope; nested defers copy them, as block locals may be gone by then.
+func (cppe *CPPEmitter) PreVisitDeferStmt(node *ast.DeferStmt, indent int) {
+	scope := cppe.deferScopes[len(cppe.deferScopes)-1]
+	capture := "="
+	for _, stmt := range scope.body.List {
+		if stmt == node {
+			capture = "&"
+		}
+	}
+	// Named results outlive any block, deferred calls may change them
+	if capture == "=" {
+		for _, name := range resultNames(enclosingFuncResults(cppe.pkg, cppe.currentFuncDecl, node.Pos())) {
+			capture += ", &" + name
+		}
+	}
+	cppe.emitToFile(cppe.emitAsString(scope.stack+".push(["+capture, indent))
+}
+
+// namedResultsValue is the value a function returns from its named results
+func namedResultsValue(names []string) string {
+	if len(names) > 1 {
+		return "std::make_tuple(" + strings.Join(names, ", ") + ")"
+	}
+	return names[0]
+}
+
+func (cppe *CPPEmitter) PreVisitDeferStmtArg(node DeferArg, indent int) {
+	cppe.emitToFile(", " + node.Name + " = ")
+}
+
+func (cppe *CPPEmitter) PreVisitDeferStmtCall(node *ast.CallExpr, indent int) {
+	cppe.emitToFile("]() mutable { ")
+}
+
+func (cppe *CPPEmitter) PostVisitDeferStmtCall(node *ast.CallExpr, indent int) {
+	cppe.emitToFile("; });")
+}
+
+// A goroutine is a lambda like a deferred call, its arguments are evaluated
+// into init captures at the go statement and other variables are copied
+func (cppe *CPPEmitter) PreVisitGoStmt(node *ast.GoStmt, indent int) {
+	cppe.emitToFile(cppe.emitAsString("go([=", indent))
+}
+
+func (cppe *CPPEmitter) PreVisitGoStmtArg(node DeferArg, indent int) {
+	cppe.emitToFile(", " + node.Name + " = ")
+}
+
+func (cppe *CPPEmitter) PreVisitGoStmtCall(node *ast.CallExpr, indent int) {
+	cppe.emitToFile("]() mutable { ")
+}
+
+func (cppe *CPPEmitter) PostVisitGoStmtCall(node *ast.CallExpr, indent int) {
+	cppe.emitToFile("; });")
+}
+
+func (cppe *CPPEmitter) PreVisitSendStmt(node *ast.SendStmt, indent int) {
+	cppe.emitToFile(cppe.emitAsString("chan_send(", indent))
+}
+
+func (cppe *CPPEmitter) PreVisitSendStmtValue(node ast.Expr, indent int) {
+	cppe.emitToFile(", ")
+}
+
+func (cppe *CPPEmitter) PostVisitSendStmt(node *ast.SendStmt, indent int) {
+	cppe.emitToFile(");")
+}
+
+// A select evaluates its cases into _selectN_i variables, chan_select runs
+// the first ready one and a switch on its index runs the clause:
+//
+//	auto _select1_0 = select_recv(ch);
+//	switch (chan_select({_select1_0}, false)) {
+//	case 0: {
+//	  auto v = _select1_0->value;
+func (cppe *CPPEmitter) PreVisitSelectStmt(node *ast.SelectStmt, indent int) {
+	cppe.selectCount++
+	cppe.selects = append(cppe.selects, fmt.Sprintf("_select%d", cppe.selectCount))
+	cppe.emitToFile(cppe.emitAsString("{\n", indent))
+}
+
+func (cppe *CPPEmitter) PreVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {
+	op := "select_recv("
+	if _, ok := node.Comm.(*ast.SendStmt); ok {
+		op = "select_send("
+	}
+	name := fmt.Sprintf("%s_%d", cppe.selects[len(cppe.selects)-1], index)
+	cppe.emitToFile(cppe.emitAsString("auto "+name+" = "+op, indent+2))
+}
+
+func (cppe *CPPEmitter) PreVisitSelectStmtCaseValue(node ast.Expr, indent int) {
+	cppe.emitToFile(", ")
+}
+
+func (cppe *CPPEmitter) PostVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {
+	cppe.emitToFile(");\n")
+}
+
+func (cppe *CPPEmitter) PreVisitSelectStmtClauses(node *ast.SelectStmt, indent int) {
+	name := cppe.selects[len(cppe.selects)-1]
+	var cases []string
+	for _, stmt := range node.Body.List {
+		if stmt.(*ast.CommClause).Comm != nil {
+			cases = append(cases, fmt.Sprintf("%s_%d", name, len(cases)))
+		}
+	}
+	str := fmt.Sprintf("switch (chan_select({%s}, %t)) {\n", strings.Join(cases, ", "), selectHasDefault(node))
+	cppe.emitToFile(cppe.emitAsString(str, indent+2))
+}
+
+func (cppe *CPPEmitter) PreVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {
+	if index < 0 {
+		cppe.emitToFile(cppe.emitAsString("default: {\n", indent+2))
+		return
+	}
+	cppe.emitToFile(cppe.emitAsString(fmt.Sprintf("case %d: {\n", index), indent+2))
+	name := fmt.Sprintf("%s_%d", cppe.selects[len(cppe.selects)-1], index)
+	_, _, recv := selectCaseOp(node)
+	value, ok := selectCaseNames(cppe.pkg, cppe.renames, node)
+	for _, v := range [][2]string{{value, "value"}, {ok, "ok"}} {
+		if v[0] == "" {
+			continue
+		}
+		decl := ""
+		if recv.Tok == token.DEFINE {
+			decl = "auto "
+		}
+		cppe.emitToFile(cppe.emitAsString(fmt.Sprintf("%s%s = %s->%s;\n", decl, v[0], name, v[1]), indent+4))
+	}
+	if cells := cppe.closureCellDecls(node, indent+4); cells != "" {
+		cppe.emitToFile(cells + "\n")
+	}
+}
+
+func (cppe *CPPEmitter) PostVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {
+	cppe.emitToFile("\n" + cppe.emitAsString("break;\n", indent+4) + cppe.emitAsString("}\n", indent+2))
+}
+
+func (cppe *CPPEmitter) PostVisitSelectStmt(node *ast.SelectStmt, indent int) {
+	cppe.selects = cppe.selects[:len(cppe.selects)-1]
+	cppe.emitToFile(cppe.emitAsString("}\n", indent+2) + cppe.emitAsString("}", indent))
+}
+
 func (cppe *CPPEmitter) PostVisitBlockStmtList(node ast.Stmt, index int, indent int) {
 	str := cppe.emitAsString("\n", indent)
 	cppe.emitToFile(str)
@@ -900,6 +2565,38 @@ func (cppe *CPPEmitter) PostVisitFuncDecl(node *ast.FuncDecl, indent int) {
 func (cppe *CPPEmitter) PreVisitFuncDeclBody(node *ast.BlockStmt, indent int) {
 	str := cppe.emitAsString("\n", 0)
 	cppe.emitToFile(str)
+	cppe.deferScopes = pushDeferScope(cppe.pkg, cppe.deferScopes, node, &cppe.deferCount)
+	// Methods bind the Go receiver name to *this: a reference for pointer
+	// receivers so mutations are visible to the caller, a copy otherwise
+	if name := recvName(cppe.currentFuncDecl); name != "" {
+		if hasPointerRecv(cppe.currentFuncDecl) {
+			cppe.pendingRecvDecl = fmt.Sprintf("auto& %s = *this;\n", name)
+		} else {
+			cppe.pendingRecvDecl = fmt.Sprintf("auto %s = *this;\n", name)
+		}
+	}
+}
+
+func (cppe *CPPEmitter) PreVisitFuncDeclSignature(node *ast.FuncDecl, indent int) {
+	cppe.currentFuncDecl = node
+	cppe.closureCells = findClosureCells(cppe.pkg, node, nil)
+	addSlicedArrayCells(cppe.pkg, node, cppe.closureCells)
+	cppe.renames = shadowRenames(cppe.pkg, node)
+	// Methods are declared inside their struct, not among the forward declarations
+	if cppe.forwardDecl && !cppe.insideStructMethod && node.Recv != nil {
+		cppe.suppressEmit = true
+	}
+	if !cppe.suppressEmit && !cppe.insideStructMethod {
+		cppe.emitToFile(cppTemplatePrefix(typeParams(cppe.pkg, node)))
+	}
+}
+
+// cppTemplatePrefix declares the type parameters of a generic function or struct
+func cppTemplatePrefix(params *types.TypeParamList) string {
+	if params.Len() == 0 {
+		return ""
+	}
+	return "template <typename " + strings.Join(typeParamNames(params), ", typename ") + ">\n"
 }
 
 func (cppe *CPPEmitter) PreVisitFuncDeclSignatureTypeResults(node *ast.FuncDecl, indent int) {
@@ -922,7 +2619,15 @@ func (cppe *CPPEmitter) PostVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl,
 
 func (cppe *CPPEmitter) PreVisitFuncDeclName(node *ast.Ident, indent int) {
 	DebugLogPrintf("CPPEmitter: PreVisitFuncDeclName: %s", node.Name)
-	str := cppe.emitAsString(node.Name, 0)
+	name := node.Name
+	if recv := recvTypeName(cppe.currentFuncDecl); recv != "" && !cppe.insideStructMethod {
+		// Out-of-line member function definition
+		if params := typeParams(cppe.pkg, cppe.currentFuncDecl); params.Len() > 0 {
+			recv += "<" + strings.Join(typeParamNames(params), ", ") + ">"
+		}
+		name = recv + "::" + name
+	}
+	str := cppe.emitAsString(name, 0)
 	cppe.emitToFile(str)
 }
 
@@ -971,9 +2676,11 @@ func (cppe *CPPEmitter) PostVisitFuncDeclSignature(node *ast.FuncDecl, indent in
 		str := cppe.emitAsString(";\n", 0)
 		cppe.emitToFile(str)
 	}
+	cppe.suppressEmit = false
 }
 
 func (cppe *CPPEmitter) PreVisitGenStructInfo(node GenTypeInfo, indent int) {
+	cppe.emitToFile(cppTemplatePrefix(structTypeParams(cppe.pkg, node.Name)))
 	str := cppe.emitAsString(fmt.Sprintf("struct %s\n", node.Name), 0)
 	err := cppe.emitToFile(str)
 	if err != nil {
@@ -993,6 +2700,76 @@ func (cppe *CPPEmitter) PostVisitGenStructInfo(node GenTypeInfo, indent int) {
 	}
 }
 
+// PreVisitGenStructInfos forward declares all structs, so member function
+// declarations may refer to structs that are defined later
+func (cppe *CPPEmitter) PreVisitGenStructInfos(node []GenTypeInfo, indent int) {
+	for _, info := range node {
+		if info.Struct != nil {
+			cppe.emitToFile(cppTemplatePrefix(structTypeParams(cppe.pkg, info.Name)))
+			cppe.emitToFile(fmt.Sprintf("struct %s;\n", info.Name))
+		}
+	}
+	cppe.emitToFile("\n")
+}
+
+// PreVisitGenStructMethod declares a method as a member function of its struct
+func (cppe *CPPEmitter) PreVisitGenStructMethod(node *ast.FuncDecl, indent int) {
+	cppe.insideStructMethod = true
+	cppe.forwardDecl = true
+	cppe.emitToFile(cppe.emitAsString("", indent))
+}
+
+func (cppe *CPPEmitter) PostVisitGenStructMethod(node *ast.FuncDecl, indent int) {
+	cppe.insideStructMethod = false
+	cppe.forwardDecl = false
+}
+
+// PreVisitGenInterfaceInfo declares an interface as a value type holding a
+// shared pointer to an abstract base. The Impl template adapts any type with
+// the right methods, so structs satisfy the interface implicitly and
+// conversions to it are implicit constructor calls.
+func (cppe *CPPEmitter) PreVisitGenInterfaceInfo(node GenTypeInfo, indent int) {
+	cppe.emitToFile(fmt.Sprintf("struct %s\n{\n", node.Name))
+	cppe.emitToFile("  struct Iface\n  {\n")
+	cppe.emitToFile("    virtual ~Iface() = default;\n")
+	cppe.ifaceImplText = ""
+}
+
+func (cppe *CPPEmitter) PostVisitGenInterfaceInfo(node GenTypeInfo, indent int) {
+	cppe.emitToFile("  };\n")
+	cppe.emitToFile("  template <typename T> struct Impl : Iface\n  {\n")
+	cppe.emitToFile("    T value;\n")
+	cppe.emitToFile("    Impl(T v) : value(v) {}\n")
+	cppe.emitToFile(cppe.ifaceImplText)
+	cppe.emitToFile("  };\n")
+	cppe.emitToFile("  std::shared_ptr<Iface> ptr;\n")
+	cppe.emitToFile(fmt.Sprintf("  %s() = default;\n", node.Name))
+	cppe.emitToFile(fmt.Sprintf("  template <typename T, typename = std::enable_if_t<!std::is_same<T, %s>::value>>\n", node.Name))
+	cppe.emitToFile(fmt.Sprintf("  %s(T v) : ptr(std::make_shared<Impl<T>>(v)) {}\n", node.Name))
+	cppe.emitToFile("  Iface *operator->() const { return ptr.get(); }\n")
+	cppe.emitToFile("};\n\n")
+}
+
+// PreVisitGenInterfaceMethod collects the method signature, it is emitted as
+// a pure virtual function and as a forwarding override in Impl
+func (cppe *CPPEmitter) PreVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {
+	cppe.captureText = true
+	cppe.capturedText = ""
+}
+
+func (cppe *CPPEmitter) PostVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {
+	cppe.captureText = false
+	var args []string
+	for _, param := range node.Type.Params.List {
+		for _, name := range param.Names {
+			args = append(args, name.Name)
+		}
+	}
+	cppe.emitToFile(fmt.Sprintf("    virtual %s = 0;\n", cppe.capturedText))
+	cppe.ifaceImplText += fmt.Sprintf("    %s override { return value.%s(%s); }\n",
+		cppe.capturedText, node.Name.Name, strings.Join(args, ", "))
+}
+
 func (cppe *CPPEmitter) PreVisitFuncDeclSignatures(indent int) {
 	// Generate forward function declarations
 	str := cppe.emitAsString("// Forward declarations\n", 0)
@@ -1020,14 +2797,66 @@ func (cppe *CPPEmitter) PostVisitGenStructFieldName(node *ast.Ident, indent int)
 }
 
 func (cppe *CPPEmitter) PreVisitGenDeclConstName(node *ast.Ident, indent int) {
-	str := cppe.emitAsString(fmt.Sprintf("constexpr auto %s = ", node.Name), 0)
+	str := cppe.emitAsString(fmt.Sprintf("constexpr %s %s = ", cppe.constType(node), node.Name), 0)
 	cppe.emitToFile(str)
 }
+
+// constType returns the declared type of a typed numeric or boolean constant,
+// untyped and string constants keep auto
+func (cppe *CPPEmitter) constType(node *ast.Ident) string {
+	con, ok := cppe.pkg.TypesInfo.Defs[node].(*types.Const)
+	if !ok {
+		return "auto"
+	}
+	basic, ok := con.Type().Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsUntyped != 0 || basic.Info()&types.IsString != 0 {
+		return "auto"
+	}
+	if named, ok := con.Type().(*types.Named); ok {
+		if named.Obj().Pkg() != cppe.pkg.Types {
+			return "auto"
+		}
+		return named.Obj().Name()
+	}
+	name := types.Typ[basic.Kind()].Name()
+	if n, ok := cppTypesMap[name]; ok {
+		return n
+	}
+	if name == "int" || name == "bool" {
+		return name
+	}
+	return "auto"
+}
 func (cppe *CPPEmitter) PostVisitGenDeclConstName(node *ast.Ident, indent int) {
 	str := cppe.emitAsString(";\n", 0)
 	cppe.emitToFile(str)
 }
 
+// Package-level variables become globals. Definitions in one translation unit
+// are initialized in the order they appear, which follows Go's init order
+func (cppe *CPPEmitter) PreVisitGenDeclVar(node PackageVar, indent int) {
+	cppe.currentPackageVar = node
+}
+
+func (cppe *CPPEmitter) PostVisitGenDeclVarType(node ast.Expr, indent int) {
+	cppe.emitToFile(" " + cppe.currentPackageVar.Name.Name)
+}
+
+func (cppe *CPPEmitter) PreVisitGenDeclVarValue(node ast.Expr, indent int) {
+	cppe.emitToFile(" = ")
+}
+
+func (cppe *CPPEmitter) PostVisitGenDeclVar(node PackageVar, indent int) {
+	if node.Value == nil {
+		cppe.emitToFile("{}")
+	}
+	cppe.emitToFile(";\n")
+}
+
+func (cppe *CPPEmitter) PostVisitGenDeclVars(node []PackageVar, indent int) {
+	cppe.emitToFile("\n")
+}
+
 func (cppe *CPPEmitter) PreVisitTypeAliasName(node *ast.Ident, indent int) {
 	cppe.emitToFile(fmt.Sprintf("using "))
 }
@@ -1070,7 +2899,7 @@ func (cppe *CPPEmitter) GenerateMakefile() error {
 	switch graphicsBackend {
 	case "sdl2":
 		makefile = fmt.Sprintf(`CXX = g++
-CXXFLAGS = -O3 -std=c++17 -I%s $(shell sdl2-config --cflags)
+CXXFLAGS = -O3 -std=c++17 -fwrapv -pthread -I%s $(shell sdl2-config --cflags)
 LDFLAGS = $(shell sdl2-config --libs)
 
 TARGET = %s
@@ -1089,7 +2918,7 @@ clean:
 
 	case "none":
 		makefile = fmt.Sprintf(`CXX = g++
-CXXFLAGS = -O3 -std=c++17 -I%s
+CXXFLAGS = -O3 -std=c++17 -fwrapv -pthread -I%s
 LDFLAGS =
 
 TARGET = %s
@@ -1109,7 +2938,7 @@ clean:
 	default: // tigr (default)
 		makefile = fmt.Sprintf(`CXX = g++
 CC = gcc
-CXXFLAGS = -O3 -std=c++17 -I%s
+CXXFLAGS = -O3 -std=c++17 -fwrapv -pthread -I%s
 CFLAGS = -O3
 
 TARGET = %s
@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"golang.org/x/tools/go/packages"
@@ -22,12 +23,25 @@ var csTypesMap = map[string]string{
 	"int64":   destTypes[3],
 	"uint8":   destTypes[4],
 	"uint16":  destTypes[5],
+	"uint32":  "uint",
+	"uint64":  "ulong",
 	"any":     destTypes[6],
 	"string":  destTypes[7],
 	"float32": destTypes[8],
 	"float64": destTypes[9],
 }
 
+// csCastType returns the C# type an expression of type t is cast back to,
+// as C# arithmetic on the small integer types yields int. int and uint need
+// no cast, they are at least as wide as C# int.
+func csCastType(t types.Type) (string, bool) {
+	if isIntType(t) {
+		return "", false
+	}
+	typeVal, ok := csTypesMap[t.String()]
+	return typeVal, ok
+}
+
 type AliasRepr struct {
 	PackageName string // Package name of the alias
 	TypeName    string
@@ -52,11 +66,10 @@ type CSharpEmitter struct {
 	assignmentToken   string
 	forwardDecls      bool
 	shouldGenerate    bool
-	numFuncResults    int
+	funcTypes         []funcTypeTokens
+	funcLitResultStart int
 	aliases           map[string]Alias
 	currentPackage    string
-	isArray           bool
-	arrayType         string
 	isTuple           bool
 	isInfiniteLoop    bool // Track if current for loop is infinite (no init, cond, post)
 	// Key-value range loop support
@@ -77,6 +90,48 @@ type CSharpEmitter struct {
 	currentAliasName           string            // Current type alias name being processed
 	typeAliasMap               map[string]string // Maps alias names to underlying type names
 	suppressTypeAliasSelectorX bool              // Suppress X part emission for type alias selectors
+	// Map support
+	isMapRange       bool
+	isStringRange    bool
+	mapRangeValues   bool
+	isSliceRange     bool
+	insideAssignLhs  bool
+	mapCommaOkExpr   ast.Expr
+	mapMakeHintExpr  ast.Expr
+	sliceMakeLenExpr ast.Expr // Length argument of the make([]T, n) call being emitted
+	mapCompositeLits []bool
+	// Empty slice literals being emitted, []T{} has an array unlike the nil slice
+	emptySliceLits []bool
+	// Token positions of the last map element assignment target m[k],
+	// used to rewrite m[k] op= v and m[k]++ into reads that tolerate missing keys
+	mapLhsStart   int
+	mapLhsBracket int
+	mapLhsEnd     int
+	// Array support
+	arrayLengths     map[int64]bool // Lengths of the ArrayN<T> inline array types to declare
+	arrayCompositeLits []bool
+	// Method support
+	currentFuncDecl *ast.FuncDecl           // Function or method being emitted
+	renames         map[types.Object]string // Locals renamed so they don't shadow an outer variable
+	slicedArray     *ast.Ident              // Sliced array of the slice expression, whose box is sliced
+	newCallArg      ast.Expr                // Type argument of the new(T) call being emitted
+	pendingRecvDecl string                  // Receiver binding to emit at the start of a method body
+	deferScopes     []deferScope            // Enclosing function bodies, innermost last
+	deferCount      int                     // Number of defer stacks named so far
+	// Interface support
+	insideInterface   bool     // Emitting the method signatures of an interface
+	typeAssertCommaOk ast.Expr // Type assertion of a comma-ok assignment (v, ok := x.(T))
+	chanRecvCommaOk   ast.Expr // Receive of a comma-ok assignment (v, ok := <-ch)
+	selectCount       int
+	selects           []string // Names of the select statements being emitted
+	typeSwitches      []csTypeSwitch
+	typeSwitchCount   int
+	currentPackageVar PackageVar        // Package-level variable being declared
+	typeParamEquals   []bool            // Whether each enclosing binary expression compares type parameter values
+	wideShiftCounts   map[ast.Expr]bool // Shift counts wider than int, C# shifts take an int
+	intOps            []intOp           // Integer operations of the binary expressions being emitted, innermost last
+	// Labeled loops
+	continueLabels map[*ast.BlockStmt]string // Bodies of loops targeted by continue L, by label
 }
 
 func (*CSharpEmitter) lowerToBuiltins(selector string) string {
@@ -90,11 +145,25 @@ func (*CSharpEmitter) lowerToBuiltins(selector string) string {
 	case "Printf":
 		return "Formatter.Printf"
 	case "Print":
-		return "Formatter.Printf"
+		return "Formatter.Print"
 	case "len":
 		return "SliceBuiltins.Length"
 	case "append":
 		return "SliceBuiltins.Append"
+	case "cap":
+		return "SliceBuiltins.Cap"
+	case "copy":
+		return "SliceBuiltins.Copy"
+	case "delete":
+		return "MapBuiltins.Delete"
+	case "close":
+		return "ChanBuiltins.Close"
+	case "panic":
+		// PanicBuiltins.Panic returns the exception so that C# sees the
+		// function does not continue
+		return "throw PanicBuiltins.Panic"
+	case "recover":
+		return "PanicBuiltins.Recover"
 	}
 	return selector
 }
@@ -122,7 +191,7 @@ func (*CSharpEmitter) lowerToBuiltins(selector string) string {
 //    - The suppressTypeAliasEmit flag prevents any output during this phase
 //
 // 2. CONVERSION (convertGoTypeToCSharp function):
-//    - Slice types: "[]T" -> "List<T>" (recursive for nested types)
+//    - Slice types: "[]T" -> "Slice<T>" (recursive for nested types)
 //    - Map types: "map[K]V" -> "Dictionary<K, V>"
 //    - Package paths: "uql/ast.Statement" -> "ast.Statement" (strip path prefix)
 //    - Basic types: "int8" -> "sbyte", etc. (via csTypesMap)
@@ -131,7 +200,7 @@ func (*CSharpEmitter) lowerToBuiltins(selector string) string {
 //    - PreVisitIdent: If identifier is in typeAliasMap, emit underlying type
 //    - PreVisitSelectorExpr: If selector (e.g., ast.AST) refers to alias,
 //      set suppressTypeAliasSelectorX=true to skip package prefix
-//    - This transforms "ast.AST" into just "List<ast.Statement>"
+//    - This transforms "ast.AST" into just "Slice<ast.Statement>"
 //
 // 4. SUPPRESSION FLAGS:
 //    - suppressTypeAliasEmit: Blocks PreVisitIdent, PreVisitArrayType,
@@ -147,23 +216,23 @@ func (*CSharpEmitter) lowerToBuiltins(selector string) string {
 //
 //   Generated C#:
 //     // No "using AST = ..." - alias definition produces no output
-//     public static (List<ast.Statement>, sbyte) Parse() {
-//         List<ast.Statement> result = default;
+//     public static (Slice<ast.Statement>, sbyte) Parse() {
+//         Slice<ast.Statement> result = default;
 //         return (result, 0);
 //     }
 //
 // =============================================================================
 
 // convertGoTypeToCSharp converts a Go type string to C# syntax
-// Handles: slices ([]T -> List<T>), package paths (pkg/subpkg.Type -> subpkg.Type), basic type mappings
+// Handles: slices ([]T -> Slice<T>), package paths (pkg/subpkg.Type -> subpkg.Type), basic type mappings
 func (cse *CSharpEmitter) convertGoTypeToCSharp(goType string) string {
 	result := goType
 
-	// Handle slice types: []T -> List<T>
+	// Handle slice types: []T -> Slice<T>
 	if strings.HasPrefix(result, "[]") {
 		elementType := result[2:]
 		elementType = cse.convertGoTypeToCSharp(elementType) // Recursive for nested types
-		return "List<" + elementType + ">"
+		return "Slice<" + elementType + ">"
 	}
 
 	// Handle map types: map[K]V -> Dictionary<K, V>
@@ -272,6 +341,7 @@ func (cse *CSharpEmitter) executeIfNotForwardDecls(fn func()) {
 }
 
 func (cse *CSharpEmitter) PreVisitProgram(indent int) {
+	csTypesMap["int"], csTypesMap["uint"] = intTypeNames("int", "uint", "long", "ulong")
 	cse.aliases = make(map[string]Alias)
 	cse.typeAliasMap = make(map[string]string)
 	outputFile := cse.Output
@@ -288,38 +358,685 @@ func (cse *CSharpEmitter) PreVisitProgram(indent int) {
 		fmt.Println("Error writing to file:", err)
 		return
 	}
-	builtin := `public static class SliceBuiltins
+	builtin := `// A Go slice: Length elements from Offset in a backing array that the slices
+// taken from it share, of which Capacity elements are usable. The default
+// value is the nil slice.
+public struct Slice<T> : IEnumerable<T>
 {
-  public static List<T> Append<T>(this List<T> list, T element)
+  public T[] Array;
+  public int Offset;
+  public int Length;
+  public int Capacity;
+
+  public Slice(T[] array, int offset, int length, int capacity)
+  {
+    Array = array;
+    Offset = offset;
+    Length = length;
+    Capacity = capacity;
+  }
+
+  // []T{}: an empty slice, which unlike the nil slice has an array
+  public Slice(int capacity) : this(new T[capacity], 0, 0, capacity) {}
+
+  public ref T this[long index] => ref SliceBuiltins.At(this, index);
+
+  // Lets a collection initializer build a slice literal. The literal owns its
+  // array, which grows by doubling, while its capacity stays its length as in Go
+  public void Add(T element)
+  {
+    if (Array == null || Length == Array.Length)
+      System.Array.Resize(ref Array, Length == 0 ? 4 : Length * 2);
+    Array[Length] = element;
+    Length++;
+    Capacity = Length;
+  }
+
+  public IEnumerator<T> GetEnumerator()
   {
-    if (list == null) list = new List<T>();
-    list.Add(element);
-    return list;
+    for (int i = 0; i < Length; i++) yield return Array[Offset + i];
   }
 
-  public static List<T> Append<T>(this List<T> list, params T[] elements)
+  // Go compares slices only with nil, the default Slice without an array
+  public static bool operator ==(Slice<T> a, Slice<T> b) => a.Array == b.Array && a.Offset == b.Offset && a.Length == b.Length;
+  public static bool operator !=(Slice<T> a, Slice<T> b) => !(a == b);
+  public override bool Equals(object other) => other is Slice<T> s && this == s;
+  public override int GetHashCode() => HashCode.Combine(Array, Offset, Length);
+
+  IEnumerator IEnumerable.GetEnumerator() => GetEnumerator();
+
+  // make([]T, length, capacity): zero values, the capacity ones usable by append
+  public static Slice<T> Make(long length, long capacity)
+  {
+    if (length < 0)
+      throw PanicBuiltins.RuntimeError("makeslice: len out of range");
+    if (capacity < length)
+      throw PanicBuiltins.RuntimeError("makeslice: cap out of range");
+    var array = new T[capacity];
+    if (typeof(T) == typeof(string))
+      for (long i = 0; i < capacity; i++) array[i] = (T)(object)"";
+    return new Slice<T>(array, 0, (int)length, (int)capacity);
+  }
+
+  public static Slice<T> Make(long length)
+  {
+    return Make(length, length);
+  }
+}
+
+// A sliced array keeps its elements in the backing array its slices share,
+// Value is that array seen as the inline array
+public class ArrayBox<TArray, T> where TArray : struct
+{
+  T[] elements;
+
+  public ArrayBox(TArray value, int length)
+  {
+    elements = new T[length];
+    Value = value;
+  }
+
+  public ref TArray Value => ref System.Runtime.CompilerServices.Unsafe.As<T, TArray>(ref System.Runtime.InteropServices.MemoryMarshal.GetArrayDataReference(elements));
+
+  public Slice<T> Elements() => new Slice<T>(elements, 0, elements.Length, elements.Length);
+}
+
+public static class SliceBuiltins
+{
+  public static Slice<T> Append<T>(this Slice<T> s, T element)
   {
-    if (list == null) list = new List<T>();
-    list.AddRange(elements);
-    return list;
+    s = Grow(s, 1);
+    s.Array[s.Offset + s.Length - 1] = element;
+    return s;
   }
 
-  public static List<T> Append<T>(this List<T> list, List<T> elements)
+  public static Slice<T> Append<T>(this Slice<T> s, params T[] elements)
   {
-    if (list == null) list = new List<T>();
-    list.AddRange(elements);
-    return list;
+    s = Grow(s, elements.Length);
+    System.Array.Copy(elements, 0, s.Array, s.Offset + s.Length - elements.Length, elements.Length);
+    return s;
+  }
+
+  // append(s, t...), t may share the backing array of s
+  public static Slice<T> Append<T>(this Slice<T> s, Slice<T> elements)
+  {
+    s = Grow(s, elements.Length);
+    if (elements.Length > 0)
+      System.Array.Copy(elements.Array, elements.Offset, s.Array, s.Offset + s.Length - elements.Length, elements.Length);
+    return s;
+  }
+
+  // Extends s by n elements, in its backing array while the capacity allows
+  // and otherwise in a new array of twice the capacity
+  static Slice<T> Grow<T>(Slice<T> s, int n)
+  {
+    int length = s.Length + n;
+    if (length > s.Capacity)
+    {
+      var array = new T[Math.Max(length, s.Capacity * 2)];
+      if (typeof(T) == typeof(string))
+        for (int i = s.Length; i < array.Length; i++) array[i] = (T)(object)"";
+      if (s.Length > 0)
+        System.Array.Copy(s.Array, s.Offset, array, 0, s.Length);
+      s = new Slice<T>(array, 0, s.Length, array.Length);
+    }
+    s.Length = length;
+    return s;
+  }
+
+  public static ` + csTypesMap["int"] + ` Length<T>(Slice<T> s)
+  {
+    return s.Length;
   }
 
   // Fix: Ensure Length works for collections and not generic T
-  public static int Length<T>(ICollection<T> collection)
+  public static ` + csTypesMap["int"] + ` Length<T>(ICollection<T> collection)
   {
     return collection == null ? 0 : collection.Count;
   }
-  public static int Length(string s)
+  public static ` + csTypesMap["int"] + ` Length(string s)
   {
     return s == null ? 0 : s.Length;
   }
+
+  public static ` + csTypesMap["int"] + ` Cap<T>(Slice<T> s)
+  {
+    return s.Capacity;
+  }
+
+  // copy(dst, src) copies as many elements as both have and returns how
+  // many, the two may overlap
+  public static ` + csTypesMap["int"] + ` Copy<T>(Slice<T> dst, Slice<T> src)
+  {
+    int n = Math.Min(dst.Length, src.Length);
+    if (n > 0)
+      System.Array.Copy(src.Array, src.Offset, dst.Array, dst.Offset, n);
+    return n;
+  }
+
+  // s[low:high:max] shares the backing array of s
+  public static Slice<T> Slice<T>(Slice<T> s, long low, long high, long max)
+  {
+    CheckSliceBounds(low, high, max, s.Capacity, true, "capacity");
+    return new Slice<T>(s.Array, s.Offset + (int)low, (int)(high - low), (int)(max - low));
+  }
+
+  public static Slice<T> Slice<T>(Slice<T> s, long low, long high)
+  {
+    CheckSliceBounds(low, high, s.Capacity, s.Capacity, false, "capacity");
+    return new Slice<T>(s.Array, s.Offset + (int)low, (int)(high - low), s.Capacity - (int)low);
+  }
+
+  public static Slice<T> Slice<T>(Slice<T> s, long low)
+  {
+    return Slice(s, low, s.Length);
+  }
+
+  public static string Slice(string s, long low, long high)
+  {
+    CheckSliceBounds(low, high, Length(s), Length(s), false, "length");
+    return s.Substring((int)low, (int)(high - low));
+  }
+
+  public static string Slice(string s, long low)
+  {
+    return Slice(s, low, Length(s));
+  }
+
+  // Checks low <= high <= max <= limit, the capacity of a slice or the
+  // length of a string, and panics with Go's message otherwise
+  static void CheckSliceBounds(long low, long high, long max, long limit, bool slice3, string limitName)
+  {
+    if (slice3 && (max < 0 || max > limit))
+      throw PanicBuiltins.RuntimeError("slice bounds out of range [::" + max + "] with " + limitName + " " + limit);
+    if (!slice3 && (high < 0 || high > limit))
+      throw PanicBuiltins.RuntimeError("slice bounds out of range [:" + high + "] with " + limitName + " " + limit);
+    if (slice3 && (high < 0 || high > max))
+      throw PanicBuiltins.RuntimeError("slice bounds out of range [:" + high + ":" + max + "]");
+    if (low < 0 || low > high)
+      throw PanicBuiltins.RuntimeError("slice bounds out of range [" + low + ":" + high + (slice3 ? ":]" : "]"));
+  }
+
+  // Elements of a slice, none for a nil slice
+  public static IEnumerable<T> Range<T>(Slice<T> s)
+  {
+    return s;
+  }
+
+  // Byte offsets and runes of a string, as range over a string yields them.
+  // Offsets count UTF-8 bytes, a lone surrogate decodes to U+FFFD.
+  public static IEnumerable<(` + csTypesMap["int"] + `, int)> Range(string s)
+  {
+    ` + csTypesMap["int"] + ` offset = 0;
+    for (int i = 0; i < s.Length; i++)
+    {
+      int r = s[i];
+      if (char.IsHighSurrogate(s[i]) && i + 1 < s.Length && char.IsLowSurrogate(s[i + 1]))
+      {
+        r = char.ConvertToUtf32(s[i], s[i + 1]);
+        i++;
+      }
+      else if (char.IsSurrogate(s[i]))
+      {
+        r = 0xFFFD;
+      }
+      yield return (offset, r);
+      offset += r < 0x80 ? 1 : r < 0x800 ? 2 : r < 0x10000 ? 3 : 4;
+    }
+  }
+
+  // Element of a slice, checked like Go checks every index. The reference
+  // lets the element be assigned or mutated in place.
+  public static ref T At<T>(Slice<T> s, long index)
+  {
+    CheckIndex(index, s.Length);
+    return ref s.Array[s.Offset + (int)index];
+  }
+
+  public static char At(string s, long index)
+  {
+    CheckIndex(index, Length(s));
+    return s[(int)index];
+  }
+
+  static void CheckIndex(long index, long length)
+  {
+    if (index < 0)
+      throw PanicBuiltins.RuntimeError("index out of range [" + index + "]");
+    if (index >= length)
+      throw PanicBuiltins.RuntimeError("index out of range [" + index + "] with length " + length);
+  }
+}
+public static class IntBuiltins
+{
+  // Go shifts: a count of at least the width of x shifts out every bit, a
+  // negative count panics
+  public static T Shl<T>(T x, long n) where T : System.Numerics.IBinaryInteger<T>
+  {
+    return ShiftCount(n) >= Bits<T>() ? T.Zero : x << (int)n;
+  }
+
+  public static T Shl<T>(T x, ulong n) where T : System.Numerics.IBinaryInteger<T>
+  {
+    return n >= (ulong)Bits<T>() ? T.Zero : x << (int)n;
+  }
+
+  public static T Shr<T>(T x, long n) where T : System.Numerics.IBinaryInteger<T>
+  {
+    return ShiftCount(n) >= Bits<T>() ? (T.IsNegative(x) ? -T.One : T.Zero) : x >> (int)n;
+  }
+
+  public static T Shr<T>(T x, ulong n) where T : System.Numerics.IBinaryInteger<T>
+  {
+    return n >= (ulong)Bits<T>() ? (T.IsNegative(x) ? -T.One : T.Zero) : x >> (int)n;
+  }
+
+  // Go division: the most negative value divided by -1 is itself, with a
+  // remainder of 0, where .NET throws
+  public static T Quo<T>(T x, T y) where T : System.Numerics.IBinaryInteger<T>
+  {
+    return y == -T.One ? -x : x / y;
+  }
+
+  public static T Rem<T>(T x, T y) where T : System.Numerics.IBinaryInteger<T>
+  {
+    return y == -T.One ? T.Zero : x % y;
+  }
+
+  static long ShiftCount(long n)
+  {
+    if (n < 0)
+      throw PanicBuiltins.RuntimeError("negative shift amount");
+    return n;
+  }
+
+  static int Bits<T>() where T : System.Numerics.IBinaryInteger<T>
+  {
+    return T.Zero.GetByteCount() * 8;
+  }
+}
+public static class MapBuiltins
+{
+  // Go map lookup: a missing key yields the zero value
+  public static V Get<K, V>(Dictionary<K, V> m, K key)
+  {
+    V value;
+    if (m != null && m.TryGetValue(key, out value)) return value;
+    return default(V);
+  }
+
+  // Comma-ok lookup: v, ok := m[k]
+  public static (V, bool) GetOk<K, V>(Dictionary<K, V> m, K key)
+  {
+    V value;
+    if (m != null && m.TryGetValue(key, out value)) return (value, true);
+    return (default(V), false);
+  }
+
+  public static void Delete<K, V>(Dictionary<K, V> m, K key)
+  {
+    if (m != null) m.Remove(key);
+  }
+
+  // Keys in ascending order, so range over a map is the same in every backend
+  public static List<K> Keys<K, V>(Dictionary<K, V> m)
+  {
+    List<K> keys = m == null ? new List<K>() : new List<K>(m.Keys);
+    if (typeof(K) == typeof(string))
+      keys.Sort((a, b) => string.CompareOrdinal((string)(object)a, (string)(object)b));
+    else
+      keys.Sort();
+    return keys;
+  }
+
+  // Values in ascending key order
+  public static List<V> Values<K, V>(Dictionary<K, V> m)
+  {
+    List<V> values = new List<V>();
+    foreach (var key in Keys(m)) values.Add(m[key]);
+    return values;
+  }
+}
+public static class FuncBuiltins
+{
+  // A lambda called in place takes the delegate type of its parameters and result
+  public static T Lambda<T>(T f) where T : Delegate => f;
+}
+public static class InterfaceBuiltins
+{
+  // Comma-ok type assertion: v, ok := x.(T)
+  public static (T, bool) AssertOk<T>(object value)
+  {
+    if (value is T result) return (result, true);
+    return (default(T), false);
+  }
+}
+// A pointer to a struct, the box holding the struct value
+public class Ptr<T>
+{
+  public T Value;
+
+  public Ptr(T value)
+  {
+    Value = value;
+  }
+}
+public static class Ptr
+{
+  // &x and new(T) box a copy of the value
+  public static Ptr<T> New<T>(T value)
+  {
+    return new Ptr<T>(value);
+  }
+}
+// A Go panic: the value passed to panic() and the message printed when
+// nothing recovers it
+public class GoPanic : Exception
+{
+  public object Value;
+  public bool Recovered;
+
+  public GoPanic(object value, string message) : base(message)
+  {
+    Value = value;
+  }
+}
+public static class PanicBuiltins
+{
+  // The panic whose deferred calls are running, recover() stops it
+  [ThreadStatic] static GoPanic recoverable;
+
+  public static GoPanic Panic(object value)
+  {
+    return new GoPanic(value, Message(value));
+  }
+
+  // Panics raised by the runtime carry their message as the value
+  public static GoPanic RuntimeError(string message)
+  {
+    message = "runtime error: " + message;
+    return new GoPanic(message, message);
+  }
+
+  public static object Recover()
+  {
+    if (recoverable == null || recoverable.Recovered) return null;
+    recoverable.Recovered = true;
+    return recoverable.Value;
+  }
+
+  // Panic values print like Go prints them
+  static string Message(object value)
+  {
+    switch (value)
+    {
+      case string s: return s;
+      case bool b: return b ? "true" : "false";
+      case double d: return d.ToString("+0.000000e+000;-0.000000e+000", System.Globalization.CultureInfo.InvariantCulture);
+      case float f: return ((double)f).ToString("+0.000000e+000;-0.000000e+000", System.Globalization.CultureInfo.InvariantCulture);
+      case sbyte or byte or short or ushort or int or uint or long or ulong: return value.ToString();
+      default: return "(panic value)";
+    }
+  }
+
+  // Exceptions of the .NET runtime become the panics Go raises for them
+  static GoPanic ToPanic(Exception e)
+  {
+    switch (e)
+    {
+      case GoPanic p: return p;
+      case DivideByZeroException: return RuntimeError("integer divide by zero");
+      case NullReferenceException: return RuntimeError("invalid memory address or nil pointer dereference");
+      case InvalidCastException: return new GoPanic("interface conversion: interface is not the asserted type", "interface conversion: interface is not the asserted type");
+      default: return new GoPanic(e.Message, e.Message);
+    }
+  }
+
+  // Runs the deferred calls of a function unwinding with e, recover() in
+  // them stops the panic. Reports whether it was recovered.
+  public static bool Recovered(Exception e, Stack<Action> defers)
+  {
+    GoPanic p = ToPanic(e);
+    GoPanic outer = recoverable;
+    recoverable = p;
+    try
+    {
+      while (defers.Count > 0) defers.Pop()();
+    }
+    finally
+    {
+      recoverable = outer;
+    }
+    return p.Recovered;
+  }
+
+  // An unrecovered panic prints its message and exits with status 2, like Go
+  public static void Exit(Exception e)
+  {
+    Console.Out.Flush();
+    Console.Error.WriteLine("panic: " + ToPanic(e).Message);
+    Environment.Exit(2);
+  }
+}
+// Goroutines run on threads, one at a time: the running goroutine holds the
+// scheduler lock and releases it only while it waits for a channel. When
+// every goroutine waits, none is left to wake the others.
+public static class Scheduler
+{
+  static readonly object Lock = new object();
+  static int alive = 1;
+  // Goroutines waiting since the last change, a change wakes all of them
+  static int waiting = 0;
+  static long changes = 0;
+
+  // Initialized by the main goroutine, which runs first
+  static Scheduler()
+  {
+    System.Threading.Monitor.Enter(Lock);
+  }
+
+  // go f(): runs f in a new goroutine
+  public static void Go(Action f)
+  {
+    alive++;
+    var thread = new System.Threading.Thread(() =>
+    {
+      System.Threading.Monitor.Enter(Lock);
+      try
+      {
+        f();
+      }
+      catch (Exception e)
+      {
+        PanicBuiltins.Exit(e);
+      }
+      alive--;
+      if (waiting > 0 && waiting == alive) Deadlock();
+      System.Threading.Monitor.Exit(Lock);
+    });
+    // Goroutines still waiting do not keep the program running
+    thread.IsBackground = true;
+    thread.Start();
+  }
+
+  static void Deadlock()
+  {
+    Console.Out.Flush();
+    Console.Error.WriteLine("fatal error: all goroutines are asleep - deadlock!");
+    Environment.Exit(2);
+  }
+
+  // Wakes the goroutines waiting for a channel to change
+  public static void NotifyChange()
+  {
+    changes++;
+    waiting = 0;
+    System.Threading.Monitor.PulseAll(Lock);
+  }
+
+  // Waits for a channel to change, letting the other goroutines run
+  public static void WaitForChange()
+  {
+    if (++waiting == alive) Deadlock();
+    long seen = changes;
+    while (changes == seen) System.Threading.Monitor.Wait(Lock);
+  }
+
+  // Operations on a nil channel block forever
+  public static void BlockForever()
+  {
+    while (true) WaitForChange();
+  }
+}
+// A channel, null is the nil channel
+public class Chan<T>
+{
+  internal Queue<T> Items = new Queue<T>();
+  internal int Capacity;
+  internal bool Closed;
+  // An unbuffered send waits until its item is received
+  internal long Sent;
+  internal long Received;
+  // Goroutines waiting to receive, an unbuffered send in a select needs one
+  internal int Receivers;
+
+  public Chan() { }
+
+  public Chan(int capacity)
+  {
+    Capacity = capacity;
+  }
+
+  internal bool CanSend()
+  {
+    return Closed || (Capacity > 0 ? Items.Count < Capacity : Receivers > Items.Count);
+  }
+
+  internal bool CanRecv()
+  {
+    return Closed || Items.Count > 0;
+  }
+
+  internal void Send(T value)
+  {
+    if (Closed) throw PanicBuiltins.Panic("send on closed channel");
+    Items.Enqueue(value);
+    long seq = ++Sent;
+    Scheduler.NotifyChange();
+    while (Capacity == 0 && Received < seq) Scheduler.WaitForChange();
+  }
+
+  // The zero value and false once the channel is closed and drained
+  internal (T, bool) Recv()
+  {
+    if (Items.Count == 0)
+    {
+      return (typeof(T) == typeof(string) ? (T)(object)"" : default(T), false);
+    }
+    T value = Items.Dequeue();
+    Received++;
+    Scheduler.NotifyChange();
+    return (value, true);
+  }
+}
+// A case of a select statement, the cases of a nil channel are never ready
+public abstract class SelectCase
+{
+  internal abstract bool Ready();
+  internal abstract void Commit();
+  internal virtual void Waiting(int delta) { }
+}
+public class SelectRecv<T> : SelectCase
+{
+  internal Chan<T> Chan;
+  public T Value;
+  public bool Ok;
+
+  internal override bool Ready() { return Chan != null && Chan.CanRecv(); }
+  internal override void Commit() { (Value, Ok) = Chan.Recv(); }
+  internal override void Waiting(int delta)
+  {
+    if (Chan != null) Chan.Receivers += delta;
+  }
+}
+public class SelectSend<T> : SelectCase
+{
+  internal Chan<T> Chan;
+  internal T Value;
+
+  internal override bool Ready() { return Chan != null && Chan.CanSend(); }
+  internal override void Commit() { Chan.Send(Value); }
+}
+public static class ChanBuiltins
+{
+  public static void Send<T>(Chan<T> ch, T value)
+  {
+    if (ch == null) Scheduler.BlockForever();
+    while (!ch.Closed && ch.Capacity > 0 && ch.Items.Count >= ch.Capacity) Scheduler.WaitForChange();
+    ch.Send(value);
+  }
+
+  // Comma-ok receive: v, ok := <-ch
+  public static (T, bool) RecvOk<T>(Chan<T> ch)
+  {
+    if (ch == null) Scheduler.BlockForever();
+    ch.Receivers++;
+    Scheduler.NotifyChange();
+    while (!ch.CanRecv()) Scheduler.WaitForChange();
+    ch.Receivers--;
+    return ch.Recv();
+  }
+
+  public static T Recv<T>(Chan<T> ch)
+  {
+    return RecvOk(ch).Item1;
+  }
+
+  public static void Close<T>(Chan<T> ch)
+  {
+    if (ch == null) throw PanicBuiltins.Panic("close of nil channel");
+    if (ch.Closed) throw PanicBuiltins.Panic("close of closed channel");
+    ch.Closed = true;
+    Scheduler.NotifyChange();
+  }
+
+  public static SelectRecv<T> SelectRecv<T>(Chan<T> ch)
+  {
+    return new SelectRecv<T> { Chan = ch };
+  }
+
+  public static SelectSend<T> SelectSend<T>(Chan<T> ch, T value)
+  {
+    return new SelectSend<T> { Chan = ch, Value = value };
+  }
+
+  // Runs the first ready case of a select, waiting for one unless the
+  // select has a default. Returns the index of the case, -1 for default.
+  public static int Select(bool hasDefault, params SelectCase[] cases)
+  {
+    bool registered = false;
+    while (true)
+    {
+      for (int i = 0; i < cases.Length; i++)
+      {
+        if (!cases[i].Ready()) continue;
+        if (registered)
+        {
+          foreach (var c in cases) c.Waiting(-1);
+        }
+        cases[i].Commit();
+        return i;
+      }
+      if (hasDefault) return -1;
+      if (!registered)
+      {
+        registered = true;
+        foreach (var c in cases) c.Waiting(1);
+        Scheduler.NotifyChange();
+      }
+      Scheduler.WaitForChange();
+    }
+  }
 }
 public class Formatter {
     public static void Printf(string format, params object[] args)
@@ -371,6 +1088,14 @@ public class Formatter {
         Console.Write(string.Format(converted, formattedArgs.ToArray()));
     }
 
+    public static void Print(object value)
+    {
+        if (value is string s)
+            Printf(s);
+        else
+            Console.Write(value);
+    }
+
     public static string Sprintf(string format, params object[] args)
      {
         int argIndex = 0;
@@ -429,6 +1154,7 @@ public class Formatter {
 
 func (cse *CSharpEmitter) PostVisitProgram(indent int) {
 	emitTokensToFile(cse.file, cse.gir.tokenSlice)
+	cse.emitArrayTypes()
 	cse.file.Close()
 
 	// Generate .NET project files if link-runtime is enabled
@@ -442,6 +1168,64 @@ func (cse *CSharpEmitter) PostVisitProgram(indent int) {
 	}
 }
 
+// emitArrayTypes declares an inline array struct ArrayN<T> for each length
+// of a Go array type [N]T. Inline arrays are value types, so assignment and
+// argument passing copy the elements the way Go copies arrays.
+func (cse *CSharpEmitter) emitArrayTypes() {
+	lengths := make([]int64, 0, len(cse.arrayLengths))
+	for length := range cse.arrayLengths {
+		lengths = append(lengths, length)
+	}
+	sort.Slice(lengths, func(i, j int) bool { return lengths[i] < lengths[j] })
+	for _, length := range lengths {
+		name := fmt.Sprintf("Array%d", length)
+		cse.file.WriteString(fmt.Sprintf(`
+[System.Runtime.CompilerServices.InlineArray(%[2]d)]
+public struct %[1]s<T>
+{
+  private T _element0;
+
+  // Zero value: strings are empty and nested arrays hold their zero values
+  public %[1]s()
+  {
+    if (typeof(T) == typeof(string))
+    {
+      for (int i = 0; i < %[2]d; i++) this[i] = (T)(object)"";
+    }
+    else if (typeof(T).IsDefined(typeof(System.Runtime.CompilerServices.InlineArrayAttribute), false))
+    {
+      for (int i = 0; i < %[2]d; i++) this[i] = Activator.CreateInstance<T>();
+    }
+  }
+
+  public ArrayBox<%[1]s<T>, T> Box() => new(this, %[2]d);
+
+  public static %[1]s<T> Of(params T[] elements)
+  {
+    var array = new %[1]s<T>();
+    for (int i = 0; i < elements.Length; i++) array[i] = elements[i];
+    return array;
+  }
+
+  public static bool operator ==(%[1]s<T> a, %[1]s<T> b)
+  {
+    for (int i = 0; i < %[2]d; i++)
+    {
+      if (!EqualityComparer<T>.Default.Equals(a[i], b[i])) return false;
+    }
+    return true;
+  }
+
+  public static bool operator !=(%[1]s<T> a, %[1]s<T> b) => !(a == b);
+
+  public override bool Equals(object obj) => obj is %[1]s<T> other && this == other;
+
+  public override int GetHashCode() => 0;
+}
+`, name, length))
+	}
+}
+
 func (cse *CSharpEmitter) PreVisitFuncDeclSignatures(indent int) {
 	cse.forwardDecls = true
 }
@@ -458,6 +1242,10 @@ func (cse *CSharpEmitter) PreVisitFuncDeclName(node *ast.Ident, indent int) {
 		} else {
 			str = cse.emitAsString(fmt.Sprintf("%s", node.Name), 0)
 		}
+		// Methods of a generic struct use the type parameters of the struct
+		if fn, ok := cse.pkg.TypesInfo.Defs[node].(*types.Func); ok {
+			str += csTypeParams(fn.Type().(*types.Signature).TypeParams())
+		}
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
@@ -468,9 +1256,22 @@ func (cse *CSharpEmitter) PreVisitBlockStmt(node *ast.BlockStmt, indent int) {
 		str := cse.emitAsString("\n", 1)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 
+		if cse.pendingRecvDecl != "" {
+			cse.gir.emitToFileBuffer(cse.emitAsString(cse.pendingRecvDecl, indent+2), EmptyVisitMethod)
+			cse.pendingRecvDecl = ""
+		}
 		// If we have a pending value declaration from key-value range, emit it now
 		if cse.pendingRangeValueDecl {
-			valueDecl := cse.emitAsString(fmt.Sprintf("var %s = %s[%s];\n",
+			valueFormat := "var %s = %s[%s];\n"
+			if IntSize == 64 {
+				// The key is a long, C# indexers take an int
+				valueFormat = "var %s = %s[(int)%s];\n"
+			}
+			if cse.isMapRange {
+				valueFormat = "var %s = MapBuiltins.Get(%s, %s);\n"
+				cse.isMapRange = false
+			}
+			valueDecl := cse.emitAsString(fmt.Sprintf(valueFormat,
 				cse.pendingValueName, cse.pendingCollectionExpr, cse.pendingKeyName), indent+2)
 			cse.gir.emitToFileBuffer(valueDecl, EmptyVisitMethod)
 			cse.pendingRangeValueDecl = false
@@ -478,13 +1279,68 @@ func (cse *CSharpEmitter) PreVisitBlockStmt(node *ast.BlockStmt, indent int) {
 			cse.pendingCollectionExpr = ""
 			cse.pendingKeyName = ""
 		}
+		// The body of a loop targeted by continue L is nested in a block,
+		// the label ending the loop body follows it
+		if _, ok := cse.continueLabels[node]; ok {
+			cse.gir.emitToFileBuffer(cse.emitAsString("{\n", indent+2), EmptyVisitMethod)
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitFuncBodyStmts(node *ast.BlockStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if isDeferScopeBody(cse.deferScopes, node) {
+			if isMainBody(cse.pkg, cse.currentFuncDecl, node) {
+				cse.gir.emitToFileBuffer(cse.emitAsString("try {\n", indent+2), EmptyVisitMethod)
+			}
+			if stack := currentDeferStack(cse.deferScopes); stack != "" {
+				str := cse.emitAsString("var "+stack+" = new Stack<Action>();\n", indent+2)
+				str += cse.emitAsString("try {\n", indent+2)
+				cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+			}
+		}
 	})
 }
 
 func (cse *CSharpEmitter) PostVisitBlockStmt(node *ast.BlockStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if isDeferScopeBody(cse.deferScopes, node) {
+			if scope := currentDeferScope(cse.deferScopes); scope.stack != "" {
+				str := ""
+				if scope.recovers {
+					str += cse.emitAsString("} catch (Exception _panic) {\n", indent+2)
+					str += cse.emitAsString("if (!PanicBuiltins.Recovered(_panic, "+scope.stack+")) throw;\n", indent+4)
+				}
+				str += cse.emitAsString("} finally {\n", indent+2)
+				str += cse.emitAsString("while ("+scope.stack+".Count > 0) "+scope.stack+".Pop()();\n", indent+4)
+				str += cse.emitAsString("}\n", indent+2)
+				// A recovered panic returns the zero values from the function,
+				// the current ones of named results
+				if names := resultNames(enclosingFuncResults(cse.pkg, cse.currentFuncDecl, node.Lbrace+1)); names != nil {
+					value := strings.Join(names, ", ")
+					if len(names) > 1 {
+						value = "(" + value + ")"
+					}
+					str += cse.emitAsString(scope.label+": return "+value+";\n", indent+2)
+				} else if scope.recovers && funcBodyHasResults(cse.pkg, cse.currentFuncDecl, node) {
+					str += cse.emitAsString("return default;\n", indent+2)
+				}
+				cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+			}
+			if isMainBody(cse.pkg, cse.currentFuncDecl, node) {
+				str := cse.emitAsString("} catch (Exception _panic) {\n", indent+2)
+				str += cse.emitAsString("PanicBuiltins.Exit(_panic);\n", indent+4)
+				str += cse.emitAsString("}\n", indent+2)
+				cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+			}
+			cse.deferScopes = cse.deferScopes[:len(cse.deferScopes)-1]
+		}
+		if label, ok := cse.continueLabels[node]; ok {
+			str := cse.emitAsString("}\n", indent+2)
+			str += cse.emitAsString(branchLabel(label, token.CONTINUE)+":;\n", indent+2)
+			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		}
 		cse.emitToken("}", RightBrace, 1)
-		cse.isArray = false
 	})
 }
 
@@ -497,9 +1353,33 @@ func (cse *CSharpEmitter) PreVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl
 func (cse *CSharpEmitter) PostVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		cse.emitToken(")", RightParen, 0)
+		if node.Recv == nil && !cse.insideInterface {
+			cse.gir.emitToFileBuffer(csConstraints(typeParams(cse.pkg, node)), EmptyVisitMethod)
+		}
 	})
 }
 
+// csTypeParams lists the type parameters of a generic function or struct
+func csTypeParams(params *types.TypeParamList) string {
+	if params.Len() == 0 {
+		return ""
+	}
+	return "<" + strings.Join(typeParamNames(params), ", ") + ">"
+}
+
+// csConstraints constrains type parameters of numeric unions to INumber, so
+// that they support arithmetic and comparisons
+func csConstraints(params *types.TypeParamList) string {
+	var str string
+	for i := 0; i < params.Len(); i++ {
+		if param := params.At(i); isNumericConstraint(param) {
+			name := param.Obj().Name()
+			str += fmt.Sprintf(" where %s : System.Numerics.INumber<%s>", name, name)
+		}
+	}
+	return str
+}
+
 func (cse *CSharpEmitter) PreVisitIdent(e *ast.Ident, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		if !cse.shouldGenerate {
@@ -526,15 +1406,18 @@ func (cse *CSharpEmitter) PreVisitIdent(e *ast.Ident, indent int) {
 			}
 		}
 		// Capture to buffer during range collection expression visit
+		name := cse.slicedArrayUse(e, localName(cse.pkg, cse.renames, e))
 		if cse.captureRangeExpr {
-			cse.rangeCollectionExpr += e.Name
+			cse.rangeCollectionExpr += name
 			return
 		}
 		var str string
-		name := e.Name
 		name = cse.lowerToBuiltins(name)
 		if name == "nil" {
 			str = cse.emitAsString("default", indent)
+			if isPointerExpr(cse.pkg, e) || isFuncExpr(cse.pkg, e) || isAnyExpr(cse.pkg, e) {
+				str = cse.emitAsString("null", indent)
+			}
 		} else {
 			if n, ok := csTypesMap[name]; ok {
 				str = cse.emitAsString(n, indent)
@@ -550,12 +1433,54 @@ func (cse *CSharpEmitter) PreVisitIdent(e *ast.Ident, indent int) {
 	})
 }
 
+// slicedArrayUse returns how a use of a sliced array reaches it: as the Value
+// of its box, whose elements a slice expression slices
+func (cse *CSharpEmitter) slicedArrayUse(e *ast.Ident, name string) string {
+	if e == cse.slicedArray {
+		return "_" + name + ".Elements()"
+	}
+	if isSlicedArray(cse.pkg, cse.pkg.TypesInfo.Uses[e]) {
+		return "_" + name + ".Value"
+	}
+	return name
+}
+
+// slicedArrayBoxes moves the sliced arrays declared by node, a statement,
+// into the boxes their slices share
+func (cse *CSharpEmitter) slicedArrayBoxes(node ast.Node) string {
+	str := ""
+	for _, ident := range declaredIdents(node) {
+		if obj := cse.pkg.TypesInfo.Defs[ident]; isSlicedArray(cse.pkg, obj) {
+			name := objectName(cse.renames, obj)
+			str += fmt.Sprintf(" var _%s = %s.Box();", name, name)
+		}
+	}
+	return str
+}
+
+// isActionFuncLit reports whether node is a function literal without
+// parameters or results, called in place as in defer func() { ... }()
+func isActionFuncLit(node ast.Expr) bool {
+	lit, ok := node.(*ast.FuncLit)
+	return ok && lit.Type.Params.NumFields() == 0 && lit.Type.Results.NumFields() == 0
+}
+
+// isTypedFuncLit reports whether node is a function literal with parameters
+// or results, called in place as in go func(x int) { ... }(i)
+func isTypedFuncLit(node ast.Expr) bool {
+	_, ok := node.(*ast.FuncLit)
+	return ok && !isActionFuncLit(node)
+}
+
 // isTypeConversion tracks if current call expression is a type conversion
 var csIsTypeConversion bool
 var csSuppressTypeCastIdent bool
 
 func (cse *CSharpEmitter) PreVisitCallExpr(node *ast.CallExpr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if isBuiltinCall(cse.pkg, node, "new") {
+			cse.newCallArg = node.Args[0]
+		}
 		// Check if this is a type conversion (Fun is an Ident that's a type name)
 		if ident, ok := node.Fun.(*ast.Ident); ok {
 			// Check if it's a known type
@@ -577,6 +1502,14 @@ func (cse *CSharpEmitter) PreVisitCallExpr(node *ast.CallExpr, indent int) {
 
 func (cse *CSharpEmitter) PreVisitCallExprFun(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		// make(map[K]V) is emitted as new Dictionary<K, V>() and make(chan T)
+		// as new Chan<T>(), so skip "make",
+		// and new(T) as Ptr.New(new T())
+		if ident, ok := node.(*ast.Ident); ok && (ident.Name == "make" || ident.Name == "new") {
+			if tv, ok := cse.pkg.TypesInfo.Types[ident]; ok && tv.IsBuiltin() {
+				csSuppressTypeCastIdent = true
+			}
+		}
 		// If this is a type conversion, emit cast syntax: (type)
 		if csIsTypeConversion {
 			if ident, ok := node.(*ast.Ident); ok {
@@ -592,11 +1525,23 @@ func (cse *CSharpEmitter) PreVisitCallExprFun(node ast.Expr, indent int) {
 				csSuppressTypeCastIdent = true
 			}
 		}
+		// A lambda has no type of its own, cast it to a delegate to call it.
+		// FuncBuiltins.Lambda gives one with parameters its natural type
+		if isActionFuncLit(node) {
+			cse.gir.emitToFileBuffer("((Action)(", EmptyVisitMethod)
+		} else if isTypedFuncLit(node) {
+			cse.gir.emitToFileBuffer("FuncBuiltins.Lambda(", EmptyVisitMethod)
+		}
 	})
 }
 
 func (cse *CSharpEmitter) PostVisitCallExprFun(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if isActionFuncLit(node) {
+			cse.gir.emitToFileBuffer("))", EmptyVisitMethod)
+		} else if isTypedFuncLit(node) {
+			cse.gir.emitToFileBuffer(")", EmptyVisitMethod)
+		}
 		// Clear the suppression flag after the Fun expression is traversed
 		csSuppressTypeCastIdent = false
 	})
@@ -604,12 +1549,37 @@ func (cse *CSharpEmitter) PostVisitCallExprFun(node ast.Expr, indent int) {
 
 func (cse *CSharpEmitter) PreVisitCallExprArgs(node []ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		// make([]T, n, c) -> Slice<T>.Make(n, c)
+		if len(node) > 0 && isSliceTypeExpr(cse.pkg, node[0]) {
+			cse.sliceMakeLenExpr = node[1]
+			return
+		}
+		if len(node) > 0 && (isMapTypeExpr(cse.pkg, node[0]) || isChanTypeExpr(cse.pkg, node[0])) {
+			// make(map[K]V, hint) -> new Dictionary<K, V>(hint), make(chan T, size) -> new Chan<T>(size)
+			cse.gir.emitToFileBuffer("new ", EmptyVisitMethod)
+			if len(node) > 1 {
+				cse.mapMakeHintExpr = node[1]
+			}
+			return
+		}
+		if len(node) == 1 && node[0] == cse.newCallArg {
+			cse.gir.emitToFileBuffer("Ptr.New(new ", EmptyVisitMethod)
+			return
+		}
 		cse.emitToken("(", LeftParen, 0)
 	})
 }
 
 func (cse *CSharpEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if len(node) == 1 && node[0] == cse.newCallArg {
+			cse.newCallArg = nil
+			cse.gir.emitToFileBuffer("())", EmptyVisitMethod)
+			return
+		}
+		if len(node) == 1 && (isMapTypeExpr(cse.pkg, node[0]) || isChanTypeExpr(cse.pkg, node[0])) {
+			cse.emitToken("(", LeftParen, 0)
+		}
 		cse.emitToken(")", RightParen, 0)
 	})
 }
@@ -633,6 +1603,11 @@ func (cse *CSharpEmitter) PreVisitBasicLit(e *ast.BasicLit, indent int) {
 				str = (cse.emitAsString(fmt.Sprintf("\"%s\"", value), 0))
 			}
 			cse.emitToken(str, StringLiteral, 0)
+		} else if r, ok := runeLiteralValue(cse.pkg, e); ok {
+			cse.emitToken(fmt.Sprintf("%d", r), NumberLiteral, 0)
+		} else if isBoxedIntLit(cse.pkg, e) {
+			typeName := csTypesMap[cse.pkg.TypesInfo.TypeOf(e).String()]
+			cse.emitToken(fmt.Sprintf("(%s)%s", typeName, e.Value), NumberLiteral, 0)
 		} else {
 			str = (cse.emitAsString(e.Value, 0))
 			cse.emitToken(str, NumberLiteral, 0)
@@ -640,119 +1615,278 @@ func (cse *CSharpEmitter) PreVisitBasicLit(e *ast.BasicLit, indent int) {
 	})
 }
 
-func (cse *CSharpEmitter) PreVisitDeclStmtValueSpecType(node *ast.ValueSpec, index int, indent int) {
+func (cse *CSharpEmitter) PreVisitDeclStmtValueSpecType(node *ast.ValueSpec, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		// Set type context flag - the variable type will be visited next
+		cse.inTypeContext = true
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitDeclStmtValueSpecType(node *ast.ValueSpec, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		// Clear type context flag - type has been visited
+		cse.inTypeContext = false
+		pointerAndPosition := SearchPointerIndexReverse(PreVisitDeclStmtValueSpecType, cse.gir.pointerAndIndexVec)
+		if pointerAndPosition != nil {
+			for aliasName, alias := range cse.aliases {
+				if alias.UnderlyingType == cse.pkg.TypesInfo.Types[node.Type].Type.Underlying().String() {
+					cse.gir.tokenSlice, _ = RewriteTokensBetween(cse.gir.tokenSlice, pointerAndPosition.Index, len(cse.gir.tokenSlice), []string{aliasName})
+				}
+			}
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitDeclStmtValueSpecNames(node *ast.Ident, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		str := cse.emitAsString(" ", 0)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		var str string
+		if _, ok := cse.pkg.TypesInfo.ObjectOf(node).Type().Underlying().(*types.Array); ok {
+			str += " = new();"
+		} else if t, ok := cse.pkg.TypesInfo.ObjectOf(node).Type().Underlying().(*types.Basic); ok && t.Info()&types.IsString != 0 {
+			str += " = \"\";"
+		} else {
+			str += " = default;"
+		}
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitGenStructFieldType(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		str := cse.emitAsString("public", indent+2)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitGenStructFieldType(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(" ", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitGenStructFieldName(node *ast.Ident, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(";\n", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitPackage(pkg *packages.Package, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		name := pkg.Name
+		cse.pkg = pkg
+		var packageName string
+		if name == "main" {
+			packageName = "MainClass"
+		} else {
+			//packageName = capitalizeFirst(name)
+			packageName = name
+		}
+		// Use a static class instead of namespace so we can have both types and functions
+		// This allows pkg.Type and pkg.Function without needing .Api.
+		str := cse.emitAsString(fmt.Sprintf("public static class %s {\n\n", packageName), indent)
+		err := cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		err = cse.gir.emitToFileBufferString("", pkg.Name)
+		cse.currentPackage = packageName
+		cse.apiClassOpened = true // Class is already open (no separate Api class needed)
+		if err != nil {
+			fmt.Println("Error writing to file:", err)
+			return
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitPackage(pkg *packages.Package, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		// Note: Type aliases (using X = T) are not emitted here because they can't be inside a class.
+		// They would need to be at namespace or file level, but since we use static classes,
+		// we skip alias emission. The actual types will be used directly where needed.
+
+		// Close the package class
+		err := cse.gir.emitToFileBuffer("}\n", EmptyVisitMethod)
+		if err != nil {
+			fmt.Println("Error writing to file:", err)
+			return
+		}
+	})
+}
+
+// Deferred calls are pushed as lambdas to a stack that the finally block
+// around the function body pops. Arguments are evaluated into locals at the
+// defer statement, the lambda captures them.
+func (cse *CSharpEmitter) PreVisitDeferStmt(node *ast.DeferStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(cse.emitAsString("", indent), EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitDeferStmtArg(node DeferArg, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		str := cse.emitAsString("var "+node.Name+" = ", 0)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitDeferStmtArg(node DeferArg, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		str := cse.emitAsString(";\n", 0) + cse.emitAsString("", indent)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitDeferStmtCall(node *ast.CallExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		stack := currentDeferStack(cse.deferScopes)
+		cse.gir.emitToFileBuffer(stack+".Push(() => { ", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitDeferStmtCall(node *ast.CallExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer("; });", EmptyVisitMethod)
+	})
+}
+
+// A goroutine is a lambda like a deferred call, its arguments are evaluated
+// into locals at the go statement
+func (cse *CSharpEmitter) PreVisitGoStmt(node *ast.GoStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(cse.emitAsString("", indent), EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitGoStmtArg(node DeferArg, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer("var "+node.Name+" = ", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitGoStmtArg(node DeferArg, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		str := cse.emitAsString(";\n", 0) + cse.emitAsString("", indent)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitGoStmtCall(node *ast.CallExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer("Scheduler.Go(() => { ", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitGoStmtCall(node *ast.CallExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer("; });", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitSendStmt(node *ast.SendStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(cse.emitAsString("ChanBuiltins.Send(", indent), EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitSendStmtValue(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		// Reset isArray flag at the start of each variable declaration
-		// This prevents stale state from previous declarations affecting this one
-		cse.isArray = false
-		// Set type context flag - the variable type will be visited next
-		cse.inTypeContext = true
+		cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PostVisitDeclStmtValueSpecType(node *ast.ValueSpec, index int, indent int) {
+func (cse *CSharpEmitter) PostVisitSendStmt(node *ast.SendStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		// Clear type context flag - type has been visited
-		cse.inTypeContext = false
-		pointerAndPosition := SearchPointerIndexReverse(PreVisitDeclStmtValueSpecType, cse.gir.pointerAndIndexVec)
-		if pointerAndPosition != nil {
-			for aliasName, alias := range cse.aliases {
-				if alias.UnderlyingType == cse.pkg.TypesInfo.Types[node.Type].Type.Underlying().String() {
-					cse.gir.tokenSlice, _ = RewriteTokensBetween(cse.gir.tokenSlice, pointerAndPosition.Index, len(cse.gir.tokenSlice), []string{aliasName})
-				}
-			}
-		}
+		cse.gir.emitToFileBuffer(");", EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PreVisitDeclStmtValueSpecNames(node *ast.Ident, index int, indent int) {
+// A select evaluates its cases into _selectN_i variables, ChanBuiltins.Select
+// runs the first ready one and a switch on its index runs the clause
+func (cse *CSharpEmitter) PreVisitSelectStmt(node *ast.SelectStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		str := cse.emitAsString(" ", 0)
-		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		cse.selectCount++
+		cse.selects = append(cse.selects, fmt.Sprintf("_select%d", cse.selectCount))
+		cse.gir.emitToFileBuffer(cse.emitAsString("{\n", indent), EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index int, indent int) {
+func (cse *CSharpEmitter) PreVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		var str string
-		if cse.isArray {
-			str += " = new "
-			str += strings.TrimSpace(cse.arrayType)
-			str += "();"
-			cse.isArray = false
-		} else {
-			str += " = default;"
+		op := "ChanBuiltins.SelectRecv("
+		if _, ok := node.Comm.(*ast.SendStmt); ok {
+			op = "ChanBuiltins.SelectSend("
 		}
-		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		name := fmt.Sprintf("%s_%d", cse.selects[len(cse.selects)-1], index)
+		cse.gir.emitToFileBuffer(cse.emitAsString("var "+name+" = "+op, indent+2), EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PreVisitGenStructFieldType(node ast.Expr, indent int) {
+func (cse *CSharpEmitter) PreVisitSelectStmtCaseValue(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		str := cse.emitAsString("public", indent+2)
-		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PostVisitGenStructFieldType(node ast.Expr, indent int) {
+func (cse *CSharpEmitter) PostVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.gir.emitToFileBuffer(" ", EmptyVisitMethod)
-		// clean array marker as we should generate
-		// initializer only for expression statements
-		// not for struct fields
-		cse.isArray = false
+		cse.gir.emitToFileBuffer(");\n", EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PostVisitGenStructFieldName(node *ast.Ident, indent int) {
+func (cse *CSharpEmitter) PreVisitSelectStmtClauses(node *ast.SelectStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.gir.emitToFileBuffer(";\n", EmptyVisitMethod)
+		name := cse.selects[len(cse.selects)-1]
+		cases := []string{fmt.Sprintf("%t", selectHasDefault(node))}
+		for _, stmt := range node.Body.List {
+			if stmt.(*ast.CommClause).Comm != nil {
+				cases = append(cases, fmt.Sprintf("%s_%d", name, len(cases)-1))
+			}
+		}
+		str := fmt.Sprintf("switch (ChanBuiltins.Select(%s))\n", strings.Join(cases, ", "))
+		cse.gir.emitToFileBuffer(cse.emitAsString(str, indent+2)+cse.emitAsString("{\n", indent+2), EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PreVisitPackage(pkg *packages.Package, indent int) {
+func (cse *CSharpEmitter) PreVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		name := pkg.Name
-		cse.pkg = pkg
-		var packageName string
-		if name == "main" {
-			packageName = "MainClass"
-		} else {
-			//packageName = capitalizeFirst(name)
-			packageName = name
-		}
-		// Use a static class instead of namespace so we can have both types and functions
-		// This allows pkg.Type and pkg.Function without needing .Api.
-		str := cse.emitAsString(fmt.Sprintf("public static class %s {\n\n", packageName), indent)
-		err := cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
-		err = cse.gir.emitToFileBufferString("", pkg.Name)
-		cse.currentPackage = packageName
-		cse.apiClassOpened = true // Class is already open (no separate Api class needed)
-		if err != nil {
-			fmt.Println("Error writing to file:", err)
+		if index < 0 {
+			cse.gir.emitToFileBuffer(cse.emitAsString("default:\n", indent+2)+cse.emitAsString("{\n", indent+2), EmptyVisitMethod)
 			return
 		}
+		str := cse.emitAsString(fmt.Sprintf("case %d:\n", index), indent+2) + cse.emitAsString("{\n", indent+2)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		name := fmt.Sprintf("%s_%d", cse.selects[len(cse.selects)-1], index)
+		_, _, recv := selectCaseOp(node)
+		value, ok := selectCaseNames(cse.pkg, cse.renames, node)
+		for _, v := range [][2]string{{value, "Value"}, {ok, "Ok"}} {
+			if v[0] == "" {
+				continue
+			}
+			decl := ""
+			if recv.Tok == token.DEFINE {
+				decl = "var "
+			}
+			str := cse.emitAsString(fmt.Sprintf("%s%s = %s.%s;\n", decl, v[0], name, v[1]), indent+4)
+			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		}
 	})
 }
 
-func (cse *CSharpEmitter) PostVisitPackage(pkg *packages.Package, indent int) {
+func (cse *CSharpEmitter) PostVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		// Note: Type aliases (using X = T) are not emitted here because they can't be inside a class.
-		// They would need to be at namespace or file level, but since we use static classes,
-		// we skip alias emission. The actual types will be used directly where needed.
-
-		// Close the package class
-		err := cse.gir.emitToFileBuffer("}\n", EmptyVisitMethod)
-		if err != nil {
-			fmt.Println("Error writing to file:", err)
-			return
-		}
+		str := "\n" + cse.emitAsString("break;\n", indent+4) + cse.emitAsString("}\n", indent+2)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PostVisitFuncDeclSignature(node *ast.FuncDecl, indent int) {
+func (cse *CSharpEmitter) PostVisitSelectStmt(node *ast.SelectStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.isArray = false
+		cse.selects = cse.selects[:len(cse.selects)-1]
+		cse.gir.emitToFileBuffer(cse.emitAsString("}\n", indent+2)+cse.emitAsString("}", indent), EmptyVisitMethod)
 	})
 }
 
@@ -766,18 +1900,104 @@ func (cse *CSharpEmitter) PostVisitBlockStmtList(node ast.Stmt, index int, inden
 func (cse *CSharpEmitter) PostVisitFuncDecl(node *ast.FuncDecl, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString("\n\n", 0)
+		if node.Recv != nil {
+			// Close the partial struct opened for the method
+			str = cse.emitAsString("\n", 0) + cse.emitAsString("}\n\n", indent+2)
+		}
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
 
+func (cse *CSharpEmitter) PreVisitFuncDecl(node *ast.FuncDecl, indent int) {
+	cse.currentFuncDecl = node
+	cse.renames = shadowRenames(cse.pkg, node)
+}
+
+func (cse *CSharpEmitter) PreVisitFuncDeclBody(node *ast.BlockStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.deferScopes = pushDeferScope(cse.pkg, cse.deferScopes, node, &cse.deferCount)
+		// Pointer receivers alias the struct through a ref local so mutations
+		// are visible to the caller, value receivers work on a copy
+		funcDecl := cse.currentFuncDecl
+		if name := recvName(funcDecl); name != "" {
+			if hasPointerRecv(funcDecl) {
+				cse.pendingRecvDecl = fmt.Sprintf("ref var %s = ref this;\n", name)
+			} else {
+				cse.pendingRecvDecl = fmt.Sprintf("var %s = this;\n", name)
+			}
+		}
+	})
+}
+
+// PreVisitGenStructMethod skips the method signature, methods are emitted
+// with their bodies in a partial declaration of the struct
+func (cse *CSharpEmitter) PreVisitGenStructMethod(node *ast.FuncDecl, indent int) {
+	cse.forwardDecls = true
+}
+
+func (cse *CSharpEmitter) PostVisitGenStructMethod(node *ast.FuncDecl, indent int) {
+	cse.forwardDecls = false
+}
+
 func (cse *CSharpEmitter) PreVisitGenStructInfo(node GenTypeInfo, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		str := cse.emitAsString(fmt.Sprintf("public struct %s\n", node.Name), indent+2)
+		structKind := "struct"
+		// Go interfaces are satisfied implicitly, C# structs list them
+		var ifaces []string
+		if obj := cse.pkg.Types.Scope().Lookup(node.Name); obj != nil && hasMethods(obj.Type()) {
+			structKind = "partial struct"
+			for _, iface := range implementedInterfaces(cse.pkg.Types, obj.Type()) {
+				ifaces = append(ifaces, cse.qualifiedTypeName(iface))
+			}
+		}
+		params := structTypeParams(cse.pkg, node.Name)
+		name := node.Name + csTypeParams(params)
+		if len(ifaces) > 0 {
+			name += " : " + strings.Join(ifaces, ", ")
+		}
+		name += csConstraints(params)
+		str := cse.emitAsString(fmt.Sprintf("public %s %s\n", structKind, name), indent+2)
+		str += cse.emitAsString("{\n", indent+2)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+// qualifiedTypeName returns the name of a named type as seen from the current
+// package, types of other packages are members of their package class
+func (cse *CSharpEmitter) qualifiedTypeName(named *types.Named) string {
+	if pkg := named.Obj().Pkg(); pkg != nil && pkg != cse.pkg.Types {
+		return pkg.Name() + "." + named.Obj().Name()
+	}
+	return named.Obj().Name()
+}
+
+// PreVisitGenInterfaceInfo declares a Go interface as a C# interface
+func (cse *CSharpEmitter) PreVisitGenInterfaceInfo(node GenTypeInfo, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		str := cse.emitAsString(fmt.Sprintf("public interface %s\n", node.Name), indent+2)
 		str += cse.emitAsString("{\n", indent+2)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
 
+func (cse *CSharpEmitter) PostVisitGenInterfaceInfo(node GenTypeInfo, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		str := cse.emitAsString("}\n\n", indent+2)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {
+	cse.insideInterface = true
+}
+
+func (cse *CSharpEmitter) PostVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {
+	cse.insideInterface = false
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(";\n", EmptyVisitMethod)
+	})
+}
+
 func (cse *CSharpEmitter) PostVisitGenStructInfo(node GenTypeInfo, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString("};\n\n", indent+2)
@@ -791,12 +2011,43 @@ func (cse *CSharpEmitter) PreVisitArrayType(node ast.ArrayType, indent int) {
 		if cse.suppressTypeAliasEmit {
 			return
 		}
-		str := cse.emitAsString("List", indent)
+		str := cse.emitAsString("Slice", indent)
+		if node.Len != nil {
+			length := arrayLen(cse.pkg, node)
+			if cse.arrayLengths == nil {
+				cse.arrayLengths = make(map[int64]bool)
+			}
+			cse.arrayLengths[length] = true
+			str = cse.emitAsString(fmt.Sprintf("Array%d", length), indent)
+		}
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 		str = cse.emitAsString("<", 0)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
+
+// PreVisitEllipsis emits a variadic parameter ...T as Slice<T>; declared
+// functions mark it params so callers can pass the elements directly
+func (cse *CSharpEmitter) PreVisitEllipsis(node *ast.Ellipsis, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(cse.emitAsString("Slice", indent), EmptyVisitMethod)
+		cse.gir.emitToFileBuffer("<", EmptyVisitMethod)
+	})
+}
+func (cse *CSharpEmitter) PostVisitEllipsis(node *ast.Ellipsis, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(">", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitFuncDeclSignatureTypeParamsListType(node ast.Expr, argName *ast.Ident, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if _, ok := node.(*ast.Ellipsis); ok {
+			cse.gir.emitToFileBuffer("params ", EmptyVisitMethod)
+		}
+	})
+}
+
 func (cse *CSharpEmitter) PostVisitArrayType(node ast.ArrayType, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		// Skip emission during type alias handling
@@ -806,52 +2057,91 @@ func (cse *CSharpEmitter) PostVisitArrayType(node ast.ArrayType, indent int) {
 		str := cse.emitAsString(">", 0)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 
-		pointerAndPosition := SearchPointerIndexReverse(PreVisitArrayType, cse.gir.pointerAndIndexVec)
-		if pointerAndPosition != nil {
-			tokens, _ := ExtractTokens(pointerAndPosition.Index, cse.gir.tokenSlice)
-			cse.isArray = true
-			cse.arrayType = strings.Join(tokens, "")
-		}
 	})
 }
 
-func (cse *CSharpEmitter) PreVisitFuncType(node *ast.FuncType, indent int) {
+func (cse *CSharpEmitter) PreVisitMapType(node *ast.MapType, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		// All types within FuncType are type references
-		cse.inTypeContext = true
-		var str string
-		if node.Results != nil {
-			str = cse.emitAsString("Func", indent)
-		} else {
-			str = cse.emitAsString("Action", indent)
+		if cse.suppressTypeAliasEmit {
+			return
 		}
-		str += cse.emitAsString("<", 0)
+		str := cse.emitAsString("Dictionary", indent)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		str = cse.emitAsString("<", 0)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
-func (cse *CSharpEmitter) PostVisitFuncType(node *ast.FuncType, indent int) {
+
+func (cse *CSharpEmitter) PreVisitChanType(node *ast.ChanType, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		pointerAndPosition := SearchPointerIndexReverse(PreVisitFuncType, cse.gir.pointerAndIndexVec)
-		if pointerAndPosition != nil && cse.numFuncResults > 0 {
-			// For function types with return values, we need to reorder tokens
-			// to move return type to the end (C# syntax requirement)
-			tokens, _ := ExtractTokens(pointerAndPosition.Index, cse.gir.tokenSlice)
-			if len(tokens) > 2 {
-				// Find and move return type to end with comma separator
-				var reorderedTokens []string
-				reorderedTokens = append(reorderedTokens, tokens[0]) // "Func<" or "Action<"
-				if len(tokens) > 3 {
-					// Skip return type (index 1) and add parameters first
-					reorderedTokens = append(reorderedTokens, tokens[2:]...)
-					reorderedTokens = append(reorderedTokens, ",")
-					reorderedTokens = append(reorderedTokens, tokens[1]) // Add return type at end
-				}
-				cse.gir.tokenSlice, _ = RewriteTokensBetween(cse.gir.tokenSlice, pointerAndPosition.Index, len(cse.gir.tokenSlice), reorderedTokens)
-			}
+		if cse.suppressTypeAliasEmit {
+			return
+		}
+		cse.gir.emitToFileBuffer(cse.emitAsString("Chan", indent)+"<", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitChanType(node *ast.ChanType, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if cse.suppressTypeAliasEmit {
+			return
+		}
+		cse.gir.emitToFileBuffer(">", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitMapKeyType(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if cse.suppressTypeAliasEmit {
+			return
 		}
+		cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+	})
+}
 
+func (cse *CSharpEmitter) PostVisitMapType(node *ast.MapType, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if cse.suppressTypeAliasEmit {
+			return
+		}
 		str := cse.emitAsString(">", 0)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitFuncType(node *ast.FuncType, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		// All types within FuncType are type references
+		cse.inTypeContext = true
+		cse.funcTypes = append(cse.funcTypes, funcTypeTokens{start: len(cse.gir.tokenSlice)})
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitFuncTypeParams(node *ast.FieldList, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.funcTypes[len(cse.funcTypes)-1].params = len(cse.gir.tokenSlice)
+	})
+}
+
+// PostVisitFuncType emits the function type as a Func<params..., result>
+// or Action<params...> delegate, results are visited before parameters
+func (cse *CSharpEmitter) PostVisitFuncType(node *ast.FuncType, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		frame := cse.funcTypes[len(cse.funcTypes)-1]
+		cse.funcTypes = cse.funcTypes[:len(cse.funcTypes)-1]
+		results, params := frame.split(cse.gir.tokenSlice)
+		var str string
+		switch {
+		case results != "" && params != "":
+			str = "Func<" + params + ", " + results + ">"
+		case results != "":
+			str = "Func<" + results + ">"
+		case params != "":
+			str = "Action<" + params + ">"
+		default:
+			str = "Action"
+		}
+		cse.gir.tokenSlice, _ = RewriteTokensBetween(cse.gir.tokenSlice, frame.start, len(cse.gir.tokenSlice), []string{cse.emitAsString(str, indent)})
 		// Clear type context flag
 		cse.inTypeContext = false
 	})
@@ -898,20 +2188,20 @@ func (cse *CSharpEmitter) PostVisitSelectorExprX(node ast.Expr, indent int) {
 			}
 			// No need to add .Api. - everything is in the package class directly
 		}
+		// Fields and methods are selected on the value a pointer boxes
+		if isPointerExpr(cse.pkg, node) && !isPointerRecvExpr(cse.pkg, cse.currentFuncDecl, node) {
+			scopeOperator = ".Value."
+		}
+		if cse.captureRangeExpr {
+			cse.rangeCollectionExpr += scopeOperator
+			return
+		}
 
 		str = cse.emitAsString(scopeOperator, 0)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PreVisitFuncTypeResults(node *ast.FieldList, indent int) {
-	cse.executeIfNotForwardDecls(func() {
-		if node != nil {
-			cse.numFuncResults = len(node.List)
-		}
-	})
-}
-
 func (cse *CSharpEmitter) PreVisitFuncDeclSignatureTypeParamsList(node *ast.Field, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		if index > 0 {
@@ -963,8 +2253,18 @@ func (cse *CSharpEmitter) PostVisitFuncDeclSignatureTypeResultsList(node *ast.Fi
 
 func (cse *CSharpEmitter) PreVisitFuncDeclSignatureTypeResults(node *ast.FuncDecl, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		// Functions are directly in the package class (no separate Api class)
+		// Functions are directly in the package class (no separate Api class),
+		// methods are instance members of a partial declaration of their struct
 		str := cse.emitAsString("public static ", indent+2)
+		if cse.insideInterface {
+			str = cse.emitAsString("", indent+4)
+		} else if recv := recvTypeName(node); recv != "" {
+			params := typeParams(cse.pkg, node)
+			recv += csTypeParams(params) + csConstraints(params)
+			str = cse.emitAsString(fmt.Sprintf("public partial struct %s\n", recv), indent+2)
+			str += cse.emitAsString("{\n", indent+2)
+			str += cse.emitAsString("public ", indent+4)
+		}
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 		if node.Type.Results != nil {
 			if len(node.Type.Results.List) > 1 {
@@ -1057,6 +2357,20 @@ func (cse *CSharpEmitter) PostVisitTypeAliasType(node ast.Expr, indent int) {
 func (cse *CSharpEmitter) PreVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString("return ", indent)
+		// Named results are assigned first and the return jumps past the
+		// finally block, deferred calls may change them
+		names := resultNames(enclosingFuncResults(cse.pkg, cse.currentFuncDecl, node.Pos()))
+		if currentDeferStack(cse.deferScopes) != "" && names != nil && len(node.Results) > 0 {
+			if returnsNamedResults(node, names) {
+				cse.gir.emitToFileBuffer(cse.emitAsString("{ ", indent), EmptyVisitMethod)
+				cse.suppressRangeEmit = true
+				return
+			}
+			str = cse.emitAsString("{ "+strings.Join(names, ", ")+" = ", indent)
+			if len(names) > 1 {
+				str = cse.emitAsString("{ ("+strings.Join(names, ", ")+") = ", indent)
+			}
+		}
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 
 		if len(node.Results) == 1 {
@@ -1064,7 +2378,7 @@ func (cse *CSharpEmitter) PreVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 			//pos := cse.pkg.Fset.Position(node.Pos())
 			//fmt.Printf("@@Type: %s %s:%d:%d\n", tv.Type, pos.Filename, pos.Line, pos.Column)
 			if tv.Type != nil {
-				if typeVal, ok := csTypesMap[tv.Type.String()]; ok {
+				if typeVal, ok := csCastType(tv.Type); ok {
 					if !cse.isTuple && tv.Type.String() != "func()" {
 						cse.emitToken("(", LeftParen, 0)
 						str := cse.emitAsString(typeVal, 0)
@@ -1082,6 +2396,19 @@ func (cse *CSharpEmitter) PreVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 
 func (cse *CSharpEmitter) PostVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		names := resultNames(enclosingFuncResults(cse.pkg, cse.currentFuncDecl, node.Pos()))
+		if scope := currentDeferScope(cse.deferScopes); scope.stack != "" && names != nil && len(node.Results) > 0 {
+			if cse.suppressRangeEmit {
+				cse.suppressRangeEmit = false
+			} else {
+				if len(node.Results) > 1 {
+					cse.emitToken(")", RightParen, 0)
+				}
+				cse.gir.emitToFileBuffer("; ", EmptyVisitMethod)
+			}
+			cse.gir.emitToFileBuffer("goto "+scope.label+"; }", EmptyVisitMethod)
+			return
+		}
 		if len(node.Results) > 1 {
 			cse.emitToken(")", RightParen, 0)
 		}
@@ -1092,6 +2419,9 @@ func (cse *CSharpEmitter) PostVisitReturnStmt(node *ast.ReturnStmt, indent int)
 
 func (cse *CSharpEmitter) PreVisitReturnStmtResult(node ast.Expr, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if cse.suppressRangeEmit {
+			return
+		}
 		if index > 0 {
 			str := cse.emitAsString(", ", 0)
 			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
@@ -1131,6 +2461,15 @@ func (cse *CSharpEmitter) PreVisitAssignStmt(node *ast.AssignStmt, indent int) {
 		cse.suppressRangeEmit = true
 		return
 	}
+	if isMapCommaOk(cse.pkg, node) {
+		cse.mapCommaOkExpr = node.Rhs[0]
+	}
+	if isTypeAssertCommaOk(node) {
+		cse.typeAssertCommaOk = node.Rhs[0]
+	}
+	if isChanRecvCommaOk(node) {
+		cse.chanRecvCommaOk = ast.Unparen(node.Rhs[0])
+	}
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString("", indent)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
@@ -1138,6 +2477,9 @@ func (cse *CSharpEmitter) PreVisitAssignStmt(node *ast.AssignStmt, indent int) {
 }
 
 func (cse *CSharpEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {
+	cse.mapCommaOkExpr = nil
+	cse.typeAssertCommaOk = nil
+	cse.chanRecvCommaOk = nil
 	// Reset blank identifier suppression if it was set
 	if cse.suppressRangeEmit {
 		cse.suppressRangeEmit = false
@@ -1149,11 +2491,26 @@ func (cse *CSharpEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int)
 			str := cse.emitAsString(";", 0)
 			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 		}
+		cse.gir.emitToFileBuffer(cse.slicedArrayBoxes(node), EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitDeclStmt(node *ast.DeclStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(cse.slicedArrayBoxes(node), EmptyVisitMethod)
 	})
 }
 
 func (cse *CSharpEmitter) PreVisitAssignStmtRhs(node *ast.AssignStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if node.Tok != token.ASSIGN && node.Tok != token.DEFINE && isMapIndexExpr(cse.pkg, node.Lhs[0]) {
+			// m[k] op= v -> m[k] = MapBuiltins.Get(m, k) op (v)
+			op := strings.TrimSuffix(node.Tok.String(), "=")
+			cse.emitToken("=", Assignment, 1)
+			cse.gir.emitToFileBuffer(" "+cse.mapLhsRead()+" "+op+" ", EmptyVisitMethod)
+			cse.emitToken("(", LeftParen, 0)
+			return
+		}
 		opTokenType := cse.getTokenType(cse.assignmentToken)
 		cse.emitToken(cse.assignmentToken, opTokenType, indent+1)
 		cse.emitToken(" ", WhiteSpace, 0)
@@ -1162,6 +2519,9 @@ func (cse *CSharpEmitter) PreVisitAssignStmtRhs(node *ast.AssignStmt, indent int
 
 func (cse *CSharpEmitter) PostVisitAssignStmtRhs(node *ast.AssignStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if node.Tok != token.ASSIGN && node.Tok != token.DEFINE && isMapIndexExpr(cse.pkg, node.Lhs[0]) {
+			cse.emitToken(")", RightParen, 0)
+		}
 		cse.isTuple = false
 	})
 }
@@ -1184,7 +2544,7 @@ func (cse *CSharpEmitter) PostVisitAssignStmtRhsExpr(node ast.Expr, index int, i
 			//pos := cse.pkg.Fset.Position(node.Pos())
 			//fmt.Printf("@@Type: %s %s:%d:%d\n", tv.Type, pos.Filename, pos.Line, pos.Column)
 			if tv.Type != nil {
-				if typeVal, ok := csTypesMap[tv.Type.String()]; ok {
+				if typeVal, ok := csCastType(tv.Type); ok {
 					if !cse.isTuple && tv.Type.String() != "func()" {
 						cse.gir.tokenSlice, _ = RewriteTokens(cse.gir.tokenSlice, pointerAndPosition.Index, []string{}, []string{"(", typeVal, ")"})
 					}
@@ -1208,6 +2568,10 @@ func (cse *CSharpEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int
 		assignmentToken := node.Tok.String()
 		if assignmentToken == ":=" && len(node.Lhs) == 1 {
 			str := cse.emitAsString("var ", indent)
+			// var would give x := 5 the 32-bit type of the literal
+			if len(node.Rhs) == 1 && isIntConstant(cse.pkg, node.Rhs[0]) {
+				str = cse.emitAsString(csTypesMap[cse.pkg.TypesInfo.TypeOf(node.Rhs[0]).String()]+" ", indent)
+			}
 			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 		} else if assignmentToken == ":=" && len(node.Lhs) > 1 {
 			str := cse.emitAsString("var ", indent)
@@ -1222,11 +2586,13 @@ func (cse *CSharpEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int
 			assignmentToken = "="
 		}
 		cse.assignmentToken = assignmentToken
+		cse.insideAssignLhs = true
 	})
 }
 
 func (cse *CSharpEmitter) PostVisitAssignStmtLhs(node *ast.AssignStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		cse.insideAssignLhs = false
 		if node.Tok.String() == ":=" && len(node.Lhs) > 1 {
 			cse.emitToken(")", RightParen, indent)
 		} else if node.Tok.String() == "=" && len(node.Lhs) > 1 {
@@ -1235,44 +2601,212 @@ func (cse *CSharpEmitter) PostVisitAssignStmtLhs(node *ast.AssignStmt, indent in
 	})
 }
 
+// isMapRead reports whether node is a map lookup. Assignment targets keep the
+// Dictionary indexer, which inserts or overwrites the key like Go does.
+func (cse *CSharpEmitter) isMapRead(node *ast.IndexExpr) bool {
+	return !cse.insideAssignLhs && isMapIndexExpr(cse.pkg, node)
+}
+
+// mapLhsRead returns MapBuiltins.Get(m, k) for the last m[k] assignment target
+func (cse *CSharpEmitter) mapLhsRead() string {
+	mapTokens, _ := ExtractTokensBetween(cse.mapLhsStart, cse.mapLhsBracket, cse.gir.tokenSlice)
+	keyTokens, _ := ExtractTokensBetween(cse.mapLhsBracket+1, cse.mapLhsEnd-1, cse.gir.tokenSlice)
+	return "MapBuiltins.Get(" + strings.TrimSpace(strings.Join(tokensToStrings(mapTokens), "")) + ", " +
+		strings.Join(tokensToStrings(keyTokens), "") + ")"
+}
+
+func (cse *CSharpEmitter) PreVisitIndexExpr(node *ast.IndexExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if node == cse.mapCommaOkExpr {
+			cse.gir.emitToFileBuffer("MapBuiltins.GetOk(", EmptyVisitMethod)
+		} else if cse.isMapRead(node) {
+			cse.gir.emitToFileBuffer("MapBuiltins.Get(", EmptyVisitMethod)
+		} else if isMapIndexExpr(cse.pkg, node) {
+			cse.mapLhsStart = len(cse.gir.tokenSlice)
+		} else if isSliceIndexExpr(cse.pkg, node) {
+			cse.gir.emitToFileBuffer("SliceBuiltins.At(", EmptyVisitMethod)
+		}
+	})
+}
+
 func (cse *CSharpEmitter) PreVisitIndexExprIndex(node *ast.IndexExpr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if cse.isMapRead(node) || isSliceIndexExpr(cse.pkg, node) {
+			cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+			return
+		}
+		if isMapIndexExpr(cse.pkg, node) {
+			cse.mapLhsBracket = len(cse.gir.tokenSlice)
+		}
 		cse.emitToken("[", LeftBracket, 0)
+		if isArrayExpr(cse.pkg, node.X) {
+			cse.emitIndexCast(node.Index)
+		}
 	})
 }
 func (cse *CSharpEmitter) PostVisitIndexExprIndex(node *ast.IndexExpr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if cse.isMapRead(node) || isSliceIndexExpr(cse.pkg, node) {
+			cse.emitToken(")", RightParen, 0)
+			return
+		}
+		if isArrayExpr(cse.pkg, node.X) && isWideInt(cse.pkg, node.Index) {
+			cse.emitToken(")", RightParen, 0)
+		}
 		cse.emitToken("]", RightBracket, 0)
+		if isMapIndexExpr(cse.pkg, node) {
+			cse.mapLhsEnd = len(cse.gir.tokenSlice)
+		}
 	})
 }
 
 func (cse *CSharpEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		op := cse.intOp(node)
+		cse.intOps = append(cse.intOps, op)
+		cse.gir.emitToFileBuffer(op.open, EmptyVisitMethod)
 		cse.emitToken("(", LeftParen, 1)
+		// C# has no == for unconstrained type parameters
+		equals := (node.Op == token.EQL || node.Op == token.NEQ) && isTypeParam(cse.pkg, node.X)
+		cse.typeParamEquals = append(cse.typeParamEquals, equals)
+		if equals {
+			if node.Op == token.NEQ {
+				cse.gir.emitToFileBuffer("!", EmptyVisitMethod)
+			}
+			typeName := types.TypeString(cse.pkg.TypesInfo.TypeOf(node.X), nil)
+			cse.gir.emitToFileBuffer(fmt.Sprintf("EqualityComparer<%s>.Default.Equals(", typeName), EmptyVisitMethod)
+		}
+		if (node.Op == token.SHL || node.Op == token.SHR) && isWideInt(cse.pkg, node.Y) && op.sep == "" {
+			if cse.wideShiftCounts == nil {
+				cse.wideShiftCounts = make(map[ast.Expr]bool)
+			}
+			cse.wideShiftCounts[node.Y] = true
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitBinaryExprRight(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if cse.wideShiftCounts[node] {
+			cse.gir.emitToFileBuffer("(int)", EmptyVisitMethod)
+			cse.emitToken("(", LeftParen, 0)
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitBinaryExprRight(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if cse.wideShiftCounts[node] {
+			cse.emitToken(")", RightParen, 0)
+			delete(cse.wideShiftCounts, node)
+		}
 	})
 }
+// intOp returns the form of an integer operation that C# would not perform
+// like Go. Arithmetic on 8 and 16-bit integers yields int, it is cast back to
+// wrap around: (byte)(a + b). Shifts by a count that may be negative or
+// beyond the width, and signed division by a variable, call the runtime:
+// IntBuiltins.Shl<byte>(x, n), IntBuiltins.Quo<long>(a, b). Arithmetic on
+// the wider types wraps, the project is built unchecked.
+func (cse *CSharpEmitter) intOp(node *ast.BinaryExpr) intOp {
+	basic := intOpType(cse.pkg, node)
+	if basic == nil {
+		if b := bitwiseOpType(cse.pkg, node); b != nil && intBits(b) < 32 {
+			return intOp{open: "(" + csTypesMap[intTypeName(b)] + ")"}
+		}
+		return intOp{}
+	}
+	t, ok := csTypesMap[intTypeName(basic)]
+	if !ok {
+		return intOp{}
+	}
+	switch {
+	case (node.Op == token.SHL || node.Op == token.SHR) && !shiftCountInRange(cse.pkg, node, basic):
+		name := map[token.Token]string{token.SHL: "Shl", token.SHR: "Shr"}[node.Op]
+		return intOp{open: "IntBuiltins." + name + "<" + t + ">", sep: ", "}
+	case isSignedQuo(cse.pkg, node, basic) && intBits(basic) >= 32:
+		name := map[token.Token]string{token.QUO: "Quo", token.REM: "Rem"}[node.Op]
+		return intOp{open: "IntBuiltins." + name + "<" + t + ">", sep: ", "}
+	case intBits(basic) < 32:
+		return intOp{open: "(" + t + ")"}
+	}
+	return intOp{}
+}
+
 func (cse *CSharpEmitter) PostVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		cse.intOps = cse.intOps[:len(cse.intOps)-1]
+		n := len(cse.typeParamEquals)
+		if cse.typeParamEquals[n-1] {
+			cse.emitToken(")", RightParen, 0)
+		}
+		cse.typeParamEquals = cse.typeParamEquals[:n-1]
 		cse.emitToken(")", RightParen, 1)
 	})
 }
 
 func (cse *CSharpEmitter) PreVisitBinaryExprOperator(op token.Token, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		opTokenType := cse.getTokenType(op.String())
-		cse.emitToken(op.String(), opTokenType, 1)
+		if n := len(cse.typeParamEquals); n > 0 && cse.typeParamEquals[n-1] {
+			cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+			return
+		}
+		if sep := cse.intOps[len(cse.intOps)-1].sep; sep != "" {
+			cse.gir.emitToFileBuffer(sep, EmptyVisitMethod)
+			return
+		}
+		content := op.String()
+		if op == token.AND_NOT {
+			content = "& ~"
+		}
+		opTokenType := cse.getTokenType(content)
+		cse.emitToken(content, opTokenType, 1)
 		cse.emitToken(" ", WhiteSpace, 0)
 	})
 }
 
 func (cse *CSharpEmitter) PreVisitCallExprArg(node ast.Expr, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if node == cse.mapMakeHintExpr {
+			cse.mapMakeHintExpr = nil
+			cse.emitToken("(", LeftParen, 0)
+			return
+		}
+		if node == cse.sliceMakeLenExpr {
+			cse.sliceMakeLenExpr = nil
+			cse.gir.emitToFileBuffer(".Make", EmptyVisitMethod)
+			cse.emitToken("(", LeftParen, 0)
+			return
+		}
 		if index > 0 {
 			str := cse.emitAsString(", ", 0)
 			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 		}
 	})
 }
+
+// PreVisitCallExprVariadicArgs leaves packing to params for declared functions;
+// function values take a Slice built by a collection expression
+func (cse *CSharpEmitter) PreVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		packed := !node.Ellipsis.IsValid() && isFuncValueCall(cse.pkg, node)
+		if index > 0 && (packed || index < len(node.Args)) {
+			cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+		}
+		if packed {
+			cse.emitToken("[", LeftBracket, 0)
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if !node.Ellipsis.IsValid() && isFuncValueCall(cse.pkg, node) {
+			cse.emitToken("]", RightBracket, 0)
+		}
+	})
+}
+
 func (cse *CSharpEmitter) PostVisitExprStmtX(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString(";", 0)
@@ -1352,11 +2886,32 @@ func (cse *CSharpEmitter) PostVisitForStmt(node *ast.ForStmt, indent int) {
 
 func (cse *CSharpEmitter) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		// Check if this is a key-value range (both Key and Value present)
-		if node.Key != nil && node.Value != nil {
+		cse.isMapRange = isMapExpr(cse.pkg, node.X)
+		cse.mapRangeValues = node.Key == nil
+		cse.isStringRange = isStringExpr(cse.pkg, node.X)
+		if cse.isStringRange {
+			// Emit: foreach (var (i, r) in SliceBuiltins.Range(s)), the key
+			// and value are suppressed until the string expression
+			bindings := "_"
+			if node.Key != nil || node.Value != nil {
+				key, value := stringRangeNames(cse.pkg, cse.renames, node)
+				bindings = fmt.Sprintf("(%s, %s)", key, value)
+			}
+			cse.isKeyValueRange = false
+			str := cse.emitAsString(fmt.Sprintf("foreach (var %s in SliceBuiltins.Range(", bindings), indent)
+			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+			cse.suppressRangeEmit = true
+			return
+		}
+		// Check if this is a key-value range (both Key and Value present), an
+		// index-only range over a slice or array also counts the index
+		if node.Key != nil && (node.Value != nil || !cse.isMapRange) {
 			cse.isKeyValueRange = true
-			cse.rangeKeyName = node.Key.(*ast.Ident).Name
-			cse.rangeValueName = node.Value.(*ast.Ident).Name
+			cse.rangeKeyName = localName(cse.pkg, cse.renames, node.Key.(*ast.Ident))
+			cse.rangeValueName = ""
+			if node.Value != nil {
+				cse.rangeValueName = localName(cse.pkg, cse.renames, node.Value.(*ast.Ident))
+			}
 			cse.rangeCollectionExpr = ""
 			cse.suppressRangeEmit = true
 			cse.rangeStmtIndent = indent
@@ -1386,7 +2941,9 @@ func (cse *CSharpEmitter) PreVisitRangeStmtValue(node ast.Expr, indent int) {
 
 func (cse *CSharpEmitter) PostVisitRangeStmtValue(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		if cse.isKeyValueRange {
+		if cse.isStringRange {
+			cse.suppressRangeEmit = false
+		} else if cse.isKeyValueRange {
 			// Stop suppressing, start capturing collection expression
 			cse.suppressRangeEmit = false
 			cse.captureRangeExpr = true
@@ -1399,11 +2956,29 @@ func (cse *CSharpEmitter) PostVisitRangeStmtValue(node ast.Expr, indent int) {
 
 func (cse *CSharpEmitter) PreVisitRangeStmtX(node ast.Expr, indent int) {
 	// For key-value range, we're already in capture mode
+	cse.executeIfNotForwardDecls(func() {
+		if cse.isMapRange && !cse.isKeyValueRange {
+			// for k := range m iterates keys, for _, v := range m iterates values
+			if cse.mapRangeValues {
+				cse.gir.emitToFileBuffer("MapBuiltins.Values(", EmptyVisitMethod)
+			} else {
+				cse.gir.emitToFileBuffer("MapBuiltins.Keys(", EmptyVisitMethod)
+			}
+		}
+		// A nil slice is null, ranging over it yields nothing
+		_, cse.isSliceRange = cse.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Slice)
+		if cse.isSliceRange && !cse.isKeyValueRange {
+			cse.gir.emitToFileBuffer("SliceBuiltins.Range(", EmptyVisitMethod)
+		}
+	})
 }
 
 func (cse *CSharpEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		if cse.isKeyValueRange {
+		if cse.isStringRange {
+			cse.isStringRange = false
+			cse.gir.emitToFileBuffer("))\n", EmptyVisitMethod)
+		} else if cse.isKeyValueRange {
 			// Stop capturing and emit the complete for loop
 			cse.captureRangeExpr = false
 			collection := cse.rangeCollectionExpr
@@ -1411,12 +2986,23 @@ func (cse *CSharpEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 			value := cse.rangeValueName
 			indent := cse.rangeStmtIndent
 
-			// Emit: for (int key = 0; key < collection.Count; key++)
-			str := cse.emitAsString(fmt.Sprintf("for (int %s = 0; %s < %s.Count; %s++)\n", key, key, collection, key), indent)
-			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+			if cse.isMapRange {
+				// Emit: foreach (var key in MapBuiltins.Keys(collection))
+				str := cse.emitAsString(fmt.Sprintf("foreach (var %s in MapBuiltins.Keys(%s))\n", key, collection), indent)
+				cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+			} else {
+				// Emit: for (long key = 0; key < collection.Count; key++),
+				// an array has a constant length
+				length := "SliceBuiltins.Length(" + collection + ")"
+				if array, ok := cse.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Array); ok {
+					length = fmt.Sprint(array.Len())
+				}
+				str := cse.emitAsString(fmt.Sprintf("for (%s %s = 0; %s < %s; %s++)\n", csTypesMap["int"], key, key, length, key), indent)
+				cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+			}
 
 			// Set pending value declaration to be emitted at start of body block
-			cse.pendingRangeValueDecl = true
+			cse.pendingRangeValueDecl = value != ""
 			cse.pendingValueName = value
 			cse.pendingCollectionExpr = collection
 			cse.pendingKeyName = key
@@ -1427,6 +3013,13 @@ func (cse *CSharpEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 			cse.rangeValueName = ""
 			cse.rangeCollectionExpr = ""
 		} else {
+			if cse.isMapRange {
+				cse.emitToken(")", RightParen, 0)
+				cse.isMapRange = false
+			}
+			if cse.isSliceRange {
+				cse.emitToken(")", RightParen, 0)
+			}
 			cse.emitToken(")", RightParen, 0)
 			str := cse.emitAsString("\n", 0)
 			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
@@ -1438,8 +3031,26 @@ func (cse *CSharpEmitter) PostVisitRangeStmt(node *ast.RangeStmt, indent int) {
 	// Reset any range-related state
 }
 
+func (cse *CSharpEmitter) PreVisitIncDecStmt(node *ast.IncDecStmt, indent int) {
+	cse.insideAssignLhs = true
+}
+
 func (cse *CSharpEmitter) PostVisitIncDecStmt(node *ast.IncDecStmt, indent int) {
+	cse.insideAssignLhs = false
 	cse.executeIfNotForwardDecls(func() {
+		if isMapIndexExpr(cse.pkg, node.X) {
+			// m[k]++ -> m[k] = MapBuiltins.Get(m, k) + 1
+			op := "+"
+			if node.Tok == token.DEC {
+				op = "-"
+			}
+			str := " = " + cse.mapLhsRead() + " " + op + " 1"
+			if !cse.insideForPostCond {
+				str += ";"
+			}
+			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+			return
+		}
 		str := cse.emitAsString(node.Tok.String(), 0)
 		if !cse.insideForPostCond {
 			str += cse.emitAsString(";", 0)
@@ -1448,10 +3059,42 @@ func (cse *CSharpEmitter) PostVisitIncDecStmt(node *ast.IncDecStmt, indent int)
 	})
 }
 
+func (cse *CSharpEmitter) PreVisitCompositeLit(node *ast.CompositeLit, indent int) {
+	cse.mapCompositeLits = append(cse.mapCompositeLits, isMapExpr(cse.pkg, node))
+	cse.arrayCompositeLits = append(cse.arrayCompositeLits, isArrayExpr(cse.pkg, node))
+	cse.emptySliceLits = append(cse.emptySliceLits, len(node.Elts) == 0 && isSliceExpr(cse.pkg, node))
+}
+
+func (cse *CSharpEmitter) PostVisitCompositeLit(node *ast.CompositeLit, indent int) {
+	cse.mapCompositeLits = cse.mapCompositeLits[:len(cse.mapCompositeLits)-1]
+	cse.arrayCompositeLits = cse.arrayCompositeLits[:len(cse.arrayCompositeLits)-1]
+	cse.emptySliceLits = cse.emptySliceLits[:len(cse.emptySliceLits)-1]
+}
+
+// insideArrayCompositeLit reports whether the innermost composite literal is
+// an array literal, built with ArrayN<T>.Of(elements)
+func (cse *CSharpEmitter) insideArrayCompositeLit() bool {
+	return len(cse.arrayCompositeLits) > 0 && cse.arrayCompositeLits[len(cse.arrayCompositeLits)-1]
+}
+
+// insideMapCompositeLit reports whether the innermost composite literal is a map literal
+func (cse *CSharpEmitter) insideMapCompositeLit() bool {
+	return len(cse.mapCompositeLits) > 0 && cse.mapCompositeLits[len(cse.mapCompositeLits)-1]
+}
+
+// insideEmptySliceLit reports whether the innermost composite literal is an
+// empty slice literal, built with new Slice<T>(0): new Slice<T> {} would be
+// the nil slice
+func (cse *CSharpEmitter) insideEmptySliceLit() bool {
+	return len(cse.emptySliceLits) > 0 && cse.emptySliceLits[len(cse.emptySliceLits)-1]
+}
+
 func (cse *CSharpEmitter) PreVisitCompositeLitType(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		str := cse.emitAsString("new ", 0)
-		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		if !cse.insideArrayCompositeLit() {
+			str := cse.emitAsString("new ", 0)
+			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		}
 		// Set type context flag - the composite literal type will be visited next
 		cse.inTypeContext = true
 	})
@@ -1478,6 +3121,11 @@ func (cse *CSharpEmitter) PostVisitCompositeLitType(node ast.Expr, indent int) {
 func (cse *CSharpEmitter) PreVisitCompositeLitElts(node []ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString("{", 0)
+		if cse.insideArrayCompositeLit() {
+			str = cse.emitAsString(".Of(", 0)
+		} else if cse.insideEmptySliceLit() {
+			str = cse.emitAsString("(0", 0)
+		}
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
@@ -1485,6 +3133,9 @@ func (cse *CSharpEmitter) PreVisitCompositeLitElts(node []ast.Expr, indent int)
 func (cse *CSharpEmitter) PostVisitCompositeLitElts(node []ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString("}", 0)
+		if cse.insideArrayCompositeLit() || cse.insideEmptySliceLit() {
+			str = cse.emitAsString(")", 0)
+		}
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
@@ -1498,38 +3149,86 @@ func (cse *CSharpEmitter) PreVisitCompositeLitElt(node ast.Expr, index int, inde
 	})
 }
 
-// LIMITATION: C# slice syntax (arr[low..high]) has issues with the current emitter.
-// The slice expression `arr[:high]` may emit incorrectly as `arr[....high]` (4 dots instead of 2).
-// Workaround: Instead of using slice syntax like `arr = arr[:n]`, use a manual loop:
-//
-//	newArr := []T{}
-//	i := 0
-//	for {
-//	    if i >= n { break }
-//	    newArr = append(newArr, arr[i])
-//	    i = i + 1
-//	}
-//	arr = newArr
-//
-// This limitation is tracked and should be fixed in a future version.
-func (cse *CSharpEmitter) PostVisitSliceExprX(node ast.Expr, indent int) {
+// A slice expression x[low:high:max] calls SliceBuiltins.Slice, which shares
+// the backing array of a slice, or the one of the box of a sliced array
+func (cse *CSharpEmitter) PreVisitSliceExpr(node *ast.SliceExpr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.emitToken("[", LeftBracket, 0)
+		cse.slicedArray = slicedArrayIdent(cse.pkg, node.X)
+		cse.gir.emitToFileBuffer("SliceBuiltins.Slice", EmptyVisitMethod)
+		cse.emitToken("(", LeftParen, 0)
 	})
 }
 
 func (cse *CSharpEmitter) PostVisitSliceExpr(node *ast.SliceExpr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.emitToken("]", RightBracket, 0)
+		cse.emitToken(")", RightParen, 0)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitSliceExprLow(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+		if node == nil {
+			cse.gir.emitToFileBuffer("0", EmptyVisitMethod)
+		}
+		cse.emitBoundCast(node)
 	})
 }
 
 func (cse *CSharpEmitter) PostVisitSliceExprLow(node ast.Expr, indent int) {
+	cse.closeBoundCast(node)
+}
+
+func (cse *CSharpEmitter) PreVisitSliceExprHigh(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if node != nil {
+			cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+		}
+		cse.emitBoundCast(node)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitSliceExprHigh(node ast.Expr, indent int) {
+	cse.closeBoundCast(node)
+}
+
+func (cse *CSharpEmitter) PreVisitSliceExprMax(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+		cse.emitBoundCast(node)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitSliceExprMax(node ast.Expr, indent int) {
+	cse.closeBoundCast(node)
+}
+
+// emitBoundCast opens a (long) cast of a 64-bit unsigned slice bound, which
+// SliceBuiltins.Slice takes as a long
+func (cse *CSharpEmitter) emitBoundCast(bound ast.Expr) {
+	if isWideUnsignedInt(cse.pkg, bound) {
+		cse.gir.emitToFileBuffer("(long)", EmptyVisitMethod)
+		cse.emitToken("(", LeftParen, 0)
+	}
+}
+
+func (cse *CSharpEmitter) closeBoundCast(bound ast.Expr) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.gir.emitToFileBuffer("..", EmptyVisitMethod)
+		if isWideUnsignedInt(cse.pkg, bound) {
+			cse.emitToken(")", RightParen, 0)
+		}
 	})
 }
 
+// emitIndexCast opens an (int) cast of an array index or slice bound wider
+// than int, which C# indexers and ranges take
+func (cse *CSharpEmitter) emitIndexCast(index ast.Expr) {
+	if isWideInt(cse.pkg, index) {
+		cse.gir.emitToFileBuffer("(int)", EmptyVisitMethod)
+		cse.emitToken("(", LeftParen, 0)
+	}
+}
+
 func (cse *CSharpEmitter) PreVisitFuncLit(node *ast.FuncLit, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		cse.emitToken("(", LeftParen, indent)
@@ -1564,24 +3263,31 @@ func (cse *CSharpEmitter) PostVisitFuncLitTypeParam(node *ast.Field, index int,
 		// Clear type context flag - type has been visited
 		cse.inTypeContext = false
 		str := cse.emitAsString(" ", 0)
-		str += cse.emitAsString(node.Names[0].Name, indent)
+		str += cse.emitAsString(localName(cse.pkg, cse.renames, node.Names[0]), indent)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
 
 func (cse *CSharpEmitter) PreVisitFuncLitBody(node *ast.BlockStmt, indent int) {
 	// Note: Don't emit { here - PreVisitBlockStmt will handle it
+	cse.executeIfNotForwardDecls(func() {
+		cse.deferScopes = pushDeferScope(cse.pkg, cse.deferScopes, node, &cse.deferCount)
+	})
 }
 
 func (cse *CSharpEmitter) PreVisitFuncLitTypeResult(node *ast.Field, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		cse.shouldGenerate = false
+		cse.funcLitResultStart = len(cse.gir.tokenSlice)
 	})
 }
 
+// The result types of a lambda are inferred, function types among them are
+// emitted regardless of shouldGenerate and dropped here
 func (cse *CSharpEmitter) PostVisitFuncLitTypeResult(node *ast.Field, index int, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		cse.shouldGenerate = true
+		cse.gir.tokenSlice = cse.gir.tokenSlice[:cse.funcLitResultStart]
 	})
 }
 
@@ -1592,17 +3298,86 @@ func (cse *CSharpEmitter) PreVisitInterfaceType(node *ast.InterfaceType, indent
 	})
 }
 
+func (cse *CSharpEmitter) PreVisitKeyValueExpr(node *ast.KeyValueExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if cse.insideMapCompositeLit() {
+			cse.emitToken("{", LeftBrace, 0)
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitKeyValueExpr(node *ast.KeyValueExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if cse.insideMapCompositeLit() {
+			cse.emitToken("}", RightBrace, 0)
+		}
+	})
+}
+
 func (cse *CSharpEmitter) PreVisitKeyValueExprValue(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if cse.insideMapCompositeLit() {
+			cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+			return
+		}
 		str := cse.emitAsString("= ", 0)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
 
+// Instantiations of generic functions and types list their type arguments
+func (cse *CSharpEmitter) PreVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer("<", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitIndexListExprIndex(node ast.Expr, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if index > 0 {
+			cse.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(">", EmptyVisitMethod)
+	})
+}
+
 func (cse *CSharpEmitter) PreVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		// &x boxes a copy of x
+		if node.Op == token.AND {
+			cse.gir.emitToFileBuffer("Ptr.New(", EmptyVisitMethod)
+			return
+		}
+		if node.Op == token.ARROW {
+			if node == cse.chanRecvCommaOk {
+				cse.gir.emitToFileBuffer("ChanBuiltins.RecvOk", EmptyVisitMethod)
+			} else {
+				cse.gir.emitToFileBuffer("ChanBuiltins.Recv", EmptyVisitMethod)
+			}
+			cse.emitToken("(", LeftParen, 0)
+			return
+		}
+		op := node.Op.String()
+		if node.Op == token.XOR {
+			op = "~"
+		}
+		if basic := intOpType(cse.pkg, node); basic != nil {
+			// -x and ~x of 8 and 16-bit integers yield int, cast back to wrap
+			if intBits(basic) < 32 {
+				cse.gir.emitToFileBuffer("("+csTypesMap[intTypeName(basic)]+")", EmptyVisitMethod)
+			}
+			// C# has no unsigned negation
+			if node.Op == token.SUB && isUnsignedInt(basic) {
+				op = "0 - "
+			}
+		}
 		cse.emitToken("(", LeftParen, 0)
-		str := cse.emitAsString(node.Op.String(), 0)
+		str := cse.emitAsString(op, 0)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
@@ -1612,6 +3387,28 @@ func (cse *CSharpEmitter) PostVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
 	})
 }
 
+// PreVisitStarExpr emits a pointer type *T as Ptr<T>, and a dereference *p
+// as the value p boxes. The receiver of a pointer method is a reference to
+// the value already
+func (cse *CSharpEmitter) PreVisitStarExpr(node *ast.StarExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if isStructPointer(cse.pkg.TypesInfo.TypeOf(node)) {
+			cse.gir.emitToFileBuffer(cse.emitAsString("Ptr", indent), EmptyVisitMethod)
+			cse.gir.emitToFileBuffer("<", EmptyVisitMethod)
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitStarExpr(node *ast.StarExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if isStructPointer(cse.pkg.TypesInfo.TypeOf(node)) {
+			cse.gir.emitToFileBuffer(">", EmptyVisitMethod)
+		} else if !isPointerRecvExpr(cse.pkg, cse.currentFuncDecl, node.X) {
+			cse.gir.emitToFileBuffer(".Value", EmptyVisitMethod)
+		}
+	})
+}
+
 func trimBeforeChar(s string, ch byte) string {
 	pos := strings.IndexByte(s, ch)
 	if pos == -1 {
@@ -1642,6 +3439,11 @@ func (cse *CSharpEmitter) PreVisitGenDeclConstName(node *ast.Ident, indent int)
 				if mappedType, ok := csTypesMap[constType]; ok {
 					constType = mappedType
 				}
+				// An untyped constant stays a C# int where it fits, which
+				// converts implicitly to the smaller integer types like in Go
+				if con.Type() == types.Typ[types.UntypedInt] && fitsInt32(con.Val()) {
+					constType = "int"
+				}
 				// Check if it's a type alias and replace with underlying type
 				if underlyingType, ok := cse.typeAliasMap[constType]; ok {
 					constType = underlyingType
@@ -1666,30 +3468,65 @@ func (cse *CSharpEmitter) PostVisitGenDeclConst(node *ast.GenDecl, indent int) {
 	})
 }
 
-func (cse *CSharpEmitter) PreVisitSliceExprXBegin(node ast.Expr, indent int) {
+// Package-level variables become static fields of the package class, field
+// initializers run in the order they appear, which follows Go's init order
+func (cse *CSharpEmitter) PreVisitGenDeclVar(node PackageVar, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.shouldGenerate = false
+		cse.currentPackageVar = node
+		cse.inTypeContext = true
+		str := cse.emitAsString("public static ", indent+2)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PostVisitSliceExprXBegin(node ast.Expr, indent int) {
+func (cse *CSharpEmitter) PostVisitGenDeclVarType(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.shouldGenerate = true
+		cse.inTypeContext = false
+		cse.gir.emitToFileBuffer(" "+cse.currentPackageVar.Name.Name, EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PreVisitSliceExprXEnd(node ast.Expr, indent int) {
+func (cse *CSharpEmitter) PreVisitGenDeclVarValue(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.shouldGenerate = false
+		cse.gir.emitToFileBuffer(" = ", EmptyVisitMethod)
 	})
 }
 
-func (cse *CSharpEmitter) PostVisitSliceExprXEnd(node ast.Expr, indent int) {
+func (cse *CSharpEmitter) PostVisitGenDeclVar(node PackageVar, indent int) {
 	cse.executeIfNotForwardDecls(func() {
-		cse.shouldGenerate = true
+		str := ";\n"
+		if node.Value == nil {
+			switch t := cse.pkg.TypesInfo.TypeOf(node.Type).Underlying().(type) {
+			case *types.Struct:
+				str = " = new();\n"
+			case *types.Basic:
+				if t.Info()&types.IsString != 0 {
+					str = " = \"\";\n"
+				} else {
+					str = " = default;\n"
+				}
+			default:
+				str = " = default;\n"
+			}
+		}
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitGenDeclVars(node []PackageVar, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer("\n", EmptyVisitMethod)
 	})
 }
 
+// csTypeSwitch is a type switch being lowered to an if-else chain of type
+// patterns on a temporary holding the switch value
+type csTypeSwitch struct {
+	name      string   // Temporary holding the switch value
+	typeStart int      // Token index where the current case type starts
+	caseTypes []string // Types of the current case clause
+}
+
 func (cse *CSharpEmitter) PreVisitSwitchStmt(node *ast.SwitchStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString("switch ", indent)
@@ -1736,7 +3573,7 @@ func (cse *CSharpEmitter) PreVisitCaseClauseListExpr(node ast.Expr, index int, i
 		str := cse.emitAsString("case ", indent+2)
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 		tv := cse.pkg.TypesInfo.Types[node]
-		if typeVal, ok := csTypesMap[tv.Type.String()]; ok {
+		if typeVal, ok := csCastType(tv.Type); ok {
 			cse.emitToken("(", LeftParen, 0)
 			str = cse.emitAsString(typeVal, 0)
 			cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
@@ -1752,14 +3589,141 @@ func (cse *CSharpEmitter) PostVisitCaseClauseListExpr(node ast.Expr, index int,
 	})
 }
 
+// Type switches become an if-else chain of `is T` patterns on a temporary that
+// holds the switch value
+func (cse *CSharpEmitter) PreVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.typeSwitchCount++
+		cse.typeSwitches = append(cse.typeSwitches, csTypeSwitch{
+			name: fmt.Sprintf("_ts%d", cse.typeSwitchCount),
+		})
+		cse.gir.emitToFileBuffer(cse.emitAsString("{\n", indent), EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.typeSwitches = cse.typeSwitches[:len(cse.typeSwitches)-1]
+		cse.gir.emitToFileBuffer("\n"+cse.emitAsString("}", indent), EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) currentTypeSwitch() *csTypeSwitch {
+	return &cse.typeSwitches[len(cse.typeSwitches)-1]
+}
+
+func (cse *CSharpEmitter) PreVisitTypeSwitchStmtX(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		str := cse.emitAsString("var "+cse.currentTypeSwitch().name+" = ", indent+2)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitTypeSwitchStmtX(node ast.Expr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(";\n", EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.currentTypeSwitch().caseTypes = nil
+		switch {
+		case index == 0 && node.List == nil:
+			cse.gir.emitToFileBuffer(cse.emitAsString("", indent+2), EmptyVisitMethod)
+		case index == 0:
+			cse.gir.emitToFileBuffer(cse.emitAsString("if (", indent+2), EmptyVisitMethod)
+		case node.List == nil:
+			cse.gir.emitToFileBuffer(" else ", EmptyVisitMethod)
+		default:
+			cse.gir.emitToFileBuffer(" else if (", EmptyVisitMethod)
+		}
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.gir.emitToFileBuffer(cse.emitAsString("}", indent+2), EmptyVisitMethod)
+	})
+}
+
+func (cse *CSharpEmitter) PreVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		ts := cse.currentTypeSwitch()
+		if index > 0 {
+			cse.gir.emitToFileBuffer(" || ", EmptyVisitMethod)
+		}
+		cse.gir.emitToFileBuffer(ts.name+" is ", EmptyVisitMethod)
+		ts.typeStart = len(cse.gir.tokenSlice)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		ts := cse.currentTypeSwitch()
+		typeTokens, _ := ExtractTokensBetween(ts.typeStart, len(cse.gir.tokenSlice), cse.gir.tokenSlice)
+		ts.caseTypes = append(ts.caseTypes, strings.TrimSpace(strings.Join(tokensToStrings(typeTokens), "")))
+	})
+}
+
+// PreVisitTypeSwitchCaseClauseBody opens the clause and binds the switch
+// variable: to the cast value in single type clauses, to the switch value
+// otherwise
+func (cse *CSharpEmitter) PreVisitTypeSwitchCaseClauseBody(node *ast.CaseClause, index int, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if node.List != nil {
+			cse.gir.emitToFileBuffer(") ", EmptyVisitMethod)
+		}
+		cse.gir.emitToFileBuffer("{\n", EmptyVisitMethod)
+		obj := typeSwitchClauseVar(cse.pkg, node)
+		if obj == nil {
+			return
+		}
+		ts := cse.currentTypeSwitch()
+		value := ts.name
+		if len(ts.caseTypes) == 1 {
+			value = fmt.Sprintf("(%s)%s", ts.caseTypes[0], ts.name)
+		}
+		str := cse.emitAsString(fmt.Sprintf("var %s = %s;\n", objectName(cse.renames, obj), value), indent+4)
+		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	})
+}
+
+// Type assertions are casts, ((T)x), comma-ok assertions test the dynamic type
+// with InterfaceBuiltins.AssertOk<T>(x)
+func (cse *CSharpEmitter) PreVisitTypeAssertExpr(node *ast.TypeAssertExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if node == cse.typeAssertCommaOk {
+			cse.gir.emitToFileBuffer("InterfaceBuiltins.AssertOk", EmptyVisitMethod)
+			return
+		}
+		cse.emitToken("(", LeftParen, indent)
+	})
+}
+
+func (cse *CSharpEmitter) PostVisitTypeAssertExpr(node *ast.TypeAssertExpr, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		cse.emitToken(")", RightParen, 0)
+	})
+}
+
 func (cse *CSharpEmitter) PreVisitTypeAssertExprType(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if cse.typeAssertCommaOk != nil {
+			cse.gir.emitToFileBuffer("<", EmptyVisitMethod)
+			return
+		}
 		cse.emitToken("(", LeftParen, indent)
 	})
 }
 
 func (cse *CSharpEmitter) PostVisitTypeAssertExprType(node ast.Expr, indent int) {
 	cse.executeIfNotForwardDecls(func() {
+		if cse.typeAssertCommaOk != nil {
+			cse.gir.emitToFileBuffer(">", EmptyVisitMethod)
+			cse.emitToken("(", LeftParen, 0)
+			return
+		}
 		cse.emitToken(")", RightParen, indent)
 	})
 }
@@ -1767,10 +3731,33 @@ func (cse *CSharpEmitter) PostVisitTypeAssertExprType(node ast.Expr, indent int)
 func (cse *CSharpEmitter) PreVisitBranchStmt(node *ast.BranchStmt, indent int) {
 	cse.executeIfNotForwardDecls(func() {
 		str := cse.emitAsString(node.Tok.String()+";", indent)
+		if node.Label != nil {
+			str = cse.emitAsString("goto "+branchLabel(node.Label.Name, node.Tok)+";", indent)
+		}
 		cse.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	})
 }
 
+// PreVisitLabeledStmt prepares the goto targets of break L and continue L,
+// C# has no labeled loops
+func (cse *CSharpEmitter) PreVisitLabeledStmt(node *ast.LabeledStmt, indent int) {
+	if _, continues := labelTargets(node); continues {
+		if cse.continueLabels == nil {
+			cse.continueLabels = make(map[*ast.BlockStmt]string)
+		}
+		cse.continueLabels[loopBody(node.Stmt)] = node.Label.Name
+	}
+}
+
+func (cse *CSharpEmitter) PostVisitLabeledStmt(node *ast.LabeledStmt, indent int) {
+	cse.executeIfNotForwardDecls(func() {
+		if breaks, _ := labelTargets(node); breaks {
+			str := cse.emitAsString(branchLabel(node.Label.Name, token.BREAK)+":;\n", indent)
+			cse.gir.emitToFileBuffer("\n"+str, EmptyVisitMethod)
+		}
+	})
+}
+
 // GenerateCsproj creates a .csproj file for building the C# project
 func (cse *CSharpEmitter) GenerateCsproj() error {
 	if cse.LinkRuntime == "" {
@@ -1802,6 +3789,7 @@ func (cse *CSharpEmitter) GenerateCsproj() error {
     <ImplicitUsings>enable</ImplicitUsings>
     <Nullable>enable</Nullable>
     <AllowUnsafeBlocks>true</AllowUnsafeBlocks>
+    <CheckForOverflowUnderflow>false</CheckForOverflowUnderflow>
   </PropertyGroup>
 
 </Project>
@@ -1816,6 +3804,7 @@ func (cse *CSharpEmitter) GenerateCsproj() error {
     <ImplicitUsings>enable</ImplicitUsings>
     <Nullable>enable</Nullable>
     <AllowUnsafeBlocks>true</AllowUnsafeBlocks>
+    <CheckForOverflowUnderflow>false</CheckForOverflowUnderflow>
   </PropertyGroup>
 
   <!-- Compile tigr.c to native library before build -->
@@ -1851,6 +3840,7 @@ func (cse *CSharpEmitter) GenerateCsproj() error {
     <ImplicitUsings>enable</ImplicitUsings>
     <Nullable>enable</Nullable>
     <AllowUnsafeBlocks>true</AllowUnsafeBlocks>
+    <CheckForOverflowUnderflow>false</CheckForOverflowUnderflow>
   </PropertyGroup>
 
   <ItemGroup>
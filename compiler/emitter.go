@@ -85,6 +85,16 @@ type Emitter interface {
 	PreVisitCallExprArg(node ast.Expr, index int, indent int)
 	// PostVisitCallExprArg is called after visiting a specific argument of a function call.
 	PostVisitCallExprArg(node ast.Expr, index int, indent int)
+	// PreVisitCallExprVariadicArgs is called before visiting the arguments passed to the
+	// variadic parameter of a call; index is the position of that parameter.
+	PreVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int)
+	// PostVisitCallExprVariadicArgs is called after visiting the arguments passed to the variadic parameter.
+	PostVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int)
+
+	// PreVisitEllipsis is called before visiting the type of a variadic parameter.
+	PreVisitEllipsis(node *ast.Ellipsis, indent int)
+	// PostVisitEllipsis is called after visiting the type of a variadic parameter.
+	PostVisitEllipsis(node *ast.Ellipsis, indent int)
 
 	// PreVisitParenExpr is called before visiting a parenthesized expression.
 	PreVisitParenExpr(node *ast.ParenExpr, indent int)
@@ -139,6 +149,19 @@ type Emitter interface {
 	// PostVisitIndexExprIndex is called after visiting the index part of an index expression.
 	PostVisitIndexExprIndex(node *ast.IndexExpr, indent int)
 
+	// PreVisitIndexListExpr is called before visiting the instantiation of a generic function or type.
+	PreVisitIndexListExpr(node *ast.IndexListExpr, indent int)
+	// PostVisitIndexListExpr is called after visiting the instantiation of a generic function or type.
+	PostVisitIndexListExpr(node *ast.IndexListExpr, indent int)
+	// PreVisitIndexListExprIndices is called before visiting the type arguments of an instantiation.
+	PreVisitIndexListExprIndices(node *ast.IndexListExpr, indent int)
+	// PostVisitIndexListExprIndices is called after visiting the type arguments of an instantiation.
+	PostVisitIndexListExprIndices(node *ast.IndexListExpr, indent int)
+	// PreVisitIndexListExprIndex is called before visiting a type argument of an instantiation.
+	PreVisitIndexListExprIndex(node ast.Expr, index int, indent int)
+	// PostVisitIndexListExprIndex is called after visiting a type argument of an instantiation.
+	PostVisitIndexListExprIndex(node ast.Expr, index int, indent int)
+
 	// PreVisitUnaryExpr is called before visiting a unary expression.
 	PreVisitUnaryExpr(node *ast.UnaryExpr, indent int)
 	// PostVisitUnaryExpr is called after visiting a unary expression.
@@ -168,6 +191,10 @@ type Emitter interface {
 	PreVisitSliceExprHigh(node ast.Expr, indent int)
 	// PostVisitSliceExprHigh is called after visiting the high bound of a slice expression.
 	PostVisitSliceExprHigh(node ast.Expr, indent int)
+	// PreVisitSliceExprMax is called before visiting the max bound of a 3-index slice expression.
+	PreVisitSliceExprMax(node ast.Expr, indent int)
+	// PostVisitSliceExprMax is called after visiting the max bound of a 3-index slice expression.
+	PostVisitSliceExprMax(node ast.Expr, indent int)
 
 	// PreVisitFuncType is called before visiting a function type.
 	PreVisitFuncType(node *ast.FuncType, indent int)
@@ -255,6 +282,23 @@ type Emitter interface {
 	// PostVisitInterfaceType is called after visiting an interface type.
 	PostVisitInterfaceType(node *ast.InterfaceType, indent int)
 
+	// PreVisitMapType is called before visiting a map type.
+	PreVisitMapType(node *ast.MapType, indent int)
+	// PostVisitMapType is called after visiting a map type.
+	PostVisitMapType(node *ast.MapType, indent int)
+	// PreVisitChanType is called before visiting a channel type.
+	PreVisitChanType(node *ast.ChanType, indent int)
+	// PostVisitChanType is called after visiting a channel type.
+	PostVisitChanType(node *ast.ChanType, indent int)
+	// PreVisitMapKeyType is called before visiting the key type of a map type.
+	PreVisitMapKeyType(node ast.Expr, indent int)
+	// PostVisitMapKeyType is called after visiting the key type of a map type.
+	PostVisitMapKeyType(node ast.Expr, indent int)
+	// PreVisitMapValueType is called before visiting the value type of a map type.
+	PreVisitMapValueType(node ast.Expr, indent int)
+	// PostVisitMapValueType is called after visiting the value type of a map type.
+	PostVisitMapValueType(node ast.Expr, indent int)
+
 	// PreVisitExprStmt is called before visiting an expression statement.
 	PreVisitExprStmt(node *ast.ExprStmt, indent int)
 	// PostVisitExprStmt is called after visiting an expression statement.
@@ -279,10 +323,66 @@ type Emitter interface {
 	PreVisitBranchStmt(node *ast.BranchStmt, indent int)
 	// PostVisitBranchStmt is called after visiting a branch statement (break, continue, goto).
 	PostVisitBranchStmt(node *ast.BranchStmt, indent int)
+	// PreVisitLabeledStmt is called before visiting a labeled statement.
+	PreVisitLabeledStmt(node *ast.LabeledStmt, indent int)
+	// PostVisitLabeledStmt is called after visiting a labeled statement.
+	PostVisitLabeledStmt(node *ast.LabeledStmt, indent int)
 	// PreVisitIncDecStmt is called before visiting an increment/decrement statement.
 	PreVisitIncDecStmt(node *ast.IncDecStmt, indent int)
 	// PostVisitIncDecStmt is called after visiting an increment/decrement statement.
 	PostVisitIncDecStmt(node *ast.IncDecStmt, indent int)
+	// PreVisitDeferStmt is called before visiting a defer statement.
+	PreVisitDeferStmt(node *ast.DeferStmt, indent int)
+	// PostVisitDeferStmt is called after visiting a defer statement.
+	PostVisitDeferStmt(node *ast.DeferStmt, indent int)
+	// PreVisitDeferStmtArg is called before visiting an argument evaluated at the defer statement.
+	PreVisitDeferStmtArg(node DeferArg, indent int)
+	// PostVisitDeferStmtArg is called after visiting an argument evaluated at the defer statement.
+	PostVisitDeferStmtArg(node DeferArg, indent int)
+	// PreVisitDeferStmtCall is called before visiting the deferred call.
+	PreVisitDeferStmtCall(node *ast.CallExpr, indent int)
+	// PostVisitDeferStmtCall is called after visiting the deferred call.
+	PostVisitDeferStmtCall(node *ast.CallExpr, indent int)
+	// PreVisitGoStmt is called before visiting a go statement.
+	PreVisitGoStmt(node *ast.GoStmt, indent int)
+	// PostVisitGoStmt is called after visiting a go statement.
+	PostVisitGoStmt(node *ast.GoStmt, indent int)
+	// PreVisitGoStmtArg is called before visiting an argument evaluated at the go statement.
+	PreVisitGoStmtArg(node DeferArg, indent int)
+	// PostVisitGoStmtArg is called after visiting an argument evaluated at the go statement.
+	PostVisitGoStmtArg(node DeferArg, indent int)
+	// PreVisitGoStmtCall is called before visiting the call run by the new goroutine.
+	PreVisitGoStmtCall(node *ast.CallExpr, indent int)
+	// PostVisitGoStmtCall is called after visiting the call run by the new goroutine.
+	PostVisitGoStmtCall(node *ast.CallExpr, indent int)
+	// PreVisitSendStmt is called before visiting a channel send statement.
+	PreVisitSendStmt(node *ast.SendStmt, indent int)
+	// PostVisitSendStmt is called after visiting a channel send statement.
+	PostVisitSendStmt(node *ast.SendStmt, indent int)
+	// PreVisitSendStmtValue is called before visiting the value of a send statement.
+	PreVisitSendStmtValue(node ast.Expr, indent int)
+	// PostVisitSendStmtValue is called after visiting the value of a send statement.
+	PostVisitSendStmtValue(node ast.Expr, indent int)
+	// PreVisitSelectStmt is called before visiting a select statement.
+	PreVisitSelectStmt(node *ast.SelectStmt, indent int)
+	// PostVisitSelectStmt is called after visiting a select statement.
+	PostVisitSelectStmt(node *ast.SelectStmt, indent int)
+	// PreVisitSelectStmtCase is called before visiting the channel of a select case;
+	// index counts the cases other than default.
+	PreVisitSelectStmtCase(node *ast.CommClause, index int, indent int)
+	// PostVisitSelectStmtCase is called after visiting the channel and sent value of a select case.
+	PostVisitSelectStmtCase(node *ast.CommClause, index int, indent int)
+	// PreVisitSelectStmtCaseValue is called before visiting the value sent by a select case.
+	PreVisitSelectStmtCaseValue(node ast.Expr, indent int)
+	// PostVisitSelectStmtCaseValue is called after visiting the value sent by a select case.
+	PostVisitSelectStmtCaseValue(node ast.Expr, indent int)
+	// PreVisitSelectStmtClauses is called after the cases are evaluated, before the clause bodies.
+	PreVisitSelectStmtClauses(node *ast.SelectStmt, indent int)
+	// PreVisitSelectStmtClause is called before visiting the body of a select clause;
+	// index is the index of its case, -1 for default.
+	PreVisitSelectStmtClause(node *ast.CommClause, index int, indent int)
+	// PostVisitSelectStmtClause is called after visiting the body of a select clause.
+	PostVisitSelectStmtClause(node *ast.CommClause, index int, indent int)
 	// PreVisitAssignStmt is called before visiting an assignment statement.
 	PreVisitAssignStmt(node *ast.AssignStmt, indent int)
 	// PostVisitAssignStmt is called after visiting an assignment statement.
@@ -361,7 +461,20 @@ type Emitter interface {
 	PostVisitCaseClauseList(node []ast.Expr, indent int)
 	PreVisitCaseClauseListExpr(node ast.Expr, index int, indent int)
 	PostVisitCaseClauseListExpr(node ast.Expr, index int, indent int)
+	PreVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int)
+	PostVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int)
+	PreVisitTypeSwitchStmtX(node ast.Expr, indent int)
+	PostVisitTypeSwitchStmtX(node ast.Expr, indent int)
+	PreVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int)
+	PostVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int)
+	PreVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int)
+	PostVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int)
+	PreVisitTypeSwitchCaseClauseBody(node *ast.CaseClause, index int, indent int)
+	PostVisitTypeSwitchCaseClauseBody(node *ast.CaseClause, index int, indent int)
 	PreVisitBlockStmt(node *ast.BlockStmt, indent int)
+	// PreVisitFuncBodyStmts is called before the statements of a function body, after its
+	// opening and the declarations of its named results.
+	PreVisitFuncBodyStmts(node *ast.BlockStmt, indent int)
 	PostVisitBlockStmt(node *ast.BlockStmt, indent int)
 	PreVisitBlockStmtList(node ast.Stmt, index int, indent int)
 	PostVisitBlockStmtList(node ast.Stmt, index int, indent int)
@@ -397,8 +510,22 @@ type Emitter interface {
 	PostVisitGenStructFieldType(node ast.Expr, indent int)
 	PreVisitGenStructFieldName(node *ast.Ident, indent int)
 	PostVisitGenStructFieldName(node *ast.Ident, indent int)
+	PreVisitGenStructMethod(node *ast.FuncDecl, indent int)
+	PostVisitGenStructMethod(node *ast.FuncDecl, indent int)
+	PreVisitGenInterfaceInfo(node GenTypeInfo, indent int)
+	PostVisitGenInterfaceInfo(node GenTypeInfo, indent int)
+	PreVisitGenInterfaceMethod(node *ast.FuncDecl, indent int)
+	PostVisitGenInterfaceMethod(node *ast.FuncDecl, indent int)
 	PreVisitGenDeclConstName(node *ast.Ident, indent int)
 	PostVisitGenDeclConstName(node *ast.Ident, indent int)
+	PreVisitGenDeclVars(node []PackageVar, indent int)
+	PostVisitGenDeclVars(node []PackageVar, indent int)
+	PreVisitGenDeclVar(node PackageVar, indent int)
+	PostVisitGenDeclVar(node PackageVar, indent int)
+	PreVisitGenDeclVarType(node ast.Expr, indent int)
+	PostVisitGenDeclVarType(node ast.Expr, indent int)
+	PreVisitGenDeclVarValue(node ast.Expr, indent int)
+	PostVisitGenDeclVarValue(node ast.Expr, indent int)
 	PreVisitTypeAliasName(node *ast.Ident, indent int)
 	PostVisitTypeAliasName(node *ast.Ident, indent int)
 	PreVisitTypeAliasType(node ast.Expr, indent int)
@@ -0,0 +1,502 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"math"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
+)
+
+// IntSize is the width in bits of int and uint in the generated code: 64 as
+// in Go, or 32
+var IntSize = 64
+
+// intTypeNames returns the names of a backend's int and uint types for
+// IntSize, given its names of the 32 and 64-bit integer types
+func intTypeNames(int32Name, uint32Name, int64Name, uint64Name string) (string, string) {
+	if IntSize == 32 {
+		return int32Name, uint32Name
+	}
+	return int64Name, uint64Name
+}
+
+// isIntType reports whether t is int or uint, whose width depends on IntSize
+func isIntType(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	basic, ok := t.(*types.Basic)
+	return ok && (basic.Kind() == types.Int || basic.Kind() == types.Uint)
+}
+
+// isIntConstant reports whether e is a constant of type int or uint, such as
+// the 5 of x := 5, which the backends would otherwise give their default
+// integer type
+func isIntConstant(pkg *packages.Package, e ast.Expr) bool {
+	tv, ok := pkg.TypesInfo.Types[e]
+	return ok && tv.Value != nil && isIntType(tv.Type)
+}
+
+// wideIntLit returns the value of an integer constant expression, such as
+// 1 << 40, that has an operand or a result which doesn't fit in 32 bits, or
+// that complements an operand, such as ^uint8(0), or nil for other
+// expressions. The backends would evaluate it in their 32-bit default
+// integer type.
+func wideIntLit(pkg *packages.Package, expr ast.Expr) *ast.BasicLit {
+	switch expr.(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.ParenExpr:
+	default:
+		return nil
+	}
+	tv, ok := pkg.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.Int {
+		return nil
+	}
+	wide := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if e, ok := n.(ast.Expr); ok && !wide {
+			if v := pkg.TypesInfo.Types[e].Value; v != nil && v.Kind() == constant.Int {
+				wide = !fitsInt32(v)
+			}
+			if u, ok := e.(*ast.UnaryExpr); ok && u.Op == token.XOR {
+				wide = true
+			}
+		}
+		return !wide
+	})
+	if !wide {
+		return nil
+	}
+	lit := &ast.BasicLit{Kind: token.INT, Value: tv.Value.ExactString(), ValuePos: expr.Pos()}
+	pkg.TypesInfo.Types[lit] = tv
+	return lit
+}
+
+// fitsInt64 reports whether the constant v is an integer in the range of int64
+func fitsInt64(v constant.Value) bool {
+	_, exact := constant.Int64Val(constant.ToInt(v))
+	return exact
+}
+
+// fitsInt32 reports whether the constant v is an integer in the range of int32
+func fitsInt32(v constant.Value) bool {
+	i, exact := constant.Int64Val(constant.ToInt(v))
+	return exact && i >= math.MinInt32 && i <= math.MaxInt32
+}
+
+// isWideInt reports whether e has an integer type wider than 32 bits, which
+// an index of a 32-bit indexer or 32-bit arithmetic would have to narrow
+func isWideInt(pkg *packages.Package, e ast.Expr) bool {
+	if e == nil {
+		return false
+	}
+	basic, ok := pkg.TypesInfo.TypeOf(e).Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch basic.Kind() {
+	case types.Int64, types.Uint64, types.Uintptr:
+		return true
+	case types.Int, types.Uint:
+		return IntSize == 64
+	}
+	return false
+}
+
+// isWideUnsignedInt reports whether e has a 64-bit unsigned integer type,
+// which converts to a signed 64-bit parameter only explicitly
+func isWideUnsignedInt(pkg *packages.Package, e ast.Expr) bool {
+	if e == nil {
+		return false
+	}
+	basic, ok := pkg.TypesInfo.TypeOf(e).Underlying().(*types.Basic)
+	if !ok {
+		return false
+	}
+	switch basic.Kind() {
+	case types.Uint64, types.Uintptr:
+		return true
+	case types.Uint:
+		return IntSize == 64
+	}
+	return false
+}
+
+// boxedIntLits caches the boxed int literals of each package
+var boxedIntLits = map[*packages.Package]map[*ast.BasicLit]bool{}
+
+// isBoxedIntLit reports whether lit is an int or uint literal converted to an
+// interface, such as the 42 of describe(42) where describe takes an any. The
+// backends would box it with their default integer type, which a type switch
+// or assertion on int would not match.
+func isBoxedIntLit(pkg *packages.Package, lit *ast.BasicLit) bool {
+	lits, ok := boxedIntLits[pkg]
+	if !ok {
+		lits = findBoxedIntLits(pkg)
+		boxedIntLits[pkg] = lits
+	}
+	return lits[lit]
+}
+
+// findBoxedIntLits collects the int literals passed as interface arguments,
+// assigned to interface variables, fields or elements, or returned as
+// interface results. Arguments of fmt's printing functions are left out, they
+// print alike however they are boxed.
+func findBoxedIntLits(pkg *packages.Package) map[*ast.BasicLit]bool {
+	lits := make(map[*ast.BasicLit]bool)
+	mark := func(target types.Type, e ast.Expr) {
+		lit, ok := ast.Unparen(e).(*ast.BasicLit)
+		if ok && target != nil && types.IsInterface(target) && isIntConstant(pkg, lit) {
+			lits[lit] = true
+		}
+	}
+	var results []*types.Tuple
+	var inspect func(n ast.Node) bool
+	inspect = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncDecl, *ast.FuncLit:
+			var sig *types.Signature
+			var body *ast.BlockStmt
+			if decl, ok := n.(*ast.FuncDecl); ok {
+				sig, _ = pkg.TypesInfo.ObjectOf(decl.Name).Type().(*types.Signature)
+				body = decl.Body
+			} else {
+				sig, _ = pkg.TypesInfo.TypeOf(n.(*ast.FuncLit)).(*types.Signature)
+				body = n.(*ast.FuncLit).Body
+			}
+			if sig == nil || body == nil {
+				return false
+			}
+			results = append(results, sig.Results())
+			ast.Inspect(body, inspect)
+			results = results[:len(results)-1]
+			return false
+		case *ast.ReturnStmt:
+			if len(results) > 0 && results[len(results)-1].Len() == len(n.Results) {
+				for i, result := range n.Results {
+					mark(results[len(results)-1].At(i).Type(), result)
+				}
+			}
+		case *ast.CallExpr:
+			if isBuiltinCall(pkg, n, "append") && !n.Ellipsis.IsValid() {
+				if slice, ok := pkg.TypesInfo.TypeOf(n).Underlying().(*types.Slice); ok {
+					for _, arg := range n.Args[1:] {
+						mark(slice.Elem(), arg)
+					}
+				}
+				return true
+			}
+			sig, ok := pkg.TypesInfo.TypeOf(n.Fun).(*types.Signature)
+			if !ok {
+				return true
+			}
+			variadic := variadicArgsStart(pkg, n)
+			for i, arg := range n.Args {
+				if variadic >= 0 && i >= variadic {
+					if !n.Ellipsis.IsValid() {
+						mark(sig.Params().At(sig.Params().Len()-1).Type().(*types.Slice).Elem(), arg)
+					}
+				} else if i < sig.Params().Len() && (!sig.Variadic() || i < sig.Params().Len()-1) {
+					mark(sig.Params().At(i).Type(), arg)
+				}
+			}
+		case *ast.AssignStmt:
+			if n.Tok == token.ASSIGN && len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					mark(pkg.TypesInfo.TypeOf(lhs), n.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			if n.Type != nil {
+				for _, value := range n.Values {
+					mark(pkg.TypesInfo.TypeOf(n.Type), value)
+				}
+			}
+		case *ast.CompositeLit:
+			var elem, key types.Type
+			var fields *types.Struct
+			switch t := pkg.TypesInfo.TypeOf(n).Underlying().(type) {
+			case *types.Slice:
+				elem = t.Elem()
+			case *types.Array:
+				elem = t.Elem()
+			case *types.Map:
+				key, elem = t.Key(), t.Elem()
+			case *types.Struct:
+				fields = t
+			}
+			for i, e := range n.Elts {
+				if kv, ok := e.(*ast.KeyValueExpr); ok && fields != nil {
+					mark(pkg.TypesInfo.TypeOf(kv.Key), kv.Value)
+				} else if ok {
+					mark(key, kv.Key)
+					mark(elem, kv.Value)
+				} else if fields != nil && i < fields.NumFields() {
+					mark(fields.Field(i).Type(), e)
+				} else {
+					mark(elem, e)
+				}
+			}
+		}
+		return true
+	}
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, inspect)
+	}
+	return lits
+}
+
+// intOpType returns the type of a non-constant integer expression whose
+// result Go wraps around to the width of its type, or whose shift count Go
+// checks: x + y, x - y, x * y, x / y, x % y, x << n, x >> n, -x and ^x. It
+// returns nil for other expressions.
+func intOpType(pkg *packages.Package, expr ast.Expr) *types.Basic {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.ADD, token.SUB, token.MUL, token.QUO, token.REM, token.SHL, token.SHR:
+		default:
+			return nil
+		}
+	case *ast.UnaryExpr:
+		if e.Op != token.SUB && e.Op != token.XOR {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return nonConstIntType(pkg, expr)
+}
+
+// bitwiseOpType returns the type of a non-constant integer bitwise
+// expression, x & y, x | y, x ^ y or x &^ y, and nil for other expressions.
+// Backends whose bitwise operators promote their operands convert the result
+// back to it.
+func bitwiseOpType(pkg *packages.Package, node *ast.BinaryExpr) *types.Basic {
+	switch node.Op {
+	case token.AND, token.OR, token.XOR, token.AND_NOT:
+	default:
+		return nil
+	}
+	return nonConstIntType(pkg, node)
+}
+
+// nonConstIntType returns the integer type of the non-constant expression
+// expr, or nil when expr is a constant or not an integer
+func nonConstIntType(pkg *packages.Package, expr ast.Expr) *types.Basic {
+	tv := pkg.TypesInfo.Types[expr]
+	if tv.Type == nil || tv.Value != nil {
+		return nil
+	}
+	basic, ok := tv.Type.Underlying().(*types.Basic)
+	if !ok || basic.Info()&types.IsInteger == 0 {
+		return nil
+	}
+	return basic
+}
+
+// intBits returns the width in bits of the integer type basic
+func intBits(basic *types.Basic) int {
+	switch basic.Kind() {
+	case types.Int8, types.Uint8:
+		return 8
+	case types.Int16, types.Uint16:
+		return 16
+	case types.Int32, types.Uint32:
+		return 32
+	case types.Int, types.Uint:
+		return IntSize
+	}
+	return 64
+}
+
+// isUnsignedInt reports whether basic is an unsigned integer type
+func isUnsignedInt(basic *types.Basic) bool {
+	return basic.Info()&types.IsUnsigned != 0
+}
+
+// intTypeName returns the name of the integer type basic, uint8 for byte and
+// int32 for rune
+func intTypeName(basic *types.Basic) string {
+	return types.Typ[basic.Kind()].Name()
+}
+
+// shiftCountInRange reports whether the count of the shift node is a
+// constant smaller than the width of the shifted operand. Native shifts
+// differ from Go's only for larger or negative counts.
+func shiftCountInRange(pkg *packages.Package, node *ast.BinaryExpr, basic *types.Basic) bool {
+	v := pkg.TypesInfo.Types[node.Y].Value
+	if v == nil {
+		return false
+	}
+	n, exact := constant.Int64Val(constant.ToInt(v))
+	return exact && n < int64(intBits(basic))
+}
+
+// isSignedQuo reports whether node is a signed division or remainder by a
+// non-constant divisor, or by -1. Go defines the quotient of the most
+// negative value by -1 as the dividend and the remainder as 0, where native
+// 32 and 64-bit division traps.
+func isSignedQuo(pkg *packages.Package, node *ast.BinaryExpr, basic *types.Basic) bool {
+	if (node.Op != token.QUO && node.Op != token.REM) || isUnsignedInt(basic) {
+		return false
+	}
+	v := pkg.TypesInfo.Types[node.Y].Value
+	if v == nil {
+		return true
+	}
+	n, exact := constant.Int64Val(constant.ToInt(v))
+	return exact && n == -1
+}
+
+// mayDivideByZero reports whether node is an integer division or remainder
+// by a non-constant divisor, which Go checks for zero at run time. A constant
+// zero divisor does not compile.
+func mayDivideByZero(pkg *packages.Package, node *ast.BinaryExpr) bool {
+	return (node.Op == token.QUO || node.Op == token.REM) && pkg.TypesInfo.Types[node.Y].Value == nil
+}
+
+// lowerIntAssignOps turns x op= y and x++ on integers into x = x op y and
+// x = x + 1, so that the arithmetic is a binary expression each backend
+// wraps around like Go. Post statements of for loops, which backends turn
+// into native loops, and targets with calls, which would be evaluated
+// twice, are left as they are.
+func lowerIntAssignOps(pkg *packages.Package) {
+	forPosts := make(map[ast.Stmt]bool)
+	isInt := func(e ast.Expr) bool {
+		basic, ok := pkg.TypesInfo.TypeOf(e).Underlying().(*types.Basic)
+		return ok && basic.Info()&types.IsInteger != 0
+	}
+	lower := func(lhs ast.Expr, op token.Token, rhs ast.Expr) *ast.AssignStmt {
+		read := cloneExpr(pkg, lhs)
+		if read == nil {
+			return nil
+		}
+		if _, ok := rhs.(*ast.BinaryExpr); ok {
+			paren := &ast.ParenExpr{Lparen: rhs.Pos(), X: rhs, Rparen: rhs.End()}
+			pkg.TypesInfo.Types[paren] = pkg.TypesInfo.Types[rhs]
+			rhs = paren
+		}
+		value := &ast.BinaryExpr{X: read, OpPos: lhs.End(), Op: op, Y: rhs}
+		pkg.TypesInfo.Types[value] = types.TypeAndValue{Type: pkg.TypesInfo.TypeOf(lhs)}
+		return &ast.AssignStmt{Lhs: []ast.Expr{lhs}, TokPos: lhs.End(), Tok: token.ASSIGN, Rhs: []ast.Expr{value}}
+	}
+	for _, file := range pkg.Syntax {
+		astutil.Apply(file, func(c *astutil.Cursor) bool {
+			if loop, ok := c.Node().(*ast.ForStmt); ok && loop.Post != nil {
+				forPosts[loop.Post] = true
+			}
+			return true
+		}, func(c *astutil.Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.AssignStmt:
+				if forPosts[n] || len(n.Lhs) != 1 || n.Tok < token.ADD_ASSIGN || n.Tok > token.AND_NOT_ASSIGN || !isInt(n.Lhs[0]) {
+					return true
+				}
+				if assign := lower(n.Lhs[0], token.ADD+n.Tok-token.ADD_ASSIGN, n.Rhs[0]); assign != nil {
+					c.Replace(assign)
+				}
+			case *ast.IncDecStmt:
+				if forPosts[n] || !isInt(n.X) {
+					return true
+				}
+				one := &ast.BasicLit{ValuePos: n.TokPos, Kind: token.INT, Value: "1"}
+				pkg.TypesInfo.Types[one] = types.TypeAndValue{Type: pkg.TypesInfo.TypeOf(n.X), Value: constant.MakeInt64(1)}
+				op := token.ADD
+				if n.Tok == token.DEC {
+					op = token.SUB
+				}
+				if assign := lower(n.X, op, one); assign != nil {
+					c.Replace(assign)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// cloneExpr returns a copy of expr, which reads the same value, or nil when
+// expr has calls or receives
+func cloneExpr(pkg *packages.Package, expr ast.Expr) ast.Expr {
+	var clone ast.Expr
+	switch e := expr.(type) {
+	case *ast.Ident:
+		ident := &ast.Ident{NamePos: e.NamePos, Name: e.Name}
+		if obj := pkg.TypesInfo.Uses[e]; obj != nil {
+			pkg.TypesInfo.Uses[ident] = obj
+		}
+		clone = ident
+	case *ast.BasicLit:
+		clone = &ast.BasicLit{ValuePos: e.ValuePos, Kind: e.Kind, Value: e.Value}
+	case *ast.ParenExpr:
+		x := cloneExpr(pkg, e.X)
+		if x == nil {
+			return nil
+		}
+		clone = &ast.ParenExpr{Lparen: e.Lparen, X: x, Rparen: e.Rparen}
+	case *ast.StarExpr:
+		x := cloneExpr(pkg, e.X)
+		if x == nil {
+			return nil
+		}
+		clone = &ast.StarExpr{Star: e.Star, X: x}
+	case *ast.SelectorExpr:
+		x := cloneExpr(pkg, e.X)
+		if x == nil {
+			return nil
+		}
+		sel := cloneExpr(pkg, e.Sel).(*ast.Ident)
+		clone = &ast.SelectorExpr{X: x, Sel: sel}
+		if selection, ok := pkg.TypesInfo.Selections[e]; ok {
+			pkg.TypesInfo.Selections[clone.(*ast.SelectorExpr)] = selection
+		}
+	case *ast.IndexExpr:
+		x, index := cloneExpr(pkg, e.X), cloneExpr(pkg, e.Index)
+		if x == nil || index == nil {
+			return nil
+		}
+		clone = &ast.IndexExpr{X: x, Lbrack: e.Lbrack, Index: index, Rbrack: e.Rbrack}
+	case *ast.BinaryExpr:
+		x, y := cloneExpr(pkg, e.X), cloneExpr(pkg, e.Y)
+		if x == nil || y == nil {
+			return nil
+		}
+		clone = &ast.BinaryExpr{X: x, OpPos: e.OpPos, Op: e.Op, Y: y}
+	case *ast.UnaryExpr:
+		if e.Op == token.ARROW {
+			return nil
+		}
+		x := cloneExpr(pkg, e.X)
+		if x == nil {
+			return nil
+		}
+		clone = &ast.UnaryExpr{OpPos: e.OpPos, Op: e.Op, X: x}
+	default:
+		return nil
+	}
+	if tv, ok := pkg.TypesInfo.Types[expr]; ok {
+		pkg.TypesInfo.Types[clone] = tv
+	}
+	return clone
+}
+
+// intOp is the emission of a binary integer operation a backend performs
+// other than with its native operator: open, the left operand, sep, the
+// right operand and close. The zero intOp is the native operator.
+type intOp struct {
+	open, sep, close string
+}
+
+// isConstOperand reports whether operand is a constant other than a literal,
+// such as a named constant, which a backend may have declared with another
+// integer type than the operation's
+func isConstOperand(pkg *packages.Package, operand ast.Expr) bool {
+	if _, ok := ast.Unparen(operand).(*ast.BasicLit); ok {
+		return false
+	}
+	return pkg.TypesInfo.Types[operand].Value != nil
+}
@@ -3,6 +3,7 @@ package compiler
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
 	"os"
@@ -18,6 +19,7 @@ var jsTypesMap = map[string]string{
 	"int32":  "number",
 	"int64":  "number",
 	"int":    "number",
+	"uint":   "number",
 	"uint8":  "number",
 	"uint16": "number",
 	"uint32": "number",
@@ -61,14 +63,19 @@ type JSEmitter struct {
 	inMultiValueReturn    bool
 	multiValueReturnIndex int
 	numFuncResults        int
+	// Last arguments of spread calls append(s, xs...) and fmt.F(xs...),
+	// innermost last
+	spreadArgs            []ast.Expr
+	passedSlices          map[ast.Expr]bool // Slices f(xs...) passes to a variadic parameter as they are
 	// Type suppression for JavaScript (no type annotations)
 	suppressTypeEmit      bool
+	typeArgsSuppress      []bool // suppressTypeEmit saved around each type argument list
 	// For loop init section (suppress semicolon after assignment)
 	insideForInit         bool
 	// Pending slice/struct/basic type initialization
 	pendingSliceInit      bool
 	pendingStructInit     bool
-	pendingStructType     *types.Struct // Store struct type for full initialization
+	pendingStructType     types.Type    // Store struct type for full initialization
 	pendingBasicInit      *types.Basic  // Store basic type for proper initialization
 	// Namespace handling for non-main packages
 	inNamespace           bool
@@ -76,8 +83,67 @@ type JSEmitter struct {
 	inFuncLit             bool
 	// Integer division handling
 	intDivision           bool
-	// String indexing - use charCodeAt for string[i] to return number like Go
-	isStringIndex         bool
+	// Slice and string indexing - checked against the length like Go
+	sliceLvalues          []ast.Expr // Slice elements being assigned to, their index is checked in place
+	captureIndexExpr      bool       // Capture emitted text into indexExprText
+	indexExprText         string
+	// Map support
+	isMapRange            bool
+	isStringRange         bool
+	isSliceRange          bool
+	rangeStringBindings   string
+	mapTypeNode           *ast.MapType // Outermost map type being emitted as Map
+	mapLvalue             ast.Expr     // Map index expression being assigned to (m[k] = v, m[k]++)
+	mapLvalueTok          string       // Assignment operator of the map lvalue statement
+	captureMapExpr        bool         // Capture emitted text into mapExprText
+	mapExprText           string
+	mapLhsMap             string       // Captured map expression of the lvalue
+	mapLhsKey             string       // Captured key expression of the lvalue
+	mapCommaOkExpr        ast.Expr     // Map index expression of a comma-ok lookup (v, ok := m[k])
+	mapCompositeLits      []bool       // Stack tracking which composite literals are map literals
+	arrayComparisons      []bool       // Stack tracking which binary expressions compare arrays
+	nilComparisons        []bool       // Stack tracking which binary expressions compare a slice or a map with nil
+	collectionNils        map[ast.Expr]bool // nil compared with a slice or a map, not emitted
+	intOps                []intOp      // Integer operations of the binary expressions being emitted, innermost last
+	bigIntConst           ast.Expr     // Constant emitted as a BigInt literal, its operands are not emitted
+	bigIntPrintArgs       map[ast.Expr]bool // console.log arguments converted to strings, BigInts print with an n otherwise
+	// Pointers are references to the struct objects
+	derefLvalue           ast.Expr // Dereference or sliced array being assigned to (*p = v), lowered to Object.assign
+	newCallArg            ast.Expr // Type argument of the new(T) call being emitted
+	sliceMakeType         ast.Expr // Type argument of the make([]T, n) call being emitted
+	typeAssertCommaOk     ast.Expr     // Type assertion of a comma-ok assignment (v, ok := x.(T))
+	typeSwitchNames       []string     // Temporaries holding the values of enclosing type switches
+	typeSwitchCount       int
+	pendingMapInit        bool
+	// Method support
+	currentFuncDecl       *ast.FuncDecl           // Function or method being emitted
+	renames               map[types.Object]string // Locals renamed so they don't shadow an outer variable
+	pendingRecvDecl       string                  // Receiver binding to emit at the start of a method body
+	variadicParam         string                  // Rest parameter to turn into a slice at the start of the function body
+	deferScopes           []deferScope            // Enclosing function bodies, innermost last
+	deferCount            int                     // Number of defer stacks named so far
+	captureMethod         bool                    // Capture namespace methods into methodsText
+	methodsText           string                  // Prototype assignments emitted after the namespace object
+	// Package-level variables
+	initVarsPending       bool // The package declared $initVars, call it once the package is complete
+	// Goroutines and channels
+	blockingFuncs         map[types.Object]bool  // Functions that may wait for a channel, emitted async
+	blockingLits          map[*ast.FuncLit]bool  // Function literals that may wait for a channel
+	blockingMethods       map[string]bool        // Names of the methods that may wait, for calls through interfaces
+	blockingValues        bool                   // A function used as a value may wait, for calls through func values
+	asyncMain             bool                   // main is async, the program ends when its promise settles
+	chanRecvCommaOk       ast.Expr               // Receive of a comma-ok assignment (v, ok := <-ch)
+	chanTypeNode          *ast.ChanType          // Outermost channel type being emitted as a ChanType
+	selectCount           int
+	selects               []string // Names of the select statements being emitted
+	// Struct values
+	mutatedVars           map[types.Object]bool // Struct variables changed in place, as by v.X = 1
+	capturedVars          map[types.Object]bool // Variables seen by function literals or goroutines
+	ownedVars             map[types.Object]bool // Variables whose object nothing else refers to
+	paramCopies           map[types.Object]bool // Parameters whose arguments are copied by the caller
+	structCopies          map[ast.Expr]bool     // Assigned values, arguments and results copied with copyStruct
+	elementCopies         map[ast.Expr]string   // Slices whose struct elements append or copy duplicates, with the text closing them
+	rangeValueCopyArgs    string                // Arguments closing the copy of the range value, empty if it is not copied
 }
 
 func (*JSEmitter) lowerToBuiltins(selector string) string {
@@ -94,11 +160,26 @@ func (*JSEmitter) lowerToBuiltins(selector string) string {
 		return "print" // Uses process.stdout.write (no newline)
 	case "len":
 		return "len"
+	case "delete":
+		return "mapDelete"
+	case "close":
+		return "chanClose"
 	}
 	return selector
 }
 
 func (jse *JSEmitter) emitToFile(s string) error {
+	if jse.bigIntConst != nil {
+		return nil
+	}
+	if jse.captureMapExpr {
+		jse.mapExprText += s
+		return nil
+	}
+	if jse.captureIndexExpr {
+		jse.indexExprText += s
+		return nil
+	}
 	if jse.captureRangeExpr {
 		jse.rangeCollectionExpr += s
 		return nil
@@ -106,6 +187,10 @@ func (jse *JSEmitter) emitToFile(s string) error {
 	if jse.suppressRangeEmit {
 		return nil
 	}
+	if jse.captureMethod {
+		jse.methodsText += s
+		return nil
+	}
 	return emitToFile(jse.file, s)
 }
 
@@ -136,25 +221,560 @@ func (jse *JSEmitter) PreVisitProgram(indent int) {
 "use strict";
 
 // Runtime helpers
+
+// A Go slice: length elements from offset in an array shared by the slices
+// taken of one another, up to capacity of which append fills in place
+class Slice {
+  constructor(array, offset, length, capacity) {
+    this.array = array;
+    this.offset = offset;
+    this.length = length;
+    this.capacity = capacity;
+  }
+
+  *[Symbol.iterator]() {
+    for (let i = 0; i < this.length; i++) yield this.array[this.offset + i];
+  }
+}
+
+// A slice type, make() fills the slices it creates with zero()
+class SliceType {
+  constructor(zero) {
+    this.zero = zero;
+  }
+}
+
+// A slice literal owns the array of its elements
+function sliceOf(elements) {
+  return new Slice(elements, 0, elements.length, elements.length);
+}
+
+// The nil slice has no array, unlike the empty slice []T{}
+function nilSlice() {
+  return new Slice(null, 0, 0, 0);
+}
+
+// f(xs...) hands xs itself to the variadic parameter of f, which shares its
+// array, instead of spreading its elements into the rest parameter
+class Spread {
+  constructor(slice) {
+    this.slice = slice;
+  }
+}
+
+// The slice a variadic parameter holds: the slice spread by f(xs...), nil for
+// no arguments, or a new slice of the arguments
+function restSlice(args) {
+  if (args.length === 1 && args[0] instanceof Spread) {
+    return args[0].slice;
+  }
+  return args.length === 0 ? nilSlice() : sliceOf(args);
+}
+
+// The nil map, which reads as an empty map
+class NilMap extends Map {}
+
+// s == nil for a slice or a map
+function isNil(x) {
+  if (x instanceof Slice) return x.array === null;
+  return x instanceof NilMap;
+}
+
 function len(arr) {
+  if (arr instanceof Slice) return arr.length;
   if (typeof arr === 'string') return arr.length;
   if (Array.isArray(arr)) return arr.length;
+  if (arr instanceof Map) return arr.size;
   return 0;
 }
 
-function append(arr, ...items) {
+function cap(s) {
+  if (s instanceof Slice) return s.capacity;
+  if (s instanceof Chan) return s.capacity;
+  return len(s);
+}
+
+function append(s, ...items) {
   // Handle nil/undefined slices like Go does
-  if (arr == null) arr = [];
-  // Clone plain objects to preserve Go's value semantics for structs
-  // Use push for O(1) amortized instead of spread which is O(n)
-  for (const item of items) {
-    if (item && typeof item === 'object' && !Array.isArray(item)) {
-      arr.push({...item});
+  if (s == null) s = nilSlice();
+  const length = s.length + items.length;
+  let array = s.array;
+  let offset = s.offset;
+  let capacity = s.capacity;
+  // Past the capacity the elements move to a new array of twice the capacity
+  if (length > capacity) {
+    capacity = Math.max(length, 2 * capacity);
+    array = new Array(capacity);
+    for (let i = 0; i < s.length; i++) array[i] = s.array[s.offset + i];
+    offset = 0;
+  }
+  for (let i = 0; i < items.length; i++) array[offset + s.length + i] = items[i];
+  return new Slice(array, offset, length, capacity);
+}
+
+// copy(dst, src) copies as many elements as both have and returns how many,
+// src may overlap dst in their shared array. copyElement copies struct elements
+function copy(dst, src, copyElement) {
+  const n = Math.min(len(dst), len(src));
+  if (n > 0) {
+    if (dst.array === src.array) {
+      dst.array.copyWithin(dst.offset, src.offset, src.offset + n);
     } else {
-      arr.push(item);
+      for (let i = 0; i < n; i++) dst.array[dst.offset + i] = src.array[src.offset + i];
+    }
+    if (copyElement) {
+      for (let i = 0; i < n; i++) dst.array[dst.offset + i] = copyElement(dst.array[dst.offset + i]);
+    }
+  }
+  return n;
+}
+
+// Copy of a struct that keeps its prototype, and so its methods. nested maps
+// the fields holding structs or arrays to the functions copying them
+function copyStruct(s, nested) {
+  const c = Object.assign(Object.create(Object.getPrototypeOf(s)), s);
+  for (const field in nested) c[field] = nested[field](c[field]);
+  return c;
+}
+
+// Go arrays are values: a copy duplicates the array and the arrays nested
+// depth levels deep in it, copyElement copies struct elements
+function arrayCopy(a, depth, copyElement) {
+  if (depth > 1) return a.map(e => arrayCopy(e, depth - 1, copyElement));
+  return copyElement ? a.map(copyElement) : a.slice();
+}
+
+function arrayEqual(a, b) {
+  for (let i = 0; i < a.length; i++) {
+    const nested = Array.isArray(a[i]) || ArrayBuffer.isView(a[i]);
+    if (nested ? !arrayEqual(a[i], b[i]) : a[i] !== b[i]) return false;
+  }
+  return true;
+}
+
+// Go panics are thrown as GoPanic: the value passed to panic() and the
+// message printed when nothing recovers it
+class GoPanic extends Error {
+  constructor(value, message) {
+    super(message);
+    this.value = value;
+    this.recovered = false;
+  }
+}
+
+// The panic whose deferred calls are running, recover() stops it
+let recoverablePanic = null;
+
+function panic(value) {
+  throw new GoPanic(value, panicMessage(value));
+}
+
+// Panics raised by the runtime carry their message as the value
+function errorPanic(message) {
+  return new GoPanic(message, message);
+}
+
+function runtimePanic(message) {
+  return errorPanic("runtime error: " + message);
+}
+
+// Go shifts of integers of the given width: a count of at least the width
+// shifts out every bit, a negative count panics. Integers wider than 32 bits
+// are shifted arithmetically, exactly up to 2^53.
+function intShl(x, n, bits) {
+  n = Number(n);
+  if (n < 0) throw runtimePanic("negative shift amount");
+  if (n >= bits) return 0;
+  return bits > 32 ? x * 2 ** n : x << n;
+}
+
+function intShr(x, n, bits) {
+  n = Number(n);
+  if (n < 0) throw runtimePanic("negative shift amount");
+  if (bits > 32) return Math.floor(x / 2 ** Math.min(n, bits));
+  if (n >= bits) return x < 0 ? -1 : 0;
+  return x < 0 ? x >> n : x >>> n;
+}
+
+// The divisor of an integer division or remainder, zero panics like in Go
+function divisor(y) {
+  if (y === 0) throw runtimePanic("integer divide by zero");
+  return y;
+}
+
+// The count of a shift of a BigInt, counts beyond 64 shift out every bit
+function bigShift(n) {
+  if (n < 0) throw runtimePanic("negative shift amount");
+  return BigInt(n > 64 ? 64 : n);
+}
+
+// 64-bit int and uint values are numbers while they are exact, up to 2^53,
+// and BigInts beyond. Operations on them wrap around to 64 bits like in Go,
+// through BigInts when the result of the numbers would not be exact.
+const wideExactMax = BigInt(Number.MAX_SAFE_INTEGER);
+
+function wideInt(v, signed) {
+  v = signed ? BigInt.asIntN(64, v) : BigInt.asUintN(64, v);
+  return v >= -wideExactMax && v <= wideExactMax ? Number(v) : v;
+}
+
+function wideExact(r, signed) {
+  return Number.isSafeInteger(r) && (signed || r >= 0);
+}
+
+function wideAdd(a, b, signed) {
+  if (typeof a === 'number' && typeof b === 'number' && wideExact(a + b, signed)) return a + b;
+  return wideInt(BigInt(a) + BigInt(b), signed);
+}
+
+function wideSub(a, b, signed) {
+  if (typeof a === 'number' && typeof b === 'number' && wideExact(a - b, signed)) return a - b;
+  return wideInt(BigInt(a) - BigInt(b), signed);
+}
+
+function wideMul(a, b, signed) {
+  if (typeof a === 'number' && typeof b === 'number' && wideExact(a * b, signed)) return a * b + 0;
+  return wideInt(BigInt(a) * BigInt(b), signed);
+}
+
+function wideQuo(a, b, signed) {
+  if (b === 0) throw runtimePanic("integer divide by zero");
+  if (typeof a === 'number' && typeof b === 'number') return Math.trunc(a / b) + 0;
+  return wideInt(BigInt(a) / BigInt(b), signed);
+}
+
+function wideRem(a, b, signed) {
+  if (b === 0) throw runtimePanic("integer divide by zero");
+  if (typeof a === 'number' && typeof b === 'number') return a % b + 0;
+  return wideInt(BigInt(a) % BigInt(b), signed);
+}
+
+function wideShl(x, n, signed) {
+  n = Number(n);
+  if (n < 0) throw runtimePanic("negative shift amount");
+  if (n >= 64) return 0;
+  if (typeof x === 'number' && wideExact(x * 2 ** n, signed)) return x * 2 ** n;
+  return wideInt(BigInt(x) << BigInt(n), signed);
+}
+
+function wideShr(x, n, signed) {
+  n = Number(n);
+  if (n < 0) throw runtimePanic("negative shift amount");
+  if (typeof x === 'number') return Math.floor(x / 2 ** Math.min(n, 64)) + 0;
+  return wideInt(x >> BigInt(Math.min(n, 64)), signed);
+}
+
+// Bitwise operations on numbers are exact for 32-bit operands only
+function wideBits(a, b) {
+  return typeof a === 'number' && typeof b === 'number' && (a | 0) === a && (b | 0) === b;
+}
+
+function wideAnd(a, b, signed) {
+  return wideBits(a, b) ? a & b : wideInt(BigInt(a) & BigInt(b), signed);
+}
+
+function wideOr(a, b, signed) {
+  return wideBits(a, b) ? a | b : wideInt(BigInt(a) | BigInt(b), signed);
+}
+
+function wideXor(a, b, signed) {
+  return wideBits(a, b) ? a ^ b : wideInt(BigInt(a) ^ BigInt(b), signed);
+}
+
+function wideAndNot(a, b, signed) {
+  return wideBits(a, b) ? a & ~b : wideInt(BigInt(a) & ~BigInt(b), signed);
+}
+
+function wideNeg(x, signed) {
+  if (typeof x === 'number' && wideExact(-x, signed)) return -x + 0;
+  return wideInt(-BigInt(x), signed);
+}
+
+function wideNot(x, signed) {
+  if (typeof x === 'number' && wideExact(-x - 1, signed)) return -x - 1;
+  return wideInt(~BigInt(x), signed);
+}
+
+function recover() {
+  if (recoverablePanic === null || recoverablePanic.recovered) return null;
+  recoverablePanic.recovered = true;
+  return recoverablePanic.value;
+}
+
+// Panic values print like Go prints them
+function panicMessage(value) {
+  if (typeof value === 'string') return value;
+  if (typeof value === 'number' && !Number.isInteger(value)) {
+    const [mantissa, exponent] = value.toExponential(6).split('e');
+    const sign = mantissa.startsWith('-') ? '' : '+';
+    return sign + mantissa + 'e' + exponent[0] + exponent.slice(1).padStart(3, '0');
+  }
+  if (typeof value === 'number' || typeof value === 'bigint' || typeof value === 'boolean') return String(value);
+  return "(panic value)";
+}
+
+// Errors of the JavaScript runtime become the panics Go raises for them
+function toPanic(e) {
+  if (e instanceof GoPanic) return e;
+  if (e instanceof TypeError) return runtimePanic("invalid memory address or nil pointer dereference");
+  if (e instanceof RangeError && e.message === "Division by zero") return runtimePanic("integer divide by zero");
+  return errorPanic(String(e && e.message));
+}
+
+// Runs the deferred calls of a function unwinding with e, recover() in them
+// stops the panic. Reports whether it was recovered.
+function recoverPanic(e, defers) {
+  const p = toPanic(e);
+  const outer = recoverablePanic;
+  recoverablePanic = p;
+  try {
+    while (defers.length > 0) defers.pop()();
+  } finally {
+    recoverablePanic = outer;
+  }
+  return p.recovered;
+}
+
+// recoverPanic of a function whose deferred calls may wait for a channel
+async function recoverPanicAsync(e, defers) {
+  const p = toPanic(e);
+  const outer = recoverablePanic;
+  recoverablePanic = p;
+  try {
+    while (defers.length > 0) await defers.pop()();
+  } finally {
+    recoverablePanic = outer;
+  }
+  return p.recovered;
+}
+
+// An unrecovered panic prints its message and exits with status 2, like Go
+function exitPanic(e) {
+  if (typeof process === 'undefined') throw e;
+  console.error("panic: " + toPanic(e).message);
+  process.exit(2);
+}
+
+// Goroutines are async functions run by a cooperative scheduler: a goroutine
+// runs until it waits for a channel. When every goroutine waits, none is
+// left to wake the others.
+let goroutinesAlive = 1;
+// Goroutines waiting since the last change, a change wakes all of them
+let goroutineWakers = [];
+
+function deadlock() {
+  const message = "fatal error: all goroutines are asleep - deadlock!";
+  if (typeof process === 'undefined') throw new Error(message);
+  console.error(message);
+  process.exit(2);
+}
+
+// Wakes the goroutines waiting for a channel to change
+function notifyChange() {
+  const wakers = goroutineWakers;
+  goroutineWakers = [];
+  for (const wake of wakers) wake();
+}
+
+// Waits for a channel to change, letting the other goroutines run
+function waitForChange() {
+  if (goroutineWakers.length + 1 === goroutinesAlive) deadlock();
+  return new Promise(resolve => goroutineWakers.push(resolve));
+}
+
+// go f(): runs f in a new goroutine once the current one waits or returns
+function go(f) {
+  goroutinesAlive++;
+  Promise.resolve().then(f).then(() => {
+    goroutinesAlive--;
+    if (goroutineWakers.length > 0 && goroutineWakers.length === goroutinesAlive) deadlock();
+  }, exitPanic);
+}
+
+// A channel type, make() creates channels whose closed receives return zero()
+class ChanType {
+  constructor(zero) {
+    this.zero = zero;
+  }
+}
+
+// A channel, null is the nil channel
+class Chan {
+  constructor(capacity, zero) {
+    this.items = [];
+    this.capacity = capacity;
+    this.zero = zero;
+    this.closed = false;
+    // An unbuffered send waits until its item is received
+    this.sent = 0;
+    this.received = 0;
+    // Goroutines waiting to receive, an unbuffered send in a select needs one
+    this.receivers = 0;
+  }
+
+  canSend() {
+    return this.closed || (this.capacity > 0 ? this.items.length < this.capacity : this.receivers > this.items.length);
+  }
+
+  canRecv() {
+    return this.closed || this.items.length > 0;
+  }
+
+  async send(value) {
+    if (this.closed) throw errorPanic("send on closed channel");
+    this.items.push(value);
+    const seq = ++this.sent;
+    notifyChange();
+    while (this.capacity === 0 && this.received < seq) await waitForChange();
+  }
+
+  // The zero value and false once the channel is closed and drained
+  recv() {
+    if (this.items.length === 0) return [this.zero(), false];
+    const value = this.items.shift();
+    this.received++;
+    notifyChange();
+    return [value, true];
+  }
+}
+
+// Operations on a nil channel block forever
+async function blockForever() {
+  for (;;) await waitForChange();
+}
+
+async function chanSend(ch, value) {
+  if (ch === null) await blockForever();
+  while (!ch.closed && ch.capacity > 0 && ch.items.length >= ch.capacity) await waitForChange();
+  await ch.send(value);
+}
+
+// Comma-ok receive: v, ok := <-ch
+async function chanRecvOk(ch) {
+  if (ch === null) await blockForever();
+  ch.receivers++;
+  notifyChange();
+  while (!ch.canRecv()) await waitForChange();
+  ch.receivers--;
+  return ch.recv();
+}
+
+async function chanRecv(ch) {
+  return (await chanRecvOk(ch))[0];
+}
+
+function chanClose(ch) {
+  if (ch === null) throw errorPanic("close of nil channel");
+  if (ch.closed) throw errorPanic("close of closed channel");
+  ch.closed = true;
+  notifyChange();
+}
+
+// Cases of a select statement, the cases of a nil channel are never ready
+function selectRecv(ch) {
+  return {
+    ready: () => ch !== null && ch.canRecv(),
+    async commit() { [this.value, this.ok] = ch.recv(); },
+    waiting: delta => { if (ch !== null) ch.receivers += delta; },
+  };
+}
+
+function selectSend(ch, value) {
+  return {
+    ready: () => ch !== null && ch.canSend(),
+    commit: () => ch.send(value),
+    waiting: () => {},
+  };
+}
+
+// Runs the first ready case of a select, waiting for one unless the select
+// has a default. Returns the index of the case, -1 for default.
+async function chanSelect(cases, hasDefault) {
+  let registered = false;
+  for (;;) {
+    for (let i = 0; i < cases.length; i++) {
+      if (!cases[i].ready()) continue;
+      if (registered) {
+        for (const c of cases) c.waiting(-1);
+      }
+      await cases[i].commit();
+      return i;
+    }
+    if (hasDefault) return -1;
+    if (!registered) {
+      registered = true;
+      for (const c of cases) c.waiting(1);
+      notifyChange();
     }
+    await waitForChange();
+  }
+}
+
+// Index of a slice or string, checked like Go checks every index
+function indexCheck(arr, i) {
+  const n = len(arr);
+  if (i < 0) throw runtimePanic("index out of range [" + i + "]");
+  if (i >= n) throw runtimePanic("index out of range [" + i + "] with length " + n);
+  return i;
+}
+
+function sliceAt(s, i) {
+  return s.array[s.offset + indexCheck(s, i)];
+}
+
+// Bounds of x[low:high:max] checked like Go, limit is the capacity of a slice
+// or the length of a string or array
+function checkSliceBounds(low, high, max, limit, slice3, limitName) {
+  if (slice3 && (max < 0 || max > limit)) {
+    throw runtimePanic("slice bounds out of range [::" + max + "] with " + limitName + " " + limit);
+  }
+  if (!slice3 && (high < 0 || high > limit)) {
+    throw runtimePanic("slice bounds out of range [:" + high + "] with " + limitName + " " + limit);
+  }
+  if (slice3 && (high < 0 || high > max)) {
+    throw runtimePanic("slice bounds out of range [:" + high + ":" + max + "]");
+  }
+  if (low < 0 || low > high) {
+    throw runtimePanic("slice bounds out of range [" + low + ":" + high + (slice3 ? ":]" : "]"));
+  }
+}
+
+// x[low:high:max] shares the array of a slice or the elements of an array;
+// high and max default to the length and the capacity
+function sliceExpr(x, low, high, max) {
+  if (typeof x === 'string') {
+    if (high === undefined) high = x.length;
+    checkSliceBounds(low, high, high, x.length, false, "length");
+    return x.substring(low, high);
+  }
+  let limitName = "capacity";
+  if (x == null) {
+    x = nilSlice();
+  } else if (!(x instanceof Slice)) {
+    x = sliceOf(x);
+    limitName = "length";
   }
-  return arr;
+  if (high === undefined) high = x.length;
+  const slice3 = max !== undefined;
+  if (!slice3) max = x.capacity;
+  checkSliceBounds(low, high, max, x.capacity, slice3, limitName);
+  return new Slice(x.array, x.offset + low, high - low, max - low);
+}
+
+// Strings index to the byte value like Go
+function stringAt(s, i) {
+  return s.charCodeAt(indexCheck(s, i));
+}
+
+// Type assertion x.(T), check tests the dynamic type of the value
+function typeAssert(v, check) {
+  if (!check(v)) throw errorPanic("interface conversion: interface is not the asserted type");
+  return v;
+}
+
+// Comma-ok type assertion: v, ok := x.(T)
+function typeAssertOk(v, check, zero) {
+  return check(v) ? [v, true] : [zero, false];
 }
 
 function stringFormat(fmt, ...args) {
@@ -165,10 +785,10 @@ function stringFormat(fmt, ...args) {
     const arg = args[i++];
     switch (match) {
       case '%s': return String(arg);
-      case '%d': return parseInt(arg, 10);
+      case '%d': return typeof arg === 'bigint' ? String(arg) : parseInt(arg, 10);
       case '%f': return parseFloat(arg);
       case '%v': return String(arg);
-      case '%x': return parseInt(arg, 10).toString(16);
+      case '%x': return (typeof arg === 'bigint' ? arg : parseInt(arg, 10)).toString(16);
       case '%c': return String.fromCharCode(arg);
       default: return arg;
     }
@@ -201,25 +821,76 @@ function print(...args) {
 }
 
 function make(type, length, capacity) {
-  if (Array.isArray(type)) {
-    return new Array(length || 0).fill(type[0] === 'number' ? 0 : null);
+  if (type === Map) {
+    return new Map();
+  }
+  if (type instanceof ChanType) {
+    return new Chan(length || 0, type.zero);
+  }
+  if (type instanceof SliceType) {
+    length = Number(length);
+    capacity = capacity === undefined ? length : Number(capacity);
+    if (length < 0) throw runtimePanic("makeslice: len out of range");
+    if (capacity < length) throw runtimePanic("makeslice: cap out of range");
+    const array = new Array(capacity);
+    for (let i = 0; i < capacity; i++) array[i] = type.zero();
+    return new Slice(array, 0, length, capacity);
   }
   return [];
 }
 
+// Go map helpers - a missing key yields the zero value
+function mapGet(m, key, zero) {
+  return m.has(key) ? m.get(key) : zero;
+}
+
+function mapGetOk(m, key, zero) {
+  return m.has(key) ? [m.get(key), true] : [zero, false];
+}
+
+function mapDelete(m, key) {
+  m.delete(key);
+}
+
+// Keys are visited in sorted order so that every backend iterates maps the same way
+function mapKeys(m) {
+  return Array.from(m.keys()).sort((a, b) => (a < b ? -1 : (a > b ? 1 : 0)));
+}
+
+// Byte offsets and runes of a string, as range over a string yields them.
+// Offsets count UTF-8 bytes, a lone surrogate decodes to U+FFFD.
+function stringRange(s) {
+  const runes = [];
+  let offset = 0;
+  for (const ch of s) {
+    let r = ch.codePointAt(0);
+    if (r >= 0xD800 && r <= 0xDFFF) {
+      r = 0xFFFD;
+    }
+    runes.push([offset, r]);
+    offset += r < 0x80 ? 1 : r < 0x800 ? 2 : r < 0x10000 ? 3 : 4;
+  }
+  return runes;
+}
+
 // Type conversion functions
-// Handle string-to-int conversion for character codes (Go rune semantics)
-function int8(v) { return typeof v === 'string' ? v.charCodeAt(0) | 0 : v | 0; }
-function int16(v) { return typeof v === 'string' ? v.charCodeAt(0) | 0 : v | 0; }
-function int32(v) { return typeof v === 'string' ? v.charCodeAt(0) | 0 : v | 0; }
-function int64(v) { return typeof v === 'string' ? v.charCodeAt(0) : v; }  // BigInt not used for simplicity
-function int(v) { return typeof v === 'string' ? v.charCodeAt(0) | 0 : v | 0; }
-function uint8(v) { return typeof v === 'string' ? v.charCodeAt(0) & 0xFF : (v | 0) & 0xFF; }
-function uint16(v) { return typeof v === 'string' ? v.charCodeAt(0) & 0xFFFF : (v | 0) & 0xFFFF; }
-function uint32(v) { return typeof v === 'string' ? v.charCodeAt(0) >>> 0 : (v | 0) >>> 0; }
-function uint64(v) { return typeof v === 'string' ? v.charCodeAt(0) : v; }  // BigInt not used for simplicity
-function float32(v) { return v; }
-function float64(v) { return v; }
+// The integer operand of a conversion to 32 bits or less: a string is the
+// code of its first character (Go rune semantics), a BigInt keeps its low
+// 32 bits
+function low32(v) {
+  if (typeof v === 'string') return v.charCodeAt(0);
+  if (typeof v === 'bigint') return Number(BigInt.asIntN(32, v));
+  return v;
+}
+function int8(v) { return low32(v) << 24 >> 24; }
+function int16(v) { return low32(v) << 16 >> 16; }
+function int32(v) { return low32(v) | 0; }
+` + jsIntConversions() + `
+function uint8(v) { return low32(v) & 0xFF; }
+function uint16(v) { return low32(v) & 0xFFFF; }
+function uint32(v) { return low32(v) >>> 0; }
+function float32(v) { return typeof v === 'bigint' ? Number(v) : v; }
+function float64(v) { return typeof v === 'bigint' ? Number(v) : v; }
 function string(v) { return String(v); }
 function bool(v) { return Boolean(v); }
 
@@ -455,7 +1126,12 @@ const graphics = {
 
 func (jse *JSEmitter) PostVisitProgram(indent int) {
 	// Add main() call at the end
-	jse.file.WriteString("\n// Run main\nmain();\n")
+	if jse.asyncMain {
+		// Go ends the program when main returns, whatever other goroutines do
+		jse.file.WriteString("\n// Run main\nmain().then(() => {\n  if (typeof process !== 'undefined') process.exit(0);\n}, exitPanic);\n")
+	} else {
+		jse.file.WriteString("\n// Run main\ntry {\n  main();\n} catch (e) {\n  exitPanic(e);\n}\n")
+	}
 	jse.file.Close()
 
 	// Create HTML wrapper if graphics runtime is enabled
@@ -494,6 +1170,9 @@ func (jse *JSEmitter) createHTMLWrapper() {
 func (jse *JSEmitter) PreVisitPackage(pkg *packages.Package, indent int) {
 	jse.pkg = pkg
 	jse.currentPackage = pkg.Name
+	jse.currentFuncDecl = nil
+	jse.findBlockingFuncs(pkg)
+	jse.findStructCopies(pkg)
 	// For non-main packages, create a namespace object
 	if pkg.Name != "main" {
 		jse.inNamespace = true
@@ -501,70 +1180,705 @@ func (jse *JSEmitter) PreVisitPackage(pkg *packages.Package, indent int) {
 	}
 }
 
-func (jse *JSEmitter) PostVisitPackage(pkg *packages.Package, indent int) {
-	// Close the namespace object for non-main packages
-	if pkg.Name != "main" {
-		jse.emitToFile("};\n")
-		jse.inNamespace = false
-	}
-}
-
-// PreVisitFuncDecl handles function declarations
-func (jse *JSEmitter) PreVisitFuncDecl(node *ast.FuncDecl, indent int) {
-	if jse.forwardDecl {
-		return
+// findBlockingFuncs records the functions of pkg that may wait for a
+// channel: the ones that send, receive or select, and the ones calling them.
+// They are emitted as async functions and their calls are awaited.
+// The callee of a call through a func value or an interface is not known, such
+// a call may wait when any function used as a value, or any method of that
+// name, may wait.
+func (jse *JSEmitter) findBlockingFuncs(pkg *packages.Package) {
+	if jse.blockingFuncs == nil {
+		jse.blockingFuncs = make(map[types.Object]bool)
+		jse.blockingLits = make(map[*ast.FuncLit]bool)
+		jse.blockingMethods = make(map[string]bool)
+	}
+	type funcUnit struct {
+		obj         types.Object
+		lit         *ast.FuncLit
+		blocks      bool
+		calls       []types.Object
+		litCalls    []*ast.FuncLit
+		methodCalls []string // Methods called through an interface
+		valueCalls  bool     // Calls a func value
+	}
+	var units []*funcUnit
+	// Functions and literals used as values rather than called directly
+	callees := make(map[ast.Node]bool)
+	valueFuncs := make(map[types.Object]bool)
+	valueLits := make(map[*ast.FuncLit]bool)
+	var scan func(u *funcUnit, node ast.Node)
+	scan = func(u *funcUnit, node ast.Node) {
+		ast.Inspect(node, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				if !callees[n] {
+					valueLits[n] = true
+				}
+				lit := &funcUnit{lit: n}
+				units = append(units, lit)
+				scan(lit, n.Body)
+				return false
+			case *ast.Ident:
+				if fn, ok := pkg.TypesInfo.Uses[n].(*types.Func); ok && !callees[n] {
+					valueFuncs[fn.Origin()] = true
+				}
+			case *ast.GoStmt:
+				// The goroutine runs on its own, only its arguments are
+				// evaluated here
+				markCallee(callees, n.Call.Fun)
+				if lit, ok := n.Call.Fun.(*ast.FuncLit); ok {
+					scan(u, lit)
+				}
+				for _, arg := range n.Call.Args {
+					scan(u, arg)
+				}
+				return false
+			case *ast.SendStmt, *ast.SelectStmt:
+				u.blocks = true
+			case *ast.UnaryExpr:
+				if n.Op == token.ARROW {
+					u.blocks = true
+				}
+			case *ast.RangeStmt:
+				if isChanExpr(pkg, n.X) {
+					u.blocks = true
+				}
+			case *ast.CallExpr:
+				markCallee(callees, n.Fun)
+				if lit, ok := ast.Unparen(n.Fun).(*ast.FuncLit); ok {
+					u.litCalls = append(u.litCalls, lit)
+				} else if fn := calledFunc(pkg, n); fn != nil {
+					if isInterfaceMethod(fn) {
+						u.methodCalls = append(u.methodCalls, fn.Name())
+					} else {
+						u.calls = append(u.calls, fn)
+					}
+				} else if pkg.TypesInfo.Types[n.Fun].IsValue() {
+					u.valueCalls = true
+				}
+			}
+			return true
+		})
+	}
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				u := &funcUnit{obj: pkg.TypesInfo.Defs[fn.Name]}
+				units = append(units, u)
+				scan(u, fn.Body)
+			}
+		}
 	}
-	if jse.inNamespace {
-		// Inside a namespace object, use method syntax
-		str := jse.emitAsString("\n", indent)
-		jse.emitToFile(str)
-	} else {
-		str := jse.emitAsString("\nfunction ", indent)
-		jse.emitToFile(str)
+	// A unit blocks when it calls one that blocks, repeat until none changes
+	for changed := true; changed; {
+		changed = false
+		for _, u := range units {
+			for _, fn := range u.calls {
+				u.blocks = u.blocks || jse.blockingFuncs[fn]
+			}
+			for _, lit := range u.litCalls {
+				u.blocks = u.blocks || jse.blockingLits[lit]
+			}
+			for _, name := range u.methodCalls {
+				u.blocks = u.blocks || jse.blockingMethods[name]
+			}
+			u.blocks = u.blocks || (u.valueCalls && jse.blockingValues)
+			if !u.blocks {
+				continue
+			}
+			if u.lit != nil && !jse.blockingLits[u.lit] {
+				jse.blockingLits[u.lit] = true
+				jse.blockingValues = jse.blockingValues || valueLits[u.lit]
+				changed = true
+			} else if u.lit == nil && !jse.blockingFuncs[u.obj] {
+				jse.blockingFuncs[u.obj] = true
+				jse.blockingValues = jse.blockingValues || valueFuncs[u.obj]
+				if u.obj.(*types.Func).Type().(*types.Signature).Recv() != nil {
+					jse.blockingMethods[u.obj.Name()] = true
+				}
+				changed = true
+			}
+		}
 	}
 }
 
-func (jse *JSEmitter) PreVisitFuncDeclSignature(node *ast.FuncDecl, indent int) {
-	if jse.forwardDecl {
-		return
+// markCallee records the function expression of a call, which is not a use
+// of the function as a value
+func markCallee(callees map[ast.Node]bool, fun ast.Expr) {
+	fun = ast.Unparen(fun)
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
 	}
-	// Count results for multi-value returns
-	if node.Type.Results != nil {
-		jse.numFuncResults = len(node.Type.Results.List)
-	} else {
-		jse.numFuncResults = 0
+	callees[fun] = true
+	if sel, ok := fun.(*ast.SelectorExpr); ok {
+		callees[sel.Sel] = true
 	}
 }
 
-// Suppress return type emission (JavaScript doesn't have return types)
-func (jse *JSEmitter) PreVisitFuncDeclSignatureTypeResults(node *ast.FuncDecl, indent int) {
-	jse.suppressTypeEmit = true
-}
-
-func (jse *JSEmitter) PostVisitFuncDeclSignatureTypeResults(node *ast.FuncDecl, indent int) {
-	jse.suppressTypeEmit = false
+// isInterfaceMethod reports whether fn is the method of an interface type
+func isInterfaceMethod(fn types.Object) bool {
+	recv := fn.Type().(*types.Signature).Recv()
+	return recv != nil && types.IsInterface(recv.Type())
 }
 
-func (jse *JSEmitter) PreVisitFuncDeclName(node *ast.Ident, indent int) {
-	if jse.forwardDecl {
-		return
+// isBlockingCall reports whether call may wait for a channel
+func (jse *JSEmitter) isBlockingCall(call *ast.CallExpr) bool {
+	if lit, ok := ast.Unparen(call.Fun).(*ast.FuncLit); ok {
+		return jse.blockingLits[lit]
 	}
-	if jse.inNamespace {
-		// Method syntax: name: function
-		jse.emitToFile(node.Name + ": function")
-	} else {
-		jse.emitToFile(node.Name)
+	if fn := calledFunc(jse.pkg, call); fn != nil {
+		if isInterfaceMethod(fn) {
+			return jse.blockingMethods[fn.Name()]
+		}
+		return jse.blockingFuncs[fn]
+	}
+	return jse.blockingValues && jse.pkg.TypesInfo.Types[call.Fun].IsValue()
+}
+
+// findStructCopies finds the struct variables of pkg whose value may be
+// shared instead of copied. Go copies a struct on assignment, passing and
+// return, which is only visible when a copy is changed in place, as by
+// v.X = 1 or a pointer method call: two variables that are never changed in
+// place can share one object. A variable changed in place owns its object,
+// since it always receives a copy, and so can hand it to the caller when it
+// is returned. Function literals see the variables they capture at any time,
+// which are therefore copied like variables changed in place.
+func (jse *JSEmitter) findStructCopies(pkg *packages.Package) {
+	if jse.mutatedVars == nil {
+		jse.mutatedVars = make(map[types.Object]bool)
+		jse.capturedVars = make(map[types.Object]bool)
+		jse.ownedVars = make(map[types.Object]bool)
+		jse.paramCopies = make(map[types.Object]bool)
+	}
+	var funcs []*ast.FuncDecl
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+				funcs = append(funcs, fn)
+			}
+		}
 	}
-}
-
-func (jse *JSEmitter) PreVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl, indent int) {
-	if jse.forwardDecl {
-		return
+	mutate := func(expr ast.Expr) {
+		// Assigning a whole variable rebinds it, the old object is unchanged
+		if _, ok := ast.Unparen(expr).(*ast.Ident); ok {
+			return
+		}
+		if obj := assignedVar(pkg, expr); obj != nil {
+			jse.mutatedVars[obj] = true
+		}
 	}
-	jse.emitToFile("(")
-}
-
-func (jse *JSEmitter) PreVisitFuncDeclSignatureTypeParamsList(node *ast.Field, index int, indent int) {
+	for _, fn := range funcs {
+		var lits []*ast.FuncLit
+		var visit func(n ast.Node) bool
+		visit = func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncLit:
+				lits = append(lits, n)
+				ast.Inspect(n.Body, visit)
+				lits = lits[:len(lits)-1]
+				return false
+			case *ast.AssignStmt:
+				for _, lhs := range n.Lhs {
+					mutate(lhs)
+				}
+			case *ast.IncDecStmt:
+				mutate(n.X)
+			case *ast.RangeStmt:
+				if n.Tok == token.ASSIGN {
+					mutate(n.Key)
+					mutate(n.Value)
+				}
+			case *ast.CallExpr:
+				if recv := pointerMethodValueRecv(pkg, n); recv != nil {
+					if obj := assignedVar(pkg, recv); obj != nil {
+						jse.mutatedVars[obj] = true
+					}
+				}
+			case *ast.GoStmt:
+				// A goroutine may use its arguments after its caller returned
+				for _, arg := range n.Call.Args {
+					if obj := assignedVar(pkg, arg); obj != nil {
+						jse.capturedVars[obj] = true
+					}
+				}
+			case *ast.Ident:
+				v, ok := pkg.TypesInfo.Uses[n].(*types.Var)
+				if !ok || v.IsField() || isPackageVar(v) {
+					break
+				}
+				for _, lit := range lits {
+					if v.Pos() < lit.Pos() || v.Pos() >= lit.End() {
+						jse.capturedVars[v] = true
+					}
+				}
+			}
+			return true
+		}
+		ast.Inspect(fn.Body, visit)
+	}
+	// Callers copy the arguments of the parameters changed in place or
+	// captured, and of the ones holding a type parameter, which may be a struct.
+	// Methods copy their value receiver on the same condition
+	for _, fn := range funcs {
+		fields := fn.Type.Params.List
+		if fn.Recv != nil && !hasPointerRecv(fn) {
+			fields = append(fields[:len(fields):len(fields)], fn.Recv.List...)
+		}
+		for _, field := range fields {
+			for _, name := range field.Names {
+				obj := pkg.TypesInfo.Defs[name]
+				if obj == nil {
+					continue
+				}
+				_, isTypeParam := obj.Type().(*types.TypeParam)
+				jse.paramCopies[obj] = jse.mutatedVars[obj] || jse.capturedVars[obj] || isTypeParam
+			}
+		}
+	}
+	// A variable owns its object when each value it is given is new or
+	// copied. The parameters of function literals are always copied, the
+	// other parameters and value receivers when paramCopies says so
+	shared := make(map[types.Object]bool)
+	given := func(lhs ast.Expr, rhs ast.Expr) {
+		ident, ok := ast.Unparen(lhs).(*ast.Ident)
+		if !ok {
+			return
+		}
+		if obj := pkg.TypesInfo.ObjectOf(ident); obj != nil && (rhs == nil || !isFreshValue(pkg, rhs) && !jse.needsStructCopy(rhs, jse.isMutableHolder(lhs))) {
+			shared[obj] = true
+		}
+	}
+	for _, fn := range funcs {
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.AssignStmt:
+				for i, lhs := range n.Lhs {
+					if len(n.Lhs) == len(n.Rhs) {
+						given(lhs, n.Rhs[i])
+					} else if i == 0 && (isMapCommaOk(pkg, n) || isTypeAssertCommaOk(n)) {
+						given(lhs, nil)
+					}
+				}
+			case *ast.ValueSpec:
+				for i, name := range n.Names {
+					if i < len(n.Values) {
+						given(name, n.Values[i])
+					}
+				}
+			case *ast.RangeStmt:
+				if n.Value != nil && jse.rangeValueCopy(n) == "" && !isChanExpr(pkg, n.X) {
+					given(n.Value, nil)
+				}
+			case *ast.CaseClause:
+				if obj, ok := pkg.TypesInfo.Implicits[n].(*types.Var); ok {
+					shared[obj] = true
+				}
+			}
+			return true
+		})
+		ast.Inspect(fn, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok {
+				if obj, ok := pkg.TypesInfo.Defs[ident].(*types.Var); ok && !obj.IsField() {
+					copied, isParam := jse.paramCopies[obj]
+					jse.ownedVars[obj] = !shared[obj] && (copied || !isParam)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// isFreshValue reports whether expr makes a new value that nothing else
+// refers to: a composite literal, a call result or a dereference, which the
+// emitter copies
+func isFreshValue(pkg *packages.Package, expr ast.Expr) bool {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.CompositeLit, *ast.StarExpr:
+		return true
+	case *ast.UnaryExpr:
+		return e.Op == token.ARROW
+	case *ast.CallExpr:
+		if tv, ok := pkg.TypesInfo.Types[e.Fun]; ok && tv.IsType() && len(e.Args) == 1 {
+			return isFreshValue(pkg, e.Args[0])
+		}
+		return true
+	}
+	return false
+}
+
+// copiedStruct returns the struct type of the value expr evaluates to, looking
+// through conversions such as any(v), nil when it is not a struct
+func (jse *JSEmitter) copiedStruct(expr ast.Expr) *types.Struct {
+	expr = ast.Unparen(expr)
+	if call, ok := expr.(*ast.CallExpr); ok && len(call.Args) == 1 {
+		if tv, ok := jse.pkg.TypesInfo.Types[call.Fun]; ok && tv.IsType() {
+			return jse.copiedStruct(call.Args[0])
+		}
+	}
+	t := jse.pkg.TypesInfo.TypeOf(expr)
+	if t == nil {
+		return nil
+	}
+	st, _ := t.Underlying().(*types.Struct)
+	return st
+}
+
+// needsStructCopy reports whether the struct value of expr must be copied
+// when stored in a place that may be changed in place, or when the place expr
+// is read from may be
+func (jse *JSEmitter) needsStructCopy(expr ast.Expr, mutableHolder bool) bool {
+	if jse.copiedStruct(expr) == nil || isFreshValue(jse.pkg, expr) {
+		return false
+	}
+	return mutableHolder || jse.isMutableStorage(expr)
+}
+
+// isMutableStorage reports whether the struct expr reads may be changed in
+// place while it is shared: a variable changed in place or captured, a
+// package variable, or an element or a field reached through a slice or a
+// pointer, including a variable boxed because its address is taken. A map
+// value can only be replaced
+func (jse *JSEmitter) isMutableStorage(expr ast.Expr) bool {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		v, ok := jse.pkg.TypesInfo.Uses[e].(*types.Var)
+		if !ok || isPackageVar(v) {
+			return true
+		}
+		return jse.mutatedVars[v] || jse.capturedVars[v]
+	case *ast.SelectorExpr:
+		sel, ok := jse.pkg.TypesInfo.Selections[e]
+		if !ok || sel.Kind() != types.FieldVal || sel.Indirect() || isPointerExpr(jse.pkg, e.X) {
+			return true
+		}
+		return jse.isMutableStorage(e.X)
+	case *ast.IndexExpr:
+		switch jse.pkg.TypesInfo.TypeOf(e.X).Underlying().(type) {
+		case *types.Map:
+			return false
+		case *types.Array:
+			return jse.isMutableStorage(e.X)
+		}
+		return true
+	case *ast.TypeAssertExpr:
+		return false
+	}
+	return true
+}
+
+// isMutableHolder reports whether a struct assigned to lhs may be changed in
+// place there: unless lhs is a map value or a variable that is never changed
+// in place
+func (jse *JSEmitter) isMutableHolder(lhs ast.Expr) bool {
+	if isMapIndexExpr(jse.pkg, lhs) {
+		return false
+	}
+	ident, ok := ast.Unparen(lhs).(*ast.Ident)
+	if !ok {
+		return true
+	}
+	if ident.Name == "_" {
+		return false
+	}
+	obj := jse.pkg.TypesInfo.ObjectOf(ident)
+	if obj == nil || isPackageVar(obj) {
+		return true
+	}
+	return jse.mutatedVars[obj] || jse.capturedVars[obj]
+}
+
+// isMapValueHolder reports whether a struct stored as a map value must be
+// copied although the map can only replace it: when value reads a variable
+// that does not own its object, such as a parameter sharing the struct of its
+// caller, which may still change it in place
+func (jse *JSEmitter) isMapValueHolder(value ast.Expr) bool {
+	root := ownedRoot(jse.pkg, value)
+	return root != nil && !jse.ownedVars[root]
+}
+
+// rangeValueCopy returns the arguments closing copyStruct( around the struct
+// a range statement reads from a slice, an array or a map, empty when the
+// value is not copied
+func (jse *JSEmitter) rangeValueCopy(node *ast.RangeStmt) string {
+	if node.Value == nil {
+		return ""
+	}
+	st, ok := jse.pkg.TypesInfo.TypeOf(node.Value).Underlying().(*types.Struct)
+	if !ok {
+		return ""
+	}
+	mutable := jse.isMutableHolder(node.Value)
+	switch jse.pkg.TypesInfo.TypeOf(node.X).Underlying().(type) {
+	case *types.Slice:
+		mutable = true
+	case *types.Array:
+		mutable = mutable || jse.isMutableStorage(node.X)
+	case *types.Map:
+	default:
+		return ""
+	}
+	if !mutable {
+		return ""
+	}
+	return structCopyArgs(st)
+}
+
+// isMovedResult reports whether a returned expr can hand its object to the
+// caller without a copy: a variable that owns its object, or one of its
+// fields, that no function literal sees after the return
+func (jse *JSEmitter) isMovedResult(ret *ast.ReturnStmt, expr ast.Expr) bool {
+	root := ownedRoot(jse.pkg, expr)
+	if root == nil || !jse.ownedVars[root] || jse.capturedVars[root] {
+		return false
+	}
+	// return v, v must not return one object twice
+	for _, result := range ret.Results {
+		if result != expr && ownedRoot(jse.pkg, result) == root {
+			return false
+		}
+	}
+	return true
+}
+
+// ownedRoot returns the local variable whose object holds the value of expr:
+// expr itself, or the variable a field or an array element of it belongs to
+func ownedRoot(pkg *packages.Package, expr ast.Expr) types.Object {
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		v, ok := pkg.TypesInfo.Uses[e].(*types.Var)
+		if !ok || isPackageVar(v) {
+			return nil
+		}
+		return v
+	case *ast.SelectorExpr:
+		if sel, ok := pkg.TypesInfo.Selections[e]; ok && sel.Kind() == types.FieldVal && !sel.Indirect() && !isPointerExpr(pkg, e.X) {
+			return ownedRoot(pkg, e.X)
+		}
+	case *ast.IndexExpr:
+		if _, ok := pkg.TypesInfo.TypeOf(e.X).Underlying().(*types.Array); ok {
+			return ownedRoot(pkg, e.X)
+		}
+	}
+	return nil
+}
+
+// structCopyArgs returns the arguments closing copyStruct( for a value of the
+// struct type st: the functions copying its fields that are structs or arrays
+func structCopyArgs(st *types.Struct) string {
+	var nested []string
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		if copyFunc := valueCopyFunc(field.Type()); copyFunc != "" {
+			nested = append(nested, field.Name()+": "+copyFunc)
+		}
+	}
+	if len(nested) == 0 {
+		return ")"
+	}
+	return ", {" + strings.Join(nested, ", ") + "})"
+}
+
+// valueCopyFunc returns the function copying a value of type t, empty for
+// types whose values are not copied
+func valueCopyFunc(t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		return "v => copyStruct(v" + structCopyArgs(u)
+	case *types.Array:
+		return "v => arrayCopy(v" + arrayCopyArgs(u)
+	}
+	return ""
+}
+
+// arrayCopyArgs returns the arguments closing arrayCopy( for a value of the
+// array type array: the number of nested array levels and the function
+// copying the struct elements
+func arrayCopyArgs(array *types.Array) string {
+	depth := 0
+	var elem types.Type = array
+	for {
+		nested, ok := elem.Underlying().(*types.Array)
+		if !ok {
+			break
+		}
+		depth++
+		elem = nested.Elem()
+	}
+	if st, ok := elem.Underlying().(*types.Struct); ok {
+		return fmt.Sprintf(", %d, v => copyStruct(v%s)", depth, structCopyArgs(st))
+	}
+	return fmt.Sprintf(", %d)", depth)
+}
+
+func (jse *JSEmitter) PostVisitPackage(pkg *packages.Package, indent int) {
+	// Close the namespace object for non-main packages
+	if pkg.Name != "main" {
+		jse.emitToFile("};\n")
+		jse.inNamespace = false
+	}
+	// Methods of namespaced structs are attached once the namespace exists
+	if jse.methodsText != "" {
+		jse.emitToFile(jse.methodsText)
+		jse.methodsText = ""
+	}
+	if jse.initVarsPending {
+		if pkg.Name != "main" {
+			jse.emitToFile(pkg.Name + ".")
+		}
+		jse.emitToFile("$initVars();\n")
+		jse.initVarsPending = false
+	}
+}
+
+// structClassName returns the class of a struct type with methods, qualified
+// by its namespace, or "" for structs without methods (plain object literals)
+func (jse *JSEmitter) structClassName(t types.Type) string {
+	named, ok := t.(*types.Named)
+	if !ok || !hasMethods(named) || named.Obj().Pkg() == nil {
+		return ""
+	}
+	if pkgName := named.Obj().Pkg().Name(); pkgName != "main" {
+		return pkgName + "." + named.Obj().Name()
+	}
+	return named.Obj().Name()
+}
+
+// PreVisitGenStructInfo declares a class for structs with methods, methods
+// are attached to its prototype
+func (jse *JSEmitter) PreVisitGenStructInfo(node GenTypeInfo, indent int) {
+	obj := jse.pkg.Types.Scope().Lookup(node.Name)
+	if obj == nil || !hasMethods(obj.Type()) {
+		return
+	}
+	if jse.inNamespace {
+		jse.emitToFile(fmt.Sprintf("\n%s: class {},\n", node.Name))
+	} else {
+		jse.emitToFile(fmt.Sprintf("\nclass %s {}\n", node.Name))
+	}
+}
+
+// PreVisitGenStructMethod skips the method signature, JavaScript methods are
+// defined on the class prototype
+func (jse *JSEmitter) PreVisitGenStructMethod(node *ast.FuncDecl, indent int) {
+	jse.forwardDecl = true
+}
+
+func (jse *JSEmitter) PostVisitGenStructMethod(node *ast.FuncDecl, indent int) {
+	jse.forwardDecl = false
+}
+
+// PreVisitGenInterfaceMethod skips interface method signatures, interface
+// values are duck typed
+func (jse *JSEmitter) PreVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {
+	jse.forwardDecl = true
+}
+
+func (jse *JSEmitter) PostVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {
+	jse.forwardDecl = false
+}
+
+// PreVisitFuncDecl handles function declarations
+func (jse *JSEmitter) PreVisitFuncDecl(node *ast.FuncDecl, indent int) {
+	jse.currentFuncDecl = node
+	jse.renames = shadowRenames(jse.pkg, node)
+	if jse.forwardDecl {
+		return
+	}
+	if node.Recv != nil {
+		// Methods can't be members of the namespace object literal, they are
+		// assigned to the class prototype after the namespace is closed
+		jse.captureMethod = jse.inNamespace
+		jse.emitToFile("\n")
+		return
+	}
+	if jse.inNamespace {
+		// Inside a namespace object, use method syntax
+		str := jse.emitAsString("\n", indent)
+		jse.emitToFile(str)
+	} else {
+		str := jse.emitAsString("\n"+jse.asyncPrefix(node)+"function ", indent)
+		jse.emitToFile(str)
+	}
+}
+
+// asyncPrefix returns "async " for a function that may wait for a channel
+func (jse *JSEmitter) asyncPrefix(node *ast.FuncDecl) string {
+	if !jse.blockingFuncs[jse.pkg.TypesInfo.Defs[node.Name]] {
+		return ""
+	}
+	if jse.pkg.Name == "main" && node.Recv == nil && node.Name.Name == "main" {
+		jse.asyncMain = true
+	}
+	return "async "
+}
+
+func (jse *JSEmitter) PreVisitFuncDeclBody(node *ast.BlockStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.deferScopes = pushDeferScope(jse.pkg, jse.deferScopes, node, &jse.deferCount)
+	// Pointer receivers alias this, value receivers work on a copy when they
+	// may be changed in place
+	if name := recvName(jse.currentFuncDecl); name != "" {
+		recv := jse.pkg.TypesInfo.Defs[jse.currentFuncDecl.Recv.List[0].Names[0]]
+		st, isStruct := recv.Type().Underlying().(*types.Struct)
+		if isStruct && jse.paramCopies[recv] {
+			jse.pendingRecvDecl = fmt.Sprintf("let %s = copyStruct(this%s;\n", name, structCopyArgs(st))
+		} else {
+			jse.pendingRecvDecl = fmt.Sprintf("let %s = this;\n", name)
+		}
+	}
+}
+
+func (jse *JSEmitter) PreVisitFuncDeclSignature(node *ast.FuncDecl, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	// Count results for multi-value returns
+	if node.Type.Results != nil {
+		jse.numFuncResults = len(node.Type.Results.List)
+	} else {
+		jse.numFuncResults = 0
+	}
+}
+
+// Suppress return type emission (JavaScript doesn't have return types)
+func (jse *JSEmitter) PreVisitFuncDeclSignatureTypeResults(node *ast.FuncDecl, indent int) {
+	jse.suppressTypeEmit = true
+}
+
+func (jse *JSEmitter) PostVisitFuncDeclSignatureTypeResults(node *ast.FuncDecl, indent int) {
+	jse.suppressTypeEmit = false
+}
+
+func (jse *JSEmitter) PreVisitFuncDeclName(node *ast.Ident, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if recv := recvTypeName(jse.currentFuncDecl); recv != "" {
+		if jse.inNamespace {
+			recv = jse.currentPackage + "." + recv
+		}
+		jse.emitToFile(recv + ".prototype." + node.Name + " = " + jse.asyncPrefix(jse.currentFuncDecl) + "function")
+	} else if jse.inNamespace {
+		// Method syntax: name: function
+		jse.emitToFile(node.Name + ": " + jse.asyncPrefix(jse.currentFuncDecl) + "function")
+	} else {
+		jse.emitToFile(node.Name)
+	}
+}
+
+func (jse *JSEmitter) PreVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile("(")
+}
+
+func (jse *JSEmitter) PreVisitFuncDeclSignatureTypeParamsList(node *ast.Field, index int, indent int) {
 	if jse.forwardDecl {
 		return
 	}
@@ -580,6 +1894,11 @@ func (jse *JSEmitter) PreVisitFuncDeclSignatureTypeParamsListType(node ast.Expr,
 
 func (jse *JSEmitter) PostVisitFuncDeclSignatureTypeParamsListType(node ast.Expr, argName *ast.Ident, index int, indent int) {
 	jse.suppressTypeEmit = false
+	// A variadic parameter becomes a rest parameter
+	if _, ok := node.(*ast.Ellipsis); ok && !jse.forwardDecl {
+		jse.emitToFile("...")
+		jse.variadicParam = localName(jse.pkg, jse.renames, argName)
+	}
 }
 
 func (jse *JSEmitter) PreVisitFuncDeclSignatureTypeParamsArgName(node *ast.Ident, index int, indent int) {
@@ -601,7 +1920,10 @@ func (jse *JSEmitter) PostVisitFuncDecl(node *ast.FuncDecl, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	if jse.inNamespace {
+	if node.Recv != nil {
+		jse.emitToFile(";\n")
+		jse.captureMethod = false
+	} else if jse.inNamespace {
 		// Add comma after method in namespace object
 		jse.emitToFile(",\n")
 	} else {
@@ -624,22 +1946,342 @@ func (jse *JSEmitter) PreVisitBlockStmt(node *ast.BlockStmt, indent int) {
 		return
 	}
 	jse.emitToFile("{\n")
+	if jse.pendingRecvDecl != "" {
+		jse.emitToFile(jse.emitAsString(jse.pendingRecvDecl, indent+1))
+		jse.pendingRecvDecl = ""
+	}
 	// Emit pending range value declaration
 	if jse.pendingRangeValueDecl {
-		str := jse.emitAsString("let "+jse.rangeValueName+" = "+jse.rangeCollectionExpr+"["+jse.rangeKeyName+"];\n", indent+1)
+		valueExpr := jse.rangeCollectionExpr + "[" + jse.rangeKeyName + "]"
+		if jse.isMapRange {
+			valueExpr = jse.rangeCollectionExpr + ".get(" + jse.rangeKeyName + ")"
+		} else if jse.isSliceRange {
+			valueExpr = "sliceAt(" + jse.rangeCollectionExpr + ", " + jse.rangeKeyName + ")"
+		}
+		if jse.rangeValueCopyArgs != "" {
+			valueExpr = "copyStruct(" + valueExpr + jse.rangeValueCopyArgs
+		}
+		str := jse.emitAsString("let "+jse.rangeValueName+" = "+valueExpr+";\n", indent+1)
 		jse.emitToFile(str)
 		jse.pendingRangeValueDecl = false
 	}
 }
 
+func (jse *JSEmitter) PreVisitFuncBodyStmts(node *ast.BlockStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if jse.variadicParam != "" {
+		jse.emitToFile(jse.emitAsString(jse.variadicParam+" = restSlice("+jse.variadicParam+");\n", indent+1))
+		jse.variadicParam = ""
+	}
+	if isDeferScopeBody(jse.deferScopes, node) {
+		if stack := currentDeferStack(jse.deferScopes); stack != "" {
+			jse.emitToFile(jse.emitAsString("const "+stack+" = [];\n", indent+1))
+			// Returns with named results break out of the try, so that the
+			// deferred calls run before the results are read
+			if names := resultNames(enclosingFuncResults(jse.pkg, jse.currentFuncDecl, node.Lbrace+1)); names != nil {
+				jse.emitToFile(jse.emitAsString(currentDeferScope(jse.deferScopes).label+": ", indent+1))
+			} else {
+				jse.emitToFile(jse.emitAsString("", indent+1))
+			}
+			jse.emitToFile("try {\n")
+		}
+	}
+}
+
 func (jse *JSEmitter) PostVisitBlockStmt(node *ast.BlockStmt, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if isDeferScopeBody(jse.deferScopes, node) {
+		if scope := currentDeferScope(jse.deferScopes); scope.stack != "" {
+			// Deferred calls that may wait for a channel are async functions
+			recovered, await := "recoverPanic(_panic, "+scope.stack+")", ""
+			if jse.hasBlockingDefer(node) {
+				recovered, await = "(await recoverPanicAsync(_panic, "+scope.stack+"))", "await "
+			}
+			if scope.recovers {
+				jse.emitToFile(jse.emitAsString("} catch (_panic) {\n", indent+1))
+				jse.emitToFile(jse.emitAsString("if (!"+recovered+") throw _panic;\n", indent+2))
+			}
+			jse.emitToFile(jse.emitAsString("} finally {\n", indent+1))
+			jse.emitToFile(jse.emitAsString("while ("+scope.stack+".length > 0) "+await+scope.stack+".pop()();\n", indent+2))
+			jse.emitToFile(jse.emitAsString("}\n", indent+1))
+			// A recovered panic returns the zero values from the function,
+			// the current ones of named results
+			if names := resultNames(enclosingFuncResults(jse.pkg, jse.currentFuncDecl, node.Lbrace+1)); names != nil {
+				jse.emitToFile(jse.emitAsString("return "+jsNamedResultsValue(names)+";\n", indent+1))
+			} else if scope.recovers {
+				jse.emitRecoveredReturn(node, indent+1)
+			}
+		}
+		jse.deferScopes = jse.deferScopes[:len(jse.deferScopes)-1]
+	}
 	str := jse.emitAsString("}\n", indent)
 	jse.emitToFile(str)
 }
 
+// emitRecoveredReturn returns the zero values of the function whose body
+// is block, an array of them when it has several results
+func (jse *JSEmitter) emitRecoveredReturn(block *ast.BlockStmt, indent int) {
+	results := enclosingFuncResults(jse.pkg, jse.currentFuncDecl, block.Lbrace+1)
+	if results == nil || results.Len() == 0 {
+		return
+	}
+	jse.emitToFile(jse.emitAsString("return ", indent))
+	if results.Len() > 1 {
+		jse.emitToFile("[")
+	}
+	for i := 0; i < results.Len(); i++ {
+		if i > 0 {
+			jse.emitToFile(", ")
+		}
+		jse.emitDefaultValue(results.At(i).Type())
+	}
+	if results.Len() > 1 {
+		jse.emitToFile("]")
+	}
+	jse.emitToFile(";\n")
+}
+
+// jsNamedResultsValue is the value a function returns from its named results
+func jsNamedResultsValue(names []string) string {
+	if len(names) > 1 {
+		return "[" + strings.Join(names, ", ") + "]"
+	}
+	return names[0]
+}
+
+// A goroutine is an async arrow function like a deferred call, its arguments
+// are evaluated into constants at the go statement
+func (jse *JSEmitter) PreVisitGoStmt(node *ast.GoStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(jse.emitAsString("", indent))
+}
+
+func (jse *JSEmitter) PreVisitGoStmtArg(node DeferArg, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile("const " + node.Name + " = ")
+	jse.openValueCopy(node.Value, jse.needsStructCopy(node.Value, false))
+}
+
+func (jse *JSEmitter) PostVisitGoStmtArg(node DeferArg, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.closeValueCopy(node.Value, jse.needsStructCopy(node.Value, false))
+	jse.emitToFile(";\n" + jse.emitAsString("", indent))
+}
+
+func (jse *JSEmitter) PreVisitGoStmtCall(node *ast.CallExpr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile("go(async () => { ")
+}
+
+func (jse *JSEmitter) PostVisitGoStmtCall(node *ast.CallExpr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile("; });\n")
+}
+
+func (jse *JSEmitter) PreVisitSendStmt(node *ast.SendStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(jse.emitAsString("await chanSend(", indent))
+}
+
+func (jse *JSEmitter) PreVisitSendStmtValue(node ast.Expr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(", ")
+	jse.openValueCopy(node, jse.needsStructCopy(node, true))
+}
+
+func (jse *JSEmitter) PostVisitSendStmtValue(node ast.Expr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.closeValueCopy(node, jse.needsStructCopy(node, true))
+}
+
+func (jse *JSEmitter) PostVisitSendStmt(node *ast.SendStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(");\n")
+}
+
+// A select evaluates its cases into _selectN_i constants, chanSelect runs
+// the first ready one and a switch on its index runs the clause
+func (jse *JSEmitter) PreVisitSelectStmt(node *ast.SelectStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.selectCount++
+	jse.selects = append(jse.selects, fmt.Sprintf("_select%d", jse.selectCount))
+	jse.emitToFile(jse.emitAsString("{\n", indent))
+}
+
+func (jse *JSEmitter) PreVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	op := "selectRecv("
+	if _, ok := node.Comm.(*ast.SendStmt); ok {
+		op = "selectSend("
+	}
+	name := fmt.Sprintf("%s_%d", jse.selects[len(jse.selects)-1], index)
+	jse.emitToFile(jse.emitAsString("const "+name+" = "+op, indent+1))
+}
+
+func (jse *JSEmitter) PreVisitSelectStmtCaseValue(node ast.Expr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(", ")
+	jse.openValueCopy(node, jse.needsStructCopy(node, true))
+}
+
+func (jse *JSEmitter) PostVisitSelectStmtCaseValue(node ast.Expr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.closeValueCopy(node, jse.needsStructCopy(node, true))
+}
+
+func (jse *JSEmitter) PostVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(");\n")
+}
+
+func (jse *JSEmitter) PreVisitSelectStmtClauses(node *ast.SelectStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	name := jse.selects[len(jse.selects)-1]
+	var cases []string
+	for _, stmt := range node.Body.List {
+		if stmt.(*ast.CommClause).Comm != nil {
+			cases = append(cases, fmt.Sprintf("%s_%d", name, len(cases)))
+		}
+	}
+	str := fmt.Sprintf("switch (await chanSelect([%s], %t)) {\n", strings.Join(cases, ", "), selectHasDefault(node))
+	jse.emitToFile(jse.emitAsString(str, indent+1))
+}
+
+func (jse *JSEmitter) PreVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if index < 0 {
+		jse.emitToFile(jse.emitAsString("default: {\n", indent+1))
+		return
+	}
+	jse.emitToFile(jse.emitAsString(fmt.Sprintf("case %d: {\n", index), indent+1))
+	name := fmt.Sprintf("%s_%d", jse.selects[len(jse.selects)-1], index)
+	_, _, recv := selectCaseOp(node)
+	value, ok := selectCaseNames(jse.pkg, jse.renames, node)
+	for _, v := range [][2]string{{value, "value"}, {ok, "ok"}} {
+		if v[0] == "" {
+			continue
+		}
+		decl := ""
+		if recv.Tok == token.DEFINE {
+			decl = "let "
+		}
+		jse.emitToFile(jse.emitAsString(fmt.Sprintf("%s%s = %s.%s;\n", decl, v[0], name, v[1]), indent+2))
+	}
+}
+
+func (jse *JSEmitter) PostVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(jse.emitAsString("break;\n", indent+2) + jse.emitAsString("}\n", indent+1))
+}
+
+func (jse *JSEmitter) PostVisitSelectStmt(node *ast.SelectStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.selects = jse.selects[:len(jse.selects)-1]
+	jse.emitToFile(jse.emitAsString("}\n", indent+1) + jse.emitAsString("}\n", indent))
+}
+
+// Deferred calls are pushed as arrow functions to a stack that the finally
+// block around the function body pops. Arguments and receivers are evaluated
+// into constants at the defer statement, copying the values that may change
+// in place, the arrow function captures them.
+func (jse *JSEmitter) PreVisitDeferStmt(node *ast.DeferStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(jse.emitAsString("", indent))
+}
+
+func (jse *JSEmitter) PreVisitDeferStmtArg(node DeferArg, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile("const " + node.Name + " = ")
+	jse.openValueCopy(node.Value, jse.needsStructCopy(node.Value, false))
+}
+
+func (jse *JSEmitter) PostVisitDeferStmtArg(node DeferArg, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.closeValueCopy(node.Value, jse.needsStructCopy(node.Value, false))
+	jse.emitToFile(";\n" + jse.emitAsString("", indent))
+}
+
+func (jse *JSEmitter) PreVisitDeferStmtCall(node *ast.CallExpr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if jse.isBlockingCall(node) {
+		jse.emitToFile(currentDeferStack(jse.deferScopes) + ".push(async () => { ")
+		return
+	}
+	jse.emitToFile(currentDeferStack(jse.deferScopes) + ".push(() => { ")
+}
+
+// hasBlockingDefer reports whether a deferred call of the function body may
+// wait for a channel, the defer stack is then popped with await
+func (jse *JSEmitter) hasBlockingDefer(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			found = found || jse.isBlockingCall(n.Call)
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+func (jse *JSEmitter) PostVisitDeferStmtCall(node *ast.CallExpr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile("; });\n")
+}
+
 // Assignment statements
 func (jse *JSEmitter) PreVisitAssignStmt(node *ast.AssignStmt, indent int) {
 	if jse.forwardDecl {
@@ -661,6 +2303,38 @@ func (jse *JSEmitter) PreVisitAssignStmt(node *ast.AssignStmt, indent int) {
 	if allBlank {
 		jse.suppressRangeEmit = true // Reuse this flag to suppress entire statement
 	}
+	if len(node.Lhs) == 1 && isMapIndexExpr(jse.pkg, node.Lhs[0]) {
+		jse.mapLvalue = node.Lhs[0]
+		jse.mapLvalueTok = node.Tok.String()
+	}
+	for _, lhs := range node.Lhs {
+		if isSliceIndexExpr(jse.pkg, lhs) {
+			jse.sliceLvalues = append(jse.sliceLvalues, lhs)
+		}
+	}
+	if isMapCommaOk(jse.pkg, node) {
+		jse.mapCommaOkExpr = node.Rhs[0]
+	}
+	if isTypeAssertCommaOk(node) {
+		jse.typeAssertCommaOk = node.Rhs[0]
+	}
+	if isChanRecvCommaOk(node) {
+		jse.chanRecvCommaOk = ast.Unparen(node.Rhs[0])
+	}
+	if star, ok := node.Lhs[0].(*ast.StarExpr); ok && len(node.Lhs) == 1 && node.Tok == token.ASSIGN {
+		jse.derefLvalue = star
+	}
+	// The slices of a sliced array share its JS array, which an assignment
+	// fills in place
+	if ident := slicedArrayIdent(jse.pkg, node.Lhs[0]); ident != nil && len(node.Lhs) == 1 && node.Tok == token.ASSIGN {
+		jse.derefLvalue = ident
+	}
+	if len(node.Lhs) == len(node.Rhs) {
+		for i, rhs := range node.Rhs {
+			holder := jse.isMutableHolder(node.Lhs[i]) || isMapIndexExpr(jse.pkg, node.Lhs[i]) && jse.isMapValueHolder(rhs)
+			jse.registerStructCopy(rhs, jse.needsStructCopy(rhs, holder))
+		}
+	}
 }
 
 func (jse *JSEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int) {
@@ -678,6 +2352,10 @@ func (jse *JSEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int) {
 		// Multi-value assignment (not declaration) needs destructuring
 		str := jse.emitAsString("[", indent)
 		jse.emitToFile(str)
+	} else if jse.derefLvalue != nil {
+		// *p = v copies the fields of v into the struct p points to, a = v
+		// the elements of v into the sliced array a
+		jse.emitToFile(jse.emitAsString("Object.assign(", indent))
 	} else {
 		str := jse.emitAsString("", indent)
 		jse.emitToFile(str)
@@ -703,7 +2381,75 @@ func (jse *JSEmitter) PreVisitAssignStmtRhs(node *ast.AssignStmt, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	jse.emitToFile(" " + jse.assignmentToken + " ")
+	// Map writes are lowered to m.set(k, ...), the operator is already emitted
+	if jse.mapLvalue != nil {
+		return
+	}
+	if jse.derefLvalue != nil {
+		jse.emitToFile(", ")
+		return
+	}
+	jse.emitToFile(" " + jse.assignmentToken + " ")
+}
+
+func (jse *JSEmitter) PreVisitAssignStmtRhsExpr(node ast.Expr, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.openValueCopy(node, jse.structCopies[node])
+}
+
+func (jse *JSEmitter) PostVisitAssignStmtRhsExpr(node ast.Expr, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.closeValueCopy(node, jse.structCopies[node])
+	delete(jse.structCopies, node)
+}
+
+// openValueCopy starts copying an array read from a variable, or a struct
+// when copyStruct is set, so that assigning or passing it does not alias the
+// original
+func (jse *JSEmitter) openValueCopy(expr ast.Expr, copyStruct bool) {
+	if jse.copiedArray(expr) != nil {
+		jse.emitToFile("arrayCopy(")
+	} else if copyStruct {
+		jse.emitToFile("copyStruct(")
+	}
+}
+
+func (jse *JSEmitter) closeValueCopy(expr ast.Expr, copyStruct bool) {
+	if array := jse.copiedArray(expr); array != nil {
+		jse.emitToFile(arrayCopyArgs(array))
+	} else if copyStruct {
+		jse.emitToFile(structCopyArgs(jse.copiedStruct(expr)))
+	}
+}
+
+// copiedArray returns the array type of expr when it is copied, nil when expr
+// is not an array or is a new array already
+func (jse *JSEmitter) copiedArray(expr ast.Expr) *types.Array {
+	switch expr.(type) {
+	case *ast.CompositeLit, *ast.CallExpr:
+		return nil
+	}
+	t := jse.pkg.TypesInfo.TypeOf(expr)
+	if t == nil {
+		return nil
+	}
+	array, _ := t.Underlying().(*types.Array)
+	return array
+}
+
+// registerStructCopy records whether the struct value of expr is copied
+func (jse *JSEmitter) registerStructCopy(expr ast.Expr, copied bool) {
+	if !copied {
+		return
+	}
+	if jse.structCopies == nil {
+		jse.structCopies = make(map[ast.Expr]bool)
+	}
+	jse.structCopies[expr] = true
 }
 
 func (jse *JSEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {
@@ -727,9 +2473,32 @@ func (jse *JSEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if jse.mapLvalue != nil {
+		if jse.mapLvalueTok == "=" {
+			jse.emitToFile(")")
+		} else {
+			jse.emitToFile("))")
+		}
+		jse.mapLvalue = nil
+	}
+	if jse.derefLvalue != nil {
+		jse.emitToFile(")")
+		jse.derefLvalue = nil
+	}
+	jse.mapCommaOkExpr = nil
+	jse.typeAssertCommaOk = nil
+	jse.chanRecvCommaOk = nil
+	jse.sliceLvalues = nil
 	// Don't emit semicolon inside for loop init or post conditions
 	if !jse.insideForPostCond && !jse.insideForInit {
 		jse.emitToFile(";\n")
+		// v, ok := m[k] and v, ok := x.(T) share the struct they read
+		if ident, ok := node.Lhs[0].(*ast.Ident); ok && (isMapCommaOk(jse.pkg, node) || isTypeAssertCommaOk(node)) && jse.isMutableHolder(ident) {
+			if st := jse.copiedStruct(ident); st != nil {
+				name := localName(jse.pkg, jse.renames, ident)
+				jse.emitToFile(jse.emitAsString(fmt.Sprintf("%s = copyStruct(%s%s;\n", name, name, structCopyArgs(st)), indent))
+			}
+		}
 	}
 }
 
@@ -740,6 +2509,16 @@ func (jse *JSEmitter) PreVisitAssignStmtLhsExpr(node ast.Expr, index int, indent
 	if index > 0 {
 		jse.emitToFile(", ")
 	}
+	// Blank identifiers become holes of the destructuring pattern: [, ok]
+	if ident, ok := node.(*ast.Ident); ok && ident.Name == "_" {
+		jse.suppressTypeEmit = true
+	}
+}
+
+func (jse *JSEmitter) PostVisitAssignStmtLhsExpr(node ast.Expr, index int, indent int) {
+	if ident, ok := node.(*ast.Ident); ok && ident.Name == "_" {
+		jse.suppressTypeEmit = false
+	}
 }
 
 // Expression statements
@@ -759,19 +2538,39 @@ func (jse *JSEmitter) PreVisitIdent(node *ast.Ident, indent int) {
 	if jse.suppressTypeEmit {
 		return
 	}
-	name := node.Name
+	name := localName(jse.pkg, jse.renames, node)
 	// Apply builtin lowering
 	lowered := jse.lowerToBuiltins(name)
 	// If lowered to empty string, don't emit (e.g., "fmt" package)
 	if lowered == "" {
 		return
 	}
+	// Constants of BigInt types are emitted as BigInt literals, an untyped
+	// constant is declared as a number
+	if lit := jse.bigIntLit(node); lit != "" {
+		jse.emitToFile(lit)
+		return
+	}
 	// Handle special identifiers
 	switch lowered {
 	case "true", "false":
 		jse.emitToFile(lowered)
 	case "nil":
-		jse.emitToFile("null")
+		// Compared with a slice or a map, nil is an argument of isNil()
+		if jse.collectionNils[node] {
+			return
+		}
+		// Nil slices and maps are objects so they can be ranged over and read
+		nilValue := "null"
+		if t := jse.pkg.TypesInfo.TypeOf(node); t != nil {
+			switch t.Underlying().(type) {
+			case *types.Slice:
+				nilValue = "nilSlice()"
+			case *types.Map:
+				nilValue = "new NilMap()"
+			}
+		}
+		jse.emitToFile(nilValue)
 	default:
 		// Check if this identifier refers to a constant or function in the current namespace
 		if jse.inNamespace && jse.pkg != nil && jse.pkg.TypesInfo != nil {
@@ -783,16 +2582,20 @@ func (jse *JSEmitter) PreVisitIdent(node *ast.Ident, indent int) {
 						needsThis = true
 					}
 				}
-				// Check for functions
-				if _, isFunc := obj.(*types.Func); isFunc {
+				// Check for package-level variables
+				if isPackageVar(obj) && obj.Pkg().Name() == jse.currentPackage {
+					needsThis = true
+				}
+				// Check for functions (methods are selected on their receiver)
+				if fn, isFunc := obj.(*types.Func); isFunc && fn.Type().(*types.Signature).Recv() == nil {
 					if obj.Pkg() != nil && obj.Pkg().Name() == jse.currentPackage {
 						needsThis = true
 					}
 				}
 				if needsThis {
-					// Inside function literals, use package name instead of 'this'
-					// because 'this' has different meaning inside closures
-					if jse.inFuncLit {
+					// Inside function literals and methods, use package name instead
+					// of 'this' because 'this' has different meaning there
+					if jse.inFuncLit || (jse.currentFuncDecl != nil && jse.currentFuncDecl.Recv != nil) {
 						jse.emitToFile(jse.currentPackage + "." + lowered)
 					} else {
 						jse.emitToFile("this." + lowered)
@@ -810,6 +2613,10 @@ func (jse *JSEmitter) PreVisitBasicLit(node *ast.BasicLit, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if lit := jse.bigIntLit(node); lit != "" {
+		jse.emitToFile(lit)
+		return
+	}
 	switch node.Kind {
 	case token.STRING:
 		// Handle raw strings
@@ -846,6 +2653,8 @@ func (jse *JSEmitter) PreVisitBasicLit(node *ast.BasicLit, indent int) {
 				default:
 					charCode = int(inner[1])
 				}
+			} else if r, ok := runeLiteralValue(jse.pkg, node); ok {
+				charCode = int(r)
 			} else {
 				// Fallback to emitting as string
 				jse.emitToFile(node.Value)
@@ -865,6 +2674,37 @@ func (jse *JSEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	jse.openBigIntConst(node)
+	// Arrays are compared element by element: a == b becomes arrayEqual(a, b)
+	isArrayComparison := (node.Op == token.EQL || node.Op == token.NEQ) && isArrayExpr(jse.pkg, node.X)
+	jse.arrayComparisons = append(jse.arrayComparisons, isArrayComparison)
+	// Slices and maps are nil without an array: s == nil becomes isNil(s)
+	isNilComparison := isCollectionNilComparison(jse.pkg, node)
+	jse.nilComparisons = append(jse.nilComparisons, isNilComparison)
+	op := jse.intOp(node)
+	jse.intOps = append(jse.intOps, op)
+	if isNilComparison {
+		if jse.collectionNils == nil {
+			jse.collectionNils = make(map[ast.Expr]bool)
+		}
+		jse.collectionNils[node.Y] = true
+		if node.Op == token.NEQ {
+			jse.emitToFile("!")
+		}
+		jse.emitToFile("isNil(")
+		return
+	}
+	if isArrayComparison {
+		if node.Op == token.NEQ {
+			jse.emitToFile("!")
+		}
+		jse.emitToFile("arrayEqual(")
+		return
+	}
+	if op.open != "" {
+		jse.emitToFile(op.open)
+		return
+	}
 	// Check for integer division
 	if node.Op == token.QUO {
 		// Check if both operands are integer types
@@ -877,6 +2717,10 @@ func (jse *JSEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 				if leftIsBasic && rightIsBasic {
 					if (leftBasic.Info()&types.IsInteger) != 0 && (rightBasic.Info()&types.IsInteger) != 0 {
 						jse.intDivision = true
+						// | 0 would truncate a 64-bit quotient to 32 bits
+						if isWideInt(jse.pkg, node) {
+							jse.emitToFile("Math.trunc")
+						}
 					}
 				}
 			}
@@ -889,6 +2733,17 @@ func (jse *JSEmitter) PreVisitBinaryExprOperator(op token.Token, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if jse.nilComparisons[len(jse.nilComparisons)-1] {
+		return
+	}
+	if jse.arrayComparisons[len(jse.arrayComparisons)-1] {
+		jse.emitToFile(", ")
+		return
+	}
+	if sep := jse.intOps[len(jse.intOps)-1].sep; sep != "" {
+		jse.emitToFile(sep)
+		return
+	}
 	opStr := op.String()
 	// Handle Go operators that need conversion
 	switch opStr {
@@ -896,6 +2751,8 @@ func (jse *JSEmitter) PreVisitBinaryExprOperator(op token.Token, indent int) {
 		jse.emitToFile(" && ")
 	case "||":
 		jse.emitToFile(" || ")
+	case "&^":
+		jse.emitToFile(" & ~")
 	default:
 		jse.emitToFile(" " + opStr + " ")
 	}
@@ -905,40 +2762,396 @@ func (jse *JSEmitter) PostVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	defer jse.closeBigIntConst(node)
+	jse.arrayComparisons = jse.arrayComparisons[:len(jse.arrayComparisons)-1]
+	jse.nilComparisons = jse.nilComparisons[:len(jse.nilComparisons)-1]
+	op := jse.intOps[len(jse.intOps)-1]
+	jse.intOps = jse.intOps[:len(jse.intOps)-1]
+	if op.close != "" {
+		jse.emitToFile(op.close)
+		return
+	}
 	// Only add | 0 for the actual division operation, not nested expressions
 	if node.Op == token.QUO && jse.intDivision {
 		// Use bitwise OR to convert to integer (truncate towards zero)
-		jse.emitToFile(" | 0)")
+		if isWideInt(jse.pkg, node) {
+			jse.emitToFile(")")
+		} else {
+			jse.emitToFile(" | 0)")
+		}
 		jse.intDivision = false
 	} else {
 		jse.emitToFile(")")
 	}
 }
 
+// intOp returns the form of a non-constant integer operation on numbers that
+// gives Go's result. Results of integers up to 32 bits are truncated to their
+// width, which wraps them around: ((a + b) & 0xFF), ((a + b) << 24 >> 24),
+// (Math.imul(a, b) >>> 0). Shifts by a count that may be negative or beyond
+// the width call the runtime: intShl(x, n, 8), and a divisor that may be zero
+// is checked: ((a / divisor(b)) | 0). Wider integers are exact up to 2^53,
+// their division is truncated and their shifts are arithmetic.
+func (jse *JSEmitter) intOp(node *ast.BinaryExpr) intOp {
+	basic := intOpType(jse.pkg, node)
+	if basic == nil {
+		// Bitwise operations yield int32, a uint32 result is made unsigned again
+		if b := bitwiseOpType(jse.pkg, node); b != nil && isJSWideInt(b) {
+			return jsWideOp(node.Op, b)
+		} else if b != nil && intBits(b) == 32 && isUnsignedInt(b) {
+			return intOp{open: "((", close: ") >>> 0)"}
+		}
+		return intOp{}
+	}
+	if isJSBigInt(basic) {
+		return jsBigIntOp(node.Op, basic)
+	}
+	if isJSWideInt(basic) {
+		return jsWideOp(node.Op, basic)
+	}
+	bits := intBits(basic)
+	// A BigInt count is converted by the shift helpers
+	shift := (node.Op == token.SHL || node.Op == token.SHR) &&
+		(!shiftCountInRange(jse.pkg, node, basic) || isJSBigInt(jse.pkg.TypesInfo.TypeOf(node.Y)))
+	// The divisor is checked for zero by divisor()
+	quo, quoClose := "", ""
+	if mayDivideByZero(jse.pkg, node) {
+		quo, quoClose = " "+node.Op.String()+" divisor(", ")"
+	}
+	if bits == 64 {
+		// + 0 turns a negative zero, which prints as -0, into 0
+		switch {
+		case node.Op == token.QUO:
+			return intOp{open: "(Math.trunc(", sep: quo, close: quoClose + ") + 0)"}
+		case node.Op == token.MUL || node.Op == token.REM:
+			return intOp{open: "((", sep: quo, close: quoClose + ") + 0)"}
+		case node.Op == token.SHL:
+			return intOp{open: "intShl(", sep: ", ", close: ", 64)"}
+		case node.Op == token.SHR:
+			return intOp{open: "intShr(", sep: ", ", close: ", 64)"}
+		}
+		return intOp{}
+	}
+	wrap := jsWrap(basic)
+	switch {
+	case shift && node.Op == token.SHL:
+		return intOp{open: "(intShl(", sep: ", ", close: fmt.Sprintf(", %d)%s)", bits, wrap)}
+	case shift:
+		return intOp{open: "intShr(", sep: ", ", close: fmt.Sprintf(", %d)", bits)}
+	case node.Op == token.SHR && isUnsignedInt(basic):
+		return intOp{open: "(", sep: " >>> ", close: ")"}
+	case node.Op == token.SHR:
+		return intOp{}
+	case node.Op == token.MUL && bits == 32:
+		// The product of 32-bit integers may not be exact
+		return intOp{open: "(Math.imul(", sep: ", ", close: ")" + wrap + ")"}
+	}
+	return intOp{open: "((", sep: quo, close: quoClose + ")" + wrap + ")"}
+}
+
+// jsBigIntOp returns the form of an operation on BigInts of the integer type
+// basic, whose result is truncated to 64 bits: BigInt.asIntN(64, a * b). The
+// count of a shift, a number or a BigInt, is converted by bigShift().
+func jsBigIntOp(op token.Token, basic *types.Basic) intOp {
+	switch op {
+	case token.SHL:
+		return intOp{open: jsBigIntWrap(basic), sep: " << bigShift(", close: "))"}
+	case token.SHR:
+		return intOp{open: "(", sep: " >> bigShift(", close: "))"}
+	case token.QUO:
+		// The quotient of the most negative int64 by -1 overflows
+		if isUnsignedInt(basic) {
+			return intOp{open: "(", close: ")"}
+		}
+	case token.REM:
+		return intOp{}
+	}
+	return intOp{open: jsBigIntWrap(basic), close: ")"}
+}
+
+// jsWideOp returns the form of an operation on 64-bit int or uint values,
+// which the runtime wraps around to 64 bits: wideAdd(a, b, true)
+func jsWideOp(op token.Token, basic *types.Basic) intOp {
+	name := map[token.Token]string{
+		token.ADD: "wideAdd", token.SUB: "wideSub", token.MUL: "wideMul",
+		token.QUO: "wideQuo", token.REM: "wideRem", token.SHL: "wideShl",
+		token.SHR: "wideShr", token.AND: "wideAnd", token.OR: "wideOr",
+		token.XOR: "wideXor", token.AND_NOT: "wideAndNot",
+	}[op]
+	return intOp{open: name + "(", sep: ", ", close: fmt.Sprintf(", %t)", !isUnsignedInt(basic))}
+}
+
+// jsBigIntWrap returns the call truncating a BigInt to the 64-bit integer
+// type basic, up to its argument
+func jsBigIntWrap(basic *types.Basic) string {
+	if isUnsignedInt(basic) {
+		return "BigInt.asUintN(64, "
+	}
+	return "BigInt.asIntN(64, "
+}
+
+// jsWrap returns the operation truncating a number to the integer type
+// basic of up to 32 bits
+func jsWrap(basic *types.Basic) string {
+	bits := intBits(basic)
+	switch {
+	case bits == 32 && isUnsignedInt(basic):
+		return " >>> 0"
+	case bits == 32:
+		return " | 0"
+	case isUnsignedInt(basic):
+		return fmt.Sprintf(" & 0x%X", 1<<bits-1)
+	}
+	return fmt.Sprintf(" << %d >> %d", 32-bits, 32-bits)
+}
+
 // Unary expressions
 func (jse *JSEmitter) PreVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
-	if jse.forwardDecl {
+	if jse.forwardDecl || jse.openBigIntConst(node) {
+		return
+	}
+	// &T{...} is the new object itself, &x refers to a copy of x
+	if node.Op == token.AND {
+		if _, ok := node.X.(*ast.CompositeLit); !ok {
+			jse.emitToFile("copyStruct(")
+		}
+		return
+	}
+	if node.Op == token.ARROW {
+		if node == jse.chanRecvCommaOk {
+			jse.emitToFile("(await chanRecvOk(")
+		} else {
+			jse.emitToFile("(await chanRecv(")
+		}
+		return
+	}
+	// -x and ^x are truncated to the width of x, ^x of wider integers is -x - 1
+	if basic := intOpType(jse.pkg, node); basic != nil {
+		if isJSBigInt(basic) {
+			if node.Op == token.XOR {
+				jse.emitToFile(jsBigIntWrap(basic) + "~")
+			} else {
+				jse.emitToFile(jsBigIntWrap(basic) + "-")
+			}
+			return
+		}
+		if isJSWideInt(basic) && node.Op == token.XOR {
+			jse.emitToFile("wideNot(")
+		} else if isJSWideInt(basic) {
+			jse.emitToFile("wideNeg(")
+		} else if node.Op == token.XOR && intBits(basic) == 64 {
+			jse.emitToFile("(-")
+		} else if node.Op == token.XOR {
+			jse.emitToFile("(~")
+		} else {
+			jse.emitToFile("(-")
+		}
 		return
 	}
 	jse.emitToFile(node.Op.String())
 }
 
+func (jse *JSEmitter) PostVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if jse.bigIntConst == node {
+		jse.closeBigIntConst(node)
+		return
+	}
+	if basic := intOpType(jse.pkg, node); basic != nil {
+		if isJSBigInt(basic) {
+			jse.emitToFile(")")
+		} else if isJSWideInt(basic) {
+			jse.emitToFile(fmt.Sprintf(", %t)", !isUnsignedInt(basic)))
+		} else if intBits(basic) == 64 && node.Op == token.XOR {
+			jse.emitToFile(" - 1)")
+		} else if intBits(basic) == 64 {
+			jse.emitToFile(" + 0)")
+		} else {
+			jse.emitToFile(jsWrap(basic) + ")")
+		}
+	}
+	if _, ok := node.X.(*ast.CompositeLit); node.Op == token.AND && !ok {
+		jse.emitToFile(jse.derefCopyArgs(node.X))
+	}
+	if node.Op == token.ARROW {
+		jse.emitToFile("))")
+	}
+}
+
+// PreVisitStarExpr emits a dereference *p as a copy of the struct p refers
+// to, unless it is assigned to. Pointer types are not emitted
+func (jse *JSEmitter) PreVisitStarExpr(node *ast.StarExpr, indent int) {
+	if jse.forwardDecl || node == jse.derefLvalue || isStructPointer(jse.pkg.TypesInfo.TypeOf(node)) {
+		return
+	}
+	jse.emitToFile("copyStruct(")
+}
+
+func (jse *JSEmitter) PostVisitStarExpr(node *ast.StarExpr, indent int) {
+	if jse.forwardDecl || node == jse.derefLvalue || isStructPointer(jse.pkg.TypesInfo.TypeOf(node)) {
+		return
+	}
+	jse.emitToFile(jse.derefCopyArgs(node))
+}
+
+// derefCopyArgs returns the arguments closing the copyStruct( of &x or *p,
+// whose struct value expr is
+func (jse *JSEmitter) derefCopyArgs(expr ast.Expr) string {
+	if st := jse.copiedStruct(expr); st != nil {
+		return structCopyArgs(st)
+	}
+	return ")"
+}
+
 // Call expressions
 func (jse *JSEmitter) PreVisitCallExpr(node *ast.CallExpr, indent int) {
+	if jse.forwardDecl || jse.openBigIntConst(node) {
+		return
+	}
+	if jse.isBlockingCall(node) {
+		jse.emitToFile("(await ")
+	}
+	if node.Ellipsis.IsValid() && len(node.Args) > 0 {
+		last := node.Args[len(node.Args)-1]
+		if fn := calledFunc(jse.pkg, node); isBuiltinCall(jse.pkg, node, "append") || (fn != nil && fn.Pkg() != nil && fn.Pkg().Path() == "fmt") {
+			jse.spreadArgs = append(jse.spreadArgs, last)
+		} else {
+			if jse.passedSlices == nil {
+				jse.passedSlices = make(map[ast.Expr]bool)
+			}
+			jse.passedSlices[last] = true
+		}
+	}
+	// console.log prints BigInts with an n suffix, fmt.Println does not
+	if jse.isFmtCall(node, "Println") {
+		for _, arg := range node.Args {
+			if t := jse.pkg.TypesInfo.TypeOf(arg); isJSBigInt(t) || isJSWideInt(t) {
+				if jse.bigIntPrintArgs == nil {
+					jse.bigIntPrintArgs = make(map[ast.Expr]bool)
+				}
+				jse.bigIntPrintArgs[arg] = true
+			}
+		}
+	}
+	jse.registerArgCopies(node)
+	if isSliceMakeCall(jse.pkg, node) {
+		jse.sliceMakeType = node.Args[0]
+	}
+	// new(T) is a new zero value object
+	if isBuiltinCall(jse.pkg, node, "new") {
+		jse.emitDefaultValue(jse.pkg.TypesInfo.TypeOf(node.Args[0]))
+		jse.suppressRangeEmit = true
+		jse.newCallArg = node.Args[0]
+	}
+}
+
+// registerArgCopies records the arguments of call copied with copyStruct, and
+// the slices whose struct elements append(s, xs...) and copy(dst, src)
+// duplicate. Arguments are copied for the parameters that paramCopies says,
+// for the ones of calls that are not resolved, and when an interface
+// parameter keeps a struct that its source may change in place
+func (jse *JSEmitter) registerArgCopies(call *ast.CallExpr) {
+	if tv, ok := jse.pkg.TypesInfo.Types[call.Fun]; ok && tv.IsType() {
+		return
+	}
+	var params *types.Tuple
+	variadic := false
+	if fn, ok := calledFunc(jse.pkg, call).(*types.Func); ok {
+		sel, isSel := call.Fun.(*ast.SelectorExpr)
+		if s, ok := jse.pkg.TypesInfo.Selections[sel]; !isSel || !ok || s.Kind() != types.MethodExpr {
+			sig := fn.Type().(*types.Signature)
+			params, variadic = sig.Params(), sig.Variadic()
+		}
+	}
+	// fmt functions only format their arguments
+	callee := calledFunc(jse.pkg, call)
+	formats := callee != nil && callee.Pkg() != nil && callee.Pkg().Path() == "fmt"
+	for i, arg := range call.Args {
+		mutableHolder := true
+		if params != nil && (!variadic || i < params.Len()-1) && i < params.Len() {
+			if copied, ok := jse.paramCopies[params.At(i)]; ok {
+				mutableHolder = copied
+			}
+		}
+		copied := mutableHolder && jse.needsStructCopy(arg, true)
+		if t := argParamType(params, variadic, call, i); t != nil && types.IsInterface(t) && !formats {
+			copied = copied || jse.needsStructCopy(arg, false)
+		}
+		jse.registerStructCopy(arg, copied)
+	}
+	elementCopy := func(slice ast.Expr, closing string) {
+		t, ok := jse.pkg.TypesInfo.TypeOf(slice).Underlying().(*types.Slice)
+		if !ok {
+			return
+		}
+		if copyElement := valueCopyFunc(t.Elem()); copyElement != "" {
+			if jse.elementCopies == nil {
+				jse.elementCopies = make(map[ast.Expr]string)
+			}
+			jse.elementCopies[slice] = ", " + copyElement + closing
+		}
+	}
+	switch {
+	case isBuiltinCall(jse.pkg, call, "append") && call.Ellipsis.IsValid():
+		elementCopy(call.Args[1], ")")
+	case isBuiltinCall(jse.pkg, call, "copy"):
+		elementCopy(call.Args[1], "")
+	}
+}
+
+// argParamType returns the type of the parameter the i-th argument of call
+// is passed to, the element type for the variadic ones, nil when unknown
+func argParamType(params *types.Tuple, variadic bool, call *ast.CallExpr, i int) types.Type {
+	if params == nil || params.Len() == 0 {
+		return nil
+	}
+	if variadic && i >= params.Len()-1 {
+		if call.Ellipsis.IsValid() {
+			return nil
+		}
+		return params.At(params.Len() - 1).Type().(*types.Slice).Elem()
+	}
+	if i < params.Len() {
+		return params.At(i).Type()
+	}
+	return nil
+}
+
+func (jse *JSEmitter) PostVisitCallExpr(node *ast.CallExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if jse.bigIntConst == node {
+		jse.closeBigIntConst(node)
+		return
+	}
+	if jse.isBlockingCall(node) {
+		jse.emitToFile(")")
+	}
 }
 
 func (jse *JSEmitter) PreVisitCallExprFun(node ast.Expr, indent int) {
 	// Don't emit here - the function name will be emitted by PreVisitIdent
 	// through traverseExpression
+	if jse.forwardDecl {
+		return
+	}
+	// A function literal called in place must be parenthesized, otherwise
+	// it parses as a declaration at the start of a statement
+	if _, ok := node.(*ast.FuncLit); ok {
+		jse.emitToFile("(")
+	}
 }
 
 func (jse *JSEmitter) PostVisitCallExprFun(node ast.Expr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if _, ok := node.(*ast.FuncLit); ok {
+		jse.emitToFile(")")
+	}
 	jse.emitToFile("(")
 }
 
@@ -949,12 +3162,92 @@ func (jse *JSEmitter) PreVisitCallExprArg(node ast.Expr, index int, indent int)
 	if index > 0 {
 		jse.emitToFile(", ")
 	}
+	// append(s, xs...) and fmt functions spread the slice into rest
+	// parameters, f(xs...) passes the slice itself
+	if n := len(jse.spreadArgs); n > 0 && jse.spreadArgs[n-1] == node {
+		jse.spreadArgs = jse.spreadArgs[:n-1]
+		jse.emitToFile("...")
+		if _, ok := jse.elementCopies[node]; ok {
+			jse.emitToFile("Array.from(")
+		}
+	}
+	if jse.passedSlices[node] {
+		jse.emitToFile("new Spread(")
+	}
+	// Slice types are emitted as a SliceType holding the zero value of the
+	// elements, e.g. make([]int, n) -> make(new SliceType(() => (0)), n)
+	if node == jse.sliceMakeType {
+		jse.emitToFile("new SliceType(() => (")
+		jse.emitDefaultValue(jse.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Slice).Elem())
+		jse.emitToFile("))")
+		jse.suppressRangeEmit = true
+		return
+	}
+	jse.openValueCopy(node, jse.structCopies[node])
+	if jse.bigIntPrintArgs[node] {
+		jse.emitToFile("String(")
+	}
+}
+
+func (jse *JSEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if node == jse.sliceMakeType {
+		jse.suppressRangeEmit = false
+		jse.sliceMakeType = nil
+		return
+	}
+	if jse.bigIntPrintArgs[node] {
+		delete(jse.bigIntPrintArgs, node)
+		jse.emitToFile(")")
+	}
+	jse.closeValueCopy(node, jse.structCopies[node])
+	delete(jse.structCopies, node)
+	if closing, ok := jse.elementCopies[node]; ok {
+		delete(jse.elementCopies, node)
+		jse.emitToFile(closing)
+	}
+	if jse.passedSlices[node] {
+		delete(jse.passedSlices, node)
+		jse.emitToFile(")")
+	}
+}
+
+// isFmtCall reports whether call calls the function name of package fmt
+func (jse *JSEmitter) isFmtCall(call *ast.CallExpr, name string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != name {
+		return false
+	}
+	x, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	pkgName, ok := jse.pkg.TypesInfo.Uses[x].(*types.PkgName)
+	return ok && pkgName.Imported().Path() == "fmt"
+}
+
+// PreVisitCallExprVariadicArgs separates the variadic arguments, which rest
+// parameters collect without packing
+func (jse *JSEmitter) PreVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if index > 0 && index < len(node.Args) {
+		jse.emitToFile(", ")
+	}
 }
 
 func (jse *JSEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if len(node) == 1 && node[0] == jse.newCallArg {
+		jse.suppressRangeEmit = false
+		jse.newCallArg = nil
+		return
+	}
 	jse.emitToFile(")")
 }
 
@@ -963,6 +3256,28 @@ func (jse *JSEmitter) PreVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	// The caller may change the result in place, a variable that owns its
+	// object hands it over
+	for _, result := range node.Results {
+		jse.registerStructCopy(result, jse.needsStructCopy(result, true) && !jse.isMovedResult(node, result))
+	}
+	if scope := currentDeferScope(jse.deferScopes); scope.stack != "" && len(node.Results) > 0 {
+		// Named results are assigned first, deferred calls may change them
+		if names := resultNames(enclosingFuncResults(jse.pkg, jse.currentFuncDecl, node.Pos())); names != nil {
+			jse.emitToFile(jse.emitAsString("{ ", indent))
+			if returnsNamedResults(node, names) {
+				jse.suppressRangeEmit = true
+			} else if len(names) > 1 {
+				jse.emitToFile("[" + strings.Join(names, ", ") + "] = ")
+				if len(node.Results) > 1 {
+					jse.emitToFile("[")
+				}
+			} else {
+				jse.emitToFile(names[0] + " = ")
+			}
+			return
+		}
+	}
 	str := jse.emitAsString("return ", indent)
 	jse.emitToFile(str)
 	if len(node.Results) > 1 {
@@ -979,12 +3294,34 @@ func (jse *JSEmitter) PreVisitReturnStmtResult(node ast.Expr, index int, indent
 	if index > 0 {
 		jse.emitToFile(", ")
 	}
+	jse.openValueCopy(node, jse.structCopies[node])
+}
+
+func (jse *JSEmitter) PostVisitReturnStmtResult(node ast.Expr, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.closeValueCopy(node, jse.structCopies[node])
+	delete(jse.structCopies, node)
 }
 
 func (jse *JSEmitter) PostVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if scope := currentDeferScope(jse.deferScopes); scope.stack != "" && len(node.Results) > 0 {
+		if names := resultNames(enclosingFuncResults(jse.pkg, jse.currentFuncDecl, node.Pos())); names != nil {
+			if returnsNamedResults(node, names) {
+				jse.suppressRangeEmit = false
+			} else if len(node.Results) > 1 {
+				jse.emitToFile("]; ")
+			} else {
+				jse.emitToFile("; ")
+			}
+			jse.emitToFile("break " + scope.label + "; }\n")
+			return
+		}
+	}
 	if len(node.Results) > 1 {
 		jse.emitToFile("]")
 		jse.inMultiValueReturn = false
@@ -1074,6 +3411,24 @@ func (jse *JSEmitter) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	jse.isMapRange = isMapExpr(jse.pkg, node.X)
+	jse.isStringRange = isStringExpr(jse.pkg, node.X)
+	jse.isSliceRange = isSliceExpr(jse.pkg, node.X)
+	jse.rangeStringBindings = ""
+	if jse.isStringRange {
+		// for (const [i, r] of stringRange(s)), omitted names are left out
+		key, value := stringRangeNames(jse.pkg, jse.renames, node)
+		switch {
+		case key == "_" && value == "_":
+			jse.rangeStringBindings = "_"
+		case value == "_":
+			jse.rangeStringBindings = fmt.Sprintf("[%s]", key)
+		case key == "_":
+			jse.rangeStringBindings = fmt.Sprintf("[, %s]", value)
+		default:
+			jse.rangeStringBindings = fmt.Sprintf("[%s, %s]", key, value)
+		}
+	}
 	// Handle different range patterns
 	// Note: Go AST sets Key=nil when using blank identifier _, so we check Value first
 	if node.Value != nil {
@@ -1089,8 +3444,8 @@ func (jse *JSEmitter) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
 				jse.rangeKeyName = "_idx"
 				DebugLogPrintf("JSEmitter: Range key is blank _, using _idx")
 			} else {
-				jse.rangeKeyName = keyIdent.Name
-				DebugLogPrintf("JSEmitter: Range key is %s", keyIdent.Name)
+				jse.rangeKeyName = localName(jse.pkg, jse.renames, keyIdent)
+				DebugLogPrintf("JSEmitter: Range key is %s", jse.rangeKeyName)
 			}
 		} else {
 			// Key is not a simple identifier, use synthetic index
@@ -1098,8 +3453,9 @@ func (jse *JSEmitter) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
 			DebugLogPrintf("JSEmitter: Range key not ident, using _idx")
 		}
 		if valIdent, ok := node.Value.(*ast.Ident); ok {
-			jse.rangeValueName = valIdent.Name
+			jse.rangeValueName = localName(jse.pkg, jse.renames, valIdent)
 		}
+		jse.rangeValueCopyArgs = jse.rangeValueCopy(node)
 		jse.rangeCollectionExpr = ""
 		jse.suppressRangeEmit = true
 		jse.rangeStmtIndent = indent
@@ -1110,7 +3466,7 @@ func (jse *JSEmitter) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
 			if keyIdent.Name == "_" {
 				jse.rangeKeyName = "_idx"
 			} else {
-				jse.rangeKeyName = keyIdent.Name
+				jse.rangeKeyName = localName(jse.pkg, jse.renames, keyIdent)
 			}
 		} else {
 			jse.rangeKeyName = "_idx"
@@ -1120,6 +3476,11 @@ func (jse *JSEmitter) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
 		jse.rangeStmtIndent = indent
 	} else {
 		DebugLogPrintf("JSEmitter: Range has nil Key and nil Value")
+		jse.isKeyValueRange = false
+		jse.rangeKeyName = "_idx"
+		jse.rangeCollectionExpr = ""
+		jse.suppressRangeEmit = true
+		jse.rangeStmtIndent = indent
 	}
 }
 
@@ -1158,7 +3519,18 @@ func (jse *JSEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 	key := jse.rangeKeyName
 	rangeIndent := jse.rangeStmtIndent
 
-	if jse.isKeyValueRange {
+	if jse.isStringRange {
+		// Emit: for (const [i, r] of stringRange(collection))
+		str := jse.emitAsString(fmt.Sprintf("for (const %s of stringRange(%s)) ", jse.rangeStringBindings, collection), rangeIndent)
+		jse.emitToFile(str)
+	} else if jse.isMapRange {
+		// Emit: for (let key of mapKeys(collection))
+		str := jse.emitAsString(fmt.Sprintf("for (let %s of mapKeys(%s)) ", key, collection), rangeIndent)
+		jse.emitToFile(str)
+		if jse.isKeyValueRange && jse.rangeValueName != "" && jse.rangeValueName != "_" {
+			jse.pendingRangeValueDecl = true
+		}
+	} else if jse.isKeyValueRange {
 		// Emit: for (let key = 0; key < collection.length; key++)
 		str := jse.emitAsString(fmt.Sprintf("for (let %s = 0; %s < %s.length; %s++) ", key, key, collection, key), rangeIndent)
 		jse.emitToFile(str)
@@ -1178,8 +3550,11 @@ func (jse *JSEmitter) PostVisitRangeStmt(node *ast.RangeStmt, indent int) {
 		return
 	}
 	jse.isKeyValueRange = false
+	jse.isStringRange = false
+	jse.isSliceRange = false
 	jse.rangeKeyName = ""
 	jse.rangeValueName = ""
+	jse.rangeValueCopyArgs = ""
 	jse.rangeCollectionExpr = ""
 }
 
@@ -1188,6 +3563,13 @@ func (jse *JSEmitter) PreVisitIncDecStmt(node *ast.IncDecStmt, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if isMapIndexExpr(jse.pkg, node.X) {
+		jse.mapLvalue = node.X
+		jse.mapLvalueTok = node.Tok.String()
+	}
+	if isSliceIndexExpr(jse.pkg, node.X) {
+		jse.sliceLvalues = append(jse.sliceLvalues, node.X)
+	}
 	if !jse.insideForPostCond {
 		str := jse.emitAsString("", indent)
 		jse.emitToFile(str)
@@ -1204,7 +3586,13 @@ func (jse *JSEmitter) PostVisitIncDecStmt(node *ast.IncDecStmt, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	jse.emitToFile(node.Tok.String())
+	if jse.mapLvalue != nil {
+		jse.emitToFile(")")
+		jse.mapLvalue = nil
+	} else {
+		jse.emitToFile(node.Tok.String())
+	}
+	jse.sliceLvalues = nil
 	if !jse.insideForPostCond {
 		jse.emitToFile(";\n")
 	}
@@ -1215,37 +3603,112 @@ func (jse *JSEmitter) PreVisitIndexExpr(node *ast.IndexExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if isMapIndexExpr(jse.pkg, node) {
+		if node == jse.mapLvalue {
+			// Capture the map and key expressions, they are needed twice for m[k] op= v
+			jse.captureMapExpr = true
+			jse.mapExprText = ""
+		} else if node == jse.mapCommaOkExpr {
+			jse.emitToFile("mapGetOk(")
+		} else {
+			jse.emitToFile("mapGet(")
+		}
+	} else if isSliceIndexExpr(jse.pkg, node) {
+		if jse.isSliceLvalue(node) {
+			// The slice is needed three times for s.array[s.offset + indexCheck(s, i)] = v
+			jse.captureIndexExpr = true
+			jse.indexExprText = ""
+		} else if isStringExpr(jse.pkg, node.X) {
+			jse.emitToFile("stringAt(")
+		} else {
+			jse.emitToFile("sliceAt(")
+		}
+	}
+}
+
+// isSliceLvalue reports whether node is a slice element being assigned to
+func (jse *JSEmitter) isSliceLvalue(node ast.Expr) bool {
+	for _, lvalue := range jse.sliceLvalues {
+		if lvalue == node {
+			return true
+		}
+	}
+	return false
 }
 
 func (jse *JSEmitter) PreVisitIndexExprIndex(node *ast.IndexExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	// Check if indexing a string - need to use charCodeAt() to get byte value like Go
-	jse.isStringIndex = false
-	if jse.pkg != nil && jse.pkg.TypesInfo != nil {
-		tv := jse.pkg.TypesInfo.Types[node.X]
-		if tv.Type != nil {
-			if basic, ok := tv.Type.(*types.Basic); ok && basic.Kind() == types.String {
-				jse.isStringIndex = true
-				jse.emitToFile(".charCodeAt(")
-				return
-			}
+	if isMapIndexExpr(jse.pkg, node) {
+		if node == jse.mapLvalue {
+			jse.mapLhsMap = jse.mapExprText
+			jse.mapExprText = ""
+		} else {
+			jse.emitToFile(", ")
 		}
+		return
+	}
+	if isSliceIndexExpr(jse.pkg, node) {
+		if jse.isSliceLvalue(node) {
+			jse.captureIndexExpr = false
+			s := jse.indexExprText
+			jse.emitToFile(s + ".array[" + s + ".offset + indexCheck(" + s + ", ")
+		} else {
+			jse.emitToFile(", ")
+		}
+	} else {
+		jse.emitToFile("[")
+	}
+	// Strings and slice bounds take a number index
+	if isJSBigInt(jse.pkg.TypesInfo.TypeOf(node.Index)) {
+		jse.emitToFile("Number(")
 	}
-	jse.emitToFile("[")
 }
 
 func (jse *JSEmitter) PostVisitIndexExpr(node *ast.IndexExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	if jse.isStringIndex {
+	if isMapIndexExpr(jse.pkg, node) {
+		zeroType := jse.pkg.TypesInfo.TypeOf(node.X).Underlying().(*types.Map).Elem()
+		if node != jse.mapLvalue {
+			jse.emitToFile(", ")
+			jse.emitDefaultValue(zeroType)
+			jse.emitToFile(")")
+			return
+		}
+		jse.captureMapExpr = false
+		jse.mapLhsKey = jse.mapExprText
+		jse.emitToFile(jse.mapLhsMap + ".set(" + jse.mapLhsKey + ", ")
+		if jse.mapLvalueTok == "=" {
+			return
+		}
+		// m[k] op= v -> m.set(k, mapGet(m, k, zero) op (v))
+		jse.emitToFile("mapGet(" + jse.mapLhsMap + ", " + jse.mapLhsKey + ", ")
+		jse.emitDefaultValue(zeroType)
+		switch jse.mapLvalueTok {
+		case "++":
+			jse.emitToFile(") + 1")
+		case "--":
+			jse.emitToFile(") - 1")
+		default:
+			jse.emitToFile(") " + strings.TrimSuffix(jse.mapLvalueTok, "=") + " (")
+		}
+		return
+	}
+	if isJSBigInt(jse.pkg.TypesInfo.TypeOf(node.Index)) {
 		jse.emitToFile(")")
-		jse.isStringIndex = false
-	} else {
-		jse.emitToFile("]")
 	}
+	if isSliceIndexExpr(jse.pkg, node) {
+		if jse.isSliceLvalue(node) {
+			jse.emitToFile(")]")
+		} else {
+			jse.emitToFile(")")
+		}
+		return
+	}
+	jse.emitToFile("]")
 }
 
 // Composite literals (arrays, objects)
@@ -1253,22 +3716,32 @@ func (jse *JSEmitter) PreVisitCompositeLit(node *ast.CompositeLit, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	// Check if it's a struct or array
+	isMapLit := isMapExpr(jse.pkg, node)
+	jse.mapCompositeLits = append(jse.mapCompositeLits, isMapLit)
+	if isMapLit {
+		jse.emitToFile("new Map([")
+		return
+	}
+	// Arrays of numbers are typed arrays, e.g. [3]int{1, 2} -> new Int32Array([1, 2, 0])
+	if array, ok := jse.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Array); ok {
+		if typed := jsTypedArray(array); typed != "" {
+			jse.emitToFile("new " + typed + "(")
+		}
+		jse.emitToFile("[")
+		return
+	}
+	// Slice literals own a new array, e.g. []int{1, 2} -> sliceOf([1, 2])
+	if _, ok := jse.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Slice); ok {
+		jse.emitToFile("sliceOf([")
+		return
+	}
+	// Check if it's a struct
 	if node.Type != nil {
 		switch node.Type.(type) {
-		case *ast.ArrayType:
-			jse.emitToFile("[")
-			return
-		case *ast.Ident, *ast.SelectorExpr:
-			// Check if this named type is actually a slice type alias
-			if jse.pkg != nil && jse.pkg.TypesInfo != nil {
-				if typeAndValue, ok := jse.pkg.TypesInfo.Types[node]; ok {
-					underlying := typeAndValue.Type.Underlying()
-					if _, isSlice := underlying.(*types.Slice); isSlice {
-						jse.emitToFile("[")
-						return
-					}
-				}
+		case *ast.Ident, *ast.SelectorExpr, *ast.IndexListExpr:
+			// Structs with methods are instances of their class
+			if className := jse.structClassName(jse.pkg.TypesInfo.TypeOf(node)); className != "" {
+				jse.emitToFile("Object.assign(new " + className + "(), ")
 			}
 			// Struct initialization - use object literal syntax
 			// Check if it has named fields (KeyValueExpr)
@@ -1295,6 +3768,17 @@ func (jse *JSEmitter) PostVisitCompositeLitType(node ast.Expr, indent int) {
 	jse.suppressTypeEmit = false
 }
 
+// Type arguments of generic instantiations are erased
+func (jse *JSEmitter) PreVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {
+	jse.typeArgsSuppress = append(jse.typeArgsSuppress, jse.suppressTypeEmit)
+	jse.suppressTypeEmit = true
+}
+
+func (jse *JSEmitter) PostVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {
+	jse.suppressTypeEmit = jse.typeArgsSuppress[len(jse.typeArgsSuppress)-1]
+	jse.typeArgsSuppress = jse.typeArgsSuppress[:len(jse.typeArgsSuppress)-1]
+}
+
 func (jse *JSEmitter) PreVisitCompositeLitElt(node ast.Expr, index int, indent int) {
 	if jse.forwardDecl {
 		return
@@ -1302,23 +3786,50 @@ func (jse *JSEmitter) PreVisitCompositeLitElt(node ast.Expr, index int, indent i
 	if index > 0 {
 		jse.emitToFile(", ")
 	}
+	jse.openValueCopy(node, jse.needsStructCopy(node, true))
+}
+
+func (jse *JSEmitter) PostVisitCompositeLitElt(node ast.Expr, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.closeValueCopy(node, jse.needsStructCopy(node, true))
 }
 
 func (jse *JSEmitter) PostVisitCompositeLit(node *ast.CompositeLit, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	isMapLit := jse.insideMapCompositeLit()
+	jse.mapCompositeLits = jse.mapCompositeLits[:len(jse.mapCompositeLits)-1]
+	if isMapLit {
+		jse.emitToFile("])")
+		return
+	}
+	if array, ok := jse.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Array); ok {
+		// Elements missing from the literal are zero
+		for i := int64(len(node.Elts)); i < array.Len(); i++ {
+			if i > 0 {
+				jse.emitToFile(", ")
+			}
+			jse.emitDefaultValue(array.Elem())
+		}
+		jse.emitToFile("]")
+		if jsTypedArray(array) != "" {
+			jse.emitToFile(")")
+		}
+		return
+	}
+	if _, ok := jse.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Slice); ok {
+		jse.emitToFile("])")
+		return
+	}
 	if node.Type != nil {
 		switch node.Type.(type) {
-		case *ast.Ident, *ast.SelectorExpr:
-			// Check if this named type is actually a slice type alias
+		case *ast.Ident, *ast.SelectorExpr, *ast.IndexListExpr:
 			if jse.pkg != nil && jse.pkg.TypesInfo != nil {
 				if typeAndValue, ok := jse.pkg.TypesInfo.Types[node]; ok {
 					underlying := typeAndValue.Type.Underlying()
-					if _, isSlice := underlying.(*types.Slice); isSlice {
-						jse.emitToFile("]")
-						return
-					}
 					// If it's a struct, emit default values for missing fields
 					if structType, isStruct := underlying.(*types.Struct); isStruct {
 						// Collect specified field names
@@ -1349,18 +3860,85 @@ func (jse *JSEmitter) PostVisitCompositeLit(node *ast.CompositeLit, indent int)
 			}
 			// Close struct/object literal
 			jse.emitToFile("}")
+			if jse.structClassName(jse.pkg.TypesInfo.TypeOf(node)) != "" {
+				jse.emitToFile(")")
+			}
 			return
 		}
 	}
 	jse.emitToFile("]")
 }
 
+// insideMapCompositeLit reports whether the innermost composite literal is a map literal
+func (jse *JSEmitter) insideMapCompositeLit() bool {
+	return len(jse.mapCompositeLits) > 0 && jse.mapCompositeLits[len(jse.mapCompositeLits)-1]
+}
+
+// jsTypedArray names the typed array holding the elements of an array of
+// numbers or BigInts. 64-bit int and uint, which may be BigInts, and int64
+// and uint64 numbers stay plain arrays. Without BigInts, 64-bit int and uint
+// are held by a Float64Array, exact up to 2^53.
+func jsTypedArray(array *types.Array) string {
+	basic, ok := array.Elem().Underlying().(*types.Basic)
+	if !ok {
+		return ""
+	}
+	switch basic.Kind() {
+	case types.Int8:
+		return "Int8Array"
+	case types.Int16:
+		return "Int16Array"
+	case types.Int32:
+		return "Int32Array"
+	case types.Int64:
+		if JSBigInt {
+			return "BigInt64Array"
+		}
+	case types.Int:
+		if IntSize == 32 {
+			return "Int32Array"
+		}
+		if !JSBigInt {
+			return "Float64Array"
+		}
+	case types.Uint8:
+		return "Uint8Array"
+	case types.Uint16:
+		return "Uint16Array"
+	case types.Uint32:
+		return "Uint32Array"
+	case types.Uint64:
+		if JSBigInt {
+			return "BigUint64Array"
+		}
+	case types.Uint:
+		if IntSize == 32 {
+			return "Uint32Array"
+		}
+		if !JSBigInt {
+			return "Float64Array"
+		}
+	case types.Float32:
+		return "Float32Array"
+	case types.Float64:
+		return "Float64Array"
+	}
+	return ""
+}
+
 // emitDefaultValue emits the JavaScript default value for a Go type
 func (jse *JSEmitter) emitDefaultValue(t types.Type) {
+	// The zero value of a type parameter is only known for numeric constraints
+	if param, ok := t.(*types.TypeParam); ok && isNumericConstraint(param) {
+		jse.emitToFile("0")
+		return
+	}
 	switch underlying := t.Underlying().(type) {
 	case *types.Basic:
 		info := underlying.Info()
-		if info&types.IsInteger != 0 || info&types.IsFloat != 0 {
+		if isJSBigInt(underlying) {
+			jse.emitToFile("0n")
+		} else if info&types.IsInteger != 0 || info&types.IsFloat != 0 {
 			jse.emitToFile("0")
 		} else if info&types.IsBoolean != 0 {
 			jse.emitToFile("false")
@@ -1370,9 +3948,21 @@ func (jse *JSEmitter) emitDefaultValue(t types.Type) {
 			jse.emitToFile("null")
 		}
 	case *types.Slice:
-		jse.emitToFile("[]")
+		jse.emitToFile("nilSlice()")
+	case *types.Array:
+		if typed := jsTypedArray(underlying); typed != "" {
+			jse.emitToFile(fmt.Sprintf("new %s(%d)", typed, underlying.Len()))
+			return
+		}
+		jse.emitToFile(fmt.Sprintf("Array.from({length: %d}, () => (", underlying.Len()))
+		jse.emitDefaultValue(underlying.Elem())
+		jse.emitToFile("))")
 	case *types.Struct:
 		// Recursively initialize all struct fields
+		className := jse.structClassName(t)
+		if className != "" {
+			jse.emitToFile("Object.assign(new " + className + "(), ")
+		}
 		jse.emitToFile("{")
 		for i := 0; i < underlying.NumFields(); i++ {
 			if i > 0 {
@@ -1383,6 +3973,11 @@ func (jse *JSEmitter) emitDefaultValue(t types.Type) {
 			jse.emitDefaultValue(field.Type())
 		}
 		jse.emitToFile("}")
+		if className != "" {
+			jse.emitToFile(")")
+		}
+	case *types.Map:
+		jse.emitToFile("new NilMap()")
 	case *types.Pointer:
 		jse.emitToFile("null")
 	default:
@@ -1395,6 +3990,11 @@ func (jse *JSEmitter) PreVisitSelectorExpr(node *ast.SelectorExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	jse.openBigIntConst(node)
+}
+
+func (jse *JSEmitter) PostVisitSelectorExpr(node *ast.SelectorExpr, indent int) {
+	jse.closeBigIntConst(node)
 }
 
 func (jse *JSEmitter) PostVisitSelectorExprX(node ast.Expr, indent int) {
@@ -1520,14 +4120,29 @@ func (jse *JSEmitter) PreVisitDeclStmtValueSpecType(node *ast.ValueSpec, index i
 		// Use type info to detect actual type (handles type aliases)
 		if jse.pkg != nil && jse.pkg.TypesInfo != nil {
 			if typeAndValue, ok := jse.pkg.TypesInfo.Types[node.Type]; ok {
+				if _, isParam := typeAndValue.Type.(*types.TypeParam); isParam {
+					jse.pendingStructInit = true
+					jse.pendingStructType = typeAndValue.Type
+					return
+				}
 				underlying := typeAndValue.Type.Underlying()
 				if _, isSlice := underlying.(*types.Slice); isSlice {
 					jse.pendingSliceInit = true
 					return
 				}
-				if structType, isStruct := underlying.(*types.Struct); isStruct {
+				if _, isMap := underlying.(*types.Map); isMap {
+					jse.pendingMapInit = true
+					return
+				}
+				// Arrays are zeroed element by element, like struct fields
+				if _, isArray := underlying.(*types.Array); isArray {
 					jse.pendingStructInit = true
-					jse.pendingStructType = structType
+					jse.pendingStructType = typeAndValue.Type
+					return
+				}
+				if _, isStruct := underlying.(*types.Struct); isStruct {
+					jse.pendingStructInit = true
+					jse.pendingStructType = typeAndValue.Type
 					return
 				}
 				// Handle basic types (string, int, bool, etc.)
@@ -1567,21 +4182,16 @@ func (jse *JSEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index int
 		return
 	}
 	if jse.pendingSliceInit {
-		jse.emitToFile(" = []")
+		jse.emitToFile(" = nilSlice()")
 		jse.pendingSliceInit = false
+	} else if jse.pendingMapInit {
+		jse.emitToFile(" = new NilMap()")
+		jse.pendingMapInit = false
 	} else if jse.pendingStructInit {
 		if jse.pendingStructType != nil {
 			// Emit full struct with all fields initialized
-			jse.emitToFile(" = {")
-			for i := 0; i < jse.pendingStructType.NumFields(); i++ {
-				if i > 0 {
-					jse.emitToFile(", ")
-				}
-				field := jse.pendingStructType.Field(i)
-				jse.emitToFile(field.Name() + ": ")
-				jse.emitDefaultValue(field.Type())
-			}
-			jse.emitToFile("}")
+			jse.emitToFile(" = ")
+			jse.emitDefaultValue(jse.pendingStructType)
 			jse.pendingStructType = nil
 		} else {
 			jse.emitToFile(" = {}")
@@ -1590,7 +4200,9 @@ func (jse *JSEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index int
 	} else if jse.pendingBasicInit != nil {
 		// Emit default value for basic types (string, int, bool, etc.)
 		info := jse.pendingBasicInit.Info()
-		if info&types.IsInteger != 0 || info&types.IsFloat != 0 {
+		if isJSBigInt(jse.pendingBasicInit) {
+			jse.emitToFile(" = 0n")
+		} else if info&types.IsInteger != 0 || info&types.IsFloat != 0 {
 			jse.emitToFile(" = 0")
 		} else if info&types.IsBoolean != 0 {
 			jse.emitToFile(" = false")
@@ -1602,6 +4214,94 @@ func (jse *JSEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index int
 	jse.emitToFile(";\n")
 }
 
+// jsIntConversions returns the int, uint, int64 and uint64 conversion
+// functions. int and uint truncate to 32 bits only when IntSize is 32, 64-bit
+// ones are BigInts beyond 2^53 unless JSBigInt is off. int64 and uint64
+// convert to BigInts unless JSBigInt is off.
+func jsIntConversions() string {
+	conversions := `function int(v) { return low32(v) | 0; }
+function uint(v) { return low32(v) >>> 0; }`
+	if IntSize == 64 && JSBigInt {
+		conversions = `function int(v) { return typeof v === 'bigint' ? wideInt(v, true) : Math.trunc(low32(v)); }
+function uint(v) { v = typeof v === 'bigint' ? v : Math.trunc(low32(v)); return v >= 0 && typeof v === 'number' ? v : wideInt(BigInt(v), false); }`
+	} else if IntSize == 64 {
+		conversions = `function int(v) { return typeof v === 'bigint' ? Number(BigInt.asIntN(64, v)) : Math.trunc(low32(v)); }
+function uint(v) { return typeof v === 'bigint' ? Number(BigInt.asUintN(64, v)) : Math.trunc(low32(v)); }`
+	}
+	if !JSBigInt {
+		return conversions + `
+function int64(v) { return typeof v === 'string' ? v.charCodeAt(0) : Math.trunc(v); }
+function uint64(v) { return typeof v === 'string' ? v.charCodeAt(0) : Math.trunc(v); }`
+	}
+	return conversions + `
+function int64(v) { return BigInt.asIntN(64, typeof v === 'bigint' ? v : BigInt(Math.trunc(low32(v)))); }
+function uint64(v) { return BigInt.asUintN(64, typeof v === 'bigint' ? v : BigInt(Math.trunc(low32(v)))); }`
+}
+
+// JSBigInt makes int64 and uint64 values BigInts in the generated
+// JavaScript, and 64-bit int and uint values BigInts beyond 2^53, exact over
+// their whole range. Without it they are numbers, which are faster but exact
+// only up to 2^53.
+var JSBigInt = true
+
+// isJSBigInt reports whether the values of type t are BigInts
+func isJSBigInt(t types.Type) bool {
+	if !JSBigInt || t == nil {
+		return false
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && (basic.Kind() == types.Int64 || basic.Kind() == types.Uint64)
+}
+
+// isJSWideInt reports whether the values of type t are 64-bit int or uint
+// values, numbers up to 2^53 and BigInts beyond, which the wide*() runtime
+// functions operate on
+func isJSWideInt(t types.Type) bool {
+	if !JSBigInt || IntSize != 64 || t == nil {
+		return false
+	}
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && (basic.Kind() == types.Int || basic.Kind() == types.Uint)
+}
+
+// bigIntLit returns the BigInt literal of expr, a constant of a BigInt type
+// or a 64-bit int or uint constant beyond 2^53, or "" for other expressions
+func (jse *JSEmitter) bigIntLit(expr ast.Expr) string {
+	tv, ok := jse.pkg.TypesInfo.Types[expr]
+	if !ok || tv.Value == nil {
+		return ""
+	}
+	if isJSWideInt(tv.Type) {
+		if n, exact := constant.Int64Val(constant.ToInt(tv.Value)); exact && n >= -(1<<53-1) && n <= 1<<53-1 {
+			return ""
+		}
+	} else if !isJSBigInt(tv.Type) {
+		return ""
+	}
+	return constant.ToInt(tv.Value).ExactString() + "n"
+}
+
+// openBigIntConst emits expr as a BigInt literal if it is a constant of a
+// BigInt type, and suppresses its operands until closeBigIntConst(expr)
+func (jse *JSEmitter) openBigIntConst(expr ast.Expr) bool {
+	if jse.bigIntConst != nil {
+		return false
+	}
+	lit := jse.bigIntLit(expr)
+	if lit == "" {
+		return false
+	}
+	jse.emitToFile(lit)
+	jse.bigIntConst = expr
+	return true
+}
+
+func (jse *JSEmitter) closeBigIntConst(expr ast.Expr) {
+	if jse.bigIntConst == expr {
+		jse.bigIntConst = nil
+	}
+}
+
 // isBuiltinType returns true if the type name is a Go built-in type
 func isBuiltinType(name string) bool {
 	switch name {
@@ -1620,11 +4320,62 @@ func (jse *JSEmitter) PostVisitDeclStmt(node *ast.DeclStmt, indent int) {
 	}
 }
 
-// Variable declarations
-func (jse *JSEmitter) PreVisitGenDeclVar(node *ast.GenDecl, indent int) {
+// Package-level variables are assigned by a $initVars function in Go's init
+// order. It runs once the package is complete, so that initializers can call
+// functions and methods declared after the variables
+func (jse *JSEmitter) PreVisitGenDeclVars(node []PackageVar, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.initVarsPending = true
+	if jse.inNamespace {
+		jse.emitToFile("$initVars: function() {\n")
+		return
+	}
+	for _, pv := range node {
+		jse.emitToFile("let " + pv.Name.Name + ";\n")
+	}
+	jse.emitToFile("function $initVars() {\n")
+}
+
+func (jse *JSEmitter) PostVisitGenDeclVars(node []PackageVar, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if jse.inNamespace {
+		jse.emitToFile("},\n\n")
+	} else {
+		jse.emitToFile("}\n\n")
+	}
+}
+
+func (jse *JSEmitter) PreVisitGenDeclVar(node PackageVar, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if jse.inNamespace {
+		jse.emitToFile(jse.emitAsString("this."+node.Name.Name+" = ", indent+2))
+	} else {
+		jse.emitToFile(jse.emitAsString(node.Name.Name+" = ", indent+2))
+	}
+}
+
+func (jse *JSEmitter) PreVisitGenDeclVarType(node ast.Expr, indent int) {
+	jse.suppressTypeEmit = true
+}
+
+func (jse *JSEmitter) PostVisitGenDeclVarType(node ast.Expr, indent int) {
+	jse.suppressTypeEmit = false
+}
+
+func (jse *JSEmitter) PostVisitGenDeclVar(node PackageVar, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if node.Value == nil {
+		jse.emitDefaultValue(jse.pkg.TypesInfo.TypeOf(node.Type))
+	}
+	jse.emitToFile(";\n")
 }
 
 func (jse *JSEmitter) PreVisitValueSpec(node *ast.ValueSpec, indent int) {
@@ -1702,7 +4453,7 @@ func (jse *JSEmitter) PostVisitGenDeclConstName(node *ast.Ident, indent int) {
 
 // Parenthesized expressions
 func (jse *JSEmitter) PreVisitParenExpr(node *ast.ParenExpr, indent int) {
-	if jse.forwardDecl {
+	if jse.forwardDecl || jse.openBigIntConst(node) {
 		return
 	}
 	jse.emitToFile("(")
@@ -1713,6 +4464,7 @@ func (jse *JSEmitter) PostVisitParenExpr(node *ast.ParenExpr, indent int) {
 		return
 	}
 	jse.emitToFile(")")
+	jse.closeBigIntConst(node)
 }
 
 // Break and continue
@@ -1721,9 +4473,20 @@ func (jse *JSEmitter) PreVisitBranchStmt(node *ast.BranchStmt, indent int) {
 		return
 	}
 	str := jse.emitAsString(node.Tok.String()+";\n", indent)
+	if node.Label != nil {
+		str = jse.emitAsString(node.Tok.String()+" "+node.Label.Name+";\n", indent)
+	}
 	jse.emitToFile(str)
 }
 
+// JavaScript labels loops and switch statements like Go
+func (jse *JSEmitter) PreVisitLabeledStmt(node *ast.LabeledStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(jse.emitAsString(node.Label.Name+":\n", indent))
+}
+
 // Switch statements
 func (jse *JSEmitter) PreVisitSwitchStmt(node *ast.SwitchStmt, indent int) {
 	if jse.forwardDecl {
@@ -1793,75 +4556,152 @@ func (jse *JSEmitter) PreVisitKeyValueExpr(node *ast.KeyValueExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	// Map literal entries are [key, value] pairs
+	if jse.insideMapCompositeLit() {
+		jse.emitToFile("[")
+	}
 }
 
-func (jse *JSEmitter) PreVisitKeyValueExprValue(node ast.Expr, indent int) {
+func (jse *JSEmitter) PostVisitKeyValueExpr(node *ast.KeyValueExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	jse.emitToFile(": ")
+	if jse.insideMapCompositeLit() {
+		jse.emitToFile("]")
+	}
 }
 
-// Slice expressions
-// Go: a[low:high] => JS: a.slice(low, high)
-// Go: a[low:] => JS: a.slice(low)
-// Go: a[:high] => JS: a.slice(0, high)
-func (jse *JSEmitter) PreVisitSliceExpr(node *ast.SliceExpr, indent int) {
+func (jse *JSEmitter) PreVisitKeyValueExprValue(node ast.Expr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	if jse.insideMapCompositeLit() {
+		jse.emitToFile(", ")
+	} else {
+		jse.emitToFile(": ")
+	}
+	jse.openValueCopy(node, jse.needsStructCopy(node, jse.isKeyValueHolder(node)))
 }
 
-func (jse *JSEmitter) PostVisitSliceExprX(node ast.Expr, indent int) {
+func (jse *JSEmitter) PostVisitKeyValueExprValue(node ast.Expr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	// After the array name, emit .slice(
-	jse.emitToFile(".slice(")
+	jse.closeValueCopy(node, jse.needsStructCopy(node, jse.isKeyValueHolder(node)))
 }
 
-func (jse *JSEmitter) PreVisitSliceExprXBegin(node ast.Expr, indent int) {
-	// Suppress the second X visit - it's for Go's internal slice bounds
-	jse.suppressRangeEmit = true
+// isKeyValueHolder reports whether the value of a key: value element may be
+// changed in place where it is stored. Field and element values can, map
+// values can only be replaced
+func (jse *JSEmitter) isKeyValueHolder(value ast.Expr) bool {
+	if jse.insideMapCompositeLit() {
+		return jse.isMapValueHolder(value)
+	}
+	return true
 }
 
-func (jse *JSEmitter) PostVisitSliceExprXBegin(node ast.Expr, indent int) {
-	jse.suppressRangeEmit = false
+// Map types are emitted as the Map constructor, e.g. make(map[K]V) -> make(Map)
+func (jse *JSEmitter) PreVisitMapType(node *ast.MapType, indent int) {
+	if jse.forwardDecl || jse.suppressTypeEmit {
+		return
+	}
+	jse.emitToFile("Map")
+	jse.suppressTypeEmit = true
+	jse.mapTypeNode = node
+}
+
+func (jse *JSEmitter) PostVisitMapType(node *ast.MapType, indent int) {
+	if node == jse.mapTypeNode {
+		jse.suppressTypeEmit = false
+		jse.mapTypeNode = nil
+	}
+}
+
+// Channel types are emitted as a ChanType holding the zero value of the
+// elements, e.g. make(chan int) -> make(new ChanType(() => (0)))
+func (jse *JSEmitter) PreVisitChanType(node *ast.ChanType, indent int) {
+	if jse.forwardDecl || jse.suppressTypeEmit {
+		return
+	}
+	jse.emitToFile("new ChanType(() => (")
+	jse.emitDefaultValue(jse.pkg.TypesInfo.TypeOf(node.Value))
+	jse.emitToFile("))")
+	jse.suppressTypeEmit = true
+	jse.chanTypeNode = node
+}
+
+func (jse *JSEmitter) PostVisitChanType(node *ast.ChanType, indent int) {
+	if node == jse.chanTypeNode {
+		jse.suppressTypeEmit = false
+		jse.chanTypeNode = nil
+	}
+}
+
+// Slice expressions share the array of a slice and copy an array or a
+// typed array, e.g. a[low:high:max] => sliceExpr(a, low, high, max)
+func (jse *JSEmitter) PreVisitSliceExpr(node *ast.SliceExpr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile("sliceExpr(")
 }
 
 func (jse *JSEmitter) PreVisitSliceExprLow(node ast.Expr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
+	jse.emitToFile(", ")
 	// If Low is nil (like a[:high]), emit 0
 	if node == nil {
 		jse.emitToFile("0")
 	}
+	jse.openSliceBound(node)
 }
 
 func (jse *JSEmitter) PostVisitSliceExprLow(node ast.Expr, indent int) {
+	jse.closeSliceBound(node)
+}
+
+func (jse *JSEmitter) PreVisitSliceExprHigh(node ast.Expr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	// If High is not nil, emit comma before it
+	if node != nil {
+		jse.emitToFile(", ")
+	}
+	jse.openSliceBound(node)
+}
+
+func (jse *JSEmitter) PostVisitSliceExprHigh(node ast.Expr, indent int) {
+	jse.closeSliceBound(node)
+}
+
+func (jse *JSEmitter) PreVisitSliceExprMax(node ast.Expr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	// We'll add comma in PreVisitSliceExprHigh if High is not nil
+	jse.emitToFile(", ")
+	jse.openSliceBound(node)
 }
 
-func (jse *JSEmitter) PreVisitSliceExprXEnd(node ast.Expr, indent int) {
-	// Suppress the third X visit
-	jse.suppressRangeEmit = true
+func (jse *JSEmitter) PostVisitSliceExprMax(node ast.Expr, indent int) {
+	jse.closeSliceBound(node)
 }
 
-func (jse *JSEmitter) PostVisitSliceExprXEnd(node ast.Expr, indent int) {
-	jse.suppressRangeEmit = false
+// Slice bounds are numbers, BigInt bounds are converted
+func (jse *JSEmitter) openSliceBound(node ast.Expr) {
+	if node != nil && isJSBigInt(jse.pkg.TypesInfo.TypeOf(node)) {
+		jse.emitToFile("Number(")
+	}
 }
 
-func (jse *JSEmitter) PreVisitSliceExprHigh(node ast.Expr, indent int) {
+func (jse *JSEmitter) closeSliceBound(node ast.Expr) {
 	if jse.forwardDecl {
 		return
 	}
-	// If High is not nil, emit comma before it
-	if node != nil {
-		jse.emitToFile(", ")
+	if node != nil && isJSBigInt(jse.pkg.TypesInfo.TypeOf(node)) {
+		jse.emitToFile(")")
 	}
 }
 
@@ -1872,23 +4712,35 @@ func (jse *JSEmitter) PostVisitSliceExpr(node *ast.SliceExpr, indent int) {
 	jse.emitToFile(")")
 }
 
-// Type assertions (limited support)
+// Type assertions check the dynamic type at runtime: x.(T) becomes
+// typeAssert(x, check) and the comma-ok form typeAssertOk(x, check, zero)
 func (jse *JSEmitter) PreVisitTypeAssertExpr(node *ast.TypeAssertExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	// In JavaScript, just return the value (dynamic typing)
+	if node == jse.typeAssertCommaOk {
+		jse.emitToFile("typeAssertOk(")
+	} else {
+		jse.emitToFile("typeAssert(")
+	}
 }
 
 func (jse *JSEmitter) PostVisitTypeAssertExpr(node *ast.TypeAssertExpr, indent int) {
 	if jse.forwardDecl {
 		return
 	}
-	// No-op for JavaScript
+	t := jse.pkg.TypesInfo.TypeOf(node.Type)
+	jse.emitToFile(", (v) => " + jse.typeCheck(t, "v"))
+	if node == jse.typeAssertCommaOk {
+		jse.emitToFile(", ")
+		jse.emitDefaultValue(t)
+	}
+	jse.emitToFile(")")
 }
 
+// The asserted type has no JavaScript representation, PostVisitTypeAssertExpr
+// emits its check instead
 func (jse *JSEmitter) PreVisitTypeAssertExprType(node ast.Expr, indent int) {
-	// Suppress type emission - JavaScript has no type assertions
 	jse.suppressTypeEmit = true
 }
 
@@ -1896,12 +4748,146 @@ func (jse *JSEmitter) PostVisitTypeAssertExprType(node ast.Expr, indent int) {
 	jse.suppressTypeEmit = false
 }
 
+// typeCheck returns an expression testing whether the value v has the Go type
+// t. Structs with methods are classes and are told apart by their constructor,
+// other values by their JavaScript representation
+func (jse *JSEmitter) typeCheck(t types.Type, v string) string {
+	if className := jse.structClassName(t); className != "" {
+		return v + " instanceof " + className
+	}
+	switch underlying := t.Underlying().(type) {
+	case *types.Basic:
+		info := underlying.Info()
+		if isJSBigInt(underlying) {
+			return "typeof " + v + " === 'bigint'"
+		} else if info&types.IsInteger != 0 {
+			return "Number.isInteger(" + v + ")"
+		} else if info&types.IsFloat != 0 {
+			return "typeof " + v + " === 'number'"
+		} else if info&types.IsBoolean != 0 {
+			return "typeof " + v + " === 'boolean'"
+		} else if info&types.IsString != 0 {
+			return "typeof " + v + " === 'string'"
+		}
+	case *types.Slice:
+		return v + " instanceof Slice"
+	case *types.Map:
+		return v + " instanceof Map"
+	case *types.Struct:
+		return v + " !== null && typeof " + v + " === 'object' && !Array.isArray(" + v + ") && !(" + v + " instanceof Slice) && !(" + v + " instanceof Map)"
+	case *types.Signature:
+		return "typeof " + v + " === 'function'"
+	}
+	return v + " !== null"
+}
+
+// Type switches become an if-else chain of type checks on a temporary that
+// holds the switch value
+func (jse *JSEmitter) PreVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.typeSwitchCount++
+	jse.typeSwitchNames = append(jse.typeSwitchNames, fmt.Sprintf("_ts%d", jse.typeSwitchCount))
+	jse.emitToFile(jse.emitAsString("{\n", indent))
+}
+
+func (jse *JSEmitter) PostVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.typeSwitchNames = jse.typeSwitchNames[:len(jse.typeSwitchNames)-1]
+	jse.emitToFile("\n" + jse.emitAsString("}\n", indent))
+}
+
+func (jse *JSEmitter) currentTypeSwitch() string {
+	return jse.typeSwitchNames[len(jse.typeSwitchNames)-1]
+}
+
+func (jse *JSEmitter) PreVisitTypeSwitchStmtX(node ast.Expr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(jse.emitAsString("const "+jse.currentTypeSwitch()+" = ", indent+2))
+}
+
+func (jse *JSEmitter) PostVisitTypeSwitchStmtX(node ast.Expr, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(";\n")
+}
+
+func (jse *JSEmitter) PreVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	switch {
+	case index == 0 && node.List == nil:
+		jse.emitToFile(jse.emitAsString("", indent+2))
+	case index == 0:
+		jse.emitToFile(jse.emitAsString("if (", indent+2))
+	case node.List == nil:
+		jse.emitToFile(" else ")
+	default:
+		jse.emitToFile(" else if (")
+	}
+}
+
+func (jse *JSEmitter) PostVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	jse.emitToFile(jse.emitAsString("}", indent+2))
+}
+
+// PreVisitTypeSwitchCaseClauseType emits the check of a case type, the type
+// itself has no JavaScript representation
+func (jse *JSEmitter) PreVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if index > 0 {
+		jse.emitToFile(" || ")
+	}
+	t := jse.pkg.TypesInfo.TypeOf(node)
+	jse.emitToFile(jse.typeCheck(t, jse.currentTypeSwitch()))
+	jse.suppressTypeEmit = true
+}
+
+func (jse *JSEmitter) PostVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {
+	jse.suppressTypeEmit = false
+}
+
+// PreVisitTypeSwitchCaseClauseBody opens the clause and binds the switch
+// variable, values keep their representation so no conversion is needed
+func (jse *JSEmitter) PreVisitTypeSwitchCaseClauseBody(node *ast.CaseClause, index int, indent int) {
+	if jse.forwardDecl {
+		return
+	}
+	if node.List != nil {
+		jse.emitToFile(") ")
+	}
+	jse.emitToFile("{\n")
+	if obj := typeSwitchClauseVar(jse.pkg, node); obj != nil {
+		value := jse.currentTypeSwitch()
+		// A struct changed in place is a copy of the one in the interface
+		if st, ok := obj.Type().Underlying().(*types.Struct); ok && (jse.mutatedVars[obj] || jse.capturedVars[obj]) {
+			value = "copyStruct(" + value + structCopyArgs(st)
+		}
+		jse.emitToFile(jse.emitAsString(fmt.Sprintf("let %s = %s;\n", objectName(jse.renames, obj), value), indent+4))
+	}
+}
+
 // Function literals (closures)
 func (jse *JSEmitter) PreVisitFuncLit(node *ast.FuncLit, indent int) {
 	if jse.forwardDecl {
 		return
 	}
 	jse.inFuncLit = true
+	if jse.blockingLits[node] {
+		jse.emitToFile("async ")
+	}
 	jse.emitToFile("function(")
 }
 
@@ -1921,8 +4907,19 @@ func (jse *JSEmitter) PreVisitFuncLitTypeParam(node *ast.Field, index int, inden
 		if i > 0 {
 			jse.emitToFile(", ")
 		}
-		jse.emitToFile(name.Name)
+		if _, ok := node.Type.(*ast.Ellipsis); ok {
+			jse.emitToFile("...")
+			jse.variadicParam = localName(jse.pkg, jse.renames, name)
+		}
+		jse.emitToFile(localName(jse.pkg, jse.renames, name))
+	}
+}
+
+func (jse *JSEmitter) PreVisitFuncLitBody(node *ast.BlockStmt, indent int) {
+	if jse.forwardDecl {
+		return
 	}
+	jse.deferScopes = pushDeferScope(jse.pkg, jse.deferScopes, node, &jse.deferCount)
 }
 
 func (jse *JSEmitter) PostVisitFuncLitTypeParams(node *ast.FieldList, indent int) {
@@ -1961,6 +4958,9 @@ func (jse *JSEmitter) mapGoTypeToJS(t types.Type) string {
 	}
 	switch underlying := t.Underlying().(type) {
 	case *types.Basic:
+		if isJSBigInt(underlying) {
+			return "bigint"
+		}
 		return jse.getJSType(underlying.Name())
 	case *types.Slice:
 		return "Array"
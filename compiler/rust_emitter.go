@@ -13,7 +13,7 @@ import (
 	"golang.org/x/tools/go/packages"
 )
 
-var rustDestTypes = []string{"i8", "i16", "i32", "i64", "u8", "u16", "Box<dyn Any>", "String", "i32"}
+var rustDestTypes = []string{"i8", "i16", "i32", "i64", "u8", "u16", "Box<dyn Any>", "String"}
 
 var rustTypesMap = map[string]string{
 	"int8":    rustDestTypes[0],
@@ -26,7 +26,6 @@ var rustTypesMap = map[string]string{
 	"uint64":  "u64",
 	"any":     rustDestTypes[6],
 	"string":  rustDestTypes[7],
-	"int":     rustDestTypes[8],
 	"bool":    "bool",
 	"float32": "f32",
 	"float64": "f64",
@@ -54,7 +53,13 @@ type RustEmitter struct {
 	assignmentToken              string
 	forwardDecls                 bool
 	shouldGenerate               bool
-	numFuncResults               int
+	funcTypes                    []funcTypeTokens        // Token positions of the function types being emitted, innermost last
+	nilTests                     map[ast.Expr]string     // Operands of nil comparisons emitted as a test of the left operand
+	skipOperator                 bool                    // The operator of the binary expression was emitted as a test
+	funcValues                   map[ast.Expr]bool       // Names of declared functions used as function values
+	bareFuncLits                 map[*ast.FuncLit]bool   // Function literals passed to the runtime, which takes any callable
+	calledFuncLits               map[*ast.FuncLit]bool   // Function literals called directly
+	boundFuncLits                map[*ast.FuncLit]bool   // Function literals bound to a variable with :=
 	aliases                      map[string]Alias
 	currentPackage               string
 	isArray                      bool
@@ -78,8 +83,11 @@ type RustEmitter struct {
 	compLitTypeNoDefaultStack    []bool                  // Stack to save/restore currentCompLitTypeNoDefault for nested composite literals
 	inFuncParam                  bool                    // Track if we're in function parameter type (for slice -> &[T])
 	currentCallIsAppend          bool                    // Track if current function call is to append (takes ownership)
+	sliceExprs                   []*ast.SliceExpr        // Slice expressions being emitted, innermost last
 	inCallExprArg                bool                    // Track if we're inside a call expression argument (for closure wrapping)
-	closureWrappedInRc           bool                    // Track if current closure was wrapped in Rc::new()
+	closureWrapperEnds           []string                // Closing of the wrapper of each enclosing closure, innermost last
+	closureShouldGenerate        []bool                  // Whether tokens were generated outside each enclosing closure
+	funcLitResultsStart          int                     // Token index where the result types of a closure start
 	currentCompLitType           types.Type              // Track the current composite literal's type for checking at post-visit
 	compLitTypeStack             []types.Type            // Stack of composite literal types
 	processedPkgsInterfaceTypes  map[string]bool         // Cache for package interface{} type checks
@@ -92,6 +100,8 @@ type RustEmitter struct {
 	callExprFunMarkerStack       []int                   // Stack of indices for nested call markers
 	callExprFunEndMarkerStack    []int                   // Stack of end indices for nested call markers
 	callExprArgsMarkerStack      []int                   // Stack of indices for nested call arg markers
+	callExprFunLitStack          []bool                  // Stack of whether nested calls call a function literal
+	appendCalls                  []*ast.CallExpr         // Stack of nested calls, nil entries for calls other than append
 	localClosureAssign           bool                    // Track if current assignment has a function literal RHS
 	localClosures                map[string]*ast.FuncLit // Map of local closure names to their AST
 	localClosureBodyTokens       map[string][]Token      // Map of local closure names to their body tokens
@@ -101,12 +111,16 @@ type RustEmitter struct {
 	localClosureBodyStartIndex   int                     // Token index where closure body starts (after opening brace)
 	localClosureAssignStartIndex int                     // Token index where the assignment statement starts
 	currentCompLitIsSlice        bool                    // Track if current composite literal is a slice type alias
-	binaryNeedsLeftCast          bool                    // Track if left operand of binary expr needs cast to i32
+	callArg                      ast.Expr                // Call argument about to be emitted
+	compLitIsCallArg             bool                    // Current composite literal is passed directly as a call argument
+	compLitIsBoxed               bool                    // Current composite literal is converted to an interface
+	binaryNeedsLeftCast          bool                    // Track if left operand of binary expr needs cast to int
 	binaryNeedsLeftCastStack     []bool                  // Stack for nested binary expressions
 	binaryNeedsRightCast         string                  // Type to cast right operand of binary expr (e.g., "u8")
 	binaryNeedsRightCastStack    []string                // Stack for nested binary expressions
 	inFloatBinaryExpr            bool                    // Track if we're in a binary expr where operands should be float
 	inFloatBinaryExprStack       []bool                  // Stack for nested binary expressions
+	intOps                       []intOp                 // Integer operations of the binary expressions being emitted, innermost last
 	// Key-value range loop support
 	isKeyValueRange              bool
 	rangeKeyName                 string
@@ -115,6 +129,15 @@ type RustEmitter struct {
 	captureRangeExpr             bool
 	suppressRangeEmit            bool
 	rangeStmtIndent              int
+	// Map support
+	isMapRange                   bool       // Current range statement iterates over a map
+	isStringRange                bool       // Current range statement iterates over a string
+	mapRangeValues               bool       // Map range binds only the value (for _, v := range m)
+	mapCompositeLits             []bool     // Stack tracking which composite literals are map literals
+	arrayCompositeLits           []bool     // Stack tracking which composite literals are array literals
+	mapLvalue                    ast.Expr   // Map index expression being assigned to (m[k] = v, m[k]++)
+	mapCommaOkExpr               ast.Expr   // Map index expression of a comma-ok lookup (v, ok := m[k])
+	currentCallIsMapDelete       bool       // Track if current function call is to delete
 	// Liveness analysis for cross-statement clone detection
 	varFutureUses                map[string]bool   // Variables that will be used in later statements from current position
 	currentFuncBody              *ast.BlockStmt    // Current function body being processed
@@ -129,6 +152,77 @@ type RustEmitter struct {
 	loopIncrementVal             string            // Value to increment by
 	inForLoopBody                bool              // Track if current block is the for loop body
 	forLoopBodyDepth             int               // Depth counter to track nested blocks within loop body
+	outerLoopIncrements          []rustLoopIncrement // Increment state of the enclosing for loops
+	continueIncrements           []rustContinue      // Increment a continue of each enclosing loop runs first
+	// Method support
+	currentFuncDecl              *ast.FuncDecl     // Function or method being emitted
+	pendingRecvDecl              string            // Receiver binding to emit at the start of a method body
+	deferScopes                  []deferScope      // Enclosing function bodies, innermost last
+	deferCount                   int               // Number of defer stacks named so far
+	sharedResults                map[types.Object]bool // Named results used by deferred calls, kept in a Shared cell
+	closureCells                 closureCells          // Local variables shared with closures, kept in a Shared cell
+	inlinedClosures              map[*ast.FuncLit]bool // Local closures inlined at their call sites
+	methodRecvElem               ast.Expr          // Slice element that is the receiver of a method call, s[i].M()
+	// Interface support
+	insideInterface              bool                         // Emitting the method signatures of an interface trait
+	currentInterface             string                       // Name of the interface whose trait is being emitted
+	interfaceSigStart            int                          // Token index where the current trait method signature starts
+	interfaceMethods             map[string][]rustTraitMethod // Trait method signatures by interface, see interfaceKey
+	typeAssertCommaOk            ast.Expr                     // Type assertion of a comma-ok assignment (v, ok := x.(T))
+	chanRecvCommaOk              ast.Expr                     // Receive of a comma-ok assignment (v, ok := <-ch)
+	selectCount                  int
+	selects                      []string // Names of the select statements being emitted
+	typeSwitches                 []rustTypeSwitch
+	typeSwitchCount              int
+	interfaceConversions         map[ast.Expr]string          // Arguments and elements converted to an interface, by wrapper constructor
+	// Package-level variable support
+	currentPackageVar            PackageVar             // Package-level variable being declared
+	pkgVarInitCalls              []string               // Calls forcing package variable initialization, run at the start of main
+	pendingPkgVarInit            bool                   // Emit pkgVarInitCalls at the start of the next block (main body)
+	pkgVarWrites                 map[*ast.Ident]bool    // Package variables written by the current statement, bound to a local copy
+	pkgVarWriteStack             [][]*ast.Ident         // Package variables written by each enclosing statement
+	pkgVarIndexed                map[*ast.Ident]bool    // Package variables indexed in place, without cloning the whole value
+	inPackageVarValue            bool                   // Emitting the initializer of a package-level variable
+	// Generics support
+	typeParamBounds              map[*types.TypeParam]map[string]bool // Trait bounds inferred for each type parameter
+	// Labeled loops
+	whileLabels                  map[*ast.ForStmt]string // Labels of for loops lowered to an init statement and a while loop
+	// Pointer support
+	pointerPlaces                map[ast.Expr]bool          // Dereferences assigned to, through borrow_mut
+	pointerSelectors             []string                   // Closing text of the enclosing selectors, "" when X is not a pointer
+	pointerCalls                 map[*ast.CallExpr]bool     // Method calls through a pointer, inside a with_borrow closure
+	newCallStart                 int                        // Token index where the new(T) call being emitted starts
+}
+
+// rustTraitMethod is a method of an interface trait, kept so that structs of
+// any package can forward the trait method to their own method
+type rustTraitMethod struct {
+	signature string
+	name      string
+	args      []string
+}
+
+// rustLoopIncrement is the pending increment of a for loop lowered to a
+// while loop, saved while a nested for loop is emitted
+type rustLoopIncrement struct {
+	pending bool
+	name    string
+	op      string
+	val     string
+	inBody  bool
+	depth   int
+}
+
+// rustContinue is the increment of an enclosing loop lowered to a while loop,
+// which a continue of the loop runs first. Other loops have no increment.
+type rustContinue struct {
+	label     string
+	increment string
+}
+
+// interfaceKey identifies a named interface across packages
+func interfaceKey(named *types.Named) string {
+	return named.Obj().Pkg().Path() + "." + named.Obj().Name()
 }
 
 func (*RustEmitter) lowerToBuiltins(selector string) string {
@@ -145,6 +239,10 @@ func (*RustEmitter) lowerToBuiltins(selector string) string {
 		return "printf"
 	case "len":
 		return "len"
+	case "delete":
+		return "map_delete"
+	case "close":
+		return "chan_close"
 	}
 	return selector
 }
@@ -462,6 +560,16 @@ func (re *RustEmitter) analyzeParamMutations(params []*ast.Field, body *ast.Bloc
 		re.collectMutatedVarsInStmt(stmt, mutatedVars)
 	}
 
+	// Calling a pointer method borrows the receiver mutably
+	ast.Inspect(body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok && isPointerMethodSelector(re.pkg, sel) {
+				re.collectMutatedVarsInExpr(sel.X, mutatedVars)
+			}
+		}
+		return true
+	})
+
 	// Check which parameters are in the mutated set
 	for _, field := range params {
 		for _, name := range field.Names {
@@ -512,6 +620,7 @@ func (re *RustEmitter) GetFile() *os.File {
 
 func (re *RustEmitter) PreVisitProgram(indent int) {
 	re.aliases = make(map[string]Alias)
+	rustTypesMap["int"], rustTypesMap["uint"] = intTypeNames("i32", "u32", "i64", "u64")
 	outputFile := re.Output
 
 	// For Cargo projects, write to src/main.rs instead
@@ -534,6 +643,9 @@ func (re *RustEmitter) PreVisitProgram(indent int) {
 	builtin := `use std::fmt;
 use std::any::Any;
 use std::rc::Rc;
+use std::cell::UnsafeCell;
+use std::collections::HashMap;
+use std::hash::Hash;
 
 // Type aliases (Go-style)
 type Int8 = i8;
@@ -544,6 +656,8 @@ type Uint8 = u8;
 type Uint16 = u16;
 type Uint32 = u32;
 type Uint64 = u64;
+type Int = ` + rustTypesMap["int"] + `;
+type Uint = ` + rustTypesMap["uint"] + `;
 
 // println equivalents - multiple versions for different arg counts
 pub fn println<T: fmt::Display>(val: T) {
@@ -594,15 +708,247 @@ pub fn byte_to_char(b: i8) -> String {
     (b as u8 as char).to_string()
 }
 
-// Go-style append - takes ownership to avoid cloning
-pub fn append<T>(mut vec: Vec<T>, value: T) -> Vec<T> {
-    vec.push(value);
-    vec
+// A Go slice: length elements from offset in an array shared by the slices
+// taken of one another, up to capacity of which append fills in place. Every
+// slice may write the shared array, as in Go, so it is kept in an UnsafeCell.
+// The nil slice has no array.
+pub struct Slice<T> {
+    array: Option<Rc<UnsafeCell<Vec<T>>>>,
+    offset: usize,
+    length: usize,
+    capacity: usize,
+}
+
+// A slice literal: slice![a, b] owns a new array of its elements
+macro_rules! slice {
+    ($($element:expr),* $(,)?) => { Slice::from_vec(vec![$($element),*]) };
+}
+
+impl<T> Slice<T> {
+    // The nil slice
+    pub fn new() -> Self {
+        Slice { array: None, offset: 0, length: 0, capacity: 0 }
+    }
+    // Wraps a vector; unlike From, the element type is taken from the
+    // expected type so closures coerce to dyn Fn
+    pub fn from_vec(elements: Vec<T>) -> Self {
+        let length = elements.len();
+        Slice { array: Some(Rc::new(UnsafeCell::new(elements))), offset: 0, length, capacity: length }
+    }
+
+    pub fn is_nil(&self) -> bool {
+        self.array.is_none()
+    }
+
+    // The elements of the array, none for the nil slice
+    fn elements(&self) -> &mut [T] {
+        match &self.array {
+            Some(array) => unsafe { &mut *array.get() },
+            None => &mut [],
+        }
+    }
+
+    // The array of a sliced array, which its slices share
+    fn as_array<const N: usize>(&self) -> &mut [T; N] {
+        self.elements().try_into().unwrap()
+    }
+
+    // s[low:high:max] shares the array of s
+    fn reslice(&self, low: Int, high: Int, max: Int) -> Self {
+        Slice { array: self.array.clone(), offset: self.offset + low as usize, length: (high - low) as usize, capacity: (max - low) as usize }
+    }
+}
+
+impl<T: Clone + Default> Slice<T> {
+    // make([]T, length)
+    pub fn make(length: Int) -> Self {
+        Slice::make_cap(length, length)
+    }
+
+    // make([]T, length, capacity): zero values, the capacity ones usable by append
+    pub fn make_cap(length: Int, capacity: Int) -> Self {
+        if length < 0 {
+            panic(Box::new("runtime error: makeslice: len out of range".to_string()));
+        }
+        if capacity < length {
+            panic(Box::new("runtime error: makeslice: cap out of range".to_string()));
+        }
+        Slice { array: Some(Rc::new(UnsafeCell::new(vec![T::default(); capacity as usize]))), offset: 0, length: length as usize, capacity: capacity as usize }
+    }
+}
+
+impl<T> From<Vec<T>> for Slice<T> {
+    fn from(elements: Vec<T>) -> Self {
+        Slice::from_vec(elements)
+    }
+}
+
+impl<T> FromIterator<T> for Slice<T> {
+    fn from_iter<I: IntoIterator<Item = T>>(elements: I) -> Self {
+        Slice::from(elements.into_iter().collect::<Vec<T>>())
+    }
+}
+
+// A copy of a slice shares its array
+impl<T> Clone for Slice<T> {
+    fn clone(&self) -> Self {
+        Slice { array: self.array.clone(), offset: self.offset, length: self.length, capacity: self.capacity }
+    }
+}
+
+impl<T> Default for Slice<T> {
+    fn default() -> Self {
+        Slice::new()
+    }
+}
+
+impl<T> std::ops::Deref for Slice<T> {
+    type Target = [T];
+    fn deref(&self) -> &[T] {
+        &self.elements()[self.offset..self.offset + self.length]
+    }
+}
+
+impl<T> std::ops::DerefMut for Slice<T> {
+    fn deref_mut(&mut self) -> &mut [T] {
+        let (offset, length) = (self.offset, self.length);
+        &mut self.elements()[offset..offset + length]
+    }
+}
+
+impl<T: Clone> IntoIterator for Slice<T> {
+    type Item = T;
+    type IntoIter = std::vec::IntoIter<T>;
+    fn into_iter(self) -> Self::IntoIter {
+        self.to_vec().into_iter()
+    }
+}
+
+impl<'a, T> IntoIterator for &'a Slice<T> {
+    type Item = &'a T;
+    type IntoIter = std::slice::Iter<'a, T>;
+    fn into_iter(self) -> Self::IntoIter {
+        self.iter()
+    }
+}
+
+impl<T: PartialEq> PartialEq for Slice<T> {
+    fn eq(&self, other: &Self) -> bool {
+        **self == **other
+    }
+}
+
+impl<T: fmt::Debug> fmt::Debug for Slice<T> {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        f.debug_list().entries(self.iter()).finish()
+    }
+}
+
+// Extends s by n elements, in its array while the capacity allows and
+// otherwise in a new array of twice the capacity
+fn slice_grow<T: Clone + Default>(s: Slice<T>, n: usize) -> Slice<T> {
+    let length = s.length + n;
+    if length <= s.capacity {
+        return Slice { length, ..s };
+    }
+    let capacity = length.max(2 * s.capacity);
+    let mut array = Vec::with_capacity(capacity);
+    array.extend_from_slice(&s);
+    array.resize(capacity, T::default());
+    Slice { array: Some(Rc::new(UnsafeCell::new(array))), offset: 0, length, capacity }
+}
+
+// Go-style append, writes in the array of s while its capacity allows
+pub fn append<T: Clone + Default>(s: Slice<T>, value: T) -> Slice<T> {
+    let mut s = slice_grow(s, 1);
+    let last = s.length - 1;
+    s[last] = value;
+    s
+}
+
+// append(s, a, b) and append(s, t...), t may share the array of s
+pub fn append_many<T: Clone + Default>(s: Slice<T>, values: Slice<T>) -> Slice<T> {
+    let values = values.to_vec();
+    let start = s.length;
+    let mut s = slice_grow(s, values.len());
+    for (i, value) in values.into_iter().enumerate() {
+        s[start + i] = value;
+    }
+    s
+}
+
+pub fn cap<T>(s: Slice<T>) -> Int {
+    s.capacity as Int
+}
+
+// copy(dst, src) copies as many elements as both have and returns how many,
+// src may overlap dst in their shared array
+pub fn copy<T: Clone>(mut dst: Slice<T>, src: Slice<T>) -> Int {
+    let n = dst.length.min(src.length);
+    let values = src[..n].to_vec();
+    dst[..n].clone_from_slice(&values);
+    n as Int
+}
+
+// Bounds of x[low:high:max] checked like Go, limit is the capacity of a slice
+// or the length of a string or array
+fn check_slice_bounds(low: Int, high: Int, max: Int, limit: Int, slice3: bool, limit_name: &str) {
+    if slice3 && (max < 0 || max > limit) {
+        panic(Box::new(format!("runtime error: slice bounds out of range [::{}] with {} {}", max, limit_name, limit)));
+    }
+    if !slice3 && (high < 0 || high > limit) {
+        panic(Box::new(format!("runtime error: slice bounds out of range [:{}] with {} {}", high, limit_name, limit)));
+    }
+    if slice3 && (high < 0 || high > max) {
+        panic(Box::new(format!("runtime error: slice bounds out of range [:{}:{}]", high, max)));
+    }
+    if low < 0 || low > high {
+        panic(Box::new(format!("runtime error: slice bounds out of range [{}:{}{}", low, high, if slice3 { ":]" } else { "]" })));
+    }
+}
+
+// Slice expressions x[low:high] and x[low:]; a slice shares its array, a
+// string is copied. A sliced array is sliced as the Slice holding it.
+pub trait SliceExpr {
+    type Output;
+    fn slice(&self, low: Int, high: Int) -> Self::Output;
+    fn slice_from(&self, low: Int) -> Self::Output;
+}
+
+// Slice expressions x[low:high:max]
+pub trait SliceExpr3 {
+    type Output;
+    fn slice3(&self, low: Int, high: Int, max: Int) -> Self::Output;
+}
+
+impl<T> SliceExpr for Slice<T> {
+    type Output = Slice<T>;
+    fn slice(&self, low: Int, high: Int) -> Slice<T> {
+        check_slice_bounds(low, high, self.capacity as Int, self.capacity as Int, false, "capacity");
+        self.reslice(low, high, self.capacity as Int)
+    }
+    fn slice_from(&self, low: Int) -> Slice<T> {
+        self.slice(low, self.length as Int)
+    }
+}
+
+impl<T> SliceExpr3 for Slice<T> {
+    type Output = Slice<T>;
+    fn slice3(&self, low: Int, high: Int, max: Int) -> Slice<T> {
+        check_slice_bounds(low, high, max, self.capacity as Int, true, "capacity");
+        self.reslice(low, high, max)
+    }
 }
 
-pub fn append_many<T: Clone>(mut vec: Vec<T>, values: &[T]) -> Vec<T> {
-    vec.extend_from_slice(values);
-    vec
+impl SliceExpr for String {
+    type Output = String;
+    fn slice(&self, low: Int, high: Int) -> String {
+        check_slice_bounds(low, high, high, self.len() as Int, false, "length");
+        String::from_utf8_lossy(&self.as_bytes()[low as usize..high as usize]).into_owned()
+    }
+    fn slice_from(&self, low: Int) -> String {
+        self.slice(low, self.len() as Int)
+    }
 }
 
 // Simple string_format using format!
@@ -624,191 +970,1608 @@ pub fn string_format2<T: fmt::Display>(fmt_str: &str, val: T) -> String {
     rust_fmt.replace("{}", &format!("{}", val))
 }
 
-pub fn len<T>(slice: &[T]) -> i32 {
-    slice.len() as i32
+pub fn len<T>(slice: &[T]) -> Int {
+    slice.len() as Int
 }
-`
-	str := re.emitAsString(builtin, indent)
-	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 
-	// Include runtime module if link-runtime is enabled
-	if re.LinkRuntime != "" {
-		runtimeInclude := `
-// Graphics runtime
-mod graphics;
-use graphics::*;
-`
-		re.gir.emitToFileBuffer(runtimeInclude, EmptyVisitMethod)
-	}
+// Count of a Go shift: a negative count panics, a count beyond u32 shifts
+// out every bit just as u32::MAX does
+pub fn shift_count(n: i128) -> u32 {
+    if n < 0 {
+        panic(Box::new("runtime error: negative shift amount".to_string()));
+    }
+    n.min(u32::MAX as i128) as u32
+}
 
-	re.insideForPostCond = false
+// A map value, the default is the nil map. Clones share the entries, as Go
+// maps are references to them
+pub struct Map<K, V>(Option<Rc<std::cell::RefCell<HashMap<K, V>>>>);
+
+impl<K: Hash + Eq, V> Map<K, V> {
+    pub fn new() -> Self {
+        Map(Some(Rc::new(std::cell::RefCell::new(HashMap::new()))))
+    }
+
+    pub fn is_nil(&self) -> bool {
+        self.0.is_none()
+    }
+
+    // Reads borrow the entries for the duration of a closure, a nil map has none
+    pub fn with<R>(&self, f: impl FnOnce(&HashMap<K, V>) -> R) -> R {
+        match &self.0 {
+            Some(m) => f(&m.borrow()),
+            None => f(&HashMap::new()),
+        }
+    }
+
+    pub fn len(&self) -> usize {
+        self.with(|m| m.len())
+    }
+
+    // m[k] = v -> *m.entry(k).or_default() = v, as with a HashMap
+    pub fn entry(&self, key: K) -> MapEntry<'_, K, V> {
+        MapEntry(self, key)
+    }
 }
 
-func (re *RustEmitter) PostVisitProgram(indent int) {
-	emitTokensToFile(re.file, re.gir.tokenSlice)
-	re.file.Close()
+pub struct MapEntry<'a, K, V>(&'a Map<K, V>, K);
 
-	// Generate Cargo project files if link-runtime is enabled
-	if re.LinkRuntime != "" {
-		if err := re.GenerateCargoToml(); err != nil {
-			log.Printf("Warning: %v", err)
-		}
-		if err := re.GenerateGraphicsMod(); err != nil {
-			log.Printf("Warning: %v", err)
-		}
-		if err := re.GenerateBuildRs(); err != nil {
-			log.Printf("Warning: %v", err)
-		}
-	}
+impl<'a, K: Hash + Eq, V: Default> MapEntry<'a, K, V> {
+    pub fn or_default(self) -> std::cell::RefMut<'a, V> {
+        let MapEntry(map, key) = self;
+        match &map.0 {
+            Some(m) => std::cell::RefMut::map(m.borrow_mut(), |m| m.entry(key).or_default()),
+            None => panic(Box::new("assignment to entry in nil map".to_string())),
+        }
+    }
 }
 
-func (re *RustEmitter) PreVisitFuncDeclSignatures(indent int) {
-	re.forwardDecls = true
+impl<K: Hash + Eq, V, const N: usize> From<[(K, V); N]> for Map<K, V> {
+    fn from(entries: [(K, V); N]) -> Self {
+        Map(Some(Rc::new(std::cell::RefCell::new(HashMap::from(entries)))))
+    }
 }
 
-func (re *RustEmitter) PostVisitFuncDeclSignatures(indent int) {
-	re.forwardDecls = false
+impl<K, V> Clone for Map<K, V> {
+    fn clone(&self) -> Self {
+        Map(self.0.clone())
+    }
 }
 
-func (re *RustEmitter) PreVisitFuncDeclName(node *ast.Ident, indent int) {
-	if re.forwardDecls {
-		return
-	}
-	var str string
-	str = re.emitAsString(fmt.Sprintf("pub fn %s", node.Name), 0)
-	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+impl<K, V> Default for Map<K, V> {
+    fn default() -> Self {
+        Map(None)
+    }
 }
 
-func (re *RustEmitter) PreVisitFuncDeclBody(node *ast.BlockStmt, indent int) {
-	// Perform liveness analysis before emitting the function body
-	re.analyzeVariableLiveness(node)
+// Maps are equal when they share their entries or are both nil
+impl<K, V> PartialEq for Map<K, V> {
+    fn eq(&self, other: &Self) -> bool {
+        match (&self.0, &other.0) {
+            (Some(a), Some(b)) => Rc::ptr_eq(a, b),
+            (None, None) => true,
+            _ => false,
+        }
+    }
 }
 
-func (re *RustEmitter) PreVisitBlockStmtList(node ast.Stmt, index int, indent int) {
-	// Update current statement index if this statement is in the function body
-	if re.currentFuncBody != nil {
-		for i, stmt := range re.currentFuncBody.List {
-			if stmt == node {
-				re.currentStmtIndex = i
-				break
-			}
-		}
-	}
+impl<K: fmt::Debug, V: fmt::Debug> fmt::Debug for Map<K, V> {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        match &self.0 {
+            Some(m) => write!(f, "{:?}", m.borrow()),
+            None => write!(f, "{{}}"),
+        }
+    }
 }
 
-func (re *RustEmitter) PreVisitBlockStmt(node *ast.BlockStmt, indent int) {
-	if re.forwardDecls {
-		return
-	}
-	// Track block depth when inside a for loop body that needs increment
-	if re.inForLoopBody {
-		re.forLoopBodyDepth++
-	} else if re.pendingLoopIncrement {
-		// This is the for loop body block
-		re.inForLoopBody = true
-		re.forLoopBodyDepth = 1
-	}
-	re.emitToken("{", LeftBrace, 1)
-	str := re.emitAsString("\n", 0)
-	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+// Go-style map lookup - a missing key yields the zero value
+pub fn map_get<K: Hash + Eq, V: Clone + Default>(m: &Map<K, V>, key: &K) -> V {
+    m.with(|m| m.get(key).cloned().unwrap_or_default())
 }
 
-func (re *RustEmitter) PostVisitBlockStmt(node *ast.BlockStmt, indent int) {
-	if re.forwardDecls {
-		return
-	}
-	// Track block depth and add loop increment only when exiting the loop body block itself
-	if re.inForLoopBody {
-		re.forLoopBodyDepth--
-		if re.forLoopBodyDepth == 0 && re.pendingLoopIncrement && re.loopIncrementVar != "" {
-			// Emit the increment statement at end of loop body
-			incStr := re.emitAsString(re.loopIncrementVar+" "+re.loopIncrementOp+" "+re.loopIncrementVal+";\n", indent)
-			re.gir.emitToFileBuffer(incStr, EmptyVisitMethod)
-			// Clear the flags
-			re.inForLoopBody = false
-			re.pendingLoopIncrement = false
-		}
-	}
-	re.emitToken("}", RightBrace, 1)
-	// Note: removed isArray = false as it interfered with composite literal stack management
+// Go-style comma-ok map lookup: v, ok := m[k]
+pub fn map_get_ok<K: Hash + Eq, V: Clone + Default>(m: &Map<K, V>, key: &K) -> (V, bool) {
+    m.with(|m| match m.get(key) {
+        Some(v) => (v.clone(), true),
+        None => (V::default(), false),
+    })
 }
 
-func (re *RustEmitter) PreVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl, indent int) {
-	if re.forwardDecls {
-		return
-	}
-	// Analyze which parameters are mutated in the function body
-	if node.Type != nil && node.Type.Params != nil {
-		re.analyzeParamMutations(node.Type.Params.List, node.Body)
-	} else {
-		re.mutatedParams = make(map[string]bool)
-	}
-	re.shouldGenerate = true
-	re.inFuncParam = true // Track that we're in function parameters
-	re.emitToken("(", LeftParen, 0)
+// Go-style comma-ok type assertion on interface{} values: v, ok := x.(T)
+pub trait DowncastOk {
+    fn downcast_ok<T: 'static + Clone + Default>(&self) -> (T, bool);
 }
 
-func (re *RustEmitter) PostVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl, indent int) {
-	if re.forwardDecls {
-		return
-	}
-	re.shouldGenerate = false
-	re.inFuncParam = false // Done with function parameters
-	re.emitToken(")", RightParen, 0)
+impl DowncastOk for Box<dyn Any> {
+    fn downcast_ok<T: 'static + Clone + Default>(&self) -> (T, bool) {
+        match self.downcast_ref::<T>() {
+            Some(value) => (value.clone(), true),
+            None => (T::default(), false),
+        }
+    }
+}
 
-	p1 := SearchPointerIndexReverse("@PreVisitFuncDeclSignatureTypeResults", re.gir.pointerAndIndexVec)
-	p2 := SearchPointerIndexReverse("@PostVisitFuncDeclSignatureTypeResults", re.gir.pointerAndIndexVec)
-	if p1 != nil && p2 != nil {
-		results, err := ExtractTokensBetween(p1.Index, p2.Index, re.gir.tokenSlice)
-		if err != nil {
-			fmt.Println("Error extracting results:", err)
-			return
-		}
+pub fn map_delete<K: Hash + Eq, V>(m: &mut Map<K, V>, key: &K) {
+    if let Some(m) = &m.0 {
+        m.borrow_mut().remove(key);
+    }
+}
 
-		re.gir.tokenSlice, err = RewriteTokensBetween(re.gir.tokenSlice, p1.Index, p2.Index, []string{""})
-		if err != nil {
-			fmt.Println("Error rewriting file buffer:", err)
-			return
-		}
-		if strings.TrimSpace(strings.Join(tokensToStrings(results), "")) != "" {
-			re.gir.tokenSlice = append(re.gir.tokenSlice, CreateToken(RustKeyword, " -> "))
-			re.gir.tokenSlice = append(re.gir.tokenSlice, CreateToken(Identifier, strings.Join(tokensToStrings(results), "")))
-		}
-	}
+// Map iteration helpers - keys are visited in sorted order so that every
+// backend agrees. The entries are copied, so the loop body may change the map
+pub fn map_keys<K: Hash + Eq + Ord + Clone, V>(m: &Map<K, V>) -> Vec<K> {
+    let mut keys: Vec<K> = m.with(|m| m.keys().cloned().collect());
+    keys.sort();
+    keys
 }
 
-func (re *RustEmitter) PreVisitIdent(e *ast.Ident, indent int) {
-	if re.forwardDecls {
-		return
-	}
-	if !re.shouldGenerate {
-		return
-	}
-	// Skip emission during key-value range key/value visits
-	if re.suppressRangeEmit {
-		return
-	}
-	// Capture to buffer during range collection expression visit
-	if re.captureRangeExpr {
-		re.rangeCollectionExpr += e.Name
-		return
-	}
-	re.gir.emitToFileBuffer("", "@PreVisitIdent")
+pub fn map_values<K: Hash + Eq + Ord + Clone, V: Clone>(m: &Map<K, V>) -> Vec<V> {
+    let keys = map_keys(m);
+    m.with(|m| keys.iter().map(|k| m[k].clone()).collect())
+}
 
-	var str string
-	name := e.Name
-	name = re.lowerToBuiltins(name)
-	if name == "nil" {
-		// In Go, nil for slices means empty slice - use Vec::new() in Rust
-		// For pointers/interfaces, None would be correct, but Vec::new() is safer
-		// for the common case of slice assignment
-		str = re.emitAsString("Vec::new()", indent)
-	} else {
-		if n, ok := rustTypesMap[name]; ok {
-			str = re.emitAsString(n, indent)
-		} else {
+pub fn map_entries<K: Hash + Eq + Ord + Clone, V: Clone>(m: &Map<K, V>) -> Vec<(K, V)> {
+    let keys = map_keys(m);
+    m.with(|m| keys.into_iter().map(|k| { let v = m[&k].clone(); (k, v) }).collect())
+}
+
+// Byte offsets and runes of a string, as range over a string yields them
+pub fn string_range(s: &str) -> Vec<(Int, i32)> {
+    s.char_indices().map(|(i, c)| (i as Int, c as i32)).collect()
+}
+
+// Deferred calls of a function, run in LIFO order when it returns or unwinds
+pub struct Defers<'a>(Vec<Box<dyn FnOnce() + 'a>>);
+
+impl<'a> Defers<'a> {
+    pub fn new() -> Self {
+        Defers(Vec::new())
+    }
+
+    pub fn push(&mut self, f: Box<dyn FnOnce() + 'a>) {
+        self.0.push(f);
+    }
+}
+
+impl<'a> Defers<'a> {
+    // Runs the deferred calls before a function returns its named results
+    pub fn run(&mut self) {
+        while let Some(f) = self.0.pop() {
+            f();
+        }
+    }
+
+    // Ends a function whose body ran under catch_unwind: a panic runs the
+    // deferred calls, recover() in them stops it and the zero values are returned
+    pub fn finish<R: Default>(self, result: std::thread::Result<R>) -> R {
+        self.finish_or(result, R::default)
+    }
+
+    // Like finish, a recovered panic returns the named results
+    pub fn finish_or<R>(mut self, result: std::thread::Result<R>, results: impl FnOnce() -> R) -> R {
+        let payload = match result {
+            Ok(value) => return value,
+            Err(payload) => payload,
+        };
+        if payload.downcast_ref::<GoPanic>().is_none() {
+            let value: Box<dyn Any> = Box::new(native_panic_message(&payload));
+            PANIC_VALUE.with(|v| *v.borrow_mut() = Some(value));
+        }
+        struct Restore(bool);
+        impl Drop for Restore {
+            fn drop(&mut self) {
+                RECOVERABLE.with(|r| r.set(self.0));
+            }
+        }
+        let _restore = Restore(RECOVERABLE.with(|r| r.replace(true)));
+        while let Some(f) = self.0.pop() {
+            f();
+        }
+        if PANIC_VALUE.with(|v| v.borrow().is_some()) {
+            std::panic::resume_unwind(payload);
+        }
+        results()
+    }
+}
+
+// A named result shared with the deferred calls that use it, accessed like a
+// package-level variable
+pub struct Shared<T>(std::rc::Rc<std::cell::RefCell<T>>);
+
+impl<T> Shared<T> {
+    pub fn new(value: T) -> Self {
+        Shared(std::rc::Rc::new(std::cell::RefCell::new(value)))
+    }
+
+    pub fn with_borrow<R>(&self, f: impl FnOnce(&T) -> R) -> R {
+        f(&self.0.borrow())
+    }
+
+    pub fn set(&self, value: T) {
+        *self.0.borrow_mut() = value;
+    }
+}
+
+impl<T> Clone for Shared<T> {
+    fn clone(&self) -> Self {
+        Shared(self.0.clone())
+    }
+}
+
+// A pointer to a struct, copies of the pointer share the value. Reads borrow
+// the value for the duration of a closure, writes through borrow_mut
+pub struct Ptr<T>(Option<Rc<std::cell::RefCell<T>>>);
+
+impl<T> Ptr<T> {
+    pub fn new(value: T) -> Self {
+        Ptr(Some(Rc::new(std::cell::RefCell::new(value))))
+    }
+
+    pub fn nil() -> Self {
+        Ptr(None)
+    }
+
+    fn cell(&self) -> &std::cell::RefCell<T> {
+        match &self.0 {
+            Some(cell) => cell,
+            None => panic(Box::new("runtime error: invalid memory address or nil pointer dereference".to_string())),
+        }
+    }
+
+    pub fn with_borrow<R>(&self, f: impl FnOnce(&T) -> R) -> R {
+        f(&self.cell().borrow())
+    }
+
+    pub fn with_borrow_mut<R>(&self, f: impl FnOnce(&mut T) -> R) -> R {
+        f(&mut self.cell().borrow_mut())
+    }
+
+    pub fn borrow_mut(&self) -> std::cell::RefMut<'_, T> {
+        self.cell().borrow_mut()
+    }
+}
+
+impl<T> Clone for Ptr<T> {
+    fn clone(&self) -> Self {
+        Ptr(self.0.clone())
+    }
+}
+
+impl<T> Default for Ptr<T> {
+    fn default() -> Self {
+        Ptr(None)
+    }
+}
+
+// Pointers are equal when they point to the same value or are both nil
+impl<T> PartialEq for Ptr<T> {
+    fn eq(&self, other: &Self) -> bool {
+        match (&self.0, &other.0) {
+            (Some(a), Some(b)) => Rc::ptr_eq(a, b),
+            (None, None) => true,
+            _ => false,
+        }
+    }
+}
+
+impl<T> fmt::Debug for Ptr<T> {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        write!(f, "{}", if self.0.is_some() { "&{...}" } else { "<nil>" })
+    }
+}
+
+impl<'a> Drop for Defers<'a> {
+    fn drop(&mut self) {
+        while let Some(f) = self.0.pop() {
+            f();
+        }
+    }
+}
+
+// Go panics unwind with a GoPanic payload holding the message, the value
+// passed to panic() is kept aside because it cannot cross the unwind
+pub struct GoPanic(pub String);
+
+thread_local! {
+    // Value of the panic unwinding the stack, recover() takes it
+    static PANIC_VALUE: std::cell::RefCell<Option<Box<dyn Any>>> = std::cell::RefCell::new(None);
+    // Set while the deferred calls of a panicking function run
+    static RECOVERABLE: std::cell::Cell<bool> = std::cell::Cell::new(false);
+}
+
+pub fn panic(value: Box<dyn Any>) -> ! {
+    let message = panic_message(&value);
+    PANIC_VALUE.with(|v| *v.borrow_mut() = Some(value));
+    std::panic::panic_any(GoPanic(message))
+}
+
+// Function values are Option<Rc<dyn Fn>>, calling a nil one panics like Go
+pub fn func_value<F: ?Sized>(f: &Option<Rc<F>>) -> &Rc<F> {
+    match f {
+        Some(f) => f,
+        None => panic(Box::new("runtime error: invalid memory address or nil pointer dereference".to_string())),
+    }
+}
+
+// A closure bound to a variable becomes a function value through the helper
+// of its arity, which names the dyn Fn type from the closure's signature
+pub fn func0<R, F: Fn() -> R + 'static>(f: F) -> Option<Rc<dyn Fn() -> R>> { Some(Rc::new(f)) }
+pub fn func1<A, R, F: Fn(A) -> R + 'static>(f: F) -> Option<Rc<dyn Fn(A) -> R>> { Some(Rc::new(f)) }
+pub fn func2<A, B, R, F: Fn(A, B) -> R + 'static>(f: F) -> Option<Rc<dyn Fn(A, B) -> R>> { Some(Rc::new(f)) }
+pub fn func3<A, B, C, R, F: Fn(A, B, C) -> R + 'static>(f: F) -> Option<Rc<dyn Fn(A, B, C) -> R>> { Some(Rc::new(f)) }
+pub fn func4<A, B, C, D, R, F: Fn(A, B, C, D) -> R + 'static>(f: F) -> Option<Rc<dyn Fn(A, B, C, D) -> R>> { Some(Rc::new(f)) }
+
+pub fn recover() -> Box<dyn Any> {
+    if RECOVERABLE.with(|r| r.get()) {
+        if let Some(value) = PANIC_VALUE.with(|v| v.borrow_mut().take()) {
+            return value;
+        }
+    }
+    Box::new(())
+}
+
+// Panic values print like Go prints them
+fn panic_message(value: &Box<dyn Any>) -> String {
+    if let Some(s) = value.downcast_ref::<String>() {
+        return s.clone();
+    }
+    if let Some(s) = value.downcast_ref::<&str>() {
+        return s.to_string();
+    }
+    if let Some(b) = value.downcast_ref::<bool>() {
+        return b.to_string();
+    }
+    if let Some(f) = value.downcast_ref::<f64>() {
+        let text = format!("{:+.6e}", f);
+        let (mantissa, exponent) = text.split_once('e').unwrap();
+        let exponent: i32 = exponent.parse().unwrap();
+        return format!("{}e{}{:03}", mantissa, if exponent < 0 { '-' } else { '+' }, exponent.abs());
+    }
+    macro_rules! integer_message {
+        ($($t:ty),*) => {
+            $(if let Some(n) = value.downcast_ref::<$t>() {
+                return n.to_string();
+            })*
+        };
+    }
+    integer_message!(i8, i16, i32, i64, u8, u16, u32, u64, isize, usize);
+    "(panic value)".to_string()
+}
+
+// Panics of the Rust runtime become the panics Go raises for them
+fn native_panic_message(payload: &Box<dyn Any + Send>) -> String {
+    let text = if let Some(s) = payload.downcast_ref::<String>() {
+        s.clone()
+    } else if let Some(s) = payload.downcast_ref::<&str>() {
+        s.to_string()
+    } else {
+        return "(panic value)".to_string();
+    };
+    if let Some(rest) = text.strip_prefix("index out of bounds: the len is ") {
+        if let Some((len, index)) = rest.split_once(" but the index is ") {
+            let index = index.parse::<u64>().unwrap_or(0) as i64;
+            if index < 0 {
+                return format!("runtime error: index out of range [{}]", index);
+            }
+            return format!("runtime error: index out of range [{}] with length {}", index, len);
+        }
+    }
+    if text.starts_with("attempt to divide by zero") || text.starts_with("attempt to calculate the remainder with a divisor of zero") {
+        return "runtime error: integer divide by zero".to_string();
+    }
+    text
+}
+
+// Runs main, an unrecovered panic prints its message and exits with status 2 like Go
+pub fn run_main<F: FnOnce()>(f: F) {
+    std::panic::set_hook(Box::new(|_| {}));
+    if let Err(payload) = std::panic::catch_unwind(std::panic::AssertUnwindSafe(f)) {
+        exit_panic(payload);
+    }
+}
+
+fn exit_panic(payload: Box<dyn Any + Send>) -> ! {
+    let message = match payload.downcast_ref::<GoPanic>() {
+        Some(p) => p.0.clone(),
+        None => native_panic_message(&payload),
+    };
+    use std::io::Write;
+    let _ = std::io::stdout().flush();
+    eprintln!("panic: {}", message);
+    std::process::exit(2);
+}
+
+// A package-level variable, initialized on first access. Goroutines share it,
+// they run one at a time.
+pub struct PackageVar<T> {
+    cell: std::sync::OnceLock<std::cell::RefCell<T>>,
+    init: fn() -> T,
+}
+
+unsafe impl<T> Sync for PackageVar<T> {}
+
+impl<T> PackageVar<T> {
+    pub const fn new(init: fn() -> T) -> Self {
+        PackageVar { cell: std::sync::OnceLock::new(), init }
+    }
+
+    pub fn with<R>(&self, f: impl FnOnce(&std::cell::RefCell<T>) -> R) -> R {
+        f(self.cell.get_or_init(|| std::cell::RefCell::new((self.init)())))
+    }
+
+    pub fn with_borrow<R>(&self, f: impl FnOnce(&T) -> R) -> R {
+        self.with(|cell| f(&cell.borrow()))
+    }
+
+    pub fn with_borrow_mut<R>(&self, f: impl FnOnce(&mut T) -> R) -> R {
+        self.with(|cell| f(&mut cell.borrow_mut()))
+    }
+
+    pub fn set(&self, value: T) {
+        self.with(|cell| *cell.borrow_mut() = value);
+    }
+}
+
+// Goroutines run on threads, one at a time: the running goroutine holds the
+// scheduler lock and releases it only while it waits for a channel. When
+// every goroutine waits, none is left to wake the others.
+struct Scheduler {
+    alive: usize,
+    // Goroutines waiting since the last change, a change wakes all of them
+    waiting: usize,
+    changes: u64,
+}
+
+static SCHEDULER: std::sync::Mutex<Scheduler> = std::sync::Mutex::new(Scheduler { alive: 1, waiting: 0, changes: 0 });
+static CHANGED: std::sync::Condvar = std::sync::Condvar::new();
+
+thread_local! {
+    // The scheduler lock held by the running goroutine
+    static RUNNING: std::cell::RefCell<Option<std::sync::MutexGuard<'static, Scheduler>>> = std::cell::RefCell::new(None);
+}
+
+fn lock_scheduler() -> std::sync::MutexGuard<'static, Scheduler> {
+    SCHEDULER.lock().unwrap_or_else(|e| e.into_inner())
+}
+
+// The main goroutine takes the lock on first use, before any other goroutine starts
+fn with_scheduler<R>(f: impl FnOnce(&mut Scheduler) -> R) -> R {
+    RUNNING.with(|running| {
+        let mut running = running.borrow_mut();
+        let guard = running.get_or_insert_with(lock_scheduler);
+        f(guard)
+    })
+}
+
+fn deadlock() -> ! {
+    use std::io::Write;
+    let _ = std::io::stdout().flush();
+    eprintln!("fatal error: all goroutines are asleep - deadlock!");
+    std::process::exit(2);
+}
+
+// Wakes the goroutines waiting for a channel to change
+fn notify_change() {
+    with_scheduler(|s| {
+        s.changes += 1;
+        s.waiting = 0;
+    });
+    CHANGED.notify_all();
+}
+
+// Waits for a channel to change, letting the other goroutines run
+fn wait_for_change() {
+    let seen = with_scheduler(|s| {
+        s.waiting += 1;
+        if s.waiting == s.alive {
+            deadlock();
+        }
+        s.changes
+    });
+    RUNNING.with(|running| {
+        let mut guard = running.borrow_mut().take().unwrap();
+        while guard.changes == seen {
+            guard = CHANGED.wait(guard).unwrap_or_else(|e| e.into_inner());
+        }
+        *running.borrow_mut() = Some(guard);
+    });
+}
+
+// Operations on a nil channel block forever
+fn block_forever() -> ! {
+    loop {
+        wait_for_change();
+    }
+}
+
+// Only the goroutine holding the scheduler lock runs, so the Rc values a
+// goroutine captures are never used by two threads at once
+struct Goroutine<F>(F);
+
+unsafe impl<F> Send for Goroutine<F> {}
+
+impl<F: FnOnce()> Goroutine<F> {
+    fn run(self) {
+        (self.0)()
+    }
+}
+
+// go f(): runs f in a new goroutine
+pub fn go<F: FnOnce() + 'static>(f: F) {
+    with_scheduler(|s| s.alive += 1);
+    let goroutine = Goroutine(f);
+    std::thread::spawn(move || {
+        RUNNING.with(|running| *running.borrow_mut() = Some(lock_scheduler()));
+        if let Err(payload) = std::panic::catch_unwind(std::panic::AssertUnwindSafe(|| goroutine.run())) {
+            exit_panic(payload);
+        }
+        with_scheduler(|s| {
+            s.alive -= 1;
+            if s.waiting > 0 && s.waiting == s.alive {
+                deadlock();
+            }
+        });
+        RUNNING.with(|running| running.borrow_mut().take());
+    });
+}
+
+pub struct Channel<T> {
+    items: std::collections::VecDeque<T>,
+    capacity: usize,
+    closed: bool,
+    // An unbuffered send waits until its item is received
+    sent: u64,
+    received: u64,
+    // Goroutines waiting to receive, an unbuffered send in a select needs one
+    receivers: usize,
+}
+
+impl<T: Default> Channel<T> {
+    fn can_send(&self) -> bool {
+        self.closed || if self.capacity > 0 { self.items.len() < self.capacity } else { self.receivers > self.items.len() }
+    }
+
+    fn can_recv(&self) -> bool {
+        self.closed || !self.items.is_empty()
+    }
+}
+
+// A channel value, the default is the nil channel. Clones share the channel.
+pub struct Chan<T>(Option<Rc<std::cell::RefCell<Channel<T>>>>);
+
+impl<T: Default> Chan<T> {
+    pub fn new(capacity: i64) -> Self {
+        Chan(Some(Rc::new(std::cell::RefCell::new(Channel {
+            items: std::collections::VecDeque::new(),
+            capacity: capacity as usize,
+            closed: false,
+            sent: 0,
+            received: 0,
+            receivers: 0,
+        }))))
+    }
+
+    fn channel(&self) -> std::cell::RefMut<'_, Channel<T>> {
+        match &self.0 {
+            Some(c) => c.borrow_mut(),
+            None => block_forever(),
+        }
+    }
+
+    fn send(&self, value: T) {
+        let seq = {
+            let mut c = self.channel();
+            if c.closed {
+                panic(Box::new("send on closed channel".to_string()));
+            }
+            c.items.push_back(value);
+            c.sent += 1;
+            c.sent
+        };
+        notify_change();
+        while self.channel().capacity == 0 && self.channel().received < seq {
+            wait_for_change();
+        }
+    }
+
+    // The zero value and false once the channel is closed and drained
+    fn recv(&self) -> (T, bool) {
+        let value = {
+            let mut c = self.channel();
+            match c.items.pop_front() {
+                Some(value) => {
+                    c.received += 1;
+                    value
+                }
+                None => return (T::default(), false),
+            }
+        };
+        notify_change();
+        (value, true)
+    }
+}
+
+impl<T> Clone for Chan<T> {
+    fn clone(&self) -> Self {
+        Chan(self.0.clone())
+    }
+}
+
+impl<T> Default for Chan<T> {
+    fn default() -> Self {
+        Chan(None)
+    }
+}
+
+impl<T> PartialEq for Chan<T> {
+    fn eq(&self, other: &Self) -> bool {
+        match (&self.0, &other.0) {
+            (Some(a), Some(b)) => Rc::ptr_eq(a, b),
+            (None, None) => true,
+            _ => false,
+        }
+    }
+}
+
+impl<T> fmt::Debug for Chan<T> {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        match &self.0 {
+            Some(c) => write!(f, "{:p}", Rc::as_ptr(c)),
+            None => write!(f, "<nil>"),
+        }
+    }
+}
+
+pub fn chan_send<T: Default>(c: &Chan<T>, value: T) {
+    loop {
+        let full = {
+            let c = c.channel();
+            !c.closed && c.capacity > 0 && c.items.len() >= c.capacity
+        };
+        if !full {
+            break;
+        }
+        wait_for_change();
+    }
+    c.send(value);
+}
+
+// Comma-ok receive: v, ok := <-ch
+pub fn chan_recv_ok<T: Default>(c: &Chan<T>) -> (T, bool) {
+    c.channel().receivers += 1;
+    notify_change();
+    while !c.channel().can_recv() {
+        wait_for_change();
+    }
+    c.channel().receivers -= 1;
+    c.recv()
+}
+
+pub fn chan_recv<T: Default>(c: &Chan<T>) -> T {
+    chan_recv_ok(c).0
+}
+
+pub fn chan_close<T: Default>(c: Chan<T>) {
+    let Some(channel) = &c.0 else {
+        panic(Box::new("close of nil channel".to_string()));
+    };
+    if channel.borrow().closed {
+        panic(Box::new("close of closed channel".to_string()));
+    }
+    channel.borrow_mut().closed = true;
+    notify_change();
+}
+
+// A case of a select statement, the cases of a nil channel are never ready
+pub trait SelectCase {
+    fn ready(&self) -> bool;
+    fn commit(&self);
+    fn waiting(&self, _delta: isize) {}
+}
+
+pub struct SelectRecv<T> {
+    chan: Chan<T>,
+    result: std::cell::RefCell<(T, bool)>,
+}
+
+impl<T: Default + Clone> SelectRecv<T> {
+    pub fn value(&self) -> T {
+        self.result.borrow().0.clone()
+    }
+
+    pub fn ok(&self) -> bool {
+        self.result.borrow().1
+    }
+}
+
+impl<T: Default> SelectCase for SelectRecv<T> {
+    fn ready(&self) -> bool {
+        self.chan.0.is_some() && self.chan.channel().can_recv()
+    }
+
+    fn commit(&self) {
+        *self.result.borrow_mut() = self.chan.recv();
+    }
+
+    fn waiting(&self, delta: isize) {
+        if self.chan.0.is_some() {
+            let mut c = self.chan.channel();
+            c.receivers = (c.receivers as isize + delta) as usize;
+        }
+    }
+}
+
+pub struct SelectSend<T> {
+    chan: Chan<T>,
+    value: std::cell::RefCell<Option<T>>,
+}
+
+impl<T: Default> SelectCase for SelectSend<T> {
+    fn ready(&self) -> bool {
+        self.chan.0.is_some() && self.chan.channel().can_send()
+    }
+
+    fn commit(&self) {
+        self.chan.send(self.value.borrow_mut().take().unwrap());
+    }
+}
+
+pub fn select_recv<T: Default>(c: &Chan<T>) -> SelectRecv<T> {
+    SelectRecv { chan: c.clone(), result: std::cell::RefCell::new((T::default(), false)) }
+}
+
+pub fn select_send<T: Default>(c: &Chan<T>, value: T) -> SelectSend<T> {
+    SelectSend { chan: c.clone(), value: std::cell::RefCell::new(Some(value)) }
+}
+
+// Runs the first ready case of a select, waiting for one unless the select
+// has a default. Returns the index of the case, -1 for default.
+pub fn chan_select(cases: &[&dyn SelectCase], has_default: bool) -> i32 {
+    let mut registered = false;
+    loop {
+        for (i, case) in cases.iter().enumerate() {
+            if case.ready() {
+                if registered {
+                    for c in cases {
+                        c.waiting(-1);
+                    }
+                }
+                case.commit();
+                return i as i32;
+            }
+        }
+        if has_default {
+            return -1;
+        }
+        if !registered {
+            registered = true;
+            for c in cases {
+                c.waiting(1);
+            }
+            notify_change();
+        }
+        wait_for_change();
+    }
+}
+`
+	str := re.emitAsString(builtin, indent)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+
+	// Include runtime module if link-runtime is enabled
+	if re.LinkRuntime != "" {
+		runtimeInclude := `
+// Graphics runtime
+mod graphics;
+use graphics::*;
+`
+		re.gir.emitToFileBuffer(runtimeInclude, EmptyVisitMethod)
+	}
+
+	re.insideForPostCond = false
+}
+
+func (re *RustEmitter) PostVisitProgram(indent int) {
+	emitTokensToFile(re.file, re.gir.tokenSlice)
+	re.file.Close()
+
+	// Generate Cargo project files if link-runtime is enabled
+	if re.LinkRuntime != "" {
+		if err := re.GenerateCargoToml(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		if err := re.GenerateGraphicsMod(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		if err := re.GenerateBuildRs(); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+}
+
+func (re *RustEmitter) PreVisitFuncDeclSignatures(indent int) {
+	re.forwardDecls = true
+}
+
+func (re *RustEmitter) PostVisitFuncDeclSignatures(indent int) {
+	re.forwardDecls = false
+}
+
+func (re *RustEmitter) PreVisitFuncDeclName(node *ast.Ident, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	var str string
+	str = re.emitAsString(fmt.Sprintf("pub fn %s", node.Name), 0)
+	if fn, ok := re.pkg.TypesInfo.Defs[node].(*types.Func); ok {
+		str += re.rustTypeParams(fn.Type().(*types.Signature).TypeParams())
+	}
+	if re.insideInterface {
+		// Trait items take the visibility of the trait
+		str = re.emitAsString(fmt.Sprintf("fn %s", node.Name), 0)
+	}
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitFuncDeclBody(node *ast.BlockStmt, indent int) {
+	// Perform liveness analysis before emitting the function body
+	re.analyzeVariableLiveness(node)
+	if !re.forwardDecls {
+		re.deferScopes = pushDeferScope(re.pkg, re.deferScopes, node, &re.deferCount)
+	}
+	// Package-level variables are initialized before main runs
+	if re.currentPackage == "main" && re.currentFuncDecl != nil &&
+		re.currentFuncDecl.Recv == nil && re.currentFuncDecl.Name.Name == "main" {
+		re.pendingPkgVarInit = !re.forwardDecls
+	}
+	// Bind the Go receiver name: pointer receivers borrow self mutably,
+	// value receivers work on their own copy
+	if name := recvName(re.currentFuncDecl); name != "" {
+		if hasPointerRecv(re.currentFuncDecl) {
+			re.pendingRecvDecl = fmt.Sprintf("let %s = self;\n", name)
+		} else {
+			re.pendingRecvDecl = fmt.Sprintf("let mut %s = self.clone();\n", name)
+		}
+	}
+}
+
+// PreVisitFuncDecl opens an impl block for methods, one per method
+func (re *RustEmitter) PreVisitFuncDecl(node *ast.FuncDecl, indent int) {
+	re.currentFuncDecl = node
+	if re.forwardDecls {
+		return
+	}
+	re.inlinedClosures = inlinedClosures(re.pkg, node.Body)
+	re.closureCells = findClosureCells(re.pkg, node, re.inlinedClosures)
+	// A sliced array moves into a Slice its closures share
+	for site, objs := range re.closureCells.sites {
+		var kept []types.Object
+		for _, obj := range objs {
+			if isSlicedArray(re.pkg, obj) {
+				delete(re.closureCells.vars, obj)
+			} else {
+				kept = append(kept, obj)
+			}
+		}
+		re.closureCells.sites[site] = kept
+	}
+	if recv := recvTypeName(node); recv != "" {
+		impl := "impl " + recv
+		// Methods of a generic struct are generic over its type parameters
+		if params := typeParams(re.pkg, node); params.Len() > 0 {
+			impl = fmt.Sprintf("impl%s %s<%s>", re.rustTypeParams(params), recv, strings.Join(typeParamNames(params), ", "))
+		}
+		str := re.emitAsString(impl+" {\n", 0)
+		re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	}
+}
+
+// PreVisitGenInterfaceInfo declares a Go interface as a trait and a value type
+// wrapping a shared trait object. The wrapper dereferences to the trait, so
+// method calls work unchanged, and it is Clone and Default (a nil interface)
+// like other Go values.
+func (re *RustEmitter) PreVisitGenInterfaceInfo(node GenTypeInfo, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if re.interfaceMethods == nil {
+		re.interfaceMethods = make(map[string][]rustTraitMethod)
+	}
+	re.currentInterface = node.Name
+	str := re.emitAsString(fmt.Sprintf("pub trait %sTrait {\n", node.Name), indent+2)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitGenInterfaceInfo(node GenTypeInfo, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	name := node.Name
+	str := "    fn as_any(&self) -> &dyn Any;\n"
+	str += "}\n\n"
+	str += "#[derive(Clone, Default)]\n"
+	str += fmt.Sprintf("pub struct %s(Option<Rc<dyn %sTrait>>);\n\n", name, name)
+	str += fmt.Sprintf("impl %s {\n", name)
+	str += fmt.Sprintf("    pub fn new<T: %sTrait + 'static>(value: T) -> Self {\n", name)
+	str += fmt.Sprintf("        %s(Some(Rc::new(value)))\n", name)
+	str += "    }\n"
+	str += "    pub fn downcast_ref<T: 'static>(&self) -> Option<&T> {\n"
+	str += "        self.0.as_ref()?.as_any().downcast_ref::<T>()\n"
+	str += "    }\n"
+	str += "    pub fn downcast_ok<T: 'static + Clone + Default>(&self) -> (T, bool) {\n"
+	str += "        match self.downcast_ref::<T>() {\n"
+	str += "            Some(value) => (value.clone(), true),\n"
+	str += "            None => (T::default(), false),\n"
+	str += "        }\n"
+	str += "    }\n"
+	str += "}\n\n"
+	str += fmt.Sprintf("impl std::ops::Deref for %s {\n", name)
+	str += fmt.Sprintf("    type Target = dyn %sTrait;\n", name)
+	str += "    fn deref(&self) -> &Self::Target {\n"
+	str += "        self.0.as_deref().expect(\"invalid memory address or nil pointer dereference\")\n"
+	str += "    }\n"
+	str += "}\n\n"
+	str += fmt.Sprintf("impl fmt::Debug for %s {\n", name)
+	str += "    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {\n"
+	str += fmt.Sprintf("        write!(f, \"%s\")\n", name)
+	str += "    }\n"
+	str += "}\n\n"
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.insideInterface = true
+	re.gir.emitToFileBuffer(re.emitAsString("", indent+2), EmptyVisitMethod)
+	re.interfaceSigStart = len(re.gir.tokenSlice)
+}
+
+func (re *RustEmitter) PostVisitGenInterfaceMethod(node *ast.FuncDecl, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.insideInterface = false
+	sigTokens, _ := ExtractTokensBetween(re.interfaceSigStart, len(re.gir.tokenSlice), re.gir.tokenSlice)
+	method := rustTraitMethod{
+		signature: strings.TrimSpace(strings.Join(tokensToStrings(sigTokens), "")),
+		name:      node.Name.Name,
+	}
+	for _, param := range node.Type.Params.List {
+		for _, name := range param.Names {
+			method.args = append(method.args, name.Name)
+		}
+	}
+	obj := re.pkg.Types.Scope().Lookup(re.currentInterface)
+	if obj != nil {
+		key := interfaceKey(obj.Type().(*types.Named))
+		re.interfaceMethods[key] = append(re.interfaceMethods[key], method)
+	}
+	re.gir.emitToFileBuffer(";\n", EmptyVisitMethod)
+}
+
+// PostVisitGenStructInfos implements the interface traits satisfied by the
+// structs of the package, each trait method forwards to the struct method
+func (re *RustEmitter) PostVisitGenStructInfos(node []GenTypeInfo, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	for _, info := range node {
+		if info.Struct == nil {
+			continue
+		}
+		obj := re.pkg.Types.Scope().Lookup(info.Name)
+		if obj == nil || !hasMethods(obj.Type()) {
+			continue
+		}
+		for _, iface := range implementedInterfaces(re.pkg.Types, obj.Type()) {
+			re.gir.emitToFileBuffer(re.traitImpl(info.Name, iface), EmptyVisitMethod)
+		}
+	}
+}
+
+// traitImpl returns the impl of an interface trait for a struct of the current
+// package. Signatures are written in the scope of the interface package, so
+// impls of traits from other packages import that package in a nested scope.
+func (re *RustEmitter) traitImpl(structName string, iface *types.Named) string {
+	ifacePkg := iface.Obj().Pkg()
+	traitName := iface.Obj().Name() + "Trait"
+	str := ""
+	indent := ""
+	if ifacePkg != re.pkg.Types {
+		str += "const _: () = {\n"
+		str += fmt.Sprintf("    use crate::%s::*;\n", ifacePkg.Name())
+		traitName = "crate::" + ifacePkg.Name() + "::" + traitName
+		if re.pkg.Name == "main" {
+			structName = "crate::" + structName
+		} else {
+			structName = "crate::" + re.pkg.Name + "::" + structName
+		}
+		indent = "    "
+	}
+	str += fmt.Sprintf("%simpl %s for %s {\n", indent, traitName, structName)
+	for _, method := range re.interfaceMethods[interfaceKey(iface)] {
+		args := append([]string{"self"}, method.args...)
+		str += fmt.Sprintf("%s    %s {\n", indent, method.signature)
+		str += fmt.Sprintf("%s        Self::%s(%s)\n", indent, method.name, strings.Join(args, ", "))
+		str += fmt.Sprintf("%s    }\n", indent)
+	}
+	str += fmt.Sprintf("%s    fn as_any(&self) -> &dyn Any {\n", indent)
+	str += fmt.Sprintf("%s        self\n", indent)
+	str += fmt.Sprintf("%s    }\n", indent)
+	str += fmt.Sprintf("%s}\n", indent)
+	if ifacePkg != re.pkg.Types {
+		str += "};\n"
+	}
+	return str + "\n"
+}
+
+// qualifiedTypeName returns the path of a named type as seen from the current
+// package. Modules import the crate root, so main package types need no path.
+func (re *RustEmitter) qualifiedTypeName(named *types.Named) string {
+	pkg := named.Obj().Pkg()
+	if pkg == nil || pkg == re.pkg.Types || pkg.Name() == "main" {
+		return named.Obj().Name()
+	}
+	return pkg.Name() + "::" + named.Obj().Name()
+}
+
+// interfaceConversion returns the wrapper constructor that converts expr to the
+// interface type target, or "" when expr needs no conversion
+func (re *RustEmitter) interfaceConversion(target types.Type, expr ast.Expr) string {
+	named, ok := target.(*types.Named)
+	if !ok || !isNonEmptyInterface(named) {
+		return ""
+	}
+	src := re.pkg.TypesInfo.TypeOf(expr)
+	if src == nil || types.IsInterface(src) {
+		return ""
+	}
+	if basic, ok := src.(*types.Basic); ok && basic.Kind() == types.UntypedNil {
+		return ""
+	}
+	return re.qualifiedTypeName(named) + "::new("
+}
+
+// markInterfaceConversion records that expr, an argument, element or result,
+// is implicitly converted to the interface type target
+func (re *RustEmitter) markInterfaceConversion(target types.Type, expr ast.Expr) {
+	if conversion := re.interfaceConversion(target, expr); conversion != "" {
+		if re.interfaceConversions == nil {
+			re.interfaceConversions = make(map[ast.Expr]string)
+		}
+		re.interfaceConversions[expr] = conversion
+	}
+}
+
+// markAnyConversion records that expr, a concrete argument, is boxed into an
+// empty interface parameter
+func (re *RustEmitter) markAnyConversion(target types.Type, expr ast.Expr) {
+	iface, ok := target.Underlying().(*types.Interface)
+	if !ok || !iface.Empty() {
+		return
+	}
+	src := re.pkg.TypesInfo.TypeOf(expr)
+	if src == nil || types.IsInterface(src) {
+		return
+	}
+	if basic, ok := src.(*types.Basic); ok && basic.Kind() == types.UntypedNil {
+		return
+	}
+	if re.interfaceConversions == nil {
+		re.interfaceConversions = make(map[ast.Expr]string)
+	}
+	re.interfaceConversions[expr] = "Box::new("
+}
+
+func (re *RustEmitter) openInterfaceConversion(expr ast.Expr) {
+	if conversion, ok := re.interfaceConversions[expr]; ok {
+		re.gir.emitToFileBuffer(conversion, EmptyVisitMethod)
+	}
+}
+
+func (re *RustEmitter) closeInterfaceConversion(expr ast.Expr) {
+	if _, ok := re.interfaceConversions[expr]; ok {
+		re.gir.emitToFileBuffer(")", EmptyVisitMethod)
+		delete(re.interfaceConversions, expr)
+	}
+}
+
+// PreVisitGenStructMethod skips the method signature, Rust methods are
+// declared in impl blocks next to the function definitions
+func (re *RustEmitter) PreVisitGenStructMethod(node *ast.FuncDecl, indent int) {
+	re.forwardDecls = true
+}
+
+func (re *RustEmitter) PostVisitGenStructMethod(node *ast.FuncDecl, indent int) {
+	re.forwardDecls = false
+}
+
+func (re *RustEmitter) PreVisitBlockStmtList(node ast.Stmt, index int, indent int) {
+	// Update current statement index if this statement is in the function body
+	if re.currentFuncBody != nil {
+		for i, stmt := range re.currentFuncBody.List {
+			if stmt == node {
+				re.currentStmtIndex = i
+				break
+			}
+		}
+	}
+}
+
+func (re *RustEmitter) PreVisitBlockStmt(node *ast.BlockStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	// Track block depth when inside a for loop body that needs increment
+	if re.inForLoopBody {
+		re.forLoopBodyDepth++
+	} else if re.pendingLoopIncrement {
+		// This is the for loop body block
+		re.inForLoopBody = true
+		re.forLoopBodyDepth = 1
+	}
+	re.emitToken("{", LeftBrace, 1)
+	str := re.emitAsString("\n", 0)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	if re.pendingRecvDecl != "" {
+		re.gir.emitToFileBuffer(re.emitAsString(re.pendingRecvDecl, indent+2), EmptyVisitMethod)
+		re.pendingRecvDecl = ""
+	}
+	re.gir.emitToFileBuffer(re.closureCellDecls(node, indent+2, "\n"), EmptyVisitMethod)
+	if re.pendingPkgVarInit {
+		for _, call := range re.pkgVarInitCalls {
+			re.gir.emitToFileBuffer(re.emitAsString(call+"\n", indent+2), EmptyVisitMethod)
+		}
+		re.pendingPkgVarInit = false
+	}
+}
+
+func (re *RustEmitter) PreVisitFuncBodyStmts(node *ast.BlockStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if isDeferScopeBody(re.deferScopes, node) {
+		if isMainBody(re.pkg, re.currentFuncDecl, node) {
+			re.gir.emitToFileBuffer(re.emitAsString("run_main(|| {\n", indent+2), EmptyVisitMethod)
+		}
+		if scope := currentDeferScope(re.deferScopes); scope.stack != "" {
+			str := ""
+			results := enclosingFuncResults(re.pkg, re.currentFuncDecl, node.Lbrace+1)
+			for obj := range deferredResults(re.pkg, node, results) {
+				if re.sharedResults == nil {
+					re.sharedResults = make(map[types.Object]bool)
+				}
+				re.sharedResults[obj] = true
+			}
+			for i, name := range resultNames(results) {
+				if re.sharedResults[results.At(i)] {
+					name = escapeRustKeyword(name)
+					str += re.emitAsString("let "+name+" = Shared::new("+name+");\n", indent+2)
+				}
+			}
+			str += re.emitAsString("let mut "+scope.stack+" = Defers::new();\n", indent+2)
+			// A body whose defers may recover runs under catch_unwind, the
+			// closure returns what the function returns
+			if scope.recovers {
+				str += re.emitAsString("let _r = std::panic::catch_unwind(std::panic::AssertUnwindSafe(|| {\n", indent+2)
+			}
+			re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		}
+	}
+}
+
+func (re *RustEmitter) PostVisitBlockStmt(node *ast.BlockStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	// Track block depth and add loop increment only when exiting the loop body block itself
+	if re.inForLoopBody {
+		re.forLoopBodyDepth--
+		if re.forLoopBodyDepth == 0 && re.pendingLoopIncrement && re.loopIncrementVar != "" {
+			// Emit the increment statement at end of loop body
+			incStr := re.emitAsString(re.loopIncrementVar+" "+re.loopIncrementOp+" "+re.loopIncrementVal+";\n", indent)
+			re.gir.emitToFileBuffer(incStr, EmptyVisitMethod)
+			// Clear the flags
+			re.inForLoopBody = false
+			re.pendingLoopIncrement = false
+		}
+	}
+	if isDeferScopeBody(re.deferScopes, node) {
+		if scope := currentDeferScope(re.deferScopes); scope.recovers {
+			str := re.emitAsString("}));\n", indent+2)
+			// A recovered panic returns the named results as the deferred
+			// calls left them
+			if results := enclosingFuncResults(re.pkg, re.currentFuncDecl, node.Lbrace+1); resultNames(results) != nil {
+				str += re.emitAsString(scope.stack+".finish_or(_r, || "+re.namedResultsValue(results)+")\n", indent+2)
+			} else {
+				str += re.emitAsString(scope.stack+".finish(_r)\n", indent+2)
+			}
+			re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		}
+		if isMainBody(re.pkg, re.currentFuncDecl, node) {
+			re.gir.emitToFileBuffer(re.emitAsString("});\n", indent+2), EmptyVisitMethod)
+		}
+		re.deferScopes = re.deferScopes[:len(re.deferScopes)-1]
+	}
+	re.emitToken("}", RightBrace, 1)
+	// Note: removed isArray = false as it interfered with composite literal stack management
+}
+
+// Deferred calls are pushed as boxed move closures to a guard that runs them
+// when the function returns or unwinds. Arguments are evaluated into locals
+// at the defer statement; local variables the call uses are cloned into the
+// closure, as the guard outlives any borrow of them.
+func (re *RustEmitter) PreVisitDeferStmt(node *ast.DeferStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = true
+	re.gir.emitToFileBuffer(re.emitAsString("{ ", indent), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitDeferStmt(node *ast.DeferStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(" }", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitDeferStmtArg(node DeferArg, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer("let "+node.Name+" = ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitDeferStmtArg(node DeferArg, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(re.valueCopy(node.Value)+"; ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitDeferStmtCall(node *ast.CallExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	str := ""
+	for _, v := range capturedVars(re.pkg, node) {
+		name := escapeRustKeyword(v.Name())
+		str += fmt.Sprintf("let mut %s = %s.clone(); ", name, name)
+	}
+	str += currentDeferStack(re.deferScopes) + ".push(Box::new(move || { "
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitDeferStmtCall(node *ast.CallExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer("; }));", EmptyVisitMethod)
+}
+
+// A goroutine is a move closure like a deferred call: arguments are
+// evaluated into locals at the go statement and the local variables the call
+// uses are cloned into the closure
+func (re *RustEmitter) PreVisitGoStmt(node *ast.GoStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = true
+	re.gir.emitToFileBuffer(re.emitAsString("{ ", indent), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitGoStmt(node *ast.GoStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(" }", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitGoStmtArg(node DeferArg, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer("let "+node.Name+" = ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitGoStmtArg(node DeferArg, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(re.valueCopy(node.Value)+"; ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitGoStmtCall(node *ast.CallExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	str := ""
+	for _, v := range capturedVars(re.pkg, node) {
+		name := escapeRustKeyword(v.Name())
+		str += fmt.Sprintf("let mut %s = %s.clone(); ", name, name)
+	}
+	re.gir.emitToFileBuffer(str+"go(move || { ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitGoStmtCall(node *ast.CallExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer("; });", EmptyVisitMethod)
+}
+
+// valueCopy returns ".clone()" when node names a variable that moving the
+// value out of would leave unusable, as a send or a goroutine argument does
+func (re *RustEmitter) valueCopy(node ast.Expr) string {
+	switch ast.Unparen(node).(type) {
+	case *ast.Ident, *ast.SelectorExpr, *ast.IndexExpr:
+	default:
+		return ""
+	}
+	if !isMovedType(re.pkg.TypesInfo.TypeOf(node)) {
+		return ""
+	}
+	return ".clone()"
+}
+
+// isMovedType reports whether a value of type t is moved rather than copied,
+// and can be cloned
+func isMovedType(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	// Box<dyn Any> can't be cloned
+	if iface, ok := t.Underlying().(*types.Interface); ok && iface.NumMethods() == 0 {
+		return false
+	}
+	if basic, ok := t.Underlying().(*types.Basic); ok && basic.Info()&types.IsString == 0 {
+		return false
+	}
+	return true
+}
+
+func (re *RustEmitter) PreVisitSendStmt(node *ast.SendStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = true
+	re.gir.emitToFileBuffer(re.emitAsString("chan_send(&", indent), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitSendStmtValue(node ast.Expr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitSendStmtValue(node ast.Expr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(re.valueCopy(node), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitSendStmt(node *ast.SendStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(");", EmptyVisitMethod)
+}
+
+// A select evaluates its cases into _selectN_i variables, chan_select runs
+// the first ready one and a match on its index runs the clause
+func (re *RustEmitter) PreVisitSelectStmt(node *ast.SelectStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = true
+	re.selectCount++
+	re.selects = append(re.selects, fmt.Sprintf("_select%d", re.selectCount))
+	re.gir.emitToFileBuffer(re.emitAsString("{\n", indent), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	op := "select_recv(&"
+	if _, ok := node.Comm.(*ast.SendStmt); ok {
+		op = "select_send(&"
+	}
+	name := fmt.Sprintf("%s_%d", re.selects[len(re.selects)-1], index)
+	re.gir.emitToFileBuffer(re.emitAsString("let "+name+" = "+op, indent+2), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitSelectStmtCaseValue(node ast.Expr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitSelectStmtCaseValue(node ast.Expr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(re.valueCopy(node), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitSelectStmtCase(node *ast.CommClause, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(");\n", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitSelectStmtClauses(node *ast.SelectStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	name := re.selects[len(re.selects)-1]
+	var cases []string
+	for _, stmt := range node.Body.List {
+		if stmt.(*ast.CommClause).Comm != nil {
+			cases = append(cases, fmt.Sprintf("&%s_%d", name, len(cases)))
+		}
+	}
+	str := fmt.Sprintf("match chan_select(&[%s], %t) {\n", strings.Join(cases, ", "), selectHasDefault(node))
+	re.gir.emitToFileBuffer(re.emitAsString(str, indent+2), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(re.emitAsString(fmt.Sprintf("%d => {\n", index), indent+2), EmptyVisitMethod)
+	if index < 0 {
+		return
+	}
+	name := fmt.Sprintf("%s_%d", re.selects[len(re.selects)-1], index)
+	_, _, recv := selectCaseOp(node)
+	value, ok := selectCaseNames(re.pkg, nil, node)
+	for _, v := range [][2]string{{value, "value"}, {ok, "ok"}} {
+		if v[0] == "" {
+			continue
+		}
+		decl := ""
+		if recv.Tok == token.DEFINE {
+			decl = "let mut "
+		}
+		str := fmt.Sprintf("%s%s = %s.%s();\n", decl, escapeRustKeyword(v[0]), name, v[1])
+		re.gir.emitToFileBuffer(re.emitAsString(str, indent+4), EmptyVisitMethod)
+	}
+	re.gir.emitToFileBuffer(re.closureCellDecls(node, indent+4, "\n"), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitSelectStmtClause(node *ast.CommClause, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(re.emitAsString("}\n", indent+2), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitSelectStmt(node *ast.SelectStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.selects = re.selects[:len(re.selects)-1]
+	str := re.emitAsString("_ => {}\n", indent+2) + re.emitAsString("}\n", indent+2) + re.emitAsString("}", indent)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	// Analyze which parameters are mutated in the function body
+	if node.Type != nil && node.Type.Params != nil {
+		re.analyzeParamMutations(node.Type.Params.List, node.Body)
+	} else {
+		re.mutatedParams = make(map[string]bool)
+	}
+	re.shouldGenerate = true
+	re.inFuncParam = true // Track that we're in function parameters
+	re.emitToken("(", LeftParen, 0)
+	if node.Recv != nil || re.insideInterface {
+		selfParam := "&self"
+		if hasPointerRecv(node) {
+			selfParam = "&mut self"
+		}
+		if len(node.Type.Params.List) > 0 {
+			selfParam += ", "
+		}
+		re.gir.emitToFileBuffer(selfParam, EmptyVisitMethod)
+	}
+}
+
+func (re *RustEmitter) PostVisitFuncDeclSignatureTypeParams(node *ast.FuncDecl, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = false
+	re.inFuncParam = false // Done with function parameters
+	re.emitToken(")", RightParen, 0)
+
+	p1 := SearchPointerIndexReverse("@PreVisitFuncDeclSignatureTypeResults", re.gir.pointerAndIndexVec)
+	p2 := SearchPointerIndexReverse("@PostVisitFuncDeclSignatureTypeResults", re.gir.pointerAndIndexVec)
+	if p1 != nil && p2 != nil {
+		results, err := ExtractTokensBetween(p1.Index, p2.Index, re.gir.tokenSlice)
+		if err != nil {
+			fmt.Println("Error extracting results:", err)
+			return
+		}
+
+		re.gir.tokenSlice, err = RewriteTokensBetween(re.gir.tokenSlice, p1.Index, p2.Index, []string{""})
+		if err != nil {
+			fmt.Println("Error rewriting file buffer:", err)
+			return
+		}
+		if strings.TrimSpace(strings.Join(tokensToStrings(results), "")) != "" {
+			re.gir.tokenSlice = append(re.gir.tokenSlice, CreateToken(RustKeyword, " -> "))
+			re.gir.tokenSlice = append(re.gir.tokenSlice, CreateToken(Identifier, strings.Join(tokensToStrings(results), "")))
+		}
+	}
+}
+
+func (re *RustEmitter) PreVisitIdent(e *ast.Ident, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if !re.shouldGenerate {
+		return
+	}
+	// Skip emission during key-value range key/value visits
+	if re.suppressRangeEmit {
+		return
+	}
+	// Package-level variables live in thread-local RefCells
+	if name, ok := re.packageVarAccess(e); ok {
+		if re.captureRangeExpr {
+			re.rangeCollectionExpr += name
+			return
+		}
+		re.gir.emitToFileBuffer("", "@PreVisitIdent")
+		re.emitToken(re.emitAsString(name, indent), Identifier, 0)
+		return
+	}
+	// Capture to buffer during range collection expression visit
+	if re.captureRangeExpr {
+		re.rangeCollectionExpr += re.slicedArrayUse(e, e.Name)
+		return
+	}
+	// The nil of a nil test is not emitted
+	if _, ok := re.nilTests[e]; ok && e.Name == "nil" {
+		return
+	}
+	re.gir.emitToFileBuffer("", "@PreVisitIdent")
+
+	var str string
+	name := e.Name
+	name = re.lowerToBuiltins(name)
+	if name == "nil" && isPointerExpr(re.pkg, e) {
+		str = re.emitAsString("Ptr::nil()", indent)
+	} else if name == "nil" && isFuncExpr(re.pkg, e) {
+		str = re.emitAsString("None", indent)
+	} else if name == "nil" && isAnyExpr(re.pkg, e) {
+		// The value recover() returns when there is no panic
+		str = re.emitAsString("Box::new(())", indent)
+	} else if name == "nil" && isMapExpr(re.pkg, e) {
+		str = re.emitAsString("Map::default()", indent)
+	} else if name == "nil" {
+		// The nil slice
+		str = re.emitAsString("Slice::new()", indent)
+	} else {
+		if n, ok := rustTypesMap[name]; ok {
+			str = re.emitAsString(n, indent)
+		} else if re.funcValues[e] {
+			// A function used as a value
+			str = re.emitAsString("Some(Rc::new("+name+"))", indent)
+		} else {
 			// Escape Rust keywords
-			name = escapeRustKeyword(name)
+			name = re.slicedArrayUse(e, escapeRustKeyword(name))
 			str = re.emitAsString(name, indent)
 		}
 	}
@@ -836,10 +2599,15 @@ func (re *RustEmitter) PreVisitCallExprArgs(node []ast.Expr, indent int) {
 			return
 		}
 		funNameStr := strings.Join(tokensToStrings(funName), "")
+		// A function literal called in place is not a builtin, whatever its body calls
+		if len(re.callExprFunLitStack) > 0 && re.callExprFunLitStack[len(re.callExprFunLitStack)-1] {
+			funNameStr = ""
+		}
 		// Track if this is an append call (takes ownership, not reference)
 		if strings.Contains(funNameStr, "append") {
 			re.currentCallIsAppend = true
 		}
+		re.currentCallIsMapDelete = strings.TrimSpace(funNameStr) == "map_delete"
 		// Skip adding & for type conversions
 		if isConversion, _ := re.isTypeConversion(funNameStr); !isConversion {
 			if strings.Contains(funNameStr, "len") {
@@ -860,12 +2628,12 @@ func (re *RustEmitter) isTypeConversion(funName string) (bool, string) {
 		"int16":   "i16",
 		"int32":   "i32",
 		"int64":   "i64",
-		"int":     "i32",
+		"int":     rustTypesMap["int"],
 		"uint8":   "u8",
 		"uint16":  "u16",
 		"uint32":  "u32",
 		"uint64":  "u64",
-		"uint":    "u32",
+		"uint":    rustTypesMap["uint"],
 		"float32": "f32",
 		"float64": "f64",
 		"byte":    "u8",
@@ -904,6 +2672,9 @@ func (re *RustEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {
 		if len(re.callExprArgsMarkerStack) > 0 {
 			re.callExprArgsMarkerStack = re.callExprArgsMarkerStack[:len(re.callExprArgsMarkerStack)-1]
 		}
+		if len(re.callExprFunLitStack) > 0 {
+			re.callExprFunLitStack = re.callExprFunLitStack[:len(re.callExprFunLitStack)-1]
+		}
 	}()
 
 	// Use stack indices for the current call (top of stacks)
@@ -916,6 +2687,15 @@ func (re *RustEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {
 	p2Index := re.callExprFunEndMarkerStack[len(re.callExprFunEndMarkerStack)-1]
 	pArgsIndex := re.callExprArgsMarkerStack[len(re.callExprArgsMarkerStack)-1]
 
+	if call := re.currentAppendCall(); call != nil && appendsMany(call) {
+		if !call.Ellipsis.IsValid() {
+			re.gir.emitToFileBuffer("]", EmptyVisitMethod)
+		}
+		re.emitToken(")", RightParen, 0)
+		re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, p1Index, p2Index, []string{"append_many"})
+		return
+	}
+
 	funName, err := ExtractTokensBetween(p1Index, p2Index, re.gir.tokenSlice)
 	if err == nil {
 		funNameStr := strings.Join(tokensToStrings(funName), "")
@@ -953,6 +2733,72 @@ func (re *RustEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {
 			}
 		}
 
+		// Handle make(chan T) and make(chan T, size) -> Chan::<T>::new(size)
+		if strings.TrimSpace(funNameStr) == "make" && len(node) > 0 && isChanTypeExpr(re.pkg, node[0]) {
+			argTokens, err := ExtractTokensBetween(pArgsIndex, len(re.gir.tokenSlice), re.gir.tokenSlice)
+			if err == nil {
+				argStr := strings.TrimPrefix(strings.TrimSpace(strings.Join(tokensToStrings(argTokens), "")), "(")
+				size := "0"
+				depth := 0
+				for i, c := range argStr {
+					if c == '<' {
+						depth++
+					} else if c == '>' {
+						depth--
+					} else if c == ',' && depth == 0 {
+						size = strings.TrimSpace(argStr[i+1:])
+						argStr = argStr[:i]
+						break
+					}
+				}
+				typeStr := strings.Replace(strings.TrimSpace(argStr), "Chan<", "Chan::<", 1)
+				re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, p1Index, len(re.gir.tokenSlice), []string{typeStr, "::new((", size, ") as i64)"})
+				return
+			}
+		}
+
+		// Handle make([]T, n) -> Slice::<T>::make(n) and
+		// make([]T, n, c) -> Slice::<T>::make_cap(n, c)
+		if strings.TrimSpace(funNameStr) == "make" && len(node) > 1 && isSliceTypeExpr(re.pkg, node[0]) {
+			argTokens, err := ExtractTokensBetween(pArgsIndex, len(re.gir.tokenSlice), re.gir.tokenSlice)
+			if err == nil {
+				args := splitTopLevelArgs(strings.TrimPrefix(strings.TrimSpace(strings.Join(tokensToStrings(argTokens), "")), "("))
+				if len(args) == len(node) {
+					typeStr := strings.Replace(args[0], "Slice<", "Slice::<", 1)
+					newTokens := []string{typeStr, "::make((", args[1], ") as Int)"}
+					if len(args) == 3 {
+						newTokens = []string{typeStr, "::make_cap((", args[1], ") as Int, (", args[2], ") as Int)"}
+					}
+					re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, p1Index, len(re.gir.tokenSlice), newTokens)
+					return
+				}
+			}
+		}
+
+		// Handle make(map[K]V) and make(map[K]V, hint) -> Map::<K, V>::new()
+		// The capacity hint is dropped, it doesn't affect map semantics
+		if strings.TrimSpace(funNameStr) == "make" && len(node) > 0 && isMapTypeExpr(re.pkg, node[0]) {
+			argTokens, err := ExtractTokensBetween(pArgsIndex, len(re.gir.tokenSlice), re.gir.tokenSlice)
+			if err == nil {
+				argStr := strings.TrimPrefix(strings.TrimSpace(strings.Join(tokensToStrings(argTokens), "")), "(")
+				// The map type ends at the first comma outside of angle brackets
+				depth := 0
+				for i, c := range argStr {
+					if c == '<' {
+						depth++
+					} else if c == '>' {
+						depth--
+					} else if c == ',' && depth == 0 {
+						argStr = argStr[:i]
+						break
+					}
+				}
+				typeStr := strings.Replace(strings.TrimSpace(argStr), "Map<", "Map::<", 1)
+				re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, p1Index, len(re.gir.tokenSlice), []string{typeStr, "::new()"})
+				return
+			}
+		}
+
 		// Handle println with 0 args
 		if funNameStr == "println" && len(node) == 0 {
 			re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, p1Index, p2Index, []string{"println0"})
@@ -1026,10 +2872,10 @@ func (re *RustEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {
 			}
 		}
 
-		// Handle len() on String - convert to method syntax: len(str) -> str.len() as i32
+		// Handle len() on String and Map - convert to method syntax: len(str) -> str.len() as i64
 		if funNameStr == "len" && len(node) == 1 {
 			argType := re.pkg.TypesInfo.Types[node[0]]
-			if argType.Type != nil && argType.Type.String() == "string" {
+			if argType.Type != nil && (argType.Type.String() == "string" || isMapExpr(re.pkg, node[0])) {
 				// Extract the argument tokens
 				argTokens, err := ExtractTokensBetween(pArgsIndex, len(re.gir.tokenSlice), re.gir.tokenSlice)
 				if err == nil && len(argTokens) > 0 {
@@ -1040,8 +2886,10 @@ func (re *RustEmitter) PostVisitCallExprArgs(node []ast.Expr, indent int) {
 					}
 					argStr = strings.TrimPrefix(argStr, "&")
 					argStr = strings.TrimSpace(argStr)
-					// Generate: str.len() as i32
-					newTokens := []string{argStr, ".len() as i32"}
+					// len() only borrows, no need to clone the argument
+					argStr = strings.TrimSuffix(argStr, ".clone()")
+					// Generate: str.len() as i64
+					newTokens := []string{argStr, ".len() as " + rustTypesMap["int"]}
 					re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, p1Index, len(re.gir.tokenSlice), newTokens)
 					return // Skip emitting closing paren
 				}
@@ -1099,6 +2947,8 @@ func (re *RustEmitter) PreVisitBasicLit(e *ast.BasicLit, indent int) {
 			} else if len(inner) == 1 {
 				// Single character - use ASCII value
 				numVal = int(inner[0])
+			} else if r, ok := runeLiteralValue(re.pkg, e); ok {
+				numVal = int(r)
 			} else {
 				// Fallback - just emit as is
 				str = re.emitAsString(charVal, 0)
@@ -1132,6 +2982,16 @@ func (re *RustEmitter) PreVisitBasicLit(e *ast.BasicLit, indent int) {
 		}
 		if needsFloatSuffix && !strings.Contains(value, ".") {
 			value = value + ".0"
+		} else if !needsFloatSuffix && tv.Value != nil && (!fitsInt32(tv.Value) || isBoxedIntLit(re.pkg, e)) {
+			// Rust would infer i32 for the literal where no type is given,
+			// or box it as an i32
+			typeName := "int"
+			if basic, ok := tv.Type.Underlying().(*types.Basic); ok && basic.Info()&types.IsUntyped == 0 {
+				typeName = basic.Name()
+			}
+			if rustType, ok := rustTypesMap[typeName]; ok {
+				value = value + rustType
+			}
 		}
 		str = (re.emitAsString(value, 0))
 		re.emitToken(str, NumberLiteral, 0)
@@ -1185,7 +3045,8 @@ func (re *RustEmitter) PostVisitDeclStmtValueSpecType(node *ast.ValueSpec, index
 			if _, isNamed := typeInfo.Type.(*types.Named); !isNamed {
 				// Type is a basic/primitive type - check for alias replacement
 				for aliasName, alias := range re.aliases {
-					if alias.UnderlyingType == typeInfo.Type.Underlying().String() {
+					// Only aliases declared in the current package are in scope
+					if alias.UnderlyingType == typeInfo.Type.Underlying().String() && alias.PackageName == re.pkg.Name+".Api" && namesIdent(node.Type, aliasName) {
 						re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, len(re.gir.tokenSlice), []string{aliasName})
 						break
 					}
@@ -1243,9 +3104,16 @@ func (re *RustEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index in
 	}
 	re.gir.emitToFileBuffer("", "@PostVisitDeclStmtValueSpecNames")
 	var str string
-	if re.isArray {
-		str += " = Vec::new()"
-		re.isArray = false
+	if array, ok := re.pkg.TypesInfo.ObjectOf(node).Type().Underlying().(*types.Array); ok {
+		str += " = " + rustArrayZero(array)
+	} else if isStructPointer(re.pkg.TypesInfo.ObjectOf(node).Type()) {
+		str += " = Ptr::nil()"
+	} else if isFuncExpr(re.pkg, node) {
+		str += " = None"
+	} else if isAnyExpr(re.pkg, node) {
+		str += " = Box::new(())"
+	} else if isSliceExpr(re.pkg, node) {
+		str += " = Slice::new()"
 	} else {
 		// Add default initialization based on type
 		// Primitive numeric types get zero initialization
@@ -1259,6 +3127,8 @@ func (re *RustEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index in
 			str += " = " + defaultVal
 		} else if typeName == "String" {
 			str += " = String::new()"
+		} else if strings.HasPrefix(typeName, "Map<") {
+			str += " = Map::default()"
 		} else if len(typeName) > 0 && !strings.Contains(typeName, "Box<dyn") {
 			// For struct types declared without value (var x StructType), initialize with default
 			// Skip Box<dyn Any> - can't call default() on trait objects
@@ -1273,6 +3143,7 @@ func (re *RustEmitter) PostVisitDeclStmtValueSpecNames(node *ast.Ident, index in
 			}
 		}
 	}
+	re.isArray = false
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 }
 
@@ -1356,6 +3227,10 @@ func (re *RustEmitter) PreVisitPackage(pkg *packages.Package, indent int) {
 	if re.localClosureBodyTokens == nil {
 		re.localClosureBodyTokens = make(map[string][]Token)
 	}
+	re.funcValues = declaredFuncValues(pkg)
+	re.bareFuncLits = make(map[*ast.FuncLit]bool)
+	re.calledFuncLits = make(map[*ast.FuncLit]bool)
+	re.boundFuncLits = make(map[*ast.FuncLit]bool)
 	// Check if package has any interface{} types
 	re.pkgHasInterfaceTypes = re.packageHasInterfaceTypes(pkg)
 	// Cache this package's result
@@ -1410,10 +3285,6 @@ func (re *RustEmitter) typeHasInterfaceFields(t types.Type) bool {
 			if strings.Contains(fieldTypeStr, "interface{}") || strings.Contains(fieldTypeStr, "interface {") {
 				return true
 			}
-			// Check for function fields (Box<dyn Fn> in Rust doesn't implement Default)
-			if strings.Contains(fieldTypeStr, "func(") {
-				return true
-			}
 			// Check nested structs recursively
 			if re.typeHasInterfaceFields(field.Type()) {
 				return true
@@ -1454,6 +3325,10 @@ func (re *RustEmitter) PostVisitFuncDecl(node *ast.FuncDecl, indent int) {
 		return
 	}
 	str := re.emitAsString("\n\n", 0)
+	if node.Recv != nil {
+		// Close the impl block opened in PreVisitFuncDecl
+		str = re.emitAsString("\n}\n\n", 0)
+	}
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 }
 
@@ -1466,9 +3341,11 @@ func (re *RustEmitter) PreVisitGenStructInfo(node GenTypeInfo, indent int) {
 	var str string
 	hasInterfaceFields := re.structHasInterfaceFields(node.Name)
 	hasFunctionFields := re.structHasFunctionFields(node.Name)
-	if hasFunctionFields {
-		// Structs with function fields can derive Clone (Rc implements Clone)
-		// but not Default or Debug (dyn Fn doesn't implement these)
+	if hasFunctionFields && !hasInterfaceFields {
+		// Structs with function fields can derive Clone and Default
+		// (Option<Rc> implements them) but not Debug (dyn Fn doesn't)
+		str = re.emitAsString("#[derive(Clone, Default)]\n", indent+2)
+	} else if hasFunctionFields {
 		str = re.emitAsString("#[derive(Clone)]\n", indent+2)
 	} else if hasInterfaceFields {
 		// Only derive Debug for structs with Any/interface{} fields
@@ -1476,7 +3353,15 @@ func (re *RustEmitter) PreVisitGenStructInfo(node GenTypeInfo, indent int) {
 	} else {
 		// Check if struct only has primitive/Copy types (can derive Copy)
 		canCopy := re.structCanDeriveCopy(node.Name)
-		if canCopy {
+		if rustStructNeedsDefaultImpl(re.pkg, node.Name) {
+			// Default is only derived for arrays of up to 32 elements,
+			// PostVisitGenStructInfo implements it instead
+			if canCopy {
+				str = re.emitAsString("#[derive(Clone, Copy, Debug)]\n", indent+2)
+			} else {
+				str = re.emitAsString("#[derive(Clone, Debug)]\n", indent+2)
+			}
+		} else if canCopy {
 			str = re.emitAsString("#[derive(Default, Clone, Copy, Debug)]\n", indent+2)
 		} else {
 			// Add derive macros for Default (needed for ..Default::default() in struct init)
@@ -1484,7 +3369,11 @@ func (re *RustEmitter) PreVisitGenStructInfo(node GenTypeInfo, indent int) {
 		}
 	}
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
-	str = re.emitAsString(fmt.Sprintf("pub struct %s\n", node.Name), indent+2)
+	name := node.Name
+	if params := structTypeParams(re.pkg, node.Name); params.Len() > 0 {
+		name += "<" + strings.Join(typeParamNames(params), ", ") + ">"
+	}
+	str = re.emitAsString(fmt.Sprintf("pub struct %s\n", name), indent+2)
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	re.emitToken("{", LeftBrace, indent+2)
 	str = re.emitAsString("\n", 0)
@@ -1520,10 +3409,6 @@ func (re *RustEmitter) structHasInterfaceFieldsRecursive(structName string, visi
 										if strings.Contains(typeStr, "interface{}") || strings.Contains(typeStr, "interface {") {
 											return true
 										}
-										// Check for function fields (Box<dyn Fn> in Rust doesn't implement Clone)
-										if strings.Contains(typeStr, "func(") {
-											return true
-										}
 										// Check nested struct fields recursively
 										if named, ok := fieldType.(*types.Named); ok {
 											if _, isStruct := named.Underlying().(*types.Struct); isStruct {
@@ -1573,6 +3458,7 @@ func (re *RustEmitter) structCanDeriveCopy(structName string) bool {
 										typeStr := fieldType.String()
 										// If field is a slice/array, String, or interface{}, can't derive Copy
 										if strings.HasPrefix(typeStr, "[]") ||
+											strings.HasPrefix(typeStr, "map[") ||
 											typeStr == "string" ||
 											strings.Contains(typeStr, "interface") {
 											return false
@@ -1581,6 +3467,20 @@ func (re *RustEmitter) structCanDeriveCopy(structName string) bool {
 										if strings.HasPrefix(typeStr, "func(") {
 											return false
 										}
+										// A type parameter may be instantiated with a non-Copy type
+										if _, isParam := fieldType.(*types.TypeParam); isParam {
+											return false
+										}
+										// Pointers share their value through an Rc
+										if _, isPtr := fieldType.(*types.Pointer); isPtr {
+											return false
+										}
+										if _, isChan := fieldType.Underlying().(*types.Chan); isChan {
+											return false
+										}
+										if array, isArray := fieldType.Underlying().(*types.Array); isArray && !rustArrayIsCopy(array) {
+											return false
+										}
 										// If field is a struct type, can't safely derive Copy
 										// (the nested struct might have non-Copy fields)
 										if named, ok := fieldType.(*types.Named); ok {
@@ -1605,6 +3505,65 @@ func (re *RustEmitter) structCanDeriveCopy(structName string) bool {
 	return false
 }
 
+// rustArrayZero returns the zero value of an array type: a repeat expression
+// for arrays of Copy types, std::array::from_fn for other element types
+func rustArrayZero(array *types.Array) string {
+	switch elem := array.Elem().Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case elem.Info()&types.IsBoolean != 0:
+			return fmt.Sprintf("[false; %d]", array.Len())
+		case elem.Info()&types.IsFloat != 0:
+			return fmt.Sprintf("[0.0; %d]", array.Len())
+		case elem.Info()&types.IsNumeric != 0:
+			return fmt.Sprintf("[0; %d]", array.Len())
+		}
+	case *types.Array:
+		inner := rustArrayZero(elem)
+		if rustArrayIsCopy(elem) {
+			return fmt.Sprintf("[%s; %d]", inner, array.Len())
+		}
+		return "std::array::from_fn(|_| " + inner + ")"
+	}
+	return "std::array::from_fn(|_| Default::default())"
+}
+
+// rustArrayIsCopy reports whether an array type is Copy in Rust, which holds
+// for arrays of numbers and booleans
+func rustArrayIsCopy(array *types.Array) bool {
+	switch elem := array.Elem().Underlying().(type) {
+	case *types.Basic:
+		return elem.Info()&(types.IsNumeric|types.IsBoolean) != 0
+	case *types.Array:
+		return rustArrayIsCopy(elem)
+	}
+	return false
+}
+
+// rustHoldsFuncValues reports whether values of t hold function values, which
+// implement Clone and Default but not Debug or Copy
+func rustHoldsFuncValues(t types.Type, visited map[types.Type]bool) bool {
+	if visited[t] {
+		return false
+	}
+	visited[t] = true
+	switch u := t.Underlying().(type) {
+	case *types.Signature:
+		return true
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if rustHoldsFuncValues(u.Field(i).Type(), visited) {
+				return true
+			}
+		}
+	case *types.Slice:
+		return rustHoldsFuncValues(u.Elem(), visited)
+	case *types.Array:
+		return rustHoldsFuncValues(u.Elem(), visited)
+	}
+	return false
+}
+
 // structHasFunctionFields checks if a struct has function/closure fields
 func (re *RustEmitter) structHasFunctionFields(structName string) bool {
 	for _, file := range re.pkg.Syntax {
@@ -1617,13 +3576,8 @@ func (re *RustEmitter) structHasFunctionFields(structName string) bool {
 								if structType.Fields != nil {
 									for _, field := range structType.Fields.List {
 										fieldType := re.pkg.TypesInfo.Types[field.Type].Type
-										typeStr := fieldType.String()
-										// Check for function types (will become Box<dyn Fn...>)
-										if strings.HasPrefix(typeStr, "func(") {
-											return true
-										}
-										// Check underlying type for function signatures
-										if _, isSig := fieldType.Underlying().(*types.Signature); isSig {
+										// Function values, directly or in nested structs and slices
+										if rustHoldsFuncValues(fieldType, make(map[types.Type]bool)) {
 											return true
 										}
 									}
@@ -1646,20 +3600,84 @@ func (re *RustEmitter) PostVisitGenStructInfo(node GenTypeInfo, indent int) {
 	re.emitToken("}", RightBrace, indent+2)
 	str := re.emitAsString("\n\n", 0)
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	if rustStructNeedsDefaultImpl(re.pkg, node.Name) {
+		re.gir.emitToFileBuffer(rustStructDefaultImpl(re.pkg, node.Name), EmptyVisitMethod)
+	}
 	re.shouldGenerate = false
 }
 
+// rustStructNeedsDefaultImpl reports whether a non-generic struct has an
+// array field too long for #[derive(Default)]
+func rustStructNeedsDefaultImpl(pkg *packages.Package, name string) bool {
+	obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return false
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return false
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		for t := st.Field(i).Type().Underlying(); ; {
+			array, isArray := t.(*types.Array)
+			if !isArray {
+				break
+			}
+			if array.Len() > 32 {
+				return true
+			}
+			t = array.Elem().Underlying()
+		}
+	}
+	return false
+}
+
+// rustStructDefaultImpl implements Default for a struct, zeroing array
+// fields with rustArrayZero
+func rustStructDefaultImpl(pkg *packages.Package, name string) string {
+	st := pkg.Types.Scope().Lookup(name).Type().Underlying().(*types.Struct)
+	str := fmt.Sprintf("impl Default for %s {\n", name)
+	str += "    fn default() -> Self {\n"
+	str += fmt.Sprintf("        %s {\n", name)
+	for i := 0; i < st.NumFields(); i++ {
+		field := st.Field(i)
+		value := "Default::default()"
+		if array, ok := field.Type().Underlying().(*types.Array); ok {
+			value = rustArrayZero(array)
+		}
+		str += fmt.Sprintf("            %s: %s,\n", escapeRustKeyword(field.Name()), value)
+	}
+	str += "        }\n"
+	str += "    }\n"
+	str += "}\n\n"
+	return str
+}
+
 func (re *RustEmitter) PreVisitArrayType(node ast.ArrayType, indent int) {
 	if re.forwardDecls {
 		return
 	}
+	// A fixed-size array [N]T is a Rust array [T; N]
+	if node.Len != nil {
+		re.gir.emitToFileBuffer("[", EmptyVisitMethod)
+		return
+	}
 	re.gir.emitToFileBuffer("", "@@PreVisitArrayType")
+	re.emitToken("Slice", Identifier, 0)
 	re.emitToken("<", LeftAngle, 0)
 }
 func (re *RustEmitter) PostVisitArrayType(node ast.ArrayType, indent int) {
 	if re.forwardDecls {
 		return
 	}
+	if node.Len != nil {
+		re.gir.emitToFileBuffer(fmt.Sprintf("; %d]", arrayLen(re.pkg, node)), EmptyVisitMethod)
+		return
+	}
 
 	re.emitToken(">", RightAngle, 0)
 
@@ -1668,51 +3686,54 @@ func (re *RustEmitter) PostVisitArrayType(node ast.ArrayType, indent int) {
 		tokens, _ := ExtractTokens(pointerAndPosition.Index, re.gir.tokenSlice)
 		re.isArray = true
 		re.arrayType = strings.Join(tokens, "")
-		// Prepend "Vec" before the array type tokens
-		re.gir.tokenSlice, _ = RewriteTokens(re.gir.tokenSlice, pointerAndPosition.Index, []string{}, []string{"Vec"})
 	}
 }
 
-func (re *RustEmitter) PreVisitFuncType(node *ast.FuncType, indent int) {
+// PreVisitEllipsis emits a variadic parameter ...T as Slice<T>
+func (re *RustEmitter) PreVisitEllipsis(node *ast.Ellipsis, indent int) {
 	if re.forwardDecls {
 		return
 	}
-	re.gir.emitToFileBuffer("", "@@PreVisitFuncType")
-	// Use Rc<dyn Fn> for function types - Rc implements Clone so structs with function fields can be cloned
-	str := re.emitAsString("Rc<dyn Fn(", indent)
-	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	re.emitToken("Slice", Identifier, 0)
+	re.emitToken("<", LeftAngle, 0)
 }
-func (re *RustEmitter) PostVisitFuncType(node *ast.FuncType, indent int) {
+func (re *RustEmitter) PostVisitEllipsis(node *ast.Ellipsis, indent int) {
 	if re.forwardDecls {
 		return
 	}
+	re.emitToken(">", RightAngle, 0)
+}
 
-	pointerAndPosition := SearchPointerIndexReverse("@@PreVisitFuncType", re.gir.pointerAndIndexVec)
-	if pointerAndPosition != nil && re.numFuncResults > 0 {
-		// For function types with return values, we need to reorder tokens
-		// to move return type to the end (Rust syntax requirement)
-		tokens, _ := ExtractTokens(pointerAndPosition.Index, re.gir.tokenSlice)
-		if len(tokens) > 2 {
-			// Find and move return type to end with arrow separator
-			var reorderedTokens []string
-			reorderedTokens = append(reorderedTokens, tokens[0]) // "Rc<dyn Fn("
-			if len(tokens) > 3 {
-				// Skip return type (index 1) and add parameters first
-				reorderedTokens = append(reorderedTokens, tokens[2:]...)
-				reorderedTokens = append(reorderedTokens, ") -> ")
-				reorderedTokens = append(reorderedTokens, tokens[1]) // Add return type at end
-				reorderedTokens = append(reorderedTokens, ">")
-			} else {
-				reorderedTokens = append(reorderedTokens, tokens[1:]...)
-				reorderedTokens = append(reorderedTokens, ")>")
-			}
-			re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, len(re.gir.tokenSlice), reorderedTokens)
-			return
-		}
+func (re *RustEmitter) PreVisitFuncType(node *ast.FuncType, indent int) {
+	if re.forwardDecls {
+		return
 	}
+	re.funcTypes = append(re.funcTypes, funcTypeTokens{start: len(re.gir.tokenSlice)})
+}
 
-	re.emitToken(")", RightParen, 0)
-	re.emitToken(">", RightAngle, 0)
+func (re *RustEmitter) PreVisitFuncTypeParams(node *ast.FieldList, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.funcTypes[len(re.funcTypes)-1].params = len(re.gir.tokenSlice)
+}
+
+// PostVisitFuncType emits the function type as Option<Rc<dyn Fn(params) -> result>>,
+// None is the nil function and Rc lets copies share the closure. Results are
+// visited before parameters
+func (re *RustEmitter) PostVisitFuncType(node *ast.FuncType, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	frame := re.funcTypes[len(re.funcTypes)-1]
+	re.funcTypes = re.funcTypes[:len(re.funcTypes)-1]
+	results, params := frame.split(re.gir.tokenSlice)
+	str := "Option<Rc<dyn Fn(" + params + ")"
+	if results != "" {
+		str += " -> " + results
+	}
+	str += ">>"
+	re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, frame.start, len(re.gir.tokenSlice), []string{re.emitAsString(str, indent)})
 }
 
 func (re *RustEmitter) PreVisitFuncTypeParam(node *ast.Field, index int, indent int) {
@@ -1778,17 +3799,15 @@ func (re *RustEmitter) PostVisitSelectorExprX(node ast.Expr, indent int) {
 		}
 	}
 
-	str = re.emitAsString(scopeOperator, 0)
-	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
-}
-
-func (re *RustEmitter) PreVisitFuncTypeResults(node *ast.FieldList, indent int) {
-	if re.forwardDecls {
-		return
+	if open := re.pointerSelector(); open != "" {
+		scopeOperator = open
 	}
-	if node != nil {
-		re.numFuncResults = len(node.List)
+	if re.captureRangeExpr {
+		re.rangeCollectionExpr += scopeOperator
+		return
 	}
+	str = re.emitAsString(scopeOperator, 0)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 }
 
 func (re *RustEmitter) PreVisitFuncDeclSignatureTypeParamsList(node *ast.Field, index int, indent int) {
@@ -1833,7 +3852,8 @@ func (re *RustEmitter) PostVisitFuncDeclSignatureTypeResultsList(node *ast.Field
 			if _, isNamed := typeInfo.Type.(*types.Named); !isNamed {
 				// Type is a basic/primitive type - check for alias replacement
 				for aliasName, alias := range re.aliases {
-					if alias.UnderlyingType == typeInfo.Type.Underlying().String() {
+					// Only aliases declared in the current package are in scope
+					if alias.UnderlyingType == typeInfo.Type.Underlying().String() && alias.PackageName == re.pkg.Name+".Api" && namesIdent(node.Type, aliasName) {
 						re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, len(re.gir.tokenSlice), []string{aliasName})
 						break
 					}
@@ -1929,7 +3949,20 @@ func (re *RustEmitter) PreVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 	re.shouldGenerate = true
 	re.inReturnStmt = true
 	str := re.emitAsString("return ", indent)
+	// Named results are assigned first, deferred calls may change them
+	if re.deferredReturnResults(node) != nil {
+		str = re.emitAsString("return { let _results = ", indent)
+	}
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	if results := enclosingFuncResults(re.pkg, re.currentFuncDecl, node.Pos()); results != nil && results.Len() == len(node.Results) {
+		for i, result := range node.Results {
+			re.markInterfaceConversion(results.At(i).Type(), result)
+			// Closures returning any box their results already
+			if !re.currentFuncReturnsAny {
+				re.markAnyConversion(results.At(i).Type(), result)
+			}
+		}
+	}
 
 	if len(node.Results) > 1 {
 		re.inMultiValueReturn = true
@@ -1948,9 +3981,172 @@ func (re *RustEmitter) PostVisitReturnStmt(node *ast.ReturnStmt, indent int) {
 	re.inMultiValueReturn = false
 	re.inReturnStmt = false
 	str := re.emitAsString(";", 0)
+	if results := re.deferredReturnResults(node); results != nil {
+		names := resultNames(results)
+		for i, name := range names {
+			value := "_results"
+			if len(names) > 1 {
+				value = fmt.Sprintf("_results.%d", i)
+			}
+			name = escapeRustKeyword(name)
+			if re.sharedResults[results.At(i)] {
+				str += fmt.Sprintf(" %s.set(%s);", name, value)
+			} else {
+				str += fmt.Sprintf(" %s = %s;", name, value)
+			}
+		}
+		str += " " + currentDeferStack(re.deferScopes) + ".run(); " + re.namedResultsValue(results) + " };"
+	}
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 }
 
+// deferredReturnResults returns the named results of the function a return
+// statement with values leaves, nil unless deferred calls run before it
+func (re *RustEmitter) deferredReturnResults(node *ast.ReturnStmt) *types.Tuple {
+	if currentDeferStack(re.deferScopes) == "" || len(node.Results) == 0 {
+		return nil
+	}
+	results := enclosingFuncResults(re.pkg, re.currentFuncDecl, node.Pos())
+	if resultNames(results) == nil {
+		return nil
+	}
+	return results
+}
+
+// namedResultsValue reads the current values of named results, a tuple of
+// them when there are several
+func (re *RustEmitter) namedResultsValue(results *types.Tuple) string {
+	var values []string
+	for i, name := range resultNames(results) {
+		name = escapeRustKeyword(name)
+		if re.sharedResults[results.At(i)] {
+			values = append(values, name+".with_borrow(|v| v.clone())")
+		} else {
+			values = append(values, name+".clone()")
+		}
+	}
+	if len(values) == 1 {
+		return values[0]
+	}
+	return "(" + strings.Join(values, ", ") + ")"
+}
+
+// deferredResults returns the named results that the deferred calls of a
+// function body use, not counting nested function literals
+func deferredResults(pkg *packages.Package, body *ast.BlockStmt, results *types.Tuple) map[types.Object]bool {
+	named := make(map[types.Object]bool)
+	for i := 0; results != nil && i < results.Len(); i++ {
+		named[results.At(i)] = true
+	}
+	used := make(map[types.Object]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			ast.Inspect(n.Call, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok && named[pkg.TypesInfo.Uses[ident]] {
+					used[pkg.TypesInfo.Uses[ident]] = true
+				}
+				return true
+			})
+			return false
+		}
+		return true
+	})
+	return used
+}
+
+// inlinedClosures returns the local closures of a function body that are
+// inlined at their call sites: closures without parameters or results bound
+// with := outside other function literals, without returns or nested function
+// literals, that are only called directly as statements of the same function
+func inlinedClosures(pkg *packages.Package, body *ast.BlockStmt) map[*ast.FuncLit]bool {
+	inlined := make(map[*ast.FuncLit]bool)
+	if body == nil {
+		return inlined
+	}
+	lits := make(map[types.Object]*ast.FuncLit)
+	var stack []ast.Node
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+		assign, ok := n.(*ast.AssignStmt)
+		if !ok || assign.Tok != token.DEFINE || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+			return true
+		}
+		lit, ok := assign.Rhs[0].(*ast.FuncLit)
+		if !ok || lit.Type.Params.NumFields() > 0 || lit.Type.Results.NumFields() > 0 || hasReturnOrFuncLit(lit.Body) {
+			return true
+		}
+		for _, outer := range stack {
+			if _, ok := outer.(*ast.FuncLit); ok {
+				return true
+			}
+		}
+		if obj := pkg.TypesInfo.Defs[assign.Lhs[0].(*ast.Ident)]; obj != nil {
+			lits[obj] = lit
+		}
+		return true
+	})
+	calledOnly := make(map[types.Object]bool)
+	for obj := range lits {
+		calledOnly[obj] = true
+	}
+	stack = nil
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		stack = append(stack, n)
+		ident, ok := n.(*ast.Ident)
+		if !ok || lits[pkg.TypesInfo.Uses[ident]] == nil {
+			return true
+		}
+		obj := pkg.TypesInfo.Uses[ident]
+		// ident, its call and the statement calling it, outside function literals
+		if len(stack) < 3 {
+			calledOnly[obj] = false
+			return true
+		}
+		call, isCall := stack[len(stack)-2].(*ast.CallExpr)
+		_, isStmt := stack[len(stack)-3].(*ast.ExprStmt)
+		if !isCall || call.Fun != ident || len(call.Args) > 0 || !isStmt {
+			calledOnly[obj] = false
+		}
+		for _, outer := range stack {
+			if _, ok := outer.(*ast.FuncLit); ok {
+				calledOnly[obj] = false
+			}
+		}
+		return true
+	})
+	for obj, lit := range lits {
+		if calledOnly[obj] {
+			inlined[lit] = true
+		}
+	}
+	return inlined
+}
+
+// hasReturnOrFuncLit reports whether a function body has a return statement or
+// a nested function literal, which an inlined body can't hold
+func hasReturnOrFuncLit(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit, *ast.ReturnStmt:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
 func (re *RustEmitter) PreVisitReturnStmtResult(node ast.Expr, index int, indent int) {
 	if index > 0 {
 		str := re.emitAsString(", ", 0)
@@ -1970,12 +4166,14 @@ func (re *RustEmitter) PreVisitReturnStmtResult(node ast.Expr, index int, indent
 			}
 		}
 	}
+	re.openInterfaceConversion(node)
 }
 
 func (re *RustEmitter) PostVisitReturnStmtResult(node ast.Expr, index int, indent int) {
 	if re.forwardDecls {
 		return
 	}
+	defer re.closeInterfaceConversion(node)
 	// Add .clone() to the first result in a multi-value return if it's an identifier
 	// This prevents "borrow of moved value" errors when subsequent results reference fields
 	if re.inMultiValueReturn && index == 0 {
@@ -1998,6 +4196,73 @@ func (re *RustEmitter) PostVisitReturnStmtResult(node ast.Expr, index int, inden
 
 func (re *RustEmitter) PreVisitCallExpr(node *ast.CallExpr, indent int) {
 	re.shouldGenerate = true
+	// A method called through a pointer is called inside a closure borrowing the value
+	if sel, ok := node.Fun.(*ast.SelectorExpr); ok && isMethodSelector(re.pkg, sel) && isPointerExpr(re.pkg, sel.X) && !isPointerRecvExpr(re.pkg, re.currentFuncDecl, sel.X) {
+		if re.pointerCalls == nil {
+			re.pointerCalls = make(map[*ast.CallExpr]bool)
+		}
+		re.pointerCalls[node] = true
+	}
+	// Closures called directly, or passed to the runtime which takes any
+	// callable, stay bare closures
+	if funcLit, ok := node.Fun.(*ast.FuncLit); ok {
+		re.calledFuncLits[funcLit] = true
+	}
+	if !re.takesFuncValues(node) {
+		for _, arg := range node.Args {
+			switch arg := arg.(type) {
+			case *ast.FuncLit:
+				re.bareFuncLits[arg] = true
+			case *ast.Ident:
+				delete(re.funcValues, arg)
+			}
+		}
+	}
+	// new(T) allocates the zero value, PostVisitCallExpr rewrites the call
+	if isBuiltinCall(re.pkg, node, "new") {
+		re.newCallStart = len(re.gir.tokenSlice)
+	}
+	if isBuiltinCall(re.pkg, node, "append") {
+		re.appendCalls = append(re.appendCalls, node)
+	} else {
+		re.appendCalls = append(re.appendCalls, nil)
+	}
+	// Arguments passed as interface parameters or appended to a slice of
+	// interfaces are converted explicitly
+	if ident, ok := node.Fun.(*ast.Ident); ok && ident.Name == "append" && len(node.Args) > 0 && !node.Ellipsis.IsValid() {
+		if slice, ok := re.pkg.TypesInfo.TypeOf(node.Args[0]).Underlying().(*types.Slice); ok {
+			for _, arg := range node.Args[1:] {
+				re.markInterfaceConversion(slice.Elem(), arg)
+			}
+		}
+	} else if sig, ok := re.pkg.TypesInfo.TypeOf(node.Fun).(*types.Signature); ok {
+		for i, arg := range node.Args {
+			if i < sig.Params().Len() && !(sig.Variadic() && i >= sig.Params().Len()-1) {
+				re.markInterfaceConversion(sig.Params().At(i).Type(), arg)
+				re.markAnyConversion(sig.Params().At(i).Type(), arg)
+			} else if variadicArgsStart(re.pkg, node) >= 0 && !node.Ellipsis.IsValid() {
+				// Values packed into the Slice of a variadic parameter
+				elem := sig.Params().At(sig.Params().Len() - 1).Type().(*types.Slice).Elem()
+				re.markInterfaceConversion(elem, arg)
+				re.markAnyConversion(elem, arg)
+			}
+		}
+	}
+	// Methods borrow their receiver, so a slice element receiver is not cloned;
+	// pointer methods must mutate the element in place
+	if sel, ok := node.Fun.(*ast.SelectorExpr); ok && isMethodSelector(re.pkg, sel) {
+		recv := sel.X
+		for {
+			inner, ok := recv.(*ast.SelectorExpr)
+			if !ok {
+				break
+			}
+			recv = inner.X
+		}
+		if indexExpr, ok := recv.(*ast.IndexExpr); ok {
+			re.methodRecvElem = indexExpr
+		}
+	}
 	// In += context, string functions return String but += expects &str
 	// Add & before calls to string_format (Sprintf)
 	if re.inAssignRhs && re.assignmentToken == "+=" {
@@ -2010,6 +4275,17 @@ func (re *RustEmitter) PreVisitCallExpr(node *ast.CallExpr, indent int) {
 }
 
 func (re *RustEmitter) PostVisitCallExpr(node *ast.CallExpr, indent int) {
+	if re.pointerCalls[node] {
+		re.gir.emitToFileBuffer(")", EmptyVisitMethod)
+	}
+	if isBuiltinCall(re.pkg, node, "new") {
+		if named, ok := re.pkg.TypesInfo.TypeOf(node.Args[0]).(*types.Named); ok {
+			re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, re.newCallStart, len(re.gir.tokenSlice), []string{"Ptr::new(" + re.qualifiedTypeName(named) + "::default())"})
+		}
+	}
+	if len(re.appendCalls) > 0 {
+		re.appendCalls = re.appendCalls[:len(re.appendCalls)-1]
+	}
 	// Note: Do NOT set shouldGenerate = false here!
 	// This would prevent subsequent operands in expressions from being generated.
 	// For example, in (a + b) + c where b is a call, setting false would suppress 'c'.
@@ -2040,6 +4316,8 @@ func (re *RustEmitter) PreVisitDeclStmt(node *ast.DeclStmt, indent int) {
 func (re *RustEmitter) PostVisitDeclStmt(node *ast.DeclStmt, indent int) {
 	// Reordering is now done per-name in PostVisitDeclStmtValueSpecNames
 	re.emitToken(";", Semicolon, 0)
+	re.gir.emitToFileBuffer(re.closureCellDecls(node, 0, ""), EmptyVisitMethod)
+	re.gir.emitToFileBuffer(re.slicedArrayDecls(node), EmptyVisitMethod)
 	re.shouldGenerate = false
 }
 
@@ -2062,10 +4340,28 @@ func (re *RustEmitter) PreVisitAssignStmt(node *ast.AssignStmt, indent int) {
 		return
 	}
 	re.shouldGenerate = true
+	if len(node.Lhs) == 1 && isMapIndexExpr(re.pkg, node.Lhs[0]) {
+		re.mapLvalue = node.Lhs[0]
+	}
+	if isMapCommaOk(re.pkg, node) {
+		re.mapCommaOkExpr = node.Rhs[0]
+	}
+	if isTypeAssertCommaOk(node) {
+		re.typeAssertCommaOk = node.Rhs[0]
+	}
+	if isChanRecvCommaOk(node) {
+		re.chanRecvCommaOk = ast.Unparen(node.Rhs[0])
+	}
 	str := re.emitAsString("", indent)
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	re.openPackageVarWrites(node.Lhs, 0)
+	re.markPointerPlaces(node.Lhs)
 }
 func (re *RustEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {
+	re.mapLvalue = nil
+	re.mapCommaOkExpr = nil
+	re.typeAssertCommaOk = nil
+	re.chanRecvCommaOk = nil
 	// Reset blank identifier suppression if it was set
 	if re.suppressRangeEmit {
 		re.suppressRangeEmit = false
@@ -2075,6 +4371,9 @@ func (re *RustEmitter) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {
 	if !re.insideForPostCond {
 		re.emitToken(";", Semicolon, 0)
 	}
+	re.closePackageVarWrites()
+	re.gir.emitToFileBuffer(re.closureCellDecls(node, 0, ""), EmptyVisitMethod)
+	re.gir.emitToFileBuffer(re.slicedArrayDecls(node), EmptyVisitMethod)
 	re.shouldGenerate = false
 }
 
@@ -2111,9 +4410,18 @@ func (re *RustEmitter) PreVisitAssignStmtRhs(node *ast.AssignStmt, indent int) {
 			}
 		}
 	}
+
+	// Assigning a concrete value to an interface variable wraps it
+	if len(node.Lhs) == 1 && len(node.Rhs) == 1 && re.assignmentToken == "=" {
+		re.markInterfaceConversion(re.pkg.TypesInfo.TypeOf(node.Lhs[0]), node.Rhs[0])
+		re.openInterfaceConversion(node.Rhs[0])
+	}
 }
 
 func (re *RustEmitter) PostVisitAssignStmtRhs(node *ast.AssignStmt, indent int) {
+	if len(node.Rhs) == 1 {
+		defer re.closeInterfaceConversion(node.Rhs[0])
+	}
 	// Check if we need to add a type cast for constant assignments
 	// This handles untyped int constants assigned to i8 variables
 	if len(node.Lhs) == 1 && len(node.Rhs) == 1 {
@@ -2171,6 +4479,11 @@ func (re *RustEmitter) PostVisitAssignStmtRhs(node *ast.AssignStmt, indent int)
 						needsClone = true
 					}
 
+					// Map, copies share the entries
+					if _, isMap := rhsType.Type.Underlying().(*types.Map); isMap {
+						needsClone = true
+					}
+
 					// Struct type (named or anonymous)
 					if named, ok := rhsType.Type.(*types.Named); ok {
 						if _, isStruct := named.Underlying().(*types.Struct); isStruct {
@@ -2181,12 +4494,36 @@ func (re *RustEmitter) PostVisitAssignStmtRhs(node *ast.AssignStmt, indent int)
 						needsClone = true
 					}
 
+					// Type parameter, which may be instantiated with a non-Copy type
+					if _, isParam := rhsType.Type.(*types.TypeParam); isParam {
+						needsClone = true
+					}
+
+					// Pointer, copies share the value
+					if isStructPointer(rhsType.Type) {
+						needsClone = true
+					}
+
+					// Function value, copies share the closure
+					if re.isFuncValueCopy(rhsIdent) {
+						needsClone = true
+					}
+
+					// Channel, copies share the channel
+					if isChanExpr(re.pkg, rhsIdent) {
+						needsClone = true
+					}
+
 					if needsClone {
 						re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
 					}
 				}
 			}
 		}
+		// A channel or slice field stays usable after its copy is stored
+		if sel, ok := node.Rhs[0].(*ast.SelectorExpr); ok && (isChanExpr(re.pkg, sel) || isSliceExpr(re.pkg, sel)) {
+			re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+		}
 	}
 
 	// For local closure assignments, remove the entire statement from token stream
@@ -2213,7 +4550,8 @@ func (re *RustEmitter) PreVisitAssignStmtLhsExpr(node ast.Expr, index int, inden
 		str := re.emitAsString(", ", indent)
 		re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 		// For multi-value declarations, add mut before each subsequent variable
-		if re.inMultiValueDecl {
+		// (the blank identifier can't be mutable)
+		if ident, isIdent := node.(*ast.Ident); re.inMultiValueDecl && !(isIdent && ident.Name == "_") {
 			re.emitToken("mut", RustKeyword, 0)
 			re.emitToken(" ", WhiteSpace, 0)
 		}
@@ -2264,7 +4602,7 @@ func (re *RustEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int) {
 		re.currentClosureName = ""
 	}
 	if assignmentToken == ":=" && len(node.Rhs) == 1 {
-		if funcLit, ok := node.Rhs[0].(*ast.FuncLit); ok {
+		if funcLit, ok := node.Rhs[0].(*ast.FuncLit); ok && re.inlinedClosures[funcLit] {
 			if ident, ok := node.Lhs[0].(*ast.Ident); ok {
 				re.localClosureAssign = true
 				re.currentClosureName = ident.Name
@@ -2276,6 +4614,9 @@ func (re *RustEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int) {
 				return
 			}
 		}
+		if funcLit, ok := node.Rhs[0].(*ast.FuncLit); ok {
+			re.boundFuncLits[funcLit] = true
+		}
 	}
 	re.inMultiValueDecl = false
 	if assignmentToken == ":=" && len(node.Lhs) == 1 {
@@ -2289,8 +4630,10 @@ func (re *RustEmitter) PreVisitAssignStmtLhs(node *ast.AssignStmt, indent int) {
 		re.emitToken("let", RustKeyword, indent)
 		re.emitToken(" ", WhiteSpace, 0)
 		re.emitToken("(", LeftParen, 0)
-		re.emitToken("mut", RustKeyword, 0)
-		re.emitToken(" ", WhiteSpace, 0)
+		if ident, isIdent := node.Lhs[0].(*ast.Ident); !isIdent || ident.Name != "_" {
+			re.emitToken("mut", RustKeyword, 0)
+			re.emitToken(" ", WhiteSpace, 0)
+		}
 	} else if assignmentToken == "=" && len(node.Lhs) > 1 {
 		re.emitToken("(", LeftParen, indent)
 		re.isTuple = true
@@ -2312,12 +4655,56 @@ func (re *RustEmitter) PostVisitAssignStmtLhs(node *ast.AssignStmt, indent int)
 		re.emitToken(")", RightParen, indent)
 	} else if node.Tok.String() == "=" && len(node.Lhs) > 1 {
 		re.emitToken(")", RightParen, indent)
+	} else if node.Tok == token.DEFINE && !re.insideForPostCond && isIntConstant(re.pkg, node.Rhs[0]) {
+		// An unconstrained integer literal would default to i32
+		re.gir.emitToFileBuffer(": "+rustTypesMap[re.pkg.TypesInfo.TypeOf(node.Rhs[0]).Underlying().String()], EmptyVisitMethod)
 	}
 	re.inAssignLhs = false // Done with LHS
 	re.shouldGenerate = false
 }
 
+// Instantiations of generic functions and types list their type arguments,
+// the turbofish form is valid in both type and expression position
+func (re *RustEmitter) PreVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer("::<", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitIndexListExprIndex(node ast.Expr, index int, indent int) {
+	if re.forwardDecls || index == 0 {
+		return
+	}
+	re.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitIndexListExprIndices(node *ast.IndexListExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(">", EmptyVisitMethod)
+}
+
 func (re *RustEmitter) PreVisitIndexExpr(node *ast.IndexExpr, indent int) {
+	if isMapIndexExpr(re.pkg, node) {
+		if node == re.mapLvalue {
+			// m[k] = v -> *m.entry(k).or_default() = v
+			re.gir.emitToFileBuffer("*", EmptyVisitMethod)
+		} else if node == re.mapCommaOkExpr {
+			re.gir.emitToFileBuffer("map_get_ok(&", EmptyVisitMethod)
+		} else {
+			re.gir.emitToFileBuffer("map_get(&", EmptyVisitMethod)
+		}
+		return
+	}
+	// Index a package-level variable in place instead of cloning all of it
+	if ident := re.indexedPackageVar(node); ident != nil {
+		if re.pkgVarIndexed == nil {
+			re.pkgVarIndexed = make(map[*ast.Ident]bool)
+		}
+		re.pkgVarIndexed[ident] = true
+	}
 	// For assignment RHS, check if the element type is a function (needs borrowing in Rust)
 	if re.inAssignRhs {
 		tv := re.pkg.TypesInfo.Types[node.X]
@@ -2332,8 +4719,40 @@ func (re *RustEmitter) PreVisitIndexExpr(node *ast.IndexExpr, indent int) {
 	}
 }
 
+func (re *RustEmitter) PostVisitIndexExpr(node *ast.IndexExpr, indent int) {
+	if ident := re.indexedPackageVar(node); ident != nil && re.pkgVarIndexed[ident] {
+		delete(re.pkgVarIndexed, ident)
+		re.gir.emitToFileBuffer(".clone())", EmptyVisitMethod)
+	}
+}
+
+// indexedPackageVar returns the package-level variable indexed by node, unless
+// the enclosing statement writes to it
+func (re *RustEmitter) indexedPackageVar(node *ast.IndexExpr) *ast.Ident {
+	if isMapIndexExpr(re.pkg, node) {
+		return nil
+	}
+	x := node.X
+	if sel, ok := x.(*ast.SelectorExpr); ok {
+		x = sel.Sel
+	}
+	ident, ok := x.(*ast.Ident)
+	if !ok || !re.isCellVar(re.pkg.TypesInfo.Uses[ident]) || re.pkgVarWrites[ident] {
+		return nil
+	}
+	return ident
+}
+
 func (re *RustEmitter) PreVisitIndexExprIndex(node *ast.IndexExpr, indent int) {
 	re.shouldGenerate = true
+	if isMapIndexExpr(re.pkg, node) {
+		if node == re.mapLvalue {
+			re.gir.emitToFileBuffer(".entry(", EmptyVisitMethod)
+		} else {
+			re.gir.emitToFileBuffer(", &", EmptyVisitMethod)
+		}
+		return
+	}
 	// If the base expression is a string, we need .as_bytes() for indexing
 	if node.X != nil {
 		tv := re.pkg.TypesInfo.Types[node.X]
@@ -2345,6 +4764,20 @@ func (re *RustEmitter) PreVisitIndexExprIndex(node *ast.IndexExpr, indent int) {
 
 }
 func (re *RustEmitter) PostVisitIndexExprIndex(node *ast.IndexExpr, indent int) {
+	if isMapIndexExpr(re.pkg, node) {
+		if node == re.mapLvalue {
+			// entry() takes the key by value - clone string variables so they stay usable
+			if tv := re.pkg.TypesInfo.Types[node.Index]; tv.Type != nil && tv.Type.String() == "string" {
+				if _, isBasicLit := node.Index.(*ast.BasicLit); !isBasicLit {
+					re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+				}
+			}
+			re.gir.emitToFileBuffer(").or_default()", EmptyVisitMethod)
+		} else {
+			re.gir.emitToFileBuffer(")", EmptyVisitMethod)
+		}
+		return
+	}
 	// Check if the index type is an integer (not usize) - need to add "as usize"
 	if node.Index != nil {
 		tv := re.pkg.TypesInfo.Types[node.Index]
@@ -2352,25 +4785,44 @@ func (re *RustEmitter) PostVisitIndexExprIndex(node *ast.IndexExpr, indent int)
 			typeStr := tv.Type.String()
 			// Go int types need to be cast to usize for Rust indexing
 			if typeStr == "int" || typeStr == "int32" || typeStr == "int64" ||
-				typeStr == "int8" || typeStr == "int16" {
+				typeStr == "int8" || typeStr == "int16" || typeStr == "uint" ||
+				typeStr == "uint32" || typeStr == "uint64" {
 				re.gir.emitToFileBuffer(" as usize", EmptyVisitMethod)
 			}
 		}
 	}
 	re.emitToken("]", RightBracket, 0)
 
-	// Add .clone() for Vec element access when the element type doesn't implement Copy
+	// Add .clone() for Slice element access when the element type doesn't implement Copy
 	// This is needed because Rust doesn't allow moving out of indexed collections
 	// BUT: Don't add .clone() when we're in the LHS of an assignment (we're assigning TO it)
+	if node == re.methodRecvElem {
+		re.methodRecvElem = nil
+		return
+	}
 	if node.X != nil && !re.inAssignLhs {
 		tv := re.pkg.TypesInfo.Types[node.X]
 		if tv.Type != nil {
 			// Check if it's a slice/array type
-			underlying := tv.Type.Underlying()
-			if sliceType, ok := underlying.(*types.Slice); ok {
-				elemType := sliceType.Elem()
-				// Check if element type is a struct (non-Copy type)
-				if _, isStruct := elemType.Underlying().(*types.Struct); isStruct {
+			var elemType types.Type
+			switch t := tv.Type.Underlying().(type) {
+			case *types.Slice:
+				elemType = t.Elem()
+			case *types.Array:
+				elemType = t.Elem()
+				// Arrays of non-Copy elements are not Copy either
+				if array, isArray := elemType.Underlying().(*types.Array); isArray && !rustArrayIsCopy(array) {
+					re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+				}
+			}
+			if elemType != nil {
+				// Check if element type is a struct or interface (non-Copy type)
+				_, isParam := elemType.(*types.TypeParam)
+				if _, isStruct := elemType.Underlying().(*types.Struct); isStruct || isParam || isNonEmptyInterface(elemType) {
+					re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+				}
+				// Slice headers share their buffer, so cloning one is cheap
+				if _, isSlice := elemType.Underlying().(*types.Slice); isSlice {
 					re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
 				}
 				// Check if element type is a string (also non-Copy in Rust)
@@ -2387,6 +4839,10 @@ func (re *RustEmitter) PostVisitIndexExprIndex(node *ast.IndexExpr, indent int)
 func (re *RustEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 	re.shouldGenerate = true
 	re.emitToken("(", LeftParen, 1)
+	re.markNilTest(node)
+	op := re.intOp(node)
+	re.intOps = append(re.intOps, op)
+	re.gir.emitToFileBuffer(op.open, EmptyVisitMethod)
 
 	// Save current state for nested expressions
 	re.binaryNeedsLeftCastStack = append(re.binaryNeedsLeftCastStack, re.binaryNeedsLeftCast)
@@ -2419,7 +4875,7 @@ func (re *RustEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 
 	// Check if left operand is u8/i8/u16/i16 and right is a constant
 	// Go's type checker sees both as same type after implicit conversion, but
-	// we generate constants as i32, so we need to handle type mismatches
+	// we generate untyped constants as int, so we need to handle type mismatches
 	isComparisonOp := node.Op == token.EQL || node.Op == token.NEQ ||
 		node.Op == token.LSS || node.Op == token.GTR ||
 		node.Op == token.LEQ || node.Op == token.GEQ
@@ -2448,7 +4904,7 @@ func (re *RustEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 
 			if rightIsConst {
 				if isComparisonOp {
-					// For comparisons, cast left to i32 (result is bool)
+					// For comparisons, cast left to int (result is bool)
 					re.binaryNeedsLeftCast = true
 				} else if isBitwiseOp {
 					// For bitwise ops, cast right constant to match left type
@@ -2459,7 +4915,7 @@ func (re *RustEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 			}
 
 			// Also check if right side is a binary expression or paren expr containing one
-			// (e.g., 0xFF - FlagZ or (0xFF - FlagZ)) that will evaluate to i32 in Rust
+			// (e.g., 0xFF - FlagZ or (0xFF - FlagZ)) that will evaluate to int in Rust
 			if isBitwiseOp {
 				// Get the actual expression, unwrapping ParenExpr if needed
 				rightExpr := node.Y
@@ -2469,7 +4925,7 @@ func (re *RustEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 
 				if _, ok := rightExpr.(*ast.BinaryExpr); ok {
 					// Check if the expression contains constants or literals
-					// that will result in i32
+					// that will result in int
 					hasIntLiteral := false
 					ast.Inspect(rightExpr, func(n ast.Node) bool {
 						if lit, ok := n.(*ast.BasicLit); ok {
@@ -2496,11 +4952,90 @@ func (re *RustEmitter) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 	}
 }
 
+// intOp returns the wrapping form of an integer operation, which debug
+// builds would otherwise check for overflow, and the checked form of a shift
+// whose count may be negative or beyond the width: i64::wrapping_add(a, b),
+// u8::checked_shl(x, shift_count(n as i128)).unwrap_or(0)
+func (re *RustEmitter) intOp(node *ast.BinaryExpr) intOp {
+	basic := intOpType(re.pkg, node)
+	if basic == nil {
+		return intOp{}
+	}
+	t := re.mapGoTypeToRust(intTypeName(basic))
+	switch node.Op {
+	case token.ADD, token.SUB, token.MUL:
+		name := map[token.Token]string{token.ADD: "add", token.SUB: "sub", token.MUL: "mul"}[node.Op]
+		return intOp{open: t + "::wrapping_" + name + "(", sep: ", ", close: ")"}
+	case token.QUO, token.REM:
+		if !isSignedQuo(re.pkg, node, basic) {
+			return intOp{}
+		}
+		name := map[token.Token]string{token.QUO: "div", token.REM: "rem"}[node.Op]
+		return intOp{open: t + "::wrapping_" + name + "(", sep: ", ", close: ")"}
+	}
+	if shiftCountInRange(re.pkg, node, basic) {
+		return intOp{}
+	}
+	if node.Op == token.SHR && !isUnsignedInt(basic) {
+		// Shifting out every bit leaves the sign
+		return intOp{open: t + "::wrapping_shr(", sep: ", shift_count(", close: fmt.Sprintf(" as i128).min(%s::BITS - 1))", t)}
+	}
+	name := map[token.Token]string{token.SHL: "shl", token.SHR: "shr"}[node.Op]
+	return intOp{open: t + "::checked_" + name + "(", sep: ", shift_count(", close: " as i128)).unwrap_or(0)"}
+}
+
+// markNilTest records the comparison of a function, an interface{}, a slice
+// or a map with nil, which is emitted as a test of the left operand instead
+func (re *RustEmitter) markNilTest(node *ast.BinaryExpr) {
+	if (node.Op != token.EQL && node.Op != token.NEQ) || !isNilIdent(node.Y) {
+		return
+	}
+	var test string
+	switch {
+	case isFuncExpr(re.pkg, node.X) && node.Op == token.EQL:
+		test = ".is_none()"
+	case isFuncExpr(re.pkg, node.X):
+		test = ".is_some()"
+	case isAnyNilComparison(re.pkg, node):
+		// A nil interface{} holds the unit value
+		test = ".is::<()>()"
+		if node.Op == token.NEQ {
+			re.gir.emitToFileBuffer("!", EmptyVisitMethod)
+		}
+	case isCollectionNilComparison(re.pkg, node):
+		test = ".is_nil()"
+		if node.Op == token.NEQ {
+			re.gir.emitToFileBuffer("!", EmptyVisitMethod)
+		}
+	default:
+		return
+	}
+	if re.nilTests == nil {
+		re.nilTests = make(map[ast.Expr]string)
+	}
+	re.nilTests[node.X] = test
+	re.nilTests[node.Y] = ""
+}
+
 func (re *RustEmitter) PostVisitBinaryExprLeft(node ast.Expr, indent int) {
-	// Add cast to i32 if needed for type compatibility with constants
+	if test, ok := re.nilTests[node]; ok {
+		re.gir.emitToFileBuffer(test, EmptyVisitMethod)
+		re.skipOperator = true
+	}
+	// Add cast to int if needed for type compatibility with constants
 	if re.binaryNeedsLeftCast {
-		re.gir.emitToFileBuffer(" as i32", EmptyVisitMethod)
+		re.gir.emitToFileBuffer(" as "+rustTypesMap["int"], EmptyVisitMethod)
+	}
+	re.castIntOpConst(node)
+}
+
+// castIntOpConst casts a named constant operand of a wrapping operation,
+// whose operands must have the same type, to the type of the operation
+func (re *RustEmitter) castIntOpConst(operand ast.Expr) {
+	if re.forwardDecls || re.intOps[len(re.intOps)-1].sep != ", " || !isConstOperand(re.pkg, operand) {
+		return
 	}
+	re.gir.emitToFileBuffer(" as "+re.mapGoTypeToRust(intTypeName(re.pkg.TypesInfo.TypeOf(operand).Underlying().(*types.Basic))), EmptyVisitMethod)
 }
 
 func (re *RustEmitter) PreVisitBinaryExprRight(node ast.Expr, indent int) {
@@ -2516,10 +5051,13 @@ func (re *RustEmitter) PostVisitBinaryExprRight(node ast.Expr, indent int) {
 	if re.binaryNeedsRightCast != "" && re.binaryNeedsRightCast != "&" && !re.forwardDecls {
 		re.gir.emitToFileBuffer(fmt.Sprintf(" as %s", re.binaryNeedsRightCast), EmptyVisitMethod)
 	}
+	re.castIntOpConst(node)
+	re.gir.emitToFileBuffer(re.intOps[len(re.intOps)-1].close, EmptyVisitMethod)
 }
 
 func (re *RustEmitter) PostVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 	re.emitToken(")", RightParen, 1)
+	re.intOps = re.intOps[:len(re.intOps)-1]
 	// Restore previous state for nested expressions
 	if len(re.binaryNeedsLeftCastStack) > 0 {
 		re.binaryNeedsLeftCast = re.binaryNeedsLeftCastStack[len(re.binaryNeedsLeftCastStack)-1]
@@ -2539,7 +5077,18 @@ func (re *RustEmitter) PostVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 }
 
 func (re *RustEmitter) PreVisitBinaryExprOperator(op token.Token, indent int) {
+	if re.skipOperator {
+		re.skipOperator = false
+		return
+	}
+	if sep := re.intOps[len(re.intOps)-1].sep; sep != "" {
+		re.gir.emitToFileBuffer(sep, EmptyVisitMethod)
+		return
+	}
 	content := op.String()
+	if op == token.AND_NOT {
+		content = "& !"
+	}
 	opTokenType := re.getTokenType(content)
 	re.emitToken(content, opTokenType, 0)
 	re.emitToken(" ", WhiteSpace, 0)
@@ -2550,8 +5099,110 @@ func (re *RustEmitter) PreVisitCallExprArg(node ast.Expr, index int, indent int)
 		str := re.emitAsString(", ", 0)
 		re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	}
+	// delete(m, k) -> map_delete(&mut m, &k)
+	if re.currentCallIsMapDelete {
+		if index == 0 {
+			re.gir.emitToFileBuffer("&mut ", EmptyVisitMethod)
+		} else {
+			re.gir.emitToFileBuffer("&", EmptyVisitMethod)
+		}
+	}
+	// append(c.items, v) in a pointer method: the slice can't be moved out of
+	// the borrowed receiver, take it instead since the result is assigned back
+	if re.currentCallIsAppend && index == 0 && re.isPointerRecvPath(node) && !isSelfAppend(re.currentAppendCall()) {
+		re.gir.emitToFileBuffer("std::mem::take(&mut ", EmptyVisitMethod)
+	}
+	// append(s, a, b) -> append_many(s, slice![a, b])
+	if call := re.currentAppendCall(); call != nil && index == 1 && appendsMany(call) && !call.Ellipsis.IsValid() {
+		re.gir.emitToFileBuffer("slice![", EmptyVisitMethod)
+	}
+	re.openInterfaceConversion(node)
 	// Track that we're inside a call argument (for closure wrapping decisions)
 	re.inCallExprArg = true
+	re.callArg = node
+}
+
+// PreVisitCallExprVariadicArgs packs the variadic arguments into a Slice built
+// at the call site, nil without arguments; a spread slice is passed as is
+func (re *RustEmitter) PreVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if index > 0 {
+		re.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+	}
+	if index == len(node.Args) {
+		re.gir.emitToFileBuffer("Slice::new()", EmptyVisitMethod)
+	} else if !node.Ellipsis.IsValid() {
+		re.gir.emitToFileBuffer("slice![", EmptyVisitMethod)
+	}
+}
+
+func (re *RustEmitter) PostVisitCallExprVariadicArgs(node *ast.CallExpr, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if index < len(node.Args) && !node.Ellipsis.IsValid() {
+		re.gir.emitToFileBuffer("]", EmptyVisitMethod)
+	}
+}
+
+// currentAppendCall returns the append call whose arguments are being
+// emitted, nil when the innermost call is not append
+func (re *RustEmitter) currentAppendCall() *ast.CallExpr {
+	if len(re.appendCalls) == 0 {
+		return nil
+	}
+	return re.appendCalls[len(re.appendCalls)-1]
+}
+
+// appendsMany reports whether an append call adds a spread slice or several
+// values, which is lowered to append_many
+func appendsMany(call *ast.CallExpr) bool {
+	return call.Ellipsis.IsValid() || len(call.Args) > 2
+}
+
+// splitTopLevelArgs splits emitted call arguments at the commas outside of
+// brackets, angle brackets included for the type argument of make
+func splitTopLevelArgs(argStr string) []string {
+	var args []string
+	depth := 0
+	start := 0
+	for i, c := range argStr {
+		switch c {
+		case '<', '(', '[', '{':
+			depth++
+		case '>', ')', ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				args = append(args, strings.TrimSpace(argStr[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(args, strings.TrimSpace(argStr[start:]))
+}
+
+// isSelfAppend reports whether a call is append(s, s...)
+func isSelfAppend(call *ast.CallExpr) bool {
+	return call != nil && call.Ellipsis.IsValid() && len(call.Args) == 2 &&
+		types.ExprString(call.Args[0]) == types.ExprString(call.Args[1])
+}
+
+// isPointerRecvPath reports whether expr is a field path rooted at the
+// receiver of the current pointer method, e.g. c.items or c.inner.items
+func (re *RustEmitter) isPointerRecvPath(expr ast.Expr) bool {
+	if re.currentFuncDecl == nil || !hasPointerRecv(re.currentFuncDecl) {
+		return false
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	for ok {
+		expr = sel.X
+		sel, ok = expr.(*ast.SelectorExpr)
+	}
+	ident, isIdent := expr.(*ast.Ident)
+	return isIdent && ident.Name == recvName(re.currentFuncDecl)
 }
 
 func (re *RustEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int) {
@@ -2559,14 +5210,36 @@ func (re *RustEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int
 		re.inCallExprArg = false
 		return
 	}
+	defer re.closeInterfaceConversion(node)
+	// append_many moves the spread slice in, and append(s, s...) can't move s
+	// before reading it
+	if call := re.currentAppendCall(); call != nil && call.Ellipsis.IsValid() && (index > 0 || isSelfAppend(call)) {
+		re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+		re.inCallExprArg = false
+		return
+	}
+	if re.currentCallIsAppend && index == 0 && re.isPointerRecvPath(node) {
+		re.gir.emitToFileBuffer(")", EmptyVisitMethod)
+		re.inCallExprArg = false
+		return
+	}
 	// Check if the argument type needs .clone()
 	tv := re.pkg.TypesInfo.Types[node]
 	if tv.Type != nil {
 		typeStr := tv.Type.String()
 
-		// Clone Vec/slice types (but not for append which takes ownership)
+		// Clone slices, a clone shares the array
 		if strings.HasPrefix(typeStr, "[]") {
-			if !re.currentCallIsAppend {
+			if !tv.IsType() {
+				re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+			}
+			re.inCallExprArg = false
+			return
+		}
+
+		// Clone maps, a clone shares the entries (delete takes the map itself)
+		if _, isMap := tv.Type.Underlying().(*types.Map); isMap {
+			if !re.currentCallIsMapDelete && !tv.IsType() {
 				re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
 			}
 			re.inCallExprArg = false
@@ -2586,7 +5259,7 @@ func (re *RustEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int
 		if named, ok := tv.Type.(*types.Named); ok {
 			// Check if underlying type is a slice (e.g., type AST []Statement)
 			if _, isSlice := named.Underlying().(*types.Slice); isSlice {
-				if !re.currentCallIsAppend {
+				if !tv.IsType() {
 					re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
 				}
 				re.inCallExprArg = false
@@ -2622,6 +5295,18 @@ func (re *RustEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int
 			re.inCallExprArg = false
 			return
 		}
+		// Interface values share their Rc, cloning them is cheap
+		if isNonEmptyInterface(tv.Type) && !tv.IsType() {
+			re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+			re.inCallExprArg = false
+			return
+		}
+		// So do pointers, function values and channels
+		if (isStructPointer(tv.Type) && !tv.IsType()) || re.isFuncValueCopy(node) || (isChanExpr(re.pkg, node) && !tv.IsType()) {
+			re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+			re.inCallExprArg = false
+			return
+		}
 	}
 
 	// Liveness-based clone: if this identifier will be used in a later statement,
@@ -2631,16 +5316,14 @@ func (re *RustEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int
 			// Check if the type requires cloning (non-Copy types)
 			if tv.Type != nil {
 				typeStr := tv.Type.String()
-				// Clone for slice/Vec types
+				// Clone for slice types, a clone shares the array
 				if strings.Contains(typeStr, "[]") {
-					if !re.currentCallIsAppend {
-						re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
-					}
+					re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
 					re.inCallExprArg = false
 					return
 				}
-				// Clone for string types
-				if typeStr == "string" {
+				// Clone for string and type parameter types
+				if _, isParam := tv.Type.(*types.TypeParam); isParam || typeStr == "string" {
 					re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
 					re.inCallExprArg = false
 					return
@@ -2649,9 +5332,7 @@ func (re *RustEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int
 				if named, ok := tv.Type.(*types.Named); ok {
 					// Check if underlying type is a slice (e.g., type AST []Statement)
 					if _, isSlice := named.Underlying().(*types.Slice); isSlice {
-						if !re.currentCallIsAppend {
-							re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
-						}
+						re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
 						re.inCallExprArg = false
 						return
 					}
@@ -2668,9 +5349,19 @@ func (re *RustEmitter) PostVisitCallExprArg(node ast.Expr, index int, indent int
 	re.inCallExprArg = false
 }
 
+func (re *RustEmitter) PreVisitExprStmtX(node ast.Expr, indent int) {
+	// Pointer methods called on a package-level variable mutate it
+	var recv []ast.Expr
+	if x := re.pointerMethodRecv(node); x != nil {
+		recv = append(recv, x)
+	}
+	re.openPackageVarWrites(recv, 0)
+}
+
 func (re *RustEmitter) PostVisitExprStmtX(node ast.Expr, indent int) {
 	str := re.emitAsString(";", 0)
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	re.closePackageVarWrites()
 }
 
 func (re *RustEmitter) PreVisitIfStmt(node *ast.IfStmt, indent int) {
@@ -2694,6 +5385,15 @@ func (re *RustEmitter) PostVisitIfStmtCond(node *ast.IfStmt, indent int) {
 
 func (re *RustEmitter) PreVisitForStmt(node *ast.ForStmt, indent int) {
 	re.insideForPostCond = true
+	// Save the increment of an enclosing loop, PostVisitForStmt restores it
+	re.outerLoopIncrements = append(re.outerLoopIncrements, rustLoopIncrement{
+		pending: re.pendingLoopIncrement,
+		name:    re.loopIncrementVar,
+		op:      re.loopIncrementOp,
+		val:     re.loopIncrementVal,
+		inBody:  re.inForLoopBody,
+		depth:   re.forLoopBodyDepth,
+	})
 	// Reset all for loop tracking flags
 	re.sawIncrement = false
 	re.sawDecrement = false
@@ -2703,10 +5403,8 @@ func (re *RustEmitter) PreVisitForStmt(node *ast.ForStmt, indent int) {
 	re.pendingLoopIncrement = false
 	re.inForLoopBody = false
 
-	// Check for compound condition (contains && or ||)
-	hasCompoundCond := re.hasCompoundCondition(node.Cond)
-	if hasCompoundCond && node.Init != nil && node.Post != nil {
-		// This is a for loop with compound condition - will be converted to while loop
+	if re.isWhileLoop(node) {
+		// This for loop will be converted to a while loop
 		// Extract the loop variable and increment info from Init and Post
 		if assign, ok := node.Init.(*ast.AssignStmt); ok && len(assign.Lhs) > 0 {
 			if ident, ok := assign.Lhs[0].(*ast.Ident); ok {
@@ -2736,6 +5434,11 @@ func (re *RustEmitter) PreVisitForStmt(node *ast.ForStmt, indent int) {
 		}
 		re.pendingLoopIncrement = true
 	}
+	loop := rustContinue{label: strings.TrimSuffix(re.whileLabels[node], ": ")}
+	if re.pendingLoopIncrement {
+		loop.increment = re.loopIncrementVar + " " + re.loopIncrementOp + " " + re.loopIncrementVal + ";"
+	}
+	re.continueIncrements = append(re.continueIncrements, loop)
 
 	// Detect loop type upfront to emit correct Rust keyword
 	var str string
@@ -2751,6 +5454,40 @@ func (re *RustEmitter) PreVisitForStmt(node *ast.ForStmt, indent int) {
 	re.shouldGenerate = true
 }
 
+// restoreLoopIncrement restores the increment state saved by PreVisitForStmt
+func (re *RustEmitter) restoreLoopIncrement() {
+	n := len(re.outerLoopIncrements)
+	if n == 0 {
+		return
+	}
+	outer := re.outerLoopIncrements[n-1]
+	re.outerLoopIncrements = re.outerLoopIncrements[:n-1]
+	re.pendingLoopIncrement = outer.pending
+	re.loopIncrementVar = outer.name
+	re.loopIncrementOp = outer.op
+	re.loopIncrementVal = outer.val
+	re.inForLoopBody = outer.inBody
+	re.forLoopBodyDepth = outer.depth
+}
+
+// isWhileLoop reports whether a three-clause for loop is lowered to a while
+// loop incrementing its variable at the end of the body, instead of a for over
+// a range: when its condition is compound or its body assigns the variable
+func (re *RustEmitter) isWhileLoop(node *ast.ForStmt) bool {
+	if node.Init == nil || node.Post == nil {
+		return false
+	}
+	if re.hasCompoundCondition(node.Cond) {
+		return true
+	}
+	init, ok := node.Init.(*ast.AssignStmt)
+	if !ok || len(init.Lhs) == 0 {
+		return false
+	}
+	loopVar := re.pkg.TypesInfo.ObjectOf(init.Lhs[0].(*ast.Ident))
+	return loopVar != nil && assignsVar(re.pkg, node.Body, loopVar)
+}
+
 // hasCompoundCondition checks if the expression contains && or ||
 func (re *RustEmitter) hasCompoundCondition(expr ast.Expr) bool {
 	if expr == nil {
@@ -2798,6 +5535,8 @@ func (re *RustEmitter) PostVisitForStmtCond(node ast.Expr, indent int) {
 }
 
 func (re *RustEmitter) PostVisitForStmt(node *ast.ForStmt, indent int) {
+	defer re.restoreLoopIncrement()
+	re.continueIncrements = re.continueIncrements[:len(re.continueIncrements)-1]
 	re.shouldGenerate = false
 	re.insideForPostCond = false
 
@@ -2835,6 +5574,13 @@ func (re *RustEmitter) PostVisitForStmt(node *ast.ForStmt, indent int) {
 					}
 				}
 				startStr = strings.TrimSpace(startStr)
+				// A literal start gets the type of the loop variable, it
+				// would default to i32
+				if init, ok := node.Init.(*ast.AssignStmt); ok && len(init.Rhs) == 1 {
+					if _, isLit := init.Rhs[0].(*ast.BasicLit); isLit && isIntConstant(re.pkg, init.Rhs[0]) {
+						startStr += rustTypesMap[re.pkg.TypesInfo.TypeOf(init.Rhs[0]).Underlying().String()]
+					}
+				}
 				if startStr != "" {
 					rangeTokens = append(rangeTokens, CreateToken(Identifier, startStr))
 				}
@@ -2965,7 +5711,7 @@ func (re *RustEmitter) PostVisitForStmt(node *ast.ForStmt, indent int) {
 			break
 		}
 	}
-	if pFor != nil && p6 != nil && hasInit && hasCond && hasCompoundCond && len(forVars) > 0 {
+	if pFor != nil && p6 != nil && hasInit && hasCond && (hasCompoundCond || re.isWhileLoop(node)) && len(forVars) > 0 {
 		// Build new tokens: init statement + while loop
 		newTokens := []string{}
 
@@ -2981,7 +5727,7 @@ func (re *RustEmitter) PostVisitForStmt(node *ast.ForStmt, indent int) {
 		newTokens = append(newTokens, ";\n")
 
 		// Add while loop with condition
-		newTokens = append(newTokens, "while ")
+		newTokens = append(newTokens, re.whileLabels[node]+"while ")
 		for _, tok := range condTokens {
 			if tok.Content != ";" {
 				newTokens = append(newTokens, tok.Content)
@@ -3080,12 +5826,34 @@ func (re *RustEmitter) PostVisitForStmt(node *ast.ForStmt, indent int) {
 }
 
 func (re *RustEmitter) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
+	re.continueIncrements = append(re.continueIncrements, rustContinue{})
 	re.shouldGenerate = true
-	// Check if this is a key-value range (both Key and Value present)
-	if node.Key != nil && node.Value != nil {
+	re.isMapRange = isMapExpr(re.pkg, node.X)
+	re.mapRangeValues = node.Key == nil
+	re.isStringRange = isStringExpr(re.pkg, node.X)
+	if re.isStringRange {
+		// Emit: for (i, r) in string_range(&s), the key and value are
+		// suppressed until the string expression
+		bindings := "_"
+		if node.Key != nil || node.Value != nil {
+			key, value := stringRangeNames(re.pkg, nil, node)
+			bindings = fmt.Sprintf("(%s, %s)", key, value)
+		}
+		re.isKeyValueRange = false
+		str := re.emitAsString(fmt.Sprintf("for %s in string_range(&", bindings), indent)
+		re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		re.suppressRangeEmit = true
+		return
+	}
+	// Check if this is a key-value range (both Key and Value present), or an
+	// index-only range over a slice, array or string
+	if node.Key != nil && (node.Value != nil || !re.isMapRange) {
 		re.isKeyValueRange = true
 		re.rangeKeyName = node.Key.(*ast.Ident).Name
-		re.rangeValueName = node.Value.(*ast.Ident).Name
+		re.rangeValueName = ""
+		if node.Value != nil {
+			re.rangeValueName = node.Value.(*ast.Ident).Name
+		}
 		re.rangeCollectionExpr = ""
 		re.suppressRangeEmit = true
 		re.rangeStmtIndent = indent
@@ -3110,7 +5878,9 @@ func (re *RustEmitter) PreVisitRangeStmtValue(node ast.Expr, indent int) {
 }
 
 func (re *RustEmitter) PostVisitRangeStmtValue(node ast.Expr, indent int) {
-	if re.isKeyValueRange {
+	if re.isStringRange {
+		re.suppressRangeEmit = false
+	} else if re.isKeyValueRange {
 		// Stop suppressing, start capturing collection expression
 		re.suppressRangeEmit = false
 		re.captureRangeExpr = true
@@ -3122,10 +5892,21 @@ func (re *RustEmitter) PostVisitRangeStmtValue(node ast.Expr, indent int) {
 
 func (re *RustEmitter) PreVisitRangeStmtX(node ast.Expr, indent int) {
 	// For key-value range, we're already in capture mode
+	if re.isMapRange && !re.isKeyValueRange {
+		if re.mapRangeValues {
+			re.gir.emitToFileBuffer("map_values(&", EmptyVisitMethod)
+		} else {
+			re.gir.emitToFileBuffer("map_keys(&", EmptyVisitMethod)
+		}
+	}
 }
 
 func (re *RustEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
-	if re.isKeyValueRange {
+	if re.isStringRange {
+		re.isStringRange = false
+		re.gir.emitToFileBuffer(")\n", EmptyVisitMethod)
+		re.shouldGenerate = false
+	} else if re.isKeyValueRange {
 		// Stop capturing and emit the complete for loop
 		re.captureRangeExpr = false
 		collection := re.rangeCollectionExpr
@@ -3140,8 +5921,21 @@ func (re *RustEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 			iterMethod = ".bytes().enumerate()"
 		}
 
-		// Emit: for (key, value) in collection.clone().iter().enumerate()
-		str := re.emitAsString(fmt.Sprintf("for (%s, %s) in %s%s\n", key, value, collection, iterMethod), indent)
+		var str string
+		if value == "" {
+			// Index-only range: iterate the indices, not the elements
+			length := fmt.Sprintf("(%s.len() as %s)", collection, rustTypesMap["int"])
+			if array, ok := re.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Array); ok {
+				length = fmt.Sprintf("%d", array.Len())
+			}
+			str = re.emitAsString(fmt.Sprintf("for %s in 0..%s\n", key, length), indent)
+		} else if re.isMapRange {
+			// Emit: for (key, value) in map_entries(&collection)
+			str = re.emitAsString(fmt.Sprintf("for (%s, %s) in map_entries(&%s)\n", key, value, collection), indent)
+		} else {
+			// Emit: for (key, value) in collection.clone().iter().enumerate()
+			str = re.emitAsString(fmt.Sprintf("for (%s, %s) in %s%s\n", key, value, collection, iterMethod), indent)
+		}
 		re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 
 		// Reset range state
@@ -3153,7 +5947,10 @@ func (re *RustEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 	} else {
 		// Check the type of the expression being ranged over
 		tv := re.pkg.TypesInfo.Types[node]
-		if tv.Type != nil {
+		if re.isMapRange {
+			// Close map_keys(& / map_values(&
+			re.gir.emitToFileBuffer(")", EmptyVisitMethod)
+		} else if tv.Type != nil {
 			typeStr := tv.Type.String()
 			if typeStr == "string" {
 				// String needs .bytes() to iterate and get i8 values
@@ -3172,11 +5969,17 @@ func (re *RustEmitter) PostVisitRangeStmtX(node ast.Expr, indent int) {
 }
 
 func (re *RustEmitter) PostVisitRangeStmt(node *ast.RangeStmt, indent int) {
+	re.continueIncrements = re.continueIncrements[:len(re.continueIncrements)-1]
 	// Reset any range-related state
 }
 
 func (re *RustEmitter) PreVisitIncDecStmt(node *ast.IncDecStmt, indent int) {
 	re.shouldGenerate = true
+	if isMapIndexExpr(re.pkg, node.X) {
+		re.mapLvalue = node.X
+	}
+	re.openPackageVarWrites([]ast.Expr{node.X}, 0)
+	re.markPointerPlaces([]ast.Expr{node.X})
 	// Track if we see ++ or -- for for loop rewriting
 	if node.Tok.String() == "++" {
 		re.sawIncrement = true
@@ -3199,6 +6002,8 @@ func (re *RustEmitter) PostVisitIncDecStmt(node *ast.IncDecStmt, indent int) {
 	if !re.insideForPostCond {
 		re.emitToken(";", Semicolon, 0)
 	}
+	re.closePackageVarWrites()
+	re.mapLvalue = nil
 	re.shouldGenerate = false
 }
 
@@ -3208,6 +6013,8 @@ func (re *RustEmitter) PreVisitCompositeLit(node *ast.CompositeLit, indent int)
 	// Reset for this composite literal
 	re.isArray = false
 	re.currentCompLitIsSlice = false
+	re.compLitIsCallArg = node == re.callArg
+	_, re.compLitIsBoxed = re.interfaceConversions[node]
 
 	// Push the type to the stack so we can check it in PostVisitCompositeLitElts
 	var compLitType types.Type
@@ -3218,8 +6025,8 @@ func (re *RustEmitter) PreVisitCompositeLit(node *ast.CompositeLit, indent int)
 			// Check if the underlying type is a slice (for type aliases like AST = []Statement)
 			if underlying := compLitType.Underlying(); underlying != nil {
 				if _, ok := underlying.(*types.Slice); ok {
-					// Only use Vec::new() for empty slice literals
-					// For non-empty, set isArray so vec![] syntax is used
+					// Only use an empty Slice for empty slice literals
+					// For non-empty, set isArray so slice![] syntax is used
 					if len(node.Elts) == 0 {
 						re.currentCompLitIsSlice = true
 					} else {
@@ -3230,6 +6037,62 @@ func (re *RustEmitter) PreVisitCompositeLit(node *ast.CompositeLit, indent int)
 		}
 	}
 	re.compLitTypeStack = append(re.compLitTypeStack, compLitType)
+	isMapLit := false
+	if compLitType != nil {
+		_, isMapLit = compLitType.Underlying().(*types.Map)
+	}
+	re.mapCompositeLits = append(re.mapCompositeLits, isMapLit)
+	isArrayLit := false
+	if compLitType != nil {
+		_, isArrayLit = compLitType.Underlying().(*types.Array)
+	}
+	re.arrayCompositeLits = append(re.arrayCompositeLits, isArrayLit)
+	if compLitType != nil {
+		re.markCompositeLitInterfaceConversions(node, compLitType)
+	}
+}
+
+// markCompositeLitInterfaceConversions marks the elements of a composite literal
+// that are stored in interface-typed slots
+func (re *RustEmitter) markCompositeLitInterfaceConversions(node *ast.CompositeLit, compLitType types.Type) {
+	for _, elt := range node.Elts {
+		kv, isKeyValue := elt.(*ast.KeyValueExpr)
+		switch t := compLitType.Underlying().(type) {
+		case *types.Slice:
+			if isKeyValue {
+				re.markInterfaceConversion(t.Elem(), kv.Value)
+			} else {
+				re.markInterfaceConversion(t.Elem(), elt)
+			}
+		case *types.Array:
+			re.markInterfaceConversion(t.Elem(), elt)
+		case *types.Map:
+			if isKeyValue {
+				re.markInterfaceConversion(t.Elem(), kv.Value)
+			}
+		case *types.Struct:
+			if !isKeyValue {
+				continue
+			}
+			if key, ok := kv.Key.(*ast.Ident); ok {
+				for i := 0; i < t.NumFields(); i++ {
+					if t.Field(i).Name() == key.Name {
+						re.markInterfaceConversion(t.Field(i).Type(), kv.Value)
+					}
+				}
+			}
+		}
+	}
+}
+
+// insideMapCompositeLit reports whether the innermost composite literal is a map literal
+func (re *RustEmitter) insideMapCompositeLit() bool {
+	return len(re.mapCompositeLits) > 0 && re.mapCompositeLits[len(re.mapCompositeLits)-1]
+}
+
+// insideArrayCompositeLit reports whether the innermost composite literal is an array literal
+func (re *RustEmitter) insideArrayCompositeLit() bool {
+	return len(re.arrayCompositeLits) > 0 && re.arrayCompositeLits[len(re.arrayCompositeLits)-1]
 }
 
 // packageScopeHasInterfaceTypes checks if any struct in the package has interface{} fields
@@ -3253,20 +6116,53 @@ func (re *RustEmitter) PreVisitCompositeLitType(node ast.Expr, indent int) {
 func (re *RustEmitter) PostVisitCompositeLitType(node ast.Expr, indent int) {
 	pointerAndPosition := SearchPointerIndexReverse("@PreVisitCompositeLitType", re.gir.pointerAndIndexVec)
 	if pointerAndPosition != nil {
-		// For slice type aliases (like AST = []Statement), replace with Vec::new()
-		// The braces will be suppressed in PreVisitCompositeLitElts/PostVisitCompositeLitElts
+		// A slice or array literal converted to an interface has no expected
+		// type to infer its elements from, Rust would box []int{1} as a slice
+		// of i32: identity::<Slice<i64>>(slice!{1}) gives it one
+		if re.compLitIsBoxed && (re.isArray || re.currentCompLitIsSlice || re.insideArrayCompositeLit()) {
+			typeTokens, _ := ExtractTokensBetween(pointerAndPosition.Index, len(re.gir.tokenSlice), re.gir.tokenSlice)
+			typeStr := strings.Join(tokensToStrings(typeTokens), "")
+			defer func() {
+				re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, pointerAndPosition.Index, []string{"std::convert::identity::<" + typeStr + ">("})
+			}()
+		}
+		// Map literal: Map<K, V> -> Map::<K, V>::from
+		if re.insideMapCompositeLit() {
+			typeTokens, _ := ExtractTokensBetween(pointerAndPosition.Index, len(re.gir.tokenSlice), re.gir.tokenSlice)
+			typeStr := strings.Replace(strings.Join(tokensToStrings(typeTokens), ""), "Map<", "Map::<", 1)
+			re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, len(re.gir.tokenSlice), []string{typeStr, "::from"})
+			return
+		}
+		// Array literal: [N]T{a, b} -> [a, b], a declared variable keeps the
+		// type so that the elements get the element type
+		if re.insideArrayCompositeLit() {
+			newTokens := []string{}
+			start := pointerAndPosition.Index
+			if !(re.inKeyValueExpr || re.inFieldAssign || re.inReturnStmt || re.inPackageVarValue || re.compLitIsCallArg) {
+				typeTokens, _ := ExtractTokensBetween(pointerAndPosition.Index, len(re.gir.tokenSlice), re.gir.tokenSlice)
+				newTokens = append(newTokens, ":")
+				newTokens = append(newTokens, tokensToStrings(typeTokens)...)
+				newTokens = append(newTokens, " = ")
+				start = pointerAndPosition.Index - len("=") - len(" ")
+			}
+			re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, start, len(re.gir.tokenSlice), newTokens)
+			return
+		}
+		// An empty slice literal []T{} is an empty Slice with an array, unlike
+		// the nil Slice::new(). The braces will be suppressed in
+		// PreVisitCompositeLitElts/PostVisitCompositeLitElts
 		if re.currentCompLitIsSlice {
-			if re.inKeyValueExpr || re.inFieldAssign || re.inReturnStmt {
+			if re.inKeyValueExpr || re.inFieldAssign || re.inReturnStmt || re.inPackageVarValue || re.compLitIsCallArg {
 				// Inside struct field initialization, field assignment, or return statement
-				re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, len(re.gir.tokenSlice), []string{"Vec::new()"})
+				re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, len(re.gir.tokenSlice), []string{"Slice::from_vec(Vec::new())"})
 			} else {
-				// Variable declaration: let x = []Type{} -> let x: Vec<type> = Vec::new()
+				// Variable declaration: let x = []Type{} -> let x: Slice<type> = Slice::from_vec(Vec::new())
 				// Extract the type tokens for the type annotation
 				vecTypeStrRepr, _ := ExtractTokensBetween(pointerAndPosition.Index, len(re.gir.tokenSlice), re.gir.tokenSlice)
 				newTokens := []string{}
 				newTokens = append(newTokens, ":")
 				newTokens = append(newTokens, tokensToStrings(vecTypeStrRepr)...)
-				newTokens = append(newTokens, " = Vec::new()")
+				newTokens = append(newTokens, " = Slice::from_vec(Vec::new())")
 				re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index-len("=")-len(" "), len(re.gir.tokenSlice), newTokens)
 			}
 			return
@@ -3279,7 +6175,8 @@ func (re *RustEmitter) PostVisitCompositeLitType(node ast.Expr, indent int) {
 			if _, isNamed := typeInfo.Type.(*types.Named); !isNamed {
 				// Type is a basic/primitive type - check for alias replacement
 				for aliasName, alias := range re.aliases {
-					if alias.UnderlyingType == typeInfo.Type.Underlying().String() {
+					// Only aliases declared in the current package are in scope
+					if alias.UnderlyingType == typeInfo.Type.Underlying().String() && alias.PackageName == re.pkg.Name+".Api" && namesIdent(node, aliasName) {
 						re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, len(re.gir.tokenSlice), []string{aliasName})
 						break
 					}
@@ -3290,18 +6187,18 @@ func (re *RustEmitter) PostVisitCompositeLitType(node ast.Expr, indent int) {
 			// TODO that's still hack
 			// we operate on string representation of the type
 			// has to be rewritten to use some kind of IR
-			if re.inKeyValueExpr || re.inFieldAssign || re.inReturnStmt {
-				// Inside struct field initialization, field assignment, or return statement: []Type{} -> vec![]
-				// Just replace the type with vec!, keeping context intact
-				newTokens := []string{"vec!"}
+			if re.inKeyValueExpr || re.inFieldAssign || re.inReturnStmt || re.inPackageVarValue || re.compLitIsCallArg {
+				// Inside struct field initialization, field assignment, or return statement: []Type{} -> slice![]
+				// Just replace the type with slice!, keeping context intact
+				newTokens := []string{"slice!"}
 				re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index, len(re.gir.tokenSlice), newTokens)
 			} else {
-				// Variable declaration: let x = []Type{} -> let x: Vec<type> = vec![]
+				// Variable declaration: let x = []Type{} -> let x: Slice<type> = slice![]
 				vecTypeStrRepr, _ := ExtractTokensBetween(pointerAndPosition.Index, len(re.gir.tokenSlice), re.gir.tokenSlice)
 				newTokens := []string{}
 				newTokens = append(newTokens, ":")
 				newTokens = append(newTokens, tokensToStrings(vecTypeStrRepr)...)
-				newTokens = append(newTokens, " = vec!")
+				newTokens = append(newTokens, " = slice!")
 				re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, pointerAndPosition.Index-len("=")-len(" "), len(re.gir.tokenSlice), newTokens)
 			}
 		}
@@ -3309,10 +6206,18 @@ func (re *RustEmitter) PostVisitCompositeLitType(node ast.Expr, indent int) {
 }
 
 func (re *RustEmitter) PreVisitCompositeLitElts(node []ast.Expr, indent int) {
-	// Skip braces for slice type aliases - Vec::new() is already emitted
+	if re.insideMapCompositeLit() {
+		re.gir.emitToFileBuffer("([", EmptyVisitMethod)
+		return
+	}
+	// Skip braces for empty slice literals - the empty Slice is already emitted
 	if re.currentCompLitIsSlice {
 		return
 	}
+	if re.insideArrayCompositeLit() {
+		re.emitToken("[", LeftBracket, 0)
+		return
+	}
 	re.emitToken("{", LeftBrace, 0)
 }
 
@@ -3339,8 +6244,19 @@ func (re *RustEmitter) PostVisitCompositeLitElts(node []ast.Expr, indent int) {
 		}
 	}
 
-	// Skip braces and default for slice type aliases - Vec::new() is already emitted
-	if re.currentCompLitIsSlice {
+	// Skip braces and default for empty slice literals - the empty Slice is already emitted
+	if re.insideMapCompositeLit() {
+		re.gir.emitToFileBuffer("])", EmptyVisitMethod)
+	} else if re.insideArrayCompositeLit() {
+		// Elements missing from the literal are zero
+		for i := int64(len(node)); i < currentType.Underlying().(*types.Array).Len(); i++ {
+			if i > 0 {
+				re.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+			}
+			re.gir.emitToFileBuffer("Default::default()", EmptyVisitMethod)
+		}
+		re.emitToken("]", RightBracket, 0)
+	} else if re.currentCompLitIsSlice {
 		re.currentCompLitIsSlice = false
 	} else {
 		if !re.isArray && !isSliceType && !typeHasNoDefault {
@@ -3364,6 +6280,23 @@ func (re *RustEmitter) PostVisitCompositeLitElts(node []ast.Expr, indent int) {
 	if len(re.compLitTypeStack) > 0 {
 		re.compLitTypeStack = re.compLitTypeStack[:len(re.compLitTypeStack)-1]
 	}
+	if len(re.mapCompositeLits) > 0 {
+		re.mapCompositeLits = re.mapCompositeLits[:len(re.mapCompositeLits)-1]
+	}
+	if len(re.arrayCompositeLits) > 0 {
+		re.arrayCompositeLits = re.arrayCompositeLits[:len(re.arrayCompositeLits)-1]
+	}
+}
+
+// PostVisitCompositeLit closes the identity call of a boxed slice or array literal
+func (re *RustEmitter) PostVisitCompositeLit(node *ast.CompositeLit, indent int) {
+	if _, boxed := re.interfaceConversions[node]; !boxed {
+		return
+	}
+	switch re.pkg.TypesInfo.TypeOf(node).Underlying().(type) {
+	case *types.Slice, *types.Array:
+		re.gir.emitToFileBuffer(")", EmptyVisitMethod)
+	}
 }
 
 func (re *RustEmitter) PreVisitCompositeLitElt(node ast.Expr, index int, indent int) {
@@ -3371,49 +6304,93 @@ func (re *RustEmitter) PreVisitCompositeLitElt(node ast.Expr, index int, indent
 		str := re.emitAsString(", ", 0)
 		re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 	}
+	re.openInterfaceConversion(node)
+}
+
+func (re *RustEmitter) PostVisitCompositeLitElt(node ast.Expr, index int, indent int) {
+	// A pointer or function variable stays usable after its copy is stored
+	if _, isIdent := node.(*ast.Ident); (isIdent && isPointerExpr(re.pkg, node)) || re.isFuncValueCopy(node) {
+		re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+	}
+	re.closeInterfaceConversion(node)
+}
+
+// Slice expressions call the SliceExpr methods of the runtime:
+// a[low:high] -> a.slice(low, high), a[low:] -> a.slice_from(low) and
+// a[low:high:max] -> a.slice3(low, high, max)
+func (re *RustEmitter) PreVisitSliceExpr(node *ast.SliceExpr, indent int) {
+	re.sliceExprs = append(re.sliceExprs, node)
+}
+
+func (re *RustEmitter) PostVisitSliceExprX(node ast.Expr, indent int) {
+	sliceExpr := re.sliceExprs[len(re.sliceExprs)-1]
+	switch {
+	case sliceExpr.Slice3:
+		re.gir.emitToFileBuffer(".slice3", EmptyVisitMethod)
+	case sliceExpr.High == nil:
+		re.gir.emitToFileBuffer(".slice_from", EmptyVisitMethod)
+	default:
+		re.gir.emitToFileBuffer(".slice", EmptyVisitMethod)
+	}
+	re.emitToken("(", LeftParen, 0)
+}
+
+func (re *RustEmitter) PostVisitSliceExpr(node *ast.SliceExpr, indent int) {
+	re.emitToken(")", RightParen, 0)
+	re.sliceExprs = re.sliceExprs[:len(re.sliceExprs)-1]
+}
+
+func (re *RustEmitter) PreVisitSliceExprLow(node ast.Expr, indent int) {
+	if node == nil {
+		re.gir.emitToFileBuffer("0", EmptyVisitMethod)
+	}
+	re.openSliceBound(node)
+}
+
+func (re *RustEmitter) PostVisitSliceExprLow(node ast.Expr, indent int) {
+	re.closeSliceBound(node)
 }
 
-func (re *RustEmitter) PreVisitSliceExpr(node *ast.SliceExpr, indent int) {
-	// Don't add & - we'll add .to_vec() at the end to get Vec back
+func (re *RustEmitter) PreVisitSliceExprHigh(node ast.Expr, indent int) {
+	if node != nil {
+		re.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+	}
+	re.openSliceBound(node)
 }
 
-func (re *RustEmitter) PostVisitSliceExprX(node ast.Expr, indent int) {
-	re.emitToken("[", LeftBracket, 0)
-	re.shouldGenerate = false
+func (re *RustEmitter) PostVisitSliceExprHigh(node ast.Expr, indent int) {
+	re.closeSliceBound(node)
 }
 
-func (re *RustEmitter) PostVisitSliceExpr(node *ast.SliceExpr, indent int) {
-	re.emitToken("]", RightBracket, 0)
-	// Convert slice to Vec to match Go semantics
-	re.gir.emitToFileBuffer(".to_vec()", EmptyVisitMethod)
-	re.shouldGenerate = true
+func (re *RustEmitter) PreVisitSliceExprMax(node ast.Expr, indent int) {
+	re.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+	re.openSliceBound(node)
 }
 
-func (re *RustEmitter) PreVisitSliceExprLow(node ast.Expr, indent int) {
-	// Re-enable generation for the low index expression
-	re.shouldGenerate = true
+func (re *RustEmitter) PostVisitSliceExprMax(node ast.Expr, indent int) {
+	re.closeSliceBound(node)
 }
 
-func (re *RustEmitter) PostVisitSliceExprLow(node ast.Expr, indent int) {
-	// Cast to usize for slice indexing (Rust requires usize for slice indices)
-	if node != nil {
-		re.gir.emitToFileBuffer(" as usize", EmptyVisitMethod)
+// Slice bounds are Ints, bounds of other integer types are converted
+func (re *RustEmitter) openSliceBound(node ast.Expr) {
+	if re.isNonIntBound(node) {
+		re.emitToken("(", LeftParen, 0)
 	}
-	re.gir.emitToFileBuffer("..", EmptyVisitMethod)
-	re.shouldGenerate = false
 }
 
-func (re *RustEmitter) PreVisitSliceExprHigh(node ast.Expr, indent int) {
-	// Re-enable generation for the high index expression
-	re.shouldGenerate = true
+func (re *RustEmitter) closeSliceBound(node ast.Expr) {
+	if re.isNonIntBound(node) {
+		re.emitToken(")", RightParen, 0)
+		re.gir.emitToFileBuffer(" as Int", EmptyVisitMethod)
+	}
 }
 
-func (re *RustEmitter) PostVisitSliceExprHigh(node ast.Expr, indent int) {
-	// Cast to usize for slice indexing (Rust requires usize for slice indices)
-	if node != nil {
-		re.gir.emitToFileBuffer(" as usize", EmptyVisitMethod)
+func (re *RustEmitter) isNonIntBound(node ast.Expr) bool {
+	if node == nil {
+		return false
 	}
-	re.shouldGenerate = false
+	basic, ok := re.pkg.TypesInfo.TypeOf(node).Underlying().(*types.Basic)
+	return ok && basic.Kind() != types.Int && basic.Kind() != types.UntypedInt
 }
 
 func (re *RustEmitter) PreVisitFuncLit(node *ast.FuncLit, indent int) {
@@ -3437,16 +6414,37 @@ func (re *RustEmitter) PreVisitFuncLit(node *ast.FuncLit, indent int) {
 			}
 		}
 	}
-	// Only wrap closure with Rc::new() when NOT passed as a direct call argument
-	// Functions that take closure arguments expect FnMut, not Rc<dyn Fn>
-	if !re.inCallExprArg {
-		wrapperStr := "Rc::new("
-		str := re.emitAsString(wrapperStr, 0)
-		re.gir.emitToFileBuffer(str, EmptyVisitMethod)
-		re.closureWrappedInRc = true
-	} else {
-		re.closureWrappedInRc = false
+	// A closure used as a function value is wrapped in Some(Rc::new()), it
+	// may outlive the function so it owns clones of what it captures. Clones
+	// of the variables kept in Shared cells share the value.
+	end := ""
+	switch {
+	case re.calledFuncLits[node]:
+		re.gir.emitToFileBuffer("(", EmptyVisitMethod)
+		end = ")"
+	case !re.bareFuncLits[node]:
+		str := ""
+		for _, v := range capturedVars(re.pkg, node) {
+			if re.closureCells.vars[v] || isMovedType(v.Type()) {
+				name := escapeRustKeyword(v.Name())
+				str += fmt.Sprintf("let %s = %s.clone(); ", name, name)
+			}
+		}
+		if str != "" {
+			re.gir.emitToFileBuffer("{ "+str, EmptyVisitMethod)
+			end = " }"
+		}
+		// A bound closure has no expected type to be coerced to
+		if arity := node.Type.Params.NumFields(); re.boundFuncLits[node] && arity <= 4 {
+			re.gir.emitToFileBuffer(fmt.Sprintf("func%d(move ", arity), EmptyVisitMethod)
+			end = ")" + end
+		} else {
+			re.gir.emitToFileBuffer("Some(Rc::new(move ", EmptyVisitMethod)
+			end = "))" + end
+		}
 	}
+	re.closureWrapperEnds = append(re.closureWrapperEnds, end)
+	re.closureShouldGenerate = append(re.closureShouldGenerate, re.shouldGenerate)
 	re.emitToken("|", Identifier, indent)
 }
 func (re *RustEmitter) PostVisitFuncLit(node *ast.FuncLit, indent int) {
@@ -3465,12 +6463,17 @@ func (re *RustEmitter) PostVisitFuncLit(node *ast.FuncLit, indent int) {
 		return
 	}
 	re.emitToken("}", RightBrace, 0)
-	// Close the Rc::new() wrapper only if it was opened
-	if re.closureWrappedInRc {
-		re.emitToken(")", RightParen, 0)
+	// Close the wrapper if one was opened
+	if n := len(re.closureWrapperEnds); n > 0 {
+		if end := re.closureWrapperEnds[n-1]; end != "" {
+			re.emitToken(end, RightParen, 0)
+		}
+		re.closureWrapperEnds = re.closureWrapperEnds[:n-1]
+		// The statements of the body leave the flag of the enclosing expression
+		re.shouldGenerate = re.closureShouldGenerate[n-1]
+		re.closureShouldGenerate = re.closureShouldGenerate[:n-1]
 	}
 	re.currentFuncReturnsAny = false
-	re.closureWrappedInRc = false
 }
 
 func (re *RustEmitter) PostVisitFuncLitTypeParams(node *ast.FieldList, indent int) {
@@ -3500,6 +6503,9 @@ func (re *RustEmitter) PostVisitFuncLitTypeParam(node *ast.Field, index int, ind
 }
 
 func (re *RustEmitter) PreVisitFuncLitBody(node *ast.BlockStmt, indent int) {
+	if !re.forwardDecls {
+		re.deferScopes = pushDeferScope(re.pkg, re.deferScopes, node, &re.deferCount)
+	}
 	// For local closures being inlined, skip wrapper emission but record body start
 	if re.localClosureAssign && re.currentClosureName != "" {
 		re.localClosureBodyStartIndex = len(re.gir.tokenSlice)
@@ -3513,9 +6519,19 @@ func (re *RustEmitter) PreVisitFuncLitBody(node *ast.BlockStmt, indent int) {
 
 func (re *RustEmitter) PreVisitFuncLitTypeResults(node *ast.FieldList, indent int) {
 	re.shouldGenerate = false
+	re.funcLitResultsStart = len(re.gir.tokenSlice)
+}
+
+// The result types of a closure are inferred, function types among them are
+// emitted regardless of shouldGenerate and dropped here
+func (re *RustEmitter) PostVisitFuncLitTypeResults(node *ast.FieldList, indent int) {
+	re.gir.tokenSlice = re.gir.tokenSlice[:re.funcLitResultsStart]
 }
 
 func (re *RustEmitter) PreVisitInterfaceType(node *ast.InterfaceType, indent int) {
+	if re.forwardDecls {
+		return
+	}
 	str := re.emitAsString("Box<dyn Any>", indent)
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 }
@@ -3523,71 +6539,496 @@ func (re *RustEmitter) PreVisitInterfaceType(node *ast.InterfaceType, indent int
 func (re *RustEmitter) PostVisitInterfaceType(node *ast.InterfaceType, indent int) {
 }
 
-func (re *RustEmitter) PreVisitKeyValueExprValue(node ast.Expr, indent int) {
-	// In Rust struct initialization, use `:` not `=`
-	str := re.emitAsString(": ", 0)
-	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+func (re *RustEmitter) PreVisitMapType(node *ast.MapType, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.emitToken("Map", Identifier, 0)
+	re.emitToken("<", LeftAngle, 0)
+}
+
+func (re *RustEmitter) PostVisitMapKeyType(node ast.Expr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.emitToken(",", Comma, 0)
+	re.emitToken(" ", WhiteSpace, 0)
+}
+
+func (re *RustEmitter) PostVisitMapType(node *ast.MapType, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.emitToken(">", RightAngle, 0)
+}
+
+func (re *RustEmitter) PreVisitChanType(node *ast.ChanType, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.emitToken("Chan", Identifier, 0)
+	re.emitToken("<", LeftAngle, 0)
+}
+
+func (re *RustEmitter) PostVisitChanType(node *ast.ChanType, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.emitToken(">", RightAngle, 0)
+}
+
+func (re *RustEmitter) PreVisitKeyValueExprValue(node ast.Expr, indent int) {
+	// Map literal entries are (key, value) tuples
+	if re.insideMapCompositeLit() {
+		re.gir.emitToFileBuffer(", ", EmptyVisitMethod)
+		re.openInterfaceConversion(node)
+		return
+	}
+	// In Rust struct initialization, use `:` not `=`
+	str := re.emitAsString(": ", 0)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	re.openInterfaceConversion(node)
+}
+
+func (re *RustEmitter) PostVisitKeyValueExprValue(node ast.Expr, indent int) {
+	defer re.closeInterfaceConversion(node)
+	// Add .clone() for non-Copy types in struct field assignments
+	// This is needed because Rust closures that move values become FnOnce, not Fn
+	// For slices (Slice in Rust) and strings, we need to clone to avoid moving the captured variable
+	if node != nil {
+		tv := re.pkg.TypesInfo.Types[node]
+		if tv.Type != nil {
+			typeStr := tv.Type.String()
+			// Check if it's a slice type (will become Slice in Rust), string type or type parameter
+			_, isParam := tv.Type.(*types.TypeParam)
+			_, isMap := tv.Type.Underlying().(*types.Map)
+			_, isChan := tv.Type.Underlying().(*types.Chan)
+			if strings.HasPrefix(typeStr, "[]") || typeStr == "string" || isParam || isMap || isChan || isStructPointer(tv.Type) || re.isFuncValueCopy(node) {
+				re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+			}
+		}
+	}
+}
+
+func (re *RustEmitter) PreVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
+	// &x allocates a copy of x
+	if node.Op == token.AND {
+		re.gir.emitToFileBuffer("Ptr::new(", EmptyVisitMethod)
+		return
+	}
+	if node.Op == token.ARROW {
+		if node == re.chanRecvCommaOk {
+			re.gir.emitToFileBuffer("chan_recv_ok", EmptyVisitMethod)
+		} else {
+			re.gir.emitToFileBuffer("chan_recv", EmptyVisitMethod)
+		}
+		re.emitToken("(", LeftParen, 0)
+		re.gir.emitToFileBuffer("&", EmptyVisitMethod)
+		return
+	}
+	re.emitToken("(", LeftParen, 0)
+	op := node.Op.String()
+	if node.Op == token.XOR {
+		op = "!"
+	} else if basic := intOpType(re.pkg, node); basic != nil {
+		// -x of the most negative value is itself, and unsigned -x wraps
+		op = re.mapGoTypeToRust(intTypeName(basic)) + "::wrapping_neg("
+	}
+	str := re.emitAsString(op, 0)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+func (re *RustEmitter) PostVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
+	if node.Op == token.SUB && intOpType(re.pkg, node) != nil {
+		re.emitToken(")", RightParen, 0)
+	}
+	if node.Op == token.AND {
+		switch node.X.(type) {
+		case *ast.CompositeLit, *ast.CallExpr:
+		default:
+			re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+		}
+	}
+	re.emitToken(")", RightParen, 0)
+}
+
+// PreVisitStarExpr emits a pointer type *T as Ptr<T>. A dereference *p reads
+// a copy of the value, or borrows it mutably when assigned to. The receiver
+// of a pointer method is a mutable reference already
+func (re *RustEmitter) PreVisitStarExpr(node *ast.StarExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if isStructPointer(re.pkg.TypesInfo.TypeOf(node)) {
+		re.gir.emitToFileBuffer(re.emitAsString("Ptr<", indent), EmptyVisitMethod)
+	} else if re.pointerPlaces[node] || isPointerRecvExpr(re.pkg, re.currentFuncDecl, node.X) {
+		re.gir.emitToFileBuffer("*", EmptyVisitMethod)
+	}
+}
+
+func (re *RustEmitter) PostVisitStarExpr(node *ast.StarExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if isStructPointer(re.pkg.TypesInfo.TypeOf(node)) {
+		re.gir.emitToFileBuffer(">", EmptyVisitMethod)
+	} else if re.pointerPlaces[node] {
+		re.gir.emitToFileBuffer(".borrow_mut()", EmptyVisitMethod)
+	} else if !isPointerRecvExpr(re.pkg, re.currentFuncDecl, node.X) {
+		re.gir.emitToFileBuffer(".with_borrow(|__p| __p.clone())", EmptyVisitMethod)
+	}
+}
+
+// markPointerPlaces records the dereference an assignment target goes
+// through last, which borrows the value mutably: p.f, p.s[i], *p
+func (re *RustEmitter) markPointerPlaces(lhs []ast.Expr) {
+	if re.pointerPlaces == nil {
+		re.pointerPlaces = make(map[ast.Expr]bool)
+	}
+	for _, expr := range lhs {
+		re.markPointerPlace(expr)
+	}
+}
+
+func (re *RustEmitter) markPointerPlace(expr ast.Expr) {
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			if !isPointerRecvExpr(re.pkg, re.currentFuncDecl, e.X) {
+				re.pointerPlaces[e] = true
+			}
+			return
+		case *ast.SelectorExpr:
+			if isPointerExpr(re.pkg, e.X) {
+				if !isPointerRecvExpr(re.pkg, re.currentFuncDecl, e.X) {
+					re.pointerPlaces[e] = true
+				}
+				return
+			}
+			expr = e.X
+		case *ast.IndexExpr:
+			if isMapIndexExpr(re.pkg, e) {
+				return
+			}
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		default:
+			return
+		}
+	}
+}
+
+// PreVisitSelectorExpr selects a field or method through a pointer: a field
+// is read inside with_borrow, assigned through borrow_mut, and a method is
+// called inside with_borrow or with_borrow_mut
+func (re *RustEmitter) PreVisitSelectorExpr(node *ast.SelectorExpr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if !isPointerExpr(re.pkg, node.X) || isPointerRecvExpr(re.pkg, re.currentFuncDecl, node.X) {
+		re.pointerSelectors = append(re.pointerSelectors, "")
+		return
+	}
+	switch {
+	case re.pointerPlaces[node]:
+		re.pointerSelectors = append(re.pointerSelectors, ".borrow_mut().")
+	case isPointerMethodSelector(re.pkg, node):
+		re.pointerSelectors = append(re.pointerSelectors, ".with_borrow_mut(|__p| __p.")
+	case isMethodSelector(re.pkg, node):
+		re.pointerSelectors = append(re.pointerSelectors, ".with_borrow(|__p| __p.")
+	default:
+		re.pointerSelectors = append(re.pointerSelectors, ".with_borrow(|__p| __p.")
+	}
+}
+
+func (re *RustEmitter) PostVisitSelectorExpr(node *ast.SelectorExpr, indent int) {
+	if re.forwardDecls || len(re.pointerSelectors) == 0 {
+		return
+	}
+	open := re.pointerSelectors[len(re.pointerSelectors)-1]
+	re.pointerSelectors = re.pointerSelectors[:len(re.pointerSelectors)-1]
+	if open == "" || re.pointerPlaces[node] || isMethodSelector(re.pkg, node) {
+		return
+	}
+	closing := ".clone())"
+	if re.captureRangeExpr {
+		re.rangeCollectionExpr += closing
+		return
+	}
+	re.gir.emitToFileBuffer(closing, EmptyVisitMethod)
+}
+
+// pointerSelector returns the operator selecting through X, the
+// pointer of the innermost selector, or "" when X is not a pointer
+func (re *RustEmitter) pointerSelector() string {
+	if len(re.pointerSelectors) == 0 {
+		return ""
+	}
+	return re.pointerSelectors[len(re.pointerSelectors)-1]
+}
+
+func (re *RustEmitter) PreVisitGenDeclConstName(node *ast.Ident, indent int) {
+	// TODO dummy implementation
+	// not very well performed
+	for constIdent, obj := range re.pkg.TypesInfo.Defs {
+		if obj == nil {
+			continue
+		}
+		if con, ok := obj.(*types.Const); ok {
+			if constIdent.Name != node.Name {
+				continue
+			}
+			constType := con.Type().String()
+			constType = strings.TrimPrefix(constType, "untyped ")
+			if constType == re.pkg.TypesInfo.Defs[node].Type().String() {
+				constType = trimBeforeChar(constType, '.')
+			}
+
+			// Map Go types to Rust types for constants
+			// Keep untyped int as int since Go's implicit type conversion at usage
+			// sites will be handled by explicit casts in binary expressions
+			rustType := re.mapGoTypeToRust(constType)
+			str := re.emitAsString(fmt.Sprintf("pub const %s: %s = ", node.Name, rustType), 0)
+
+			re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+		}
+	}
+}
+func (re *RustEmitter) PostVisitGenDeclConstName(node *ast.Ident, indent int) {
+	str := re.emitAsString(";\n", 0)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+func (re *RustEmitter) PostVisitGenDeclConst(node *ast.GenDecl, indent int) {
+	str := re.emitAsString("\n", 0)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
+// Package-level variables become PackageVar statics holding a RefCell. They
+// are initialized lazily on first access, so main forces them in Go's init order.
+func (re *RustEmitter) PreVisitGenDeclVars(node []PackageVar, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = true
+}
+
+func (re *RustEmitter) PostVisitGenDeclVars(node []PackageVar, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = false
+	str := re.emitAsString("\n", indent)
+	str += re.emitAsString("pub fn init_package_vars() {\n", indent)
+	for _, v := range node {
+		if v.Value != nil {
+			str += re.emitAsString(fmt.Sprintf("%s.with(|_| {});\n", packageVarStatic(v.Name.Name)), indent+2)
+		}
+	}
+	str += re.emitAsString("}\n\n", indent)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+	call := "init_package_vars();"
+	if re.currentPackage != "main" {
+		call = re.currentPackage + "::" + call
+	}
+	re.pkgVarInitCalls = append(re.pkgVarInitCalls, call)
+}
+
+func (re *RustEmitter) PreVisitGenDeclVar(node PackageVar, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.currentPackageVar = node
+	re.isArray = false
+	str := re.emitAsString(fmt.Sprintf("pub static %s: PackageVar<", packageVarStatic(node.Name.Name)), indent)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitGenDeclVarType(node ast.Expr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer("> = PackageVar::new(|| ", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitGenDeclVarValue(node ast.Expr, indent int) {
+	re.inPackageVarValue = true
+}
+
+func (re *RustEmitter) PostVisitGenDeclVarValue(node ast.Expr, indent int) {
+	re.inPackageVarValue = false
+}
+
+func (re *RustEmitter) PostVisitGenDeclVar(node PackageVar, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if node.Value == nil {
+		re.gir.emitToFileBuffer("Default::default()", EmptyVisitMethod)
+	}
+	re.gir.emitToFileBuffer(");\n", EmptyVisitMethod)
+}
+
+// isCellVar reports whether a variable lives in a cell: a package-level
+// variable, a named result shared with deferred calls or a local variable
+// shared with closures
+func (re *RustEmitter) isCellVar(obj types.Object) bool {
+	return isPackageVar(obj) || re.sharedResults[obj] || re.closureCells.vars[obj]
+}
+
+// packageVarStatic returns the name of the static holding a package-level
+// variable. Rust doesn't allow parameters and locals to shadow a static, as
+// Go allows them to shadow a package-level variable.
+func packageVarStatic(name string) string {
+	return "__pkg_" + name
+}
+
+// cellName returns the name of the cell holding a variable
+func (re *RustEmitter) cellName(e *ast.Ident) string {
+	if isPackageVar(re.pkg.TypesInfo.Uses[e]) {
+		return packageVarStatic(e.Name)
+	}
+	return escapeRustKeyword(e.Name)
+}
+
+// closureCellDecls moves the variables declared by node, a statement or the
+// block of their function or range loop, into Shared cells
+func (re *RustEmitter) closureCellDecls(node ast.Node, indent int, sep string) string {
+	str := ""
+	for _, obj := range re.closureCells.sites[node] {
+		name := escapeRustKeyword(obj.Name())
+		str += re.emitAsString(fmt.Sprintf(" let %s = Shared::new(%s);%s", name, name, sep), indent)
+	}
+	return str
+}
+
+// slicedArrayDecls moves the sliced arrays declared by node, a statement, into
+// the array of a Slice shadowing them, which their slices share
+func (re *RustEmitter) slicedArrayDecls(node ast.Node) string {
+	str := ""
+	for _, ident := range declaredIdents(node) {
+		if isSlicedArray(re.pkg, re.pkg.TypesInfo.Defs[ident]) {
+			name := escapeRustKeyword(ident.Name)
+			str += fmt.Sprintf(" let %s = Slice::from_vec(Vec::from(%s));", name, name)
+		}
+	}
+	return str
+}
+
+// slicedArrayUse returns how a use of a sliced array reaches it: through the
+// array of its Slice, which is sliced itself by a slice expression
+func (re *RustEmitter) slicedArrayUse(e *ast.Ident, name string) string {
+	obj := re.pkg.TypesInfo.Uses[e]
+	if !isSlicedArray(re.pkg, obj) {
+		return name
+	}
+	if n := len(re.sliceExprs); n > 0 && ast.Unparen(re.sliceExprs[n-1].X) == e {
+		return name
+	}
+	return fmt.Sprintf("(*%s.as_array::<%d>())", name, obj.Type().Underlying().(*types.Array).Len())
 }
 
-func (re *RustEmitter) PostVisitKeyValueExprValue(node ast.Expr, indent int) {
-	// Add .clone() for non-Copy types in struct field assignments
-	// This is needed because Rust closures that move values become FnOnce, not Fn
-	// For slices (Vec in Rust) and strings, we need to clone to avoid moving the captured variable
-	if node != nil {
-		tv := re.pkg.TypesInfo.Types[node]
-		if tv.Type != nil {
-			typeStr := tv.Type.String()
-			// Check if it's a slice type (will become Vec in Rust) or string type
-			if strings.HasPrefix(typeStr, "[]") || typeStr == "string" {
-				re.gir.emitToFileBuffer(".clone()", EmptyVisitMethod)
+// packageVarRoot returns the variable of the current package that an
+// assignable expression such as counter, names[i] or config.depth is rooted at
+func (re *RustEmitter) packageVarRoot(expr ast.Expr) *ast.Ident {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			if obj := re.pkg.TypesInfo.Uses[e]; re.isCellVar(obj) && obj.Pkg() == re.pkg.Types {
+				return e
 			}
+			return nil
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.SelectorExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.StarExpr:
+			expr = e.X
+		default:
+			return nil
 		}
 	}
 }
 
-func (re *RustEmitter) PreVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
-	re.emitToken("(", LeftParen, 0)
-	str := re.emitAsString(node.Op.String(), 0)
-	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
-}
-func (re *RustEmitter) PostVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
-	re.emitToken(")", RightParen, 0)
+// packageVarAccess returns how a package-level variable is accessed: a
+// statement writing to it works on a local copy that is stored back afterwards,
+// an indexed read borrows the element and any other read clones the value
+func (re *RustEmitter) packageVarAccess(e *ast.Ident) (string, bool) {
+	if !re.isCellVar(re.pkg.TypesInfo.Uses[e]) {
+		return "", false
+	}
+	name := re.cellName(e)
+	if re.pkgVarWrites[e] {
+		return "__" + e.Name, true
+	}
+	if re.pkgVarIndexed[e] {
+		return name + ".with_borrow(|v| v", true
+	}
+	return name + ".with_borrow(|v| v.clone())", true
 }
 
-func (re *RustEmitter) PreVisitGenDeclConstName(node *ast.Ident, indent int) {
-	// TODO dummy implementation
-	// not very well performed
-	for constIdent, obj := range re.pkg.TypesInfo.Defs {
-		if obj == nil {
-			continue
-		}
-		if con, ok := obj.(*types.Const); ok {
-			if constIdent.Name != node.Name {
-				continue
-			}
-			constType := con.Type().String()
-			constType = strings.TrimPrefix(constType, "untyped ")
-			if constType == re.pkg.TypesInfo.Defs[node].Type().String() {
-				constType = trimBeforeChar(constType, '.')
+// openPackageVarWrites binds a local copy of each package-level variable
+// written by a statement
+func (re *RustEmitter) openPackageVarWrites(lhs []ast.Expr, indent int) {
+	var written []*ast.Ident
+	if !re.insideForPostCond {
+		for _, expr := range lhs {
+			if root := re.packageVarRoot(expr); root != nil {
+				written = append(written, root)
 			}
-
-			// Map Go types to Rust types for constants
-			// Keep untyped int as i32 since Go's implicit type conversion at usage
-			// sites will be handled by explicit casts in binary expressions
-			rustType := re.mapGoTypeToRust(constType)
-			str := re.emitAsString(fmt.Sprintf("pub const %s: %s = ", node.Name, rustType), 0)
-
-			re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 		}
 	}
-}
-func (re *RustEmitter) PostVisitGenDeclConstName(node *ast.Ident, indent int) {
-	str := re.emitAsString(";\n", 0)
+	re.pkgVarWriteStack = append(re.pkgVarWriteStack, written)
+	if len(written) == 0 {
+		return
+	}
+	if re.pkgVarWrites == nil {
+		re.pkgVarWrites = make(map[*ast.Ident]bool)
+	}
+	str := re.emitAsString("{ ", indent)
+	for _, root := range written {
+		re.pkgVarWrites[root] = true
+		str += fmt.Sprintf("let mut __%s = %s.with_borrow(|v| v.clone()); ", root.Name, re.cellName(root))
+	}
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 }
-func (re *RustEmitter) PostVisitGenDeclConst(node *ast.GenDecl, indent int) {
-	str := re.emitAsString("\n", 0)
-	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+
+// closePackageVarWrites stores the local copies back into the variables
+func (re *RustEmitter) closePackageVarWrites() {
+	written := re.pkgVarWriteStack[len(re.pkgVarWriteStack)-1]
+	re.pkgVarWriteStack = re.pkgVarWriteStack[:len(re.pkgVarWriteStack)-1]
+	if len(written) == 0 {
+		return
+	}
+	var str string
+	for _, root := range written {
+		delete(re.pkgVarWrites, root)
+		str += fmt.Sprintf(" %s.set(__%s);", re.cellName(root), root.Name)
+	}
+	re.gir.emitToFileBuffer(str+" }", EmptyVisitMethod)
+}
+
+// pointerMethodRecv returns the receiver of a call to a pointer method
+func (re *RustEmitter) pointerMethodRecv(expr ast.Expr) ast.Expr {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !isPointerMethodSelector(re.pkg, sel) {
+		return nil
+	}
+	return sel.X
+}
+
+// rustTypeSwitch is a type switch being lowered to an if-else chain of
+// downcasts on a reference to the switch value
+type rustTypeSwitch struct {
+	name      string   // Reference to the switch value
+	iface     bool     // The value is an interface wrapper, not Box<dyn Any>
+	typeStart int      // Token index where the current case type starts
+	caseTypes []string // Types of the current case clause
 }
 
 func (re *RustEmitter) PreVisitSwitchStmt(node *ast.SwitchStmt, indent int) {
@@ -3617,15 +7058,15 @@ func (re *RustEmitter) PostVisitSwitchStmt(node *ast.SwitchStmt, indent int) {
 }
 
 func (re *RustEmitter) PostVisitSwitchStmtTag(node ast.Expr, indent int) {
-	// Check if we need to cast to i32 to match constants (which are i32 by default)
+	// Check if we need to cast to int to match constants (which are int by default)
 	if node != nil {
 		tv := re.pkg.TypesInfo.Types[node]
 		if tv.Type != nil {
 			typeStr := tv.Type.String()
-			// Cast smaller integer types to i32 so they match constant types
+			// Cast smaller integer types to int so they match constant types
 			if typeStr == "int8" || typeStr == "uint8" ||
 				typeStr == "int16" || typeStr == "uint16" {
-				str := re.emitAsString(" as i32", 0)
+				str := re.emitAsString(" as "+rustTypesMap["int"], 0)
 				re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 			}
 		}
@@ -3665,6 +7106,122 @@ func (re *RustEmitter) PostVisitCaseClauseListExpr(node ast.Expr, index int, ind
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 }
 
+// Type switches become an if-else chain of downcast_ref::<T>() tests on a
+// reference to the switch value
+func (re *RustEmitter) PreVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.typeSwitchCount++
+	x, _ := typeSwitchSubject(node)
+	re.typeSwitches = append(re.typeSwitches, rustTypeSwitch{
+		name:  fmt.Sprintf("_ts%d", re.typeSwitchCount),
+		iface: isNonEmptyInterface(re.pkg.TypesInfo.TypeOf(x)),
+	})
+	re.gir.emitToFileBuffer(re.emitAsString("{\n", indent), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.typeSwitches = re.typeSwitches[:len(re.typeSwitches)-1]
+	re.gir.emitToFileBuffer("\n"+re.emitAsString("}", indent), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) currentTypeSwitch() *rustTypeSwitch {
+	return &re.typeSwitches[len(re.typeSwitches)-1]
+}
+
+func (re *RustEmitter) PreVisitTypeSwitchStmtX(node ast.Expr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = true
+	str := re.emitAsString("let "+re.currentTypeSwitch().name+" = &", indent+2)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PostVisitTypeSwitchStmtX(node ast.Expr, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(";\n", EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.currentTypeSwitch().caseTypes = nil
+	switch {
+	case index == 0 && node.List == nil:
+		re.gir.emitToFileBuffer(re.emitAsString("", indent+2), EmptyVisitMethod)
+	case index == 0:
+		re.gir.emitToFileBuffer(re.emitAsString("if ", indent+2), EmptyVisitMethod)
+	case node.List == nil:
+		re.gir.emitToFileBuffer(" else ", EmptyVisitMethod)
+	default:
+		re.gir.emitToFileBuffer(" else if ", EmptyVisitMethod)
+	}
+}
+
+func (re *RustEmitter) PostVisitTypeSwitchCaseClause(node *ast.CaseClause, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.gir.emitToFileBuffer(re.emitAsString("}", indent+2), EmptyVisitMethod)
+}
+
+func (re *RustEmitter) PreVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	re.shouldGenerate = true
+	ts := re.currentTypeSwitch()
+	if index > 0 {
+		re.gir.emitToFileBuffer(" || ", EmptyVisitMethod)
+	}
+	re.gir.emitToFileBuffer(ts.name+".downcast_ref::<", EmptyVisitMethod)
+	ts.typeStart = len(re.gir.tokenSlice)
+}
+
+func (re *RustEmitter) PostVisitTypeSwitchCaseClauseType(node ast.Expr, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	ts := re.currentTypeSwitch()
+	typeTokens, _ := ExtractTokensBetween(ts.typeStart, len(re.gir.tokenSlice), re.gir.tokenSlice)
+	ts.caseTypes = append(ts.caseTypes, strings.TrimSpace(strings.Join(tokensToStrings(typeTokens), "")))
+	re.gir.emitToFileBuffer(">().is_some()", EmptyVisitMethod)
+}
+
+// PreVisitTypeSwitchCaseClauseBody opens the clause and binds the switch
+// variable: to the downcast value in single type clauses, to the switch value
+// otherwise
+func (re *RustEmitter) PreVisitTypeSwitchCaseClauseBody(node *ast.CaseClause, index int, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if node.List != nil {
+		re.gir.emitToFileBuffer(" ", EmptyVisitMethod)
+	}
+	re.gir.emitToFileBuffer("{\n", EmptyVisitMethod)
+	obj := typeSwitchClauseVar(re.pkg, node)
+	if obj == nil {
+		return
+	}
+	ts := re.currentTypeSwitch()
+	value := ts.name
+	if len(ts.caseTypes) == 1 {
+		value = fmt.Sprintf("%s.downcast_ref::<%s>().unwrap().clone()", ts.name, ts.caseTypes[0])
+	} else if ts.iface {
+		value = ts.name + ".clone()"
+	}
+	str := re.emitAsString(fmt.Sprintf("let mut %s = %s;\n", obj.Name(), value), indent+4)
+	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
+}
+
 func (re *RustEmitter) PreVisitTypeAssertExpr(node *ast.TypeAssertExpr, indent int) {
 	re.gir.emitToFileBuffer("", "@PreVisitTypeAssertExpr")
 }
@@ -3707,16 +7264,28 @@ func (re *RustEmitter) PostVisitTypeAssertExpr(node *ast.TypeAssertExpr, indent
 
 		// Generate Rust downcast syntax: X.downcast_ref::<Type>().unwrap().clone()
 		newTokens := []string{exprStr, ".downcast_ref::<", typeStr, ">().unwrap().clone()"}
+		if node == re.typeAssertCommaOk {
+			// Comma-ok form yields (value, ok) instead of panicking
+			newTokens = []string{exprStr, ".downcast_ok::<", typeStr, ">()"}
+		}
 		re.gir.tokenSlice, _ = RewriteTokensBetween(re.gir.tokenSlice, p0.Index, p4.Index, newTokens)
 	}
 }
 
 func (re *RustEmitter) PreVisitKeyValueExpr(node *ast.KeyValueExpr, indent int) {
 	re.shouldGenerate = true
+	if re.insideMapCompositeLit() {
+		re.gir.emitToFileBuffer("(", EmptyVisitMethod)
+		return
+	}
 	re.inKeyValueExpr = true
 }
 
 func (re *RustEmitter) PostVisitKeyValueExpr(node *ast.KeyValueExpr, indent int) {
+	if re.insideMapCompositeLit() {
+		re.gir.emitToFileBuffer(")", EmptyVisitMethod)
+		return
+	}
 	re.inKeyValueExpr = false
 	// Add type cast if needed for struct field initialization
 	// This handles untyped int constants assigned to int8 fields
@@ -3800,25 +7369,79 @@ func (re *RustEmitter) getFieldTypeForKeyValue(node *ast.KeyValueExpr, fieldName
 	return ""
 }
 
+// rustLabel returns the Rust spelling of a Go label. Labels that are Rust
+// keywords are invalid lifetimes, and not all of them may be raw, so they
+// are prefixed with an underscore instead.
+func rustLabel(name string) string {
+	if escapeRustKeyword(name) != name {
+		return "'_" + name
+	}
+	return "'" + name
+}
+
 func (re *RustEmitter) PreVisitBranchStmt(node *ast.BranchStmt, indent int) {
+	if node.Tok == token.CONTINUE {
+		// A while loop lowered from a for loop increments before continuing
+		for i := len(re.continueIncrements) - 1; i >= 0; i-- {
+			loop := re.continueIncrements[i]
+			if node.Label == nil || loop.label == rustLabel(node.Label.Name) {
+				if loop.increment != "" {
+					re.gir.emitToFileBuffer(re.emitAsString(loop.increment+"\n", indent), EmptyVisitMethod)
+				}
+				break
+			}
+		}
+	}
 	str := re.emitAsString(node.Tok.String()+";", indent)
+	if node.Label != nil {
+		str = re.emitAsString(fmt.Sprintf("%s %s;", node.Tok, rustLabel(node.Label.Name)), indent)
+	}
 	re.gir.emitToFileBuffer(str, EmptyVisitMethod)
 }
 
-func (re *RustEmitter) PreVisitCallExprFun(node ast.Expr, indent int) {
-	// Check if this is a selector expression (obj.field) where the field is a function type
-	// In Rust, calling a function stored in a struct field requires: (obj.field)(args)
-	if sel, ok := node.(*ast.SelectorExpr); ok {
-		// Get the type of the selector (the field)
-		if tv := re.pkg.TypesInfo.Selections[sel]; tv != nil {
-			// Check if the field type is a function type (Signature)
-			if _, isSig := tv.Type().Underlying().(*types.Signature); isSig {
-				re.gir.emitToFileBuffer("(", EmptyVisitMethod)
+// PreVisitLabeledStmt labels a loop, or a block around a labeled switch so
+// that break L leaves the match
+func (re *RustEmitter) PreVisitLabeledStmt(node *ast.LabeledStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	label := rustLabel(node.Label.Name) + ": "
+	switch stmt := node.Stmt.(type) {
+	case *ast.SwitchStmt:
+		re.gir.emitToFileBuffer(re.emitAsString(label+"{\n", indent), EmptyVisitMethod)
+	case *ast.ForStmt:
+		if re.isWhileLoop(stmt) {
+			// PostVisitForStmt labels the while loop following the init statement
+			if re.whileLabels == nil {
+				re.whileLabels = make(map[*ast.ForStmt]string)
 			}
+			re.whileLabels[stmt] = label
+			return
 		}
+		re.gir.emitToFileBuffer(re.emitAsString(label, indent), EmptyVisitMethod)
+	default:
+		re.gir.emitToFileBuffer(re.emitAsString(label, indent), EmptyVisitMethod)
+	}
+}
+
+func (re *RustEmitter) PostVisitLabeledStmt(node *ast.LabeledStmt, indent int) {
+	if re.forwardDecls {
+		return
+	}
+	if _, isSwitch := node.Stmt.(*ast.SwitchStmt); isSwitch {
+		re.gir.emitToFileBuffer(re.emitAsString("}\n", indent), EmptyVisitMethod)
+	}
+}
+
+func (re *RustEmitter) PreVisitCallExprFun(node ast.Expr, indent int) {
+	// A function value is called through func_value(), which panics on nil
+	if re.isFuncValueCall(node) {
+		re.gir.emitToFileBuffer("func_value(&", EmptyVisitMethod)
 	}
 	// Push the current position to the stack for nested call handling
 	re.callExprFunMarkerStack = append(re.callExprFunMarkerStack, len(re.gir.tokenSlice))
+	_, isFuncLit := node.(*ast.FuncLit)
+	re.callExprFunLitStack = append(re.callExprFunLitStack, isFuncLit)
 	re.gir.emitToFileBuffer("", "@PreVisitCallExprFun")
 }
 
@@ -3826,14 +7449,77 @@ func (re *RustEmitter) PostVisitCallExprFun(node ast.Expr, indent int) {
 	// Push the current position to the stack for nested call handling (end of function name)
 	re.callExprFunEndMarkerStack = append(re.callExprFunEndMarkerStack, len(re.gir.tokenSlice))
 	re.gir.emitToFileBuffer("", "@PostVisitCallExprFun")
-	// Close the paren if we opened one for function field call
-	if sel, ok := node.(*ast.SelectorExpr); ok {
-		if tv := re.pkg.TypesInfo.Selections[sel]; tv != nil {
-			if _, isSig := tv.Type().Underlying().(*types.Signature); isSig {
-				re.gir.emitToFileBuffer(")", EmptyVisitMethod)
-			}
+	if re.isFuncValueCall(node) {
+		re.gir.emitToFileBuffer(")", EmptyVisitMethod)
+	}
+}
+
+// isFuncValueCall reports whether fun, the callee of a call, is a function
+// value rather than a declared function, method, builtin, conversion,
+// function literal or inlined local closure
+func (re *RustEmitter) isFuncValueCall(fun ast.Expr) bool {
+	fun = ast.Unparen(fun)
+	if tv, ok := re.pkg.TypesInfo.Types[fun]; !ok || tv.IsType() {
+		return false
+	}
+	if !isFuncExpr(re.pkg, fun) {
+		return false
+	}
+	switch f := fun.(type) {
+	case *ast.FuncLit:
+		return false
+	case *ast.Ident:
+		_, isVar := re.pkg.TypesInfo.Uses[f].(*types.Var)
+		return isVar && re.localClosures[f.Name] == nil
+	case *ast.SelectorExpr:
+		if sel := re.pkg.TypesInfo.Selections[f]; sel != nil {
+			return sel.Kind() == types.FieldVal
+		}
+		_, isVar := re.pkg.TypesInfo.Uses[f.Sel].(*types.Var)
+		return isVar
+	case *ast.IndexExpr:
+		// An instantiation of a generic function
+		if ident, ok := f.X.(*ast.Ident); ok {
+			_, isFunc := re.pkg.TypesInfo.Uses[ident].(*types.Func)
+			return !isFunc
 		}
+	case *ast.IndexListExpr:
+		return false
 	}
+	return true
+}
+
+// takesFuncValues reports whether the function called by call takes function
+// values, the runtime takes any callable instead
+func (re *RustEmitter) takesFuncValues(call *ast.CallExpr) bool {
+	if _, ok := re.pkg.TypesInfo.TypeOf(call.Fun).(*types.Signature); !ok {
+		return false
+	}
+	var callee types.Object
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		callee = re.pkg.TypesInfo.Uses[fun]
+	case *ast.SelectorExpr:
+		callee = re.pkg.TypesInfo.Uses[fun.Sel]
+	}
+	return callee == nil || callee.Pkg() == nil || !strings.HasPrefix(callee.Pkg().Path(), "runtime/")
+}
+
+// isFuncValueCopy reports whether expr is a variable or field holding a
+// function value, a copy of it clones the Rc
+func (re *RustEmitter) isFuncValueCopy(expr ast.Expr) bool {
+	if !isFuncExpr(re.pkg, expr) {
+		return false
+	}
+	switch e := expr.(type) {
+	case *ast.Ident:
+		_, isVar := re.pkg.TypesInfo.Uses[e].(*types.Var)
+		return isVar
+	case *ast.SelectorExpr:
+		sel := re.pkg.TypesInfo.Selections[e]
+		return sel != nil && sel.Kind() == types.FieldVal
+	}
+	return false
 }
 
 func (re *RustEmitter) PreVisitFuncDeclSignatureTypeParamsListType(node ast.Expr, argName *ast.Ident, index int, indent int) {
@@ -4125,3 +7811,205 @@ func (re *RustEmitter) GenerateBuildRs() error {
 	DebugLogPrintf("Generated build.rs at %s (graphics: %s)", buildRsPath, graphicsBackend)
 	return nil
 }
+
+// rustBoundOrder fixes the order in which inferred trait bounds are emitted
+var rustBoundOrder = []string{
+	"Clone", "Copy", "Default", "std::fmt::Debug", "std::fmt::Display",
+	"PartialEq", "PartialOrd",
+	"Add", "Sub", "Mul", "Div", "Rem",
+	"AddAssign", "SubAssign", "MulAssign", "DivAssign", "RemAssign",
+}
+
+// rustOperatorBounds maps Go operators to the std::ops trait they need
+var rustOperatorBounds = map[token.Token]string{
+	token.ADD: "Add", token.SUB: "Sub", token.MUL: "Mul", token.QUO: "Div", token.REM: "Rem",
+	token.ADD_ASSIGN: "AddAssign", token.SUB_ASSIGN: "SubAssign", token.MUL_ASSIGN: "MulAssign",
+	token.QUO_ASSIGN: "DivAssign", token.REM_ASSIGN: "RemAssign",
+}
+
+// rustTypeParams declares type parameters with the trait bounds they need,
+// e.g. <T: Clone + Default + std::fmt::Debug + PartialEq>
+func (re *RustEmitter) rustTypeParams(params *types.TypeParamList) string {
+	if params.Len() == 0 {
+		return ""
+	}
+	var decls []string
+	for i := 0; i < params.Len(); i++ {
+		param := params.At(i)
+		name := param.Obj().Name()
+		bounds := re.boundsOf(param)
+		var traits []string
+		for _, bound := range rustBoundOrder {
+			if !bounds[bound] {
+				continue
+			}
+			switch {
+			case strings.HasSuffix(bound, "Assign"):
+				bound = "std::ops::" + bound
+			case rustOperatorTrait(bound):
+				bound = fmt.Sprintf("std::ops::%s<Output = %s>", bound, name)
+			}
+			traits = append(traits, bound)
+		}
+		decls = append(decls, name+": "+strings.Join(traits, " + "))
+	}
+	return "<" + strings.Join(decls, ", ") + ">"
+}
+
+func rustOperatorTrait(bound string) bool {
+	for _, trait := range rustOperatorBounds {
+		if trait == bound {
+			return true
+		}
+	}
+	return false
+}
+
+// boundsOf infers the trait bounds of a type parameter. Go constraints only
+// say which operators a type parameter supports, so the bounds come from how
+// the declaring function uses its values: the operators applied to them,
+// printing and the bounds of the generic functions they are passed to.
+func (re *RustEmitter) boundsOf(param *types.TypeParam) map[string]bool {
+	if re.typeParamBounds == nil {
+		re.typeParamBounds = make(map[*types.TypeParam]map[string]bool)
+	}
+	if bounds, ok := re.typeParamBounds[param]; ok {
+		return bounds
+	}
+	// Values are cloned and zero-initialized like any other Go value
+	bounds := map[string]bool{"Clone": true, "Default": true, "std::fmt::Debug": true}
+	// Registered before the walk so that recursive calls terminate
+	re.typeParamBounds[param] = bounds
+	if isNumericConstraint(param) {
+		for _, bound := range []string{"Copy", "PartialEq", "PartialOrd", "std::fmt::Display"} {
+			bounds[bound] = true
+		}
+	} else if types.Comparable(param) && param.Constraint() != types.Universe.Lookup("any").Type() {
+		bounds["PartialEq"] = true
+	}
+	pkg, decl := declaringFunc(re.pkg, param.Obj().Pos(), map[*packages.Package]bool{})
+	if decl == nil {
+		return bounds
+	}
+	info := pkg.TypesInfo
+	isParam := func(expr ast.Expr) bool {
+		return info.TypeOf(expr) == types.Type(param)
+	}
+	ast.Inspect(decl, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.BinaryExpr:
+			if !isParam(node.X) {
+				break
+			}
+			switch node.Op {
+			case token.EQL, token.NEQ:
+				bounds["PartialEq"] = true
+			case token.LSS, token.GTR, token.LEQ, token.GEQ:
+				bounds["PartialOrd"] = true
+			default:
+				if trait, ok := rustOperatorBounds[node.Op]; ok {
+					bounds[trait] = true
+				}
+			}
+		case *ast.AssignStmt:
+			if trait, ok := rustOperatorBounds[node.Tok]; ok && isParam(node.Lhs[0]) {
+				bounds[trait] = true
+			}
+		case *ast.CallExpr:
+			if sel, ok := node.Fun.(*ast.SelectorExpr); ok {
+				if pkgName, ok := info.Uses[identOf(sel.X)].(*types.PkgName); ok && pkgName.Imported().Path() == "fmt" {
+					for _, arg := range node.Args {
+						if isParam(arg) {
+							bounds["std::fmt::Display"] = true
+						}
+					}
+				}
+			}
+			re.addCalleeBounds(info, node.Fun, param, bounds)
+		}
+		return true
+	})
+	return bounds
+}
+
+// addCalleeBounds adds the bounds that a generic function or a method of a
+// generic type places on the type arguments param is passed as
+func (re *RustEmitter) addCalleeBounds(info *types.Info, fun ast.Expr, param *types.TypeParam, bounds map[string]bool) {
+	var calleeParams *types.TypeParamList
+	var typeArgs *types.TypeList
+	if index, ok := fun.(*ast.IndexListExpr); ok {
+		fun = index.X
+	}
+	switch fn := fun.(type) {
+	case *ast.Ident, *ast.SelectorExpr:
+		ident := identOf(fn)
+		if sel, ok := fn.(*ast.SelectorExpr); ok {
+			ident = sel.Sel
+			if selection, ok := info.Selections[sel]; ok {
+				recv := selection.Recv()
+				if ptr, ok := recv.(*types.Pointer); ok {
+					recv = ptr.Elem()
+				}
+				method, isFunc := selection.Obj().(*types.Func)
+				named, isNamed := recv.(*types.Named)
+				if !isFunc || !isNamed {
+					return
+				}
+				calleeParams = method.Origin().Type().(*types.Signature).RecvTypeParams()
+				typeArgs = named.TypeArgs()
+				break
+			}
+		}
+		inst, ok := info.Instances[ident]
+		callee, isFunc := info.Uses[ident].(*types.Func)
+		if !ok || !isFunc {
+			return
+		}
+		calleeParams = callee.Type().(*types.Signature).TypeParams()
+		typeArgs = inst.TypeArgs
+	default:
+		return
+	}
+	for i := 0; i < typeArgs.Len() && i < calleeParams.Len(); i++ {
+		if typeArgs.At(i) != types.Type(param) || calleeParams.At(i) == param {
+			continue
+		}
+		for bound := range re.boundsOf(calleeParams.At(i)) {
+			bounds[bound] = true
+		}
+	}
+}
+
+// declaringFunc finds the function declaration at pos in pkg or its imports
+func declaringFunc(pkg *packages.Package, pos token.Pos, seen map[*packages.Package]bool) (*packages.Package, *ast.FuncDecl) {
+	if seen[pkg] {
+		return nil, nil
+	}
+	seen[pkg] = true
+	for _, file := range pkg.Syntax {
+		if pos < file.Pos() || pos > file.End() {
+			continue
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Pos() <= pos && pos < fn.End() {
+				return pkg, fn
+			}
+		}
+	}
+	for _, imported := range pkg.Imports {
+		if found, decl := declaringFunc(imported, pos, seen); decl != nil {
+			return found, decl
+		}
+	}
+	return nil, nil
+}
+
+func identOf(expr ast.Expr) *ast.Ident {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e
+	case *ast.SelectorExpr:
+		return e.Sel
+	}
+	return nil
+}
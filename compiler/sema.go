@@ -3,9 +3,12 @@ package compiler
 import (
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
+	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/go/packages"
+	"math"
 	"os"
 )
 
@@ -14,77 +17,273 @@ import (
 // ============================================
 // SECTION 1: Unsupported Go Features (Errors)
 // ============================================
-// - Pointers (*T, &x)
-// - Maps (map[K]V)
-// - Defer statements
-// - Goroutines (go keyword)
-// - Channels (chan T)
-// - Select statements
-// - Goto and labels
-// - Method receivers
-// - Variadic functions (...T)
-// - Non-empty interfaces
-// - Struct embedding (anonymous fields)
+// - Goto, and labels on statements other than for and switch
+// - Anonymous non-empty interfaces and interface embedding
+// - Method values and method expressions (obj.Method, T.Method) not called
 // - Init functions
-// - Named return values
-// - iota constant enumeration
-// - Type switch statements
-// - Package-level variable declarations
 //
 // ============================================
 // SECTION 2: Backend-Specific Constraints
 // ============================================
-// - Range over inline composite literals
-// - Nil comparisons (== nil, != nil)
+// - Map keys other than strings, integers and booleans
+// - Methods on receivers other than struct types of the same package
+// - Nil comparisons (== nil, != nil) of interfaces with methods
+// - Pointers to types other than structs, the address of anything but a
+//   composite literal or a local variable, pointers converted to interfaces
+//   and pointer receivers used as pointer values
 // - String variable reuse after concatenation (Rust move semantics)
 // - Same variable multiple times in expression (Rust ownership)
 // - Slice self-assignment (Rust borrow checker)
-// - Multiple closures capturing same variable (Rust borrow checker)
 // - Struct field initialization order (C++ designated initializers)
-// - Variable shadowing (C# does not allow shadowing within same function)
+// - Indexed elements in array literals
+// - Type names of the Rust runtime (Slice, Map, Ptr, Chan, Int, ...)
+// - Embedding types other than structs, and interfaces satisfied through
+//   methods promoted from an embedded struct
+// - Type parameters on types other than structs, and constraints other than
+//   any, comparable and unions of numeric types
+// - len and cap of channels, break inside select and select cases receiving
+//   into anything but variables
 //
 // ============================================
 // SECTION 3: Supported with Limitations
 // ============================================
 // - interface{} / any - maps to std::any (C++), Box<dyn Any> (Rust), object (C#)
-//   Note: type assertions x.(T) supported in C++ only for now
+// - Named interfaces with methods - abstract base with virtual methods (C++),
+//   interface (C#), dyn Trait (Rust), duck typing (JS)
+//   Note: type assertions x.(T) must name a concrete type, and an interface
+//   value can't be converted to another interface type, interface{} included
+// - Type switches - lowered to if-else chains on the dynamic type
+//   Note: cases must name concrete types, no nil case and no break, and
+//   no two cases may share an underlying type unless they are structs
+// - map[K]V - maps to map (C++), Dictionary (C#), Map (Rust), Map (JS)
+//   Note: range over a map visits keys in sorted order in every backend
+// - iota constant enumeration - values are evaluated at transpile time and
+//   emitted as typed constants
+// - Package-level variables - initialized in dependency order as globals (C++),
+//   static fields (C#), thread_local RefCells (Rust), module scope (JS)
+//   Note: the type must be declared unless the value is a composite literal
+// - defer - deferred calls run in LIFO order on every return, from a defer
+//   stack (C++, Rust) or a finally block (C#, JS)
+//   Note: arguments and method receivers are evaluated at the defer statement
+// - panic/recover - panics unwind as exceptions (C++, C#, JS) or Rust panics,
+//   recover() called directly by a deferred function stops them
+//   Note: the recovered value of a runtime error is its message string
+// - Variadic functions (...T) - the arguments are packed into a slice, and
+//   f(xs...) passes xs itself, as a params Slice (C#) or by unwrapping it from
+//   the rest parameter (JS)
+// - Named results - zero-valued locals declared ahead of the function body, a
+//   naked return returns them and deferred calls may change them
+// - Struct embedding - the embedded struct is a field named after its type,
+//   promoted fields and methods are accessed through it (s.X is s.Rect.X)
+//   Note: in C++ the field is named with a trailing underscore (Rect_)
+// - Generic functions and struct types - templates (C++), generics (C#),
+//   generics with trait bounds inferred from the body (Rust), erased (JS)
+//   Note: numeric constraints need .NET 7 INumber<T> in C#
+// - Labeled break and continue - Rust loop labels, JS labels, goto to labels
+//   generated after the loop or at the end of its body (C++, C#)
+// - Fixed-size arrays [N]T - std::array (C++), inline array structs (C#),
+//   [T; N] (Rust), typed arrays or arrays (JS), copied on assignment
+//   Note: only local arrays can be sliced, a[lo:hi] shares their elements;
+//   array literal elements cannot be indexed
+// - Pointers to structs *T - std::shared_ptr (C++), Ptr<T> class (C#),
+//   Ptr<T> over Rc<RefCell<T>> (Rust), object references (JS)
+//   Note: a local variable whose address is taken is allocated as a pointer,
+//   in Rust a method called through a pointer borrows its value mutably
+// - nil slices, maps, functions and interface{} values - a nil slice or map
+//   reads as an empty one but has no storage, so []T{} and make(...) are not
+//   nil; a nil function is an empty std::function (C++), null (C#, JS) or None
+//   of an Option (Rust), a nil interface{} is an empty std::any (C++), null
+//   (C#, JS) or a boxed () (Rust)
+// - Goroutines and channels - goroutines run one at a time on threads (C++,
+//   C#, Rust) or as async functions (JS), switching when they block on a
+//   channel; when every goroutine is blocked the program fails with Go's
+//   deadlock error
+//   Note: in Rust a goroutine can't block in a method of a pointer other
+//   goroutines use
+// - Range over integers, strings and inline literals - an integer range is a
+//   counting loop, a string range decodes UTF-8 runes at their byte offsets and
+//   an inline literal is stored in a temporary before the loop
+//   Note: invalid UTF-8 decodes to U+FFFD, which in C# and JS strings only
+//   comes from lone surrogates
 type SemaChecker struct {
 	Emitter
-	pkg      *packages.Package
-	constCtx bool
+	pkg *packages.Package
 	// Track string variables consumed by concatenation (for Rust compatibility)
 	consumedStringVars map[string]token.Pos
-	// Track variables used in closures for multiple closure detection
-	closureVars map[string]token.Pos
 	// Track range loop targets to detect mutation during iteration
 	rangeTargets map[string]token.Pos
-	// Track closure captures for detecting mutation after capture
-	closureCaptures map[string]token.Pos
 	// Track current function's parameters for mutation+return detection
 	currentFuncParams map[string]bool
 	mutatedParams     map[string]bool
-	// Track variable declarations per scope for shadowing detection
-	// Each map in the slice represents a scope level (index 0 = outermost)
-	scopeStack []map[string]token.Pos
 }
 
 func (sema *SemaChecker) PreVisitPackage(pkg *packages.Package, indent int) {
 	sema.pkg = pkg
 	// Reset consumed variables map for each package
 	sema.consumedStringVars = make(map[string]token.Pos)
-	// Reset closure variables map for each package
-	sema.closureVars = make(map[string]token.Pos)
 	// Reset range targets for each package
 	sema.rangeTargets = make(map[string]token.Pos)
-	// Reset closure captures for each package
-	sema.closureCaptures = make(map[string]token.Pos)
 
-	// Check for package-level variable declarations (not supported by transpiler)
+	// Check package-level variable declarations
 	sema.checkPackageLevelVars(pkg)
+	sema.checkGenericTypes(pkg)
+	sema.checkPointerConversions(pkg)
+	sema.checkInterfaceConversions(pkg)
+	sema.checkMethodValues(pkg)
+	sema.checkTypeNames(pkg)
+	if IntSize == 32 {
+		sema.checkIntConstants(pkg)
+	}
+}
+
+// checkIntConstants checks that constants of type int and uint fit in 32 bits
+// when the generated code declares them 32 bits wide. The C# and Rust
+// compilers reject wider literals, C++ and JS would silently truncate them.
+func (sema *SemaChecker) checkIntConstants(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			expr, ok := n.(ast.Expr)
+			if !ok {
+				return true
+			}
+			tv, ok := pkg.TypesInfo.Types[expr]
+			if !ok || tv.Value == nil || tv.Value.Kind() != constant.Int || !isIntType(tv.Type.Underlying()) {
+				return true
+			}
+			if tv.Type.Underlying().(*types.Basic).Kind() == types.Int {
+				if _, fits := constant.Int64Val(tv.Value); fits && constant.Compare(tv.Value, token.GEQ, constant.MakeInt64(math.MinInt32)) &&
+					constant.Compare(tv.Value, token.LEQ, constant.MakeInt64(math.MaxInt32)) {
+					return true
+				}
+			} else if value, fits := constant.Uint64Val(tv.Value); fits && value <= math.MaxUint32 {
+				return true
+			}
+			fmt.Printf("\033[31m\033[1mCompilation error: constant %s overflows %s with -int-size=32\033[0m\n", tv.Value.ExactString(), tv.Type.String())
+			fmt.Printf("  '%s' does not fit in 32 bits.\n", types.ExprString(expr))
+			fmt.Println()
+			fmt.Println("  \033[32mUse a 64-bit type for the value:\033[0m")
+			fmt.Printf("    int64(%s)\n", types.ExprString(expr))
+			os.Exit(-1)
+			return false
+		})
+	}
+}
+
+// rustRuntimeNames are the types and traits the Rust backend declares or
+// imports at the top of every program. A type of the same name would be
+// declared twice.
+var rustRuntimeNames = map[string]bool{
+	"Any": true, "Rc": true, "UnsafeCell": true, "HashMap": true, "Hash": true,
+	"Int": true, "Int8": true, "Int16": true, "Int32": true, "Int64": true,
+	"Uint": true, "Uint8": true, "Uint16": true, "Uint32": true, "Uint64": true,
+	"Slice": true, "SliceExpr": true, "SliceExpr3": true, "Map": true, "MapEntry": true,
+	"DowncastOk": true, "Defers": true, "Shared": true, "Ptr": true, "GoPanic": true,
+	"PackageVar": true, "Scheduler": true, "Goroutine": true, "Channel": true, "Chan": true,
+	"SelectCase": true, "SelectRecv": true, "SelectSend": true,
+}
+
+// checkTypeNames checks that no type is named after a type of the Rust runtime
+func (sema *SemaChecker) checkTypeNames(pkg *packages.Package) {
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		if _, isType := scope.Lookup(name).(*types.TypeName); !isType || !rustRuntimeNames[name] {
+			continue
+		}
+		fmt.Printf("\033[31m\033[1mCompilation error: type name '%s' is reserved\033[0m\n", name)
+		fmt.Printf("  The Rust backend declares a runtime type named '%s' in every program.\n", name)
+		fmt.Println()
+		fmt.Println("  \033[32mRename the type:\033[0m")
+		fmt.Printf("    type My%s struct { ... }\n", name)
+		os.Exit(-1)
+	}
+}
+
+// checkGenericTypes checks generic type declarations, only struct types can
+// have type parameters
+func (sema *SemaChecker) checkGenericTypes(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				if typeSpec.TypeParams == nil {
+					continue
+				}
+				if _, isStruct := typeSpec.Type.(*ast.StructType); !isStruct {
+					fmt.Println("\033[31m\033[1mCompilation error: generic non-struct types are not supported\033[0m")
+					fmt.Printf("  Type '%s' has type parameters but is not a struct.\n", typeSpec.Name.Name)
+					fmt.Println("  Backends declare generic types as templates or generic structs.")
+					fmt.Println()
+					fmt.Println("  \033[33mInstead of:\033[0m")
+					fmt.Println("    type List[T any] []T")
+					fmt.Println()
+					fmt.Println("  \033[32mWrap the value in a struct:\033[0m")
+					fmt.Println("    type List[T any] struct { items []T }")
+					os.Exit(-1)
+				}
+				if named, ok := pkg.TypesInfo.Defs[typeSpec.Name].Type().(*types.Named); ok {
+					sema.checkTypeParams(typeSpec.Name.Name, named.TypeParams())
+				}
+			}
+		}
+	}
+}
+
+// checkTypeParams checks the constraints of type parameters. Only any,
+// comparable and unions of numeric types have an equivalent in every backend:
+// C# and Rust need to know the operations a type parameter supports.
+func (sema *SemaChecker) checkTypeParams(name string, params *types.TypeParamList) {
+	for i := 0; i < params.Len(); i++ {
+		param := params.At(i)
+		constraint := param.Constraint()
+		iface := constraint.Underlying().(*types.Interface)
+		if iface.Empty() || constraint == types.Universe.Lookup("comparable").Type() {
+			continue
+		}
+		if iface.NumMethods() == 0 && isNumericConstraint(param) && numericTerms(constraint) {
+			continue
+		}
+		fmt.Println("\033[31m\033[1mCompilation error: unsupported type parameter constraint\033[0m")
+		fmt.Printf("  Type parameter '%s' of '%s' is constrained by %s.\n", param.Obj().Name(), name, types.TypeString(constraint, types.RelativeTo(sema.pkg.Types)))
+		fmt.Println("  Constraints must be any, comparable or a union of numeric types.")
+		fmt.Println()
+		fmt.Println("  \033[32mUse a supported constraint:\033[0m")
+		fmt.Println("    type Number interface { ~int | ~int64 | ~float64 }")
+		os.Exit(-1)
+	}
+}
+
+// numericTerms reports whether every term of a constraint is a numeric type
+func numericTerms(constraint types.Type) bool {
+	iface := constraint.Underlying().(*types.Interface)
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		switch embedded := iface.EmbeddedType(i).(type) {
+		case *types.Union:
+			for j := 0; j < embedded.Len(); j++ {
+				basic, ok := embedded.Term(j).Type().Underlying().(*types.Basic)
+				if !ok || basic.Info()&types.IsNumeric == 0 {
+					return false
+				}
+			}
+		case *types.Named:
+			if !numericTerms(embedded) {
+				return false
+			}
+		default:
+			basic, ok := embedded.Underlying().(*types.Basic)
+			if !ok || basic.Info()&types.IsNumeric == 0 {
+				return false
+			}
+		}
+	}
+	return true
 }
 
-// checkPackageLevelVars detects package-level variable declarations which are not supported
-// The transpiler does not handle var declarations at package scope
+// checkPackageLevelVars checks package-level variable declarations. Backends
+// declare them as globals or static fields, which need a type up front
 func (sema *SemaChecker) checkPackageLevelVars(pkg *packages.Package) {
 	for _, file := range pkg.Syntax {
 		for _, decl := range file.Decls {
@@ -92,21 +291,38 @@ func (sema *SemaChecker) checkPackageLevelVars(pkg *packages.Package) {
 			if !ok || genDecl.Tok != token.VAR {
 				continue
 			}
-			// Found a package-level var declaration
 			for _, spec := range genDecl.Specs {
 				valueSpec, ok := spec.(*ast.ValueSpec)
 				if !ok {
 					continue
 				}
-				for _, name := range valueSpec.Names {
-					fmt.Println("\033[31m\033[1mCompilation error: package-level variables are not supported\033[0m")
-					fmt.Printf("  Variable '%s' is declared at package level.\n", name.Name)
-					fmt.Println("  Package-level variable declarations are not transpiled correctly.")
+				if len(valueSpec.Values) > 0 && len(valueSpec.Values) != len(valueSpec.Names) {
+					fmt.Println("\033[31m\033[1mCompilation error: package-level variables initialized from a multi-value call are not supported\033[0m")
+					fmt.Printf("  Variables '%s' share one initializer.\n", valueSpec.Names[0].Name)
+					fmt.Println()
+					fmt.Println("  \033[32mDeclare each variable with its own initializer.\033[0m")
+					os.Exit(-1)
+				}
+				for i, name := range valueSpec.Names {
+					if name.Name == "_" {
+						fmt.Println("\033[31m\033[1mCompilation error: blank package-level variables are not supported\033[0m")
+						fmt.Println("  Call the initializer from main instead.")
+						os.Exit(-1)
+					}
+					if valueSpec.Type != nil {
+						continue
+					}
+					if lit, ok := valueSpec.Values[i].(*ast.CompositeLit); ok && lit.Type != nil {
+						continue
+					}
+					fmt.Println("\033[31m\033[1mCompilation error: package-level variable without explicit type\033[0m")
+					fmt.Printf("  Variable '%s' needs a declared type, backends declare it before its initializer runs.\n", name.Name)
+					fmt.Println()
+					fmt.Println("  \033[33mInstead of:\033[0m")
+					fmt.Printf("    var %s = value\n", name.Name)
 					fmt.Println()
-					fmt.Println("  \033[32mMove the variable inside a function, or use a function that returns the value:\033[0m")
-					fmt.Println("    func getMyVar() T {")
-					fmt.Println("        return T{...}")
-					fmt.Println("    }")
+					fmt.Println("  \033[32mUse explicit type:\033[0m")
+					fmt.Printf("    var %s T = value\n", name.Name)
 					os.Exit(-1)
 				}
 			}
@@ -118,81 +334,259 @@ func (sema *SemaChecker) checkPackageLevelVars(pkg *packages.Package) {
 // SECTION 1: Unsupported Go Features
 // ============================================
 
-// PreVisitStarExpr checks for pointer types (*T) which are not supported
+// PreVisitStarExpr checks that pointer types (*T) and dereferences point to
+// struct types, the only values backends allocate behind a shared reference
 func (sema *SemaChecker) PreVisitStarExpr(node *ast.StarExpr, indent int) {
-	fmt.Println("\033[31m\033[1mCompilation error: pointer types are not supported\033[0m")
-	fmt.Println("  Pointer types (*T) and pointer dereferencing are not allowed.")
-	fmt.Println("  goany targets languages with different memory models (Rust, C#, JS).")
+	t := sema.pkg.TypesInfo.TypeOf(node.X)
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	if t != nil && isStructPointer(types.NewPointer(t)) {
+		if _, named := t.(*types.Named); named {
+			return
+		}
+	}
+	fmt.Println("\033[31m\033[1mCompilation error: pointers to non-struct types are not supported\033[0m")
+	fmt.Printf("  Pointer type '*%s' is not allowed.\n", types.ExprString(node.X))
+	fmt.Println("  Only pointers to named struct types have a shared reference in every target language.")
 	fmt.Println()
-	fmt.Println("  \033[32mUse value types or slices instead.\033[0m")
+	fmt.Println("  \033[32mWrap the value in a struct:\033[0m")
+	fmt.Println("    type Box struct { Value int }")
+	fmt.Println("    p := &Box{}")
 	os.Exit(-1)
 }
 
-// PreVisitUnaryExpr checks for address-of operator (&x) which is not supported
+// PreVisitUnaryExpr checks the address-of operator (&x). Backends allocate a
+// new value for it, so it is only supported on composite literals, and on
+// local variables, which are turned into pointers to a copy of their initial
+// value beforehand
 func (sema *SemaChecker) PreVisitUnaryExpr(node *ast.UnaryExpr, indent int) {
-	if node.Op == token.AND {
-		fmt.Println("\033[31m\033[1mCompilation error: address-of operator is not supported\033[0m")
-		fmt.Println("  The address-of operator (&x) is not allowed.")
-		fmt.Println("  goany targets languages with different memory models.")
-		fmt.Println()
-		fmt.Println("  \033[32mUse value types or redesign without pointers.\033[0m")
-		os.Exit(-1)
+	if node.Op != token.AND {
+		return
 	}
-}
-
-// PreVisitMapType checks for map types which are not supported
-func (sema *SemaChecker) PreVisitMapType(node *ast.MapType, indent int) {
-	fmt.Println("\033[31m\033[1mCompilation error: map types are not supported\033[0m")
-	fmt.Println("  Map types (map[K]V) are not allowed.")
-	fmt.Println("  Maps have different semantics across target languages.")
+	if _, ok := node.X.(*ast.CompositeLit); ok || boxedValues[node] {
+		return
+	}
+	fmt.Println("\033[31m\033[1mCompilation error: address-of operator is not supported here\033[0m")
+	fmt.Printf("  The address of '%s' cannot be taken.\n", types.ExprString(node.X))
+	fmt.Println("  Only composite literals (&T{...}) and local struct variables declared")
+	fmt.Println("  alone with x := value, var x T or var x T = value can have their address")
+	fmt.Println("  taken. Such a variable holds a copy of its initial value.")
 	fmt.Println()
-	fmt.Println("  \033[32mUse slices with linear search or struct arrays instead.\033[0m")
+	fmt.Println("  \033[32mUse a pointer from the start:\033[0m")
+	fmt.Println("    p := &T{...}")
 	os.Exit(-1)
 }
 
-// PreVisitDeferStmt checks for defer statements which are not supported
-func (sema *SemaChecker) PreVisitDeferStmt(node *ast.DeferStmt, indent int) {
-	fmt.Println("\033[31m\033[1mCompilation error: defer statements are not supported\033[0m")
-	fmt.Println("  The defer keyword is not allowed.")
-	fmt.Println("  Defer has no direct equivalent in C++, Rust, or JavaScript.")
+// PreVisitSliceExpr checks that a sliced array is a local variable, whose
+// elements backends move into the backing array its slices share
+func (sema *SemaChecker) PreVisitSliceExpr(node *ast.SliceExpr, indent int) {
+	if !isArrayExpr(sema.pkg, node.X) || slicedArrayIdent(sema.pkg, node.X) != nil {
+		return
+	}
+	fmt.Println("\033[31m\033[1mCompilation error: slicing this array is not supported\033[0m")
+	fmt.Printf("  %s slices an array that is not a local variable.\n", types.ExprString(node))
+	fmt.Println("  Only local array variables declared with x := value or var x [N]T")
+	fmt.Println("  can be sliced, the slices share their elements.")
 	fmt.Println()
-	fmt.Println("  \033[32mCall cleanup code explicitly at each return point.\033[0m")
+	fmt.Println("  \033[32mCopy the array into a local variable, or use a slice:\033[0m")
+	fmt.Println("    a := t.Pixels")
+	fmt.Println("    s := a[:]")
 	os.Exit(-1)
 }
 
-// PreVisitGoStmt checks for go statements (goroutines) which are not supported
-func (sema *SemaChecker) PreVisitGoStmt(node *ast.GoStmt, indent int) {
-	fmt.Println("\033[31m\033[1mCompilation error: goroutines are not supported\033[0m")
-	fmt.Println("  The go keyword for launching goroutines is not allowed.")
-	fmt.Println("  Concurrency models differ significantly across target languages.")
+// PreVisitCallExpr checks that new(T) allocates a struct type, like &T{}
+func (sema *SemaChecker) PreVisitCallExpr(node *ast.CallExpr, indent int) {
+	if (isBuiltinCall(sema.pkg, node, "len") || isBuiltinCall(sema.pkg, node, "cap")) && isChanExpr(sema.pkg, node.Args[0]) {
+		fmt.Println("\033[31m\033[1mCompilation error: len and cap of channels are not supported\033[0m")
+		fmt.Printf("  %s is not allowed.\n", types.ExprString(node))
+		fmt.Println("  The number of queued elements changes as goroutines run.")
+		os.Exit(-1)
+	}
+	if !isBuiltinCall(sema.pkg, node, "new") {
+		return
+	}
+	if t := sema.pkg.TypesInfo.TypeOf(node.Args[0]); t != nil && isStructPointer(types.NewPointer(t)) {
+		if _, named := t.(*types.Named); named {
+			return
+		}
+	}
+	fmt.Println("\033[31m\033[1mCompilation error: pointers to non-struct types are not supported\033[0m")
+	fmt.Printf("  new(%s) is not allowed.\n", types.ExprString(node.Args[0]))
+	fmt.Println("  Only pointers to named struct types have a shared reference in every target language.")
 	fmt.Println()
-	fmt.Println("  \033[32mUse sequential code or platform-specific concurrency.\033[0m")
+	fmt.Println("  \033[32mWrap the value in a struct:\033[0m")
+	fmt.Println("    type Box struct { Value int }")
+	fmt.Println("    p := new(Box)")
 	os.Exit(-1)
 }
 
-// PreVisitChanType checks for channel types which are not supported
-func (sema *SemaChecker) PreVisitChanType(node *ast.ChanType, indent int) {
-	fmt.Println("\033[31m\033[1mCompilation error: channel types are not supported\033[0m")
-	fmt.Println("  Channel types (chan T) are not allowed.")
-	fmt.Println("  Channels are Go-specific and have no direct equivalent in target languages.")
+// checkPointerReceiver checks that the receiver of a pointer receiver method
+// is only used to access fields and methods, or dereferenced. Backends pass
+// it by reference, there is no pointer value to return or store
+func (sema *SemaChecker) checkPointerReceiver(node *ast.FuncDecl) {
+	name := recvName(node)
+	if !hasPointerRecv(node) || name == "" || node.Body == nil {
+		return
+	}
+	recv := sema.pkg.TypesInfo.Defs[node.Recv.List[0].Names[0]]
+	astutil.Apply(node.Body, func(c *astutil.Cursor) bool {
+		ident, ok := c.Node().(*ast.Ident)
+		if !ok || sema.pkg.TypesInfo.Uses[ident] != recv {
+			return true
+		}
+		if _, ok := c.Parent().(*ast.SelectorExpr); ok && c.Name() == "X" {
+			return true
+		}
+		if _, ok := c.Parent().(*ast.StarExpr); ok {
+			return true
+		}
+		fmt.Println("\033[31m\033[1mCompilation error: pointer receiver used as a value\033[0m")
+		fmt.Printf("  Receiver '%s' of method '%s' is used as a pointer value.\n", name, node.Name.Name)
+		fmt.Println("  A pointer receiver can only access fields and methods, or be dereferenced.")
+		fmt.Println()
+		fmt.Println("  \033[32mUse a function taking the pointer instead:\033[0m")
+		fmt.Printf("    func %s(%s %s) { ... }\n", node.Name.Name, name, types.ExprString(node.Recv.List[0].Type))
+		os.Exit(-1)
+		return true
+	}, nil)
+}
+
+// checkPointerConversions checks that no pointer is converted to an interface
+// type. Interfaces hold struct values in the backends, not references to them
+func (sema *SemaChecker) checkPointerConversions(pkg *packages.Package) {
+	visitConversions(pkg, func(expr ast.Expr, target types.Type) {
+		if _, isParam := target.(*types.TypeParam); isParam || !types.IsInterface(target) || !isPointerExpr(pkg, expr) {
+			return
+		}
+		fmt.Println("\033[31m\033[1mCompilation error: pointer converted to an interface\033[0m")
+		fmt.Printf("  Pointer '%s' is used as a value of type %s.\n", types.ExprString(expr), types.TypeString(target, types.RelativeTo(pkg.Types)))
+		fmt.Println("  Interfaces hold struct values, not pointers to them.")
+		fmt.Println()
+		fmt.Println("  \033[32mDereference the pointer, or pass a field:\033[0m")
+		fmt.Printf("    *%s\n", types.ExprString(expr))
+		os.Exit(-1)
+	})
+}
+
+// checkInterfaceConversions checks that no interface value is converted to
+// another interface type. Backends store the concrete value behind each
+// interface type differently, and checking the method set of the dynamic
+// type at run time is not supported.
+func (sema *SemaChecker) checkInterfaceConversions(pkg *packages.Package) {
+	visitConversions(pkg, func(expr ast.Expr, target types.Type) {
+		source := pkg.TypesInfo.TypeOf(expr)
+		if source == nil || !types.IsInterface(source) || !types.IsInterface(target) || types.Identical(source, target) {
+			return
+		}
+		if _, isParam := source.(*types.TypeParam); isParam {
+			return
+		}
+		if _, isParam := target.(*types.TypeParam); isParam {
+			return
+		}
+		fmt.Println("\033[31m\033[1mCompilation error: conversion between interface types is not supported\033[0m")
+		fmt.Printf("  '%s' of type %s is used as a value of type %s.\n", types.ExprString(expr),
+			types.TypeString(source, types.RelativeTo(pkg.Types)), types.TypeString(target, types.RelativeTo(pkg.Types)))
+		fmt.Println()
+		fmt.Println("  \033[32mAssert the concrete type first:\033[0m")
+		fmt.Printf("    if v, ok := %s.(MyStruct); ok { ... use v ... }\n", types.ExprString(expr))
+		os.Exit(-1)
+	})
+}
+
+// checkMethodValues checks that methods are only called, not used as function
+// values (obj.Method or T.Method). Backends emit methods as members, which
+// can't be taken as functions bound to their receiver.
+func (sema *SemaChecker) checkMethodValues(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		called := make(map[ast.Expr]bool)
+		ast.Inspect(file, func(n ast.Node) bool {
+			if call, ok := n.(*ast.CallExpr); ok {
+				called[ast.Unparen(call.Fun)] = true
+			}
+			sel, ok := n.(*ast.SelectorExpr)
+			if !ok || called[sel] {
+				return true
+			}
+			selection, ok := pkg.TypesInfo.Selections[sel]
+			if !ok || (selection.Kind() != types.MethodVal && selection.Kind() != types.MethodExpr) {
+				return true
+			}
+			fmt.Println("\033[31m\033[1mCompilation error: method values are not supported\033[0m")
+			fmt.Printf("  Method '%s' is used as a function value.\n", types.ExprString(sel))
+			fmt.Println()
+			fmt.Println("  \033[32mWrap it in a func literal:\033[0m")
+			fmt.Printf("    func(...) { %s(...) }\n", types.ExprString(sel))
+			os.Exit(-1)
+			return false
+		})
+	}
+}
+
+// PreVisitMapType checks that map keys are hashable and ordered in every backend
+// Rust HashMap keys need Hash + Eq, and range over a map iterates keys in sorted
+// order so that all backends produce the same output
+func (sema *SemaChecker) PreVisitMapType(node *ast.MapType, indent int) {
+	if sema.pkg == nil || sema.pkg.TypesInfo == nil {
+		return
+	}
+	keyType := sema.pkg.TypesInfo.TypeOf(node.Key)
+	if keyType == nil {
+		return
+	}
+	if basic, ok := keyType.Underlying().(*types.Basic); ok {
+		if basic.Info()&(types.IsString|types.IsInteger|types.IsBoolean) != 0 {
+			return
+		}
+	}
+	fmt.Println("\033[31m\033[1mCompilation error: unsupported map key type\033[0m")
+	fmt.Printf("  Map key type '%s' is not allowed.\n", keyType.String())
+	fmt.Println("  Map keys must be strings, integers or booleans so they can be")
+	fmt.Println("  hashed and iterated in the same order in all target languages.")
 	fmt.Println()
-	fmt.Println("  \033[32mUse other communication patterns suitable for your target.\033[0m")
+	fmt.Println("  \033[32mUse a string or integer key derived from the value instead.\033[0m")
 	os.Exit(-1)
 }
 
-// PreVisitSelectStmt checks for select statements which are not supported
+// PreVisitSelectStmt checks that select cases receive into variables and that
+// their bodies don't break out of the select
 func (sema *SemaChecker) PreVisitSelectStmt(node *ast.SelectStmt, indent int) {
-	fmt.Println("\033[31m\033[1mCompilation error: select statements are not supported\033[0m")
-	fmt.Println("  Select statements for channel operations are not allowed.")
-	fmt.Println("  Select is Go-specific and has no direct equivalent in target languages.")
-	os.Exit(-1)
+	for _, stmt := range node.Body.List {
+		clause := stmt.(*ast.CommClause)
+		if _, _, recv := selectCaseOp(clause); recv != nil {
+			for _, lhs := range recv.Lhs {
+				if _, ok := lhs.(*ast.Ident); !ok {
+					fmt.Println("\033[31m\033[1mCompilation error: select case receiving into an expression is not supported\033[0m")
+					fmt.Printf("  %s is not a variable.\n", types.ExprString(lhs))
+					fmt.Println()
+					fmt.Println("  \033[32mReceive into a variable and assign it in the case body.\033[0m")
+					os.Exit(-1)
+				}
+			}
+		}
+		for _, bodyStmt := range clause.Body {
+			if pos := clauseBreak(bodyStmt); pos.IsValid() {
+				fmt.Println("\033[31m\033[1mCompilation error: break inside a select statement is not supported\033[0m")
+				fmt.Println("  A break would leave the enclosing loop in some target languages.")
+				fmt.Println()
+				fmt.Println("  \033[32mUse if-else inside the case instead of breaking out of the select.\033[0m")
+				os.Exit(-1)
+			}
+		}
+	}
 }
 
-// PreVisitLabeledStmt checks for labeled statements which are not supported
+// PreVisitLabeledStmt checks that labels name loops or switch statements,
+// the targets of break and continue. Labels for goto are not supported
 func (sema *SemaChecker) PreVisitLabeledStmt(node *ast.LabeledStmt, indent int) {
+	switch node.Stmt.(type) {
+	case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt:
+		return
+	}
 	fmt.Println("\033[31m\033[1mCompilation error: labeled statements are not supported\033[0m")
 	fmt.Printf("  Label '%s:' is not allowed.\n", node.Label.Name)
-	fmt.Println("  Labels and goto have limited support in target languages.")
+	fmt.Println("  Only for loops and switch statements can be labeled, as targets of break and continue.")
 	fmt.Println()
 	fmt.Println("  \033[32mUse structured control flow (functions, loops with break).\033[0m")
 	os.Exit(-1)
@@ -210,31 +604,13 @@ func (sema *SemaChecker) PreVisitBranchStmt(node *ast.BranchStmt, indent int) {
 	}
 }
 
-// PreVisitFuncDecl checks for method receivers, init functions, variadic params, and named returns
+// PreVisitFuncDecl checks for method receivers and init functions
 func (sema *SemaChecker) PreVisitFuncDecl(node *ast.FuncDecl, indent int) {
-	// Reset closure variables for each function to avoid false positives
-	// between closures in different functions
-	sema.closureVars = make(map[string]token.Pos)
 	// Reset range targets for each function
 	sema.rangeTargets = make(map[string]token.Pos)
-	// Reset closure captures for each function
-	sema.closureCaptures = make(map[string]token.Pos)
 	// Track current function's parameters for mutation+return detection
 	sema.currentFuncParams = make(map[string]bool)
 	sema.mutatedParams = make(map[string]bool)
-	// Initialize scope stack for variable shadowing detection
-	sema.scopeStack = []map[string]token.Pos{}
-	// Push initial function scope and add parameters
-	funcScope := make(map[string]token.Pos)
-	if node.Type != nil && node.Type.Params != nil {
-		for _, field := range node.Type.Params.List {
-			for _, name := range field.Names {
-				funcScope[name.Name] = name.Pos()
-			}
-		}
-	}
-	sema.scopeStack = append(sema.scopeStack, funcScope)
-
 	// Collect parameter names for mutation tracking
 	if node.Type != nil && node.Type.Params != nil {
 		for _, field := range node.Type.Params.List {
@@ -244,19 +620,28 @@ func (sema *SemaChecker) PreVisitFuncDecl(node *ast.FuncDecl, indent int) {
 		}
 	}
 
-	// Check for method receivers
+	// Methods are lowered to member functions of the receiver type, so the
+	// receiver must be a struct declared in the same package
 	if node.Recv != nil && len(node.Recv.List) > 0 {
-		fmt.Println("\033[31m\033[1mCompilation error: method receivers are not supported\033[0m")
-		fmt.Printf("  Function '%s' has a receiver, making it a method.\n", node.Name.Name)
-		fmt.Println("  Methods are not allowed; use standalone functions instead.")
-		fmt.Println()
-		fmt.Println("  \033[33mInstead of:\033[0m")
-		fmt.Println("    func (t *Type) Method() { ... }")
-		fmt.Println()
-		fmt.Println("  \033[32mUse:\033[0m")
-		fmt.Println("    func TypeMethod(t Type) Type { ... }")
-		os.Exit(-1)
+		if !sema.isLocalStructType(recvTypeName(node)) {
+			fmt.Println("\033[31m\033[1mCompilation error: methods are only supported on struct types\033[0m")
+			fmt.Printf("  Method '%s' has a receiver that is not a struct type.\n", node.Name.Name)
+			fmt.Println("  Methods become member functions of the receiver type, which requires a struct.")
+			fmt.Println()
+			fmt.Println("  \033[33mInstead of:\033[0m")
+			fmt.Println("    type Celsius float64")
+			fmt.Println("    func (c Celsius) String() string { ... }")
+			fmt.Println()
+			fmt.Println("  \033[32mWrap the value in a struct:\033[0m")
+			fmt.Println("    type Celsius struct { Value float64 }")
+			os.Exit(-1)
+		}
+	}
+
+	if node.Recv == nil {
+		sema.checkTypeParams(node.Name.Name, typeParams(sema.pkg, node))
 	}
+	sema.checkPointerReceiver(node)
 
 	// Check for init functions
 	if node.Name.Name == "init" {
@@ -267,44 +652,9 @@ func (sema *SemaChecker) PreVisitFuncDecl(node *ast.FuncDecl, indent int) {
 		fmt.Println("  \033[32mCall initialization explicitly from main() or use constructors.\033[0m")
 		os.Exit(-1)
 	}
-
-	// Check for variadic parameters
-	if node.Type != nil && node.Type.Params != nil {
-		for _, field := range node.Type.Params.List {
-			if _, ok := field.Type.(*ast.Ellipsis); ok {
-				fmt.Println("\033[31m\033[1mCompilation error: variadic functions are not supported\033[0m")
-				fmt.Printf("  Function '%s' has variadic parameter (...T).\n", node.Name.Name)
-				fmt.Println("  Variadic functions have different semantics across target languages.")
-				fmt.Println()
-				fmt.Println("  \033[32mUse a slice parameter instead:\033[0m")
-				fmt.Println("    func foo(args []T) { ... }")
-				os.Exit(-1)
-			}
-		}
-	}
-
-	// Check for named return values
-	if node.Type != nil && node.Type.Results != nil {
-		for _, field := range node.Type.Results.List {
-			if len(field.Names) > 0 {
-				fmt.Println("\033[31m\033[1mCompilation error: named return values are not supported\033[0m")
-				fmt.Printf("  Function '%s' has named return values.\n", node.Name.Name)
-				fmt.Println("  Named returns have no equivalent in C++, Rust, or JavaScript.")
-				fmt.Println()
-				fmt.Println("  \033[33mInstead of:\033[0m")
-				fmt.Println("    func foo() (result int) { ... }")
-				fmt.Println()
-				fmt.Println("  \033[32mUse:\033[0m")
-				fmt.Println("    func foo() int { ... }")
-				os.Exit(-1)
-			}
-		}
-	}
 }
 
 func (sema *SemaChecker) PreVisitGenDeclConstName(node *ast.Ident, indent int) {
-	sema.constCtx = true
-
 	// Check if the constant is declared without an explicit type
 	if sema.pkg != nil && sema.pkg.TypesInfo != nil {
 		if obj := sema.pkg.TypesInfo.Defs[node]; obj != nil {
@@ -328,13 +678,6 @@ func (sema *SemaChecker) PreVisitGenDeclConstName(node *ast.Ident, indent int) {
 }
 
 func (sema *SemaChecker) PreVisitIdent(node *ast.Ident, indent int) {
-	if sema.constCtx {
-		if node.String() == "iota" {
-			fmt.Println("\033[31m\033[1mCompilation error : iota is not allowed for now\033[0m")
-			os.Exit(-1)
-		}
-	}
-
 	// Check if this identifier was consumed by string concatenation
 	if sema.consumedStringVars != nil {
 		if consumedPos, wasConsumed := sema.consumedStringVars[node.Name]; wasConsumed {
@@ -360,50 +703,7 @@ func (sema *SemaChecker) PreVisitIdent(node *ast.Ident, indent int) {
 	// This pattern is valid in Go, and Rust handles it via .clone()
 }
 
-func (sema *SemaChecker) PostVisitGenDeclConstName(node *ast.Ident, indent int) {
-	sema.constCtx = false
-}
-
-// PostVisitFuncDecl clears the scope stack after function processing
-func (sema *SemaChecker) PostVisitFuncDecl(node *ast.FuncDecl, indent int) {
-	sema.scopeStack = nil
-}
-
-// PreVisitBlockStmt pushes a new scope for variable shadowing detection
-func (sema *SemaChecker) PreVisitBlockStmt(node *ast.BlockStmt, indent int) {
-	if sema.scopeStack != nil {
-		sema.scopeStack = append(sema.scopeStack, make(map[string]token.Pos))
-	}
-}
-
-// PostVisitBlockStmt pops the current scope
-func (sema *SemaChecker) PostVisitBlockStmt(node *ast.BlockStmt, indent int) {
-	if sema.scopeStack != nil && len(sema.scopeStack) > 0 {
-		sema.scopeStack = sema.scopeStack[:len(sema.scopeStack)-1]
-	}
-}
-
-// PreVisitForStmt pushes a scope for the for loop (to contain the init variable)
-// In Go, `for i := 0; ...` creates an implicit scope containing both Init and Body
-func (sema *SemaChecker) PreVisitForStmt(node *ast.ForStmt, indent int) {
-	if sema.scopeStack != nil {
-		sema.scopeStack = append(sema.scopeStack, make(map[string]token.Pos))
-	}
-}
-
-// PostVisitForStmt pops the for loop scope
-func (sema *SemaChecker) PostVisitForStmt(node *ast.ForStmt, indent int) {
-	if sema.scopeStack != nil && len(sema.scopeStack) > 0 {
-		sema.scopeStack = sema.scopeStack[:len(sema.scopeStack)-1]
-	}
-}
-
 func (sema *SemaChecker) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
-	// Push scope for range loop variables (like for i, v := range ...)
-	if sema.scopeStack != nil {
-		sema.scopeStack = append(sema.scopeStack, make(map[string]token.Pos))
-	}
-
 	// Handle for _, v := range (value-only): set Key to nil so emitters work correctly
 	// for i, v := range (key-value) is now allowed and handled by emitters
 	// for i := range (index-only) is allowed (Value is nil)
@@ -415,12 +715,6 @@ func (sema *SemaChecker) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
 		// Otherwise, keep both Key and Value for key-value range loops
 	}
 
-	// Check for range over inline composite literal (e.g., for _, x := range []int{1,2,3})
-	if _, ok := node.X.(*ast.CompositeLit); ok {
-		fmt.Println("\033[31m\033[1mCompilation error : range over inline slice literal (e.g., for _, x := range []int{1,2,3}) is not allowed for now\033[0m")
-		os.Exit(-1)
-	}
-
 	// Track range target for mutation detection
 	if ident, ok := node.X.(*ast.Ident); ok {
 		if sema.rangeTargets == nil {
@@ -429,35 +723,34 @@ func (sema *SemaChecker) PreVisitRangeStmt(node *ast.RangeStmt, indent int) {
 		sema.rangeTargets[ident.Name] = node.Pos()
 
 		// Check for mutations to range target inside the loop body
-		sema.checkRangeBodyMutation(node.Body, ident.Name)
+		sema.checkRangeBodyMutation(node.Body, ident)
 	}
 }
 
-// PostVisitRangeStmt clears the range target after the loop and pops scope
+// PostVisitRangeStmt clears the range target after the loop
 func (sema *SemaChecker) PostVisitRangeStmt(node *ast.RangeStmt, indent int) {
 	if ident, ok := node.X.(*ast.Ident); ok {
 		if sema.rangeTargets != nil {
 			delete(sema.rangeTargets, ident.Name)
 		}
 	}
-	// Pop scope for range loop variables
-	if sema.scopeStack != nil && len(sema.scopeStack) > 0 {
-		sema.scopeStack = sema.scopeStack[:len(sema.scopeStack)-1]
-	}
 }
 
-// checkRangeBodyMutation checks if the range target is mutated inside the loop body
-func (sema *SemaChecker) checkRangeBodyMutation(body *ast.BlockStmt, targetName string) {
+// checkRangeBodyMutation checks if the range target is mutated inside the loop
+// body, a variable of the body with the same name is a different one
+func (sema *SemaChecker) checkRangeBodyMutation(body *ast.BlockStmt, target *ast.Ident) {
 	if body == nil {
 		return
 	}
+	targetName := target.Name
+	targetObj := sema.pkg.TypesInfo.ObjectOf(target)
 
 	ast.Inspect(body, func(n ast.Node) bool {
 		switch stmt := n.(type) {
 		case *ast.AssignStmt:
 			for _, lhs := range stmt.Lhs {
 				if ident, ok := lhs.(*ast.Ident); ok {
-					if ident.Name == targetName {
+					if sema.pkg.TypesInfo.ObjectOf(ident) == targetObj {
 						fmt.Println("\033[31m\033[1mCompilation error: collection mutation during iteration\033[0m")
 						fmt.Printf("  Variable '%s' is modified while being iterated over.\n", targetName)
 						fmt.Println("  This pattern fails in Rust due to borrow checker rules.")
@@ -482,13 +775,23 @@ func (sema *SemaChecker) checkRangeBodyMutation(body *ast.BlockStmt, targetName
 	})
 }
 
-// PreVisitBinaryExpr checks for nil comparisons which are not supported for slices
-// and tracks string variable consumption for Rust compatibility
+// PreVisitBinaryExpr checks that nil comparisons are of types every backend
+// has a nil for, and tracks string variable consumption for Rust compatibility
 func (sema *SemaChecker) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 	// Check for == nil or != nil comparisons
 	if node.Op == token.EQL || node.Op == token.NEQ {
-		if isNilIdent(node.Y) || isNilIdent(node.X) {
-			fmt.Println("\033[31m\033[1mCompilation error : nil comparison (== nil or != nil) is not allowed for now\033[0m")
+		operand := node.X
+		if isNilIdent(node.X) {
+			operand = node.Y
+		}
+		if (isNilIdent(node.Y) || isNilIdent(node.X)) && !isNilableType(sema.pkg.TypesInfo.TypeOf(operand)) {
+			fmt.Println("\033[31m\033[1mCompilation error: nil comparison is not supported for this type\033[0m")
+			fmt.Printf("  '%s' of type %s is compared with nil.\n", types.ExprString(operand), sema.pkg.TypesInfo.TypeOf(operand))
+			fmt.Println("  Only pointers, slices, maps, functions and interface{} values can be nil.")
+			fmt.Println()
+			fmt.Println("  \033[32mTrack whether the value is set separately:\033[0m")
+			fmt.Println("    var shape Shape")
+			fmt.Println("    hasShape := false")
 			os.Exit(-1)
 		}
 	}
@@ -535,15 +838,7 @@ func (sema *SemaChecker) PreVisitBinaryExpr(node *ast.BinaryExpr, indent int) {
 // PreVisitAssignStmt checks for problematic patterns like: x += x + a
 // where x is both borrowed (for +=) and moved (in x + a) in the same statement
 // Also checks for slice self-assignment: slice[i] = slice[j]
-// Also checks for variable shadowing (C# compatibility)
 func (sema *SemaChecker) PreVisitAssignStmt(node *ast.AssignStmt, indent int) {
-	// Check for variable shadowing (C# does not allow shadowing within the same function)
-	sema.checkVariableShadowing(node)
-
-	// Note: Closure capture mutation check (checkClosureCaptureMutation) was too aggressive.
-	// The Rust emitter uses .clone() automatically for struct values, so mutation after
-	// capture is safe in practice. Disabled to avoid false positives.
-
 	// Check for slice self-assignment pattern
 	sema.checkSliceSelfAssignment(node)
 
@@ -618,110 +913,188 @@ func (sema *SemaChecker) PostVisitAssignStmt(node *ast.AssignStmt, indent int) {
 	}
 }
 
-// checkVariableShadowing detects when a new variable declaration shadows an outer scope variable
-// C# does not allow variable shadowing within the same function/method
-func (sema *SemaChecker) checkVariableShadowing(node *ast.AssignStmt) {
-	// Only check short variable declarations (:=)
-	if node.Tok != token.DEFINE {
-		return
+// PreVisitInterfaceType checks for interface types - interfaces with methods
+// must be declared as named types, inline interface literals are only
+// supported when empty (interface{} / any)
+func (sema *SemaChecker) PreVisitInterfaceType(node *ast.InterfaceType, indent int) {
+	// Empty interface (interface{} / any) is supported
+	// Maps to: C++ std::any, Rust Box<dyn Any>, C# object
+	if node.Methods != nil && len(node.Methods.List) > 0 {
+		fmt.Println("\033[31m\033[1mCompilation error: anonymous non-empty interfaces are not supported\033[0m")
+		fmt.Println("  Interfaces with methods must be declared as named types.")
+		fmt.Println("  Backends generate an abstract type per interface declaration.")
+		fmt.Println()
+		fmt.Println("  \033[33mInstead of:\033[0m")
+		fmt.Println("    func draw(s interface{ Area() int }) { ... }")
+		fmt.Println()
+		fmt.Println("  \033[32mDeclare the interface:\033[0m")
+		fmt.Println("    type Shape interface { Area() int }")
+		fmt.Println("    func draw(s Shape) { ... }")
+		os.Exit(-1)
 	}
+}
 
-	if sema.scopeStack == nil || len(sema.scopeStack) == 0 {
-		return
+// PreVisitGenInterfaceInfo checks named interface declarations for embedded
+// interfaces, which are not supported
+func (sema *SemaChecker) PreVisitGenInterfaceInfo(node GenTypeInfo, indent int) {
+	for _, field := range node.Interface.Methods.List {
+		if len(field.Names) > 0 {
+			continue
+		}
+		fmt.Println("\033[31m\033[1mCompilation error: interface embedding is not supported\033[0m")
+		fmt.Printf("  Interface '%s' embeds another interface or type constraint.\n", node.Name)
+		fmt.Println()
+		fmt.Println("  \033[32mList the methods of the embedded interface explicitly.\033[0m")
+		os.Exit(-1)
 	}
+}
 
-	// Get the current scope (last element in the stack)
-	currentScopeIdx := len(sema.scopeStack) - 1
+// PreVisitTypeAssertExpr checks that type assertions target concrete types
+func (sema *SemaChecker) PreVisitTypeAssertExpr(node *ast.TypeAssertExpr, indent int) {
+	if node.Type == nil || sema.pkg == nil {
+		return
+	}
+	if t := sema.pkg.TypesInfo.TypeOf(node.Type); t != nil && types.IsInterface(t) {
+		fmt.Println("\033[31m\033[1mCompilation error: type assertion to an interface type is not supported\033[0m")
+		fmt.Printf("  Asserting to '%s' requires runtime method set checks.\n", t.String())
+		fmt.Println()
+		fmt.Println("  \033[32mAssert to a concrete type instead.\033[0m")
+		os.Exit(-1)
+	}
+}
 
-	// Check each variable being declared
-	for _, lhs := range node.Lhs {
-		ident, ok := lhs.(*ast.Ident)
-		if !ok {
+// checkEmbeddedFields checks that only struct types are embedded, by value.
+// The base pass has named embedded fields after their type.
+func (sema *SemaChecker) checkEmbeddedFields(node *ast.StructType) {
+	if node.Fields == nil {
+		return
+	}
+	for _, field := range node.Fields.List {
+		if len(field.Names) == 0 {
 			continue
 		}
-
-		// Skip blank identifier
-		if ident.Name == "_" {
+		v, ok := sema.pkg.TypesInfo.Defs[field.Names[0]].(*types.Var)
+		if !ok || !v.Embedded() {
 			continue
 		}
-
-		// Check if this variable exists in any outer scope (but not current scope)
-		for i := 0; i < currentScopeIdx; i++ {
-			if prevPos, exists := sema.scopeStack[i][ident.Name]; exists {
-				fmt.Println("\033[31m\033[1mCompilation error: variable shadowing is not supported\033[0m")
-				fmt.Printf("  Variable '%s' shadows a variable from an outer scope.\n", ident.Name)
-				fmt.Println("  C# does not allow variable shadowing within the same function.")
-				fmt.Println()
-				fmt.Println("  \033[33mInstead of:\033[0m")
-				fmt.Printf("    %s := ...  // outer scope\n", ident.Name)
-				fmt.Println("    {")
-				fmt.Printf("        %s := ...  // shadows outer %s\n", ident.Name, ident.Name)
-				fmt.Println("    }")
-				fmt.Println()
-				fmt.Println("  \033[32mUse a different variable name:\033[0m")
-				fmt.Printf("    %s := ...  // outer scope\n", ident.Name)
-				fmt.Println("    {")
-				fmt.Printf("        %s2 := ...  // or another descriptive name\n", ident.Name)
-				fmt.Println("    }")
-				_ = prevPos // suppress unused variable warning
-				os.Exit(-1)
+		if _, isStruct := v.Type().Underlying().(*types.Struct); isStruct {
+			if _, isNamed := v.Type().(*types.Named); isNamed {
+				continue
 			}
 		}
-
-		// Add this variable to the current scope
-		sema.scopeStack[currentScopeIdx][ident.Name] = ident.Pos()
-	}
-}
-
-// PreVisitInterfaceType checks for interface types - only empty interface{} is supported
-func (sema *SemaChecker) PreVisitInterfaceType(node *ast.InterfaceType, indent int) {
-	// Empty interface (interface{} / any) is supported
-	// Maps to: C++ std::any, Rust Box<dyn Any>, C# object
-	// Non-empty interfaces are NOT supported
-	if node.Methods != nil && len(node.Methods.List) > 0 {
-		fmt.Println("\033[31m\033[1mCompilation error: non-empty interfaces are not supported\033[0m")
-		fmt.Println("  Only empty interface (interface{} / any) is allowed.")
-		fmt.Println("  Interfaces with methods have no direct equivalent in all target languages.")
+		fmt.Println("\033[31m\033[1mCompilation error: only struct types can be embedded\033[0m")
+		fmt.Printf("  Embedded field '%s' has type %s.\n", v.Name(), v.Type().String())
+		fmt.Println("  Embedded pointers and interfaces have no field to delegate to in the target languages.")
 		fmt.Println()
-		fmt.Println("  \033[32mUse concrete types or interface{} with type assertions.\033[0m")
+		fmt.Println("  \033[32mUse a named field:\033[0m")
+		fmt.Printf("    type MyStruct struct { field %s }\n", types.TypeString(v.Type(), types.RelativeTo(sema.pkg.Types)))
 		os.Exit(-1)
 	}
 }
 
-// PreVisitStructType checks for struct embedding (anonymous fields) which is not supported
-func (sema *SemaChecker) PreVisitStructType(node *ast.StructType, indent int) {
-	if node.Fields == nil {
+// PreVisitGenStructInfo checks that a struct implements interfaces with its
+// own methods. Backends don't generate methods forwarding to an embedded
+// struct, so methods promoted from it can't satisfy an interface.
+func (sema *SemaChecker) PreVisitGenStructInfo(node GenTypeInfo, indent int) {
+	if sema.pkg == nil {
 		return
 	}
-	for _, field := range node.Fields.List {
-		// Anonymous field (embedding) has no names
-		if len(field.Names) == 0 {
-			// Get the embedded type name for the error message
-			typeName := "unknown"
-			switch t := field.Type.(type) {
-			case *ast.Ident:
-				typeName = t.Name
-			case *ast.SelectorExpr:
-				typeName = t.Sel.Name
+	obj := sema.pkg.Types.Scope().Lookup(node.Name)
+	if obj == nil {
+		return
+	}
+	if node.Struct != nil {
+		sema.checkEmbeddedFields(node.Struct)
+	}
+	for _, iface := range implementedInterfaces(sema.pkg.Types, obj.Type()) {
+		methods := iface.Underlying().(*types.Interface)
+		for i := 0; i < methods.NumMethods(); i++ {
+			_, index, _ := types.LookupFieldOrMethod(obj.Type(), false, sema.pkg.Types, methods.Method(i).Name())
+			if len(index) < 2 {
+				continue
 			}
-			fmt.Println("\033[31m\033[1mCompilation error: struct embedding is not supported\033[0m")
-			fmt.Printf("  Embedded field '%s' (anonymous field) is not allowed.\n", typeName)
-			fmt.Println("  Struct embedding has different semantics in target languages.")
+			fmt.Println("\033[31m\033[1mCompilation error: interface satisfied by a promoted method is not supported\033[0m")
+			fmt.Printf("  Struct '%s' implements '%s' through method '%s' of an embedded struct.\n", node.Name, iface.Obj().Name(), methods.Method(i).Name())
 			fmt.Println()
-			fmt.Println("  \033[33mInstead of:\033[0m")
-			fmt.Printf("    type MyStruct struct { %s }\n", typeName)
-			fmt.Println()
-			fmt.Println("  \033[32mUse named field:\033[0m")
-			fmt.Printf("    type MyStruct struct { field %s }\n", typeName)
+			fmt.Println("  \033[32mDeclare the method on the struct and call the embedded one:\033[0m")
+			fmt.Printf("    func (s %s) %s(...) { return s.Embedded.%s(...) }\n", node.Name, methods.Method(i).Name(), methods.Method(i).Name())
 			os.Exit(-1)
 		}
 	}
 }
 
-// PreVisitTypeSwitchStmt checks for type switch statements (not supported)
+// PreVisitTypeSwitchStmt checks the clauses of a type switch. Backends lower
+// type switches to if-else chains, so cases must name concrete types and a
+// break can't leave the switch. Named types other than structs are aliases of
+// their underlying type, so two cases can't share an underlying type
 func (sema *SemaChecker) PreVisitTypeSwitchStmt(node *ast.TypeSwitchStmt, indent int) {
-	fmt.Println("\033[31m\033[1mCompilation error : type switch statement is not allowed for now\033[0m")
-	os.Exit(-1)
+	if sema.pkg == nil {
+		return
+	}
+	var caseTypes []types.Type
+	for _, stmt := range node.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		for _, expr := range clause.List {
+			if isNilIdent(expr) {
+				fmt.Println("\033[31m\033[1mCompilation error: nil case in a type switch is not supported\033[0m")
+				fmt.Println("  Type switches are lowered to a test of the dynamic type for each case,")
+				fmt.Println("  and a nil value has no dynamic type to test.")
+				fmt.Println()
+				fmt.Println("  \033[32mCompare an interface{} value with nil before the switch:\033[0m")
+				fmt.Println("    if v == nil { ... } else { switch x := v.(type) { ... } }")
+				os.Exit(-1)
+			}
+			if t := sema.pkg.TypesInfo.TypeOf(expr); t != nil && types.IsInterface(t) {
+				fmt.Println("\033[31m\033[1mCompilation error: type switch case on an interface type is not supported\033[0m")
+				fmt.Printf("  Case '%s' requires runtime method set checks.\n", t.String())
+				fmt.Println()
+				fmt.Println("  \033[32mList the concrete types instead.\033[0m")
+				os.Exit(-1)
+			}
+			t := sema.pkg.TypesInfo.TypeOf(expr)
+			if t == nil {
+				continue
+			}
+			for _, other := range caseTypes {
+				if types.Identical(runtimeType(t), runtimeType(other)) {
+					fmt.Println("\033[31m\033[1mCompilation error: type switch cases with the same underlying type are not supported\033[0m")
+					fmt.Printf("  Cases '%s' and '%s' are both emitted as '%s'.\n", other.String(), t.String(), runtimeType(t).String())
+					fmt.Println()
+					fmt.Println("  \033[32mWrap one of the values in a struct type to tell them apart.\033[0m")
+					os.Exit(-1)
+				}
+			}
+			caseTypes = append(caseTypes, t)
+		}
+		for _, bodyStmt := range clause.Body {
+			if pos := clauseBreak(bodyStmt); pos.IsValid() {
+				fmt.Println("\033[31m\033[1mCompilation error: break inside a type switch is not supported\033[0m")
+				fmt.Println("  Type switches are lowered to if-else chains, a break would leave the enclosing loop.")
+				fmt.Println()
+				fmt.Println("  \033[32mUse if-else inside the case instead of breaking out of the switch.\033[0m")
+				os.Exit(-1)
+			}
+		}
+	}
+}
+
+// clauseBreak returns the position of an unlabeled break in stmt that would
+// leave an enclosing type switch or select, or token.NoPos
+func clauseBreak(stmt ast.Stmt) token.Pos {
+	pos := token.NoPos
+	ast.Inspect(stmt, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt, *ast.SelectStmt, *ast.FuncLit:
+			// A break in these belongs to them
+			return false
+		case *ast.BranchStmt:
+			if n.Tok == token.BREAK && n.Label == nil && !pos.IsValid() {
+				pos = n.Pos()
+			}
+		}
+		return true
+	})
+	return pos
 }
 
 // isNilIdent checks if an expression is the nil identifier
@@ -732,6 +1105,16 @@ func isNilIdent(expr ast.Expr) bool {
 	return false
 }
 
+// runtimeType returns the type the backends give values of t: named struct
+// types are distinct types, other named types are aliases of their underlying
+// type
+func runtimeType(t types.Type) types.Type {
+	if _, isStruct := t.Underlying().(*types.Struct); isStruct {
+		return t
+	}
+	return t.Underlying()
+}
+
 // PreVisitCompositeLit checks for struct field initialization order
 // C++ designated initializers require fields to be in declaration order
 func (sema *SemaChecker) PreVisitCompositeLit(node *ast.CompositeLit, indent int) {
@@ -745,6 +1128,23 @@ func (sema *SemaChecker) PreVisitCompositeLit(node *ast.CompositeLit, indent int
 		return
 	}
 
+	// Array literals list their elements in order, the backends fill the rest with zero values
+	if _, isArray := tv.Type.Underlying().(*types.Array); isArray {
+		for _, elt := range node.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				fmt.Println("\033[31m\033[1mCompilation error: indexed array literal elements are not supported\033[0m")
+				fmt.Printf("  Element at index %s is given by its index.\n", types.ExprString(kv.Key))
+				fmt.Println("  Array literals must list their elements in order.")
+				fmt.Println()
+				fmt.Println("  \033[32mList the elements in order, or assign them after the declaration:\033[0m")
+				fmt.Println("    var a [4]int")
+				fmt.Println("    a[2] = 1")
+				os.Exit(-1)
+			}
+		}
+		return
+	}
+
 	// Check if it's a struct type
 	structType, ok := tv.Type.Underlying().(*types.Struct)
 	if !ok {
@@ -828,38 +1228,17 @@ func (sema *SemaChecker) isNonCopyType(t types.Type) bool {
 	return false
 }
 
-// collectIdentifiers collects identifiers that are actually "consumed" (moved) in an expression
-// It excludes identifiers used as the base of selector expressions (field access doesn't move)
-// and identifiers that are the Sel part of selector expressions (field names)
-func (sema *SemaChecker) collectIdentifiers(node ast.Node) []*ast.Ident {
-	var idents []*ast.Ident
-	// Track identifiers that are used as selector bases (field access doesn't move)
-	selectorBases := make(map[*ast.Ident]bool)
-	// Track identifiers that are field names (the Sel part of SelectorExpr)
-	selectorFields := make(map[*ast.Ident]bool)
-
-	// First pass: find all selector bases and field names
-	ast.Inspect(node, func(n ast.Node) bool {
-		if sel, ok := n.(*ast.SelectorExpr); ok {
-			if ident, ok := sel.X.(*ast.Ident); ok {
-				selectorBases[ident] = true
-			}
-			// The Sel part is always the field name, not a variable
-			selectorFields[sel.Sel] = true
-		}
-		return true
-	})
-
-	// Second pass: collect identifiers that are not selector bases or field names
-	ast.Inspect(node, func(n ast.Node) bool {
-		if ident, ok := n.(*ast.Ident); ok {
-			if !selectorBases[ident] && !selectorFields[ident] {
-				idents = append(idents, ident)
-			}
-		}
-		return true
-	})
-	return idents
+// isLocalStructType checks if name refers to a struct type declared in the current package
+func (sema *SemaChecker) isLocalStructType(name string) bool {
+	if name == "" || sema.pkg == nil || sema.pkg.Types == nil {
+		return false
+	}
+	obj, ok := sema.pkg.Types.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return false
+	}
+	_, isStruct := obj.Type().Underlying().(*types.Struct)
+	return isStruct
 }
 
 // getDirectFunctionArgs returns variable names passed directly to a function call
@@ -1043,43 +1422,6 @@ func (sema *SemaChecker) collectAllIdentsInExpr(expr ast.Expr) []*ast.Ident {
 	return idents
 }
 
-// checkClosureCaptureMutation checks if a variable captured by a closure is mutated after capture
-func (sema *SemaChecker) checkClosureCaptureMutation(node *ast.AssignStmt) {
-	if sema.closureCaptures == nil || len(sema.closureCaptures) == 0 {
-		return
-	}
-	if sema.pkg == nil || sema.pkg.TypesInfo == nil {
-		return
-	}
-
-	for _, lhs := range node.Lhs {
-		if ident, ok := lhs.(*ast.Ident); ok {
-			if capturePos, captured := sema.closureCaptures[ident.Name]; captured {
-				// Check if this assignment is after the closure capture
-				if node.Pos() > capturePos {
-					// Check if it's a non-Copy type
-					if obj := sema.pkg.TypesInfo.Uses[ident]; obj != nil {
-						if sema.isNonCopyType(obj.Type()) {
-							fmt.Println("\033[31m\033[1mCompilation error: mutation of variable after closure capture\033[0m")
-							fmt.Printf("  Variable '%s' is captured by a closure and then mutated.\n", ident.Name)
-							fmt.Println("  This pattern fails in Rust due to ownership rules.")
-							fmt.Println()
-							fmt.Println("  \033[33mInstead of:\033[0m")
-							fmt.Printf("    fn := func() { use(%s) }\n", ident.Name)
-							fmt.Printf("    %s = modify(%s)  // mutation after capture\n", ident.Name, ident.Name)
-							fmt.Println()
-							fmt.Println("  \033[32mMutate before capture or use separate variables:\033[0m")
-							fmt.Printf("    %s = modify(%s)\n", ident.Name, ident.Name)
-							fmt.Printf("    fn := func() { use(%s) }  // capture after mutation\n", ident.Name)
-							os.Exit(-1)
-						}
-					}
-				}
-			}
-		}
-	}
-}
-
 // checkSliceSelfAssignment checks for slice self-reference patterns that cause Rust borrow issues:
 // - slice[i] = slice[j] (direct self-assignment)
 // - slice[i] = slice[i] + slice[j] (self-reference in expression)
@@ -1182,75 +1524,3 @@ func (sema *SemaChecker) isCopyType(t types.Type) bool {
 	// Strings, slices, structs, etc. are NOT Copy (they're moved)
 	return false
 }
-
-// PreVisitFuncLit checks for multiple closures capturing the same non-Copy variable
-func (sema *SemaChecker) PreVisitFuncLit(node *ast.FuncLit, indent int) {
-	if sema.pkg == nil || sema.pkg.TypesInfo == nil {
-		return
-	}
-
-	// Collect all identifiers used in the closure body
-	idents := sema.collectIdentifiers(node.Body)
-
-	// Dedupe: track which variables we've already processed for this closure
-	processedInThisClosure := make(map[string]bool)
-
-	for _, ident := range idents {
-		// Skip if we've already processed this variable name in this closure
-		if processedInThisClosure[ident.Name] {
-			continue
-		}
-
-		// Get the object this identifier refers to
-		if obj := sema.pkg.TypesInfo.Uses[ident]; obj != nil {
-			// Skip constants, functions, and type names
-			if _, isConst := obj.(*types.Const); isConst {
-				continue
-			}
-			if _, isFunc := obj.(*types.Func); isFunc {
-				continue
-			}
-			if _, isTypeName := obj.(*types.TypeName); isTypeName {
-				continue
-			}
-
-			// Check if it's a non-Copy type
-			if !sema.isNonCopyType(obj.Type()) {
-				continue
-			}
-
-			// Check if this is a variable from an outer scope (closure capture)
-			// by verifying it was declared before this function literal
-			if obj.Pos() < node.Pos() {
-				// Mark as processed for this closure
-				processedInThisClosure[ident.Name] = true
-
-				// Check if another closure already captured this variable
-				if prevPos, exists := sema.closureVars[ident.Name]; exists {
-					fmt.Println("\033[31m\033[1mCompilation error: multiple closures capture same variable\033[0m")
-					fmt.Printf("  Variable '%s' (non-Copy type) is captured by multiple closures.\n", ident.Name)
-					fmt.Println("  This causes Rust borrow checker issues.")
-					fmt.Println()
-					fmt.Println("  \033[33mInstead of:\033[0m")
-					fmt.Printf("    fn1 := func() { use(%s) }\n", ident.Name)
-					fmt.Printf("    fn2 := func() { use(%s) }  // error: already captured\n", ident.Name)
-					fmt.Println()
-					fmt.Println("  \033[32mUse Arc/Rc for shared ownership or redesign:\033[0m")
-					fmt.Printf("    %s1 := %s.clone()\n", ident.Name, ident.Name)
-					fmt.Printf("    fn1 := func() { use(%s) }\n", ident.Name)
-					fmt.Printf("    fn2 := func() { use(%s1) }\n", ident.Name)
-					_ = prevPos // suppress unused variable warning
-					os.Exit(-1)
-				}
-				// Mark this variable as captured by a closure
-				sema.closureVars[ident.Name] = node.Pos()
-				// Also track for mutation-after-capture detection
-				if sema.closureCaptures == nil {
-					sema.closureCaptures = make(map[string]token.Pos)
-				}
-				sema.closureCaptures[ident.Name] = node.Pos()
-			}
-		}
-	}
-}
-
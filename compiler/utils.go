@@ -3,9 +3,18 @@ package compiler
 import (
 	"errors"
 	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"unicode"
+
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
 )
 
 // TopologicalSort performs a topological sort on the given graph.
@@ -353,3 +362,2283 @@ func (gir *GoFIR) emitStringAsToken(s string, tokenType TokenType, pointer Visit
 	token := CreateToken(tokenType, s)
 	return gir.emitTokenToFileBuffer(token, pointer)
 }
+
+// isMapExpr reports whether expr is a value of map type
+func isMapExpr(pkg *packages.Package, expr ast.Expr) bool {
+	if pkg == nil || pkg.TypesInfo == nil || expr == nil {
+		return false
+	}
+	t := pkg.TypesInfo.TypeOf(expr)
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Map)
+	return ok
+}
+
+// stringRangeNames returns the key and value names of a range over a string,
+// using "_" for the ones that are omitted or blank
+func stringRangeNames(pkg *packages.Package, renames map[types.Object]string, node *ast.RangeStmt) (string, string) {
+	name := func(e ast.Expr) string {
+		if ident, ok := e.(*ast.Ident); ok {
+			return localName(pkg, renames, ident)
+		}
+		return "_"
+	}
+	return name(node.Key), name(node.Value)
+}
+
+// runeLiteralValue returns the code point of a non-ASCII rune literal, which
+// the backends emit numerically since their character types are not runes
+func runeLiteralValue(pkg *packages.Package, lit *ast.BasicLit) (int64, bool) {
+	if lit.Kind != token.CHAR || pkg == nil || pkg.TypesInfo == nil {
+		return 0, false
+	}
+	tv, ok := pkg.TypesInfo.Types[lit]
+	if !ok || tv.Value == nil {
+		return 0, false
+	}
+	v, exact := constant.Int64Val(constant.ToInt(tv.Value))
+	if !exact || v < 128 {
+		return 0, false
+	}
+	return v, true
+}
+
+// isMapTypeExpr reports whether expr denotes a map type, e.g. the first argument of make(map[K]V)
+func isMapTypeExpr(pkg *packages.Package, expr ast.Expr) bool {
+	if pkg == nil || pkg.TypesInfo == nil || expr == nil {
+		return false
+	}
+	tv, ok := pkg.TypesInfo.Types[expr]
+	if !ok || !tv.IsType() {
+		return false
+	}
+	_, ok = tv.Type.Underlying().(*types.Map)
+	return ok
+}
+
+// isChanTypeExpr reports whether expr denotes a channel type, e.g. the first argument of make(chan T)
+func isChanTypeExpr(pkg *packages.Package, expr ast.Expr) bool {
+	if pkg == nil || pkg.TypesInfo == nil || expr == nil {
+		return false
+	}
+	tv, ok := pkg.TypesInfo.Types[expr]
+	if !ok || !tv.IsType() {
+		return false
+	}
+	_, ok = tv.Type.Underlying().(*types.Chan)
+	return ok
+}
+
+// isSliceTypeExpr reports whether expr denotes a slice type, e.g. the first argument of make([]T, n)
+func isSliceTypeExpr(pkg *packages.Package, expr ast.Expr) bool {
+	if pkg == nil || pkg.TypesInfo == nil || expr == nil {
+		return false
+	}
+	tv, ok := pkg.TypesInfo.Types[expr]
+	if !ok || !tv.IsType() {
+		return false
+	}
+	_, ok = tv.Type.Underlying().(*types.Slice)
+	return ok
+}
+
+// isSliceExpr reports whether expr is a slice value
+func isSliceExpr(pkg *packages.Package, expr ast.Expr) bool {
+	t := pkg.TypesInfo.TypeOf(expr)
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Slice)
+	return ok
+}
+
+// isMapIndexExpr reports whether expr is a map lookup m[k]
+func isMapIndexExpr(pkg *packages.Package, expr ast.Expr) bool {
+	indexExpr, ok := expr.(*ast.IndexExpr)
+	return ok && isMapExpr(pkg, indexExpr.X)
+}
+
+// isSliceIndexExpr reports whether expr indexes a slice or a string, the
+// indexes Go checks against the length at run time
+func isSliceIndexExpr(pkg *packages.Package, expr ast.Expr) bool {
+	indexExpr, ok := expr.(*ast.IndexExpr)
+	if !ok {
+		return false
+	}
+	tv, ok := pkg.TypesInfo.Types[indexExpr.X]
+	if !ok || tv.Type == nil {
+		return false
+	}
+	_, isSlice := tv.Type.Underlying().(*types.Slice)
+	return isSlice || isStringExpr(pkg, indexExpr.X)
+}
+
+// isArrayExpr reports whether expr is a fixed-size array value
+func isArrayExpr(pkg *packages.Package, expr ast.Expr) bool {
+	tv, ok := pkg.TypesInfo.Types[expr]
+	if !ok || tv.Type == nil {
+		return false
+	}
+	_, isArray := tv.Type.Underlying().(*types.Array)
+	return isArray
+}
+
+// isChanExpr reports whether expr is a channel value
+func isChanExpr(pkg *packages.Package, expr ast.Expr) bool {
+	t := pkg.TypesInfo.TypeOf(expr)
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Chan)
+	return ok
+}
+
+// calledFunc returns the function or method call calls statically, nil for
+// calls of function values, builtins and conversions
+func calledFunc(pkg *packages.Package, call *ast.CallExpr) types.Object {
+	fun := ast.Unparen(call.Fun)
+	switch f := fun.(type) {
+	case *ast.IndexExpr:
+		fun = f.X
+	case *ast.IndexListExpr:
+		fun = f.X
+	}
+	var ident *ast.Ident
+	switch f := fun.(type) {
+	case *ast.Ident:
+		ident = f
+	case *ast.SelectorExpr:
+		ident = f.Sel
+	default:
+		return nil
+	}
+	fn, ok := pkg.TypesInfo.Uses[ident].(*types.Func)
+	if !ok {
+		return nil
+	}
+	return fn.Origin()
+}
+
+// isChanRecv reports whether expr receives from a channel: <-ch
+func isChanRecv(expr ast.Expr) bool {
+	unary, ok := ast.Unparen(expr).(*ast.UnaryExpr)
+	return ok && unary.Op == token.ARROW
+}
+
+// isChanRecvCommaOk reports whether node is a comma-ok receive: v, ok := <-ch
+func isChanRecvCommaOk(node *ast.AssignStmt) bool {
+	return len(node.Lhs) == 2 && len(node.Rhs) == 1 && isChanRecv(node.Rhs[0])
+}
+
+// selectCaseOp returns the channel of a select case, the value a send case
+// sends and the assignment of the value a receive case receives. The value
+// and the assignment are nil when absent.
+func selectCaseOp(clause *ast.CommClause) (ast.Expr, ast.Expr, *ast.AssignStmt) {
+	switch comm := clause.Comm.(type) {
+	case *ast.SendStmt:
+		return comm.Chan, comm.Value, nil
+	case *ast.ExprStmt:
+		return ast.Unparen(comm.X).(*ast.UnaryExpr).X, nil, nil
+	case *ast.AssignStmt:
+		return ast.Unparen(comm.Rhs[0]).(*ast.UnaryExpr).X, nil, comm
+	}
+	return nil, nil, nil
+}
+
+// selectCaseNames returns the names a select case receives into, the value
+// and the ok flag, "" when absent or blank. Variables the case declares may
+// be renamed by shadowRenames
+func selectCaseNames(pkg *packages.Package, renames map[types.Object]string, clause *ast.CommClause) (string, string) {
+	_, _, recv := selectCaseOp(clause)
+	if recv == nil {
+		return "", ""
+	}
+	names := []string{"", ""}
+	for i, lhs := range recv.Lhs {
+		if ident, ok := lhs.(*ast.Ident); ok && ident.Name != "_" {
+			names[i] = localName(pkg, renames, ident)
+		}
+	}
+	return names[0], names[1]
+}
+
+// selectHasDefault reports whether a select statement has a default clause
+func selectHasDefault(node *ast.SelectStmt) bool {
+	for _, stmt := range node.Body.List {
+		if stmt.(*ast.CommClause).Comm == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isStringExpr reports whether expr is a string value
+func isStringExpr(pkg *packages.Package, expr ast.Expr) bool {
+	tv, ok := pkg.TypesInfo.Types[expr]
+	if !ok || tv.Type == nil {
+		return false
+	}
+	basic, ok := tv.Type.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsString != 0
+}
+
+// isMapMakeCall reports whether node is make(map[K]V) or make(map[K]V, hint)
+func isMapMakeCall(pkg *packages.Package, node *ast.CallExpr) bool {
+	ident, ok := node.Fun.(*ast.Ident)
+	if !ok || ident.Name != "make" || len(node.Args) == 0 {
+		return false
+	}
+	return isMapTypeExpr(pkg, node.Args[0])
+}
+
+// isChanMakeCall reports whether node is make(chan T) or make(chan T, size)
+func isChanMakeCall(pkg *packages.Package, node *ast.CallExpr) bool {
+	ident, ok := node.Fun.(*ast.Ident)
+	if !ok || ident.Name != "make" || len(node.Args) == 0 {
+		return false
+	}
+	return isChanTypeExpr(pkg, node.Args[0])
+}
+
+// isSliceMakeCall reports whether node is make([]T, n) or make([]T, n, c)
+func isSliceMakeCall(pkg *packages.Package, node *ast.CallExpr) bool {
+	ident, ok := node.Fun.(*ast.Ident)
+	if !ok || ident.Name != "make" || len(node.Args) == 0 {
+		return false
+	}
+	return isSliceTypeExpr(pkg, node.Args[0])
+}
+
+// isMapCommaOk reports whether node is a comma-ok lookup: v, ok := m[k]
+func isMapCommaOk(pkg *packages.Package, node *ast.AssignStmt) bool {
+	return len(node.Lhs) == 2 && len(node.Rhs) == 1 && isMapIndexExpr(pkg, node.Rhs[0])
+}
+
+// recvTypeName returns the name of the receiver base type of a method, or "" for plain functions
+func recvTypeName(node *ast.FuncDecl) string {
+	if node.Recv == nil || len(node.Recv.List) == 0 {
+		return ""
+	}
+	typ := node.Recv.List[0].Type
+	if star, ok := typ.(*ast.StarExpr); ok {
+		typ = star.X
+	}
+	// The receiver of a generic type lists its type parameters, S[T]
+	switch generic := typ.(type) {
+	case *ast.IndexExpr:
+		typ = generic.X
+	case *ast.IndexListExpr:
+		typ = generic.X
+	}
+	if ident, ok := typ.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// recvName returns the receiver identifier of a method, or "" when it is unnamed or blank
+func recvName(node *ast.FuncDecl) string {
+	if node.Recv == nil || len(node.Recv.List) == 0 || len(node.Recv.List[0].Names) == 0 {
+		return ""
+	}
+	if name := node.Recv.List[0].Names[0].Name; name != "_" {
+		return name
+	}
+	return ""
+}
+
+// hasPointerRecv reports whether node is a method with a pointer receiver (*T)
+func hasPointerRecv(node *ast.FuncDecl) bool {
+	if node.Recv == nil || len(node.Recv.List) == 0 {
+		return false
+	}
+	_, ok := node.Recv.List[0].Type.(*ast.StarExpr)
+	return ok
+}
+
+// isMethodSelector reports whether sel selects a method of a value, e.g. c.Inc in c.Inc()
+func isMethodSelector(pkg *packages.Package, sel *ast.SelectorExpr) bool {
+	if pkg == nil || pkg.TypesInfo == nil {
+		return false
+	}
+	selection, ok := pkg.TypesInfo.Selections[sel]
+	return ok && selection.Kind() == types.MethodVal
+}
+
+// isPointerMethodSelector reports whether sel selects a method declared with a pointer receiver
+func isPointerMethodSelector(pkg *packages.Package, sel *ast.SelectorExpr) bool {
+	if !isMethodSelector(pkg, sel) {
+		return false
+	}
+	sig, ok := pkg.TypesInfo.Selections[sel].Obj().Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return false
+	}
+	_, isPtr := sig.Recv().Type().(*types.Pointer)
+	return isPtr
+}
+
+// hasMethods reports whether t is a named type with at least one method declared on it
+func hasMethods(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	return ok && named.NumMethods() > 0
+}
+
+// isNonEmptyInterface reports whether t is an interface type with methods
+func isNonEmptyInterface(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	if _, ok := t.(*types.TypeParam); ok {
+		return false
+	}
+	iface, ok := t.Underlying().(*types.Interface)
+	return ok && iface.NumMethods() > 0
+}
+
+// isTypeAssertCommaOk reports whether node is a comma-ok type assertion (v, ok := x.(T))
+func isTypeAssertCommaOk(node *ast.AssignStmt) bool {
+	if len(node.Lhs) != 2 || len(node.Rhs) != 1 {
+		return false
+	}
+	_, ok := node.Rhs[0].(*ast.TypeAssertExpr)
+	return ok
+}
+
+// implementedInterfaces returns the named non-empty interfaces declared in pkg or
+// in the packages it imports that the type t implements. Interfaces are listed by
+// package path and name, so the result is deterministic.
+func implementedInterfaces(pkg *types.Package, t types.Type) []*types.Named {
+	var result []*types.Named
+	visited := make(map[*types.Package]bool)
+	var visit func(p *types.Package)
+	visit = func(p *types.Package) {
+		if visited[p] {
+			return
+		}
+		visited[p] = true
+		scope := p.Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() {
+				continue
+			}
+			named, ok := typeName.Type().(*types.Named)
+			if !ok || !isNonEmptyInterface(named) {
+				continue
+			}
+			if types.Implements(t, named.Underlying().(*types.Interface)) {
+				result = append(result, named)
+			}
+		}
+		imports := append([]*types.Package(nil), p.Imports()...)
+		sort.Slice(imports, func(i, j int) bool { return imports[i].Path() < imports[j].Path() })
+		for _, imp := range imports {
+			visit(imp)
+		}
+	}
+	visit(pkg)
+	return result
+}
+
+// enclosingFuncResults returns the result types of the innermost function, the
+// declaration itself or a function literal in it, whose body contains pos
+func enclosingFuncResults(pkg *packages.Package, decl *ast.FuncDecl, pos token.Pos) *types.Tuple {
+	if decl == nil {
+		return nil
+	}
+	var sig *types.Signature
+	if obj := pkg.TypesInfo.Defs[decl.Name]; obj != nil {
+		sig, _ = obj.Type().(*types.Signature)
+	}
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		lit, ok := n.(*ast.FuncLit)
+		if !ok || pos <= lit.Body.Lbrace || pos >= lit.Body.Rbrace {
+			return n == nil || (n.Pos() <= pos && pos < n.End())
+		}
+		if litSig, ok := pkg.TypesInfo.TypeOf(lit).(*types.Signature); ok {
+			sig = litSig
+		}
+		return true
+	})
+	if sig == nil {
+		return nil
+	}
+	return sig.Results()
+}
+
+// typeSwitchSubject returns the value x of a type switch, switch v := x.(type),
+// and the identifier v bound to it, nil when the switch binds no variable
+func typeSwitchSubject(node *ast.TypeSwitchStmt) (ast.Expr, *ast.Ident) {
+	switch assign := node.Assign.(type) {
+	case *ast.AssignStmt:
+		return assign.Rhs[0].(*ast.TypeAssertExpr).X, assign.Lhs[0].(*ast.Ident)
+	case *ast.ExprStmt:
+		return assign.X.(*ast.TypeAssertExpr).X, nil
+	}
+	return nil, nil
+}
+
+// typeSwitchClauses returns the clauses of a type switch with the default
+// clause moved last, so that backends can lower the switch to an if-else chain
+func typeSwitchClauses(node *ast.TypeSwitchStmt) []*ast.CaseClause {
+	var clauses []*ast.CaseClause
+	var defaultClause *ast.CaseClause
+	for _, stmt := range node.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			defaultClause = clause
+			continue
+		}
+		clauses = append(clauses, clause)
+	}
+	if defaultClause != nil {
+		clauses = append(clauses, defaultClause)
+	}
+	return clauses
+}
+
+// typeSwitchClauseVar returns the variable a type switch binds in a clause, or
+// nil when the switch binds no variable or the clause body doesn't use it
+func typeSwitchClauseVar(pkg *packages.Package, clause *ast.CaseClause) *types.Var {
+	obj, ok := pkg.TypesInfo.Implicits[clause].(*types.Var)
+	if !ok {
+		return nil
+	}
+	used := false
+	for _, stmt := range clause.Body {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if ident, ok := n.(*ast.Ident); ok && pkg.TypesInfo.Uses[ident] == obj {
+				used = true
+			}
+			return !used
+		})
+	}
+	if !used {
+		return nil
+	}
+	return obj
+}
+
+// constSpecValue returns the expression emitted for the i-th name of a
+// constant spec. Values that depend on iota, and names that implicitly repeat
+// the previous expression, are replaced by a literal holding the value go/types
+// evaluated for the constant
+func constSpecValue(pkg *packages.Package, spec *ast.ValueSpec, i int) ast.Expr {
+	if i < len(spec.Values) && !usesIota(pkg, spec.Values[i]) {
+		return spec.Values[i]
+	}
+	con, ok := pkg.TypesInfo.Defs[spec.Names[i]].(*types.Const)
+	if !ok {
+		return nil
+	}
+	return constantLit(con.Val())
+}
+
+// usesIota reports whether expr refers to the predeclared iota
+func usesIota(pkg *packages.Package, expr ast.Expr) bool {
+	iota := types.Universe.Lookup("iota")
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && pkg.TypesInfo.Uses[ident] == iota {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// constantLit builds a literal expression for a constant value
+func constantLit(val constant.Value) ast.Expr {
+	switch val.Kind() {
+	case constant.Int:
+		return &ast.BasicLit{Kind: token.INT, Value: val.ExactString()}
+	case constant.Float:
+		f, _ := constant.Float64Val(val)
+		str := strconv.FormatFloat(f, 'g', -1, 64)
+		if !strings.ContainsAny(str, ".e") {
+			str += ".0"
+		}
+		return &ast.BasicLit{Kind: token.FLOAT, Value: str}
+	case constant.String:
+		return &ast.BasicLit{Kind: token.STRING, Value: val.ExactString()}
+	case constant.Bool:
+		return &ast.Ident{Name: val.String()}
+	}
+	return &ast.BasicLit{Kind: token.INT, Value: val.ExactString()}
+}
+
+// namesIdent reports whether the type expression expr is the identifier name
+func namesIdent(expr ast.Expr, name string) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// packageVars returns the package-level variables of pkg in the order Go
+// initializes them: zero-initialized variables first, then the variables with
+// initializers in dependency order
+func packageVars(pkg *packages.Package) []PackageVar {
+	type declared struct {
+		spec  *ast.ValueSpec
+		index int
+	}
+	decls := make(map[types.Object]declared)
+	var vars []PackageVar
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range genDecl.Specs {
+				valueSpec := spec.(*ast.ValueSpec)
+				for i, name := range valueSpec.Names {
+					if name.Name == "_" {
+						continue
+					}
+					if len(valueSpec.Values) == 0 {
+						vars = append(vars, PackageVar{Name: name, Type: valueSpec.Type})
+						continue
+					}
+					decls[pkg.TypesInfo.Defs[name]] = declared{valueSpec, i}
+				}
+			}
+		}
+	}
+	for _, init := range pkg.TypesInfo.InitOrder {
+		d, ok := decls[init.Lhs[0]]
+		if !ok || len(init.Lhs) != 1 {
+			continue
+		}
+		pv := PackageVar{Name: d.spec.Names[d.index], Type: d.spec.Type, Value: init.Rhs}
+		if pv.Type == nil {
+			if lit, ok := init.Rhs.(*ast.CompositeLit); ok {
+				pv.Type = lit.Type
+			}
+		}
+		vars = append(vars, pv)
+	}
+	return vars
+}
+
+// isPackageVar reports whether obj is a package-level variable
+func isPackageVar(obj types.Object) bool {
+	v, ok := obj.(*types.Var)
+	return ok && !v.IsField() && v.Pkg() != nil && v.Parent() == v.Pkg().Scope()
+}
+
+// hasDefer reports whether a function body contains a defer statement,
+// not counting the bodies of nested function literals
+func hasDefer(body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// deferScope is the body of a function being emitted and the name of the
+// stack its deferred calls are pushed to, empty when it has no defers.
+// recovers is set when a deferred call may stop a panic.
+type deferScope struct {
+	body     *ast.BlockStmt
+	stack    string
+	label    string // Where returns go to run the deferred calls first
+	recovers bool
+}
+
+// pushDeferScope enters a function body, naming its defer stack after the
+// counter so that nested function literals get their own stack
+func pushDeferScope(pkg *packages.Package, scopes []deferScope, body *ast.BlockStmt, count *int) []deferScope {
+	scope := deferScope{body: body}
+	if hasDefer(body) {
+		*count++
+		scope.stack = fmt.Sprintf("_defers%d", *count)
+		scope.label = fmt.Sprintf("_return%d", *count)
+		scope.recovers = deferRecovers(pkg, body)
+	}
+	return append(scopes, scope)
+}
+
+// currentDeferScope returns the innermost function body
+func currentDeferScope(scopes []deferScope) deferScope {
+	if len(scopes) == 0 {
+		return deferScope{}
+	}
+	return scopes[len(scopes)-1]
+}
+
+// deferRecovers reports whether a deferred call in a function body calls
+// recover() directly, only then can the function stop a panic
+func deferRecovers(pkg *packages.Package, body *ast.BlockStmt) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.DeferStmt:
+			if callsRecover(pkg, n.Call.Fun) {
+				found = true
+			}
+			return false
+		}
+		return !found
+	})
+	return found
+}
+
+// callsRecover reports whether the body of fun, a function literal or a
+// function of the package, calls recover()
+func callsRecover(pkg *packages.Package, fun ast.Expr) bool {
+	var body *ast.BlockStmt
+	switch fun := fun.(type) {
+	case *ast.FuncLit:
+		body = fun.Body
+	case *ast.Ident:
+		obj := pkg.TypesInfo.Uses[fun]
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil && pkg.TypesInfo.Defs[fn.Name] == obj {
+					body = fn.Body
+				}
+			}
+		}
+	}
+	if body == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.CallExpr:
+			if isBuiltinCall(pkg, n, "recover") {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// isBuiltinCall reports whether call calls the Go builtin function name
+func isBuiltinCall(pkg *packages.Package, call *ast.CallExpr, name string) bool {
+	ident, ok := call.Fun.(*ast.Ident)
+	if !ok || ident.Name != name {
+		return false
+	}
+	_, ok = pkg.TypesInfo.Uses[ident].(*types.Builtin)
+	return ok
+}
+
+// variadicArgsStart returns the index of the first argument a call passes to
+// the variadic parameter of a user function, or -1 for other calls. Builtins
+// and fmt functions are lowered to runtime helpers that take plain arguments.
+func variadicArgsStart(pkg *packages.Package, call *ast.CallExpr) int {
+	if pkg == nil || pkg.TypesInfo == nil {
+		return -1
+	}
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		if _, ok := pkg.TypesInfo.Uses[fun].(*types.Builtin); ok {
+			return -1
+		}
+	case *ast.SelectorExpr:
+		if obj, ok := pkg.TypesInfo.Uses[fun.Sel].(*types.Func); ok && obj.Pkg() != nil && obj.Pkg().Path() == "fmt" {
+			return -1
+		}
+	}
+	typ := pkg.TypesInfo.TypeOf(call.Fun)
+	if typ == nil {
+		return -1
+	}
+	sig, ok := typ.Underlying().(*types.Signature)
+	if !ok || !sig.Variadic() {
+		return -1
+	}
+	return sig.Params().Len() - 1
+}
+
+// isFuncValueCall reports whether a call goes through a function value rather
+// than a declared function or method
+func isFuncValueCall(pkg *packages.Package, call *ast.CallExpr) bool {
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return true
+	}
+	_, ok := pkg.TypesInfo.Uses[ident].(*types.Func)
+	return !ok
+}
+
+// splitResultFields gives each named result of a function type its own
+// field, so that results are counted by fields, and names blank results
+// _r0, _r1... after their position for a naked return to refer to them
+func splitResultFields(pkg *packages.Package, ftype *ast.FuncType) {
+	if ftype.Results == nil {
+		return
+	}
+	var fields []*ast.Field
+	for _, field := range ftype.Results.List {
+		if len(field.Names) <= 1 && (len(field.Names) == 0 || field.Names[0].Name != "_") {
+			fields = append(fields, field)
+			continue
+		}
+		for _, name := range field.Names {
+			if name.Name == "_" {
+				blank := ast.NewIdent(fmt.Sprintf("_r%d", len(fields)))
+				blank.NamePos = name.NamePos
+				pkg.TypesInfo.Defs[blank] = types.NewVar(name.Pos(), pkg.Types, blank.Name, pkg.TypesInfo.TypeOf(field.Type))
+				name = blank
+			}
+			fields = append(fields, &ast.Field{Names: []*ast.Ident{name}, Type: field.Type})
+		}
+	}
+	ftype.Results.List = fields
+}
+
+// expandEmbedding names each embedded field of the package's structs after its
+// type, and rewrites selectors of promoted fields and methods to go through
+// the embedded fields, so that s.X becomes s.Rect.X. Backends then see
+// embedded structs as ordinary fields.
+func expandEmbedding(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.StructType:
+				nameEmbeddedFields(pkg, n)
+			case *ast.SelectorExpr:
+				expandPromotedSelector(pkg, n)
+			}
+			return true
+		})
+	}
+}
+
+// nameEmbeddedFields gives the embedded fields of a struct type their
+// implicit name, the name of the embedded type
+func nameEmbeddedFields(pkg *packages.Package, node *ast.StructType) {
+	st, ok := pkg.TypesInfo.TypeOf(node).(*types.Struct)
+	if !ok {
+		return
+	}
+	index := 0
+	for _, field := range node.Fields.List {
+		if len(field.Names) > 0 {
+			index += len(field.Names)
+			continue
+		}
+		name := ast.NewIdent(st.Field(index).Name())
+		name.NamePos = field.Type.Pos()
+		pkg.TypesInfo.Defs[name] = st.Field(index)
+		field.Names = []*ast.Ident{name}
+		index++
+	}
+}
+
+// isEmbeddedField reports whether ident declares or refers to an embedded field
+func isEmbeddedField(pkg *packages.Package, ident *ast.Ident) bool {
+	if pkg == nil {
+		return false
+	}
+	// The type of an embedded field defines the field too, Uses has its type
+	obj := pkg.TypesInfo.Uses[ident]
+	if obj == nil {
+		obj = pkg.TypesInfo.Defs[ident]
+	}
+	v, ok := obj.(*types.Var)
+	return ok && v.Embedded()
+}
+
+// expandPromotedSelector rewrites the receiver of a promoted field or method
+// selector into the chain of embedded fields leading to it
+func expandPromotedSelector(pkg *packages.Package, sel *ast.SelectorExpr) {
+	selection, ok := pkg.TypesInfo.Selections[sel]
+	if !ok || len(selection.Index()) < 2 {
+		return
+	}
+	// Already rewritten when the receiver is an embedded field
+	tv, ok := pkg.TypesInfo.Types[sel.X]
+	if !ok || !types.Identical(tv.Type, selection.Recv()) {
+		return
+	}
+	x, t := sel.X, selection.Recv()
+	for _, index := range selection.Index()[:len(selection.Index())-1] {
+		if ptr, ok := t.Underlying().(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+		field := t.Underlying().(*types.Struct).Field(index)
+		name := ast.NewIdent(field.Name())
+		name.NamePos = sel.Sel.Pos()
+		pkg.TypesInfo.Uses[name] = field
+		x = &ast.SelectorExpr{X: x, Sel: name}
+		tv.Type = field.Type()
+		pkg.TypesInfo.Types[x] = tv
+		t = field.Type()
+	}
+	sel.X = x
+}
+
+// namedResultDecl declares a named result, var name T
+func namedResultDecl(result *ast.Field) *ast.DeclStmt {
+	return &ast.DeclStmt{Decl: &ast.GenDecl{
+		TokPos: result.Pos(),
+		Tok:    token.VAR,
+		Specs:  []ast.Spec{&ast.ValueSpec{Names: result.Names, Type: result.Type}},
+	}}
+}
+
+// firstUse returns the first identifier in node referring to obj, nil if none
+func firstUse(pkg *packages.Package, node ast.Node, obj types.Object) *ast.Ident {
+	var use *ast.Ident
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && use == nil && obj != nil && pkg.TypesInfo.Uses[ident] == obj {
+			use = ident
+		}
+		return use == nil
+	})
+	return use
+}
+
+// resultNames returns the names of the named results of a function, blank
+// results named as by splitResultFields, nil when the results are unnamed
+func resultNames(results *types.Tuple) []string {
+	if results == nil || results.Len() == 0 || results.At(0).Name() == "" {
+		return nil
+	}
+	names := make([]string, results.Len())
+	for i := range names {
+		names[i] = results.At(i).Name()
+		if names[i] == "_" {
+			names[i] = fmt.Sprintf("_r%d", i)
+		}
+	}
+	return names
+}
+
+// returnsNamedResults reports whether a return statement returns the named
+// results themselves, in order, as an expanded naked return does
+func returnsNamedResults(node *ast.ReturnStmt, names []string) bool {
+	if len(names) == 0 || len(node.Results) != len(names) {
+		return false
+	}
+	for i, result := range node.Results {
+		if ident, ok := result.(*ast.Ident); !ok || ident.Name != names[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// currentDeferStack returns the defer stack of the innermost function body
+func currentDeferStack(scopes []deferScope) string {
+	if len(scopes) == 0 {
+		return ""
+	}
+	return scopes[len(scopes)-1].stack
+}
+
+// isDeferScopeBody reports whether block is the body of the innermost function
+func isDeferScopeBody(scopes []deferScope, block *ast.BlockStmt) bool {
+	return len(scopes) > 0 && scopes[len(scopes)-1].body == block
+}
+
+// capturedVars returns the local variables a deferred call or a function
+// literal uses that are declared outside of it, in order of first use
+func capturedVars(pkg *packages.Package, node ast.Node) []*types.Var {
+	var vars []*types.Var
+	seen := make(map[types.Object]bool)
+	ast.Inspect(node, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		v, ok := pkg.TypesInfo.Uses[ident].(*types.Var)
+		if !ok || v.IsField() || isPackageVar(v) || seen[v] {
+			return true
+		}
+		if v.Pos() >= node.Pos() && v.Pos() < node.End() {
+			return true
+		}
+		seen[v] = true
+		vars = append(vars, v)
+		return true
+	})
+	return vars
+}
+
+// closureCells describes the local variables of a function that closures
+// share with it
+type closureCells struct {
+	vars  map[types.Object]bool
+	sites map[ast.Node][]types.Object // Block starting with, or statement declaring, each variable
+}
+
+// findClosureCells returns the local variables of a function that a function
+// literal captures and that are assigned after their declaration, so that the
+// function and its closures must share one value. Each is declared at the
+// start of the block of the function or range loop whose parameter or
+// iteration variable it is, in the select case receiving it, or by a
+// statement directly in a block. Variables of three-clause for loops, which
+// lowerLoopVars copies into the body when needed, named results and those
+// declared elsewhere are left alone. Function literals in
+// inlined are part of the enclosing body.
+func findClosureCells(pkg *packages.Package, decl *ast.FuncDecl, inlined map[*ast.FuncLit]bool) closureCells {
+	cells := closureCells{vars: make(map[types.Object]bool), sites: make(map[ast.Node][]types.Object)}
+	if decl.Body == nil {
+		return cells
+	}
+	captured := make(map[types.Object]bool)
+	written := make(map[types.Object]bool)
+	sites := make(map[types.Object]ast.Node)
+	defined := func(site ast.Node, idents ...*ast.Ident) {
+		for _, ident := range idents {
+			if obj := pkg.TypesInfo.Defs[ident]; obj != nil {
+				sites[obj] = site
+			}
+		}
+	}
+	params := func(site ast.Node, fields *ast.FieldList) {
+		for _, field := range fields.List {
+			defined(site, field.Names...)
+		}
+	}
+	write := func(expr ast.Expr) {
+		if v := assignedVar(pkg, expr); v != nil {
+			written[v] = true
+		}
+	}
+	blockStmts := func(list []ast.Stmt) {
+		for _, stmt := range list {
+			switch stmt := stmt.(type) {
+			case *ast.AssignStmt:
+				if stmt.Tok == token.DEFINE {
+					for _, lhs := range stmt.Lhs {
+						if ident, ok := lhs.(*ast.Ident); ok {
+							defined(stmt, ident)
+						}
+					}
+				}
+			case *ast.DeclStmt:
+				if gen, ok := stmt.Decl.(*ast.GenDecl); ok && gen.Tok == token.VAR {
+					for _, spec := range gen.Specs {
+						defined(stmt, spec.(*ast.ValueSpec).Names...)
+					}
+				}
+			}
+		}
+	}
+	params(decl.Body, decl.Type.Params)
+	var lits []*ast.FuncLit
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			params(n.Body, n.Type.Params)
+			if !inlined[n] {
+				lits = append(lits, n)
+				ast.Inspect(n.Body, visit)
+				lits = lits[:len(lits)-1]
+				return false
+			}
+		case *ast.BlockStmt:
+			blockStmts(n.List)
+		case *ast.CaseClause:
+			blockStmts(n.Body)
+		case *ast.CommClause:
+			if recv, ok := n.Comm.(*ast.AssignStmt); ok && recv.Tok == token.DEFINE {
+				for _, lhs := range recv.Lhs {
+					if ident, ok := lhs.(*ast.Ident); ok {
+						defined(n, ident)
+					}
+				}
+			}
+			blockStmts(n.Body)
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				for _, expr := range []ast.Expr{n.Key, n.Value} {
+					if ident, ok := expr.(*ast.Ident); ok {
+						defined(n.Body, ident)
+					}
+				}
+			} else if n.Tok == token.ASSIGN {
+				write(n.Key)
+				write(n.Value)
+			}
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				write(lhs)
+			}
+		case *ast.IncDecStmt:
+			write(n.X)
+		case *ast.CallExpr:
+			if recv := pointerMethodValueRecv(pkg, n); recv != nil {
+				write(recv)
+			}
+		case *ast.Ident:
+			v, ok := pkg.TypesInfo.Uses[n].(*types.Var)
+			if !ok || v.IsField() || isPackageVar(v) {
+				break
+			}
+			for _, lit := range lits {
+				if v.Pos() < lit.Pos() || v.Pos() >= lit.End() {
+					captured[v] = true
+				}
+			}
+		}
+		return true
+	}
+	ast.Inspect(decl.Body, visit)
+	for obj, site := range sites {
+		if captured[obj] && written[obj] {
+			cells.vars[obj] = true
+			cells.sites[site] = append(cells.sites[site], obj)
+		}
+	}
+	for _, objs := range cells.sites {
+		sort.Slice(objs, func(i, j int) bool { return objs[i].Pos() < objs[j].Pos() })
+	}
+	return cells
+}
+
+// assignedVar returns the variable whose value an assignment to expr changes:
+// the variable itself, or the one whose element or field it is, unless that
+// is reached through a pointer
+func assignedVar(pkg *packages.Package, expr ast.Expr) types.Object {
+	for {
+		switch e := expr.(type) {
+		case *ast.Ident:
+			if v, ok := pkg.TypesInfo.Uses[e].(*types.Var); ok && !v.IsField() {
+				return v
+			}
+			return nil
+		case *ast.IndexExpr:
+			if isPointerExpr(pkg, e.X) {
+				return nil
+			}
+			expr = e.X
+		case *ast.SelectorExpr:
+			if isPointerExpr(pkg, e.X) {
+				return nil
+			}
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		default:
+			return nil
+		}
+	}
+}
+
+// pointerMethodValueRecv returns the receiver of a call to a pointer method on
+// an addressable value, which the call may change
+func pointerMethodValueRecv(pkg *packages.Package, call *ast.CallExpr) ast.Expr {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || !isPointerMethodSelector(pkg, sel) || isPointerExpr(pkg, sel.X) {
+		return nil
+	}
+	return sel.X
+}
+
+// shadowRenames returns unique names, such as x_1, for the local variables of
+// a function that have the name of a variable of an enclosing scope of the
+// same function or of a package variable or constant. C# doesn't allow a local
+// to shadow another, and in C++ and JavaScript the initializer of x := x + 1
+// would see the new variable.
+func shadowRenames(pkg *packages.Package, decl *ast.FuncDecl) map[types.Object]string {
+	renames := make(map[types.Object]string)
+	funcScope := pkg.TypesInfo.Scopes[decl.Type]
+	if funcScope == nil || decl.Body == nil {
+		return renames
+	}
+	taken := make(map[string]bool)
+	ast.Inspect(decl, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok {
+			if obj := pkg.TypesInfo.ObjectOf(ident); obj != nil {
+				taken[obj.Name()] = true
+			}
+		}
+		return true
+	})
+	rename := func(obj types.Object, scope *types.Scope) {
+		if obj == nil || obj.Name() == "_" || scope == nil {
+			return
+		}
+		shadows := false
+		switch pkg.Types.Scope().Lookup(obj.Name()).(type) {
+		case *types.Var, *types.Const:
+			shadows = true
+		}
+		for s := scope; s != funcScope && !shadows; s = s.Parent() {
+			shadows = s.Parent().Lookup(obj.Name()) != nil
+		}
+		if !shadows {
+			return
+		}
+		name := obj.Name()
+		for i := 1; taken[name] || pkg.Types.Scope().Lookup(name) != nil; i++ {
+			name = fmt.Sprintf("%s_%d", obj.Name(), i)
+		}
+		taken[name] = true
+		renames[obj] = name
+		DebugLogPrintf("Renamed %s shadowing an outer variable to %s at %s", obj.Name(), name, pkg.Fset.Position(obj.Pos()))
+	}
+	ast.Inspect(decl.Body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Ident:
+			// Variables boxed by boxAddressedLocals are not in a scope
+			if obj, ok := pkg.TypesInfo.Defs[n].(*types.Var); ok {
+				scope := obj.Parent()
+				if scope == nil {
+					scope = funcScope.Innermost(n.Pos())
+				}
+				rename(obj, scope)
+			}
+		case *ast.CaseClause:
+			if obj := pkg.TypesInfo.Implicits[n]; obj != nil {
+				rename(obj, obj.Parent())
+			}
+		}
+		return true
+	})
+	return renames
+}
+
+// localName returns the name emitted for ident, which differs from its Go
+// name when shadowRenames renamed the variable
+func localName(pkg *packages.Package, renames map[types.Object]string, ident *ast.Ident) string {
+	if name, ok := renames[pkg.TypesInfo.ObjectOf(ident)]; ok {
+		return name
+	}
+	return ident.Name
+}
+
+// objectName returns the name emitted for the variable obj
+func objectName(renames map[types.Object]string, obj types.Object) string {
+	if name, ok := renames[obj]; ok {
+		return name
+	}
+	return obj.Name()
+}
+
+// isMainBody reports whether block is the body of the program's main function
+func isMainBody(pkg *packages.Package, decl *ast.FuncDecl, block *ast.BlockStmt) bool {
+	return pkg != nil && pkg.Name == "main" && decl != nil && decl.Recv == nil &&
+		decl.Name.Name == "main" && decl.Body == block
+}
+
+// funcBodyHasResults reports whether the function whose body is block, the
+// declaration itself or a function literal in it, returns values
+func funcBodyHasResults(pkg *packages.Package, decl *ast.FuncDecl, block *ast.BlockStmt) bool {
+	results := enclosingFuncResults(pkg, decl, block.Lbrace+1)
+	return results != nil && results.Len() > 0
+}
+
+// expandInstances rewrites each use of a generic function or type into an
+// IndexListExpr listing all of its type arguments, so that calls with
+// inferred type arguments name them like explicit instantiations. Backends
+// then see an instantiation only as an IndexListExpr.
+func expandInstances(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.Ident, *ast.SelectorExpr:
+				if _, isSel := c.Parent().(*ast.SelectorExpr); isSel && c.Name() == "Sel" {
+					break
+				}
+				if isInstanceIndex(c.Parent()) && c.Name() == "X" {
+					break
+				}
+				if expr := instanceExpr(pkg, n.(ast.Expr), nil); expr != nil {
+					c.Replace(expr)
+				}
+			case *ast.IndexExpr:
+				if expr := instanceExpr(pkg, n.X, []ast.Expr{n.Index}); expr != nil {
+					c.Replace(expr)
+				}
+			case *ast.IndexListExpr:
+				if expr := instanceExpr(pkg, n.X, n.Indices); expr != nil && len(n.Indices) < len(expr.Indices) {
+					c.Replace(expr)
+				}
+			}
+			return true
+		})
+	}
+}
+
+// isInstanceIndex reports whether node may instantiate a generic function or type
+func isInstanceIndex(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return true
+	}
+	return false
+}
+
+// instanceExpr returns the instantiation of the generic function or type
+// named by x, with the explicit type arguments followed by the inferred
+// ones. It returns nil when x doesn't name a generic function or type.
+func instanceExpr(pkg *packages.Package, x ast.Expr, explicit []ast.Expr) *ast.IndexListExpr {
+	ident, ok := x.(*ast.Ident)
+	if sel, isSel := x.(*ast.SelectorExpr); isSel {
+		ident, ok = sel.Sel, true
+	}
+	if !ok {
+		return nil
+	}
+	instance, ok := pkg.TypesInfo.Instances[ident]
+	if !ok {
+		return nil
+	}
+	expr := &ast.IndexListExpr{X: x, Lbrack: x.End(), Indices: explicit, Rbrack: x.End()}
+	for i := len(explicit); i < instance.TypeArgs.Len(); i++ {
+		expr.Indices = append(expr.Indices, typeExpr(pkg, instance.TypeArgs.At(i), x.Pos()))
+	}
+	if tv, ok := pkg.TypesInfo.Types[x]; ok {
+		tv.Type = instance.Type
+		pkg.TypesInfo.Types[expr] = tv
+	}
+	return expr
+}
+
+// genericTypeExpr returns the generic type of an instantiated type expression
+func genericTypeExpr(expr ast.Expr) ast.Expr {
+	if instance, ok := expr.(*ast.IndexListExpr); ok {
+		return instance.X
+	}
+	return expr
+}
+
+// typeExpr builds the type expression of an inferred type argument
+func typeExpr(pkg *packages.Package, t types.Type, pos token.Pos) ast.Expr {
+	var expr ast.Expr
+	switch t := t.(type) {
+	case *types.Basic:
+		name := ast.NewIdent(t.Name())
+		pkg.TypesInfo.Uses[name] = types.Universe.Lookup(t.Name())
+		expr = name
+	case *types.TypeParam:
+		name := ast.NewIdent(t.Obj().Name())
+		pkg.TypesInfo.Uses[name] = t.Obj()
+		expr = name
+	case *types.Named:
+		name := ast.NewIdent(t.Obj().Name())
+		pkg.TypesInfo.Uses[name] = t.Obj()
+		expr = name
+		if t.Obj().Pkg() != nil && t.Obj().Pkg() != pkg.Types {
+			pkgName := ast.NewIdent(t.Obj().Pkg().Name())
+			if obj := importedPkgName(pkg, t.Obj().Pkg()); obj != nil {
+				pkgName.Name = obj.Name()
+				pkg.TypesInfo.Uses[pkgName] = obj
+			}
+			expr = &ast.SelectorExpr{X: pkgName, Sel: name}
+		}
+		if t.TypeArgs().Len() > 0 {
+			instance := &ast.IndexListExpr{X: expr}
+			for i := 0; i < t.TypeArgs().Len(); i++ {
+				instance.Indices = append(instance.Indices, typeExpr(pkg, t.TypeArgs().At(i), pos))
+			}
+			expr = instance
+		}
+	case *types.Slice:
+		expr = &ast.ArrayType{Elt: typeExpr(pkg, t.Elem(), pos)}
+	case *types.Array:
+		length := &ast.BasicLit{Kind: token.INT, Value: strconv.FormatInt(t.Len(), 10), ValuePos: pos}
+		pkg.TypesInfo.Types[length] = types.TypeAndValue{Type: types.Typ[types.UntypedInt], Value: constant.MakeInt64(t.Len())}
+		expr = &ast.ArrayType{Len: length, Elt: typeExpr(pkg, t.Elem(), pos)}
+	case *types.Map:
+		expr = &ast.MapType{Key: typeExpr(pkg, t.Key(), pos), Value: typeExpr(pkg, t.Elem(), pos)}
+	case *types.Pointer:
+		expr = &ast.StarExpr{X: typeExpr(pkg, t.Elem(), pos)}
+	case *types.Signature:
+		ftype := &ast.FuncType{Params: &ast.FieldList{}}
+		for i := 0; i < t.Params().Len(); i++ {
+			ftype.Params.List = append(ftype.Params.List, &ast.Field{Type: typeExpr(pkg, t.Params().At(i).Type(), pos)})
+		}
+		if t.Results().Len() > 0 {
+			ftype.Results = &ast.FieldList{}
+			for i := 0; i < t.Results().Len(); i++ {
+				ftype.Results.List = append(ftype.Results.List, &ast.Field{Type: typeExpr(pkg, t.Results().At(i).Type(), pos)})
+			}
+		}
+		expr = ftype
+	default:
+		expr = &ast.InterfaceType{Methods: &ast.FieldList{}, Interface: pos}
+	}
+	pkg.TypesInfo.Types[expr] = types.TypeAndValue{Type: t}
+	return expr
+}
+
+// importedPkgName returns the name under which pkg imports imported
+func importedPkgName(pkg *packages.Package, imported *types.Package) *types.PkgName {
+	for _, obj := range pkg.TypesInfo.Implicits {
+		if pkgName, ok := obj.(*types.PkgName); ok && pkgName.Imported() == imported {
+			return pkgName
+		}
+	}
+	for _, obj := range pkg.TypesInfo.Defs {
+		if pkgName, ok := obj.(*types.PkgName); ok && pkgName.Imported() == imported {
+			return pkgName
+		}
+	}
+	return nil
+}
+
+// typeParams returns the type parameters of a generic function, or of the
+// receiver type of a method of a generic type
+func typeParams(pkg *packages.Package, decl *ast.FuncDecl) *types.TypeParamList {
+	obj, ok := pkg.TypesInfo.Defs[decl.Name].(*types.Func)
+	if !ok {
+		return nil
+	}
+	sig := obj.Type().(*types.Signature)
+	if sig.RecvTypeParams().Len() > 0 {
+		return sig.RecvTypeParams()
+	}
+	return sig.TypeParams()
+}
+
+// structTypeParams returns the type parameters of the named struct type of pkg
+func structTypeParams(pkg *packages.Package, name string) *types.TypeParamList {
+	if obj, ok := pkg.Types.Scope().Lookup(name).(*types.TypeName); ok {
+		if named, ok := obj.Type().(*types.Named); ok {
+			return named.TypeParams()
+		}
+	}
+	return nil
+}
+
+// typeParamNames lists the names of type parameters
+func typeParamNames(params *types.TypeParamList) []string {
+	var names []string
+	for i := 0; i < params.Len(); i++ {
+		names = append(names, params.At(i).Obj().Name())
+	}
+	return names
+}
+
+// isConstraintInterface reports whether spec declares an interface that can
+// only be used as a type constraint, e.g. interface{ ~int | ~float64 }
+func isConstraintInterface(pkg *packages.Package, spec *ast.TypeSpec) bool {
+	iface, ok := pkg.TypesInfo.TypeOf(spec.Type).(*types.Interface)
+	return ok && !iface.IsMethodSet()
+}
+
+// isTypeParam reports whether expr has a type parameter type
+func isTypeParam(pkg *packages.Package, expr ast.Expr) bool {
+	_, ok := pkg.TypesInfo.TypeOf(expr).(*types.TypeParam)
+	return ok
+}
+
+// isNumericConstraint reports whether a type parameter is constrained to a
+// union of numeric types, so that it supports arithmetic and ordering
+func isNumericConstraint(param *types.TypeParam) bool {
+	return hasUnionTerms(param.Constraint())
+}
+
+func hasUnionTerms(constraint types.Type) bool {
+	iface, ok := constraint.Underlying().(*types.Interface)
+	if !ok {
+		return false
+	}
+	for i := 0; i < iface.NumEmbeddeds(); i++ {
+		switch embedded := iface.EmbeddedType(i).(type) {
+		case *types.Union:
+			return true
+		case *types.Named:
+			if hasUnionTerms(embedded) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// labelTargets reports whether a labeled statement is the target of a break
+// or of a continue naming its label
+func labelTargets(node *ast.LabeledStmt) (breaks bool, continues bool) {
+	ast.Inspect(node.Stmt, func(n ast.Node) bool {
+		if branch, ok := n.(*ast.BranchStmt); ok && branch.Label != nil && branch.Label.Name == node.Label.Name {
+			breaks = breaks || branch.Tok == token.BREAK
+			continues = continues || branch.Tok == token.CONTINUE
+		}
+		return true
+	})
+	return breaks, continues
+}
+
+// loopBody returns the body of a for or range statement, nil for other statements
+func loopBody(stmt ast.Stmt) *ast.BlockStmt {
+	switch s := stmt.(type) {
+	case *ast.ForStmt:
+		return s.Body
+	case *ast.RangeStmt:
+		return s.Body
+	}
+	return nil
+}
+
+// branchLabel names the goto label that break L or continue L jumps to in
+// backends without labeled loops: L_break follows the labeled statement and
+// L_continue ends the body of the labeled loop
+func branchLabel(label string, tok token.Token) string {
+	if tok == token.CONTINUE {
+		return label + "_continue"
+	}
+	return label + "_break"
+}
+
+// arrayLen returns the length of a fixed-size array type, or -1 for a slice
+// type. The length of [...]T is taken from the composite literal it types
+func arrayLen(pkg *packages.Package, node ast.ArrayType) int64 {
+	if node.Len == nil {
+		return -1
+	}
+	if _, ok := node.Len.(*ast.Ellipsis); ok {
+		length := int64(-1)
+		for _, file := range pkg.Syntax {
+			ast.Inspect(file, func(n ast.Node) bool {
+				if lit, ok := n.(*ast.CompositeLit); ok {
+					if atype, ok := lit.Type.(*ast.ArrayType); ok && atype.Len == node.Len {
+						if array, ok := pkg.TypesInfo.TypeOf(lit).(*types.Array); ok {
+							length = array.Len()
+						}
+					}
+				}
+				return length < 0
+			})
+		}
+		return length
+	}
+	if tv, ok := pkg.TypesInfo.Types[node.Len]; ok && tv.Value != nil {
+		if length, exact := constant.Int64Val(constant.ToInt(tv.Value)); exact {
+			return length
+		}
+	}
+	return -1
+}
+
+// arrayLenLit returns the constant a call to len of an array evaluates to,
+// or nil for other expressions
+func arrayLenLit(pkg *packages.Package, expr ast.Expr) *ast.BasicLit {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return nil
+	}
+	if fun, ok := call.Fun.(*ast.Ident); !ok || fun.Name != "len" {
+		return nil
+	}
+	if _, ok := pkg.TypesInfo.Uses[call.Fun.(*ast.Ident)].(*types.Builtin); !ok {
+		return nil
+	}
+	argType := pkg.TypesInfo.TypeOf(call.Args[0])
+	if argType == nil {
+		return nil
+	}
+	if _, ok := argType.Underlying().(*types.Array); !ok {
+		return nil
+	}
+	tv := pkg.TypesInfo.Types[call]
+	if tv.Value == nil {
+		return nil
+	}
+	length := &ast.BasicLit{Kind: token.INT, Value: tv.Value.ExactString(), ValuePos: call.Pos()}
+	pkg.TypesInfo.Types[length] = tv
+	return length
+}
+
+// isStructPointer reports whether t is a pointer to a struct type
+func isStructPointer(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	ptr, ok := t.Underlying().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	_, ok = ptr.Elem().Underlying().(*types.Struct)
+	return ok
+}
+
+// isPointerExpr reports whether expr is a value of a pointer type
+func isPointerExpr(pkg *packages.Package, expr ast.Expr) bool {
+	if pkg == nil || pkg.TypesInfo == nil {
+		return false
+	}
+	tv, ok := pkg.TypesInfo.Types[expr]
+	return ok && !tv.IsType() && isStructPointer(tv.Type)
+}
+
+// isPointerRecvExpr reports whether expr is the receiver of decl, a method
+// with a pointer receiver, which backends bind to the value it points to
+func isPointerRecvExpr(pkg *packages.Package, decl *ast.FuncDecl, expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok || decl == nil || !hasPointerRecv(decl) || recvName(decl) == "" {
+		return false
+	}
+	return pkg.TypesInfo.Uses[ident] == pkg.TypesInfo.Defs[decl.Recv.List[0].Names[0]]
+}
+
+// visitConversions calls f for each expression of the package that is
+// implicitly converted to the type it is assigned to: assigned and declared
+// values, call arguments, results, composite literal elements and the
+// operands of comparisons
+func visitConversions(pkg *packages.Package, f func(expr ast.Expr, target types.Type)) {
+	visit := func(expr ast.Expr, target types.Type) {
+		if target != nil {
+			f(expr, target)
+		}
+	}
+	for _, file := range pkg.Syntax {
+		var decl *ast.FuncDecl
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl:
+				decl = n
+			case *ast.AssignStmt:
+				if len(n.Lhs) != len(n.Rhs) || (n.Tok != token.ASSIGN && n.Tok != token.DEFINE) {
+					break
+				}
+				for i, lhs := range n.Lhs {
+					visit(n.Rhs[i], pkg.TypesInfo.TypeOf(lhs))
+				}
+			case *ast.ValueSpec:
+				if n.Type == nil || len(n.Values) != len(n.Names) {
+					break
+				}
+				for _, value := range n.Values {
+					visit(value, pkg.TypesInfo.TypeOf(n.Type))
+				}
+			case *ast.ReturnStmt:
+				results := enclosingFuncResults(pkg, decl, n.Pos())
+				if results == nil || results.Len() != len(n.Results) {
+					break
+				}
+				for i, result := range n.Results {
+					visit(result, results.At(i).Type())
+				}
+			case *ast.CallExpr:
+				if tv, ok := pkg.TypesInfo.Types[n.Fun]; ok && tv.IsType() {
+					if len(n.Args) == 1 {
+						visit(n.Args[0], tv.Type)
+					}
+					break
+				}
+				sig, ok := pkg.TypesInfo.TypeOf(n.Fun).(*types.Signature)
+				if !ok {
+					break
+				}
+				for i, arg := range n.Args {
+					switch {
+					case sig.Variadic() && i >= sig.Params().Len()-1 && !n.Ellipsis.IsValid():
+						visit(arg, sig.Params().At(sig.Params().Len()-1).Type().(*types.Slice).Elem())
+					case i < sig.Params().Len():
+						visit(arg, sig.Params().At(i).Type())
+					}
+				}
+			case *ast.CompositeLit:
+				t := pkg.TypesInfo.TypeOf(n)
+				if t == nil {
+					break
+				}
+				for i, elt := range n.Elts {
+					kv, keyed := elt.(*ast.KeyValueExpr)
+					switch u := t.Underlying().(type) {
+					case *types.Struct:
+						if !keyed {
+							visit(elt, u.Field(i).Type())
+							break
+						}
+						for j := 0; j < u.NumFields(); j++ {
+							if u.Field(j).Name() == kv.Key.(*ast.Ident).Name {
+								visit(kv.Value, u.Field(j).Type())
+							}
+						}
+					case *types.Slice, *types.Array:
+						elem := u.(interface{ Elem() types.Type }).Elem()
+						if keyed {
+							visit(kv.Value, elem)
+						} else {
+							visit(elt, elem)
+						}
+					case *types.Map:
+						if keyed {
+							visit(kv.Key, u.Key())
+							visit(kv.Value, u.Elem())
+						}
+					}
+				}
+			case *ast.BinaryExpr:
+				if n.Op == token.EQL || n.Op == token.NEQ {
+					visit(n.X, pkg.TypesInfo.TypeOf(n.Y))
+					visit(n.Y, pkg.TypesInfo.TypeOf(n.X))
+				}
+			}
+			return true
+		})
+	}
+}
+
+// typeNils records the type each nil of the package is converted to as its
+// type, in place of untyped nil, so that backends know which zero value a
+// nil stands for
+func typeNils(pkg *packages.Package) {
+	visitConversions(pkg, func(expr ast.Expr, target types.Type) {
+		ident, ok := expr.(*ast.Ident)
+		if !ok || ident.Name != "nil" {
+			return
+		}
+		tv, ok := pkg.TypesInfo.Types[ident]
+		if !ok || !tv.IsNil() {
+			return
+		}
+		if basic, ok := target.(*types.Basic); ok && basic.Info()&types.IsUntyped != 0 {
+			return
+		}
+		tv.Type = target
+		pkg.TypesInfo.Types[ident] = tv
+	})
+}
+
+// isNilableType reports whether t has a nil value in every backend: pointers
+// to structs, slices, maps, functions and empty interfaces
+func isNilableType(t types.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Slice, *types.Map, *types.Signature:
+		return true
+	case *types.Interface:
+		return u.Empty()
+	}
+	return isStructPointer(t)
+}
+
+// funcTypeTokens records where the tokens of a function type being emitted
+// start, and where its parameters start after its results
+type funcTypeTokens struct {
+	start  int
+	params int
+}
+
+// split returns the emitted results and parameters of the function type
+func (f funcTypeTokens) split(tokens []Token) (string, string) {
+	results := strings.Join(tokensToStrings(tokens[f.start:f.params]), "")
+	params := strings.Join(tokensToStrings(tokens[f.params:]), "")
+	return strings.TrimSpace(results), strings.TrimSpace(params)
+}
+
+// isFuncExpr reports whether expr is a function value
+func isFuncExpr(pkg *packages.Package, expr ast.Expr) bool {
+	t := pkg.TypesInfo.TypeOf(expr)
+	if t == nil {
+		return false
+	}
+	_, ok := t.Underlying().(*types.Signature)
+	return ok
+}
+
+// declaredFuncValues returns the names of declared functions used as function
+// values: assigned, passed, returned or stored rather than called
+func declaredFuncValues(pkg *packages.Package) map[ast.Expr]bool {
+	values := make(map[ast.Expr]bool)
+	visitConversions(pkg, func(expr ast.Expr, target types.Type) {
+		ident, ok := expr.(*ast.Ident)
+		if !ok {
+			return
+		}
+		if _, isSig := target.Underlying().(*types.Signature); !isSig {
+			return
+		}
+		if _, isFunc := pkg.TypesInfo.Uses[ident].(*types.Func); isFunc {
+			values[ident] = true
+		}
+	})
+	return values
+}
+
+// isAnyExpr reports whether expr is an interface{} value
+func isAnyExpr(pkg *packages.Package, expr ast.Expr) bool {
+	t := pkg.TypesInfo.TypeOf(expr)
+	if t == nil {
+		return false
+	}
+	iface, ok := t.Underlying().(*types.Interface)
+	return ok && iface.Empty()
+}
+
+// isAnyNilComparison reports whether node compares an interface{} value with
+// nil, which lowerNilComparisons moved to the right
+func isAnyNilComparison(pkg *packages.Package, node *ast.BinaryExpr) bool {
+	return (node.Op == token.EQL || node.Op == token.NEQ) && isNilIdent(node.Y) && isAnyExpr(pkg, node.X)
+}
+
+// isCollectionNilComparison reports whether node compares a slice or a map
+// with nil, moved to the right by lowerNilComparisons
+func isCollectionNilComparison(pkg *packages.Package, node *ast.BinaryExpr) bool {
+	if (node.Op != token.EQL && node.Op != token.NEQ) || !isNilIdent(node.Y) {
+		return false
+	}
+	switch pkg.TypesInfo.TypeOf(node.X).Underlying().(type) {
+	case *types.Slice, *types.Map:
+		return true
+	}
+	return false
+}
+
+// lowerRanges rewrites the range statements backends don't iterate natively.
+// A composite literal or a non-constant integer ranged over is evaluated once
+// into a variable declared before the loop, and for i := range n becomes
+// for i := 0; i < n; i++, or for _range1 := 0; _range1 < n; _range1++ with
+// the body starting with i := _range1 when it assigns i. A range over a
+// channel becomes a loop receiving until the channel is closed:
+// for { v, ok := <-ch; if !ok { break }; ... }.
+func lowerRanges(pkg *packages.Package) {
+	temps := 0
+	newVar := func(name string, t types.Type, pos token.Pos) (def *ast.Ident, use func() *ast.Ident) {
+		obj := types.NewVar(pos, pkg.Types, name, t)
+		def = &ast.Ident{NamePos: pos, Name: name}
+		pkg.TypesInfo.Defs[def] = obj
+		use = func() *ast.Ident {
+			ident := &ast.Ident{NamePos: pos, Name: name}
+			pkg.TypesInfo.Uses[ident] = obj
+			pkg.TypesInfo.Types[ident] = types.TypeAndValue{Type: t}
+			return ident
+		}
+		return def, use
+	}
+	// Temporaries of labeled loops, declared before the label
+	labeledTemps := make(map[*ast.LabeledStmt]ast.Stmt)
+	for _, file := range pkg.Syntax {
+		astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+			if labeled, ok := c.Node().(*ast.LabeledStmt); ok && labeledTemps[labeled] != nil {
+				c.InsertBefore(labeledTemps[labeled])
+				return true
+			}
+			loop, ok := c.Node().(*ast.RangeStmt)
+			if !ok {
+				return true
+			}
+			tv := pkg.TypesInfo.Types[loop.X]
+			if tv.Type == nil {
+				return true
+			}
+			basic, isBasic := tv.Type.Underlying().(*types.Basic)
+			isInt := isBasic && basic.Info()&types.IsInteger != 0
+			_, isLit := loop.X.(*ast.CompositeLit)
+			chanType, isChan := tv.Type.Underlying().(*types.Chan)
+			_, isIdent := loop.X.(*ast.Ident)
+			if isLit || (isInt && tv.Value == nil) || (isChan && !isIdent) {
+				temps++
+				def, use := newVar(fmt.Sprintf("_range%d", temps), tv.Type, loop.For)
+				decl := &ast.AssignStmt{Lhs: []ast.Expr{def}, TokPos: loop.For, Tok: token.DEFINE, Rhs: []ast.Expr{loop.X}}
+				if labeled, ok := c.Parent().(*ast.LabeledStmt); ok {
+					labeledTemps[labeled] = decl
+				} else {
+					c.InsertBefore(decl)
+				}
+				loop.X = use()
+			}
+			if isChan {
+				temps++
+				okDef, okUse := newVar(fmt.Sprintf("_range%d", temps), types.Typ[types.Bool], loop.For)
+				recv := &ast.UnaryExpr{OpPos: loop.For, Op: token.ARROW, X: loop.X}
+				pkg.TypesInfo.Types[recv] = types.TypeAndValue{Type: types.NewTuple(
+					types.NewVar(loop.For, pkg.Types, "", chanType.Elem()),
+					types.NewVar(loop.For, pkg.Types, "", types.Typ[types.Bool]))}
+				var value ast.Expr = ast.NewIdent("_")
+				var assign ast.Stmt
+				if ident, ok := loop.Key.(*ast.Ident); ok && ident.Name != "_" {
+					value = ident
+					if loop.Tok == token.ASSIGN {
+						// The received value is assigned to the existing variable
+						temps++
+						def, use := newVar(fmt.Sprintf("_range%d", temps), chanType.Elem(), loop.For)
+						value = def
+						assign = &ast.AssignStmt{Lhs: []ast.Expr{ident}, TokPos: loop.For, Tok: token.ASSIGN, Rhs: []ast.Expr{use()}}
+					}
+				}
+				notOk := &ast.UnaryExpr{OpPos: loop.For, Op: token.NOT, X: okUse()}
+				pkg.TypesInfo.Types[notOk] = types.TypeAndValue{Type: types.Typ[types.Bool]}
+				body := []ast.Stmt{
+					&ast.AssignStmt{Lhs: []ast.Expr{value, okDef}, TokPos: loop.For, Tok: token.DEFINE, Rhs: []ast.Expr{recv}},
+					&ast.IfStmt{If: loop.For, Cond: notOk, Body: &ast.BlockStmt{List: []ast.Stmt{
+						&ast.BranchStmt{TokPos: loop.For, Tok: token.BREAK},
+					}}},
+				}
+				if assign != nil {
+					body = append(body, assign)
+				}
+				loop.Body.List = append(body, loop.Body.List...)
+				c.Replace(&ast.ForStmt{For: loop.For, Body: loop.Body})
+				return true
+			}
+			if !isInt {
+				return true
+			}
+			t := types.Default(tv.Type)
+			var key ast.Expr
+			var keyUse func() ast.Expr
+			tok := token.DEFINE
+			ident, named := loop.Key.(*ast.Ident)
+			named = named && ident.Name != "_"
+			if named {
+				if obj := pkg.TypesInfo.ObjectOf(ident); obj != nil {
+					t = obj.Type()
+				}
+			}
+			switch {
+			case named && loop.Tok == token.DEFINE && assignsVar(pkg, loop.Body, pkg.TypesInfo.Defs[ident]):
+				// Assigning the key doesn't change the iteration, the key is a
+				// copy of a counter declared by the loop
+				temps++
+				def, use := newVar(fmt.Sprintf("_range%d", temps), t, loop.For)
+				key, keyUse = def, func() ast.Expr { return use() }
+				copyKey := &ast.AssignStmt{Lhs: []ast.Expr{ident}, TokPos: loop.For, Tok: token.DEFINE, Rhs: []ast.Expr{use()}}
+				loop.Body.List = append([]ast.Stmt{copyKey}, loop.Body.List...)
+			case named:
+				key, tok = ident, loop.Tok
+				keyUse = func() ast.Expr {
+					use := &ast.Ident{NamePos: ident.Pos(), Name: ident.Name}
+					pkg.TypesInfo.Uses[use] = pkg.TypesInfo.ObjectOf(ident)
+					pkg.TypesInfo.Types[use] = types.TypeAndValue{Type: t}
+					return use
+				}
+			default:
+				temps++
+				def, use := newVar(fmt.Sprintf("_range%d", temps), t, loop.For)
+				key, keyUse = def, func() ast.Expr { return use() }
+			}
+			zero := &ast.BasicLit{ValuePos: loop.For, Kind: token.INT, Value: "0"}
+			if zeroTv, err := types.Eval(pkg.Fset, nil, token.NoPos, "0"); err == nil {
+				zeroTv.Type = t
+				pkg.TypesInfo.Types[zero] = zeroTv
+			}
+			cond := &ast.BinaryExpr{X: keyUse(), OpPos: loop.For, Op: token.LSS, Y: loop.X}
+			pkg.TypesInfo.Types[cond] = types.TypeAndValue{Type: types.Typ[types.Bool]}
+			c.Replace(&ast.ForStmt{
+				For:  loop.For,
+				Init: &ast.AssignStmt{Lhs: []ast.Expr{key}, TokPos: loop.For, Tok: tok, Rhs: []ast.Expr{zero}},
+				Cond: cond,
+				Post: &ast.IncDecStmt{X: keyUse(), TokPos: loop.For, Tok: token.INC},
+				Body: loop.Body,
+			})
+			return true
+		})
+	}
+}
+
+// loopVarsToCopy returns the variables of a three-clause for loop that a
+// function literal in the loop captures and that the body assigns. Go gives
+// each iteration its own copy of them, which the closures share with it.
+func loopVarsToCopy(pkg *packages.Package, loop *ast.ForStmt) []types.Object {
+	init, ok := loop.Init.(*ast.AssignStmt)
+	if !ok || init.Tok != token.DEFINE {
+		return nil
+	}
+	vars := make(map[types.Object]bool)
+	for _, lhs := range init.Lhs {
+		if obj := pkg.TypesInfo.Defs[lhs.(*ast.Ident)]; obj != nil {
+			vars[obj] = true
+		}
+	}
+	captured := make(map[types.Object]bool)
+	lits := 0
+	var visit func(n ast.Node) bool
+	visit = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FuncLit:
+			lits++
+			ast.Inspect(n.Body, visit)
+			lits--
+			return false
+		case *ast.Ident:
+			if obj := pkg.TypesInfo.Uses[n]; vars[obj] && lits > 0 {
+				captured[obj] = true
+			}
+		}
+		return true
+	}
+	ast.Inspect(loop.Body, visit)
+	var objs []types.Object
+	for _, lhs := range init.Lhs {
+		if obj := pkg.TypesInfo.Defs[lhs.(*ast.Ident)]; captured[obj] && assignsVar(pkg, loop.Body, obj) {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// assignsVar reports whether node assigns the variable obj, or an element or
+// field of it
+func assignsVar(pkg *packages.Package, node ast.Node, obj types.Object) bool {
+	assigned := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				assigned = assigned || assignedVar(pkg, lhs) == obj
+			}
+		case *ast.IncDecStmt:
+			assigned = assigned || assignedVar(pkg, n.X) == obj
+		}
+		return !assigned
+	})
+	return assigned
+}
+
+// lowerLoopVars gives each iteration of a three-clause for loop its own copy
+// of the loop variables returned by loopVarsToCopy, which closures can share:
+// for i := 0; i < n; i++ { body } becomes
+// for _loop1 := 0; _loop1 < n; _loop1++ { i := _loop1; body; _loop1 = i },
+// and a continue of the loop stores i back into _loop1 first.
+func lowerLoopVars(pkg *packages.Package) {
+	temps := 0
+	for _, file := range pkg.Syntax {
+		astutil.Apply(file, nil, func(c *astutil.Cursor) bool {
+			loop, ok := c.Node().(*ast.ForStmt)
+			if !ok {
+				return true
+			}
+			objs := loopVarsToCopy(pkg, loop)
+			if len(objs) == 0 {
+				return true
+			}
+			var label *ast.Ident
+			if labeled, ok := c.Parent().(*ast.LabeledStmt); ok {
+				label = labeled.Label
+			}
+			init := loop.Init.(*ast.AssignStmt)
+			use := func(obj types.Object) *ast.Ident {
+				ident := &ast.Ident{NamePos: loop.For, Name: obj.Name()}
+				pkg.TypesInfo.Uses[ident] = obj
+				pkg.TypesInfo.Types[ident] = types.TypeAndValue{Type: obj.Type()}
+				return ident
+			}
+			var head []ast.Stmt
+			copies := make(map[types.Object]types.Object)
+			// storeBack stores the copies back into the loop variables
+			storeBack := func() []ast.Stmt {
+				var stmts []ast.Stmt
+				for _, obj := range objs {
+					stmts = append(stmts, &ast.AssignStmt{Lhs: []ast.Expr{use(copies[obj])}, TokPos: loop.For, Tok: token.ASSIGN, Rhs: []ast.Expr{use(obj)}})
+				}
+				return stmts
+			}
+			for _, obj := range objs {
+				temps++
+				name := fmt.Sprintf("_loop%d", temps)
+				copyVar := types.NewVar(loop.For, pkg.Types, name, obj.Type())
+				copies[obj] = copyVar
+				for i, lhs := range init.Lhs {
+					if ident := lhs.(*ast.Ident); pkg.TypesInfo.Defs[ident] == obj {
+						def := &ast.Ident{NamePos: ident.Pos(), Name: name}
+						pkg.TypesInfo.Defs[def] = copyVar
+						init.Lhs[i] = def
+						head = append(head, &ast.AssignStmt{Lhs: []ast.Expr{ident}, TokPos: loop.For, Tok: token.DEFINE, Rhs: []ast.Expr{use(copyVar)}})
+					}
+				}
+				replaceUses := func(c *astutil.Cursor) bool {
+					if ident, ok := c.Node().(*ast.Ident); ok && pkg.TypesInfo.Uses[ident] == obj {
+						c.Replace(use(copyVar))
+					}
+					return true
+				}
+				if loop.Cond != nil {
+					loop.Cond = astutil.Apply(loop.Cond, nil, replaceUses).(ast.Expr)
+				}
+				if loop.Post != nil {
+					loop.Post = astutil.Apply(loop.Post, nil, replaceUses).(ast.Stmt)
+				}
+			}
+			// A continue of the loop skips the end of the body
+			loops := 0
+			astutil.Apply(loop.Body, func(c *astutil.Cursor) bool {
+				switch n := c.Node().(type) {
+				case *ast.FuncLit:
+					return false
+				case *ast.ForStmt, *ast.RangeStmt:
+					loops++
+				case *ast.BranchStmt:
+					if n.Tok == token.CONTINUE && ((n.Label == nil && loops == 0) || (n.Label != nil && label != nil && n.Label.Name == label.Name)) {
+						c.Replace(&ast.BlockStmt{Lbrace: n.Pos(), List: append(storeBack(), n), Rbrace: n.End()})
+					}
+				}
+				return true
+			}, func(c *astutil.Cursor) bool {
+				switch c.Node().(type) {
+				case *ast.ForStmt, *ast.RangeStmt:
+					loops--
+				}
+				return true
+			})
+			loop.Body.List = append(append(head, loop.Body.List...), storeBack()...)
+			return true
+		})
+	}
+}
+
+// lowerNilComparisons moves nil to the right of comparisons
+func lowerNilComparisons(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			cmp, ok := n.(*ast.BinaryExpr)
+			if ok && (cmp.Op == token.EQL || cmp.Op == token.NEQ) && isNilIdent(cmp.X) && !isNilIdent(cmp.Y) {
+				cmp.X, cmp.Y = cmp.Y, cmp.X
+			}
+			return true
+		})
+	}
+}
+
+// boxAddressedLocals turns each local struct variable whose address is taken
+// into a pointer to a struct holding its value: x := T{} becomes x := &T{},
+// &x becomes x, and other uses of x become *x. Backends then only take the
+// address of a new value, which they allocate.
+func boxAddressedLocals(pkg *packages.Package) {
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+			boxed := make(map[types.Object]*types.Var)
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				// Each pass runs this again, the boxes made before copy a value
+				unary, ok := n.(*ast.UnaryExpr)
+				if !ok || unary.Op != token.AND || boxedValues[unary] {
+					return true
+				}
+				ident, ok := unary.X.(*ast.Ident)
+				if !ok {
+					return true
+				}
+				obj, ok := pkg.TypesInfo.Uses[ident].(*types.Var)
+				if !ok || boxed[obj] != nil || !isStructPointer(types.NewPointer(obj.Type())) {
+					return true
+				}
+				if localVarDecl(pkg, fn.Body, obj) != nil {
+					boxed[obj] = types.NewVar(obj.Pos(), obj.Pkg(), obj.Name(), types.NewPointer(obj.Type()))
+				}
+				return true
+			})
+			if len(boxed) == 0 {
+				continue
+			}
+			astutil.Apply(fn.Body, nil, func(c *astutil.Cursor) bool {
+				switch n := c.Node().(type) {
+				case *ast.Ident:
+					if obj, ok := pkg.TypesInfo.Defs[n].(*types.Var); ok && boxed[obj] != nil {
+						pkg.TypesInfo.Defs[n] = boxed[obj]
+						return true
+					}
+					obj, ok := pkg.TypesInfo.Uses[n].(*types.Var)
+					if !ok || boxed[obj] == nil {
+						return true
+					}
+					pkg.TypesInfo.Uses[n] = boxed[obj]
+					tv := pkg.TypesInfo.Types[n]
+					elem := tv
+					tv.Type = boxed[obj].Type()
+					pkg.TypesInfo.Types[n] = tv
+					if unary, ok := c.Parent().(*ast.UnaryExpr); ok && unary.Op == token.AND {
+						return true
+					}
+					if _, ok := c.Parent().(*ast.SelectorExpr); ok && c.Name() == "X" {
+						return true
+					}
+					star := &ast.StarExpr{Star: n.Pos(), X: n}
+					pkg.TypesInfo.Types[star] = elem
+					c.Replace(star)
+				case *ast.UnaryExpr:
+					if ident, ok := n.X.(*ast.Ident); ok && n.Op == token.AND && isBoxed(pkg, boxed, ident) {
+						c.Replace(ident)
+					}
+				case *ast.AssignStmt:
+					if len(n.Lhs) == 1 && n.Tok == token.DEFINE && isBoxed(pkg, boxed, n.Lhs[0]) {
+						n.Rhs[0] = addressOf(pkg, n.Rhs[0])
+					}
+				case *ast.DeclStmt:
+					// var x T = e becomes x := &e, var x T becomes x := &T{}
+					gen, ok := n.Decl.(*ast.GenDecl)
+					if !ok || gen.Tok != token.VAR || len(gen.Specs) != 1 {
+						return true
+					}
+					spec := gen.Specs[0].(*ast.ValueSpec)
+					if len(spec.Names) != 1 || !isBoxed(pkg, boxed, spec.Names[0]) {
+						return true
+					}
+					t := pkg.TypesInfo.ObjectOf(spec.Names[0]).Type().(*types.Pointer)
+					value := ast.Expr(nil)
+					if len(spec.Values) == 1 {
+						value = spec.Values[0]
+					} else {
+						lit := &ast.CompositeLit{Type: typeExpr(pkg, t.Elem(), spec.Pos()), Lbrace: spec.End(), Rbrace: spec.End()}
+						pkg.TypesInfo.Types[lit] = types.TypeAndValue{Type: t.Elem()}
+						value = lit
+					}
+					c.Replace(&ast.AssignStmt{Lhs: []ast.Expr{spec.Names[0]}, TokPos: spec.Pos(), Tok: token.DEFINE, Rhs: []ast.Expr{addressOf(pkg, value)}})
+				}
+				return true
+			})
+		}
+	}
+}
+
+// localVarDecl returns the statement declaring obj alone in body, x := e or
+// var x T, or nil when it is declared otherwise, e.g. as a parameter
+func localVarDecl(pkg *packages.Package, body *ast.BlockStmt, obj types.Object) ast.Node {
+	var decl ast.Node
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) == 1 && len(n.Rhs) == 1 && n.Tok == token.DEFINE && pkg.TypesInfo.Defs[n.Lhs[0].(*ast.Ident)] == obj {
+				decl = n
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == 1 && len(n.Values) <= 1 && pkg.TypesInfo.Defs[n.Names[0]] == obj {
+				decl = n
+			}
+		}
+		return decl == nil
+	})
+	return decl
+}
+
+// slicedArrays caches the sliced array variables of each package
+var slicedArrays = map[*packages.Package]map[types.Object]bool{}
+
+// isSlicedArray reports whether obj is a local array variable that is sliced.
+// Its slices share its elements, so backends move them into the backing
+// array of a slice after the statement declaring it, and reach the array
+// through that slice from then on.
+func isSlicedArray(pkg *packages.Package, obj types.Object) bool {
+	vars, ok := slicedArrays[pkg]
+	if !ok {
+		vars = findSlicedArrays(pkg)
+		slicedArrays[pkg] = vars
+	}
+	return obj != nil && vars[obj]
+}
+
+// slicedArrayIdent returns the identifier of the sliced array variable expr
+// refers to, nil when it is not one
+func slicedArrayIdent(pkg *packages.Package, expr ast.Expr) *ast.Ident {
+	ident, ok := ast.Unparen(expr).(*ast.Ident)
+	if !ok || !isSlicedArray(pkg, pkg.TypesInfo.Uses[ident]) {
+		return nil
+	}
+	return ident
+}
+
+// findSlicedArrays collects the array variables that are sliced and declared
+// alone by a statement of a block, x := e or var x [N]T
+func findSlicedArrays(pkg *packages.Package) map[types.Object]bool {
+	declared := make(map[types.Object]bool)
+	sliced := make(map[types.Object]bool)
+	for _, file := range pkg.Syntax {
+		ast.Inspect(file, func(n ast.Node) bool {
+			var list []ast.Stmt
+			switch n := n.(type) {
+			case *ast.BlockStmt:
+				list = n.List
+			case *ast.CaseClause:
+				list = n.Body
+			case *ast.CommClause:
+				list = n.Body
+			case *ast.SliceExpr:
+				if ident, ok := ast.Unparen(n.X).(*ast.Ident); ok && isArrayExpr(pkg, ident) {
+					sliced[pkg.TypesInfo.Uses[ident]] = true
+				}
+			}
+			for _, stmt := range list {
+				switch stmt := stmt.(type) {
+				case *ast.AssignStmt:
+					if len(stmt.Lhs) == 1 && len(stmt.Rhs) == 1 && stmt.Tok == token.DEFINE {
+						declared[pkg.TypesInfo.Defs[stmt.Lhs[0].(*ast.Ident)]] = true
+					}
+				case *ast.DeclStmt:
+					for _, spec := range stmt.Decl.(*ast.GenDecl).Specs {
+						if spec, ok := spec.(*ast.ValueSpec); ok && len(spec.Names) == 1 && len(spec.Values) <= 1 {
+							declared[pkg.TypesInfo.Defs[spec.Names[0]]] = true
+						}
+					}
+				}
+			}
+			return true
+		})
+	}
+	vars := make(map[types.Object]bool)
+	for obj := range sliced {
+		if obj != nil && declared[obj] {
+			vars[obj] = true
+		}
+	}
+	return vars
+}
+
+// declaredIdents returns the identifiers a statement declares, the names of
+// a var declaration or the one of x := e
+func declaredIdents(node ast.Node) []*ast.Ident {
+	var idents []*ast.Ident
+	switch node := node.(type) {
+	case *ast.AssignStmt:
+		if node.Tok == token.DEFINE && len(node.Lhs) == 1 {
+			if ident, ok := node.Lhs[0].(*ast.Ident); ok {
+				idents = append(idents, ident)
+			}
+		}
+	case *ast.DeclStmt:
+		if gen, ok := node.Decl.(*ast.GenDecl); ok && gen.Tok == token.VAR {
+			for _, spec := range gen.Specs {
+				idents = append(idents, spec.(*ast.ValueSpec).Names...)
+			}
+		}
+	}
+	return idents
+}
+
+// isBoxed reports whether expr declares or refers to a variable boxed by
+// boxAddressedLocals
+func isBoxed(pkg *packages.Package, boxed map[types.Object]*types.Var, expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	obj := pkg.TypesInfo.ObjectOf(ident)
+	for _, v := range boxed {
+		if obj == v {
+			return true
+		}
+	}
+	return false
+}
+
+// boxedValues holds the &e made by addressOf, which backends emit as a new
+// box holding a copy of e whatever e is
+var boxedValues = map[*ast.UnaryExpr]bool{}
+
+// addressOf returns &expr, a pointer to a new value initialized with a copy
+// of expr, so that x := y boxes a copy of y rather than y itself
+func addressOf(pkg *packages.Package, expr ast.Expr) *ast.UnaryExpr {
+	unary := &ast.UnaryExpr{OpPos: expr.Pos(), Op: token.AND, X: expr}
+	tv := pkg.TypesInfo.Types[expr]
+	tv.Type = types.NewPointer(tv.Type)
+	pkg.TypesInfo.Types[unary] = tv
+	boxedValues[unary] = true
+	return unary
+}
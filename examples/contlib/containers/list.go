@@ -3,28 +3,28 @@ package containers
 import "fmt"
 
 // ListNode represents a node in the list using an array-based approach
-type ListNode struct {
-	value int // The value of the node
+type ListNode[T any] struct {
+	value T   // The value of the node
 	next  int // The index of the next node (-1 if no next node)
 }
 
-// List represents the array-based list
-type List struct {
-	nodes []ListNode // The array storing the list nodes
-	head  int        // The index of the head node (-1 if the list is empty)
+// List represents the array-based list of values of type T
+type List[T comparable] struct {
+	nodes []ListNode[T] // The array storing the list nodes
+	head  int           // The index of the head node (-1 if the list is empty)
 }
 
 // NewList creates a new empty list and returns it as a value
-func NewList() List {
-	return List{
-		nodes: []ListNode{}, // Initialize with an empty slice of nodes
-		head:  -1,           // -1 indicates the list is empty
+func NewList[T comparable]() List[T] {
+	return List[T]{
+		nodes: []ListNode[T]{}, // Initialize with an empty slice of nodes
+		head:  -1,              // -1 indicates the list is empty
 	}
 }
 
 // Add adds a new value to the end of the list and returns the modified list
-func Add(l List, value int) List {
-	newNode := ListNode{
+func Add[T comparable](l List[T], value T) List[T] {
+	newNode := ListNode[T]{
 		value: value,
 		next:  -1, // No next node as it will be the last one
 	}
@@ -54,7 +54,7 @@ func Add(l List, value int) List {
 }
 
 // Remove removes the first occurrence of a value in the list and returns the modified list
-func Remove(l List, value int) List {
+func Remove[T comparable](l List[T], value T) List[T] {
 	if l.head == -1 {
 		fmt.Println("The list is empty.")
 		return l
@@ -92,7 +92,7 @@ func Remove(l List, value int) List {
 }
 
 // Print prints the list
-func PrintList(l List) {
+func PrintList[T comparable](l List[T]) {
 	if l.head == -1 {
 		fmt.Println("The list is empty.")
 		return
@@ -100,7 +100,8 @@ func PrintList(l List) {
 
 	currIndex := l.head
 	for currIndex != -1 {
-		fmt.Printf("%d -> ", l.nodes[currIndex].value)
+		fmt.Print(l.nodes[currIndex].value)
+		fmt.Print(" -> ")
 		currIndex = l.nodes[currIndex].next
 	}
 	fmt.Println("nil")
@@ -3,29 +3,29 @@ package containers
 import "fmt"
 
 // BinaryTreeNode represents a node in the binary tree using an array-based approach
-type BinaryTreeNode struct {
-	value int // The value of the node
+type BinaryTreeNode[T any] struct {
+	value T   // The value of the node
 	left  int // The index of the left child (-1 if no left child)
 	right int // The index of the right child (-1 if no right child)
 }
 
-// BinaryTree represents the array-based binary tree
-type BinaryTree struct {
-	nodes []BinaryTreeNode // The array storing the tree nodes
-	root  int              // The index of the root node (-1 if the tree is empty)
+// BinaryTree represents the array-based binary tree of values of type T
+type BinaryTree[T comparable] struct {
+	nodes []BinaryTreeNode[T] // The array storing the tree nodes
+	root  int                 // The index of the root node (-1 if the tree is empty)
 }
 
 // NewBinaryTree creates a new empty binary tree and returns it as a value
-func NewBinaryTree() BinaryTree {
-	return BinaryTree{
-		nodes: []BinaryTreeNode{}, // Initialize with an empty slice of nodes
-		root:  -1,                 // -1 indicates the tree is empty
+func NewBinaryTree[T comparable]() BinaryTree[T] {
+	return BinaryTree[T]{
+		nodes: []BinaryTreeNode[T]{}, // Initialize with an empty slice of nodes
+		root:  -1,                    // -1 indicates the tree is empty
 	}
 }
 
 // Insert inserts a value into the binary tree, maintaining a complete binary tree, and returns the modified tree
-func Insert(t BinaryTree, value int) BinaryTree {
-	newNode := BinaryTreeNode{
+func Insert[T comparable](t BinaryTree[T], value T) BinaryTree[T] {
+	newNode := BinaryTreeNode[T]{
 		value: value,
 		left:  -1, // No left child
 		right: -1, // No right child
@@ -67,7 +67,7 @@ func Insert(t BinaryTree, value int) BinaryTree {
 }
 
 // Print prints the binary tree in a level-order traversal
-func PrintTree(t BinaryTree) {
+func PrintTree[T comparable](t BinaryTree[T]) {
 	if t.root == -1 {
 		fmt.Println("The tree is empty.")
 		return
@@ -79,7 +79,8 @@ func PrintTree(t BinaryTree) {
 		index := queue[0]
 		queue = queue[1:]
 		node := t.nodes[index]
-		fmt.Printf("%d:", node.value)
+		fmt.Print(node.value)
+		fmt.Print(":")
 
 		if node.left != -1 {
 			queue = append(queue, node.left)
@@ -93,7 +94,7 @@ func PrintTree(t BinaryTree) {
 
 // Remove removes a node by value in the binary tree and returns the modified tree
 // This implementation removes the last node and replaces the target node's value
-func RemoveFromTree(t BinaryTree, value int) BinaryTree {
+func RemoveFromTree[T comparable](t BinaryTree[T], value T) BinaryTree[T] {
 	if t.root == -1 {
 		fmt.Println("The tree is empty.")
 		return t
@@ -129,7 +130,7 @@ func RemoveFromTree(t BinaryTree, value int) BinaryTree {
 	}
 
 	// Remove the last node from the array by creating a new slice without the last element
-	newNodes := []BinaryTreeNode{}
+	newNodes := []BinaryTreeNode[T]{}
 	j := 0
 	for {
 		if j >= lastNodeIndex {
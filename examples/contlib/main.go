@@ -4,7 +4,7 @@ import "fmt"
 import "containers_tests/containers"
 
 func main() {
-	list := containers.NewList()
+	list := containers.NewList[int]()
 
 	// Add some elements to the list
 	list = containers.Add(list, 10)
@@ -25,7 +25,7 @@ func main() {
 	fmt.Println("List after removing 10:")
 	containers.PrintList(list) // Output: 30 -> 40 -> nil
 
-	tree := containers.NewBinaryTree()
+	tree := containers.NewBinaryTree[int]()
 
 	// Insert some elements into the binary tree
 	tree = containers.Insert(tree, 10)
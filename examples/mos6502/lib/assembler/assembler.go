@@ -101,6 +101,7 @@ func Tokenize(text string) []Token {
 	bytes := StringToBytes(text)
 	i := 0
 
+scan:
 	for {
 		if i >= len(bytes) {
 			break
@@ -124,14 +125,13 @@ func Tokenize(text string) []Token {
 		if b == ';' {
 			for {
 				if i >= len(bytes) {
-					break
+					break scan
 				}
 				if bytes[i] == '\n' {
-					break
+					continue scan
 				}
 				i = i + 1
 			}
-			continue
 		}
 
 		// Hash (immediate mode indicator)
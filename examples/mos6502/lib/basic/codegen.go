@@ -447,7 +447,6 @@ func genEnd() []string {
 }
 
 // CompileContext holds compilation state that needs to be passed between functions
-// (goany doesn't support package-level mutable variables)
 type CompileContext struct {
 	LabelCounter int
 	ForLoopStack []ForLoopInfo
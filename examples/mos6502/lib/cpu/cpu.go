@@ -241,15 +241,7 @@ type CPU struct {
 
 // NewCPU creates a new CPU with initialized memory
 func NewCPU() CPU {
-	mem := []uint8{}
-	i := 0
-	for {
-		if i >= 65536 {
-			break
-		}
-		mem = append(mem, uint8(0))
-		i = i + 1
-	}
+	var mem [65536]uint8
 	return CPU{
 		A:      0,
 		X:      0,
@@ -257,7 +249,7 @@ func NewCPU() CPU {
 		SP:     0xFF,
 		PC:     0x0600, // Programs start at $0600
 		Status: 0x20,   // Unused bit always set
-		Memory: mem,
+		Memory: mem[:],
 		Halted: false,
 		Cycles: 0,
 	}
@@ -3,202 +3,204 @@ package font
 // 8x8 bitmap font for ASCII characters 32-127
 // Each character is 8 bytes, where each byte represents one row
 // Bit 7 is leftmost pixel, bit 0 is rightmost pixel
+var fontData = []uint8{
+	// Character 32: ' ' (space)
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// Character 33: '!'
+	0x18, 0x18, 0x18, 0x18, 0x18, 0x00, 0x18, 0x00,
+	// Character 34: '"'
+	0x6C, 0x6C, 0x24, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// Character 35: '#'
+	0x6C, 0x6C, 0xFE, 0x6C, 0xFE, 0x6C, 0x6C, 0x00,
+	// Character 36: '$'
+	0x18, 0x3E, 0x60, 0x3C, 0x06, 0x7C, 0x18, 0x00,
+	// Character 37: '%'
+	0x00, 0xC6, 0xCC, 0x18, 0x30, 0x66, 0xC6, 0x00,
+	// Character 38: '&'
+	0x38, 0x6C, 0x38, 0x76, 0xDC, 0xCC, 0x76, 0x00,
+	// Character 39: '''
+	0x18, 0x18, 0x30, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// Character 40: '('
+	0x0C, 0x18, 0x30, 0x30, 0x30, 0x18, 0x0C, 0x00,
+	// Character 41: ')'
+	0x30, 0x18, 0x0C, 0x0C, 0x0C, 0x18, 0x30, 0x00,
+	// Character 42: '*'
+	0x00, 0x66, 0x3C, 0xFF, 0x3C, 0x66, 0x00, 0x00,
+	// Character 43: '+'
+	0x00, 0x18, 0x18, 0x7E, 0x18, 0x18, 0x00, 0x00,
+	// Character 44: ','
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x18, 0x30,
+	// Character 45: '-'
+	0x00, 0x00, 0x00, 0x7E, 0x00, 0x00, 0x00, 0x00,
+	// Character 46: '.'
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x18, 0x00,
+	// Character 47: '/'
+	0x06, 0x0C, 0x18, 0x30, 0x60, 0xC0, 0x80, 0x00,
+	// Character 48: '0'
+	0x7C, 0xC6, 0xCE, 0xD6, 0xE6, 0xC6, 0x7C, 0x00,
+	// Character 49: '1'
+	0x18, 0x38, 0x18, 0x18, 0x18, 0x18, 0x7E, 0x00,
+	// Character 50: '2'
+	0x7C, 0xC6, 0x06, 0x1C, 0x30, 0x66, 0xFE, 0x00,
+	// Character 51: '3'
+	0x7C, 0xC6, 0x06, 0x3C, 0x06, 0xC6, 0x7C, 0x00,
+	// Character 52: '4'
+	0x1C, 0x3C, 0x6C, 0xCC, 0xFE, 0x0C, 0x1E, 0x00,
+	// Character 53: '5'
+	0xFE, 0xC0, 0xC0, 0xFC, 0x06, 0xC6, 0x7C, 0x00,
+	// Character 54: '6'
+	0x38, 0x60, 0xC0, 0xFC, 0xC6, 0xC6, 0x7C, 0x00,
+	// Character 55: '7'
+	0xFE, 0xC6, 0x0C, 0x18, 0x30, 0x30, 0x30, 0x00,
+	// Character 56: '8'
+	0x7C, 0xC6, 0xC6, 0x7C, 0xC6, 0xC6, 0x7C, 0x00,
+	// Character 57: '9'
+	0x7C, 0xC6, 0xC6, 0x7E, 0x06, 0x0C, 0x78, 0x00,
+	// Character 58: ':'
+	0x00, 0x18, 0x18, 0x00, 0x00, 0x18, 0x18, 0x00,
+	// Character 59: ';'
+	0x00, 0x18, 0x18, 0x00, 0x00, 0x18, 0x18, 0x30,
+	// Character 60: '<'
+	0x06, 0x0C, 0x18, 0x30, 0x18, 0x0C, 0x06, 0x00,
+	// Character 61: '='
+	0x00, 0x00, 0x7E, 0x00, 0x00, 0x7E, 0x00, 0x00,
+	// Character 62: '>'
+	0x60, 0x30, 0x18, 0x0C, 0x18, 0x30, 0x60, 0x00,
+	// Character 63: '?'
+	0x7C, 0xC6, 0x0C, 0x18, 0x18, 0x00, 0x18, 0x00,
+	// Character 64: '@'
+	0x7C, 0xC6, 0xDE, 0xDE, 0xDE, 0xC0, 0x78, 0x00,
+	// Character 65: 'A'
+	0x38, 0x6C, 0xC6, 0xFE, 0xC6, 0xC6, 0xC6, 0x00,
+	// Character 66: 'B'
+	0xFC, 0x66, 0x66, 0x7C, 0x66, 0x66, 0xFC, 0x00,
+	// Character 67: 'C'
+	0x3C, 0x66, 0xC0, 0xC0, 0xC0, 0x66, 0x3C, 0x00,
+	// Character 68: 'D'
+	0xF8, 0x6C, 0x66, 0x66, 0x66, 0x6C, 0xF8, 0x00,
+	// Character 69: 'E'
+	0xFE, 0x62, 0x68, 0x78, 0x68, 0x62, 0xFE, 0x00,
+	// Character 70: 'F'
+	0xFE, 0x62, 0x68, 0x78, 0x68, 0x60, 0xF0, 0x00,
+	// Character 71: 'G'
+	0x3C, 0x66, 0xC0, 0xC0, 0xCE, 0x66, 0x3A, 0x00,
+	// Character 72: 'H'
+	0xC6, 0xC6, 0xC6, 0xFE, 0xC6, 0xC6, 0xC6, 0x00,
+	// Character 73: 'I'
+	0x3C, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, 0x00,
+	// Character 74: 'J'
+	0x1E, 0x0C, 0x0C, 0x0C, 0xCC, 0xCC, 0x78, 0x00,
+	// Character 75: 'K'
+	0xE6, 0x66, 0x6C, 0x78, 0x6C, 0x66, 0xE6, 0x00,
+	// Character 76: 'L'
+	0xF0, 0x60, 0x60, 0x60, 0x62, 0x66, 0xFE, 0x00,
+	// Character 77: 'M'
+	0xC6, 0xEE, 0xFE, 0xFE, 0xD6, 0xC6, 0xC6, 0x00,
+	// Character 78: 'N'
+	0xC6, 0xE6, 0xF6, 0xDE, 0xCE, 0xC6, 0xC6, 0x00,
+	// Character 79: 'O'
+	0x7C, 0xC6, 0xC6, 0xC6, 0xC6, 0xC6, 0x7C, 0x00,
+	// Character 80: 'P'
+	0xFC, 0x66, 0x66, 0x7C, 0x60, 0x60, 0xF0, 0x00,
+	// Character 81: 'Q'
+	0x7C, 0xC6, 0xC6, 0xC6, 0xD6, 0xDE, 0x7C, 0x06,
+	// Character 82: 'R'
+	0xFC, 0x66, 0x66, 0x7C, 0x6C, 0x66, 0xE6, 0x00,
+	// Character 83: 'S'
+	0x7C, 0xC6, 0x60, 0x38, 0x0C, 0xC6, 0x7C, 0x00,
+	// Character 84: 'T'
+	0x7E, 0x7E, 0x5A, 0x18, 0x18, 0x18, 0x3C, 0x00,
+	// Character 85: 'U'
+	0xC6, 0xC6, 0xC6, 0xC6, 0xC6, 0xC6, 0x7C, 0x00,
+	// Character 86: 'V'
+	0xC6, 0xC6, 0xC6, 0xC6, 0xC6, 0x6C, 0x38, 0x00,
+	// Character 87: 'W'
+	0xC6, 0xC6, 0xC6, 0xD6, 0xD6, 0xFE, 0x6C, 0x00,
+	// Character 88: 'X'
+	0xC6, 0xC6, 0x6C, 0x38, 0x6C, 0xC6, 0xC6, 0x00,
+	// Character 89: 'Y'
+	0x66, 0x66, 0x66, 0x3C, 0x18, 0x18, 0x3C, 0x00,
+	// Character 90: 'Z'
+	0xFE, 0xC6, 0x8C, 0x18, 0x32, 0x66, 0xFE, 0x00,
+	// Character 91: '['
+	0x3C, 0x30, 0x30, 0x30, 0x30, 0x30, 0x3C, 0x00,
+	// Character 92: '\'
+	0xC0, 0x60, 0x30, 0x18, 0x0C, 0x06, 0x02, 0x00,
+	// Character 93: ']'
+	0x3C, 0x0C, 0x0C, 0x0C, 0x0C, 0x0C, 0x3C, 0x00,
+	// Character 94: '^'
+	0x10, 0x38, 0x6C, 0xC6, 0x00, 0x00, 0x00, 0x00,
+	// Character 95: '_'
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF,
+	// Character 96: '`'
+	0x30, 0x18, 0x0C, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// Character 97: 'a'
+	0x00, 0x00, 0x78, 0x0C, 0x7C, 0xCC, 0x76, 0x00,
+	// Character 98: 'b'
+	0xE0, 0x60, 0x7C, 0x66, 0x66, 0x66, 0xDC, 0x00,
+	// Character 99: 'c'
+	0x00, 0x00, 0x7C, 0xC6, 0xC0, 0xC6, 0x7C, 0x00,
+	// Character 100: 'd'
+	0x1C, 0x0C, 0x7C, 0xCC, 0xCC, 0xCC, 0x76, 0x00,
+	// Character 101: 'e'
+	0x00, 0x00, 0x7C, 0xC6, 0xFE, 0xC0, 0x7C, 0x00,
+	// Character 102: 'f'
+	0x3C, 0x66, 0x60, 0xF8, 0x60, 0x60, 0xF0, 0x00,
+	// Character 103: 'g'
+	0x00, 0x00, 0x76, 0xCC, 0xCC, 0x7C, 0x0C, 0xF8,
+	// Character 104: 'h'
+	0xE0, 0x60, 0x6C, 0x76, 0x66, 0x66, 0xE6, 0x00,
+	// Character 105: 'i'
+	0x18, 0x00, 0x38, 0x18, 0x18, 0x18, 0x3C, 0x00,
+	// Character 106: 'j'
+	0x06, 0x00, 0x06, 0x06, 0x06, 0x66, 0x66, 0x3C,
+	// Character 107: 'k'
+	0xE0, 0x60, 0x66, 0x6C, 0x78, 0x6C, 0xE6, 0x00,
+	// Character 108: 'l'
+	0x38, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, 0x00,
+	// Character 109: 'm'
+	0x00, 0x00, 0xEC, 0xFE, 0xD6, 0xD6, 0xD6, 0x00,
+	// Character 110: 'n'
+	0x00, 0x00, 0xDC, 0x66, 0x66, 0x66, 0x66, 0x00,
+	// Character 111: 'o'
+	0x00, 0x00, 0x7C, 0xC6, 0xC6, 0xC6, 0x7C, 0x00,
+	// Character 112: 'p'
+	0x00, 0x00, 0xDC, 0x66, 0x66, 0x7C, 0x60, 0xF0,
+	// Character 113: 'q'
+	0x00, 0x00, 0x76, 0xCC, 0xCC, 0x7C, 0x0C, 0x1E,
+	// Character 114: 'r'
+	0x00, 0x00, 0xDC, 0x76, 0x60, 0x60, 0xF0, 0x00,
+	// Character 115: 's'
+	0x00, 0x00, 0x7E, 0xC0, 0x7C, 0x06, 0xFC, 0x00,
+	// Character 116: 't'
+	0x30, 0x30, 0xFC, 0x30, 0x30, 0x36, 0x1C, 0x00,
+	// Character 117: 'u'
+	0x00, 0x00, 0xCC, 0xCC, 0xCC, 0xCC, 0x76, 0x00,
+	// Character 118: 'v'
+	0x00, 0x00, 0xC6, 0xC6, 0xC6, 0x6C, 0x38, 0x00,
+	// Character 119: 'w'
+	0x00, 0x00, 0xC6, 0xD6, 0xD6, 0xFE, 0x6C, 0x00,
+	// Character 120: 'x'
+	0x00, 0x00, 0xC6, 0x6C, 0x38, 0x6C, 0xC6, 0x00,
+	// Character 121: 'y'
+	0x00, 0x00, 0xC6, 0xC6, 0xC6, 0x7E, 0x06, 0xFC,
+	// Character 122: 'z'
+	0x00, 0x00, 0xFE, 0x8C, 0x18, 0x32, 0xFE, 0x00,
+	// Character 123: '{'
+	0x0E, 0x18, 0x18, 0x70, 0x18, 0x18, 0x0E, 0x00,
+	// Character 124: '|'
+	0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x00,
+	// Character 125: '}'
+	0x70, 0x18, 0x18, 0x0E, 0x18, 0x18, 0x70, 0x00,
+	// Character 126: '~'
+	0x76, 0xDC, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	// Character 127: DEL (block character)
+	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
+}
 
+// GetFontData returns the font bitmap table
 func GetFontData() []uint8 {
-	return []uint8{
-		// Character 32: ' ' (space)
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		// Character 33: '!'
-		0x18, 0x18, 0x18, 0x18, 0x18, 0x00, 0x18, 0x00,
-		// Character 34: '"'
-		0x6C, 0x6C, 0x24, 0x00, 0x00, 0x00, 0x00, 0x00,
-		// Character 35: '#'
-		0x6C, 0x6C, 0xFE, 0x6C, 0xFE, 0x6C, 0x6C, 0x00,
-		// Character 36: '$'
-		0x18, 0x3E, 0x60, 0x3C, 0x06, 0x7C, 0x18, 0x00,
-		// Character 37: '%'
-		0x00, 0xC6, 0xCC, 0x18, 0x30, 0x66, 0xC6, 0x00,
-		// Character 38: '&'
-		0x38, 0x6C, 0x38, 0x76, 0xDC, 0xCC, 0x76, 0x00,
-		// Character 39: '''
-		0x18, 0x18, 0x30, 0x00, 0x00, 0x00, 0x00, 0x00,
-		// Character 40: '('
-		0x0C, 0x18, 0x30, 0x30, 0x30, 0x18, 0x0C, 0x00,
-		// Character 41: ')'
-		0x30, 0x18, 0x0C, 0x0C, 0x0C, 0x18, 0x30, 0x00,
-		// Character 42: '*'
-		0x00, 0x66, 0x3C, 0xFF, 0x3C, 0x66, 0x00, 0x00,
-		// Character 43: '+'
-		0x00, 0x18, 0x18, 0x7E, 0x18, 0x18, 0x00, 0x00,
-		// Character 44: ','
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x18, 0x30,
-		// Character 45: '-'
-		0x00, 0x00, 0x00, 0x7E, 0x00, 0x00, 0x00, 0x00,
-		// Character 46: '.'
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x18, 0x00,
-		// Character 47: '/'
-		0x06, 0x0C, 0x18, 0x30, 0x60, 0xC0, 0x80, 0x00,
-		// Character 48: '0'
-		0x7C, 0xC6, 0xCE, 0xD6, 0xE6, 0xC6, 0x7C, 0x00,
-		// Character 49: '1'
-		0x18, 0x38, 0x18, 0x18, 0x18, 0x18, 0x7E, 0x00,
-		// Character 50: '2'
-		0x7C, 0xC6, 0x06, 0x1C, 0x30, 0x66, 0xFE, 0x00,
-		// Character 51: '3'
-		0x7C, 0xC6, 0x06, 0x3C, 0x06, 0xC6, 0x7C, 0x00,
-		// Character 52: '4'
-		0x1C, 0x3C, 0x6C, 0xCC, 0xFE, 0x0C, 0x1E, 0x00,
-		// Character 53: '5'
-		0xFE, 0xC0, 0xC0, 0xFC, 0x06, 0xC6, 0x7C, 0x00,
-		// Character 54: '6'
-		0x38, 0x60, 0xC0, 0xFC, 0xC6, 0xC6, 0x7C, 0x00,
-		// Character 55: '7'
-		0xFE, 0xC6, 0x0C, 0x18, 0x30, 0x30, 0x30, 0x00,
-		// Character 56: '8'
-		0x7C, 0xC6, 0xC6, 0x7C, 0xC6, 0xC6, 0x7C, 0x00,
-		// Character 57: '9'
-		0x7C, 0xC6, 0xC6, 0x7E, 0x06, 0x0C, 0x78, 0x00,
-		// Character 58: ':'
-		0x00, 0x18, 0x18, 0x00, 0x00, 0x18, 0x18, 0x00,
-		// Character 59: ';'
-		0x00, 0x18, 0x18, 0x00, 0x00, 0x18, 0x18, 0x30,
-		// Character 60: '<'
-		0x06, 0x0C, 0x18, 0x30, 0x18, 0x0C, 0x06, 0x00,
-		// Character 61: '='
-		0x00, 0x00, 0x7E, 0x00, 0x00, 0x7E, 0x00, 0x00,
-		// Character 62: '>'
-		0x60, 0x30, 0x18, 0x0C, 0x18, 0x30, 0x60, 0x00,
-		// Character 63: '?'
-		0x7C, 0xC6, 0x0C, 0x18, 0x18, 0x00, 0x18, 0x00,
-		// Character 64: '@'
-		0x7C, 0xC6, 0xDE, 0xDE, 0xDE, 0xC0, 0x78, 0x00,
-		// Character 65: 'A'
-		0x38, 0x6C, 0xC6, 0xFE, 0xC6, 0xC6, 0xC6, 0x00,
-		// Character 66: 'B'
-		0xFC, 0x66, 0x66, 0x7C, 0x66, 0x66, 0xFC, 0x00,
-		// Character 67: 'C'
-		0x3C, 0x66, 0xC0, 0xC0, 0xC0, 0x66, 0x3C, 0x00,
-		// Character 68: 'D'
-		0xF8, 0x6C, 0x66, 0x66, 0x66, 0x6C, 0xF8, 0x00,
-		// Character 69: 'E'
-		0xFE, 0x62, 0x68, 0x78, 0x68, 0x62, 0xFE, 0x00,
-		// Character 70: 'F'
-		0xFE, 0x62, 0x68, 0x78, 0x68, 0x60, 0xF0, 0x00,
-		// Character 71: 'G'
-		0x3C, 0x66, 0xC0, 0xC0, 0xCE, 0x66, 0x3A, 0x00,
-		// Character 72: 'H'
-		0xC6, 0xC6, 0xC6, 0xFE, 0xC6, 0xC6, 0xC6, 0x00,
-		// Character 73: 'I'
-		0x3C, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, 0x00,
-		// Character 74: 'J'
-		0x1E, 0x0C, 0x0C, 0x0C, 0xCC, 0xCC, 0x78, 0x00,
-		// Character 75: 'K'
-		0xE6, 0x66, 0x6C, 0x78, 0x6C, 0x66, 0xE6, 0x00,
-		// Character 76: 'L'
-		0xF0, 0x60, 0x60, 0x60, 0x62, 0x66, 0xFE, 0x00,
-		// Character 77: 'M'
-		0xC6, 0xEE, 0xFE, 0xFE, 0xD6, 0xC6, 0xC6, 0x00,
-		// Character 78: 'N'
-		0xC6, 0xE6, 0xF6, 0xDE, 0xCE, 0xC6, 0xC6, 0x00,
-		// Character 79: 'O'
-		0x7C, 0xC6, 0xC6, 0xC6, 0xC6, 0xC6, 0x7C, 0x00,
-		// Character 80: 'P'
-		0xFC, 0x66, 0x66, 0x7C, 0x60, 0x60, 0xF0, 0x00,
-		// Character 81: 'Q'
-		0x7C, 0xC6, 0xC6, 0xC6, 0xD6, 0xDE, 0x7C, 0x06,
-		// Character 82: 'R'
-		0xFC, 0x66, 0x66, 0x7C, 0x6C, 0x66, 0xE6, 0x00,
-		// Character 83: 'S'
-		0x7C, 0xC6, 0x60, 0x38, 0x0C, 0xC6, 0x7C, 0x00,
-		// Character 84: 'T'
-		0x7E, 0x7E, 0x5A, 0x18, 0x18, 0x18, 0x3C, 0x00,
-		// Character 85: 'U'
-		0xC6, 0xC6, 0xC6, 0xC6, 0xC6, 0xC6, 0x7C, 0x00,
-		// Character 86: 'V'
-		0xC6, 0xC6, 0xC6, 0xC6, 0xC6, 0x6C, 0x38, 0x00,
-		// Character 87: 'W'
-		0xC6, 0xC6, 0xC6, 0xD6, 0xD6, 0xFE, 0x6C, 0x00,
-		// Character 88: 'X'
-		0xC6, 0xC6, 0x6C, 0x38, 0x6C, 0xC6, 0xC6, 0x00,
-		// Character 89: 'Y'
-		0x66, 0x66, 0x66, 0x3C, 0x18, 0x18, 0x3C, 0x00,
-		// Character 90: 'Z'
-		0xFE, 0xC6, 0x8C, 0x18, 0x32, 0x66, 0xFE, 0x00,
-		// Character 91: '['
-		0x3C, 0x30, 0x30, 0x30, 0x30, 0x30, 0x3C, 0x00,
-		// Character 92: '\'
-		0xC0, 0x60, 0x30, 0x18, 0x0C, 0x06, 0x02, 0x00,
-		// Character 93: ']'
-		0x3C, 0x0C, 0x0C, 0x0C, 0x0C, 0x0C, 0x3C, 0x00,
-		// Character 94: '^'
-		0x10, 0x38, 0x6C, 0xC6, 0x00, 0x00, 0x00, 0x00,
-		// Character 95: '_'
-		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF,
-		// Character 96: '`'
-		0x30, 0x18, 0x0C, 0x00, 0x00, 0x00, 0x00, 0x00,
-		// Character 97: 'a'
-		0x00, 0x00, 0x78, 0x0C, 0x7C, 0xCC, 0x76, 0x00,
-		// Character 98: 'b'
-		0xE0, 0x60, 0x7C, 0x66, 0x66, 0x66, 0xDC, 0x00,
-		// Character 99: 'c'
-		0x00, 0x00, 0x7C, 0xC6, 0xC0, 0xC6, 0x7C, 0x00,
-		// Character 100: 'd'
-		0x1C, 0x0C, 0x7C, 0xCC, 0xCC, 0xCC, 0x76, 0x00,
-		// Character 101: 'e'
-		0x00, 0x00, 0x7C, 0xC6, 0xFE, 0xC0, 0x7C, 0x00,
-		// Character 102: 'f'
-		0x3C, 0x66, 0x60, 0xF8, 0x60, 0x60, 0xF0, 0x00,
-		// Character 103: 'g'
-		0x00, 0x00, 0x76, 0xCC, 0xCC, 0x7C, 0x0C, 0xF8,
-		// Character 104: 'h'
-		0xE0, 0x60, 0x6C, 0x76, 0x66, 0x66, 0xE6, 0x00,
-		// Character 105: 'i'
-		0x18, 0x00, 0x38, 0x18, 0x18, 0x18, 0x3C, 0x00,
-		// Character 106: 'j'
-		0x06, 0x00, 0x06, 0x06, 0x06, 0x66, 0x66, 0x3C,
-		// Character 107: 'k'
-		0xE0, 0x60, 0x66, 0x6C, 0x78, 0x6C, 0xE6, 0x00,
-		// Character 108: 'l'
-		0x38, 0x18, 0x18, 0x18, 0x18, 0x18, 0x3C, 0x00,
-		// Character 109: 'm'
-		0x00, 0x00, 0xEC, 0xFE, 0xD6, 0xD6, 0xD6, 0x00,
-		// Character 110: 'n'
-		0x00, 0x00, 0xDC, 0x66, 0x66, 0x66, 0x66, 0x00,
-		// Character 111: 'o'
-		0x00, 0x00, 0x7C, 0xC6, 0xC6, 0xC6, 0x7C, 0x00,
-		// Character 112: 'p'
-		0x00, 0x00, 0xDC, 0x66, 0x66, 0x7C, 0x60, 0xF0,
-		// Character 113: 'q'
-		0x00, 0x00, 0x76, 0xCC, 0xCC, 0x7C, 0x0C, 0x1E,
-		// Character 114: 'r'
-		0x00, 0x00, 0xDC, 0x76, 0x60, 0x60, 0xF0, 0x00,
-		// Character 115: 's'
-		0x00, 0x00, 0x7E, 0xC0, 0x7C, 0x06, 0xFC, 0x00,
-		// Character 116: 't'
-		0x30, 0x30, 0xFC, 0x30, 0x30, 0x36, 0x1C, 0x00,
-		// Character 117: 'u'
-		0x00, 0x00, 0xCC, 0xCC, 0xCC, 0xCC, 0x76, 0x00,
-		// Character 118: 'v'
-		0x00, 0x00, 0xC6, 0xC6, 0xC6, 0x6C, 0x38, 0x00,
-		// Character 119: 'w'
-		0x00, 0x00, 0xC6, 0xD6, 0xD6, 0xFE, 0x6C, 0x00,
-		// Character 120: 'x'
-		0x00, 0x00, 0xC6, 0x6C, 0x38, 0x6C, 0xC6, 0x00,
-		// Character 121: 'y'
-		0x00, 0x00, 0xC6, 0xC6, 0xC6, 0x7E, 0x06, 0xFC,
-		// Character 122: 'z'
-		0x00, 0x00, 0xFE, 0x8C, 0x18, 0x32, 0xFE, 0x00,
-		// Character 123: '{'
-		0x0E, 0x18, 0x18, 0x70, 0x18, 0x18, 0x0E, 0x00,
-		// Character 124: '|'
-		0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x00,
-		// Character 125: '}'
-		0x70, 0x18, 0x18, 0x0E, 0x18, 0x18, 0x70, 0x00,
-		// Character 126: '~'
-		0x76, 0xDC, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
-		// Character 127: DEL (block character)
-		0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
-	}
+	return fontData
 }
 
 // GetCharBitmap returns the 8-byte bitmap for a character
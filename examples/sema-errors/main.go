@@ -20,33 +20,28 @@ func pointerTypeError() {
 	_ = p
 }
 
-// ERROR: Defer statements are not supported
-func deferError() {
-	defer println("cleanup") // error: defer not allowed
+// ERROR: len and cap of channels are not supported
+func channelLenError() {
+	ch := make(chan int, 1)
+	_ = len(ch) // error: len of a channel not allowed
 }
 
-// ERROR: Goroutines are not supported
-func goroutineError() {
-	go func() {}() // error: go keyword not allowed
-}
-
-// ERROR: Channel types are not supported
-func channelError() {
-	ch := make(chan int) // error: channel type not allowed
-	_ = ch
+// ERROR: Map keys must be strings, integers or booleans
+type mapKey struct {
+	X int
 }
 
-// ERROR: Map types are not supported
 func mapError() {
-	m := make(map[string]int) // error: map type not allowed
+	m := make(map[mapKey]int) // error: map key type not allowed
 	_ = m
 }
 
-// ERROR: Select statements are not supported
-func selectError() {
+// ERROR: Break inside a select statement is not supported
+func selectBreakError() {
 	ch := make(chan int)
-	select { // error: select not allowed
+	select {
 	case <-ch:
+		break // error: break inside select not allowed
 	}
 }
 
@@ -57,74 +52,57 @@ label: // error: label not allowed
 	goto label // error: goto not allowed
 }
 
-// ERROR: Method receivers are not supported
-type MyType struct {
-	Value int
-}
+// ERROR: Methods are only supported on struct types
+type MyType int
 
-func (m MyType) GetValue() int { // error: method receiver not allowed
-	return m.Value
+func (m MyType) GetValue() int { // error: receiver is not a struct
+	return int(m)
 }
 
 // ERROR: Init functions are not supported
 func init() { // error: init() not allowed
 }
 
-// ERROR: Variadic functions are not supported
-func variadicError(args ...int) { // error: variadic not allowed
-	_ = args
-}
-
-// ERROR: Named return values are not supported
-func namedReturnError() (result int) { // error: named return not allowed
-	return 0
+// ERROR: Interface embedding is not supported
+type Reader interface {
+	Read() int
 }
 
-// ERROR: Non-empty interfaces are not supported
-type Reader interface {
-	Read() int // error: interface methods not allowed
+type ReadCloser interface {
+	Reader // error: embedding not allowed
+	Close()
 }
 
-// ERROR: Struct embedding is not supported
+// ERROR: Interfaces satisfied by promoted methods are not supported
 type Base struct {
 	X int
 }
 
+func (b Base) Read() int {
+	return b.X
+}
+
 type Derived struct {
-	Base // error: embedding not allowed
-	Y int
+	Base // error: Derived implements Reader through the promoted Read
+	Y    int
 }
 
-// ERROR: Type switch is not supported
+// ERROR: Type switch with a nil case is not supported
 func typeSwitchError() {
 	var x interface{}
-	switch x.(type) { // error: type switch not allowed
+	switch x.(type) {
+	case nil: // error: nil case not allowed
 	case int:
 	}
 }
 
-// ERROR: Range over inline slice literal is not supported
-func rangeInlineError() {
-	for _, x := range []int{1, 2, 3} { // error: inline slice literal not allowed
-		_ = x
-	}
-}
-
-// ERROR: Nil comparison is not supported
+// ERROR: Nil comparison of a map is not supported
 func nilCompareError() {
-	var s []int
-	if s == nil { // error: nil comparison not allowed
+	var m map[string]int
+	if m == nil { // error: nil comparison not allowed
 	}
 }
 
-// ERROR: Iota is not supported
-const (
-	A = iota // error: iota not allowed
-	B
-	C
-)
-
-
 // ERROR: Collection mutation during iteration
 func mutationDuringIterationError() {
 	items := []int{1, 2, 3}
@@ -186,94 +164,11 @@ func nestedCallsError() {
 	_ = result
 }
 
-// ERROR: Multiple closures capture same variable (Rust borrow checker)
-func multipleClosuresError() {
-	data := []int{1, 2, 3}
-
-	fn1 := func() {
-		_ = data // first closure captures data
-	}
-
-	fn2 := func() {
-		_ = data // error: second closure also captures data
-	}
-
-	fn1()
-	fn2()
-}
-
-// ERROR: Mutation of variable after closure capture
-func mutationAfterCaptureError() {
-	data := []int{1, 2, 3}
-
-	fn := func() {
-		_ = data // closure captures data
-	}
-
-	data = append(data, 4) // error: mutating after capture
-	fn()
-}
-
 // ERROR: Slice self-assignment (Rust borrow checker)
 func sliceSelfAssignError() {
 	items := []int{1, 2, 3}
 	items = append(items, items[0]) // error: items borrowed and mutated simultaneously
 }
 
-// ============================================
-// C#-SPECIFIC PATTERNS
-// ============================================
-
-// ERROR: Variable shadowing (C# does not allow shadowing within same function)
-// In C#, you cannot declare a variable with the same name in a nested scope
-// if a variable with that name exists in an outer scope of the same function.
-func variableShadowingError() {
-	col := 0 // outer scope variable
-	for {
-		if col >= 10 {
-			break
-		}
-		col := 1 // error: shadows outer 'col' - C# doesn't allow this
-		_ = col
-	}
-	_ = col
-}
-
-// ERROR: Variable shadowing in if block
-func variableShadowingIfError() {
-	x := 5 // outer scope
-	if true {
-		x := 10 // error: shadows outer 'x'
-		_ = x
-	}
-	_ = x
-}
-
-// CORRECT: Use different variable names
-func variableShadowingFixed() {
-	col := 0
-	for {
-		if col >= 10 {
-			break
-		}
-		innerCol := 1 // different name - OK
-		_ = innerCol
-		col = col + 1
-	}
-	_ = col
-}
-
-// CORRECT: Reassignment (not redeclaration) is allowed
-func variableReassignmentOk() {
-	col := 0
-	for {
-		if col >= 10 {
-			break
-		}
-		col = col + 1 // reassignment with = is OK
-	}
-	_ = col
-}
-
 func main() {
 }
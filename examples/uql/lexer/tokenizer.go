@@ -4,15 +4,15 @@ import "fmt"
 
 // Token types as constants
 const (
-	TokenLetter           = 0
-	TokenDigit            = 1
-	TokenSpace            = 2
-	TokenSymbol           = 3
-	TokenLeftParenthesis  = 4
-	TokenRightParenthesis = 5
-	TokenPipe             = 6
-	TokenGreater          = 7
-	TokenLess             = 8
+	TokenLetter = iota
+	TokenDigit
+	TokenSpace
+	TokenSymbol
+	TokenLeftParenthesis
+	TokenRightParenthesis
+	TokenPipe
+	TokenGreater
+	TokenLess
 )
 
 func IsLetter(b int8) bool {
@@ -177,9 +177,8 @@ func drawChar(w graphics.Window, charCode int, x int32, y int32, scale int32, co
 		charCode = 32
 	}
 	offset := (charCode - 32) * 8
-	font := getFontData()
 	for row := int32(0); row < 8; row++ {
-		rowData := font[offset+int(row)]
+		rowData := fontData[offset+int(row)]
 		for col := int32(0); col < 8; col++ {
 			if (rowData & (0x80 >> col)) != 0 {
 				// Draw scaled pixel
@@ -1457,8 +1456,7 @@ func ColorPicker(ctx GuiContext, w graphics.Window, label string, x int32, y int
 // Each character is 8 bytes, where each byte represents one row
 // Bit 7 is leftmost pixel, bit 0 is rightmost pixel
 
-func getFontData() []uint8 {
-	return []uint8{
+var fontData = []uint8{
 	// Character 32: ' ' (space)
 	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	// Character 33: '!'
@@ -1651,5 +1649,4 @@ func getFontData() []uint8 {
 	0x76, 0xDC, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	// Character 127: DEL (block character)
 	0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF,
-	}
 }
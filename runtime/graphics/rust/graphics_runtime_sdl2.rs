@@ -171,8 +171,10 @@ pub fn PollEvents(mut w: Window) -> (Window, bool) {
     })
 }
 
-pub fn GetLastKey() -> i32 {
-    LAST_KEY.with(|k| *k.borrow())
+/// GetLastKey returns the last key pressed as a Go int, crate::Int is defined
+/// by the generated crate root
+pub fn GetLastKey() -> crate::Int {
+    LAST_KEY.with(|k| *k.borrow() as crate::Int)
 }
 
 /// GetMouse returns mouse position and button state
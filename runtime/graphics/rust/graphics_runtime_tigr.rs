@@ -281,8 +281,10 @@ pub fn PollEvents(mut w: Window) -> (Window, bool) {
     (w, true)
 }
 
-pub fn GetLastKey() -> i32 {
-    LAST_KEY.with(|k| *k.borrow())
+/// GetLastKey returns the last key pressed as a Go int, crate::Int is defined
+/// by the generated crate root
+pub fn GetLastKey() -> crate::Int {
+    LAST_KEY.with(|k| *k.borrow() as crate::Int)
 }
 
 pub fn GetMouse(w: Window) -> (i32, i32, i32) {
@@ -5,29 +5,17 @@ package main
 //
 // UNSUPPORTED CONSTRUCTS (not included in this file):
 //
-// 1. if slice == nil - Nil comparison for slices
-//    C++ std::vector cannot be compared to nullptr
-//
-// 2. len(string) - String length
+// 1. len(string) - String length
 //    C++ backend uses std::size() which doesn't work on C-style strings
 //
-// 3. for condition { } - While-style loops
+// 2. for condition { } - While-style loops
 //    C# backend has a bug with semicolons in loop body
 //
-// 4. iota - Constant enumeration
-//    Not yet implemented
-//
-// 5. fmt.Sprintf - String formatting
+// 3. fmt.Sprintf - String formatting
 //    Rust backend has type mismatch issues with string_format2
 //
-// 6. for _, x := range []int{1,2,3} - Range over inline slice literal
-//    Rust backend generates malformed code
-//
-// 7. []interface{} - Slice of empty interface (any type)
+// 4. []interface{} - Slice of empty interface (any type)
 //    Not supported across backends
-//
-// SUPPORTED WITH LIMITATIONS:
-// - interface{} (empty interface) - works for assignment, no type assertions
 
 import (
 	"alltests/types"
@@ -83,7 +71,7 @@ func testLoopConstructs() {
 	var a []int
 
 	// C-style for loop
-	// @test cpp="for (auto x = 0; x < 10; x++)" cs="for (var x = 0; (x < 10 ); x++)" rust="for x in 0..10"
+	// @test cpp="for (std::int64_t x = 0; x < 10; x++)" cs="for (long x = 0; (x < 10 ); x++)" rust="for x in 0i64..10"
 	for x := 0; x < 10; x++ {
 		if !(len(a) == 0) {
 		} else if len(a) == 0 {
@@ -97,7 +85,7 @@ func testLoopConstructs() {
 	}
 
 	// Range-based for loop with index and value
-	// @test cpp="for (size_t i = 0; i < nums2.size(); i++)" cs="for (int i = 0; i < nums2.Count; i++)" rust="for (i, v) in nums2.clone().iter().enumerate()"
+	// @test cpp="for (size_t i = 0; i < nums2.size(); i++)" cs="for (long i = 0; i < SliceBuiltins.Length(nums2); i++)" rust="for (i, v) in nums2.clone().iter().enumerate()"
 	nums2 := []int{10, 20, 30}
 	for i, v := range nums2 {
 		fmt.Println(i)
@@ -140,7 +128,7 @@ func testLoopConstructs() {
 	}
 
 	// Step by 2: i += 2
-	// @test cpp="for (auto i = 0; i < 10; i += 2)" cs="for (var i = 0; (i < 10 ); i += 2)" rust="for i in (0..10).step_by(2)"
+	// @test cpp="for (std::int64_t i = 0; i < 10; i += 2)" cs="for (long i = 0; (i < 10 ); i += 2)" rust="for i in (0i64..10).step_by(2)"
 	sumStep := 0
 	for i := 0; i < 10; i += 2 {
 		sumStep += i // 0 + 2 + 4 + 6 + 8 = 20
@@ -148,7 +136,7 @@ func testLoopConstructs() {
 	fmt.Println(sumStep)
 
 	// Decrement loop: i--
-	// @test cpp="for (auto i = 5; i > 0; i--)" cs="for (var i = 5; (i > 0 ); i--)" rust="for i in ((0 + 1)..=5).rev()"
+	// @test cpp="for (std::int64_t i = 5; i > 0; i--)" cs="for (long i = 5; (i > 0 ); i--)" rust="for i in ((0 + 1)..=5i64).rev()"
 	sumDecr := 0
 	for i := 5; i > 0; i-- {
 		sumDecr += i // 5 + 4 + 3 + 2 + 1 = 15
@@ -156,7 +144,7 @@ func testLoopConstructs() {
 	fmt.Println(sumDecr)
 
 	// Inclusive range: i <= n
-	// @test cpp="for (auto i = 1; i <= 5; i++)" cs="for (var i = 1; (i <= 5 ); i++)" rust="for i in 1..=5"
+	// @test cpp="for (std::int64_t i = 1; i <= 5; i++)" cs="for (long i = 1; (i <= 5 ); i++)" rust="for i in 1i64..=5"
 	sumIncl := 0
 	for i := 1; i <= 5; i++ {
 		sumIncl += i // 1 + 2 + 3 + 4 + 5 = 15
@@ -164,7 +152,7 @@ func testLoopConstructs() {
 	fmt.Println(sumIncl)
 
 	// Decrement with inclusive: i >= 0
-	// @test cpp="for (auto i = 3; i >= 0; i--)" cs="for (var i = 3; (i >= 0 ); i--)" rust="for i in (0..=3).rev()"
+	// @test cpp="for (std::int64_t i = 3; i >= 0; i--)" cs="for (long i = 3; (i >= 0 ); i--)" rust="for i in (0..=3i64).rev()"
 	sumDecrIncl := 0
 	for i := 3; i >= 0; i-- {
 		sumDecrIncl += i // 3 + 2 + 1 + 0 = 6
@@ -172,7 +160,7 @@ func testLoopConstructs() {
 	fmt.Println(sumDecrIncl)
 
 	// Step by 3 decrement: i -= 3
-	// @test cpp="for (auto i = 9; i > 0; i -= 3)" cs="for (var i = 9; (i > 0 ); i -= 3)" rust="for i in ((0 + 1)..=9).rev().step_by(3)"
+	// @test cpp="for (std::int64_t i = 9; i > 0; i -= 3)" cs="for (long i = 9; (i > 0 ); i -= 3)" rust="for i in ((0 + 1)..=9i64).rev().step_by(3)"
 	sumDecrStep := 0
 	for i := 9; i > 0; i -= 3 {
 		sumDecrStep += i // 9 + 6 + 3 = 18
@@ -633,6 +621,1440 @@ func testEmptyInterface() {
 	fmt.Println("interface{} test passed")
 }
 
+// Maps are references: the caller sees the entries a callee adds
+func addMapEntry(m map[string]int, key string, value int) {
+	m[key] = value
+}
+
+// A struct holding a map shares the entries with the map it was given
+type MapHolder struct {
+	entries map[string]int
+}
+
+// Map passed by value and summed over a range
+func sumMapValues(m map[string]int) int {
+	total := 0
+	for _, v := range m {
+		total += v
+	}
+	return total
+}
+
+// Test maps: literals, indexing, comma-ok lookup, delete, len and range
+func testMaps() {
+	// @test cpp="map<std::string, std::int64_t> {{" cs="new Dictionary<string, long>" rust="Map::<String, i64>::from(["
+	ages := map[string]int{"alice": 30, "bob": 25}
+	fmt.Println(ages["alice"])
+
+	// Insert and update
+	ages["carol"] = 35
+	ages["bob"] += 1
+	fmt.Println(ages["bob"])
+	fmt.Println(len(ages))
+
+	// Missing keys yield the zero value
+	fmt.Println(ages["dave"])
+
+	// Comma-ok lookup
+	age, ok := ages["carol"]
+	if ok {
+		fmt.Println(age)
+	}
+	_, found := ages["dave"]
+	if !found {
+		fmt.Println("dave not found")
+	}
+
+	// delete
+	delete(ages, "alice")
+	fmt.Println(len(ages))
+	fmt.Println(sumMapValues(ages))
+
+	// make and increment of missing keys
+	// @test cpp="map<std::int64_t, std::int64_t>(0)" cs="new Dictionary<long, long>()" rust="Map::<i64, i64>::new()"
+	counts := make(map[int]int)
+	counts[3]++
+	counts[3]++
+	counts[7] = 1
+	counts[7] += 4
+
+	// Range over keys and values (keys are visited in sorted order)
+	weighted := 0
+	for k, v := range counts {
+		weighted += k * v
+	}
+	fmt.Println(weighted)
+	keySum := 0
+	for k := range counts {
+		keySum += k
+	}
+	fmt.Println(keySum)
+
+	// Map with struct values
+	var people map[string]Person
+	people = make(map[string]Person)
+	people["x"] = Person{name: "Xavier", age: 40}
+	fmt.Println(people["x"].name)
+	fmt.Println(len(people))
+
+	// Copies of a map and maps passed to functions share the entries
+	shared := map[string]int{}
+	addMapEntry(shared, "k", 42)
+	fmt.Println(shared["k"])
+	alias := shared
+	alias["j"] = 7
+	delete(alias, "k")
+	fmt.Println(shared["j"])
+	fmt.Println(len(shared))
+	holder := MapHolder{entries: shared}
+	holder.entries["h"] = 3
+	fmt.Println(len(shared))
+
+	// A nil map reads as empty
+	var none map[string]int
+	fmt.Println(len(none))
+	fmt.Println(none["k"])
+}
+
+// Struct with value and pointer receiver methods
+type Account struct {
+	owner   string
+	balance int
+	history []int
+}
+
+// Pointer receiver: mutations are visible to the caller
+// @test cpp="void Account::Deposit(std::int64_t amount)" cpp="auto& a = *this;" cs="ref var a = ref this;" rust="pub fn Deposit(&mut self, amount: i64)"
+func (a *Account) Deposit(amount int) {
+	a.balance += amount
+	a.history = append(a.history, amount)
+}
+
+// Value receiver: works on a copy of the struct
+// @test cpp="auto a = *this;" cs="public partial struct Account" rust="pub fn Balance(&self) -> i64"
+func (a Account) Balance() int {
+	return a.balance
+}
+
+func (a Account) WithoutHistory() int {
+	a.history = []int{}
+	a.balance = 0
+	return len(a.history)
+}
+
+func (a *Account) Withdraw(amount int) bool {
+	if a.Balance() < amount {
+		return false
+	}
+	a.Deposit(-amount)
+	return true
+}
+
+// Test methods with value and pointer receivers
+func testMethods() {
+	acc := Account{owner: "ann", balance: 0, history: []int{}}
+	acc.Deposit(50)
+	acc.Deposit(25)
+	fmt.Println(acc.Balance())
+	fmt.Println(len(acc.history))
+
+	// A value receiver can't modify the caller's struct
+	fmt.Println(acc.WithoutHistory())
+	fmt.Println(len(acc.history))
+
+	// Pointer methods on slice elements update the element in place
+	accounts := []Account{}
+	accounts = append(accounts, Account{owner: "bob", balance: 10, history: []int{}})
+	accounts = append(accounts, Account{owner: "dan", balance: 20, history: []int{}})
+	for i := 0; i < len(accounts); i++ {
+		accounts[i].Deposit(5)
+	}
+	fmt.Println(accounts[0].Balance())
+	fmt.Println(accounts[1].Balance())
+	fmt.Println(acc.Balance())
+
+	if acc.Withdraw(30) {
+		fmt.Println(acc.Balance())
+	}
+	if !acc.Withdraw(100) {
+		fmt.Println(len(acc.history))
+	}
+}
+
+// Interface with a method set, satisfied implicitly by Circle and Rectangle
+// @test cpp="virtual std::int64_t Area() = 0;" cs="public interface Shape" rust="pub trait ShapeTrait"
+type Shape interface {
+	Area() int
+	Name() string
+}
+
+type Circle struct {
+	radius int
+}
+
+func (c Circle) Area() int {
+	return 3 * c.radius * c.radius
+}
+
+func (c Circle) Name() string {
+	return "circle"
+}
+
+type Rectangle struct {
+	width  int
+	height int
+}
+
+// Every interface a struct satisfies is implemented explicitly
+// @test cs="public partial struct Rectangle : Shape" rust="impl ShapeTrait for Rectangle"
+func (b Rectangle) Area() int {
+	return b.width * b.height
+}
+
+func (b Rectangle) Name() string {
+	return "rectangle"
+}
+
+func sumAreas(shapes []Shape) int {
+	total := 0
+	for _, s := range shapes {
+		total += s.Area()
+	}
+	return total
+}
+
+// Test interfaces with dynamic dispatch and type assertions
+func testInterfaces() {
+	var s Shape
+	s = Circle{radius: 2}
+	fmt.Println(s.Area())
+	fmt.Println(s.Name())
+
+	shapes := []Shape{Circle{radius: 1}, Rectangle{width: 2, height: 3}}
+	shapes = append(shapes, s)
+	fmt.Println(sumAreas(shapes))
+	fmt.Println(shapes[1].Name())
+
+	// Type assertions to concrete types
+	b := shapes[1].(Rectangle)
+	fmt.Println(b.width)
+	// @test cpp="type_assert_ok<Circle>" cs="InterfaceBuiltins.AssertOk<Circle>" rust="downcast_ok::<Circle>()"
+	c, ok := shapes[2].(Circle)
+	if ok {
+		fmt.Println(c.radius)
+	}
+	_, ok = shapes[1].(Circle)
+	if !ok {
+		fmt.Println("not a circle")
+	}
+}
+
+type Weekday int
+
+// Constant enumeration with iota and implicit repetition
+// @test cpp="constexpr Weekday Tuesday = 2;" cs="public const long Tuesday = 2;" rust="pub const Tuesday: Weekday = 2;"
+const (
+	Sunday Weekday = iota
+	Monday
+	Tuesday
+)
+
+type Permission uint8
+
+// Skipped values and shifted iota
+// @test cpp="constexpr Permission PermExec = 8;" cs="public const byte PermExec = 8;" rust="pub const PermExec: Permission = 8;"
+const (
+	PermRead Permission = 1 << iota
+	PermWrite
+	_
+	PermExec
+)
+
+// Test iota constant enumerations
+func testIota() {
+	day := Tuesday
+	fmt.Println(int(day))
+	if day != Sunday && day > Monday {
+		fmt.Println("after monday")
+	}
+	perms := PermRead | PermExec
+	fmt.Println(int(perms))
+	fmt.Println(int(PermWrite))
+}
+
+func describeValue(v interface{}) string {
+	// Type switch lowered to a chain of dynamic type checks
+	// @test cpp="type_is<std::int64_t>(_ts1)" cs="_ts1 is long" rust="_ts1.downcast_ref::<i64>().is_some()"
+	switch val := v.(type) {
+	case int:
+		if val > 10 {
+			return "big int"
+		}
+		return "small int"
+	case string:
+		return "string " + val
+	case bool, float64:
+		return "bool or float64"
+	case Circle:
+		return "circle " + val.Name()
+	case []int:
+		if len(val) == 0 {
+			return "no ints"
+		}
+		return "ints"
+	case [2]int:
+		if val[1] > 10 {
+			return "big pair"
+		}
+		return "small pair"
+	default:
+		return "unknown"
+	}
+}
+
+func shapeKind(s Shape) int {
+	kind := 0
+	switch s.(type) {
+	case Rectangle:
+		kind = 2
+	case Circle:
+		kind = 1
+	}
+	return kind
+}
+
+// Test type switches over interface values
+func testTypeSwitches() {
+	fmt.Println(describeValue(42))
+	fmt.Println(describeValue("hello"))
+	fmt.Println(describeValue(true))
+	fmt.Println(describeValue(Circle{radius: 3}))
+	fmt.Println(describeValue(2.5))
+	// A slice literal stored in an interface keeps its element type
+	// @test rust="std::convert::identity::<Slice<i64>>(slice! {1, 2})"
+	fmt.Println(describeValue([]int{1, 2}))
+	fmt.Println(describeValue([]int{}))
+	fmt.Println(describeValue([2]int{3, 4}))
+
+	var s Shape
+	s = Rectangle{width: 1, height: 2}
+	fmt.Println(shapeKind(s))
+	s = Circle{radius: 1}
+	fmt.Println(shapeKind(s))
+}
+
+// Package-level variables are initialized in dependency order, so tableSum
+// is initialized after squareTable even though it is declared first
+var tableSum int = sumInts(squareTable)
+
+// Lookup table built once instead of on every call
+// @test cpp="slice<std::int64_t> squareTable = buildSquareTable(6);" cs="public static Slice<long> squareTable = buildSquareTable(6);" rust="pub static __pkg_squareTable: PackageVar<Slice<i64>>"
+var squareTable []int = buildSquareTable(6)
+
+var lookups int
+
+func buildSquareTable(n int) []int {
+	table := []int{}
+	for i := 0; i < n; i++ {
+		table = append(table, i*i)
+	}
+	return table
+}
+
+func sumInts(values []int) int {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+func lookupSquare(i int) int {
+	lookups++
+	return squareTable[i]
+}
+
+// The parameter shadows the package-level variable
+func lookupsTimesTwo(lookups int) int {
+	return lookups * 2
+}
+
+// Test package-level variables
+func testPackageVars() {
+	fmt.Println(tableSum)
+	fmt.Println(lookupSquare(4))
+	fmt.Println(lookupSquare(5))
+	fmt.Println(lookups)
+	fmt.Println(lookupsTimesTwo(21))
+	squareTable[0] = 100
+	squareTable = append(squareTable, 36)
+	fmt.Println(len(squareTable))
+	fmt.Println(squareTable[0])
+}
+
+func deferredPrint(label string, value int) {
+	fmt.Println(label)
+	fmt.Println(value)
+}
+
+// Deferred calls run in LIFO order on every return path
+// @test cpp="DeferStack _defers" cs="new Stack<Action>()" rust="Defers::new()"
+func deferOnReturn(n int) int {
+	defer deferredPrint("first deferred", n)
+	if n > 3 {
+		return n * 10
+	}
+	defer func() {
+		fmt.Println("closure deferred")
+	}()
+	for i := 0; i < 2; i++ {
+		defer deferredPrint("loop deferred", i)
+	}
+	n = n + 100
+	return n
+}
+
+type DeferNote struct {
+	text string
+}
+
+func (n *DeferNote) Report() {
+	fmt.Println("note " + n.text)
+}
+
+func (n DeferNote) Show() {
+	fmt.Println("shown " + n.text)
+}
+
+// The receiver of a deferred method is evaluated at the defer statement
+// @test cpp="_defer4_recv = note" cs="var _defer5_recv = value;" rust="let _defer4_recv = note.clone();"
+func deferReceivers() {
+	note := &DeferNote{text: "first"}
+	defer note.Report()
+	note = &DeferNote{text: "second"}
+	value := DeferNote{text: "value"}
+	defer value.Show()
+	value.text = "changed"
+	note.Report()
+}
+
+// Test defer
+func testDefer() {
+	fmt.Println(deferOnReturn(5))
+	fmt.Println(deferOnReturn(1))
+	deferReceivers()
+}
+
+func popLast(items []int) int {
+	if len(items) == 0 {
+		panic("pop from empty slice")
+	}
+	return items[len(items)-1]
+}
+
+// A deferred recover() stops a panic and the function returns zero values
+// @test cpp="catch (GoPanic &_panic)" cs="PanicBuiltins.Recovered(_panic" rust="std::panic::catch_unwind"
+func safePopLast(items []int) int {
+	defer func() {
+		msg, ok := recover().(string)
+		if ok {
+			fmt.Println("recovered: " + msg)
+		}
+	}()
+	return popLast(items) * 2
+}
+
+// Runtime errors such as an index out of range are panics too
+func safeStore(items []int, i int, value int) int {
+	defer func() {
+		recover()
+	}()
+	items[i] = value
+	return items[i]
+}
+
+// Integer division by zero panics, the named result reports it
+// @test cpp="int_quo<std::int64_t>(a, b)" cpp="int_rem<std::uint8_t>(a, b)"
+func safeDivide(a int, b int) (q int) {
+	defer func() {
+		if recover() != nil {
+			q = -1
+		}
+	}()
+	return a / b
+}
+
+func safeRem8(a uint8, b uint8) (r uint8) {
+	defer func() {
+		if recover() != nil {
+			r = 255
+		}
+	}()
+	return a % b
+}
+
+// Test panic and recover
+func testPanicRecover() {
+	items := []int{3, 4}
+	empty := []int{}
+	fmt.Println(safePopLast(items))
+	fmt.Println(safePopLast(empty))
+	fmt.Println(safeStore(items, 1, 7))
+	fmt.Println(safeStore(items, 2, 7))
+	fmt.Println(safeDivide(7, 2))
+	fmt.Println(safeDivide(7, 0))
+	fmt.Println(safeRem8(7, 4))
+	fmt.Println(safeRem8(7, 0))
+}
+
+// Variadic parameters collect any number of arguments into a slice
+// @test cpp="std::int64_t sumAll(slice<std::int64_t> values)" cs="sumAll(params Slice<long> values)" rust="fn sumAll(mut values: Slice<i64>)"
+func sumAll(values ...int) int {
+	return sumInts(values)
+}
+
+func zeroFirst(values ...int) {
+	values[0] = 0
+}
+
+func countArgs(values ...int) int {
+	if values == nil {
+		return -1
+	}
+	return len(values)
+}
+
+func joinWords(sep string, words ...string) string {
+	result := ""
+	for i, w := range words {
+		if i > 0 {
+			result += sep
+		}
+		result += w
+	}
+	return result
+}
+
+// Test variadic functions and spread calls
+func testVariadic() {
+	fmt.Println(sumAll())
+	fmt.Println(sumAll(1, 2, 3))
+	nums := []int{4, 5}
+	fmt.Println(sumAll(nums...))
+	words := []string{"b", "c"}
+	fmt.Println(joinWords("-", "a"))
+	fmt.Println(joinWords("-", words...))
+	nums = append(nums, 6, 7)
+	nums = append(nums, nums...)
+	fmt.Println(len(nums))
+	fmt.Println(sumAll(nums...))
+	// A spread slice is passed as it is, the function shares its elements
+	zeroFirst(nums...)
+	fmt.Println(nums[0])
+	fmt.Println(countArgs())
+}
+
+// Named results are zero-valued locals, a naked return returns them
+// @test cpp="std::int64_t quo{};" cs="long rem = default;" rust="let mut quo: i64 = 0;"
+func quoRem(a int, b int) (quo, rem int) {
+	if b == 0 {
+		return
+	}
+	quo = a / b
+	rem = a % b
+	return
+}
+
+// Deferred calls run after the results are assigned and may change them
+// @test cpp="_defers" cs="goto _return" rust="Shared::new(total)"
+func clampedSum(values []int, limit int) (total int) {
+	defer func() {
+		if total > limit {
+			total = limit
+		}
+	}()
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Test named results and naked returns
+func testNamedResults() {
+	q, r := quoRem(17, 5)
+	fmt.Println(q)
+	fmt.Println(r)
+	q, r = quoRem(1, 0)
+	fmt.Println(q)
+	fmt.Println(r)
+	fmt.Println(clampedSum([]int{1, 2, 3}, 10))
+	fmt.Println(clampedSum([]int{5, 6, 7}, 10))
+}
+
+type Bounds struct {
+	X      int
+	Y      int
+	Width  int
+	Height int
+}
+
+func (b Bounds) Right() int {
+	return b.X + b.Width
+}
+
+func (b *Bounds) Move(dx int, dy int) {
+	b.X += dx
+	b.Y += dy
+}
+
+type Panel struct {
+	Bounds
+	Title string
+}
+
+type Dialog struct {
+	Panel
+	Modal bool
+}
+
+// Test struct embedding with promoted fields and methods
+// @test cpp="d.Panel_.Bounds_.Right()" cs="d.Panel.Bounds.Right()" rust="d.Panel.Bounds.Right()"
+func testStructEmbedding() {
+	p := Panel{Bounds: Bounds{X: 1, Y: 2, Width: 10, Height: 5}, Title: "main"}
+	fmt.Println(p.X)
+	fmt.Println(p.Right())
+	p.Move(3, 4)
+	fmt.Println(p.Bounds.X)
+	fmt.Println(p.Y)
+	d := Dialog{Panel: Panel{Bounds: Bounds{Width: 4}, Title: "dialog"}, Modal: true}
+	d.Width += 5
+	d.Move(1, 1)
+	fmt.Println(d.Right())
+	fmt.Println(d.Title)
+}
+
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+type Queue[T any] struct {
+	items []T
+}
+
+func (q *Queue[T]) Put(v T) {
+	q.items = append(q.items, v)
+}
+
+func (q Queue[T]) Front() T {
+	return q.items[0]
+}
+
+// Test generic functions and struct types
+// @test cpp="template <typename T>" cs="T sumOf<T>(Slice<T> values)" rust="impl<T: Clone"
+func sumOf[T Number](values []T) T {
+	var total T
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func indexOf[T comparable](values []T, target T) int {
+	for i := 0; i < len(values); i++ {
+		if values[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+func testGenerics() {
+	q := Queue[string]{}
+	q.Put("first")
+	q.Put("second")
+	fmt.Println(q.Front())
+	fmt.Println(sumOf([]int{1, 2, 3}))
+	fmt.Println(sumOf([]float64{0.5, 1.5}))
+	fmt.Println(indexOf([]string{"a", "b"}, "b"))
+	fmt.Println(indexOf[int]([]int{4, 5}, 6))
+}
+
+// Test labeled break and continue out of nested loops
+// @test cpp="goto search_continue" cs="goto search_break" rust="break 'search"
+func findCell(values []int, width int, target int) int {
+	found := -1
+search:
+	for row := 0; row < len(values)/width; row++ {
+		for col := 0; col < width; col++ {
+			v := values[row*width+col]
+			if v < 0 {
+				continue search
+			}
+			if v == target {
+				found = row*width + col
+				break search
+			}
+		}
+	}
+	return found
+}
+
+// Labels that are keywords in a target language are renamed there
+// @test rust="'_loop: for" rust="continue '_loop;" rust="break '_loop;"
+func countBelow(rows [][]int, limit int) int {
+	count := 0
+loop:
+	for _, row := range rows {
+		for _, v := range row {
+			if v < 0 {
+				continue loop
+			}
+			if v >= limit {
+				break loop
+			}
+			count++
+		}
+	}
+	return count
+}
+
+func testLabeledLoops() {
+	fmt.Println(findCell([]int{1, 2, -1, 4, 4, 5}, 2, 4))
+	fmt.Println(findCell([]int{1, 2}, 2, 7))
+	rows := make([][]int, 0)
+	rows = append(rows, []int{1, 2})
+	rows = append(rows, []int{3, -1, 4})
+	rows = append(rows, []int{5, 9, 6})
+	fmt.Println(countBelow(rows, 8))
+}
+
+// Test fixed-size arrays, copied on assignment like any other value
+// @test cpp="std::array<std::int64_t, 4>" cs="Array4<long>" rust="[i64; 4]"
+type Tile struct {
+	Pixels  [4]int
+	Corners [4]bool
+}
+
+func doubled(values [4]int) [4]int {
+	for i := range values {
+		values[i] *= 2
+	}
+	return values
+}
+
+// The slices of an array share its elements
+// @test cpp="slice_expr(_digits, 1)" cs="var _digits = digits.Box();" rust="let digits = Slice::from_vec(Vec::from(digits));"
+func sharedSlices(values [3]int) {
+	digits := values
+	all := digits[:]
+	tail := digits[1:]
+	all[2] = 9
+	fmt.Println(digits[2])
+	digits[1] = 4
+	fmt.Println(tail[0])
+	fmt.Println(cap(tail))
+	other := [3]int{7, 7, 7}
+	digits = other
+	fmt.Println(all[0] + tail[1])
+	total := 0
+	for _, d := range digits {
+		total += d
+	}
+	fmt.Println(total)
+}
+
+func testFixedArrays() {
+	var a [4]int
+	a[1] = 5
+	b := a
+	b[1] = 7
+	fmt.Println(a[1])
+	fmt.Println(b[1])
+	c := doubled(b)
+	fmt.Println(b[1])
+	fmt.Println(c[1])
+	fmt.Println(len(c))
+	primes := [...]int{2, 3, 5}
+	s := primes[1:]
+	fmt.Println(s[0])
+	fmt.Println(len(s))
+	sharedSlices(primes)
+	var t Tile
+	t.Pixels[3] = 9
+	t.Corners[2] = true
+	if t.Corners[2] {
+		fmt.Println(t.Pixels[3])
+	}
+	var grid [2][3]int
+	grid[1][2] = 4
+	row := grid[1]
+	row[0] = 1
+	fmt.Println(grid[1][0] + grid[1][2])
+	names := [3]string{"x", "y"}
+	fmt.Println(names[1] + names[2] + "|")
+	if a != b {
+		fmt.Println("different")
+	}
+	a[1] = 7
+	if a == b {
+		fmt.Println("equal")
+	}
+}
+
+// Test pointers to structs, copies of a pointer share the value
+// @test cpp="std::shared_ptr<Link>" cs="Ptr<Link>" rust="Ptr<Link>"
+type Link struct {
+	Val  int
+	Next *Link
+}
+
+type Chain struct {
+	Head *Link
+	Len  int
+}
+
+func (c *Chain) Push(v int) {
+	c.Head = &Link{Val: v, Next: c.Head}
+	c.Len++
+}
+
+func (c Chain) Sum() int {
+	total := 0
+	l := c.Head
+	for l != nil {
+		total += l.Val
+		l = l.Next
+	}
+	return total
+}
+
+type Tally struct {
+	Count int
+}
+
+func (t *Tally) Add(n int) {
+	t.Count += n
+}
+
+func bumpTally(t *Tally) {
+	t.Add(10)
+}
+
+func testPointers() {
+	var c Chain
+	c.Push(1)
+	c.Push(2)
+	c.Push(3)
+	fmt.Println(c.Sum())
+	fmt.Println(c.Len)
+	t := new(Tally)
+	alias := t
+	alias.Add(2)
+	bumpTally(t)
+	fmt.Println(t.Count)
+	if t == alias {
+		fmt.Println("same tally")
+	}
+	local := Tally{Count: 5}
+	p := &local
+	p.Count++
+	fmt.Println(local.Count)
+	copied := *p
+	copied.Count = 0
+	fmt.Println(p.Count)
+	*p = Tally{Count: 1}
+	fmt.Println(local.Count)
+	// A variable whose address is taken holds a copy of its initial value
+	dup := local
+	pd := &dup
+	pd.Add(100)
+	fmt.Println(local.Count)
+	fmt.Println(dup.Count)
+	seed := Tally{Count: 3}
+	other := seed
+	po := &other
+	po.Count = 9
+	fmt.Println(seed.Count)
+	var blank Tally
+	pb := &blank
+	pb.Add(4)
+	fmt.Println(blank.Count)
+	c.Head.Next = nil
+	if c.Head.Next == nil {
+		fmt.Println(c.Sum())
+	}
+}
+
+// Test nil slices, function values and interface{} values
+// @test cpp="h.Fn == nullptr" cs="Func<long, long>" rust="Option<Rc<dyn Fn(i64) -> i64>>"
+type Hook struct {
+	Name string
+	Fn   func(int) int
+}
+
+func runHook(h Hook, v int) int {
+	if h.Fn == nil {
+		return -1
+	}
+	return h.Fn(v)
+}
+
+func doubleValue(x int) int {
+	return x * 2
+}
+
+func lookupName(names []string, name string) any {
+	for _, n := range names {
+		if n == name {
+			return n
+		}
+	}
+	return nil
+}
+
+func testNilValues() {
+	var s []int
+	if s == nil {
+		fmt.Println("nil slice")
+	}
+	s = append(s, 1)
+	if s != nil {
+		fmt.Println(len(s))
+	}
+	s = nil
+	for _, x := range s {
+		fmt.Println(x)
+	}
+	fmt.Println(len(s))
+	h := Hook{Name: "double"}
+	fmt.Println(runHook(h, 4))
+	h.Fn = doubleValue
+	fmt.Println(runHook(h, 4))
+	h.Fn = func(x int) int { return x + 1 }
+	fmt.Println(runHook(h, 4))
+	var f func(int) int
+	if nil == f {
+		fmt.Println("nil func")
+	}
+	f = h.Fn
+	if f != nil {
+		fmt.Println(f(10))
+	}
+	names := []string{"a", "b"}
+	if lookupName(names, "z") == nil {
+		fmt.Println("not found")
+	}
+	found := lookupName(names, "b")
+	if found != nil {
+		fmt.Println(found.(string))
+	}
+}
+
+// Empty slices and maps are not nil, slices of a nil slice are
+// @test cpp="(empty != nullptr)" cs="new Slice<long>(0)" rust="!empty.is_nil()"
+func testEmptyIsNotNil() {
+	empty := []int{}
+	if empty != nil {
+		fmt.Println("empty slice")
+	}
+	made := make([]int, 0)
+	if made != nil {
+		fmt.Println("made slice")
+	}
+	if made[0:0] != nil {
+		fmt.Println("slice of made")
+	}
+	var none []int
+	if none[0:0] == nil {
+		fmt.Println("slice of nil")
+	}
+	var counts map[string]int
+	if counts == nil {
+		fmt.Println(len(counts))
+	}
+	counts = map[string]int{}
+	if counts != nil {
+		fmt.Println("empty map")
+	}
+	counts = nil
+	if counts == nil {
+		fmt.Println("nil map")
+	}
+}
+
+// @test cpp="for (auto [i, r] : string_range(word))" cs="foreach (var (i, r) in SliceBuiltins.Range(word))" rust="for (i, r) in string_range(&word)"
+func testRangeForms() {
+	total := 0
+	for i := range 5 {
+		total += i
+	}
+	fmt.Println(total)
+	for range 2 {
+		fmt.Println("tick")
+	}
+	word := "añb"
+	for i, r := range word {
+		fmt.Println(i)
+		fmt.Println(r)
+	}
+	for _, r := range word {
+		if r == 'ñ' {
+			fmt.Println("found")
+		}
+	}
+	for _, x := range []int{7, 8} {
+		fmt.Println(x)
+	}
+}
+
+func squareWorker(jobs chan int, results chan int) {
+	for n := range jobs {
+		results <- n * n
+	}
+	close(results)
+}
+
+// Receivers called through an interface and through a func value
+type IntSource interface {
+	Receive() int
+}
+
+type ChanSource struct {
+	values chan int
+}
+
+func (c ChanSource) Receive() int {
+	return <-c.values
+}
+
+func receiveFrom(src IntSource) int {
+	return src.Receive()
+}
+
+func receiveWith(recv func(chan int) int, values chan int) int {
+	return recv(values)
+}
+
+func receiveInt(values chan int) int {
+	return <-values
+}
+
+func sendValue(c chan int, v int) {
+	c <- v
+}
+
+// Deferred calls may send on a channel, also while recovering from a panic
+func deferSends(c chan int) {
+	defer sendValue(c, 2)
+	defer func() {
+		if recover() != nil {
+			c <- 1
+		}
+	}()
+	panic("deferred sends")
+}
+
+// @test cpp="chan_send(jobs, i);" cs="ChanBuiltins.Send(jobs, i);" rust="chan_send(&jobs, i);" cs="FuncBuiltins.Lambda((long x)=>"
+func testGoroutines() {
+	jobs := make(chan int, 3)
+	results := make(chan int)
+	go squareWorker(jobs, results)
+	for i := 1; i <= 3; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	sum := 0
+	for sq := range results {
+		sum += sq
+	}
+	fmt.Println(sum)
+	_, open := <-results
+	if !open {
+		fmt.Println("closed")
+	}
+	ready := make(chan string, 1)
+	select {
+	case msg := <-ready:
+		fmt.Println(msg)
+	default:
+		fmt.Println("nothing ready")
+	}
+	ready <- "go"
+	select {
+	case msg := <-ready:
+		fmt.Println(msg)
+	default:
+		fmt.Println("nothing ready")
+	}
+	values := make(chan int, 1)
+	values <- 5
+	fmt.Println(receiveFrom(ChanSource{values: values}))
+	go func() { values <- 6 }()
+	fmt.Println(receiveWith(receiveInt, values))
+	people := make(chan Person, 1)
+	people <- Person{name: "Ada", age: 36}
+	p := <-people
+	fmt.Println(p.age)
+	squares := make(chan int)
+	for i := 1; i <= 3; i++ {
+		go func(x int) {
+			squares <- x * x
+		}(i)
+	}
+	squareSum := 0
+	for i := 0; i < 3; i++ {
+		squareSum += <-squares
+	}
+	fmt.Println(squareSum)
+	sent := make(chan int, 2)
+	deferSends(sent)
+	fmt.Println(<-sent)
+	fmt.Println(<-sent)
+}
+
+func makeCounter() func() int {
+	count := 0
+	return func() int {
+		count++
+		return count
+	}
+}
+
+// Test closures that mutate captured variables and share them
+// @test cpp="std::make_shared<decltype(total)>(total)" rust="let total = Shared::new(total);" rust="let mut i = _loop1;"
+func testClosures() {
+	next := makeCounter()
+	next()
+	fmt.Println(next())
+	total := 0
+	add := func(n int) { total += n }
+	get := func() int { return total }
+	add(3)
+	add(4)
+	fmt.Println(get())
+	names := []string{"a"}
+	show := func() { fmt.Println(len(names)) }
+	names = append(names, "b")
+	show()
+	clicks := 0
+	handlers := []func(){}
+	for i := 0; i < 3; i++ {
+		handlers = append(handlers, func() { clicks++ })
+	}
+	for _, h := range handlers {
+		h()
+	}
+	fmt.Println(clicks)
+	// Each iteration has its own i, which its closure shares
+	var bumps []func() int
+	for i := 0; i < 6; i++ {
+		bump := func() int {
+			i++
+			return i
+		}
+		bumps = append(bumps, bump)
+		if i == 2 {
+			bump()
+			continue
+		}
+		fmt.Println(i)
+	}
+	for _, bump := range bumps {
+		fmt.Println(bump())
+	}
+	for n := range 3 {
+		twice := func() { n *= 2 }
+		twice()
+		fmt.Println(n)
+	}
+}
+
+// Test variables redeclared in nested scopes, which shadow the outer ones
+// @test cpp="auto sum_1 = sum + i;" cs="foreach (var level_2 in SliceBuiltins.Range(values))" cpp="auto _level_4 = std::make_shared<decltype(level_4)>(level_4);"
+func testShadowing() {
+	level := 1
+	if level > 0 {
+		level := level + 1
+		fmt.Println(level)
+	}
+	fmt.Println(level)
+	sum := 0
+	for i := 0; i < 3; i++ {
+		sum := sum + i
+		fmt.Println(sum)
+	}
+	values := []int{4, 5}
+	for _, level := range values {
+		fmt.Println(level)
+	}
+	scale := func(level int) int {
+		return level * 10
+	}
+	fmt.Println(scale(level))
+	levels := make(chan int, 1)
+	levels <- 7
+	select {
+	case level := <-levels:
+		raise := func() { level++ }
+		raise()
+		fmt.Println(level)
+	default:
+		fmt.Println("no level")
+	}
+	fmt.Println(level)
+}
+
+// Test int and uint, 64 bits wide as in Go. Constants such as 1 << 40 don't
+// fit with -int-size=32, which the compiler reports, so this program is built
+// with the default width.
+func testIntWidth() {
+	// @test cpp="std::int64_t cycles = 0;" cs="long cycles = 0;" rust="let mut cycles: i64 = 0;"
+	cycles := 0
+	for i := 0; i < 100; i++ {
+		cycles += 100000000
+	}
+	fmt.Println(cycles)
+	// @test cpp="1099511627776" cs="1099511627776" rust="1099511627776i64"
+	big := 1 << 40
+	fmt.Println(big)
+	fmt.Println(big / 3)
+	fmt.Println(int64(1<<33) + 1)
+	var u uint
+	u = 4000000000
+	fmt.Println(u + 1)
+	values := []int{1, 2, 3}
+	n := len(values) + big
+	fmt.Println(n)
+	fmt.Println(values[n-big-1])
+	fmt.Println(len(values[1 : n-big]))
+}
+
+// Test fixed-width integer arithmetic wrapping around, shifting and dividing
+// as in Go
+func testIntWraparound() {
+	// @test cpp="sp = std::uint8_t(sp - 1);" cs="sp = (byte)" rust="u8::wrapping_sub(sp, 1)"
+	sp := uint8(0)
+	sp--
+	fmt.Println(sp)
+	a := uint8(200)
+	// @test cpp="std::uint8_t(a + a)" cs="(byte) (a + a )" rust="u8::wrapping_add(a, a)"
+	fmt.Println(a + a)
+	b := int8(127)
+	b++
+	fmt.Println(b)
+	fmt.Println(-b)
+	c := int32(2147483647)
+	fmt.Println(c + 1)
+	fmt.Println(c * c)
+	d := uint32(0)
+	fmt.Println(d - 1)
+	fmt.Println(^d)
+	e := uint16(65535)
+	e += 2
+	fmt.Println(e)
+
+	// Shifts by counts at least the width shift every bit out
+	one := uint8(1)
+	n := 8
+	// @test cpp="int_shl<std::uint8_t>(one, n)" cs="IntBuiltins.Shl<byte> (one, n )" rust="u8::checked_shl(one, shift_count(n as i128)).unwrap_or(0)"
+	fmt.Println(one << n)
+	fmt.Println(one << (n - 1))
+	neg := int32(-8)
+	fmt.Println(neg >> 40)
+	fmt.Println(neg >> 1)
+	fmt.Println(uint32(neg) >> 1)
+
+	// Division truncates towards zero and the remainder takes the sign of
+	// the dividend
+	p := -7
+	q := 2
+	fmt.Println(p / q)
+	fmt.Println(p % q)
+	fmt.Println(-p % q)
+	fmt.Println(p % -q)
+	mn := int32(-2147483648)
+	m1 := int32(-1)
+	// @test cpp="int_quo<std::int32_t>(mn, m1)" cs="IntBuiltins.Quo<int> (mn, m1 )" rust="i32::wrapping_div(mn, m1)"
+	fmt.Println(mn / m1)
+	fmt.Println(mn % m1)
+
+	status := uint8(0x0F)
+	status |= 0xC0
+	status &^= 0x03
+	fmt.Println(status)
+	status <<= 1
+	fmt.Println(status)
+	fmt.Println(^uint8(0))
+
+	// int and uint wrap around at 64 bits, JavaScript goes through BigInts
+	zero := uint(0)
+	fmt.Println(zero - 1)
+	top := 1<<63 - 1
+	fmt.Println(top + 1)
+	wide := 1 << 40
+	fmt.Println(wide * wide)
+	fmt.Println(wide | 1)
+	fmt.Println(wide / -3)
+}
+
+// Test int64 and uint64 values beyond 2^53, which JavaScript holds as BigInts
+func testInt64Values() {
+	// FNV-1a hash
+	// @test cpp="std::uint64_t(14695981039346656037ULL)" cs="(ulong)(14695981039346656037)" rust="14695981039346656037u64"
+	h := uint64(14695981039346656037)
+	for _, b := range []uint8{104, 105} {
+		h ^= uint64(b)
+		h *= 1099511628211
+	}
+	fmt.Println(h)
+	nanos := int64(1700000000)
+	nanos = nanos*1000000000 + 123456789
+	fmt.Println(nanos)
+	fmt.Println(nanos / 1000000000)
+	fmt.Println(nanos % 1000)
+	maxInt := int64(9223372036854775807)
+	maxInt++
+	fmt.Println(maxInt)
+	var mask uint64
+	mask--
+	fmt.Println(mask >> 1)
+	fmt.Println(mask << 60)
+	fmt.Println(int32(nanos))
+	fmt.Println(uint8(mask))
+	fmt.Println(int64(len("four")) << 40)
+	seen := map[int64]bool{}
+	seen[nanos] = true
+	fmt.Println(len(seen))
+}
+
+func zeroRegion(region []int) {
+	for i := 0; i < len(region); i++ {
+		region[i] = 0
+	}
+}
+
+func testSliceAliasing() {
+	// Sub-slices share storage with the slice they were taken from
+	// @test cpp="slice_expr(buf, 1, 3)" cs="SliceBuiltins.Slice(buf, 1, 3)" rust="buf.slice(1, 3)"
+	buf := []int{1, 2, 3, 4, 5}
+	mid := buf[1:3]
+	mid[0] = 20
+	fmt.Println(buf[1])
+	fmt.Println(len(mid))
+	fmt.Println(cap(mid))
+	zeroRegion(buf[3:])
+	fmt.Println(buf[4])
+	// append reuses spare capacity
+	head := buf[:2]
+	head = append(head, 30)
+	fmt.Println(buf[2])
+	// A full slice expression limits capacity, so append reallocates
+	// @test cpp="slice_expr(buf, 0, 2, 2)" cs="SliceBuiltins.Slice(buf, 0, 2, 2)" rust="buf.slice3(0, 2, 2)"
+	capped := buf[0:2:2]
+	capped = append(capped, 40)
+	fmt.Println(buf[2])
+	fmt.Println(capped[2])
+	// @test cpp="make_slice<slice<std::int64_t>>(2, 8)" cs="Slice<long>.Make(2, 8)" rust="Slice::<i64>::make_cap((2) as Int, (8) as Int)"
+	spare := make([]int, 2, 8)
+	fmt.Println(len(spare))
+	fmt.Println(cap(spare))
+	grown := append(spare, 7)
+	grown[0] = 9
+	fmt.Println(spare[0])
+	n := copy(spare, buf)
+	fmt.Println(n)
+	fmt.Println(spare[1])
+	copy(buf[1:], buf)
+	fmt.Println(buf[4])
+}
+
+type Caret struct {
+	Row int
+	Col int
+}
+
+type Buffer struct {
+	At    Caret
+	Marks [2]int
+}
+
+// MoveCaret returns a buffer whose caret is at row, col, b is unchanged
+func MoveCaret(b Buffer, row int, col int) Buffer {
+	b.At.Row = row
+	b.At.Col = col
+	return b
+}
+
+func (c Caret) Down() Caret {
+	c.Row++
+	return c
+}
+
+type CaretBook struct {
+	byName map[string]Caret
+}
+
+// Keep stores c, which the caller may still change, in a map
+func (book *CaretBook) Keep(name string, c Caret) map[string]Caret {
+	book.byName[name] = c
+	return map[string]Caret{name: c}
+}
+
+// keepAny stores v in an interface, which must not see later changes of v's source
+func keepAny(v any) any {
+	return v
+}
+
+func testStructCopies() {
+	// Structs are values: assigning, passing and returning copy them
+	first := Buffer{At: Caret{Row: 1, Col: 1}}
+	// @test cpp="auto second = first" cs="var second = first" rust="let mut second = first.clone()"
+	second := first
+	second.At.Row = 2
+	second.Marks[0] = 5
+	fmt.Println(first.At.Row)
+	fmt.Println(first.Marks[0])
+	moved := MoveCaret(first, 3, 4)
+	fmt.Println(first.At.Col)
+	fmt.Println(moved.At.Col)
+	below := first.At.Down()
+	fmt.Println(first.At.Row)
+	fmt.Println(below.Row)
+	// Slice elements, map values and range values are copied when read
+	carets := []Caret{first.At, moved.At}
+	picked := carets[1]
+	picked.Col = 9
+	fmt.Println(carets[1].Col)
+	for _, c := range carets {
+		carets[0].Row = 7
+		fmt.Println(c.Row)
+	}
+	byName := map[string]Caret{"home": first.At}
+	home := byName["home"]
+	home.Row = 8
+	fmt.Println(byName["home"].Row)
+	saved := append([]Caret{}, carets...)
+	saved[0].Col = 6
+	fmt.Println(carets[0].Col)
+	// A map keeps its own copy of a parameter stored in it
+	book := &CaretBook{byName: make(map[string]Caret)}
+	kept := book.Keep("end", moved.At)
+	moved.At.Row = 5
+	fmt.Println(book.byName["end"].Row)
+	fmt.Println(kept["end"].Row)
+	// An interface keeps its own copy of a struct
+	boxed := keepAny(moved.At)
+	moved.At.Col = 11
+	fmt.Println(boxed.(Caret).Col)
+	// A field read out of a variable whose address is taken is copied too
+	target := Buffer{At: Caret{Row: 1, Col: 2}}
+	at := target.At
+	ptr := &target
+	ptr.At.Row = 17
+	fmt.Println(at.Row)
+	fmt.Println(target.At.Row)
+}
+
 func main() {
 	fmt.Println("=== All Language Constructs Test ===")
 
@@ -660,6 +2082,32 @@ func main() {
 	testNestedStructField()
 	testMultiPackageImport()
 	testEmptyInterface()
+	testMaps()
+	testMethods()
+	testInterfaces()
+	testTypeSwitches()
+	testIota()
+	testPackageVars()
+	testDefer()
+	testPanicRecover()
+	testVariadic()
+	testNamedResults()
+	testStructEmbedding()
+	testGenerics()
+	testLabeledLoops()
+	testFixedArrays()
+	testPointers()
+	testNilValues()
+	testEmptyIsNotNil()
+	testRangeForms()
+	testGoroutines()
+	testClosures()
+	testShadowing()
+	testIntWidth()
+	testIntWraparound()
+	testInt64Values()
+	testSliceAliasing()
+	testStructCopies()
 
 	fmt.Println("=== Done ===")
 }